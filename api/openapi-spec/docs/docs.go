@@ -15,16 +15,532 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
+        "/api/v1/customers/{customerId}/orders": {
+            "get": {
+                "description": "Returns order count, total spend per currency and last order date of the customer, along with a page of their orders.\u003cbr\u003ePass \u003cstrong\u003enext_cursor\u003c/strong\u003e from the previous response as \u003cstrong\u003ecursor\u003c/strong\u003e to get the next page.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Customers"
+                ],
+                "summary": "Get customer orders with stats",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Customer ID",
+                        "name": "customerId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "date_desc",
+                            "date_asc",
+                            "amount_desc",
+                            "amount_asc"
+                        ],
+                        "type": "string",
+                        "default": "date_desc",
+                        "description": "Sort order",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 20,
+                        "description": "Page size (1-100)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor from the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Customer stats and orders",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Pur1st2EpicONE_WBTECH-sample-microservice_internal_models.CustomerOrders"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid query parameters",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Customer has no orders",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Database unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ErrorResponse"
+                        }
+                    },
+                    "504": {
+                        "description": "Database timeout",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/customers/{customerId}/personal-data": {
+            "delete": {
+                "description": "Replaces the delivery details (name, phone, address, email) in every order of the customer with placeholders, including the copies in ingested messages and amendments. Items and payment are kept for accounting.\u003cbr\u003eThe erasure is recorded along with the erased orders and the given reference.\u003cbr\u003eRequires the admin token: \u003cstrong\u003eAuthorization: Bearer \u0026lt;token\u0026gt;\u003c/strong\u003e",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Admin"
+                ],
+                "summary": "Erase the personal data of a customer",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Customer ID",
+                        "name": "customerId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Reference of the request, e.g. a ticket number",
+                        "name": "reference",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Erasure record",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Pur1st2EpicONE_WBTECH-sample-microservice_internal_models.PersonalDataErasure"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid admin token",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Admin endpoints are disabled",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Database unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ErrorResponse"
+                        }
+                    },
+                    "504": {
+                        "description": "Database timeout",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/orders": {
+            "get": {
+                "description": "Returns orders page by page using cursor (keyset) pagination.\u003cbr\u003ePass \u003cstrong\u003enext_cursor\u003c/strong\u003e from the previous response as \u003cstrong\u003ecursor\u003c/strong\u003e to get the next page, keeping the same filters and sort.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Orders"
+                ],
+                "summary": "List orders",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Filter by customer ID",
+                        "name": "customer_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by delivery service",
+                        "name": "delivery_service",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by locale",
+                        "name": "locale",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by payment currency",
+                        "name": "currency",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Only orders with at least one item in this status",
+                        "name": "item_status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Exact delivery phone",
+                        "name": "phone",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Exact delivery email",
+                        "name": "email",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Created at or after this time (RFC 3339)",
+                        "name": "date_from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Created before this time (RFC 3339)",
+                        "name": "date_to",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "date_desc",
+                            "date_asc",
+                            "amount_desc",
+                            "amount_asc"
+                        ],
+                        "type": "string",
+                        "default": "date_desc",
+                        "description": "Sort order",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 20,
+                        "description": "Page size (1-100)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Cursor from the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Page of orders",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Pur1st2EpicONE_WBTECH-sample-microservice_internal_models.OrderPage"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid query parameters",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Database unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ErrorResponse"
+                        }
+                    },
+                    "504": {
+                        "description": "Database timeout",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
         "/api/v1/orders/{orderId}": {
             "get": {
-                "description": "Returns order details in JSON format.\u003cbr\u003eCheck \u003cstrong\u003eX-Cache\u003c/strong\u003e header for cache status: \u003cstrong\u003eHIT\u003c/strong\u003e (from cache) or \u003cstrong\u003eMISS\u003c/strong\u003e (from database)",
+                "description": "Returns order details in JSON format.\u003cbr\u003eCheck \u003cstrong\u003eX-Cache\u003c/strong\u003e header for cache status: \u003cstrong\u003eHIT\u003c/strong\u003e (from cache) or \u003cstrong\u003eMISS\u003c/strong\u003e (from database)",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Orders"
+                ],
+                "summary": "Get order by UID with cache status indication",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Order ID (UUID)",
+                        "name": "orderId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Order data",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Pur1st2EpicONE_WBTECH-sample-microservice_internal_models.Order"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the order"
+                            },
+                            "X-Cache": {
+                                "type": "string",
+                                "description": "Cache status: HIT or MISS"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Order not found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Database unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ErrorResponse"
+                        }
+                    },
+                    "504": {
+                        "description": "Database timeout",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "description": "Applies a JSON Merge Patch to the order, e.g. \u003cstrong\u003e{\"delivery\": {\"phone\": \"+9720000001\"}}\u003c/strong\u003e.\u003cbr\u003eAmendable fields: delivery, entry, locale, internal_signature, customer_id, delivery_service, sm_id, oof_shard.\u003cbr\u003ePass the \u003cstrong\u003eETag\u003c/strong\u003e of the order as \u003cstrong\u003eIf-Match\u003c/strong\u003e. Every amendment is recorded along with the order as it was before.\u003cbr\u003eRequires the admin token: \u003cstrong\u003eAuthorization: Bearer \u0026lt;token\u0026gt;\u003c/strong\u003e",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Admin"
+                ],
+                "summary": "Amend an order",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Order ID (UUID)",
+                        "name": "orderId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the order",
+                        "name": "If-Match",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "JSON Merge Patch",
+                        "name": "patch",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Amended order",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Pur1st2EpicONE_WBTECH-sample-microservice_internal_models.Order"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "New version of the order"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Unreadable body",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid admin token",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Admin endpoints are disabled",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Order not found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ErrorResponse"
+                        }
+                    },
+                    "412": {
+                        "description": "Order was changed in the meantime",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ErrorResponse"
+                        }
+                    },
+                    "413": {
+                        "description": "Patch too large",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ErrorResponse"
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported content type",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Invalid patch",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ErrorResponse"
+                        }
+                    },
+                    "428": {
+                        "description": "Missing If-Match header",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Database unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ErrorResponse"
+                        }
+                    },
+                    "504": {
+                        "description": "Database timeout",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/orders/{orderId}/history": {
+            "get": {
+                "description": "Returns every recorded item status change of the order, oldest first.\u003cbr\u003eEach event carries the status before and after the change, when it happened and when the service recorded it.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "Orders"
                 ],
-                "summary": "Get order by UID with cache status indication",
+                "summary": "Get item status history of an order",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Order ID (UUID)",
+                        "name": "orderId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Status timeline",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Pur1st2EpicONE_WBTECH-sample-microservice_internal_models.OrderHistory"
+                        }
+                    },
+                    "404": {
+                        "description": "Order not found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Database unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ErrorResponse"
+                        }
+                    },
+                    "504": {
+                        "description": "Database timeout",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/orders/{orderId}/provenance": {
+            "get": {
+                "description": "Returns the topic, partition, offset, key and timestamp of the message the order was received in, along with its raw payload.\u003cbr\u003eRequires the admin token: \u003cstrong\u003eAuthorization: Bearer \u0026lt;token\u0026gt;\u003c/strong\u003e",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Admin"
+                ],
+                "summary": "Get the Kafka message an order was received in",
                 "parameters": [
                     {
                         "type": "string",
@@ -34,17 +550,76 @@ const docTemplate = `{
                         "required": true
                     }
                 ],
+                "responses": {
+                    "200": {
+                        "description": "Order provenance",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_Pur1st2EpicONE_WBTECH-sample-microservice_internal_models.OrderProvenance"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid admin token",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Admin endpoints are disabled",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "No ingest record for the order",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Database unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ErrorResponse"
+                        }
+                    },
+                    "504": {
+                        "description": "Database timeout",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/tracking/{trackNumber}": {
+            "get": {
+                "description": "Returns order details in JSON format, looked up by its track number.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Orders"
+                ],
+                "summary": "Get order by track number",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Track number",
+                        "name": "trackNumber",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
                 "responses": {
                     "200": {
                         "description": "Order data",
                         "schema": {
                             "$ref": "#/definitions/github_com_Pur1st2EpicONE_WBTECH-sample-microservice_internal_models.Order"
-                        },
-                        "headers": {
-                            "X-Cache": {
-                                "type": "string",
-                                "description": "Cache status: HIT or MISS"
-                            }
                         }
                     },
                     "404": {
@@ -58,12 +633,63 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/internal_handler.ErrorResponse"
                         }
+                    },
+                    "503": {
+                        "description": "Database unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ErrorResponse"
+                        }
+                    },
+                    "504": {
+                        "description": "Database timeout",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ErrorResponse"
+                        }
                     }
                 }
             }
         }
     },
     "definitions": {
+        "github_com_Pur1st2EpicONE_WBTECH-sample-microservice_internal_models.CustomerOrders": {
+            "type": "object",
+            "properties": {
+                "customer_id": {
+                    "type": "string"
+                },
+                "next_cursor": {
+                    "type": "string"
+                },
+                "orders": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_Pur1st2EpicONE_WBTECH-sample-microservice_internal_models.Order"
+                    }
+                },
+                "stats": {
+                    "$ref": "#/definitions/github_com_Pur1st2EpicONE_WBTECH-sample-microservice_internal_models.CustomerStats"
+                }
+            }
+        },
+        "github_com_Pur1st2EpicONE_WBTECH-sample-microservice_internal_models.CustomerStats": {
+            "type": "object",
+            "properties": {
+                "last_order_date": {
+                    "type": "string"
+                },
+                "order_count": {
+                    "type": "integer"
+                },
+                "total_spend": {
+                    "description": "sum of payment amounts keyed by currency",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "number",
+                        "format": "float64"
+                    }
+                }
+            }
+        },
         "github_com_Pur1st2EpicONE_WBTECH-sample-microservice_internal_models.Delivery": {
             "type": "object",
             "required": [
@@ -77,22 +703,31 @@ const docTemplate = `{
             ],
             "properties": {
                 "address": {
-                    "type": "string"
+                    "type": "string",
+                    "maxLength": 255,
+                    "minLength": 5
                 },
                 "city": {
-                    "type": "string"
+                    "type": "string",
+                    "maxLength": 100,
+                    "minLength": 2
                 },
                 "email": {
-                    "type": "string"
+                    "type": "string",
+                    "maxLength": 100
                 },
                 "name": {
-                    "type": "string"
+                    "type": "string",
+                    "maxLength": 255,
+                    "minLength": 2
                 },
                 "phone": {
                     "type": "string"
                 },
                 "region": {
-                    "type": "string"
+                    "type": "string",
+                    "maxLength": 255,
+                    "minLength": 2
                 },
                 "zip": {
                     "type": "string"
@@ -114,13 +749,17 @@ const docTemplate = `{
             ],
             "properties": {
                 "brand": {
-                    "type": "string"
+                    "type": "string",
+                    "maxLength": 100,
+                    "minLength": 2
                 },
                 "chrt_id": {
                     "type": "integer"
                 },
                 "name": {
-                    "type": "string"
+                    "type": "string",
+                    "maxLength": 100,
+                    "minLength": 2
                 },
                 "nm_id": {
                     "type": "integer"
@@ -129,22 +768,39 @@ const docTemplate = `{
                     "type": "number"
                 },
                 "rid": {
-                    "type": "string"
+                    "type": "string",
+                    "maxLength": 255,
+                    "minLength": 5
                 },
                 "sale": {
-                    "type": "integer"
+                    "description": "does Wildberries ever offer a 100% discount, I wonder?",
+                    "type": "integer",
+                    "maximum": 100,
+                    "minimum": 0
                 },
                 "size": {
-                    "type": "string"
+                    "type": "string",
+                    "maxLength": 10
                 },
                 "status": {
-                    "type": "integer"
+                    "type": "integer",
+                    "enum": [
+                        100,
+                        200,
+                        202,
+                        300,
+                        400
+                    ]
                 },
                 "total_price": {
-                    "type": "number"
+                    "description": "let's assume",
+                    "type": "number",
+                    "minimum": 0
                 },
                 "track_number": {
-                    "type": "string"
+                    "type": "string",
+                    "maxLength": 255,
+                    "minLength": 10
                 }
             }
         },
@@ -167,7 +823,9 @@ const docTemplate = `{
             ],
             "properties": {
                 "customer_id": {
-                    "type": "string"
+                    "type": "string",
+                    "maxLength": 255,
+                    "minLength": 1
                 },
                 "date_created": {
                     "type": "string"
@@ -176,16 +834,20 @@ const docTemplate = `{
                     "$ref": "#/definitions/github_com_Pur1st2EpicONE_WBTECH-sample-microservice_internal_models.Delivery"
                 },
                 "delivery_service": {
-                    "type": "string"
+                    "type": "string",
+                    "maxLength": 255,
+                    "minLength": 2
                 },
                 "entry": {
                     "type": "string"
                 },
                 "internal_signature": {
-                    "type": "string"
+                    "type": "string",
+                    "maxLength": 255
                 },
                 "items": {
                     "type": "array",
+                    "minItems": 1,
                     "items": {
                         "$ref": "#/definitions/github_com_Pur1st2EpicONE_WBTECH-sample-microservice_internal_models.Item"
                     }
@@ -194,22 +856,100 @@ const docTemplate = `{
                     "type": "string"
                 },
                 "oof_shard": {
-                    "type": "string"
+                    "type": "string",
+                    "maxLength": 10,
+                    "minLength": 1
                 },
                 "order_uid": {
-                    "type": "string"
+                    "type": "string",
+                    "maxLength": 255,
+                    "minLength": 1
                 },
                 "payment": {
                     "$ref": "#/definitions/github_com_Pur1st2EpicONE_WBTECH-sample-microservice_internal_models.Payment"
                 },
                 "shardkey": {
-                    "type": "string"
+                    "type": "string",
+                    "maxLength": 10,
+                    "minLength": 1
                 },
                 "sm_id": {
                     "type": "integer"
                 },
                 "track_number": {
+                    "type": "string",
+                    "maxLength": 255,
+                    "minLength": 10
+                },
+                "version": {
+                    "description": "assigned by storage and bumped by every amendment, ignored when an order is saved",
+                    "type": "integer"
+                }
+            }
+        },
+        "github_com_Pur1st2EpicONE_WBTECH-sample-microservice_internal_models.OrderHistory": {
+            "type": "object",
+            "properties": {
+                "events": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_Pur1st2EpicONE_WBTECH-sample-microservice_internal_models.StatusEvent"
+                    }
+                },
+                "order_uid": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_Pur1st2EpicONE_WBTECH-sample-microservice_internal_models.OrderPage": {
+            "type": "object",
+            "properties": {
+                "next_cursor": {
+                    "type": "string"
+                },
+                "orders": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_Pur1st2EpicONE_WBTECH-sample-microservice_internal_models.Order"
+                    }
+                }
+            }
+        },
+        "github_com_Pur1st2EpicONE_WBTECH-sample-microservice_internal_models.OrderProvenance": {
+            "type": "object",
+            "properties": {
+                "ingested_at": {
+                    "description": "when the service received the message",
+                    "type": "string"
+                },
+                "key": {
+                    "type": "string"
+                },
+                "offset": {
+                    "type": "integer"
+                },
+                "order_uid": {
+                    "type": "string"
+                },
+                "partition": {
+                    "type": "integer"
+                },
+                "payload": {
+                    "type": "object"
+                },
+                "timestamp": {
+                    "description": "timestamp of the message set by the producer or the broker",
                     "type": "string"
+                },
+                "topic": {
+                    "type": "string"
+                },
+                "violations": {
+                    "description": "business rules the order was flagged by when it was received",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_Pur1st2EpicONE_WBTECH-sample-microservice_internal_models.RuleViolation"
+                    }
                 }
             }
         },
@@ -229,16 +969,19 @@ const docTemplate = `{
                     "type": "number"
                 },
                 "bank": {
-                    "type": "string"
+                    "type": "string",
+                    "maxLength": 50
                 },
                 "currency": {
                     "type": "string"
                 },
                 "custom_fee": {
-                    "type": "number"
+                    "type": "number",
+                    "minimum": 0
                 },
                 "delivery_cost": {
-                    "type": "number"
+                    "type": "number",
+                    "minimum": 0
                 },
                 "goods_total": {
                     "type": "number"
@@ -247,13 +990,82 @@ const docTemplate = `{
                     "type": "integer"
                 },
                 "provider": {
-                    "type": "string"
+                    "type": "string",
+                    "maxLength": 50,
+                    "minLength": 2
                 },
                 "request_id": {
-                    "type": "string"
+                    "type": "string",
+                    "maxLength": 255
                 },
                 "transaction": {
+                    "type": "string",
+                    "maxLength": 255,
+                    "minLength": 1
+                }
+            }
+        },
+        "github_com_Pur1st2EpicONE_WBTECH-sample-microservice_internal_models.PersonalDataErasure": {
+            "type": "object",
+            "properties": {
+                "customer_id": {
+                    "type": "string"
+                },
+                "erased_at": {
+                    "type": "string"
+                },
+                "order_uids": {
+                    "description": "orders whose personal data was replaced, filled in by storage",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "reference": {
+                    "description": "reference of the request, e.g. a ticket number; may be empty",
+                    "type": "string"
+                },
+                "source": {
+                    "description": "ErasureSourceAPI or ErasureSourceCLI",
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_Pur1st2EpicONE_WBTECH-sample-microservice_internal_models.RuleViolation": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string"
+                },
+                "rule": {
+                    "type": "string"
+                },
+                "severity": {
+                    "description": "SeverityReject or SeverityFlag",
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_Pur1st2EpicONE_WBTECH-sample-microservice_internal_models.StatusEvent": {
+            "type": "object",
+            "properties": {
+                "changed_at": {
+                    "type": "string"
+                },
+                "chrt_id": {
+                    "type": "integer"
+                },
+                "previous_status": {
+                    "type": "integer"
+                },
+                "recorded_at": {
+                    "type": "string"
+                },
+                "rid": {
                     "type": "string"
+                },
+                "status": {
+                    "type": "integer"
                 }
             }
         },
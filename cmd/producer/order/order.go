@@ -9,6 +9,7 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"fmt"
+	"math"
 	"math/big"
 	"time"
 
@@ -33,6 +34,7 @@ func CreateOrder(logger logger.Logger) models.Order {
 	order.Delivery = createDelivery(logger)
 	order.Payment = createPayment(order, logger)
 	order.Items = createItems(order, logger)
+	order.Payment.GoodsTotal, order.Payment.Amount = totals(order)
 	order.Locale = newLocale(logger)
 	order.InternalSignature = ""
 	order.CustomerID = newCustomerID()
@@ -66,11 +68,9 @@ func createPayment(order models.Order, logger logger.Logger) models.Payment {
 	payment.RequestID = ""
 	payment.Currency = newCurrency(logger)
 	payment.Provider = "wbpay"
-	payment.Amount = newAmount(logger)
 	payment.PaymentDT = 1637907727 // can't be bothered
 	payment.Bank = newBank(logger)
 	payment.DeliveryCost = newDeliveryCost(logger)
-	payment.GoodsTotal = newGoodsTotal(logger)
 	payment.CustomFee = newCustomFee(logger)
 
 	return payment
@@ -318,15 +318,6 @@ func newCurrency(logger logger.Logger) string {
 	return currencies[idx.Int64()]
 }
 
-func newAmount(logger logger.Logger) float64 {
-	max := big.NewInt(100000)
-	amount, err := rand.Int(rand.Reader, max)
-	if err != nil {
-		logger.LogFatal("newAmount — failed to create random number", err)
-	}
-	return float64(amount.Int64()) / 100.0
-}
-
 func newBank(logger logger.Logger) string {
 	banks := []string{"alpha", "sber", "vtb", "gazprombank", "bank of america", "deutsche bank", "chase", "santander"}
 	number := big.NewInt(int64(len(banks)))
@@ -346,15 +337,6 @@ func newDeliveryCost(logger logger.Logger) float64 {
 	return (float64(cost.Int64()) / 100.0) + 1
 }
 
-func newGoodsTotal(logger logger.Logger) float64 {
-	max := big.NewInt(200000)
-	total, err := rand.Int(rand.Reader, max)
-	if err != nil {
-		logger.LogFatal("newGoodsTotal — failed to create random number", err)
-	}
-	return float64(total.Int64()) / 100.0
-}
-
 func newCustomFee(logger logger.Logger) float64 {
 	max := big.NewInt(10000)
 	fee, err := rand.Int(rand.Reader, max)
@@ -470,7 +452,18 @@ func newSize(logger logger.Logger) string {
 }
 
 func newTotalPrice(price float64, sale int) float64 {
-	return (price * (1 - float64(sale)/100)) + 1
+	return math.Round(price*float64(100-sale)) / 100
+}
+
+// totals returns the goods total and the payment amount of the order, so that they add up
+// the way the business rules of the service expect.
+func totals(order models.Order) (goodsTotal, amount float64) {
+	for _, item := range order.Items {
+		goodsTotal += item.TotalPrice
+	}
+	goodsTotal = math.Round(goodsTotal*100) / 100
+	amount = math.Round((goodsTotal+order.Payment.DeliveryCost+order.Payment.CustomFee)*100) / 100
+	return goodsTotal, amount
 }
 
 func newNmId(logger logger.Logger) int {
@@ -0,0 +1,412 @@
+package main
+
+import (
+	"bufio"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"os/signal"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/broker"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/broker/kafka"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/configs"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/pkg/logger"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/pkg/mergepatch"
+)
+
+const dlqUsage = `usage: wb-service dlq list [filters]
+       wb-service dlq inspect PARTITION:OFFSET...
+       wb-service dlq export [-o FILE] [filters]
+       wb-service dlq replay [-patch FILE] [-topic TOPIC] [-dry-run] [filters]
+
+Triages the messages of the DLQ (kafka.dlq.topic).
+list prints them one per line, inspect prints the given ones in full with their failure headers,
+export writes them as NDJSON (to stdout unless -o is given), replay republishes them to the topic
+they were consumed from (or -topic), optionally after applying a JSON merge patch (RFC 7386) to every one.
+
+The DLQ is read with its own consumer group (kafka.dlq.group_id), which replay moves on past the messages
+it republishes, so an interrupted replay resumes where it stopped. It is never moved past a message the filters
+leave out: messages replayed after one in the same partition are replayed again by the next replay.
+list, export and replay only read the messages the group hasn't gone over yet, unless -all is given.
+
+filters:
+  -class CLASS[,CLASS...]  error class: decode, validation, business_rule, conflict, transient
+  -since TIME, -until TIME last failure at or after / before TIME (RFC 3339, or a duration such as 24h for that long ago)
+  -key KEY                 message key (order UID)
+  -all                     read the whole DLQ, including the messages the group has gone over`
+
+// dlqFilter selects DLQ messages.
+type dlqFilter struct {
+	classes      []string
+	since, until time.Time
+	key          string
+}
+
+// matches reports whether message passes the filter.
+func (f dlqFilter) matches(message configs.Message) bool {
+	if len(f.classes) > 0 && !slices.Contains(f.classes, message.Headers[kafka.HeaderErrorClass]) {
+		return false
+	}
+	if f.key != "" && kafka.ToStr(message.Key) != f.key {
+		return false
+	}
+	failedAt := lastFailure(message)
+	if !f.since.IsZero() && failedAt.Before(f.since) {
+		return false
+	}
+	if !f.until.IsZero() && !failedAt.Before(f.until) {
+		return false
+	}
+	return true
+}
+
+// lastFailure returns when message failed for the last time, or its timestamp if it carries no failure headers.
+func lastFailure(message configs.Message) time.Time {
+	if failedAt, err := time.Parse(time.RFC3339Nano, message.Headers[kafka.HeaderLastFailureAt]); err == nil {
+		return failedAt
+	}
+	return message.Timestamp
+}
+
+// dlqRecord is a DLQ message as exported to NDJSON.
+// The payload is in value if it is valid JSON and in raw otherwise.
+type dlqRecord struct {
+	Topic     string            `json:"topic"`
+	Partition int32             `json:"partition"`
+	Offset    int64             `json:"offset"`
+	Key       string            `json:"key"`
+	Timestamp time.Time         `json:"timestamp"`
+	Headers   map[string]string `json:"headers"`
+	Value     json.RawMessage   `json:"value,omitempty"`
+	Raw       string            `json:"raw,omitempty"`
+}
+
+func newDLQRecord(message configs.Message) dlqRecord {
+	record := dlqRecord{Topic: message.Topic, Partition: message.Partition, Offset: message.Offset,
+		Key: string(message.Key), Timestamp: message.Timestamp, Headers: message.Headers}
+	if json.Valid(message.Value) {
+		record.Value = message.Value
+	} else {
+		record.Raw = string(message.Value)
+	}
+	return record
+}
+
+/*
+runDLQ implements the dlq subcommand.
+
+It reads the DLQ configured in the same config.yaml as the service with a broker.Reader
+and, for replay, republishes messages with a broker.Producer. Exits with code 2 on invalid arguments
+and with code 1 if reading or republishing fails; a failed replay can simply be run again.
+*/
+func runDLQ(args []string) {
+	loggerConfig := configs.Logger{LogDir: "", Debug: false}
+	logger, _ := logger.NewLogger(loggerConfig)
+
+	usage := func() { fmt.Fprintln(os.Stderr, dlqUsage) }
+	if len(args) == 0 || !slices.Contains([]string{"list", "inspect", "export", "replay"}, args[0]) {
+		usage()
+		os.Exit(2)
+	}
+	command := args[0]
+	flags := flag.NewFlagSet("dlq "+command, flag.ContinueOnError)
+	flags.Usage = usage
+	classes := flags.String("class", "", "error classes, comma separated")
+	since := flags.String("since", "", "last failure at or after")
+	until := flags.String("until", "", "last failure before")
+	key := flags.String("key", "", "message key")
+	all := flags.Bool("all", false, "read the whole DLQ")
+	output := flags.String("o", "", "file to export to")
+	patchFile := flags.String("patch", "", "JSON merge patch to apply before republishing")
+	topic := flags.String("topic", "", "topic to republish to")
+	dryRun := flags.Bool("dry-run", false, "print what would be republished")
+	if err := flags.Parse(args[1:]); err != nil {
+		usage()
+		os.Exit(2)
+	}
+	filter, err := parseDLQFilter(*classes, *since, *until, *key)
+	positions, posErr := parsePositions(flags.Args())
+	switch {
+	case err != nil, posErr != nil:
+		fmt.Fprintln(os.Stderr, errors.Join(err, posErr))
+		usage()
+		os.Exit(2)
+	case (command == "inspect") != (len(positions) > 0),
+		command != "export" && *output != "",
+		command != "replay" && (*patchFile != "" || *topic != "" || *dryRun):
+		usage()
+		os.Exit(2)
+	}
+	var patch []byte
+	if *patchFile != "" {
+		if patch, err = os.ReadFile(*patchFile); err != nil {
+			logger.LogFatal("dlq — failed to read the patch", err)
+		}
+		if !json.Valid(patch) {
+			logger.LogFatal("dlq — the patch is not valid JSON", errors.New("invalid patch"), "file", *patchFile)
+		}
+	}
+
+	config, err := configs.Load()
+	if err != nil {
+		logger.LogFatal("dlq — failed to load configs", err)
+	}
+	reader, err := broker.NewReader(config.Consumer.DLQ)
+	if err != nil {
+		logger.LogFatal("dlq — failed to connect to Kafka", err)
+	}
+	defer reader.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	switch command {
+	case "list":
+		err = listDLQ(ctx, reader, filter, *all)
+	case "inspect":
+		err = inspectDLQ(ctx, reader, positions)
+	case "export":
+		err = exportDLQ(ctx, reader, filter, *all, *output)
+	case "replay":
+		replay := dlqReplay{reader: reader, filter: filter, patch: patch, topic: *topic, dryRun: *dryRun,
+			defaultTopic: config.Consumer.Topic, dlqTopic: config.Consumer.DLQ.Topic}
+		if !*dryRun {
+			producer, err := broker.NewProducer(config.Consumer.DLQ, logger)
+			if err != nil {
+				logger.LogError("dlq — failed to create producer", err)
+				stop()
+				reader.Close()
+				os.Exit(1)
+			}
+			defer producer.Close()
+			replay.producer = producer
+		}
+		err = replay.run(ctx, *all)
+	}
+	if err != nil {
+		logger.LogError(fmt.Sprintf("dlq — %s failed", command), err)
+		stop()
+		reader.Close()
+		os.Exit(1)
+	}
+}
+
+// parseDLQFilter builds a filter from the values of the filter flags.
+func parseDLQFilter(classes, since, until, key string) (dlqFilter, error) {
+	filter := dlqFilter{key: key}
+	known := []kafka.ErrorClass{kafka.ClassDecode, kafka.ClassValidation, kafka.ClassBusinessRule, kafka.ClassConflict, kafka.ClassTransient}
+	for class := range strings.SplitSeq(classes, ",") {
+		class = strings.TrimSpace(class)
+		if class == "" {
+			continue
+		}
+		if !slices.Contains(known, kafka.ErrorClass(class)) {
+			return dlqFilter{}, fmt.Errorf("unknown error class %q", class)
+		}
+		filter.classes = append(filter.classes, class)
+	}
+	var err error
+	if filter.since, err = parseTime(since); err != nil {
+		return dlqFilter{}, fmt.Errorf("invalid -since: %w", err)
+	}
+	if filter.until, err = parseTime(until); err != nil {
+		return dlqFilter{}, fmt.Errorf("invalid -until: %w", err)
+	}
+	return filter, nil
+}
+
+// parseTime parses an RFC 3339 time, or a duration meaning that long ago. Empty is the zero time.
+func parseTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if ago, err := time.ParseDuration(value); err == nil {
+		return time.Now().Add(-ago), nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// dlqPosition is the partition and offset of a DLQ message.
+type dlqPosition struct {
+	partition int32
+	offset    int64
+}
+
+// parsePositions parses PARTITION:OFFSET arguments.
+func parsePositions(args []string) ([]dlqPosition, error) {
+	var positions []dlqPosition
+	for _, arg := range args {
+		partition, offset, ok := strings.Cut(arg, ":")
+		p, err := strconv.ParseInt(partition, 10, 32)
+		o, offsetErr := strconv.ParseInt(offset, 10, 64)
+		if !ok || err != nil || offsetErr != nil || p < 0 || o < 0 {
+			return nil, fmt.Errorf("invalid position %q, expected PARTITION:OFFSET", arg)
+		}
+		positions = append(positions, dlqPosition{partition: int32(p), offset: o})
+	}
+	return positions, nil
+}
+
+// listDLQ prints the messages that pass filter, one per line.
+func listDLQ(ctx context.Context, reader broker.Reader, filter dlqFilter, all bool) error {
+	table := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(table, "POSITION\tLAST FAILURE\tCLASS\tRETRIES\tKEY\tERROR")
+	listed := 0
+	err := reader.Read(ctx, all, func(message configs.Message) error {
+		if !filter.matches(message) {
+			return nil
+		}
+		listed++
+		_, err := fmt.Fprintf(table, "%d:%d\t%s\t%s\t%s\t%s\t%s\n", message.Partition, message.Offset,
+			lastFailure(message).UTC().Format(time.RFC3339), message.Headers[kafka.HeaderErrorClass],
+			message.Headers[kafka.HeaderRetryCount], kafka.ToStr(message.Key), message.Headers[kafka.HeaderErrorMessage])
+		return err
+	})
+	if flushErr := table.Flush(); err == nil {
+		err = flushErr
+	}
+	fmt.Printf("messages: %d\n", listed)
+	return err
+}
+
+// inspectDLQ prints the messages at the given positions in full, headers and payload.
+func inspectDLQ(ctx context.Context, reader broker.Reader, positions []dlqPosition) error {
+	found := 0
+	err := reader.Read(ctx, true, func(message configs.Message) error {
+		if !slices.Contains(positions, dlqPosition{partition: message.Partition, offset: message.Offset}) {
+			return nil
+		}
+		found++
+		fmt.Printf("position: %d:%d\nkey: %s\ntimestamp: %s\n", message.Partition, message.Offset,
+			kafka.ToStr(message.Key), message.Timestamp.UTC().Format(time.RFC3339Nano))
+		for _, name := range slices.Sorted(maps.Keys(message.Headers)) {
+			fmt.Printf("%s: %s\n", name, message.Headers[name])
+		}
+		fmt.Printf("\n%s\n\n", message.Value)
+		return nil
+	})
+	if err == nil && found < len(positions) {
+		err = fmt.Errorf("found %d of %d messages", found, len(positions))
+	}
+	return err
+}
+
+// exportDLQ writes the messages that pass filter as NDJSON to path, or to stdout if path is empty.
+func exportDLQ(ctx context.Context, reader broker.Reader, filter dlqFilter, all bool, path string) (err error) {
+	var output io.Writer = os.Stdout
+	if path != "" {
+		file, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if closeErr := file.Close(); err == nil {
+				err = closeErr
+			}
+		}()
+		output = file
+	}
+	buffered := bufio.NewWriter(output)
+	encoder := json.NewEncoder(buffered)
+	exported := 0
+	err = reader.Read(ctx, all, func(message configs.Message) error {
+		if !filter.matches(message) {
+			return nil
+		}
+		exported++
+		return encoder.Encode(newDLQRecord(message))
+	})
+	if flushErr := buffered.Flush(); err == nil {
+		err = flushErr
+	}
+	fmt.Fprintf(os.Stderr, "messages exported: %d\n", exported)
+	return err
+}
+
+// dlqReplay republishes DLQ messages.
+type dlqReplay struct {
+	reader       broker.Reader
+	producer     broker.Producer // nil on a dry run
+	filter       dlqFilter
+	patch        []byte // JSON merge patch applied to every message, if any
+	topic        string // topic to republish to, if not the one the message was consumed from
+	defaultTopic string // topic to republish messages to that don't say where they were consumed from
+	dlqTopic     string
+	dryRun       bool
+}
+
+/*
+run republishes the messages that pass the filter and moves the consumer group past them.
+
+The group is never moved past a message the filter leaves out, so a later replay with other filters
+still finds it: once a partition has such a message, the messages of the partition replayed after it
+are not committed and are replayed again by the next run. Orders are saved idempotently,
+so they are stored only once.
+
+A message is republished with its key and (patched) value and the x-replayed-from header only,
+so failing again gives it fresh failure headers. It stops at the first message that can't be patched
+or republished, without moving the group past it.
+*/
+func (r dlqReplay) run(ctx context.Context, all bool) error {
+	replayed, skipped := 0, 0
+	leftOut := make(map[int32]bool) // partitions with a message the filter left out
+	err := r.reader.Read(ctx, all, func(message configs.Message) error {
+		if !r.filter.matches(message) {
+			skipped++
+			leftOut[message.Partition] = true
+			return nil
+		}
+		value := message.Value
+		if r.patch != nil {
+			patched, err := mergepatch.Apply(value, r.patch)
+			if err != nil {
+				return fmt.Errorf("failed to patch message %d:%d: %w", message.Partition, message.Offset, err)
+			}
+			value = patched
+		}
+		topic := r.topic
+		if topic == "" {
+			topic = cmp.Or(message.Headers[kafka.HeaderOriginalTopic], r.defaultTopic)
+		}
+		if r.dryRun {
+			replayed++
+			fmt.Printf("%d:%d -> %s key=%s\n%s\n", message.Partition, message.Offset, topic, kafka.ToStr(message.Key), value)
+			return nil
+		}
+		republished := configs.Message{
+			Topic:   topic,
+			Key:     message.Key,
+			Value:   value,
+			Headers: map[string]string{kafka.HeaderReplayedFrom: fmt.Sprintf("%s/%d/%d", r.dlqTopic, message.Partition, message.Offset)},
+		}
+		if err := r.producer.Produce(republished); err != nil {
+			return fmt.Errorf("failed to republish message %d:%d: %w", message.Partition, message.Offset, err)
+		}
+		replayed++
+		if leftOut[message.Partition] {
+			return nil
+		}
+		return r.commit(message)
+	})
+	fmt.Printf("replayed: %d, skipped: %d\n", replayed, skipped)
+	return err
+}
+
+// commit moves the consumer group past message, unless this is a dry run.
+func (r dlqReplay) commit(message configs.Message) error {
+	if r.dryRun {
+		return nil
+	}
+	return r.reader.Commit(message)
+}
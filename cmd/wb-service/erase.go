@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/cache"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/configs"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/models"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/service"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/pkg/logger"
+)
+
+const eraseUsage = `usage: wb-service erase [-reference REF] [-file PATH] [customer_id]...
+
+Replaces the personal data in every order of the given customers with irreversible placeholders
+and records the erasure, like DELETE /api/v1/customers/<customer_id>/personal-data.
+-file reads customer IDs one per line ("-" for stdin); empty lines and lines starting with # are skipped.
+A running service keeps cached copies of the erased orders until they expire; use the API to purge them at once.
+Archived orders are erased when they are restored. Messages still in the orders, retry and DLQ topics are not erased.`
+
+/*
+runErase implements the erase subcommand.
+
+It connects to the database (and the shards, if configured) from the same config.yaml and .env as the service
+and erases the personal data of every customer given, see service.Service.ErasePersonalData.
+A failed customer doesn't stop the rest; the command exits with code 1 if any failed, and with code 2 on invalid arguments.
+Erasing a customer again is harmless, so a batch can simply be run again.
+*/
+func runErase(args []string) {
+	loggerConfig := configs.Logger{LogDir: "", Debug: false}
+	logger, _ := logger.NewLogger(loggerConfig)
+
+	flags := flag.NewFlagSet("erase", flag.ContinueOnError)
+	flags.Usage = func() { fmt.Fprintln(os.Stderr, eraseUsage) }
+	reference := flags.String("reference", "", "reference of the request, e.g. a ticket number")
+	file := flags.String("file", "", "file with customer IDs, one per line")
+	if err := flags.Parse(args); err != nil {
+		flags.Usage()
+		os.Exit(2)
+	}
+	customerIDs := flags.Args()
+	if *file != "" {
+		fromFile, err := readCustomerIDs(*file)
+		if err != nil {
+			logger.LogFatal("erase — failed to read customer IDs", err)
+		}
+		customerIDs = append(customerIDs, fromFile...)
+	}
+	if len(customerIDs) == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	config, err := configs.Load()
+	if err != nil {
+		logger.LogFatal("erase — failed to load configs", err)
+	}
+	db, err := repository.ConnectDB(config.Database)
+	if err != nil {
+		logger.LogFatal("erase — failed to connect to database", err)
+	}
+	shards, err := repository.ConnectShards(config.Database)
+	if err != nil {
+		logger.LogFatal("erase — failed to connect to shards", err)
+	}
+	var storage repository.Storage
+	if len(shards) > 0 {
+		if storage, err = repository.NewShardedStorage(db, shards, config.Database, logger); err != nil {
+			logger.LogFatal("erase — invalid shard configuration", err)
+		}
+	} else {
+		storage = repository.NewStorage(db, config.Database, logger)
+	}
+	defer storage.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	if err := repository.CheckSchema(ctx, db, config.Database); err != nil {
+		logger.LogError("erase — database schema version check failed, see \"wb-service migrate status\"", err)
+		stop()
+		storage.Close()
+		os.Exit(1)
+	}
+
+	// the orders are not cached in this process, the cache is only there to satisfy the service
+	service := service.NewService(storage, cache.NewCache(ctx, storage, configs.Cache{}, logger))
+	failed := 0
+	for _, customerID := range customerIDs {
+		erasure, err := service.ErasePersonalData(ctx, customerID, models.ErasureSourceCLI, *reference, logger)
+		if err != nil {
+			logger.LogError("erase — failed to erase personal data", err, "customerID", customerID)
+			failed++
+			continue
+		}
+		fmt.Printf("%s: %d orders erased\n", customerID, len(erasure.OrderUIDs))
+	}
+	fmt.Printf("customers: %d, erased: %d, failed: %d\n", len(customerIDs), len(customerIDs)-failed, failed)
+	if failed > 0 {
+		stop()
+		storage.Close()
+		os.Exit(1)
+	}
+}
+
+// readCustomerIDs reads customer IDs from a file, one per line, or from stdin if path is "-".
+func readCustomerIDs(path string) ([]string, error) {
+	var input io.Reader = os.Stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer func() { _ = file.Close() }()
+		input = file
+	}
+	var customerIDs []string
+	scanner := bufio.NewScanner(input)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		customerIDs = append(customerIDs, line)
+	}
+	return customerIDs, scanner.Err()
+}
@@ -1,19 +1,64 @@
 // Package main initializes and runs the service.
+//
+// Running it as "wb-service migrate <command>" manages the database schema instead, see runMigrate,
+// "wb-service rebalance" moves orders between shards, see runRebalance,
+// "wb-service restore" loads archived orders back into the database, see runRestore,
+// "wb-service erase" erases the personal data of customers, see runErase,
+// "wb-service rotate-keys" re-encrypts personal data with the active key, see runRotateKeys,
+// and "wb-service dlq" lists, exports and replays the messages of the DLQ, see runDLQ.
 package main
 
 import (
+	"os"
+
 	_ "github.com/Pur1st2EpicONE/WBTECH-sample-microservice/api/openapi-spec/docs"
 	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/app"
 )
 
+// @title wb-service API
+// @version 1.0
+// @description RESTful API for querying order information by order ID
+// @host localhost:8081
+// @BasePath /
 func main() {
 
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		runMigrate(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "rebalance" {
+		runRebalance(os.Args[2:])
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "restore" {
+		runRestore(os.Args[2:])
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "erase" {
+		runErase(os.Args[2:])
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "rotate-keys" {
+		runRotateKeys(os.Args[2:])
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "dlq" {
+		runDLQ(os.Args[2:])
+		return
+	}
+
 	wbService := app.Start()
 	defer wbService.Stop()
 
 	go wbService.RunCacheCleaner()
 	go wbService.RunServer()
 	go wbService.RunConsumer()
+	go wbService.RunOutboxRelay()
+	go wbService.RunRetention()
 
 	wbService.Wait()
 
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/configs"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository/migrate"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/pkg/logger"
+	"github.com/jmoiron/sqlx"
+)
+
+const migrateUsage = `usage: wb-service migrate <command>
+
+commands:
+  up        apply all pending migrations
+  down      revert the most recently applied migration
+  status    print the current and expected schema versions
+  to N      migrate up or down to version N (0 reverts everything)`
+
+/*
+runMigrate implements the migrate subcommand.
+
+It reads the database settings from the same config.yaml and .env as the service
+and applies the migrations embedded into the binary, to every shard as well if sharding is configured.
+Exits with code 2 on invalid arguments.
+*/
+func runMigrate(args []string) {
+	loggerConfig := configs.Logger{LogDir: "", Debug: false}
+	logger, _ := logger.NewLogger(loggerConfig)
+
+	command, version, ok := parseMigrateArgs(args)
+	if !ok {
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		os.Exit(2)
+	}
+
+	config, err := configs.Load()
+	if err != nil {
+		logger.LogFatal("migrate — failed to load configs", err)
+	}
+	db, err := repository.ConnectDB(config.Database)
+	if err != nil {
+		logger.LogFatal("migrate — failed to connect to database", err)
+	}
+	defer func() { _ = db.Close() }()
+	shards, err := repository.ConnectShards(config.Database)
+	if err != nil {
+		logger.LogFatal("migrate — failed to connect to shards", err)
+	}
+
+	ctx := context.Background()
+	migrateDB(ctx, db, config.Database, command, version, logger)
+	for _, shard := range shards {
+		fmt.Printf("\nshard %s:\n", shard.Name)
+		migrateDB(ctx, shard.DB, config.Database, command, version, logger)
+		_ = shard.DB.Close()
+	}
+}
+
+// migrateDB runs a migrate command against a single database and prints its schema status.
+func migrateDB(ctx context.Context, db *sqlx.DB, config configs.Database, command string, version uint, logger logger.Logger) {
+	migrator, err := repository.NewMigrator(db, config)
+	if err != nil {
+		logger.LogFatal("migrate — failed to load migrations", err)
+	}
+
+	switch command {
+	case "up":
+		err = migrator.Up(ctx)
+	case "down":
+		err = migrator.Down(ctx)
+	case "to":
+		err = migrator.To(ctx, version)
+	}
+	if err != nil {
+		logger.LogFatal("migrate — "+command+" failed", err)
+	}
+
+	status, err := migrator.Status(ctx)
+	if err != nil {
+		logger.LogFatal("migrate — failed to read schema version", err)
+	}
+	printStatus(status)
+}
+
+// parseMigrateArgs validates the subcommand arguments and returns the command
+// along with the target version for "to".
+func parseMigrateArgs(args []string) (string, uint, bool) {
+	switch {
+	case len(args) == 1 && (args[0] == "up" || args[0] == "down" || args[0] == "status"):
+		return args[0], 0, true
+	case len(args) == 2 && args[0] == "to":
+		version, err := strconv.ParseUint(args[1], 10, 32)
+		return args[0], uint(version), err == nil
+	}
+	return "", 0, false
+}
+
+// printStatus writes the schema version summary and pending migrations to stdout.
+func printStatus(status migrate.Status) {
+	fmt.Printf("schema version: %d (binary expects %d)\n", status.Current, status.Latest)
+	if status.Dirty {
+		fmt.Println("schema is dirty: the last migration failed halfway and has to be repaired manually")
+	}
+	for _, migration := range status.Pending {
+		fmt.Printf("pending: %06d_%s\n", migration.Version, migration.Name)
+	}
+}
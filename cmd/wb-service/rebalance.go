@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/configs"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository/sharded"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/pkg/logger"
+)
+
+const rebalanceUsage = `usage: wb-service rebalance [-dry-run] [-batch-size N]
+
+Moves every order to the shard that owns its shard key under the ranges in config.yaml.
+Run it after changing the ranges; the service can keep running meanwhile.`
+
+/*
+runRebalance implements the rebalance subcommand.
+
+It connects to the main database and the shards configured in the same config.yaml and .env
+as the service, and moves misplaced orders to their owners (see sharded.Storage.Rebalance).
+An interrupted run (e.g. Ctrl+C) can simply be started again. Exits with code 2 on invalid arguments.
+*/
+func runRebalance(args []string) {
+	loggerConfig := configs.Logger{LogDir: "", Debug: false}
+	logger, _ := logger.NewLogger(loggerConfig)
+
+	flags := flag.NewFlagSet("rebalance", flag.ContinueOnError)
+	flags.Usage = func() { fmt.Fprintln(os.Stderr, rebalanceUsage) }
+	dryRun := flags.Bool("dry-run", false, "only count misplaced orders")
+	batchSize := flags.Int("batch-size", 500, "number of orders scanned at a time")
+	if err := flags.Parse(args); err != nil || flags.NArg() > 0 || *batchSize <= 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	config, err := configs.Load()
+	if err != nil {
+		logger.LogFatal("rebalance — failed to load configs", err)
+	}
+	db, err := repository.ConnectDB(config.Database)
+	if err != nil {
+		logger.LogFatal("rebalance — failed to connect to database", err)
+	}
+	shards, err := repository.ConnectShards(config.Database)
+	if err != nil {
+		logger.LogFatal("rebalance — failed to connect to shards", err)
+	}
+	storage, err := sharded.NewStorage(db, shards, config.Database, logger)
+	if err != nil {
+		logger.LogFatal("rebalance — invalid shard configuration", err)
+	}
+	defer storage.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	stats, err := storage.Rebalance(ctx, *batchSize, *dryRun)
+	fmt.Printf("scanned: %d, misplaced: %d, moved: %d, owned by no shard: %d\n", stats.Scanned, stats.Misplaced, stats.Moved, stats.Unowned)
+	if err != nil {
+		logger.LogError("rebalance — stopped before finishing, run it again to continue", err)
+		stop()
+		storage.Close()
+		os.Exit(1)
+	}
+}
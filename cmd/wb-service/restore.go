@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/configs"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository/errs"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository/postgres"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository/sqlite"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/retention"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/pkg/logger"
+)
+
+const restoreUsage = `usage: wb-service restore <archive.ndjson.gz>...
+
+Loads the orders of archives written by the retention job back into the database.
+Orders that are stored already are skipped, so an archive can be restored more than once.
+The personal data of orders whose customer was erased after the order was placed is erased before they are stored.
+Encrypted deliveries are re-encrypted with the active key; the keys they were archived with must still be configured.
+Restored orders older than app.retention.max_age are archived again on the next run of the job.`
+
+// restoreStats counts the outcomes of restoring archived orders.
+type restoreStats struct {
+	restored   int
+	skipped    int
+	conflicted int
+	erased     int // restored orders whose personal data was erased
+}
+
+/*
+runRestore implements the restore subcommand.
+
+It connects to the database configured in the same config.yaml and .env as the service and imports
+every order of the given archives with its item statuses and status history, creating the monthly
+partition of the order first. Only a single Postgres database is supported, as order partitions are.
+Archives are never rewritten, so the erasures recorded in the database are applied to the orders as they are restored.
+
+An order that conflicts with a stored one (same UID or track number, different contents) is reported
+and left out. An interrupted run can simply be started again. Exits with code 2 on invalid arguments.
+*/
+func runRestore(args []string) {
+	loggerConfig := configs.Logger{LogDir: "", Debug: false}
+	logger, _ := logger.NewLogger(loggerConfig)
+
+	flags := flag.NewFlagSet("restore", flag.ContinueOnError)
+	flags.Usage = func() { fmt.Fprintln(os.Stderr, restoreUsage) }
+	if err := flags.Parse(args); err != nil || flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	config, err := configs.Load()
+	if err != nil {
+		logger.LogFatal("restore — failed to load configs", err)
+	}
+	if config.Database.Driver == sqlite.DriverName || len(config.Database.Shards) > 0 {
+		logger.LogFatal("restore — order archives can only be restored into a single Postgres database", errors.New("unsupported storage"))
+	}
+	db, err := repository.ConnectDB(config.Database)
+	if err != nil {
+		logger.LogFatal("restore — failed to connect to database", err)
+	}
+	storage := postgres.NewStorage(db, config.Database, logger)
+	defer storage.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	if err := repository.CheckSchema(ctx, db, config.Database); err != nil {
+		logger.LogError("restore — database schema version check failed, see \"wb-service migrate status\"", err)
+		stop()
+		storage.Close()
+		os.Exit(1)
+	}
+
+	var stats restoreStats
+	partitions := make(map[time.Time]bool)
+	for _, path := range flags.Args() {
+		if err = restoreArchive(ctx, storage, path, partitions, &stats, logger); err != nil {
+			err = fmt.Errorf("%s: %w", path, err)
+			break
+		}
+	}
+	fmt.Printf("restored: %d (personal data erased: %d), already stored: %d, conflicting: %d\n",
+		stats.restored, stats.erased, stats.skipped, stats.conflicted)
+	if err != nil {
+		logger.LogError("restore — stopped before finishing, run it again to continue", err)
+		stop()
+		storage.Close()
+		os.Exit(1)
+	}
+}
+
+// restoreArchive imports the orders of the archive at path, making sure the partitions of their months exist
+// and erasing the personal data of orders whose customer was erased after they were placed.
+// partitions holds the months whose partitions were ensured already.
+func restoreArchive(ctx context.Context, storage *postgres.Storage, path string, partitions map[time.Time]bool,
+	stats *restoreStats, logger logger.Logger) error {
+	reader, err := retention.OpenArchive(path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = reader.Close() }()
+
+	for {
+		order, err := reader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		created := order.Order.DateCreated.UTC()
+		month := time.Date(created.Year(), created.Month(), 1, 0, 0, 0, 0, time.UTC)
+		if !partitions[month] {
+			// a month that has orders in the default partition can't get a partition of its own,
+			// its orders are restored into the default partition as well
+			if err := storage.EnsurePartitions(ctx, month, month); err != nil {
+				logger.LogInfo("restore — restoring orders of "+month.Format("2006-01")+" into the default partition", "reason", err.Error())
+			}
+			partitions[month] = true
+		}
+		erased, err := storage.CustomerErasedSince(ctx, order.Order.CustomerID, order.Order.DateCreated)
+		if err != nil {
+			return fmt.Errorf("failed to restore order %s: %w", order.Order.OrderUID, err)
+		}
+		if erased {
+			if err := order.ErasePersonalData(); err != nil {
+				return fmt.Errorf("failed to restore order %s: %w", order.Order.OrderUID, err)
+			}
+		}
+		switch err := storage.ImportOrder(ctx, order); {
+		case err == nil:
+			stats.restored++
+			if erased {
+				stats.erased++
+			}
+		case errors.Is(err, errs.ErrDuplicate):
+			stats.skipped++
+		case errors.Is(err, errs.ErrConflict):
+			stats.conflicted++
+			logger.LogError("restore — order conflicts with a stored one, left out", err, "orderUID", order.Order.OrderUID)
+		default:
+			return fmt.Errorf("failed to restore order %s: %w", order.Order.OrderUID, err)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/configs"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository/postgres"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository/sqlite"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/pkg/logger"
+	"github.com/jmoiron/sqlx"
+)
+
+const rotateKeysUsage = `usage: wb-service rotate-keys [-all | -decrypt] [-batch-size N]
+
+Re-encrypts the personal data of deliveries, ingested payloads and amendments that are plaintext
+or encrypted with an old key with database.encryption.active_key. Keep the old keys configured until it has finished.
+-all rewrites every row, which is needed after the blind index key has changed.
+-decrypt turns every row back into plaintext, e.g. before reverting the encryption migrations.
+The service can keep running meanwhile.`
+
+/*
+runRotateKeys implements the rotate-keys subcommand.
+
+It connects to the database (or, if configured, to every shard) from the same config.yaml and .env
+as the service and rewrites the deliveries, ingested payloads and amendments of each, see postgres.Storage.RotateKeys.
+An interrupted run can simply be started again. Exits with code 2 on invalid arguments.
+*/
+func runRotateKeys(args []string) {
+	loggerConfig := configs.Logger{LogDir: "", Debug: false}
+	logger, _ := logger.NewLogger(loggerConfig)
+
+	flags := flag.NewFlagSet("rotate-keys", flag.ContinueOnError)
+	flags.Usage = func() { fmt.Fprintln(os.Stderr, rotateKeysUsage) }
+	all := flags.Bool("all", false, "rewrite every row")
+	decrypt := flags.Bool("decrypt", false, "decrypt every row")
+	batchSize := flags.Int("batch-size", 500, "number of rows rewritten per transaction")
+	if err := flags.Parse(args); err != nil || flags.NArg() > 0 || *batchSize <= 0 || (*all && *decrypt) {
+		flags.Usage()
+		os.Exit(2)
+	}
+	mode := postgres.RotateOutdated
+	if *all {
+		mode = postgres.RotateAll
+	} else if *decrypt {
+		mode = postgres.RotateDecrypt
+	}
+
+	config, err := configs.Load()
+	if err != nil {
+		logger.LogFatal("rotate-keys — failed to load configs", err)
+	}
+	if config.Database.Driver == sqlite.DriverName {
+		logger.LogFatal("rotate-keys — personal data is only encrypted in Postgres", errors.New("unsupported storage"))
+	}
+	if config.Database.Keyring == nil {
+		logger.LogFatal("rotate-keys — database.encryption.active_key is not set", errors.New("encryption is not configured"))
+	}
+	db, err := repository.ConnectDB(config.Database)
+	if err != nil {
+		logger.LogFatal("rotate-keys — failed to connect to database", err)
+	}
+	shards, err := repository.ConnectShards(config.Database)
+	if err != nil {
+		logger.LogFatal("rotate-keys — failed to connect to shards", err)
+	}
+	databases := map[string]*sqlx.DB{"main": db}
+	if len(shards) > 0 {
+		databases = make(map[string]*sqlx.DB, len(shards))
+		for _, shard := range shards {
+			databases[shard.Name] = shard.DB
+		}
+	}
+	closeAll := func() {
+		_ = db.Close()
+		for _, shard := range shards {
+			_ = shard.DB.Close()
+		}
+	}
+	defer closeAll()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	if err := repository.CheckSchema(ctx, db, config.Database); err != nil {
+		logger.LogError("rotate-keys — database schema version check failed, see \"wb-service migrate status\"", err)
+		stop()
+		closeAll()
+		os.Exit(1)
+	}
+
+	total := 0
+	for name, database := range databases {
+		rewritten, err := postgres.NewStorage(database, config.Database, logger).RotateKeys(ctx, *batchSize, mode)
+		total += rewritten
+		if err != nil {
+			fmt.Printf("rewritten: %d\n", total)
+			logger.LogError("rotate-keys — stopped before finishing, run it again to continue", err, "database", name)
+			stop()
+			closeAll()
+			os.Exit(1)
+		}
+		logger.LogInfo("rotate-keys — database done", "database", name, "rewritten", fmt.Sprintf("%d", rewritten))
+	}
+	fmt.Printf("rewritten: %d\n", total)
+}
@@ -16,7 +16,6 @@ import (
 	"os"
 	"os/signal"
 	"sync"
-	"sync/atomic"
 	"syscall"
 	"time"
 
@@ -24,7 +23,10 @@ import (
 	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/cache"
 	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/configs"
 	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/handler"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/models"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/outbox"
 	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/retention"
 	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/server"
 	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/service"
 	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/pkg/logger"
@@ -41,6 +43,9 @@ type App struct {
 	logFile         *os.File           // output for logs, can be a file or stdout
 	server          *server.Server     // HTTP server instance
 	consumer        broker.Consumer    // consumer for processing orders
+	relay           *outbox.Relay      // publishes events from the transactional outbox
+	outboxProducer  broker.Producer    // producer used by the outbox relay
+	retention       *retention.Job     // maintains order partitions and archives old orders, nil if the storage doesn't partition orders
 	notifier        notifier.Notifier  // notifies about critical errors
 	workers         int                // number of worker goroutines for message processing
 	restartOnPanic  bool               // whether workers should restart on panic
@@ -61,10 +66,10 @@ It performs the following steps:
  1. Loads application configuration (database, server, cache, consumer, etc.).
  2. Sets up logging (file/stdout).
  3. Creates a root context with cancellation for graceful shutdown.
- 4. Connects to the database and checks connectivity.
- 5. Initializes the message broker consumer.
+ 4. Connects to the database and its read replicas or shards, checks connectivity and verifies the schema version.
+ 5. Initializes the message broker consumer and the outbox producer.
  6. Sets up a notifier to report critical errors.
- 7. Wires dependencies: repository, cache, service, HTTP handlers, and server.
+ 7. Wires dependencies: repository, cache, service, HTTP handlers, server, outbox relay, and retention job.
  8. Returns a fully configured App instance ready to run.
 */
 func Start() *App {
@@ -84,13 +89,23 @@ func Start() *App {
 	}
 	logger.LogInfo("app — connected to database", "layer", "app")
 
+	if err := repository.CheckSchema(ctx, db, config.Database); err != nil {
+		logger.LogFatal("app — database schema version check failed, see \"wb-service migrate status\"", err, "layer", "app")
+	}
+
 	consumer, err := broker.NewConsumer(config.Consumer, logger)
 	if err != nil {
 		logger.LogFatal("app — failed to create consumer", err, "layer", "app")
 	}
 
+	outboxProducer, err := broker.NewProducer(config.Outbox.Producer, logger)
+	if err != nil {
+		logger.LogFatal("app — failed to create outbox producer", err, "layer", "app")
+	}
+
 	notifier := notifier.NewNotifier(config.Notifier)
-	server, cache, storage := wireApp(db, config, logger)
+	storage := newStorage(ctx, db, config.Database, logger)
+	server, cache := wireApp(ctx, storage, config, logger)
 	wg := new(sync.WaitGroup)
 
 	return &App{
@@ -98,6 +113,9 @@ func Start() *App {
 		logFile:         logFile,
 		server:          server,
 		consumer:        consumer,
+		relay:           outbox.NewRelay(config.Outbox, storage, outboxProducer, logger),
+		outboxProducer:  outboxProducer,
+		retention:       newRetentionJob(config.Retention, storage, notifier, logger),
 		notifier:        notifier,
 		workers:         config.Workers,
 		restartOnPanic:  config.RestartOnPanic,
@@ -134,21 +152,73 @@ func newContext(logger logger.Logger) (context.Context, context.CancelFunc) {
 	return ctx, cancel
 }
 
+/*
+newStorage creates the storage on top of the connected database.
+
+With shards configured, orders are spread over them and db only keeps the order-to-shard lookup table;
+every shard must have the expected schema version as well. Otherwise read replicas, if any,
+are connected and checked once, so that healthy ones serve reads right away.
+*/
+func newStorage(ctx context.Context, db *sqlx.DB, config configs.Database, logger logger.Logger) repository.Storage {
+	shards, err := repository.ConnectShards(config)
+	if err != nil {
+		logger.LogFatal("app — failed to connect to shards", err, "layer", "app")
+	}
+	if len(shards) > 0 {
+		for _, shard := range shards {
+			if err := repository.CheckSchema(ctx, shard.DB, config); err != nil {
+				logger.LogFatal("app — schema version check of shard "+shard.Name+" failed, see \"wb-service migrate status\"", err, "layer", "app")
+			}
+		}
+		storage, err := repository.NewShardedStorage(db, shards, config, logger)
+		if err != nil {
+			logger.LogFatal("app — invalid shard configuration", err, "layer", "app")
+		}
+		logger.LogInfo(fmt.Sprintf("app — connected to %d shards", len(shards)), "layer", "app")
+		return storage
+	}
+
+	replicas, err := repository.ConnectReplicas(config)
+	if err != nil {
+		logger.LogFatal("app — failed to connect to read replicas", err, "layer", "app")
+	}
+	storage := repository.NewStorage(db, config, logger, replicas...)
+	if statuses := storage.CheckReplicas(ctx); len(statuses) > 0 {
+		logger.LogInfo(fmt.Sprintf("app — %d of %d read replicas are in rotation", countHealthy(statuses), len(statuses)), "layer", "app")
+	}
+	return storage
+}
+
+/*
+newRetentionJob creates the partition upkeep and retention job if the storage partitions orders by date,
+which only the Postgres storage of a single database does. Returns nil otherwise.
+*/
+func newRetentionJob(config configs.Retention, storage repository.Storage, notifier notifier.Notifier, logger logger.Logger) *retention.Job {
+	archiver, ok := storage.(repository.Archiver)
+	if !ok {
+		if config.MaxAge > 0 {
+			logger.LogInfo("app — order retention is not supported by this storage, orders are kept forever", "layer", "app")
+		}
+		return nil
+	}
+	return retention.NewJob(config, archiver, notifier, logger)
+}
+
 /*
 wireApp performs dependency injection for the application.
 
-It wires together the core layers — storage, cache, service, HTTP handler,
-and server — ensuring all components are properly constructed and connected.
+It wires together the core layers — cache, service, HTTP handler,
+and server — on top of the storage, ensuring all components are properly constructed and connected.
 
-Returns the fully initialized server, cache, and storage instances.
+Returns the fully initialized server and cache instances.
 */
-func wireApp(db *sqlx.DB, config configs.App, logger logger.Logger) (*server.Server, cache.Cache, repository.Storage) {
-	storage := repository.NewStorage(db, logger)
-	cache := cache.NewCache(storage, config.Cache, logger)
+func wireApp(ctx context.Context, storage repository.Storage, config configs.App, logger logger.Logger) (*server.Server, cache.Cache) {
+	cache := cache.NewCache(ctx, storage, config.Cache, logger)
 	service := service.NewService(storage, cache)
-	handler := (handler.NewHandler(service, logger)).InitRoutes()
-	server := server.NewServer(config.Server, handler)
-	return server, cache, storage
+	handler := handler.NewHandler(service, logger)
+	handler.AdminToken = config.Server.AdminToken
+	server := server.NewServer(config.Server, handler.InitRoutes())
+	return server, cache
 }
 
 /*
@@ -158,7 +228,11 @@ with cache management.
   - Continuously checks DB availability in the background.
   - Switches to "cache-only mode" and sends an alert if the DB is unreachable.
   - Restores normal operation and re-enables cleanup when the DB recovers.
+  - Resumes the consumer, which pauses its partitions when workers run into a DB outage,
+    whenever the DB is reachable.
   - Provides DB status updates to the cache cleaner.
+  - Checks read replicas on every cycle and alerts separately about each one leaving or rejoining
+    the read rotation; reads fall back to the primary meanwhile, so the cache cleaner is not affected.
 
 Notes:
   - The cleanup runs in the background and removes only cache entries
@@ -171,11 +245,13 @@ func (a *App) RunCacheCleaner() {
 	dbStatus := make(chan bool, 1)
 	go func() {
 		var notified bool
+		replicasDown := make(map[string]bool)
 		for {
 			time.Sleep(a.dbCheckInterval)
-			if err := a.storage.Ping(); err != nil {
+			a.checkReplicas(replicasDown)
+			if err := a.storage.Ping(a.ctx); err != nil {
 				for range a.dbMaxChecks {
-					if err = a.storage.Ping(); err != nil {
+					if err = a.storage.Ping(a.ctx); err != nil {
 						time.Sleep(a.dbCheckInterval)
 						continue
 					}
@@ -187,6 +263,7 @@ func (a *App) RunCacheCleaner() {
 				dbStatus <- false
 			} else {
 				notified = false
+				a.consumer.Resume()
 				dbStatus <- true
 			}
 		}
@@ -194,6 +271,36 @@ func (a *App) RunCacheCleaner() {
 	a.cache.CacheCleaner(a.ctx, a.logger, dbStatus)
 }
 
+// checkReplicas checks the read replicas and notifies about every replica that has left or rejoined
+// the read rotation since the last check. down tracks the replicas that are out of rotation.
+func (a *App) checkReplicas(down map[string]bool) {
+	for _, status := range a.storage.CheckReplicas(a.ctx) {
+		switch {
+		case status.Healthy && down[status.Name]:
+			delete(down, status.Name)
+			_ = a.notifier.Notify(fmt.Sprintf("RECOVERED — read replica %s is back in rotation", status.Name))
+		case !status.Healthy && !down[status.Name]:
+			down[status.Name] = true
+			reason := fmt.Sprintf("replication lag %s exceeds the limit", status.Lag)
+			if status.Err != nil {
+				reason = status.Err.Error()
+			}
+			_ = a.notifier.Notify(fmt.Sprintf("WARNING — read replica %s is out of rotation\n%s\nreads it served fall back to the primary", status.Name, reason))
+		}
+	}
+}
+
+// countHealthy returns the number of replicas that serve reads.
+func countHealthy(statuses []models.ReplicaStatus) int {
+	var healthy int
+	for _, status := range statuses {
+		if status.Healthy {
+			healthy++
+		}
+	}
+	return healthy
+}
+
 /*
 RunServer starts the HTTP server and listens for shutdown signals.
 
@@ -222,36 +329,73 @@ func (a *App) RunServer() {
 /*
 RunConsumer acts as a system monitor for message-processing workers.
 
-  - Starts a supervisory goroutine that shuts down the consumer when the context is cancelled.
+  - Starts the poll loop that receives messages and hands every partition to one of the workers, see runPoller.
   - Spawns multiple worker goroutines to process messages concurrently.
-  - Each worker is monitored: panics and failures are reported and handled according to the restart policy defined in the configuration.
+  - Spawns the goroutine that processes the messages of the retry topics, see runRetries.
+  - Each goroutine is monitored: panics and failures are reported and handled according to the restart policy defined in the configuration.
+  - Starts a supervisory goroutine that shuts down the consumer once all of them have stopped after the context is cancelled.
 */
 func (a *App) RunConsumer() {
+	running := new(sync.WaitGroup)
+	running.Add(a.workers + 2)
 	a.wg.Add(1)
 	go func() {
 		defer a.wg.Done()
 		<-a.ctx.Done()
+		running.Wait()
 		a.consumer.Close(a.logger)
 	}()
-	var lastWorker atomic.Int32
+	go a.runPoller(running)
 	for workerID := 1; workerID < a.workers+1; workerID++ {
-		a.wg.Add(1)
-		lastWorker.Add(1)
-		go a.runWorker(workerID, &lastWorker)
+		go a.runWorker(workerID, running)
+	}
+	go a.runRetries(running)
+}
+
+/*
+runPoller runs the poll loop of the consumer, which every worker depends on.
+
+A panic is reported and handled according to the configured restart policy.
+A poll loop that is not restarted triggers an emergency shutdown, since no worker receives messages without it.
+*/
+func (a *App) runPoller(running *sync.WaitGroup) {
+	defer running.Done()
+	for {
+		func() {
+			defer func() {
+				if panicErr := recover(); panicErr != nil {
+					a.logger.LogError("consumer — poll loop panicked", fmt.Errorf("%v", panicErr), "layer", "app")
+				}
+			}()
+			a.consumer.Poll(a.ctx, a.logger)
+		}()
+		if a.ctx.Err() != nil {
+			return
+		}
+		if !a.restartOnPanic {
+			a.logger.LogInfo("consumer — poll loop terminated, initiating emergency shutdown", "layer", "app")
+			a.Stop()
+			return
+		}
+		select {
+		case <-a.ctx.Done():
+			return
+		case <-time.After(a.restartDelay):
+		}
 	}
 }
 
 /*
 runWorker executes a single worker instance for message processing.
 
-The worker continuously polls messages from the broker and handles any panics.
+The worker continuously processes the messages of its partitions and handles any panics.
 Errors are logged, and the worker is either restarted or terminated according to
-the configured restart policy. If all workers terminate without restart, an emergency
-shutdown is triggered.
+the configured restart policy. A restarted worker carries on with the messages of the same partitions.
+A worker that terminates without restart triggers an emergency shutdown, since no other worker
+may take over its partitions without breaking their order.
 */
-func (a *App) runWorker(workerID int, lastWorker *atomic.Int32) {
-	defer a.wg.Done()
-	mu := new(sync.Mutex)
+func (a *App) runWorker(workerID int, running *sync.WaitGroup) {
+	defer running.Done()
 	for {
 		select {
 		case <-a.ctx.Done():
@@ -262,21 +406,16 @@ func (a *App) runWorker(workerID int, lastWorker *atomic.Int32) {
 				defer func() {
 					if panicErr := recover(); panicErr != nil {
 						a.logger.LogError(fmt.Sprintf("consumer — worker %d panicked", workerID), fmt.Errorf("%v", panicErr))
-						if !a.restartOnPanic {
-							a.logger.LogInfo(fmt.Sprintf("consumer — worker %d terminated", workerID), "layer", "app")
-							mu.Lock()
-							a.workers--
-							if a.workers == 0 {
-								a.logger.LogInfo("consumer — all workers have panicked, initiating emergency shutdown", "layer", "app")
-								a.Stop()
-							}
-							mu.Unlock()
-						}
 					}
 				}()
-				a.consumer.Run(a.ctx, a.storage, a.logger, workerID, lastWorker)
+				a.consumer.Run(a.ctx, a.storage, a.cache, a.logger, workerID)
 			}()
+			if a.ctx.Err() != nil {
+				continue
+			}
 			if !a.restartOnPanic {
+				a.logger.LogInfo(fmt.Sprintf("consumer — worker %d terminated, initiating emergency shutdown", workerID), "layer", "app")
+				a.Stop()
 				return
 			}
 			time.Sleep(a.restartDelay)
@@ -284,12 +423,117 @@ func (a *App) runWorker(workerID int, lastWorker *atomic.Int32) {
 	}
 }
 
+/*
+runRetries processes the messages of the retry topics until shutdown.
+
+A panic is reported and handled according to the same restart policy as the workers,
+except that it does not shut the service down: failed messages wait in the retry topics.
+*/
+func (a *App) runRetries(running *sync.WaitGroup) {
+	defer running.Done()
+	for {
+		func() {
+			defer func() {
+				if panicErr := recover(); panicErr != nil {
+					a.logger.LogError("consumer — retry consumer panicked", fmt.Errorf("%v", panicErr), "layer", "app")
+				}
+			}()
+			a.consumer.RunRetries(a.ctx, a.storage, a.cache, a.logger)
+		}()
+		if a.ctx.Err() != nil {
+			return
+		}
+		if !a.restartOnPanic {
+			a.logger.LogInfo("consumer — retry consumer terminated, failed messages stay in the retry topics", "layer", "app")
+			return
+		}
+		select {
+		case <-a.ctx.Done():
+			return
+		case <-time.After(a.restartDelay):
+		}
+	}
+}
+
+/*
+RunOutboxRelay publishes events from the transactional outbox until shutdown.
+
+A panic in the relay is reported and handled according to the same restart policy as the workers,
+except that a relay that is not restarted does not shut the service down: orders keep being
+accepted and their events wait in the outbox. The outbox producer is closed once the relay stops.
+*/
+func (a *App) RunOutboxRelay() {
+	a.wg.Add(1)
+	defer a.wg.Done()
+	defer a.outboxProducer.Close()
+	for {
+		func() {
+			defer func() {
+				if panicErr := recover(); panicErr != nil {
+					a.logger.LogError("outbox — relay panicked", fmt.Errorf("%v", panicErr), "layer", "app")
+				}
+			}()
+			a.relay.Run(a.ctx)
+		}()
+		if a.ctx.Err() != nil {
+			return
+		}
+		if !a.restartOnPanic {
+			a.logger.LogInfo("outbox — relay terminated, events stay in the outbox", "layer", "app")
+			_ = a.notifier.Notify("CRITICAL ERROR — outbox relay terminated\norder events are not being published")
+			return
+		}
+		select {
+		case <-a.ctx.Done():
+			return
+		case <-time.After(a.restartDelay):
+		}
+	}
+}
+
+/*
+RunRetention creates upcoming order partitions and archives old orders until shutdown.
+
+A panic in the job is reported and handled according to the same restart policy as the workers;
+a job that is not restarted does not shut the service down. Does nothing if the storage doesn't partition orders.
+*/
+func (a *App) RunRetention() {
+	if a.retention == nil {
+		return
+	}
+	a.wg.Add(1)
+	defer a.wg.Done()
+	for {
+		func() {
+			defer func() {
+				if panicErr := recover(); panicErr != nil {
+					a.logger.LogError("retention — job panicked", fmt.Errorf("%v", panicErr), "layer", "app")
+				}
+			}()
+			a.retention.Run(a.ctx)
+		}()
+		if a.ctx.Err() != nil {
+			return
+		}
+		if !a.restartOnPanic {
+			a.logger.LogInfo("retention — job terminated", "layer", "app")
+			_ = a.notifier.Notify("CRITICAL ERROR — retention job terminated\nnew order partitions are not being created")
+			return
+		}
+		select {
+		case <-a.ctx.Done():
+			return
+		case <-time.After(a.restartDelay):
+		}
+	}
+}
+
 /*
 Wait blocks until all application components shut down.
 
 Steps:
  1. Waits for the root context cancellation (ctx acts as a blocking point to prevent premature main exit).
- 2. Waits for all goroutines (server, consumer, workers, cache cleaner) to finish.
+ 2. Waits for all goroutines (server, consumer, workers, cache cleaner, outbox relay, retention job) to finish.
  3. Closes the storage (DB connection).
  4. Closes the log file if one was used.
 
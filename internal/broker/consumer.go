@@ -10,9 +10,9 @@ package broker
 
 import (
 	"context"
-	"sync/atomic"
 
 	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/broker/kafka"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/cache"
 	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/configs"
 	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository"
 	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/pkg/logger"
@@ -22,16 +22,32 @@ import (
 Consumer defines a message broker consumer instance.
 
 A Consumer is responsible for:
-  - Running a worker loop to consume messages.
+  - Running a single poll loop that hands messages to the workers, keeping every partition on one worker.
+  - Running a worker loop to process messages.
   - Gracefully shutting down when requested.
   - Being supervised by the orchestration layer (App) for panics or errors.
 */
 type Consumer interface {
-	// Run starts the consumer loop for a single worker.
-	// It processes messages until the context is cancelled.
-	Run(ctx context.Context, storage repository.Storage, logger logger.Logger, workerID int, lastWorker *atomic.Int32)
+	// Poll receives messages and hands them to the workers until the context is cancelled.
+	// Before partitions are taken away in a rebalance, it waits for the workers to finish their messages.
+	// A database outage a worker runs into pauses the partitions until Resume is called.
+	Poll(ctx context.Context, logger logger.Logger)
+
+	// Resume lets Poll receive messages again after a database outage paused it.
+	// It is called whenever the database is reachable, and does nothing if Poll is not paused.
+	Resume()
+
+	// Run starts the consumer loop for a single worker, numbered from 1.
+	// It processes the messages Poll hands it until the context is cancelled.
+	// Cached orders are invalidated when a message changes them.
+	Run(ctx context.Context, storage repository.Storage, cache cache.Cache, logger logger.Logger, workerID int)
+
+	// RunRetries processes the messages of the retry topics, which failed messages are forwarded to
+	// before they are given up on. It returns once the context is cancelled, or at once if there are none.
+	RunRetries(ctx context.Context, storage repository.Storage, cache cache.Cache, logger logger.Logger)
 
 	// Close terminates the consumer and releases any underlying resources.
+	// It must be called only after Poll, RunRetries and every Run have returned.
 	Close(logger logger.Logger)
 }
 
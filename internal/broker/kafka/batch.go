@@ -0,0 +1,173 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository/errs"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/pkg/logger"
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+// orderBatch collects order messages of a worker until they are saved together.
+type orderBatch struct {
+	messages   []*kafka.Message
+	deadline   time.Time // when the batch is saved even if it is not full
+	generation uint64    // of the messages, see KafkaConsumer.generation
+}
+
+// add appends msg to the batch. The first message of a batch starts the wait for the rest.
+func (b *orderBatch) add(msg *kafka.Message, wait time.Duration) {
+	if len(b.messages) == 0 {
+		b.deadline = time.Now().Add(wait)
+	}
+	b.messages = append(b.messages, msg)
+}
+
+// due reports whether the batch is full or has waited long enough.
+func (b *orderBatch) due(size int) bool {
+	return len(b.messages) > 0 && (len(b.messages) >= size || !time.Now().Before(b.deadline))
+}
+
+// take returns the collected messages and empties the batch.
+func (b *orderBatch) take() []*kafka.Message {
+	messages := b.messages
+	b.messages = nil
+	return messages
+}
+
+// timer fires when a pending batch is due even if it is not full. It never fires for an empty batch.
+func (b *orderBatch) timer() <-chan time.Time {
+	if len(b.messages) == 0 {
+		return nil
+	}
+	return time.After(time.Until(b.deadline))
+}
+
+/*
+processBatch saves a batch of order messages together and commits their offsets once.
+
+  - The whole batch is retried on transient failures with exponential backoff and jitter.
+    If retry topics are configured, a batch that fails transiently is not retried in place,
+    every order of it is forwarded to the first retry topic.
+  - Once the connection to the database is lost, see databaseDown, the batch is held back, uncommitted,
+    and Poll pauses the partitions, see reportOutage.
+    Returns true in that case, false otherwise.
+  - Orders that are already saved are skipped.
+  - Orders that fail permanently (they can't be parsed, fail validation, break a rejecting business rule,
+    conflict with a saved one or violate DB constraints) are sent to DLQ,
+    the rest of the batch is saved without them. Orders that fail transiently on their own are forwarded
+    to the first retry topic, or sent to DLQ if there is none.
+  - If the batch still fails after all retries, or fails permanently as a whole, every order of it is sent to DLQ.
+  - Offsets are committed only after every order has been saved, forwarded or sent to DLQ,
+    so an interrupted batch is redelivered as a whole.
+*/
+func (c *KafkaConsumer) processBatch(ctx context.Context, messages []*kafka.Message, storage repository.Storage, logger logger.Logger, workerID int) bool {
+	logger.Debug(fmt.Sprintf("worker %d — received a batch of %d orders from Kafka, will try saving it", workerID, len(messages)), "workerID", fmt.Sprintf("%d", workerID), "layer", "broker.kafka")
+	var results []error
+	var failure failure // of the batch as a whole
+	for {
+		var err error
+		results, err = c.handler.SaveOrders(ctx, messages, storage, logger, workerID)
+		if ctx.Err() != nil {
+			logger.LogInfo(fmt.Sprintf("worker %d — shutdown interrupted saving a batch of %d orders, it will be redelivered", workerID, len(messages)), "workerID", fmt.Sprintf("%d", workerID), "layer", "broker.kafka")
+			return false
+		}
+		if err == nil {
+			break
+		}
+		if c.databaseDown(ctx, err, storage) {
+			logger.LogInfo(fmt.Sprintf("worker %d — lost connection to database, a batch of %d orders is held back", workerID, len(messages)), "workerID", fmt.Sprintf("%d", workerID), "layer", "broker.kafka")
+			c.reportOutage()
+			return true
+		}
+		failure.failed(err, time.Now())
+		class := Classify(err)
+		forward := !class.Permanent() && len(c.retryTopics) > 0 // retried through the retry topics instead
+		if !class.Permanent() && !forward {
+			failure.retryCnt++
+		}
+		if class.Permanent() || forward || failure.retryCnt >= c.saveOrderRetryMax {
+			logger.LogError(fmt.Sprintf("worker %d — failed to process a batch of %d orders after %d retries", workerID, len(messages), failure.retryCnt), err, "errorClass", string(class), "workerID", fmt.Sprintf("%d", workerID), "layer", "broker.kafka")
+			results = make([]error, len(messages))
+			for i := range results {
+				results[i] = err
+			}
+			break
+		}
+		time.Sleep(c.retryDelay(failure.retryCnt))
+	}
+
+	for i, err := range results {
+		switch {
+		case err == nil:
+		case errors.Is(err, errs.ErrDuplicate):
+			logger.Debug(fmt.Sprintf("worker %d — order is already saved, skipping redelivered message", workerID), "orderUID", ToStr(messages[i].Key), "workerID", fmt.Sprintf("%d", workerID), "layer", "broker.kafka")
+		default:
+			logger.LogError(fmt.Sprintf("worker %d — order can't be saved", workerID), err, "orderUID", ToStr(messages[i].Key), "errorClass", string(Classify(err)), "workerID", fmt.Sprintf("%d", workerID), "layer", "broker.kafka")
+			orderFailure := failure
+			orderFailure.failed(err, time.Now())
+			c.forwardFailed(messages[i], orderFailure, workerID)
+		}
+	}
+
+	if err := c.commitBatchWithRetry(messages); err != nil {
+		logger.LogError(fmt.Sprintf("worker %d — critical error", workerID), err, "batchSize", fmt.Sprintf("%d", len(messages)), "workerID", fmt.Sprintf("%d", workerID), "layer", "broker.kafka")
+		_ = c.notifier.Notify(fmt.Sprintf("CRITICAL ERROR — Kafka commit failed\nworkerID=%d\nbatchSize=%d", workerID, len(messages)))
+		panic(fmt.Sprintf("worker self-termination: offset commit failed (workerID=%d, batchSize=%d)", workerID, len(messages)))
+	}
+	return false
+}
+
+// saveBatch processes the pending batch of a worker, see processBatch. If the batch is held back
+// for a database outage, it waits for Poll to pause the partitions, see awaitPause, and reports true.
+func (c *KafkaConsumer) saveBatch(ctx context.Context, batch *orderBatch, queue <-chan workItem, storage repository.Storage, logger logger.Logger, workerID int) bool {
+	generation := batch.generation
+	if !c.processBatch(ctx, batch.take(), storage, logger, workerID) {
+		return false
+	}
+	c.awaitPause(ctx, queue, generation)
+	return true
+}
+
+/*
+commitBatchWithRetry commits the offsets of a batch in a single request.
+
+For every partition in the batch, the offset following its last message is committed.
+It retries up to commitRetryMax times with a configured delay between attempts.
+*/
+func (c *KafkaConsumer) commitBatchWithRetry(messages []*kafka.Message) error {
+	offsets := batchOffsets(messages)
+	var err error
+	for range c.commitRetryMax {
+		if _, err = c.consumer.CommitOffsets(offsets); err != nil {
+			time.Sleep(c.commitRetryDelay)
+		} else {
+			return nil
+		}
+	}
+	return fmt.Errorf("failed to commit offsets after %d attempts: %w", c.commitRetryMax, err)
+}
+
+// batchOffsets returns the offset to commit for every partition of messages, in order of first appearance.
+func batchOffsets(messages []*kafka.Message) []kafka.TopicPartition {
+	var offsets []kafka.TopicPartition
+	positions := make(map[string]int) // position in offsets by topic and partition
+	for _, msg := range messages {
+		partition := msg.TopicPartition
+		key := partitionKey(partition)
+		i, ok := positions[key]
+		if !ok {
+			positions[key] = len(offsets)
+			offsets = append(offsets, kafka.TopicPartition{Topic: partition.Topic, Partition: partition.Partition, Offset: partition.Offset + 1})
+			continue
+		}
+		if partition.Offset+1 > offsets[i].Offset {
+			offsets[i].Offset = partition.Offset + 1
+		}
+	}
+	return offsets
+}
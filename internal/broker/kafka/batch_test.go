@@ -0,0 +1,195 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/models"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository/errs"
+	mock_repository "github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository/mocks"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/rules"
+	mock_logger "github.com/Pur1st2EpicONE/WBTECH-sample-microservice/pkg/logger/mocks"
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/golang/mock/gomock"
+)
+
+func testMessage(topic string, partition int32, offset kafka.Offset) *kafka.Message {
+	return &kafka.Message{TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: partition, Offset: offset}}
+}
+
+func TestBatchOffsets(t *testing.T) {
+	offsets := batchOffsets([]*kafka.Message{
+		testMessage("orders", 0, 10),
+		testMessage("orders", 1, 4),
+		testMessage("orders", 0, 12),
+		testMessage("orders", 0, 11),
+	})
+	if len(offsets) != 2 {
+		t.Fatalf("expected offsets of 2 partitions, got %v", offsets)
+	}
+	if offsets[0].Partition != 0 || offsets[0].Offset != 13 || *offsets[0].Topic != "orders" {
+		t.Fatalf("expected the offset after the last message of partition 0, got %v", offsets[0])
+	}
+	if offsets[1].Partition != 1 || offsets[1].Offset != 5 {
+		t.Fatalf("expected the offset after the last message of partition 1, got %v", offsets[1])
+	}
+}
+
+func TestOrderBatch(t *testing.T) {
+	batch := new(orderBatch)
+	if batch.due(2) {
+		t.Fatal("empty batch must never be due")
+	}
+	if batch.timer() != nil {
+		t.Fatal("empty batch must never wake its worker up")
+	}
+
+	batch.add(testMessage("orders", 0, 1), time.Hour)
+	if batch.due(2) {
+		t.Fatal("batch must wait to fill up")
+	}
+	batch.add(testMessage("orders", 0, 2), time.Hour)
+	if !batch.due(2) {
+		t.Fatal("full batch must be due")
+	}
+	if messages := batch.take(); len(messages) != 2 || len(batch.messages) != 0 {
+		t.Fatalf("expected take to empty the batch, got %d messages left", len(batch.messages))
+	}
+
+	batch.add(testMessage("orders", 0, 3), -time.Millisecond)
+	if !batch.due(100) {
+		t.Fatal("batch must be due once its wait is over")
+	}
+	select {
+	case <-batch.timer():
+	case <-time.After(time.Second):
+		t.Fatal("expected the timer of a due batch to fire right away")
+	}
+}
+
+func TestHandler_SaveOrders(t *testing.T) {
+	controller := gomock.NewController(t)
+	storage := mock_repository.NewMockStorage(controller)
+	logger := mock_logger.NewMockLogger(controller)
+	logger.EXPECT().Debug(gomock.Any(), gomock.Any()).AnyTimes()
+
+	valid := validOrderJSON(t, "b563feb7b2b84b6test")
+	other := validOrderJSON(t, "c563feb7b2b84b6test")
+	storage.EXPECT().SaveOrders(gomock.Any(), gomock.Len(2)).
+		DoAndReturn(func(_ context.Context, orders []*models.Order) ([]error, error) {
+			if orders[0].OrderUID != "b563feb7b2b84b6test" || orders[1].OrderUID != "c563feb7b2b84b6test" {
+				t.Errorf("unexpected orders passed to storage: %s, %s", orders[0].OrderUID, orders[1].OrderUID)
+			}
+			ingest := orders[1].Ingest
+			if ingest == nil || ingest.Topic != "orders" || ingest.Partition != 1 || ingest.Offset != 12 ||
+				ingest.Key != "c563feb7b2b84b6test" || string(ingest.Payload) != string(other) {
+				t.Errorf("expected the ingest record of the third message, got %+v", ingest)
+			}
+			return []error{nil, errs.ErrConflict}, nil
+		})
+
+	messages := []*kafka.Message{testMessage("orders", 1, 10), testMessage("orders", 1, 11), testMessage("orders", 1, 12)}
+	for i, value := range [][]byte{[]byte("not json"), valid, other} {
+		messages[i].Value = value
+	}
+	messages[2].Key = []byte("c563feb7b2b84b6test")
+	results, err := newHandler(testRules(t)).SaveOrders(context.Background(), messages, storage, logger, 1)
+	if err != nil {
+		t.Fatalf("SaveOrders failed: %v", err)
+	}
+	if results[0] == nil || results[1] != nil || !errors.Is(results[2], errs.ErrConflict) {
+		t.Fatalf("expected [parse error, saved, conflict], got %v", results)
+	}
+}
+
+func TestHandler_SaveOrders_BatchError(t *testing.T) {
+	controller := gomock.NewController(t)
+	storage := mock_repository.NewMockStorage(controller)
+	logger := mock_logger.NewMockLogger(controller)
+	storage.EXPECT().SaveOrders(gomock.Any(), gomock.Len(1)).Return(nil, errs.ErrUnavailable)
+
+	msg := testMessage("orders", 0, 1)
+	msg.Value = validOrderJSON(t, "b563feb7b2b84b6test")
+	_, err := newHandler(testRules(t)).SaveOrders(context.Background(), []*kafka.Message{msg}, storage, logger, 1)
+	if !errors.Is(err, errs.ErrUnavailable) {
+		t.Fatalf("expected ErrUnavailable, got %v", err)
+	}
+}
+
+func TestHandler_SaveOrders_BusinessRules(t *testing.T) {
+	controller := gomock.NewController(t)
+	storage := mock_repository.NewMockStorage(controller)
+	logger := mock_logger.NewMockLogger(controller)
+	logger.EXPECT().Debug(gomock.Any(), gomock.Any()).AnyTimes()
+	logger.EXPECT().LogInfo(gomock.Any(), gomock.Any()).Times(1)
+
+	flagged := withOrder(t, validOrderJSON(t, "b563feb7b2b84b6test"), func(order *models.Order) { order.Payment.Amount = 1000 })
+	rejected := withOrder(t, validOrderJSON(t, "c563feb7b2b84b6test"), func(order *models.Order) { order.Payment.Transaction = "other" })
+	storage.EXPECT().SaveOrders(gomock.Any(), gomock.Len(1)).
+		DoAndReturn(func(_ context.Context, orders []*models.Order) ([]error, error) {
+			violations := orders[0].Ingest.Violations
+			if len(violations) != 1 || violations[0].Rule != "payment_amount" || violations[0].Severity != models.SeverityFlag {
+				t.Errorf("expected the flagged order to carry its violation, got %+v", violations)
+			}
+			return []error{nil}, nil
+		})
+
+	messages := []*kafka.Message{testMessage("orders", 0, 1), testMessage("orders", 0, 2)}
+	messages[0].Value, messages[1].Value = flagged, rejected
+	results, err := newHandler(testRules(t)).SaveOrders(context.Background(), messages, storage, logger, 1)
+	if err != nil {
+		t.Fatalf("SaveOrders failed: %v", err)
+	}
+	var rejection *rules.RejectedError
+	if results[0] != nil || !errors.As(results[1], &rejection) || rejection.Violations[0].Rule != "payment_transaction" {
+		t.Fatalf("expected [saved, rejected by payment_transaction], got %v", results)
+	}
+}
+
+// withOrder returns orderJSON changed by change.
+func withOrder(t *testing.T, orderJSON []byte, change func(order *models.Order)) []byte {
+	t.Helper()
+	var order models.Order
+	if err := json.Unmarshal(orderJSON, &order); err != nil {
+		t.Fatalf("failed to unmarshal order: %v", err)
+	}
+	change(&order)
+	changed, err := json.Marshal(order)
+	if err != nil {
+		t.Fatalf("failed to marshal order: %v", err)
+	}
+	return changed
+}
+
+// testRules returns an engine with the built-in business rules at their defaults.
+func testRules(t *testing.T) *rules.Engine {
+	t.Helper()
+	engine, err := rules.New(nil, rules.Builtin()...)
+	if err != nil {
+		t.Fatalf("failed to create rules engine: %v", err)
+	}
+	return engine
+}
+
+// validOrderJSON returns a JSON order with the given UID that passes validation.
+func validOrderJSON(t *testing.T, orderUID string) []byte {
+	t.Helper()
+	order := models.Order{
+		OrderUID: orderUID, TrackNumber: "WBILMTESTTRACK", Entry: "WBIL", Locale: "en", CustomerID: "test",
+		DeliveryService: "meest", ShardKey: "9", SmID: 99, DateCreated: time.Date(2021, 11, 26, 6, 22, 19, 0, time.UTC), OofShard: "1",
+		Delivery: models.Delivery{Name: "Test Testov", Phone: "+9720000000", Zip: "2639809", City: "Kiryat Mozkin",
+			Address: "Ploshad Mira 15", Region: "Kraiot", Email: "test@gmail.com"},
+		Payment: models.Payment{Transaction: orderUID, Currency: "USD", Provider: "wbpay", Amount: 1817,
+			PaymentDT: 1637907727, Bank: "alpha", DeliveryCost: 1500, GoodsTotal: 317},
+		Items: []models.Item{{ChrtID: 9934930, TrackNumber: "WBILMTESTTRACK", Price: 453, Rid: "ab4219087a764ae0btest",
+			Name: "Mascaras", Sale: 30, Size: "0", TotalPrice: 317, NmID: 2389212, Brand: "Vivienne Sabo", Status: 202}},
+	}
+	orderJSON, err := json.Marshal(order)
+	if err != nil {
+		t.Fatalf("failed to marshal order: %v", err)
+	}
+	return orderJSON
+}
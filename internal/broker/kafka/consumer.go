@@ -2,24 +2,30 @@
 Package kafka provides Kafka-based implementations of broker interfaces.
 
 It includes:
-  - KafkaConsumer: a consumer instance that processes messages from Kafka topics.
+  - KafkaConsumer: a consumer instance that processes orders and item status-change events from Kafka topics.
   - KafkaProducer: a producer instance used for sending messages (e.g., to a DLQ).
 
-KafkaConsumer handles message consumption, retries, DLQ routing, and critical error
-notifications. It is designed to be supervised by the orchestration layer
+KafkaConsumer handles message consumption with a single poll loop feeding per-partition worker queues,
+rebalances, retries, DLQ routing, and critical error notifications. It is designed to be supervised by the orchestration layer
 (App) and can trigger self-termination on unrecoverable errors.
 */
 package kafka
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math/rand/v2"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/cache"
 	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/configs"
 	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository/errs"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/rules"
 	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/pkg/logger"
 	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/pkg/notifier"
 	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
@@ -29,66 +35,127 @@ import (
 KafkaConsumer represents a single Kafka consumer instance.
 
 It is responsible for:
-  - Polling messages from a Kafka topic.
-  - Processing messages and saving them to storage.
+  - Polling messages from a Kafka topic in a single loop and handing them to the workers, see Poll.
+  - Processing messages and saving them to storage, every partition by a single worker, see Run.
   - Handling retries for message processing and offset commits.
-  - Sending failed messages to a dead-letter queue (DLQ).
+  - Sending failed messages to a dead-letter queue (DLQ), or along the chain of retry topics if one is configured.
   - Logging critical errors and notifying via a notifier.
   - Self-termination if unrecoverable errors occur.
 
 KafkaConsumer is typically managed and monitored by the App orchestration layer.
 */
 type KafkaConsumer struct {
-	consumer                 *kafka.Consumer   // underlying Kafka consumer
-	handler                  *Handler          // message handler for processing orders
-	dlq                      *KafkaProducer    // producer for dead-letter queue
-	dlqTopic                 string            // DLQ topic name
-	saveOrderRetryDelay      time.Duration     // delay between retries when saving order fails
-	saveOrderRetryMax        int               // maximum retries for saving an order
-	commitRetryDelay         time.Duration     // delay between retries when committing offset
-	commitRetryMax           int               // maximum retries for committing offset
-	eventTypeErrorsMax       int               // max consecutive broker errors before panic
-	eventTypeErrorRetryDelay time.Duration     // delay after broker error before retry
-	dbConnectionCheckDelay   time.Duration     // delay between database connection checks when connection errors occur
-	notifier                 notifier.Notifier // notifier for critical errors
+	consumer                 *kafka.Consumer         // underlying Kafka consumer
+	topics                   []string                // topics the consumer subscribes to
+	queues                   []chan workItem         // messages waiting for every worker, by worker ID - 1
+	owners                   map[string]int          // worker queue of every assigned partition, see partitionKey; used by the poll loop only
+	firstOffsets             map[string]kafka.Offset // first offset received of every assigned partition; used by the poll loop only
+	waiting                  map[string]backlog      // messages of partitions paused for a busy worker, see dispatch; used by the poll loop only
+	generation               atomic.Uint64           // bumped by every pause; workers drop messages received before it
+	pauseMu                  sync.Mutex              // guards generation changes and nextPause
+	nextPause                chan struct{}           // closed by the next pause, see awaitPause
+	paused                   bool                    // whether a database outage has paused the partitions; used by the poll loop only
+	outages                  chan struct{}           // workers report database outages to the poll loop, see reportOutage
+	recovered                chan struct{}           // the database health monitor reports recovery to the poll loop, see Resume
+	retries                  *kafka.Consumer         // consumer of the retry topics, nil if there are none
+	retryTopics              []configs.RetryTopic    // retry topics in the order messages go through them
+	handler                  *Handler                // message handler for processing orders
+	dlq                      *KafkaProducer          // producer for dead-letter queue
+	dlqTopic                 string                  // DLQ topic name
+	statusTopic              string                  // topic with item status-change events
+	saveOrderRetryDelay      time.Duration           // delay before the first retry when saving order fails, doubled on every retry
+	saveOrderRetryMaxDelay   time.Duration           // upper bound of the delay between retries, 0 for none
+	saveOrderRetryMax        int                     // maximum retries for saving an order
+	commitRetryDelay         time.Duration           // delay between retries when committing offset
+	commitRetryMax           int                     // maximum retries for committing offset
+	eventTypeErrorsMax       int                     // max consecutive broker errors before panic
+	eventTypeErrorRetryDelay time.Duration           // delay after broker error before retry
+	dbConnectionCheckDelay   time.Duration           // delay between database connection checks of the retry consumer during outages
+	batchSize                int                     // max number of orders saved together
+	batchWait                time.Duration           // max time to wait for a batch to fill up
+	notifier                 notifier.Notifier       // notifier for critical errors
 }
 
 /*
 NewConsumer creates a new KafkaConsumer instance with the provided configuration.
 
 It initializes:
-  - A Kafka consumer connected to the specified topic.
-  - A DLQ producer for handling failed messages.
-  - A handler for processing messages.
+  - A Kafka consumer of the orders topic and, if configured, the status-update topic,
+    which subscribes to them once polling starts, see Poll.
+  - A queue of up to config.QueueSize messages for each of the config.Workers workers.
+  - If config.RetryTopics are configured, a consumer of them in a consumer group of its own
+    (config.GroupID with a "-retry" suffix), see RunRetries.
+  - A DLQ producer for handling failed messages, which also forwards them to the retry topics.
+  - A handler for processing messages, checking orders against the built-in business rules
+    as configured in config.Rules.
   - A notifier for critical errors.
 
 Returns the fully initialized KafkaConsumer or an error if setup fails.
 */
 func NewConsumer(config configs.Consumer, logger logger.Logger) (*KafkaConsumer, error) {
+	engine, err := rules.New(config.Rules, rules.Builtin()...)
+	if err != nil {
+		return nil, fmt.Errorf("invalid business rules: %w", err)
+	}
 	kafkaConsumer, err := kafka.NewConsumer(toMap(config))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create consumer: %w", err)
 	}
-	if err := kafkaConsumer.Subscribe(config.Topic, nil); err != nil {
-		return nil, fmt.Errorf("failed to subscribe to topic: %w", err)
+	topics := []string{config.Topic}
+	if config.StatusTopic != "" {
+		topics = append(topics, config.StatusTopic)
+	}
+	if err := validateRetryTopics(config.RetryTopics, append(topics, config.DLQ.Topic)...); err != nil {
+		return nil, fmt.Errorf("invalid retry topics: %w", err)
+	}
+	var retries *kafka.Consumer
+	if len(config.RetryTopics) > 0 {
+		retryConfig := toMap(config)
+		_ = retryConfig.SetKey("group.id", config.GroupID+"-retry")
+		if retries, err = kafka.NewConsumer(retryConfig); err != nil {
+			return nil, fmt.Errorf("failed to create retry consumer: %w", err)
+		}
+		var retryTopics []string
+		for _, stage := range config.RetryTopics {
+			retryTopics = append(retryTopics, stage.Topic)
+		}
+		if err := retries.SubscribeTopics(retryTopics, nil); err != nil {
+			return nil, fmt.Errorf("failed to subscribe to retry topics: %w", err)
+		}
 	}
 	dlq, err := NewProducer(config.DLQ, logger)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create DLQ: %w", err)
 	}
-	handler := newHandler()
+	handler := newHandler(engine)
+	queues := make([]chan workItem, max(config.Workers, 1))
+	for i := range queues {
+		queues[i] = make(chan workItem, config.QueueSize)
+	}
 	return &KafkaConsumer{
 		consumer:                 kafkaConsumer,
+		topics:                   topics,
+		queues:                   queues,
+		firstOffsets:             make(map[string]kafka.Offset),
+		waiting:                  make(map[string]backlog),
+		outages:                  make(chan struct{}, 1),
+		recovered:                make(chan struct{}, 1),
+		retries:                  retries,
+		retryTopics:              config.RetryTopics,
 		handler:                  handler,
 		dlq:                      dlq,
 		dlqTopic:                 config.DLQ.Topic,
+		statusTopic:              config.StatusTopic,
 		saveOrderRetryDelay:      config.SaveOrderRetryDelay,
+		saveOrderRetryMaxDelay:   config.SaveOrderRetryMaxDelay,
 		saveOrderRetryMax:        config.SaveOrderRetryMax,
 		commitRetryDelay:         config.CommitRetryDelay,
 		commitRetryMax:           config.CommitRetryMax,
 		eventTypeErrorsMax:       config.EventTypeErrorsMax,
 		eventTypeErrorRetryDelay: config.EventTypeErrorRetryDelay,
 		dbConnectionCheckDelay:   config.DbConnectionCheckDelay,
+		batchSize:                config.BatchSize,
+		batchWait:                config.BatchWait,
 		notifier:                 notifier.NewNotifier(config.Notifier)}, nil
 }
 
@@ -97,17 +164,25 @@ toMap converts a consumer or producer configuration into a Kafka ConfigMap.
 
 This helper function maps internal configuration structs to
 the format expected by the Confluent Kafka Go client.
+The session timeout and max poll interval of a consumer are left to the client defaults unless configured.
 */
 func toMap(config any) *kafka.ConfigMap {
 	switch c := config.(type) {
 	case configs.Consumer:
-		return &kafka.ConfigMap{
+		configMap := &kafka.ConfigMap{
 			"bootstrap.servers":  strings.Join(c.Brokers, ","),
 			"group.id":           c.GroupID,
 			"auto.offset.reset":  c.Kafka.AutoOffsetReset,
 			"enable.auto.commit": c.Kafka.EnableAutoCommit,
 			"client.id":          c.ClientID,
 		}
+		if c.SessionTimeoutMs > 0 {
+			_ = configMap.SetKey("session.timeout.ms", c.SessionTimeoutMs)
+		}
+		if c.MaxPollIntervalMs > 0 {
+			_ = configMap.SetKey("max.poll.interval.ms", c.MaxPollIntervalMs)
+		}
+		return configMap
 	case configs.Producer:
 		var acksValue int
 		switch c.Kafka.Acks {
@@ -137,134 +212,244 @@ func toMap(config any) *kafka.ConfigMap {
 }
 
 /*
-Run starts the KafkaConsumer loop for a single worker.
+Run starts the KafkaConsumer loop for a single worker, processing the messages of the partitions
+the worker owns in the order Poll hands them over.
 
 Behavior:
-  - Polls messages from Kafka continuously.
-  - Processes each message with retries.
+  - Gathers orders into batches of up to batchSize messages, waiting at most batchWait for a batch
+    to fill up, and saves every batch together, see processBatch. A batch size of 1 disables batching.
+  - Processes status-change events one by one with retries, applying them to stored items and
+    invalidating the affected order in cache. A pending batch is saved first, so events never overtake
+    the orders they refer to.
+  - Commits redelivered orders that are already saved without retrying them.
+  - Sends messages that fail permanently (malformed JSON, failed validation, a broken rejecting business rule,
+    a conflict with an already saved order or violated DB constraints) straight to DLQ, see ErrorClass.
+  - Forwards messages that fail transiently to the first retry topic if retry topics are configured,
+    so a failing message never holds up the ones behind it, see RunRetries. Without retry topics,
+    retries transient failures in place with exponential backoff and jitter, and sends messages to DLQ
+    once the retries are exhausted.
+  - Saves a pending batch right away when Poll asks for its partitions to be drained before a rebalance.
   - Commits offsets with retries.
-  - Sends messages to DLQ if processing fails.
   - Logs errors and triggers notifier notifications for critical errors.
-  - Pauses order processing during database outages with periodic connection checks.
+  - Holds back the message it is at once the connection to the database is lost, see databaseDown,
+    has Poll pause the partitions and waits for the pause, see awaitPause. Drops the messages received
+    before the pause, they are received again once resumed.
   - Panics for unrecoverable errors, which may trigger worker self-termination.
+    A restarted worker carries on with the same queue.
 */
-func (c *KafkaConsumer) Run(ctx context.Context, storage repository.Storage, logger logger.Logger, workerID int, lastWorker *atomic.Int32) {
+func (c *KafkaConsumer) Run(ctx context.Context, storage repository.Storage, cache cache.Cache, logger logger.Logger, workerID int) {
 	logger.LogInfo(fmt.Sprintf("worker %d — receiving orders", workerID), "layer", "broker.kafka")
-	eventTypeErrors := 0
+	queue := c.queues[workerID-1]
+	batch := new(orderBatch)
 	for {
+		if len(batch.messages) > 0 && batch.generation != c.generation.Load() {
+			batch.take() // received before a pause, received again once resumed
+		}
+		if batch.due(c.batchSize) {
+			c.saveBatch(ctx, batch, queue, storage, logger, workerID)
+			continue
+		}
 		select {
 		case <-ctx.Done():
-			if lastWorker.Load() == int32(1) { // I do realize how utterly retarded this is
-				c.dlq.Close() // should've delegated DLQ management to the Consumer, not embedded it in each worker
-				logger.LogInfo(fmt.Sprintf("worker %d — DLQ closed", workerID), "workerID", fmt.Sprintf("%d", workerID), "layer", "broker.kafka")
-			} else {
-				lastWorker.Add(-1)
+			if len(batch.messages) > 0 {
+				logger.LogInfo(fmt.Sprintf("worker %d — shutdown interrupted a batch of %d orders, it will be redelivered", workerID, len(batch.messages)), "workerID", fmt.Sprintf("%d", workerID), "layer", "broker.kafka")
 			}
 			return
-		default:
-			event := c.consumer.Poll(100)
-			if event == nil {
+		case <-batch.timer():
+		case item := <-queue:
+			if item.drained != nil {
+				generation := batch.generation
+				heldBack := len(batch.messages) > 0 && generation == c.generation.Load() &&
+					c.processBatch(ctx, batch.take(), storage, logger, workerID)
+				close(item.drained) // Poll can't pause before the drain is over
+				if heldBack {
+					c.awaitPause(ctx, queue, generation)
+				}
 				continue
 			}
-			switch eventType := event.(type) {
-			case *kafka.Message:
-				logger.Debug(fmt.Sprintf("worker %d — received a new order from Kafka, will try saving it", workerID), "workerID", fmt.Sprintf("%d", workerID), "layer", "broker.kafka")
-				eventTypeErrors = 0
-				var lastErr error
-				var notified bool
-				retryCnt := 0
-				for retryCnt < c.saveOrderRetryMax {
-					if err := c.handler.SaveOrder(eventType.Value, storage, logger, workerID); err != nil {
-						if strings.Contains(err.Error(), "connection refused") {
-							if !notified {
-								logger.LogInfo(fmt.Sprintf("worker %d — lost connection to database, order processing paused", workerID), "layer", "broker.kafka")
-								_ = c.notifier.Notify(fmt.Sprintf("CRITICAL ERROR — database connection lost, consumer worker %d paused", workerID))
-								notified = true
-							}
-							time.Sleep(c.dbConnectionCheckDelay)
-							continue
-						}
-						notified = false
-						lastErr = err
-						retryCnt++
-						if retryCnt < c.saveOrderRetryMax {
-							time.Sleep(c.saveOrderRetryDelay)
-							continue
-						}
-						break
-					}
-					if err := c.commitWithRetry(eventType); err != nil {
-						logger.LogError(fmt.Sprintf("worker %d — critical error", workerID), err, "orderUID", ToStr(eventType.Key), "workerID", fmt.Sprintf("%d", workerID), "layer", "broker.kafka")
-						_ = c.notifier.Notify(fmt.Sprintf("CRITICAL ERROR — Kafka commit failed\nworkerID=%d\norderUID=%s", workerID, ToStr(eventType.Key)))
-						panic(fmt.Sprintf("worker self-termination: offset commit failed (workerID=%d, orderUID=%s)", workerID, ToStr(eventType.Key)))
-					}
-					break
-				}
-				if retryCnt >= c.saveOrderRetryMax {
-					logger.LogError(fmt.Sprintf("worker %d — failed to process order after %d retries", workerID, c.saveOrderRetryMax), lastErr, "orderUID", ToStr(eventType.Key), "workerID", fmt.Sprintf("%d", workerID), "layer", "broker.kafka")
-					c.sendToDLQ(eventType, retryCnt, workerID)
-				}
-			case kafka.Error:
-				eventTypeErrors++
-				logger.LogError("consumer — event type error", eventType, "layer", "broker.kafka")
-				if eventTypeErrors > c.eventTypeErrorsMax {
-					_ = c.notifier.Notify(fmt.Sprintf("CRITICAL ERROR — Kafka broker is unreachable\nworkerID=%d", workerID))
-					panic(fmt.Sprintf("worker self-termination: kafka is down (workerID=%d)", workerID))
+			if item.generation != c.generation.Load() {
+				continue // received before a pause, received again once resumed
+			}
+			if c.batchSize > 1 && !c.isStatusMessage(item.msg) {
+				if len(batch.messages) == 0 {
+					batch.generation = item.generation
 				}
-				time.Sleep(c.eventTypeErrorRetryDelay)
+				batch.add(item.msg, c.batchWait)
+				continue
+			}
+			if len(batch.messages) > 0 && c.saveBatch(ctx, batch, queue, storage, logger, workerID) {
+				continue // received again once resumed
+			}
+			if c.processMessage(ctx, item.msg, storage, cache, logger, workerID) {
+				c.awaitPause(ctx, queue, item.generation)
+			}
+		}
+	}
+}
+
+// processMessage handles a single message, retrying transient failures, then commits it
+// or sends it along the retry chain or to DLQ. Reports whether the message is held back for a database outage.
+func (c *KafkaConsumer) processMessage(ctx context.Context, msg *kafka.Message, storage repository.Storage, cache cache.Cache, logger logger.Logger, workerID int) bool {
+	logger.Debug(fmt.Sprintf("worker %d — received a new order from Kafka, will try saving it", workerID), "workerID", fmt.Sprintf("%d", workerID), "layer", "broker.kafka")
+	var failure failure
+	var rejected bool
+	for failure.retryCnt < c.saveOrderRetryMax {
+		err := c.handle(ctx, msg, storage, cache, logger, workerID)
+		if ctx.Err() != nil {
+			logger.LogInfo(fmt.Sprintf("worker %d — shutdown interrupted saving the order, it will be redelivered", workerID), "orderUID", ToStr(msg.Key), "workerID", fmt.Sprintf("%d", workerID), "layer", "broker.kafka")
+			return false
+		}
+		if errors.Is(err, errs.ErrDuplicate) {
+			logger.Debug(fmt.Sprintf("worker %d — order is already saved, skipping redelivered message", workerID), "orderUID", ToStr(msg.Key), "workerID", fmt.Sprintf("%d", workerID), "layer", "broker.kafka")
+			err = nil
+		}
+		if err != nil {
+			failure.failed(err, time.Now())
+			if class := Classify(err); class.Permanent() {
+				logger.LogError(fmt.Sprintf("worker %d — order can never be saved, retries skipped", workerID), err, "orderUID", ToStr(msg.Key), "errorClass", string(class), "workerID", fmt.Sprintf("%d", workerID), "layer", "broker.kafka")
+				rejected = true
+				break
+			}
+			if c.databaseDown(ctx, err, storage) {
+				logger.LogInfo(fmt.Sprintf("worker %d — lost connection to database, order is held back", workerID), "orderUID", ToStr(msg.Key), "workerID", fmt.Sprintf("%d", workerID), "layer", "broker.kafka")
+				c.reportOutage()
+				return true
+			}
+			if len(c.retryTopics) > 0 {
+				logger.LogError(fmt.Sprintf("worker %d — failed to process order, forwarding it to retry topic %s", workerID, c.retryTopics[0].Topic), err, "orderUID", ToStr(msg.Key), "errorClass", string(Classify(err)), "workerID", fmt.Sprintf("%d", workerID), "layer", "broker.kafka")
+				rejected = true
+				break
 			}
+			failure.retryCnt++
+			if failure.retryCnt < c.saveOrderRetryMax {
+				time.Sleep(c.retryDelay(failure.retryCnt))
+				continue
+			}
+			break
+		}
+		if err := commitWithRetry(c.consumer, msg, c.commitRetryMax, c.commitRetryDelay); err != nil {
+			logger.LogError(fmt.Sprintf("worker %d — critical error", workerID), err, "orderUID", ToStr(msg.Key), "workerID", fmt.Sprintf("%d", workerID), "layer", "broker.kafka")
+			_ = c.notifier.Notify(fmt.Sprintf("CRITICAL ERROR — Kafka commit failed\nworkerID=%d\norderUID=%s", workerID, ToStr(msg.Key)))
+			panic(fmt.Sprintf("worker self-termination: offset commit failed (workerID=%d, orderUID=%s)", workerID, ToStr(msg.Key)))
+		}
+		break
+	}
+	if rejected {
+		c.sendFailed(msg, failure, workerID)
+	} else if failure.retryCnt >= c.saveOrderRetryMax {
+		logger.LogError(fmt.Sprintf("worker %d — failed to process order after %d retries", workerID, c.saveOrderRetryMax), failure.err, "orderUID", ToStr(msg.Key), "workerID", fmt.Sprintf("%d", workerID), "layer", "broker.kafka")
+		c.sendFailed(msg, failure, workerID)
+	}
+	return false
+}
+
+/*
+databaseDown reports whether err means the connection to the database is lost, which pauses the consumer.
+
+Storage reports errs.ErrUnavailable as well when the database turns work away for a while but still answers,
+e.g. when it runs out of resources, an operator cancels the session or an SQLite file is busy or locked.
+These are retried as transient failures: the connection is confirmed lost only if storage fails Ping too.
+The database health monitor resumes the consumer as soon as Ping succeeds, see Resume.
+*/
+func (c *KafkaConsumer) databaseDown(ctx context.Context, err error, storage repository.Storage) bool {
+	return errors.Is(err, errs.ErrUnavailable) && storage.Ping(ctx) != nil
+}
+
+/*
+retryDelay returns how long to wait before retry number retryCnt (counting from 1) of a transient failure.
+
+The delay starts at saveOrderRetryDelay and doubles on every retry, up to saveOrderRetryMaxDelay.
+A random half of it is jitter, so workers that failed together don't retry in lockstep.
+*/
+func (c *KafkaConsumer) retryDelay(retryCnt int) time.Duration {
+	delay := c.saveOrderRetryDelay
+	for i := 1; i < retryCnt; i++ {
+		if c.saveOrderRetryMaxDelay > 0 && delay >= c.saveOrderRetryMaxDelay/2 {
+			delay = c.saveOrderRetryMaxDelay
+			break
 		}
+		delay *= 2
+	}
+	if c.saveOrderRetryMaxDelay > 0 {
+		delay = min(delay, c.saveOrderRetryMaxDelay)
+	}
+	if delay <= 0 {
+		return 0
+	}
+	return delay/2 + rand.N(delay-delay/2+1)
+}
+
+// handle dispatches a message to the handler that matches its topic.
+func (c *KafkaConsumer) handle(ctx context.Context, msg *kafka.Message, storage repository.Storage, cache cache.Cache, logger logger.Logger, workerID int) error {
+	if c.isStatusMessage(msg) {
+		return c.handler.UpdateItemStatus(ctx, msg.Value, storage, cache, logger, workerID)
 	}
+	return c.handler.SaveOrder(ctx, msg, storage, logger, workerID)
+}
+
+// isStatusMessage reports whether msg comes from the status-update topic, directly or through a retry topic.
+func (c *KafkaConsumer) isStatusMessage(msg *kafka.Message) bool {
+	return c.statusTopic != "" && c.originalTopic(msg) == c.statusTopic
 }
 
 /*
-commitWithRetry attempts to commit a Kafka message offset multiple times.
+commitWithRetry attempts to commit a Kafka message offset of consumer multiple times.
 
-It retries up to commitRetryMax times with a configured delay between attempts.
+It retries up to commitRetryMax times with commitRetryDelay between attempts.
 Returns an error if the commit fails after all retries.
 */
-func (c *KafkaConsumer) commitWithRetry(msg *kafka.Message) error {
+func commitWithRetry(consumer *kafka.Consumer, msg *kafka.Message, commitRetryMax int, commitRetryDelay time.Duration) error {
 	var err error
-	for range c.commitRetryMax {
-		if _, err = c.consumer.CommitMessage(msg); err != nil {
-			time.Sleep(c.commitRetryDelay)
+	for range commitRetryMax {
+		if _, err = consumer.CommitMessage(msg); err != nil {
+			time.Sleep(commitRetryDelay)
 		} else {
 			return nil
 		}
 	}
-	return fmt.Errorf("failed to commit offset after %d attempts: %w", c.commitRetryMax, err)
+	return fmt.Errorf("failed to commit offset after %d attempts: %w", commitRetryMax, err)
 }
 
 /*
-sendToDLQ sends a failed message to the dead-letter queue (DLQ).
+sendFailed sends a failed message along the retry chain or to the dead-letter queue (DLQ), see forwardFailed.
 
-It attempts to produce the message to the DLQ and commit its offset.
+It attempts to produce the message and commit its offset.
 If either action fails, it logs the error, notifies via the notifier,
-and panics to trigger worker self-termination.
+and panics to trigger worker self-termination. The message carries the headers describing
+how it failed, see failureHeaders.
 
 This self-termination ensures that the worker does not keep consuming CPU
 in a tight loop when Kafka is down or offset commits repeatedly fail,
 allowing the orchestration layer to handle restart or shutdown.
 */
-func (c *KafkaConsumer) sendToDLQ(eventType *kafka.Message, retryCnt int, workerID int) {
-	headers := make(map[string]string)
+func (c *KafkaConsumer) sendFailed(eventType *kafka.Message, failure failure, workerID int) {
+	c.forwardFailed(eventType, failure, workerID)
+	if err := commitWithRetry(c.consumer, eventType, c.commitRetryMax, c.commitRetryDelay); err != nil {
+		_ = c.notifier.Notify(fmt.Sprintf("CRITICAL ERROR — failed order sent but offset commit failed\nworkerID=%d\norderUID=%s", workerID, ToStr(eventType.Key)))
+		panic(fmt.Sprintf("worker self-termination: failed order sent but offset commit failed (workerID=%d, orderUID=%s)", workerID, ToStr(eventType.Key)))
+	}
+}
+
+// produceFailed sends a failed message to topic, the DLQ or a retry topic, without committing its offset.
+// Messages for a retry topic are due at notBefore. Panics if the message could not be sent, see sendFailed.
+func (c *KafkaConsumer) produceFailed(eventType *kafka.Message, failure failure, workerID int, topic string, notBefore time.Time) {
+	headers := failureHeaders(eventType, failure, workerID)
+	if !notBefore.IsZero() {
+		headers[HeaderNotBefore] = notBefore.UTC().Format(time.RFC3339Nano)
+	}
 	msg := configs.Message{
-		Topic:     c.dlqTopic,
+		Topic:     topic,
 		Key:       eventType.Key,
 		Value:     eventType.Value,
 		Headers:   headers,
 		Timestamp: eventType.Timestamp,
-		Metadata:  map[string]any{"retryCount": retryCnt},
-		DLQ:       true,
+		Metadata:  map[string]any{"retryCount": failure.retryCnt},
+		DLQ:       topic == c.dlqTopic,
 		WorkerID:  workerID,
 	}
 	if err := c.dlq.Produce(msg); err != nil {
-		_ = c.notifier.Notify(fmt.Sprintf("CRITICAL ERROR — failed to send order to DLQ\nworkerID=%d\norderUID=%s", workerID, ToStr(eventType.Key)))
-		panic(fmt.Sprintf("worker self-termination: failed to send order to DLQ (workerID=%d, orderUID=%s)", workerID, ToStr(eventType.Key)))
-	}
-	if err := c.commitWithRetry(eventType); err != nil {
-		_ = c.notifier.Notify(fmt.Sprintf("CRITICAL ERROR — order sent to DLQ but offset commit failed\nworkerID=%d\norderUID=%s", workerID, ToStr(eventType.Key)))
-		panic(fmt.Sprintf("worker self-termination: order sent to DLQ but offset commit failed (workerID=%d, orderUID=%s)", workerID, ToStr(eventType.Key)))
+		_ = c.notifier.Notify(fmt.Sprintf("CRITICAL ERROR — failed to send order to %s\nworkerID=%d\norderUID=%s", topic, workerID, ToStr(eventType.Key)))
+		panic(fmt.Sprintf("worker self-termination: failed to send order to %s (workerID=%d, orderUID=%s)", topic, workerID, ToStr(eventType.Key)))
 	}
 }
 
@@ -282,12 +467,20 @@ func ToStr(key []byte) string {
 /*
 Close terminates the Kafka consumer instance.
 
-It releases all resources, stops receiving messages,
-and logs any errors encountered during shutdown.
+It releases all resources, including the DLQ producer, stops receiving messages,
+and logs any errors encountered during shutdown. It must be called only after Poll, RunRetries
+and every worker have returned.
 */
 func (c *KafkaConsumer) Close(logger logger.Logger) {
 	if err := c.consumer.Close(); err != nil {
 		logger.LogError("consumer — failed to stop properly", err, "layer", "broker.kafka")
 	}
+	if c.retries != nil {
+		if err := c.retries.Close(); err != nil {
+			logger.LogError("consumer — failed to stop the retry consumer properly", err, "layer", "broker.kafka")
+		}
+	}
+	c.dlq.Close()
+	logger.LogInfo("consumer — DLQ closed", "layer", "broker.kafka")
 	logger.LogInfo("consumer — stopped receiving orders", "layer", "broker.kafka")
 }
@@ -3,12 +3,12 @@ package kafka_test
 import (
 	"context"
 	"encoding/json"
-	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/cmd/producer/order"
 	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/broker"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/cache"
 	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/configs"
 	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/models"
 	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository"
@@ -40,6 +40,8 @@ func TestKafkaConsumer_Integration(t *testing.T) {
 			SaveOrderRetryMax:   2,
 			CommitRetryDelay:    100 * time.Millisecond,
 			CommitRetryMax:      2,
+			Workers:             2,
+			QueueSize:           10,
 			DLQ: configs.Producer{
 				Brokers:       []string{"localhost:9092"},
 				Topic:         "test-dlq",
@@ -63,7 +65,7 @@ func TestKafkaConsumer_Integration(t *testing.T) {
 	if err != nil {
 		t.Fatalf("failed to connect to test DB: %v", err)
 	}
-	storage := postgres.NewStorage(db, log)
+	storage := postgres.NewStorage(db, configs.Database{}, log)
 	time.Sleep(5 * time.Second)
 
 	producer, err := kafka.NewProducer(&kafka.ConfigMap{
@@ -122,19 +124,21 @@ func TestKafkaConsumer_Integration(t *testing.T) {
 
 	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
 	defer cancel()
-	var lastWorker atomic.Int32
-	lastWorker.Add(2)
-	go kc.Run(ctx, storage, log, 1, &lastWorker)
-	go kc.Run(ctx, storage, log, 2, &lastWorker)
+	cache := cache.NewCache(ctx, storage, configs.Cache{}, log)
+	go kc.Poll(ctx, log)
+	go kc.Run(ctx, storage, cache, log, 1)
+	go kc.Run(ctx, storage, cache, log, 2)
 	producer.Flush(7000)
 
 	time.Sleep(10 * time.Second)
 
-	_, err = storage.GetOrder(order.OrderUID)
+	_, err = storage.GetOrder(context.Background(), order.OrderUID)
 	if err != nil {
 		t.Fatalf("failed to fetch order: %v", err)
 	}
 
+	cancel()
+	time.Sleep(time.Second)
 	kc.Close(log)
 
 }
@@ -0,0 +1,380 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/pkg/logger"
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+// workItem is what a worker receives through its queue: a message to process,
+// or a request to finish everything received before it (drained is closed once done).
+type workItem struct {
+	msg        *kafka.Message
+	generation uint64 // of the message, see KafkaConsumer.generation
+	drained    chan struct{}
+}
+
+// backlog holds the messages of a paused partition, in order, until the queue of their worker has room for them.
+type backlog []*kafka.Message
+
+/*
+Poll subscribes to the topics and polls them until ctx is cancelled, handing every message
+to the queue of the worker its partition belongs to, see Run.
+
+Behavior:
+  - Partitions are spread over the workers as they are assigned, so the messages of a partition
+    are always processed by one worker, in order. Workers left without a partition stay idle.
+  - Before partitions are revoked, waits for the workers to finish and commit the messages
+    they have already received, so they are not processed twice by the next owner of the partition.
+  - Never waits for a worker that falls behind: once its queue is full, the partition of the message
+    is paused until the queue has room again, see dispatch. The other partitions keep flowing.
+  - Pauses the assigned partitions once a worker runs into a database outage, and rewinds them
+    to the messages that are not saved yet, see pause. Keeps polling meanwhile, so the consumer
+    stays in the group, and resumes the partitions once the database is back, see Resume.
+  - Panics once broker errors exceed eventTypeErrorsMax in a row.
+*/
+func (c *KafkaConsumer) Poll(ctx context.Context, logger logger.Logger) {
+	if err := c.consumer.SubscribeTopics(c.topics, c.rebalance(ctx, logger)); err != nil {
+		logger.LogError("consumer — failed to subscribe to topics", err, "layer", "broker.kafka")
+		panic(fmt.Sprintf("consumer self-termination: failed to subscribe to topics %v", c.topics))
+	}
+	logger.LogInfo("consumer — receiving orders", "layer", "broker.kafka")
+	eventTypeErrors := 0
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-c.outages:
+			if !c.paused {
+				c.pause(ctx, logger)
+			}
+		case <-c.recovered:
+			if c.paused {
+				c.resume(logger)
+			}
+		default:
+			c.dispatchWaiting(logger)
+			switch eventType := c.consumer.Poll(100).(type) {
+			case *kafka.Message:
+				eventTypeErrors = 0
+				if c.paused { // fetched before the pause, received again once resumed
+					if err := c.consumer.Seek(eventType.TopicPartition, 0); err != nil {
+						logger.LogError("consumer — failed to rewind partition", err, "orderUID", ToStr(eventType.Key), "layer", "broker.kafka")
+					}
+					continue
+				}
+				c.dispatch(eventType, logger)
+			case kafka.Error:
+				eventTypeErrors++
+				logger.LogError("consumer — event type error", eventType, "layer", "broker.kafka")
+				if eventTypeErrors > c.eventTypeErrorsMax {
+					_ = c.notifier.Notify("CRITICAL ERROR — Kafka broker is unreachable")
+					panic("consumer self-termination: kafka is down")
+				}
+				time.Sleep(c.eventTypeErrorRetryDelay)
+			}
+		}
+	}
+}
+
+/*
+dispatch hands msg to the queue of the worker its partition belongs to.
+
+If the queue is full, the partition is paused and msg waits, along with the messages of the partition
+fetched before the pause, until dispatchWaiting finds room for them. Messages never overtake each other.
+*/
+func (c *KafkaConsumer) dispatch(msg *kafka.Message, logger logger.Logger) {
+	key := partitionKey(msg.TopicPartition)
+	if _, ok := c.firstOffsets[key]; !ok {
+		c.firstOffsets[key] = msg.TopicPartition.Offset
+	}
+	if waiting, ok := c.waiting[key]; ok {
+		c.waiting[key] = append(waiting, msg)
+		return
+	}
+	if c.enqueue(key, msg) {
+		return
+	}
+	c.waiting[key] = backlog{msg}
+	partition := kafka.TopicPartition{Topic: msg.TopicPartition.Topic, Partition: msg.TopicPartition.Partition}
+	if err := c.consumer.Pause([]kafka.TopicPartition{partition}); err != nil {
+		logger.LogError("consumer — failed to pause partition of a busy worker", err, "partition", key, "layer", "broker.kafka")
+	}
+}
+
+// enqueue hands msg to the queue of the worker its partition belongs to, unless the queue is full.
+func (c *KafkaConsumer) enqueue(key string, msg *kafka.Message) bool {
+	owner, ok := c.owners[key]
+	if !ok { // assignments are always known before their messages, but never drop a message
+		owner = int(msg.TopicPartition.Partition) % len(c.queues)
+	}
+	select {
+	case c.queues[owner] <- workItem{msg: msg, generation: c.generation.Load()}:
+		return true
+	default:
+		return false
+	}
+}
+
+// dispatchWaiting hands the waiting messages to the queues that have room for them,
+// and resumes every partition whose messages are all handed over.
+func (c *KafkaConsumer) dispatchWaiting(logger logger.Logger) {
+	for key, messages := range c.waiting {
+		partition := kafka.TopicPartition{Topic: messages[0].TopicPartition.Topic, Partition: messages[0].TopicPartition.Partition}
+		for len(messages) > 0 && c.enqueue(key, messages[0]) {
+			messages = messages[1:]
+		}
+		if len(messages) > 0 {
+			c.waiting[key] = messages
+			continue
+		}
+		delete(c.waiting, key)
+		if err := c.consumer.Resume([]kafka.TopicPartition{partition}); err != nil {
+			logger.LogError("consumer — failed to resume partition", err, "partition", key, "layer", "broker.kafka")
+		}
+	}
+}
+
+// forgetWaiting drops the waiting messages of partitions, which are not committed and are received again
+// by their next owner. Partitions paused for a busy worker are resumed, unless a database outage keeps them paused.
+func (c *KafkaConsumer) forgetWaiting(partitions []kafka.TopicPartition, logger logger.Logger) {
+	for _, partition := range partitions {
+		key := partitionKey(partition)
+		if _, ok := c.waiting[key]; !ok {
+			continue
+		}
+		delete(c.waiting, key)
+		if c.paused {
+			continue
+		}
+		if err := c.consumer.Resume([]kafka.TopicPartition{{Topic: partition.Topic, Partition: partition.Partition}}); err != nil {
+			logger.LogError("consumer — failed to resume partition", err, "partition", key, "layer", "broker.kafka")
+		}
+	}
+}
+
+/*
+pause stops receiving messages during a database outage a worker has run into:
+
+  - Pauses every assigned partition, so polling goes on without fetching anything.
+  - Makes the workers drop the messages they have received but not started on, and waits for them
+    to finish the ones they are at, which fail fast during the outage. Messages waiting for a busy worker
+    are dropped as well.
+  - Rewinds every partition to its committed offset, that is to the first message not saved yet,
+    see rewind. Messages saved but not committed yet are received again and skipped as redelivered.
+*/
+func (c *KafkaConsumer) pause(ctx context.Context, logger logger.Logger) {
+	select {
+	case <-c.recovered: // reported before the outage
+	default:
+	}
+	partitions, err := c.consumer.Assignment()
+	if err != nil {
+		logger.LogError("consumer — failed to get assigned partitions", err, "layer", "broker.kafka")
+	}
+	if err := c.consumer.Pause(partitions); err != nil {
+		logger.LogError("consumer — failed to pause partitions", err, "layer", "broker.kafka")
+	}
+	c.paused = true
+	c.nextGeneration()
+	clear(c.waiting)
+	c.drain(ctx, partitions)
+	select {
+	case <-c.outages: // reported by other workers meanwhile
+	default:
+	}
+	c.rewind(partitions, logger)
+	logger.LogInfo(fmt.Sprintf("consumer — lost connection to database, %d partitions paused", len(partitions)), "layer", "broker.kafka")
+	_ = c.notifier.Notify("CRITICAL ERROR — database connection lost, consumer paused")
+}
+
+/*
+rewind seeks partitions back to their committed offsets, so their messages are received again
+once they are resumed. A partition nothing has been committed for yet goes back to the first message
+received since it was assigned, a partition with no message received yet is left as it is.
+*/
+func (c *KafkaConsumer) rewind(partitions []kafka.TopicPartition, logger logger.Logger) {
+	committed, err := c.consumer.Committed(partitions, int(readerTimeout.Milliseconds()))
+	if err != nil {
+		logger.LogError("consumer — failed to get committed offsets", err, "layer", "broker.kafka")
+		committed = partitions
+	}
+	for _, partition := range committed {
+		if partition.Offset < 0 {
+			offset, ok := c.firstOffsets[partitionKey(partition)]
+			if !ok {
+				continue
+			}
+			partition.Offset = offset
+		}
+		if err := c.consumer.Seek(partition, 0); err != nil {
+			logger.LogError("consumer — failed to rewind partition", err, "topic", *partition.Topic, "partition", fmt.Sprintf("%d", partition.Partition), "layer", "broker.kafka")
+		}
+	}
+}
+
+// resume resumes the assigned partitions once the database is back.
+func (c *KafkaConsumer) resume(logger logger.Logger) {
+	partitions, err := c.consumer.Assignment()
+	if err != nil {
+		logger.LogError("consumer — failed to get assigned partitions", err, "layer", "broker.kafka")
+	}
+	if err := c.consumer.Resume(partitions); err != nil {
+		logger.LogError("consumer — failed to resume partitions", err, "layer", "broker.kafka")
+		return
+	}
+	c.paused = false
+	logger.LogInfo(fmt.Sprintf("consumer — database is back, %d partitions resumed", len(partitions)), "layer", "broker.kafka")
+}
+
+/*
+Resume lets the consumer receive messages again if a database outage has paused it.
+It is meant to be called by a database health monitor whenever the database is reachable,
+and does nothing if the consumer is not paused.
+*/
+func (c *KafkaConsumer) Resume() {
+	select {
+	case c.recovered <- struct{}{}:
+	default:
+	}
+}
+
+// reportOutage tells the poll loop that a worker has run into a database outage, see pause.
+func (c *KafkaConsumer) reportOutage() {
+	select {
+	case c.outages <- struct{}{}:
+	default:
+	}
+}
+
+// nextGeneration bumps the generation, so the workers drop the messages received so far,
+// and releases the workers held for the pause, see awaitPause.
+func (c *KafkaConsumer) nextGeneration() {
+	c.pauseMu.Lock()
+	defer c.pauseMu.Unlock()
+	c.generation.Add(1)
+	if c.nextPause != nil {
+		close(c.nextPause)
+		c.nextPause = nil
+	}
+}
+
+/*
+awaitPause holds a worker that has held back a message of the given generation for a database outage
+until Poll has paused the partitions, see pause. Returns right away if the partitions have been paused since.
+
+The worker keeps reading its queue meanwhile:
+  - Messages are dropped: saving and committing a later message of the partition of the held back one
+    would move the committed offset past it, and the partition would be rewound beyond it.
+    They are received again once the partitions are rewound, or by the next owner of a revoked partition.
+  - Drain requests are answered right away, there is nothing left to finish. A rebalance can revoke
+    the partitions before Poll gets to the pause, and the revoke must not wait for the pause.
+*/
+func (c *KafkaConsumer) awaitPause(ctx context.Context, queue <-chan workItem, generation uint64) {
+	c.pauseMu.Lock()
+	if c.generation.Load() != generation {
+		c.pauseMu.Unlock()
+		return
+	}
+	if c.nextPause == nil {
+		c.nextPause = make(chan struct{})
+	}
+	paused := c.nextPause
+	c.pauseMu.Unlock()
+	for {
+		select {
+		case <-paused:
+			return
+		case <-ctx.Done():
+			return
+		case item := <-queue:
+			if item.drained != nil {
+				close(item.drained)
+			}
+		}
+	}
+}
+
+// rebalance returns the rebalance callback of the consumer. It runs within Poll,
+// and the client assigns or revokes the partitions itself once it returns.
+// Partitions assigned during a database outage are assigned here and paused right away.
+func (c *KafkaConsumer) rebalance(ctx context.Context, logger logger.Logger) kafka.RebalanceCb {
+	return func(consumer *kafka.Consumer, event kafka.Event) error {
+		switch eventType := event.(type) {
+		case kafka.AssignedPartitions:
+			c.assign(eventType.Partitions)
+			if c.paused {
+				if err := consumer.Assign(eventType.Partitions); err != nil {
+					return err
+				}
+				if err := consumer.Pause(eventType.Partitions); err != nil {
+					logger.LogError("consumer — failed to pause assigned partitions", err, "layer", "broker.kafka")
+				}
+			}
+			logger.LogInfo(fmt.Sprintf("consumer — %d partitions assigned", len(eventType.Partitions)), "layer", "broker.kafka")
+		case kafka.RevokedPartitions:
+			c.forgetWaiting(eventType.Partitions, logger)
+			c.drain(ctx, eventType.Partitions)
+			logger.LogInfo(fmt.Sprintf("consumer — %d partitions revoked", len(eventType.Partitions)), "layer", "broker.kafka")
+		}
+		return nil
+	}
+}
+
+// assign spreads partitions over the worker queues, one by one in topic and partition order.
+func (c *KafkaConsumer) assign(partitions []kafka.TopicPartition) {
+	keys := make([]string, 0, len(partitions))
+	for _, partition := range partitions {
+		keys = append(keys, partitionKey(partition))
+	}
+	slices.Sort(keys)
+	c.firstOffsets = make(map[string]kafka.Offset)
+	c.owners = make(map[string]int, len(keys))
+	for i, key := range keys {
+		c.owners[key] = i % len(c.queues)
+	}
+}
+
+/*
+drain waits until the workers owning partitions have processed and committed every message
+they have received, including pending batches. Gives up once ctx is cancelled:
+workers stop on shutdown, and whatever they haven't committed is redelivered.
+*/
+func (c *KafkaConsumer) drain(ctx context.Context, partitions []kafka.TopicPartition) {
+	owners := make(map[int]bool)
+	for _, partition := range partitions {
+		if owner, ok := c.owners[partitionKey(partition)]; ok {
+			owners[owner] = true
+		}
+	}
+	var pending []chan struct{}
+	for owner := range owners {
+		drained := make(chan struct{})
+		select {
+		case c.queues[owner] <- workItem{drained: drained}:
+			pending = append(pending, drained)
+		case <-ctx.Done():
+			return
+		}
+	}
+	for _, drained := range pending {
+		select {
+		case <-drained:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// partitionKey identifies a partition of a topic.
+func partitionKey(partition kafka.TopicPartition) string {
+	var topic string
+	if partition.Topic != nil {
+		topic = *partition.Topic
+	}
+	return fmt.Sprintf("%s/%d", topic, partition.Partition)
+}
@@ -0,0 +1,453 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	mock_cache "github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/cache/mocks"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/configs"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/models"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository/errs"
+	mock_repository "github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository/mocks"
+	mock_logger "github.com/Pur1st2EpicONE/WBTECH-sample-microservice/pkg/logger/mocks"
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/golang/mock/gomock"
+)
+
+func TestKafkaConsumer_assign(t *testing.T) {
+	orders, status := "orders", "order-status"
+	consumer := &KafkaConsumer{queues: make([]chan workItem, 2)}
+	consumer.assign([]kafka.TopicPartition{{Topic: &orders, Partition: 1}, {Topic: &status, Partition: 0}, {Topic: &orders, Partition: 0}})
+	want := map[string]int{"order-status/0": 0, "orders/0": 1, "orders/1": 0}
+	for key, owner := range want {
+		if consumer.owners[key] != owner {
+			t.Errorf("partition %s: expected worker queue %d, got %d", key, owner, consumer.owners[key])
+		}
+	}
+
+	consumer = &KafkaConsumer{queues: make([]chan workItem, 4)}
+	consumer.assign([]kafka.TopicPartition{{Topic: &orders, Partition: 0}, {Topic: &orders, Partition: 1}})
+	if consumer.owners["orders/0"] == consumer.owners["orders/1"] {
+		t.Fatalf("expected every partition to get a worker of its own, got %v", consumer.owners)
+	}
+}
+
+func TestKafkaConsumer_drain(t *testing.T) {
+	orders := "orders"
+	consumer := &KafkaConsumer{queues: []chan workItem{make(chan workItem, 1), make(chan workItem, 1)},
+		owners: map[string]int{"orders/0": 1}}
+	consumer.queues[1] <- workItem{msg: testMessage(orders, 0, 1)}
+
+	drained := make(chan struct{})
+	go func() {
+		consumer.drain(context.Background(), []kafka.TopicPartition{{Topic: &orders, Partition: 0}})
+		close(drained)
+	}()
+	if item := <-consumer.queues[1]; item.msg == nil {
+		t.Fatal("expected the message received before the drain first")
+	}
+	item := <-consumer.queues[1]
+	if item.drained == nil {
+		t.Fatal("expected a drain request after the message")
+	}
+	select {
+	case <-drained:
+		t.Fatal("drain must wait for the worker")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(item.drained)
+	<-drained
+	if len(consumer.queues[0]) != 0 {
+		t.Fatal("expected only the owner of the revoked partition to be drained")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	consumer.drain(ctx, []kafka.TopicPartition{{Topic: &orders, Partition: 0}}) // must not wait for stopped workers
+}
+
+func TestKafkaConsumer_dispatch_FullQueue(t *testing.T) {
+	client, err := kafka.NewConsumer(&kafka.ConfigMap{"bootstrap.servers": "localhost:9", "group.id": "test"})
+	if err != nil {
+		t.Fatalf("failed to create consumer: %v", err)
+	}
+	defer func() { _ = client.Close() }()
+	logger := mock_logger.NewMockLogger(gomock.NewController(t))
+	logger.EXPECT().LogError(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes() // the partitions are not assigned
+
+	orders := "orders"
+	consumer := &KafkaConsumer{consumer: client, queues: []chan workItem{make(chan workItem, 1), make(chan workItem, 1)},
+		firstOffsets: make(map[string]kafka.Offset), waiting: make(map[string]backlog)}
+	consumer.assign([]kafka.TopicPartition{{Topic: &orders, Partition: 0}, {Topic: &orders, Partition: 1}})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for offset := range kafka.Offset(3) {
+			consumer.dispatch(testMessage(orders, 0, offset), logger)
+		}
+		consumer.dispatch(testMessage(orders, 1, 0), logger)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("dispatch must not wait for a busy worker")
+	}
+	if len(consumer.waiting["orders/0"]) != 2 || len(consumer.queues[1]) != 1 {
+		t.Fatalf("expected the messages of the busy worker's partition to wait, got %d waiting", len(consumer.waiting["orders/0"]))
+	}
+
+	for want := range kafka.Offset(3) {
+		consumer.dispatchWaiting(logger)
+		item := <-consumer.queues[0]
+		if item.msg.TopicPartition.Offset != want {
+			t.Fatalf("expected offset %d, got %d", want, item.msg.TopicPartition.Offset)
+		}
+	}
+	consumer.dispatchWaiting(logger)
+	if len(consumer.waiting) != 0 {
+		t.Fatalf("expected no messages left waiting, got %v", consumer.waiting)
+	}
+}
+
+func TestKafkaConsumer_Poll(t *testing.T) {
+	cluster, err := kafka.NewMockCluster(1)
+	if err != nil {
+		t.Fatalf("failed to start mock cluster: %v", err)
+	}
+	defer cluster.Close()
+	if err := cluster.CreateTopic("orders", 2, 1); err != nil {
+		t.Fatalf("failed to create topic: %v", err)
+	}
+
+	producer, err := kafka.NewProducer(&kafka.ConfigMap{"bootstrap.servers": cluster.BootstrapServers()})
+	if err != nil {
+		t.Fatalf("failed to create producer: %v", err)
+	}
+	defer producer.Close()
+	topic := "orders"
+	const perPartition = 10
+	for i := range perPartition {
+		for partition := range int32(2) {
+			orderUID := fmt.Sprintf("b563feb7b2b84b%d%02d", partition, i)
+			if err := producer.Produce(&kafka.Message{TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: partition},
+				Key: []byte(orderUID), Value: validOrderJSON(t, orderUID)}, nil); err != nil {
+				t.Fatalf("failed to produce: %v", err)
+			}
+		}
+	}
+	producer.Flush(10000)
+
+	controller := gomock.NewController(t)
+	logger := mock_logger.NewMockLogger(controller)
+	logger.EXPECT().LogInfo(gomock.Any(), gomock.Any()).AnyTimes()
+	logger.EXPECT().Debug(gomock.Any(), gomock.Any()).AnyTimes()
+	storage := mock_repository.NewMockStorage(controller)
+	cache := mock_cache.NewMockCache(controller)
+
+	brokers := []string{cluster.BootstrapServers()}
+	consumer, err := NewConsumer(configs.Consumer{Brokers: brokers, Topic: "orders", GroupID: "test", CommitRetryMax: 1, BatchSize: 1, SaveOrderRetryMax: 1,
+		Workers: 3, QueueSize: 2,
+		DLQ: configs.Producer{Brokers: brokers, Topic: "orders-dlq", RetryAttempts: 1, EventTimeout: 10 * time.Second,
+			Kafka: &configs.KafkaProducer{Acks: "all", CompressionType: "none", BatchSize: 1000}},
+		Kafka: &configs.Kafka{AutoOffsetReset: "earliest"}}, logger)
+	if err != nil {
+		t.Fatalf("failed to create consumer: %v", err)
+	}
+
+	// more workers than partitions, each saving slower than orders arrive
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	var mu sync.Mutex
+	saved := make(map[int32][]int64)
+	storage.EXPECT().SaveOrder(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, order *models.Order) error {
+		time.Sleep(5 * time.Millisecond)
+		mu.Lock()
+		defer mu.Unlock()
+		saved[order.Ingest.Partition] = append(saved[order.Ingest.Partition], order.Ingest.Offset)
+		if len(saved[0])+len(saved[1]) == 2*perPartition {
+			cancel()
+		}
+		return nil
+	}).Times(2 * perPartition)
+
+	var running sync.WaitGroup
+	running.Add(4)
+	go func() {
+		defer running.Done()
+		consumer.Poll(ctx, logger)
+	}()
+	for workerID := 1; workerID <= 3; workerID++ {
+		go func() {
+			defer running.Done()
+			consumer.Run(ctx, storage, cache, logger, workerID)
+		}()
+	}
+	running.Wait()
+	consumer.Close(logger)
+
+	for partition, offsets := range saved {
+		for i, offset := range offsets {
+			if offset != int64(i) {
+				t.Fatalf("expected the orders of partition %d in order, got offsets %v", partition, offsets)
+			}
+		}
+	}
+}
+
+func TestKafkaConsumer_Poll_DatabaseOutage(t *testing.T) {
+	cluster, err := kafka.NewMockCluster(1)
+	if err != nil {
+		t.Fatalf("failed to start mock cluster: %v", err)
+	}
+	defer cluster.Close()
+	if err := cluster.CreateTopic("orders", 1, 1); err != nil {
+		t.Fatalf("failed to create topic: %v", err)
+	}
+
+	producer, err := kafka.NewProducer(&kafka.ConfigMap{"bootstrap.servers": cluster.BootstrapServers()})
+	if err != nil {
+		t.Fatalf("failed to create producer: %v", err)
+	}
+	defer producer.Close()
+	topic := "orders"
+	for i := range 3 {
+		orderUID := fmt.Sprintf("b563feb7b2b84b%02d", i)
+		if err := producer.Produce(&kafka.Message{TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: 0},
+			Key: []byte(orderUID), Value: validOrderJSON(t, orderUID)}, nil); err != nil {
+			t.Fatalf("failed to produce: %v", err)
+		}
+	}
+	producer.Flush(10000)
+
+	controller := gomock.NewController(t)
+	logger := mock_logger.NewMockLogger(controller)
+	logger.EXPECT().LogInfo(gomock.Any(), gomock.Any()).AnyTimes()
+	logger.EXPECT().Debug(gomock.Any(), gomock.Any()).AnyTimes()
+	storage := mock_repository.NewMockStorage(controller)
+	cache := mock_cache.NewMockCache(controller)
+
+	brokers := []string{cluster.BootstrapServers()}
+	consumer, err := NewConsumer(configs.Consumer{Brokers: brokers, Topic: "orders", GroupID: "test", CommitRetryMax: 1, BatchSize: 1, SaveOrderRetryMax: 1,
+		Workers: 1, QueueSize: 10, SessionTimeoutMs: 6000, MaxPollIntervalMs: 10000,
+		DLQ: configs.Producer{Brokers: brokers, Topic: "orders-dlq", RetryAttempts: 1, EventTimeout: 10 * time.Second,
+			Kafka: &configs.KafkaProducer{Acks: "all", CompressionType: "none", BatchSize: 1000}},
+		Kafka: &configs.Kafka{AutoOffsetReset: "earliest"}}, logger)
+	if err != nil {
+		t.Fatalf("failed to create consumer: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	var down atomic.Bool
+	down.Store(true)
+	storage.EXPECT().Ping(gomock.Any()).DoAndReturn(func(context.Context) error {
+		if down.Load() {
+			return errs.ErrUnavailable
+		}
+		return nil
+	}).AnyTimes()
+	var attempts atomic.Int32
+	failed := make(chan struct{}, 1)
+	var mu sync.Mutex
+	var saved []int64
+	storage.EXPECT().SaveOrder(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, order *models.Order) error {
+		attempts.Add(1)
+		if down.Load() {
+			select {
+			case failed <- struct{}{}:
+			default:
+			}
+			return fmt.Errorf("failed to save order: %w", errs.ErrUnavailable)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		saved = append(saved, order.Ingest.Offset)
+		if len(saved) == 3 {
+			cancel()
+		}
+		return nil
+	}).AnyTimes()
+
+	var running sync.WaitGroup
+	running.Add(2)
+	go func() {
+		defer running.Done()
+		consumer.Poll(ctx, logger)
+	}()
+	go func() {
+		defer running.Done()
+		consumer.Run(ctx, storage, cache, logger, 1)
+	}()
+
+	select {
+	case <-failed:
+	case <-ctx.Done():
+		t.Fatal("expected the worker to run into the outage")
+	}
+	time.Sleep(500 * time.Millisecond)
+	held := attempts.Load()
+	time.Sleep(time.Second)
+	if attempts.Load() != held {
+		t.Fatalf("expected no attempts while paused, got %d more", attempts.Load()-held)
+	}
+
+	down.Store(false)
+	consumer.Resume()
+	running.Wait()
+	consumer.Close(logger)
+
+	if len(saved) != 3 || saved[0] != 0 || saved[1] != 1 || saved[2] != 2 {
+		t.Fatalf("expected every order to be saved in order once resumed, got offsets %v", saved)
+	}
+}
+
+func TestKafkaConsumer_Poll_OutageKeepsLaterMessagesBack(t *testing.T) {
+	cluster, err := kafka.NewMockCluster(1)
+	if err != nil {
+		t.Fatalf("failed to start mock cluster: %v", err)
+	}
+	defer cluster.Close()
+	if err := cluster.CreateTopic("orders", 1, 1); err != nil {
+		t.Fatalf("failed to create topic: %v", err)
+	}
+
+	producer, err := kafka.NewProducer(&kafka.ConfigMap{"bootstrap.servers": cluster.BootstrapServers()})
+	if err != nil {
+		t.Fatalf("failed to create producer: %v", err)
+	}
+	defer producer.Close()
+	topic := "orders"
+	for i := range 3 {
+		orderUID := fmt.Sprintf("b563feb7b2b84b%02d", i)
+		if err := producer.Produce(&kafka.Message{TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: 0},
+			Key: []byte(orderUID), Value: validOrderJSON(t, orderUID)}, nil); err != nil {
+			t.Fatalf("failed to produce: %v", err)
+		}
+	}
+	producer.Flush(10000)
+
+	controller := gomock.NewController(t)
+	logger := mock_logger.NewMockLogger(controller)
+	logger.EXPECT().LogInfo(gomock.Any(), gomock.Any()).AnyTimes()
+	logger.EXPECT().Debug(gomock.Any(), gomock.Any()).AnyTimes()
+	storage := mock_repository.NewMockStorage(controller)
+	cache := mock_cache.NewMockCache(controller)
+
+	brokers := []string{cluster.BootstrapServers()}
+	consumer, err := NewConsumer(configs.Consumer{Brokers: brokers, Topic: "orders", GroupID: "test", CommitRetryMax: 1, BatchSize: 1, SaveOrderRetryMax: 1,
+		Workers: 1, QueueSize: 10, SessionTimeoutMs: 6000, MaxPollIntervalMs: 10000,
+		DLQ: configs.Producer{Brokers: brokers, Topic: "orders-dlq", RetryAttempts: 1, EventTimeout: 10 * time.Second,
+			Kafka: &configs.KafkaProducer{Acks: "all", CompressionType: "none", BatchSize: 1000}},
+		Kafka: &configs.Kafka{AutoOffsetReset: "earliest"}}, logger)
+	if err != nil {
+		t.Fatalf("failed to create consumer: %v", err)
+	}
+
+	// only the first order runs into the outage, the ones behind it would be saved
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	var failed atomic.Bool
+	storage.EXPECT().Ping(gomock.Any()).Return(errs.ErrUnavailable).AnyTimes()
+	var mu sync.Mutex
+	var saved []int64
+	storage.EXPECT().SaveOrder(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, order *models.Order) error {
+		if order.Ingest.Offset == 0 && failed.CompareAndSwap(false, true) {
+			return fmt.Errorf("failed to save order: %w", errs.ErrUnavailable)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		saved = append(saved, order.Ingest.Offset)
+		if len(saved) == 3 {
+			cancel()
+		}
+		return nil
+	}).AnyTimes()
+
+	var running sync.WaitGroup
+	running.Add(2)
+	go func() {
+		defer running.Done()
+		consumer.Poll(ctx, logger)
+	}()
+	go func() {
+		defer running.Done()
+		consumer.Run(ctx, storage, cache, logger, 1)
+	}()
+
+	for consumer.generation.Load() == 0 && ctx.Err() == nil {
+		time.Sleep(10 * time.Millisecond)
+	}
+	time.Sleep(500 * time.Millisecond) // the rewind follows the pause
+	consumer.Resume()
+	running.Wait()
+	consumer.Close(logger)
+
+	if len(saved) != 3 || saved[0] != 0 || saved[1] != 1 || saved[2] != 2 {
+		t.Fatalf("expected the held back order and the ones behind it to be saved in order once resumed, got offsets %v", saved)
+	}
+}
+
+func TestKafkaConsumer_awaitPause_RevokeBeforePause(t *testing.T) {
+	logger := mock_logger.NewMockLogger(gomock.NewController(t))
+	logger.EXPECT().LogInfo(gomock.Any(), gomock.Any()).AnyTimes()
+
+	orders := "orders"
+	consumer := &KafkaConsumer{queues: []chan workItem{make(chan workItem, 1)}, waiting: make(map[string]backlog)}
+	consumer.assign([]kafka.TopicPartition{{Topic: &orders, Partition: 0}})
+	consumer.queues[0] <- workItem{msg: testMessage(orders, 0, 1)} // the queue of the held worker is full
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	released := make(chan struct{})
+	go func() {
+		defer close(released)
+		consumer.awaitPause(ctx, consumer.queues[0], 0)
+	}()
+
+	// the revoke comes within the poll that would have paused the partitions
+	revoked := make(chan struct{})
+	go func() {
+		defer close(revoked)
+		_ = consumer.rebalance(ctx, logger)(nil, kafka.RevokedPartitions{Partitions: []kafka.TopicPartition{{Topic: &orders, Partition: 0}}})
+	}()
+	select {
+	case <-revoked:
+	case <-time.After(time.Second):
+		t.Fatal("a revoke must not wait for a worker held for the pause")
+	}
+	select {
+	case <-released:
+		t.Fatal("expected the worker to be held until the pause")
+	default:
+	}
+
+	consumer.nextGeneration()
+	select {
+	case <-released:
+	case <-time.After(time.Second):
+		t.Fatal("expected the pause to release the worker")
+	}
+}
+
+func TestToMap_Consumer(t *testing.T) {
+	config := configs.Consumer{Brokers: []string{"localhost:9092"}, GroupID: "test", Kafka: &configs.Kafka{AutoOffsetReset: "earliest"}}
+	configMap := toMap(config)
+	for _, key := range []string{"session.timeout.ms", "max.poll.interval.ms"} {
+		if value, _ := configMap.Get(key, nil); value != nil {
+			t.Errorf("expected %s to be left to the client default, got %v", key, value)
+		}
+	}
+
+	config.SessionTimeoutMs, config.MaxPollIntervalMs = 10000, 300000
+	configMap = toMap(config)
+	if value, _ := configMap.Get("session.timeout.ms", nil); value != 10000 {
+		t.Errorf("expected session.timeout.ms to be applied, got %v", value)
+	}
+	if value, _ := configMap.Get("max.poll.interval.ms", nil); value != 300000 {
+		t.Errorf("expected max.poll.interval.ms to be applied, got %v", value)
+	}
+}
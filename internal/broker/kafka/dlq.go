@@ -0,0 +1,135 @@
+package kafka
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/rules"
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/go-playground/validator/v10"
+)
+
+// Headers of the messages sent to the DLQ or a retry topic, telling why and where the message failed.
+// Timestamps are in RFC 3339 format with nanoseconds, in UTC.
+const (
+	HeaderErrorClass        = "x-error-class"       // see ErrorClass
+	HeaderErrorMessage      = "x-error-message"     // the error the message failed with
+	HeaderValidationErrors  = "x-validation-errors" // JSON array of FieldError, for validation failures
+	HeaderRuleViolations    = "x-rule-violations"   // JSON array of models.RuleViolation, for orders rejected by business rules
+	HeaderRetryCount        = "x-retry-count"       // how many times processing was retried
+	HeaderWorkerID          = "x-worker-id"         // the worker that gave up on the message
+	HeaderOriginalTopic     = "x-original-topic"    // the topic, partition and offset the message was consumed at
+	HeaderOriginalPartition = "x-original-partition"
+	HeaderOriginalOffset    = "x-original-offset"
+	HeaderFirstFailureAt    = "x-first-failure-at" // when processing the message failed for the first time
+	HeaderLastFailureAt     = "x-last-failure-at"  // when processing the message failed for the last time
+	HeaderServiceVersion    = "x-service-version"  // the version of the service that failed to process the message
+	HeaderNotBefore         = "x-not-before"       // when a message in a retry topic is to be processed again
+)
+
+// HeaderReplayedFrom marks a message republished from the DLQ, with the topic/partition/offset it was republished from.
+const HeaderReplayedFrom = "x-replayed-from"
+
+// FieldError is a field that failed validation, as carried in the x-validation-errors header.
+type FieldError struct {
+	Field string `json:"field"`           // namespaced field name, e.g. Order.Delivery.Email
+	Tag   string `json:"tag"`             // the validation tag that failed, e.g. email
+	Param string `json:"param,omitempty"` // the parameter of the tag, e.g. 100 for lte=100
+}
+
+// failure describes how processing a message has failed.
+type failure struct {
+	err      error
+	retryCnt int
+	firstAt  time.Time // when the first attempt failed
+	lastAt   time.Time // when the last attempt failed
+}
+
+// failed records a failed attempt at time now.
+func (f *failure) failed(err error, now time.Time) {
+	f.err = err
+	if f.firstAt.IsZero() {
+		f.firstAt = now
+	}
+	f.lastAt = now
+}
+
+/*
+failureHeaders returns the headers a message that failed with f is sent to the DLQ or a retry topic with.
+
+A message consumed from a retry topic keeps the origin and the first failure time it was forwarded with,
+so they always tell where and when the message failed for the first time.
+*/
+func failureHeaders(msg *kafka.Message, f failure, workerID int) map[string]string {
+	headers := map[string]string{
+		HeaderErrorClass:        string(Classify(f.err)),
+		HeaderRetryCount:        strconv.Itoa(f.retryCnt),
+		HeaderWorkerID:          strconv.Itoa(workerID),
+		HeaderOriginalPartition: strconv.Itoa(int(msg.TopicPartition.Partition)),
+		HeaderOriginalOffset:    strconv.FormatInt(int64(msg.TopicPartition.Offset), 10),
+		HeaderFirstFailureAt:    f.firstAt.UTC().Format(time.RFC3339Nano),
+		HeaderLastFailureAt:     f.lastAt.UTC().Format(time.RFC3339Nano),
+		HeaderServiceVersion:    serviceVersion(),
+	}
+	if f.err != nil {
+		headers[HeaderErrorMessage] = f.err.Error()
+	}
+	if msg.TopicPartition.Topic != nil {
+		headers[HeaderOriginalTopic] = *msg.TopicPartition.Topic
+	}
+	if previous := Headers(msg); previous[HeaderOriginalTopic] != "" {
+		for _, key := range []string{HeaderOriginalTopic, HeaderOriginalPartition, HeaderOriginalOffset, HeaderFirstFailureAt} {
+			if value, ok := previous[key]; ok {
+				headers[key] = value
+			}
+		}
+	}
+	var validation validator.ValidationErrors
+	if errors.As(f.err, &validation) {
+		fields := make([]FieldError, 0, len(validation))
+		for _, field := range validation {
+			fields = append(fields, FieldError{Field: field.Namespace(), Tag: field.Tag(), Param: field.Param()})
+		}
+		encoded, _ := json.Marshal(fields) // plain strings always encode
+		headers[HeaderValidationErrors] = string(encoded)
+	}
+	var rejection *rules.RejectedError
+	if errors.As(f.err, &rejection) {
+		violations, _ := json.Marshal(rejection.Violations) // plain strings always encode
+		headers[HeaderRuleViolations] = string(violations)
+	}
+	return headers
+}
+
+// Headers returns the headers of a consumed message by key. Of repeated keys, the last one wins.
+func Headers(msg *kafka.Message) map[string]string {
+	headers := make(map[string]string, len(msg.Headers))
+	for _, header := range msg.Headers {
+		headers[header.Key] = string(header.Value)
+	}
+	return headers
+}
+
+// serviceVersion returns the module version the binary was built from,
+// or the VCS revision for development builds.
+var serviceVersion = sync.OnceValue(func() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	version := info.Main.Version
+	for _, setting := range info.Settings {
+		if setting.Key == "vcs.revision" && (version == "" || version == "(devel)") {
+			version = fmt.Sprintf("(devel) %s", setting.Value)
+		}
+	}
+	if version == "" {
+		return "unknown"
+	}
+	return version
+})
@@ -0,0 +1,106 @@
+package kafka
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/configs"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/models"
+	mock_logger "github.com/Pur1st2EpicONE/WBTECH-sample-microservice/pkg/logger/mocks"
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/go-playground/validator/v10"
+	"github.com/golang/mock/gomock"
+)
+
+func TestFailureHeaders(t *testing.T) {
+	msg := testMessage("orders", 3, 42)
+	firstAt := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	lastAt := firstAt.Add(3 * time.Second)
+
+	order := new(models.Order)
+	if err := json.Unmarshal(withOrder(t, validOrderJSON(t, "b563feb7b2b84b6test"), func(order *models.Order) { order.Delivery.Email = "chain mail" }), order); err != nil {
+		t.Fatalf("failed to unmarshal order: %v", err)
+	}
+	err := classified(ClassValidation, fmt.Errorf("validation failed: %w", validator.New().Struct(order)))
+
+	headers := failureHeaders(msg, failure{err: err, retryCnt: 2, firstAt: firstAt, lastAt: lastAt}, 7)
+	want := map[string]string{
+		HeaderErrorClass:        "validation",
+		HeaderErrorMessage:      err.Error(),
+		HeaderValidationErrors:  `[{"field":"Order.Delivery.Email","tag":"email"}]`,
+		HeaderRetryCount:        "2",
+		HeaderWorkerID:          "7",
+		HeaderOriginalTopic:     "orders",
+		HeaderOriginalPartition: "3",
+		HeaderOriginalOffset:    "42",
+		HeaderFirstFailureAt:    "2025-01-01T10:00:00Z",
+		HeaderLastFailureAt:     "2025-01-01T10:00:03Z",
+	}
+	for key, value := range want {
+		if headers[key] != value {
+			t.Errorf("header %s: expected %q, got %q", key, value, headers[key])
+		}
+	}
+	if headers[HeaderServiceVersion] == "" {
+		t.Error("expected the service version header")
+	}
+	if _, ok := headers[HeaderRuleViolations]; ok {
+		t.Error("expected no rule violations for a validation failure")
+	}
+
+	transient := failureHeaders(msg, failure{err: errors.New("deadlock detected"), firstAt: firstAt, lastAt: lastAt}, 7)
+	if transient[HeaderErrorClass] != "transient" {
+		t.Errorf("expected an unclassified error to be transient, got %q", transient[HeaderErrorClass])
+	}
+}
+
+func TestKafkaProducer_Produce_KeepsHeaders(t *testing.T) {
+	cluster, err := kafka.NewMockCluster(1)
+	if err != nil {
+		t.Fatalf("failed to start mock cluster: %v", err)
+	}
+	defer cluster.Close()
+
+	controller := gomock.NewController(t)
+	logger := mock_logger.NewMockLogger(controller)
+	logger.EXPECT().LogInfo(gomock.Any(), gomock.Any()).AnyTimes()
+
+	brokers := []string{cluster.BootstrapServers()}
+	producer, err := NewProducer(configs.Producer{Brokers: brokers, RetryAttempts: 1, EventTimeout: 10 * time.Second,
+		Kafka: &configs.KafkaProducer{Acks: "all", CompressionType: "none", BatchSize: 1000}}, logger)
+	if err != nil {
+		t.Fatalf("failed to create producer: %v", err)
+	}
+	defer producer.Close()
+
+	headers := map[string]string{HeaderErrorClass: "decode", HeaderErrorMessage: "failed to unmarshal the order", HeaderOriginalOffset: "42"}
+	message := configs.Message{Topic: "orders-dlq", Key: []byte("uid1"), Value: []byte("not json"), Headers: headers, DLQ: true, WorkerID: 1}
+	if err := producer.Produce(message); err != nil {
+		t.Fatalf("failed to produce: %v", err)
+	}
+
+	consumer, err := kafka.NewConsumer(&kafka.ConfigMap{"bootstrap.servers": brokers[0], "group.id": "test", "auto.offset.reset": "earliest"})
+	if err != nil {
+		t.Fatalf("failed to create consumer: %v", err)
+	}
+	defer func() { _ = consumer.Close() }()
+	if err := consumer.Subscribe("orders-dlq", nil); err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+	received, err := consumer.ReadMessage(10 * time.Second)
+	if err != nil {
+		t.Fatalf("failed to read the message back: %v", err)
+	}
+	got := Headers(received)
+	if len(got) != len(headers) {
+		t.Fatalf("expected headers %v, got %v", headers, got)
+	}
+	for key, value := range headers {
+		if got[key] != value {
+			t.Fatalf("header %s: expected %q, got %q", key, value, got[key])
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package kafka
+
+import (
+	"errors"
+
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository/errs"
+)
+
+// ErrorClass tells why a message could not be processed, and so whether processing it again may succeed.
+type ErrorClass string
+
+const (
+	ClassDecode       ErrorClass = "decode"        // the message is not JSON of the expected shape
+	ClassValidation   ErrorClass = "validation"    // the message failed struct validation
+	ClassBusinessRule ErrorClass = "business_rule" // the order breaks a rejecting business rule
+	ClassConflict     ErrorClass = "conflict"      // the order conflicts with a stored one or violates DB constraints
+	ClassTransient    ErrorClass = "transient"     // the database failed in a way that may go away
+)
+
+// Permanent reports whether messages that failed with errors of the class fail the same way every time.
+func (c ErrorClass) Permanent() bool {
+	return c != ClassTransient
+}
+
+// ProcessingError is an error the Handler failed to process a message with, along with its class.
+type ProcessingError struct {
+	Class ErrorClass
+	Err   error
+}
+
+func (e *ProcessingError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ProcessingError) Unwrap() error {
+	return e.Err
+}
+
+// classified wraps err into a ProcessingError of the given class.
+func classified(class ErrorClass, err error) error {
+	return &ProcessingError{Class: class, Err: err}
+}
+
+/*
+storageError classifies an error storage reported for a message.
+
+Conflicts and constraint violations are permanent. Everything else is transient,
+including errs.ErrNotFound: a status update may arrive before the order it refers to has been saved
+by another worker. A redelivered message (errs.ErrDuplicate) is not a failure and is returned as is.
+*/
+func storageError(err error) error {
+	switch {
+	case errors.Is(err, errs.ErrDuplicate):
+		return err
+	case errors.Is(err, errs.ErrConflict), errors.Is(err, errs.ErrConstraint):
+		return classified(ClassConflict, err)
+	default:
+		return classified(ClassTransient, err)
+	}
+}
+
+// Classify returns the class of an error returned by the Handler.
+// Errors that carry no class are transient, so they are retried rather than dropped.
+func Classify(err error) ErrorClass {
+	var processing *ProcessingError
+	if errors.As(err, &processing) {
+		return processing.Class
+	}
+	return ClassTransient
+}
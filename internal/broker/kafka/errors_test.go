@@ -0,0 +1,106 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/models"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository/errs"
+	mock_repository "github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository/mocks"
+	mock_logger "github.com/Pur1st2EpicONE/WBTECH-sample-microservice/pkg/logger/mocks"
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/golang/mock/gomock"
+)
+
+func TestHandler_SaveOrder_ClassifiesErrors(t *testing.T) {
+	valid := validOrderJSON(t, "b563feb7b2b84b6test")
+	tests := []struct {
+		name       string
+		value      []byte
+		storageErr error
+		want       ErrorClass
+	}{
+		{name: "malformed JSON", value: []byte("not json"), want: ClassDecode},
+		{name: "failed validation", value: withOrder(t, valid, func(order *models.Order) { order.Delivery.Email = "chain mail" }), want: ClassValidation},
+		{name: "broken business rule", value: withOrder(t, valid, func(order *models.Order) { order.Payment.Transaction = "other" }), want: ClassBusinessRule},
+		{name: "conflict", value: valid, storageErr: errs.ErrConflict, want: ClassConflict},
+		{name: "constraint violation", value: valid, storageErr: errs.ErrConstraint, want: ClassConflict},
+		{name: "database outage", value: valid, storageErr: errs.ErrUnavailable, want: ClassTransient},
+		{name: "unknown database error", value: valid, storageErr: errors.New("deadlock detected"), want: ClassTransient},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			controller := gomock.NewController(t)
+			storage := mock_repository.NewMockStorage(controller)
+			logger := mock_logger.NewMockLogger(controller)
+			if tt.storageErr != nil {
+				storage.EXPECT().SaveOrder(gomock.Any(), gomock.Any()).Return(tt.storageErr)
+			}
+
+			msg := testMessage("orders", 0, 1)
+			msg.Value = tt.value
+			err := newHandler(testRules(t)).SaveOrder(context.Background(), msg, storage, logger, 1)
+			if class := Classify(err); err == nil || class != tt.want {
+				t.Fatalf("expected a %s error, got %q (%v)", tt.want, class, err)
+			}
+			if tt.storageErr != nil && !errors.Is(err, tt.storageErr) {
+				t.Fatalf("expected the storage error to be wrapped, got %v", err)
+			}
+		})
+	}
+}
+
+func TestHandler_SaveOrder_Duplicate(t *testing.T) {
+	controller := gomock.NewController(t)
+	storage := mock_repository.NewMockStorage(controller)
+	logger := mock_logger.NewMockLogger(controller)
+	storage.EXPECT().SaveOrder(gomock.Any(), gomock.Any()).Return(errs.ErrDuplicate)
+
+	msg := &kafka.Message{Value: validOrderJSON(t, "b563feb7b2b84b6test")}
+	err := newHandler(testRules(t)).SaveOrder(context.Background(), msg, storage, logger, 1)
+	var processing *ProcessingError
+	if !errors.Is(err, errs.ErrDuplicate) || errors.As(err, &processing) {
+		t.Fatalf("expected a redelivered order to be reported unclassified, got %v", err)
+	}
+}
+
+func TestKafkaConsumer_retryDelay(t *testing.T) {
+	c := &KafkaConsumer{saveOrderRetryDelay: time.Second, saveOrderRetryMaxDelay: 5 * time.Second}
+	for _, tt := range []struct {
+		retryCnt int
+		max      time.Duration
+	}{{1, time.Second}, {2, 2 * time.Second}, {3, 4 * time.Second}, {4, 5 * time.Second}, {50, 5 * time.Second}} {
+		for range 20 {
+			if delay := c.retryDelay(tt.retryCnt); delay < tt.max/2 || delay > tt.max {
+				t.Fatalf("retry %d: expected a delay between %v and %v, got %v", tt.retryCnt, tt.max/2, tt.max, delay)
+			}
+		}
+	}
+
+	uncapped := &KafkaConsumer{saveOrderRetryDelay: time.Second}
+	if delay := uncapped.retryDelay(5); delay < 8*time.Second || delay > 16*time.Second {
+		t.Fatalf("expected the delay to keep doubling without a cap, got %v", delay)
+	}
+	if delay := new(KafkaConsumer).retryDelay(3); delay != 0 {
+		t.Fatalf("expected no delay when none is configured, got %v", delay)
+	}
+}
+
+func TestKafkaConsumer_databaseDown(t *testing.T) {
+	storage := mock_repository.NewMockStorage(gomock.NewController(t))
+	c := new(KafkaConsumer)
+
+	storage.EXPECT().Ping(gomock.Any()).Return(errs.ErrUnavailable)
+	if !c.databaseDown(context.Background(), errs.ErrUnavailable, storage) {
+		t.Fatal("expected a lost connection once Ping fails too")
+	}
+	storage.EXPECT().Ping(gomock.Any()).Return(nil)
+	if c.databaseDown(context.Background(), errs.ErrUnavailable, storage) {
+		t.Fatal("expected a database that still answers Ping not to pause the consumer, e.g. a busy SQLite file")
+	}
+	if c.databaseDown(context.Background(), errs.ErrTimeout, storage) { // no Ping expected
+		t.Fatal("expected only unavailable storage to be checked")
+	}
+}
@@ -1,27 +1,38 @@
 package kafka
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"strconv"
+	"time"
 
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/cache"
 	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/models"
 	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/rules"
 	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/pkg/logger"
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
 	"github.com/go-playground/validator/v10"
 )
 
 // MessageHandler defines the contract for processing Kafka messages.
-// Each message is expected to represent an order in JSON format.
+// Each message is expected to represent either an order or an item status-change event in JSON format.
+// Orders are saved along with the message they were received in (see models.IngestRecord).
 type MessageHandler interface {
-	SaveOrder(jsonMsg []byte, storage repository.Storage, logger logger.Logger, workerID int) error
+	SaveOrder(ctx context.Context, msg *kafka.Message, storage repository.Storage, logger logger.Logger, workerID int) error
+	SaveOrders(ctx context.Context, msgs []*kafka.Message, storage repository.Storage, logger logger.Logger, workerID int) ([]error, error)
+	UpdateItemStatus(ctx context.Context, jsonMsg []byte, storage repository.Storage, cache cache.Cache, logger logger.Logger, workerID int) error
 }
 
 // Handler is a concrete implementation of MessageHandler.
 // It provides logic for parsing, validating, and storing incoming Kafka messages.
-type Handler struct{}
+type Handler struct {
+	rules *rules.Engine // business rules orders are checked against after struct validation
+}
 
-func newHandler() *Handler {
-	return new(Handler)
+func newHandler(rules *rules.Engine) *Handler {
+	return &Handler{rules: rules}
 }
 
 // SaveOrder parses a JSON message into an Order, validates it,
@@ -30,23 +41,132 @@ func newHandler() *Handler {
 // Steps:
 //  1. Unmarshal JSON into a models.Order struct.
 //  2. Validate the struct fields using go-playground/validator.
-//  3. Save the validated order to the storage.
-//  4. Log a debug message on success.
+//  3. Check the order against the business rules, see the rules package.
+//  4. Save the validated order to the storage, along with the raw message and its topic, partition, offset and key,
+//     and the rules the order was flagged by.
+//  5. Log a debug message on success.
 //
-// If unmarshaling, validation, or saving fails, an error is returned.
+// If unmarshaling, validation, or saving fails, a *ProcessingError is returned, telling whether the failure
+// is permanent (see ErrorClass). An order that breaks a rejecting rule results in one that wraps a *rules.RejectedError.
+// Cancelling ctx aborts the database write.
 // The workerID is included in logs for easier debugging in multi-worker setups.
-func (h *Handler) SaveOrder(jsonMsg []byte, storage repository.Storage, logger logger.Logger, workerID int) error {
+func (h *Handler) SaveOrder(ctx context.Context, msg *kafka.Message, storage repository.Storage, logger logger.Logger, workerID int) error {
+	order, err := h.parseOrder(validator.New(), msg)
+	if err != nil {
+		return err
+	}
+	if err := storage.SaveOrder(ctx, order); err != nil {
+		return storageError(fmt.Errorf("failed to save order %s to database: %w", order.OrderUID, err))
+	}
+	logFlagged(order, logger, workerID)
+	logger.Debug(fmt.Sprintf("worker %d — saved order to DB", workerID), "orderUID", order.OrderUID, "workerID", fmt.Sprintf("%d", workerID), "layer", "broker.kafka")
+	return nil
+}
+
+// SaveOrders parses and validates a batch of JSON messages into orders
+// and persists the valid ones together in the provided storage, along with the messages they were received in.
+//
+// The returned slice holds the outcome of every message, in order: nil if the order was saved,
+// the parsing or validation error, or the error storage reported for the order, each of them a *ProcessingError.
+// The error is returned if the batch could not be saved at all, classified the same way.
+func (h *Handler) SaveOrders(ctx context.Context, msgs []*kafka.Message, storage repository.Storage, logger logger.Logger, workerID int) ([]error, error) {
 	validate := validator.New()
+	results := make([]error, len(msgs))
+	orders := make([]*models.Order, 0, len(msgs))
+	positions := make([]int, 0, len(msgs)) // position of every parsed order in the batch
+	for i, msg := range msgs {
+		order, err := h.parseOrder(validate, msg)
+		if err != nil {
+			results[i] = err
+			continue
+		}
+		orders = append(orders, order)
+		positions = append(positions, i)
+	}
+	saved, err := storage.SaveOrders(ctx, orders)
+	if err != nil {
+		return nil, storageError(fmt.Errorf("failed to save a batch of %d orders to database: %w", len(orders), err))
+	}
+	for i, err := range saved {
+		if err != nil {
+			results[positions[i]] = storageError(fmt.Errorf("failed to save order %s to database: %w", orders[i].OrderUID, err))
+			continue
+		}
+		logFlagged(orders[i], logger, workerID)
+	}
+	logger.Debug(fmt.Sprintf("worker %d — saved a batch of orders to DB", workerID), "batchSize", fmt.Sprintf("%d", len(msgs)), "workerID", fmt.Sprintf("%d", workerID), "layer", "broker.kafka")
+	return results, nil
+}
+
+// parseOrder unmarshals a JSON message into an Order, validates it, checks it against the business rules
+// and attaches the ingest record of the message, with the rules the order was flagged by.
+// Errors are classified as ClassDecode, ClassValidation or ClassBusinessRule.
+func (h *Handler) parseOrder(validate *validator.Validate, msg *kafka.Message) (*models.Order, error) {
 	order := new(models.Order)
-	if err := json.Unmarshal(jsonMsg, order); err != nil {
-		return fmt.Errorf("failed to unmarshal the order: %w", err)
+	if err := json.Unmarshal(msg.Value, order); err != nil {
+		return nil, classified(ClassDecode, fmt.Errorf("failed to unmarshal the order: %w", err))
 	}
 	if err := validate.Struct(order); err != nil {
-		return fmt.Errorf("validation failed: %w", err)
+		return nil, classified(ClassValidation, fmt.Errorf("validation failed: %w", err))
 	}
-	if err := storage.SaveOrder(order); err != nil {
-		return fmt.Errorf("failed to save order %s to database: %w", order.OrderUID, err)
+	violations, err := h.rules.Check(order)
+	if err != nil {
+		return nil, classified(ClassBusinessRule, err)
 	}
-	logger.Debug(fmt.Sprintf("worker %d — saved order to DB", workerID), "orderUID", order.OrderUID, "workerID", fmt.Sprintf("%d", workerID), "layer", "broker.kafka")
+	order.Ingest = ingestRecord(msg)
+	order.Ingest.Violations = violations
+	return order, nil
+}
+
+// logFlagged logs the business rules a saved order was flagged by, if any.
+func logFlagged(order *models.Order, logger logger.Logger, workerID int) {
+	for _, violation := range order.Ingest.Violations {
+		logger.LogInfo(fmt.Sprintf("worker %d — order flagged by business rule %s", workerID, violation.Rule), "orderUID", order.OrderUID,
+			"violation", violation.Message, "workerID", fmt.Sprintf("%d", workerID), "layer", "broker.kafka")
+	}
+}
+
+// ingestRecord returns the provenance of a message: where in Kafka it was read from and its raw payload.
+// A message retried through a retry topic is recorded where it was first read from, see HeaderOriginalTopic.
+func ingestRecord(msg *kafka.Message) *models.IngestRecord {
+	record := &models.IngestRecord{
+		Partition:  msg.TopicPartition.Partition,
+		Offset:     int64(msg.TopicPartition.Offset),
+		Key:        string(msg.Key),
+		Timestamp:  msg.Timestamp,
+		IngestedAt: time.Now().UTC(),
+		Payload:    msg.Value,
+	}
+	if msg.TopicPartition.Topic != nil {
+		record.Topic = *msg.TopicPartition.Topic
+	}
+	if headers := Headers(msg); headers[HeaderOriginalTopic] != "" {
+		partition, _ := strconv.ParseInt(headers[HeaderOriginalPartition], 10, 32)
+		offset, _ := strconv.ParseInt(headers[HeaderOriginalOffset], 10, 64)
+		record.Topic, record.Partition, record.Offset = headers[HeaderOriginalTopic], int32(partition), offset
+	}
+	return record
+}
+
+// UpdateItemStatus parses a JSON message into a StatusUpdate, validates it,
+// applies it to the stored item and invalidates the cached order.
+//
+// The cached order is dropped only after the change is stored, so readers never
+// re-cache a stale copy. Errors are classified like those of SaveOrder.
+// A redelivered event (errs.ErrDuplicate) is returned as is, the consumer treats it as success.
+func (h *Handler) UpdateItemStatus(ctx context.Context, jsonMsg []byte, storage repository.Storage, cache cache.Cache, logger logger.Logger, workerID int) error {
+	validate := validator.New()
+	update := new(models.StatusUpdate)
+	if err := json.Unmarshal(jsonMsg, update); err != nil {
+		return classified(ClassDecode, fmt.Errorf("failed to unmarshal the status update: %w", err))
+	}
+	if err := validate.Struct(update); err != nil {
+		return classified(ClassValidation, fmt.Errorf("validation failed: %w", err))
+	}
+	if err := storage.UpdateItemStatus(ctx, *update); err != nil {
+		return storageError(fmt.Errorf("failed to update item status of order %s: %w", update.OrderUID, err))
+	}
+	cache.InvalidateOrder(update.OrderUID, logger)
+	logger.Debug(fmt.Sprintf("worker %d — updated item status", workerID), "orderUID", update.OrderUID, "chrtID", fmt.Sprintf("%d", update.ChrtID), "status", fmt.Sprintf("%d", update.Status), "workerID", fmt.Sprintf("%d", workerID), "layer", "broker.kafka")
 	return nil
 }
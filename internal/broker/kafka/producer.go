@@ -2,6 +2,8 @@ package kafka
 
 import (
 	"fmt"
+	"maps"
+	"slices"
 	"time"
 
 	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/configs"
@@ -42,47 +44,50 @@ func NewProducer(config configs.Producer, logger logger.Logger) (*KafkaProducer,
 	}, nil
 }
 
-// Produce sends a message to a Kafka topic.
+// Produce sends a message to a Kafka topic and waits until Kafka confirms the delivery.
 //
 // Steps:
-//  1. Constructs a Kafka message from key, value, and topic.
-//  2. Attempts to produce the message, retrying up to `RetryAttempts` times on failure.
-//  3. Logs errors for each failed attempt.
+//  1. Constructs a Kafka message from key, value, topic, headers and metadata, see NewKafkaMessage.
+//  2. Enqueues the message and waits for its delivery report, at most `eventTimeout`.
+//  3. Retries up to `RetryAttempts` times if either step fails, logging every failed attempt.
 //  4. If the message is for the DLQ, logs additional info on success or failure.
-//  5. Waits for the delivery event or times out based on `eventTimeout`.
-//  6. Returns any delivery or event errors to the caller.
+//  5. Returns the last error if all attempts failed.
 //
-// This ensures reliable message delivery with configurable retries, logging, and
-// proper handling for dead-letter queue messages. The retry mechanism prevents
-// transient Kafka issues from immediately failing message processing.
+// Returning only after the delivery report lets callers rely on a nil error meaning
+// the message is stored in Kafka (e.g. before marking an outbox event as sent).
+// The retry mechanism prevents transient Kafka issues from immediately failing message processing.
 func (p *KafkaProducer) Produce(message configs.Message) error {
-	order := NewKafkaMessage(message.Key, message.Value, message.Topic)
-	eventChan := make(chan kafka.Event)
+	kafkaMessage := NewKafkaMessage(message)
+	eventChan := make(chan kafka.Event, max(p.RetryAttempts, 1)) // room for late reports of timed out attempts, so the delivery goroutine never blocks
 	var err error
 	for range p.RetryAttempts {
-		if err = p.producer.Produce(order, eventChan); err != nil {
+		if err = p.producer.Produce(kafkaMessage, eventChan); err == nil {
+			err = p.awaitDelivery(eventChan)
+		}
+		if err != nil {
+			p.logger.LogError("producer — failed to send message", err, "key", ToStr(message.Key), "topic", message.Topic, "layer", "broker.kafka")
 			time.Sleep(p.produceRetryDelay)
-			p.logger.LogError("producer — failed to send order", err, "orderUID", ToStr(message.Key), "layer", "broker.kafka")
 			continue
-		} else {
-			if message.DLQ {
-				p.logger.LogInfo(fmt.Sprintf("worker %d — order is sent to DLQ", message.WorkerID), "orderUID", ToStr(message.Key), "workerID", fmt.Sprintf("%d", message.WorkerID), "layer", "broker.kafka")
-			}
-			return nil
 		}
+		if message.DLQ {
+			p.logger.LogInfo(fmt.Sprintf("worker %d — order is sent to DLQ", message.WorkerID), "orderUID", ToStr(message.Key), "workerID", fmt.Sprintf("%d", message.WorkerID), "layer", "broker.kafka")
+		}
+		return nil
 	}
 	if message.DLQ {
 		p.logger.LogError(fmt.Sprintf("worker %d — failed to send order to DLQ after %d attempts", message.WorkerID, p.RetryAttempts), err, "orderUID", ToStr(message.Key), "workerID", fmt.Sprintf("%d", message.WorkerID), "layer", "broker.kafka")
 	}
+	return err
+}
+
+// awaitDelivery waits for the delivery report of a single message.
+func (p *KafkaProducer) awaitDelivery(eventChan chan kafka.Event) error {
 	select {
 	case event := <-eventChan:
 		switch eventType := event.(type) {
 		case *kafka.Message:
-			if eventType.TopicPartition.Error != nil {
-				return eventType.TopicPartition.Error
-			}
-			return nil
-		case *kafka.Error:
+			return eventType.TopicPartition.Error
+		case kafka.Error:
 			return eventType
 		default:
 			return fmt.Errorf("unknown type of event: %T", event)
@@ -92,16 +97,26 @@ func (p *KafkaProducer) Produce(message configs.Message) error {
 	}
 }
 
-// NewKafkaMessage constructs a Kafka message with the given key, value, and topic.
+// NewKafkaMessage constructs a Kafka message with the key, value, topic and headers of message.
 //
 // It sets the partition to kafka.PartitionAny to let Kafka decide which partition
-// the message should go to.
-func NewKafkaMessage(key []byte, value []byte, topic string) *kafka.Message {
-	return &kafka.Message{
+// the message should go to. Headers are sorted by key, so equal messages are encoded the same way.
+// Metadata is not sent to Kafka: it is attached as the opaque value of the message
+// and comes back with its delivery report.
+func NewKafkaMessage(message configs.Message) *kafka.Message {
+	topic := message.Topic
+	kafkaMessage := &kafka.Message{
 		TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: kafka.PartitionAny},
-		Key:            key,
-		Value:          value,
+		Key:            message.Key,
+		Value:          message.Value,
+	}
+	for _, key := range slices.Sorted(maps.Keys(message.Headers)) {
+		kafkaMessage.Headers = append(kafkaMessage.Headers, kafka.Header{Key: key, Value: []byte(message.Headers[key])})
+	}
+	if message.Metadata != nil {
+		kafkaMessage.Opaque = message.Metadata
 	}
+	return kafkaMessage
 }
 
 // Close flushes all pending messages and closes the producer.
@@ -0,0 +1,185 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/configs"
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+// readerTimeout bounds the metadata and offset requests of a KafkaReader.
+const readerTimeout = 10 * time.Second
+
+/*
+KafkaReader reads a topic on behalf of a consumer group, for tooling such as the dlq subcommand
+rather than for the service itself.
+
+Unlike KafkaConsumer it doesn't wait for new messages: a read goes from the offsets the group
+has committed up to the end of every partition as it was when the read started.
+Offsets are only committed when asked to, so reading alone never moves the group on.
+*/
+type KafkaReader struct {
+	consumer *kafka.Consumer
+	topic    string
+}
+
+// NewReader creates a KafkaReader of config.Topic that belongs to the consumer group config.GroupID.
+func NewReader(config configs.Producer) (*KafkaReader, error) {
+	consumer, err := kafka.NewConsumer(&kafka.ConfigMap{
+		"bootstrap.servers":  strings.Join(config.Brokers, ","),
+		"group.id":           config.GroupID,
+		"client.id":          config.ClientID,
+		"enable.auto.commit": false,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create consumer: %w", err)
+	}
+	return &KafkaReader{consumer: consumer, topic: config.Topic}, nil
+}
+
+/*
+Read calls fn with every message of the topic, partition by partition in offset order,
+from the offsets the group has committed (or the beginning of partitions it hasn't read yet)
+up to the end of the topic as of the start of the call. If fromStart is set, the committed offsets
+are ignored and the whole topic is read.
+
+Read stops at the first error fn returns, and returns it. Cancelling ctx stops it as well.
+*/
+func (r *KafkaReader) Read(ctx context.Context, fromStart bool, fn func(message configs.Message) error) error {
+	partitions, ends, err := r.positions(fromStart)
+	if err != nil {
+		return err
+	}
+	if len(partitions) == 0 {
+		return nil
+	}
+	if err := r.consumer.Assign(partitions); err != nil {
+		return fmt.Errorf("failed to assign partitions: %w", err)
+	}
+	defer func() { _ = r.consumer.Unassign() }()
+
+	for len(ends) > 0 {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		switch event := r.consumer.Poll(100).(type) {
+		case nil:
+			if err := r.skipFinished(ends); err != nil {
+				return err
+			}
+		case *kafka.Message:
+			partition := event.TopicPartition.Partition
+			end, ok := ends[partition]
+			if !ok || event.TopicPartition.Offset >= end {
+				continue
+			}
+			if err := fn(toMessage(event)); err != nil {
+				return err
+			}
+			if event.TopicPartition.Offset+1 >= end {
+				delete(ends, partition)
+			}
+		case kafka.Error:
+			if event.IsFatal() {
+				return event
+			}
+		}
+	}
+	return nil
+}
+
+// skipFinished drops the partitions whose position has reached their end from ends,
+// so that a read of a partition ending in offsets with no message (e.g. after compaction) is over as well.
+func (r *KafkaReader) skipFinished(ends map[int32]kafka.Offset) error {
+	var partitions []kafka.TopicPartition
+	for partition := range ends {
+		partitions = append(partitions, kafka.TopicPartition{Topic: &r.topic, Partition: partition})
+	}
+	positions, err := r.consumer.Position(partitions)
+	if err != nil {
+		return fmt.Errorf("failed to get positions: %w", err)
+	}
+	for _, position := range positions {
+		if position.Offset >= 0 && position.Offset >= ends[position.Partition] {
+			delete(ends, position.Partition)
+		}
+	}
+	return nil
+}
+
+// positions returns the partitions to read with the offsets to start at,
+// and the end offset of every partition that has anything to read.
+func (r *KafkaReader) positions(fromStart bool) ([]kafka.TopicPartition, map[int32]kafka.Offset, error) {
+	metadata, err := r.consumer.GetMetadata(&r.topic, false, int(readerTimeout.Milliseconds()))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get metadata of topic %s: %w", r.topic, err)
+	}
+	topic, ok := metadata.Topics[r.topic]
+	if !ok || topic.Error.Code() != kafka.ErrNoError {
+		return nil, nil, fmt.Errorf("topic %s not found: %v", r.topic, topic.Error)
+	}
+
+	var partitions []kafka.TopicPartition
+	for _, partition := range topic.Partitions {
+		partitions = append(partitions, kafka.TopicPartition{Topic: &r.topic, Partition: partition.ID})
+	}
+	if !fromStart {
+		partitions, err = r.consumer.Committed(partitions, int(readerTimeout.Milliseconds()))
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get committed offsets: %w", err)
+		}
+	}
+
+	var start []kafka.TopicPartition
+	ends := make(map[int32]kafka.Offset)
+	for _, partition := range partitions {
+		low, high, err := r.consumer.QueryWatermarkOffsets(r.topic, partition.Partition, int(readerTimeout.Milliseconds()))
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get offsets of partition %d: %w", partition.Partition, err)
+		}
+		offset := partition.Offset
+		if fromStart || offset < kafka.Offset(low) { // nothing committed yet, or committed messages have been deleted since
+			offset = kafka.Offset(low)
+		}
+		if offset >= kafka.Offset(high) {
+			continue
+		}
+		start = append(start, kafka.TopicPartition{Topic: &r.topic, Partition: partition.Partition, Offset: offset})
+		ends[partition.Partition] = kafka.Offset(high)
+	}
+	return start, ends, nil
+}
+
+// Commit records that message, and every message before it in its partition, has been dealt with by the group,
+// so the next Read starts after it.
+func (r *KafkaReader) Commit(message configs.Message) error {
+	offset := kafka.TopicPartition{Topic: &r.topic, Partition: message.Partition, Offset: kafka.Offset(message.Offset + 1)}
+	if _, err := r.consumer.CommitOffsets([]kafka.TopicPartition{offset}); err != nil {
+		return fmt.Errorf("failed to commit offset %d of partition %d: %w", message.Offset, message.Partition, err)
+	}
+	return nil
+}
+
+// Close leaves the consumer group and releases the reader.
+func (r *KafkaReader) Close() {
+	_ = r.consumer.Close()
+}
+
+// toMessage converts a consumed Kafka message into a configs.Message.
+func toMessage(msg *kafka.Message) configs.Message {
+	message := configs.Message{
+		Partition: msg.TopicPartition.Partition,
+		Offset:    int64(msg.TopicPartition.Offset),
+		Key:       msg.Key,
+		Value:     msg.Value,
+		Headers:   Headers(msg),
+		Timestamp: msg.Timestamp,
+	}
+	if msg.TopicPartition.Topic != nil {
+		message.Topic = *msg.TopicPartition.Topic
+	}
+	return message
+}
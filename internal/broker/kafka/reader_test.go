@@ -0,0 +1,83 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/configs"
+	mock_logger "github.com/Pur1st2EpicONE/WBTECH-sample-microservice/pkg/logger/mocks"
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/golang/mock/gomock"
+)
+
+func TestKafkaReader(t *testing.T) {
+	cluster, err := kafka.NewMockCluster(1)
+	if err != nil {
+		t.Fatalf("failed to start mock cluster: %v", err)
+	}
+	defer cluster.Close()
+	if err := cluster.CreateTopic("orders-dlq", 1, 1); err != nil {
+		t.Fatalf("failed to create topic: %v", err)
+	}
+
+	controller := gomock.NewController(t)
+	logger := mock_logger.NewMockLogger(controller)
+	config := configs.Producer{Brokers: []string{cluster.BootstrapServers()}, Topic: "orders-dlq", GroupID: "replay",
+		RetryAttempts: 1, EventTimeout: 10 * time.Second, Kafka: &configs.KafkaProducer{Acks: "all", CompressionType: "none", BatchSize: 1000}}
+	producer, err := NewProducer(config, logger)
+	if err != nil {
+		t.Fatalf("failed to create producer: %v", err)
+	}
+	defer producer.Close()
+	for _, key := range []string{"uid1", "uid2", "uid3"} {
+		if err := producer.Produce(configs.Message{Topic: "orders-dlq", Key: []byte(key), Value: []byte("{}"),
+			Headers: map[string]string{HeaderErrorClass: "decode"}}); err != nil {
+			t.Fatalf("failed to produce: %v", err)
+		}
+	}
+
+	reader, err := NewReader(config)
+	if err != nil {
+		t.Fatalf("failed to create reader: %v", err)
+	}
+	defer reader.Close()
+	read := func(fromStart bool) []configs.Message {
+		t.Helper()
+		var messages []configs.Message
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := reader.Read(ctx, fromStart, func(message configs.Message) error {
+			messages = append(messages, message)
+			return nil
+		}); err != nil {
+			t.Fatalf("failed to read: %v", err)
+		}
+		return messages
+	}
+
+	messages := read(false)
+	if len(messages) != 3 || string(messages[0].Key) != "uid1" || messages[2].Offset != 2 || messages[0].Headers[HeaderErrorClass] != "decode" {
+		t.Fatalf("expected the 3 messages with their headers, got %+v", messages)
+	}
+	if err := reader.Commit(messages[1]); err != nil {
+		t.Fatalf("failed to commit: %v", err)
+	}
+	if messages := read(false); len(messages) != 1 || string(messages[0].Key) != "uid3" {
+		t.Fatalf("expected to resume after the committed message, got %+v", messages)
+	}
+	if messages := read(true); len(messages) != 3 {
+		t.Fatalf("expected the whole topic from the start, got %d messages", len(messages))
+	}
+
+	stop := errors.New("stop")
+	calls := 0
+	err = reader.Read(context.Background(), true, func(configs.Message) error {
+		calls++
+		return stop
+	})
+	if !errors.Is(err, stop) || calls != 1 {
+		t.Fatalf("expected the read to stop at the first error, got %v after %d calls", err, calls)
+	}
+}
@@ -0,0 +1,195 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/cache"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/configs"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository/errs"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/pkg/logger"
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+// retryWorkerID is the worker ID the retry consumer logs and forwards messages with.
+const retryWorkerID = 0
+
+// validateRetryTopics checks that the retry chain can be consumed alongside the given topics.
+func validateRetryTopics(chain []configs.RetryTopic, topics ...string) error {
+	seen := make(map[string]bool)
+	for _, topic := range topics {
+		seen[topic] = true
+	}
+	for _, stage := range chain {
+		if stage.Topic == "" || stage.Delay <= 0 {
+			return fmt.Errorf("retry topic %q: a topic and a positive delay are required", stage.Topic)
+		}
+		if seen[stage.Topic] {
+			return fmt.Errorf("retry topic %q is used twice", stage.Topic)
+		}
+		seen[stage.Topic] = true
+	}
+	return nil
+}
+
+// retryStage returns the position in the retry chain of the topic msg was consumed from, or -1 for other topics.
+func (c *KafkaConsumer) retryStage(msg *kafka.Message) int {
+	if msg.TopicPartition.Topic == nil {
+		return -1
+	}
+	for i, stage := range c.retryTopics {
+		if stage.Topic == *msg.TopicPartition.Topic {
+			return i
+		}
+	}
+	return -1
+}
+
+// originalTopic returns the topic msg was first consumed from: the topic it came in for retried messages,
+// the topic it was consumed from otherwise.
+func (c *KafkaConsumer) originalTopic(msg *kafka.Message) string {
+	if c.retryStage(msg) >= 0 {
+		return Headers(msg)[HeaderOriginalTopic]
+	}
+	if msg.TopicPartition.Topic == nil {
+		return ""
+	}
+	return *msg.TopicPartition.Topic
+}
+
+/*
+forwardFailed sends msg, which has failed with f, along the retry chain without committing its offset:
+to the retry topic after the one it was consumed from, to be processed again once the delay of that topic
+has passed (see HeaderNotBefore). Permanent failures, and messages that have gone through the whole chain,
+are sent to the DLQ instead.
+
+Panics if the message could not be sent, see sendFailed.
+*/
+func (c *KafkaConsumer) forwardFailed(msg *kafka.Message, f failure, workerID int) {
+	next := c.retryStage(msg) + 1
+	if Classify(f.err).Permanent() || next >= len(c.retryTopics) {
+		c.produceFailed(msg, f, workerID, c.dlqTopic, time.Time{})
+		return
+	}
+	stage := c.retryTopics[next]
+	c.produceFailed(msg, f, workerID, stage.Topic, f.lastAt.Add(stage.Delay))
+}
+
+/*
+RunRetries processes the messages of the retry topics until ctx is cancelled.
+Returns at once if no retry topics are configured.
+
+Behavior:
+  - Reads every retry topic with a consumer group of its own, next to the workers.
+  - Holds back messages that are not due yet: their partition is paused and rewound to them,
+    and resumed once their HeaderNotBefore time has come. Messages of a retry topic are forwarded
+    with the same delay, so every message behind a held back one is not due either.
+  - Processes due messages like the workers do and commits them.
+  - Forwards messages that fail again to the next retry topic, or to the DLQ, see forwardFailed.
+  - Holds messages back during database outages, checking the connection every dbConnectionCheckDelay.
+  - Panics if a message can't be forwarded or committed, like the workers.
+*/
+func (c *KafkaConsumer) RunRetries(ctx context.Context, storage repository.Storage, cache cache.Cache, logger logger.Logger) {
+	if c.retries == nil {
+		return
+	}
+	logger.LogInfo("retry consumer — receiving messages of the retry topics", "layer", "broker.kafka")
+	held := make(map[string]heldPartition)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+			c.resumeDue(held, logger)
+			event := c.retries.Poll(100)
+			switch eventType := event.(type) {
+			case *kafka.Message:
+				if notBefore := notBefore(eventType); time.Now().Before(notBefore) {
+					c.holdBack(eventType, notBefore, held, logger)
+					continue
+				}
+				c.processRetry(ctx, eventType, storage, cache, logger, held)
+			case kafka.Error:
+				logger.LogError("retry consumer — event type error", eventType, "layer", "broker.kafka")
+				time.Sleep(c.eventTypeErrorRetryDelay)
+			}
+		}
+	}
+}
+
+// heldPartition is a retry topic partition paused until its first message is due.
+type heldPartition struct {
+	partition kafka.TopicPartition
+	until     time.Time
+}
+
+// holdBack pauses the partition of msg and rewinds it to msg, so msg is consumed again once the partition is resumed at until.
+func (c *KafkaConsumer) holdBack(msg *kafka.Message, until time.Time, held map[string]heldPartition, logger logger.Logger) {
+	partition := kafka.TopicPartition{Topic: msg.TopicPartition.Topic, Partition: msg.TopicPartition.Partition}
+	if err := c.retries.Pause([]kafka.TopicPartition{partition}); err != nil {
+		logger.LogError("retry consumer — failed to pause partition", err, "topic", *partition.Topic, "partition", fmt.Sprintf("%d", partition.Partition), "layer", "broker.kafka")
+	}
+	partition.Offset = msg.TopicPartition.Offset
+	if err := c.retries.Seek(partition, 0); err != nil {
+		logger.LogError("retry consumer — failed to rewind partition", err, "topic", *partition.Topic, "partition", fmt.Sprintf("%d", partition.Partition), "layer", "broker.kafka")
+	}
+	held[fmt.Sprintf("%s/%d", *partition.Topic, partition.Partition)] = heldPartition{partition: partition, until: until}
+	logger.Debug("retry consumer — message is not due yet, partition paused", "orderUID", ToStr(msg.Key), "notBefore", until.Format(time.RFC3339), "layer", "broker.kafka")
+}
+
+// resumeDue resumes the held partitions whose first message is due.
+// A partition that has been revoked meanwhile can't be resumed; it starts unpaused once it is assigned again.
+func (c *KafkaConsumer) resumeDue(held map[string]heldPartition, logger logger.Logger) {
+	now := time.Now()
+	for key, partition := range held {
+		if now.Before(partition.until) {
+			continue
+		}
+		delete(held, key)
+		if err := c.retries.Resume([]kafka.TopicPartition{partition.partition}); err != nil {
+			logger.Debug("retry consumer — failed to resume partition", "partition", key, "err", err.Error(), "layer", "broker.kafka")
+		}
+	}
+}
+
+// processRetry processes a due message of a retry topic once, then commits it or forwards it along the retry chain.
+func (c *KafkaConsumer) processRetry(ctx context.Context, msg *kafka.Message, storage repository.Storage, cache cache.Cache, logger logger.Logger, held map[string]heldPartition) {
+	headers := Headers(msg)
+	retryCnt, _ := strconv.Atoi(headers[HeaderRetryCount])
+	f := failure{retryCnt: retryCnt + 1}
+	err := c.handle(ctx, msg, storage, cache, logger, retryWorkerID)
+	if ctx.Err() != nil {
+		return
+	}
+	if errors.Is(err, errs.ErrDuplicate) {
+		err = nil
+	}
+	if errors.Is(err, errs.ErrUnavailable) {
+		c.holdBack(msg, time.Now().Add(c.dbConnectionCheckDelay), held, logger)
+		return
+	}
+	if err != nil {
+		f.failed(err, time.Now())
+		logger.LogError(fmt.Sprintf("retry consumer — retry %d failed", f.retryCnt), err, "orderUID", ToStr(msg.Key), "errorClass", string(Classify(err)), "layer", "broker.kafka")
+		c.forwardFailed(msg, f, retryWorkerID)
+	} else {
+		logger.Debug(fmt.Sprintf("retry consumer — retry %d succeeded", f.retryCnt), "orderUID", ToStr(msg.Key), "layer", "broker.kafka")
+	}
+	if err := commitWithRetry(c.retries, msg, c.commitRetryMax, c.commitRetryDelay); err != nil {
+		_ = c.notifier.Notify(fmt.Sprintf("CRITICAL ERROR — Kafka commit of a retried message failed\norderUID=%s", ToStr(msg.Key)))
+		panic(fmt.Sprintf("retry consumer self-termination: offset commit failed (orderUID=%s)", ToStr(msg.Key)))
+	}
+}
+
+// notBefore returns when msg is due, the zero time if it doesn't say.
+func notBefore(msg *kafka.Message) time.Time {
+	due, err := time.Parse(time.RFC3339Nano, Headers(msg)[HeaderNotBefore])
+	if err != nil {
+		return time.Time{}
+	}
+	return due
+}
@@ -0,0 +1,160 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	mock_cache "github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/cache/mocks"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/configs"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/models"
+	mock_repository "github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository/mocks"
+	mock_logger "github.com/Pur1st2EpicONE/WBTECH-sample-microservice/pkg/logger/mocks"
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/golang/mock/gomock"
+)
+
+func TestValidateRetryTopics(t *testing.T) {
+	valid := []configs.RetryTopic{{Topic: "orders-retry-10s", Delay: 10 * time.Second}, {Topic: "orders-retry-5m", Delay: 5 * time.Minute}}
+	if err := validateRetryTopics(valid, "orders", "orders-dlq"); err != nil {
+		t.Fatalf("expected a valid chain, got %v", err)
+	}
+	for name, chain := range map[string][]configs.RetryTopic{
+		"no topic":    {{Delay: time.Second}},
+		"no delay":    {{Topic: "orders-retry"}},
+		"repeated":    {{Topic: "orders-retry", Delay: time.Second}, {Topic: "orders-retry", Delay: time.Minute}},
+		"main topic":  {{Topic: "orders", Delay: time.Second}},
+		"the dlq too": {{Topic: "orders-dlq", Delay: time.Second}},
+	} {
+		if err := validateRetryTopics(chain, "orders", "orders-dlq"); err == nil {
+			t.Errorf("%s: expected an invalid chain", name)
+		}
+	}
+}
+
+func TestKafkaConsumer_RunRetries(t *testing.T) {
+	cluster, err := kafka.NewMockCluster(1)
+	if err != nil {
+		t.Fatalf("failed to start mock cluster: %v", err)
+	}
+	defer cluster.Close()
+	for _, topic := range []string{"orders", "orders-retry-a", "orders-retry-b", "orders-dlq"} {
+		if err := cluster.CreateTopic(topic, 1, 1); err != nil {
+			t.Fatalf("failed to create topic: %v", err)
+		}
+	}
+
+	controller := gomock.NewController(t)
+	logger := mock_logger.NewMockLogger(controller)
+	logger.EXPECT().LogInfo(gomock.Any(), gomock.Any()).AnyTimes()
+	logger.EXPECT().LogError(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+	logger.EXPECT().Debug(gomock.Any(), gomock.Any()).AnyTimes()
+	storage := mock_repository.NewMockStorage(controller)
+	cache := mock_cache.NewMockCache(controller)
+
+	brokers := []string{cluster.BootstrapServers()}
+	delay := 500 * time.Millisecond
+	consumer, err := NewConsumer(configs.Consumer{Brokers: brokers, Topic: "orders", GroupID: "test", CommitRetryMax: 1,
+		DbConnectionCheckDelay: 100 * time.Millisecond,
+		RetryTopics:            []configs.RetryTopic{{Topic: "orders-retry-a", Delay: delay}, {Topic: "orders-retry-b", Delay: delay}},
+		DLQ: configs.Producer{Brokers: brokers, Topic: "orders-dlq", RetryAttempts: 1, EventTimeout: 10 * time.Second,
+			Kafka: &configs.KafkaProducer{Acks: "all", CompressionType: "none", BatchSize: 1000}},
+		Kafka: &configs.Kafka{AutoOffsetReset: "earliest"}}, logger)
+	if err != nil {
+		t.Fatalf("failed to create consumer: %v", err)
+	}
+	defer consumer.Close(logger)
+
+	msg := testMessage("orders", 0, 5)
+	msg.Key, msg.Value = []byte("b563feb7b2b84b6test"), validOrderJSON(t, "b563feb7b2b84b6test")
+	failedAt := time.Now()
+	consumer.forwardFailed(msg, failure{err: errors.New("deadlock detected"), firstAt: failedAt, lastAt: failedAt}, 1)
+
+	// fails again in the first retry topic, then is saved from the second one
+	var attempts []time.Time
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	storage.EXPECT().SaveOrder(gomock.Any(), gomock.Any()).DoAndReturn(func(context.Context, *models.Order) error {
+		attempts = append(attempts, time.Now())
+		return errors.New("deadlock detected")
+	})
+	storage.EXPECT().SaveOrder(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, order *models.Order) error {
+		attempts = append(attempts, time.Now())
+		if order.Ingest.Topic != "orders" || order.Ingest.Offset != 5 {
+			t.Errorf("expected the order to be recorded where it was first consumed, got %s/%d", order.Ingest.Topic, order.Ingest.Offset)
+		}
+		cancel()
+		return nil
+	})
+	consumer.RunRetries(ctx, storage, cache, logger)
+
+	if len(attempts) != 2 {
+		t.Fatalf("expected 2 retries, got %d", len(attempts))
+	}
+	if attempts[0].Sub(failedAt) < delay || attempts[1].Sub(attempts[0]) < delay {
+		t.Fatalf("expected every retry to wait for its delay, got %v and %v", attempts[0].Sub(failedAt), attempts[1].Sub(attempts[0]))
+	}
+}
+
+func TestKafkaConsumer_forwardFailed(t *testing.T) {
+	cluster, err := kafka.NewMockCluster(1)
+	if err != nil {
+		t.Fatalf("failed to start mock cluster: %v", err)
+	}
+	defer cluster.Close()
+
+	controller := gomock.NewController(t)
+	logger := mock_logger.NewMockLogger(controller)
+	logger.EXPECT().LogInfo(gomock.Any(), gomock.Any()).AnyTimes()
+
+	brokers := []string{cluster.BootstrapServers()}
+	dlq, err := NewProducer(configs.Producer{Brokers: brokers, RetryAttempts: 1, EventTimeout: 10 * time.Second,
+		Kafka: &configs.KafkaProducer{Acks: "all", CompressionType: "none", BatchSize: 1000}}, logger)
+	if err != nil {
+		t.Fatalf("failed to create producer: %v", err)
+	}
+	defer dlq.Close()
+	consumer := &KafkaConsumer{dlq: dlq, dlqTopic: "orders-dlq",
+		retryTopics: []configs.RetryTopic{{Topic: "orders-retry-10s", Delay: 10 * time.Second}, {Topic: "orders-retry-5m", Delay: 5 * time.Minute}}}
+
+	failedAt := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	transient := failure{err: errors.New("deadlock detected"), firstAt: failedAt, lastAt: failedAt}
+	consumer.forwardFailed(testMessage("orders", 0, 1), transient, 1)
+	consumer.forwardFailed(testMessage("orders-retry-10s", 0, 2), transient, 0)
+	consumer.forwardFailed(testMessage("orders-retry-5m", 0, 3), transient, 0)
+	consumer.forwardFailed(testMessage("orders", 0, 4), failure{err: classified(ClassDecode, errors.New("bad json")), firstAt: failedAt, lastAt: failedAt}, 1)
+
+	reader, err := kafka.NewConsumer(&kafka.ConfigMap{"bootstrap.servers": brokers[0], "group.id": "test", "auto.offset.reset": "earliest"})
+	if err != nil {
+		t.Fatalf("failed to create consumer: %v", err)
+	}
+	defer func() { _ = reader.Close() }()
+	if err := reader.SubscribeTopics([]string{"orders-retry-10s", "orders-retry-5m", "orders-dlq"}, nil); err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+	received := make(map[string][]map[string]string)
+	for range 4 {
+		msg, err := reader.ReadMessage(10 * time.Second)
+		if err != nil {
+			t.Fatalf("failed to read the forwarded messages: %v", err)
+		}
+		received[*msg.TopicPartition.Topic] = append(received[*msg.TopicPartition.Topic], Headers(msg))
+	}
+
+	if got := received["orders-retry-10s"]; len(got) != 1 || got[0][HeaderNotBefore] != "2025-01-01T10:00:10Z" || got[0][HeaderOriginalOffset] != "1" {
+		t.Errorf("expected the failed message in the first retry topic, due after its delay, got %v", got)
+	}
+	if got := received["orders-retry-5m"]; len(got) != 1 || got[0][HeaderNotBefore] != "2025-01-01T10:05:00Z" || got[0][HeaderOriginalTopic] != "orders-retry-10s" {
+		t.Errorf("expected the message failed in the first retry topic in the second one, got %v", got)
+	}
+	got := received["orders-dlq"]
+	if len(got) != 2 {
+		t.Fatalf("expected the message through the whole chain and the permanent failure in the DLQ, got %v", got)
+	}
+	for _, headers := range got {
+		if _, ok := headers[HeaderNotBefore]; ok {
+			t.Errorf("expected no due time for DLQ messages, got %v", headers)
+		}
+	}
+}
@@ -1,6 +1,8 @@
 package broker
 
 import (
+	"context"
+
 	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/broker/kafka"
 	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/configs"
 	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/pkg/logger"
@@ -35,3 +37,34 @@ func NewProducer(config configs.Producer, logger logger.Logger) (Producer, error
 	}
 	return producer, nil
 }
+
+/*
+Reader defines a reader of a topic on behalf of a consumer group, used by tooling such as
+the dlq subcommand rather than by the service itself.
+
+A Reader is responsible for:
+  - Reading the messages the group hasn't dealt with yet, or the whole topic, up to its current end.
+  - Committing the messages the group has dealt with, so a later read resumes after them.
+  - Closing the reader and releasing any resources.
+*/
+type Reader interface {
+	// Read calls fn with every message from the committed offsets of the group, or from the beginning
+	// if fromStart is set, up to the end of the topic as of the start of the call.
+	// It stops at the first error fn returns.
+	Read(ctx context.Context, fromStart bool, fn func(message configs.Message) error) error
+
+	// Commit records that message, and every message before it in its partition, has been dealt with.
+	Commit(message configs.Message) error
+
+	// Close terminates the reader and cleans up resources.
+	Close()
+}
+
+// NewReader creates a Reader of config.Topic that belongs to the consumer group config.GroupID.
+func NewReader(config configs.Producer) (Reader, error) {
+	reader, err := kafka.NewReader(config)
+	if err != nil {
+		return nil, err
+	}
+	return reader, nil
+}
@@ -24,10 +24,12 @@ import (
 type Cache interface {
 	GetCachedOrder(orderID string) (*models.Order, bool)
 	CacheOrder(order *models.Order, logger logger.Logger)
+	InvalidateOrder(orderID string, logger logger.Logger)
 	CacheCleaner(ctx context.Context, logger logger.Logger, dbStatus chan bool)
 }
 
 // NewCache creates a new in-memory cache instance, wired to the storage and logger.
-func NewCache(storage repository.Storage, config configs.Cache, logger logger.Logger) Cache {
-	return memory.NewCache(storage, config, logger)
+// Cancelling ctx aborts preloading orders from storage.
+func NewCache(ctx context.Context, storage repository.Storage, config configs.Cache, logger logger.Logger) Cache {
+	return memory.NewCache(ctx, storage, config, logger)
 }
@@ -35,8 +35,8 @@ type Cache struct {
 }
 
 // NewCache creates a new in-memory cache and preloads it with recent orders
-// from storage if enabled in configuration.
-func NewCache(storage repository.Storage, config configs.Cache, logger logger.Logger) *Cache {
+// from storage if enabled in configuration. Cancelling ctx aborts the preload.
+func NewCache(ctx context.Context, storage repository.Storage, config configs.Cache, logger logger.Logger) *Cache {
 	if !config.SaveInCache || config.CacheSize < 1 {
 		return new(Cache)
 	}
@@ -45,7 +45,7 @@ func NewCache(storage repository.Storage, config configs.Cache, logger logger.Lo
 	cachedOrders := make(map[string]*CachedOrder, config.CacheSize)
 	queue = newQueue(config.CacheSize)
 
-	allOrders, err := storage.GetOrders(config.CacheSize)
+	allOrders, err := storage.GetOrders(ctx, config.CacheSize)
 	if err != nil {
 		logger.LogError("cache — failed to load orders from database: %v", err, "layer", "cache.memory")
 	} else {
@@ -139,13 +139,22 @@ func (c *Cache) GetCachedOrder(orderID string) (*models.Order, bool) {
 // CacheOrder adds or updates an order in the cache.
 // Evicts the oldest order if the cache is full.
 // Logs information when a new order is added.
+//
+// A cached order is replaced only by a later version of it, so an amended order can be put
+// in place of the old copy, while a reader that fetched the old copy before the amendment
+// can't bring it back.
 func (c *Cache) CacheOrder(order *models.Order, logger logger.Logger) {
 	if c.queue == nil {
 		return
 	}
 	c.mu.Lock()
 	if cachedOrder, found := c.cachedOrders[order.OrderUID]; found {
-		cachedOrder.lastAccess.Store(time.Now().UnixNano())
+		if order.Version > cachedOrder.order.Version {
+			c.cachedOrders[order.OrderUID] = newCachedOrder(order)
+			logger.Debug("cache — order replaced by a later version", "orderUID", order.OrderUID, "layer", "cache.memory")
+		} else {
+			cachedOrder.lastAccess.Store(time.Now().UnixNano())
+		}
 	} else {
 		rewriteId := c.queue.enqueue(order.OrderUID)
 		if rewriteId != order.OrderUID {
@@ -157,6 +166,21 @@ func (c *Cache) CacheOrder(order *models.Order, logger logger.Logger) {
 	c.mu.Unlock()
 }
 
+// InvalidateOrder removes an order from the cache, so the next read fetches a fresh copy from storage.
+// Used when a stored order changes, e.g. when one of its items gets a new status.
+func (c *Cache) InvalidateOrder(orderID string, logger logger.Logger) {
+	if c.queue == nil {
+		return
+	}
+	c.mu.Lock()
+	_, found := c.cachedOrders[orderID]
+	delete(c.cachedOrders, orderID)
+	c.mu.Unlock()
+	if found {
+		logger.Debug("cache — order invalidated", "orderUID", orderID, "layer", "cache.memory")
+	}
+}
+
 // CacheCleaner runs in the background and periodically removes expired orders.
 //
 // The cleaner monitors database connectivity and pauses if the DB is unreachable,
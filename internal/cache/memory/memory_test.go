@@ -40,9 +40,9 @@ func TestNewCache_WithStorageMock(t *testing.T) {
 	storageMock := mock_repository.NewMockStorage(controller)
 	mockLogger := mock_logger.NewMockLogger(controller)
 	mockLogger.EXPECT().LogInfo("cache — load from database completed", "layer", "cache.memory")
-	storageMock.EXPECT().GetOrders(5).Return([]*models.Order{{OrderUID: "1"}, {OrderUID: "2"}}, nil)
+	storageMock.EXPECT().GetOrders(gomock.Any(), 5).Return([]*models.Order{{OrderUID: "1"}, {OrderUID: "2"}}, nil)
 
-	cache := NewCache(storageMock, configs.Cache{
+	cache := NewCache(context.Background(), storageMock, configs.Cache{
 		SaveInCache:     true,
 		CacheSize:       5,
 		BgCleanup:       false,
@@ -67,7 +67,7 @@ func TestNewCache_DisabledCache(t *testing.T) {
 		CacheSize:   0,
 	}
 
-	cache := NewCache(storageMock, config, mockLogger)
+	cache := NewCache(context.Background(), storageMock, config, mockLogger)
 
 	if cache == nil || cache.cachedOrders != nil {
 		t.Errorf("expected empty cache, got %+v", cache)
@@ -81,7 +81,7 @@ func TestNewCache_StorageError(t *testing.T) {
 	storageMock := mock_repository.NewMockStorage(controller)
 	mockLogger := mock_logger.NewMockLogger(controller)
 
-	storageMock.EXPECT().GetOrders(5).Return(nil, fmt.Errorf("db error"))
+	storageMock.EXPECT().GetOrders(gomock.Any(), 5).Return(nil, fmt.Errorf("db error"))
 	mockLogger.EXPECT().LogError("cache — failed to load orders from database: %v", gomock.Any(), "layer", "cache.memory")
 
 	config := configs.Cache{
@@ -92,7 +92,7 @@ func TestNewCache_StorageError(t *testing.T) {
 		CleanupInterval: time.Minute,
 	}
 
-	cache := NewCache(storageMock, config, mockLogger)
+	cache := NewCache(context.Background(), storageMock, config, mockLogger)
 
 	if len(cache.cachedOrders) != 0 {
 		t.Errorf("expected 0 cached orders on error, got %d", len(cache.cachedOrders))
@@ -172,6 +172,27 @@ func TestCacheOrder_UpdateExisting(t *testing.T) {
 	}
 }
 
+func TestCacheOrder_LaterVersion(t *testing.T) {
+	controller := gomock.NewController(t)
+	mockLogger := mock_logger.NewMockLogger(controller)
+	mockLogger.EXPECT().Debug("cache — order replaced by a later version", gomock.Any())
+	cache := &Cache{
+		queue:        newQueue(10),
+		cachedOrders: map[string]*CachedOrder{"1": newCachedOrder(&models.Order{OrderUID: "1", Version: 1})},
+	}
+
+	amended := &models.Order{OrderUID: "1", Version: 2}
+	cache.CacheOrder(amended, mockLogger)
+	if got, _ := cache.GetCachedOrder("1"); got != amended {
+		t.Fatalf("expected the later version to replace the cached order, got %+v", got)
+	}
+
+	cache.CacheOrder(&models.Order{OrderUID: "1", Version: 1}, mockLogger)
+	if got, _ := cache.GetCachedOrder("1"); got != amended {
+		t.Fatalf("expected an earlier version to leave the cached order alone, got %+v", got)
+	}
+}
+
 func TestCacheOrder_NewOrder_Overflow(t *testing.T) {
 	controller := gomock.NewController(t)
 	defer controller.Finish()
@@ -187,13 +208,13 @@ func TestCacheOrder_NewOrder_Overflow(t *testing.T) {
 		OrderTTL:        time.Second * 5,
 	}
 
-	mockStorage.EXPECT().GetOrders(gomock.Any()).Return([]*models.Order{}, nil).AnyTimes()
+	mockStorage.EXPECT().GetOrders(gomock.Any(), gomock.Any()).Return([]*models.Order{}, nil).AnyTimes()
 	mockLogger.EXPECT().LogInfo("cache — load from database completed", "layer", "cache.memory")
 	mockLogger.EXPECT().LogInfo("cache — order saved", "orderUID", "1", "layer", "cache.memory")
 	mockLogger.EXPECT().LogInfo("cache — order saved", "orderUID", "2", "layer", "cache.memory")
 	mockLogger.EXPECT().LogInfo("cache — order saved", "orderUID", "3", "layer", "cache.memory")
 
-	cache := NewCache(mockStorage, config, mockLogger)
+	cache := NewCache(context.Background(), mockStorage, config, mockLogger)
 
 	order1 := &models.Order{OrderUID: "1"}
 	order2 := &models.Order{OrderUID: "2"}
@@ -296,3 +317,28 @@ func TestCacheCleaner_DBDown(t *testing.T) {
 		t.Errorf("expected pauseCleaner to be false after DB is restored")
 	}
 }
+
+func TestInvalidateOrder(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	mockLogger := mock_logger.NewMockLogger(controller)
+	mockLogger.EXPECT().Debug("cache — order invalidated", gomock.Any()).Times(1)
+
+	cache := &Cache{
+		cachedOrders: map[string]*CachedOrder{"1": newCachedOrder(&models.Order{OrderUID: "1"})},
+		queue:        newQueue(2),
+	}
+
+	cache.InvalidateOrder("1", mockLogger)
+	cache.InvalidateOrder("2", mockLogger) // not cached, nothing to log
+
+	if _, found := cache.GetCachedOrder("1"); found {
+		t.Error("order 1 is still cached")
+	}
+}
+
+func TestInvalidateOrder_QueueNil(t *testing.T) {
+	cache := &Cache{}
+	cache.InvalidateOrder("1", nil)
+}
@@ -74,3 +74,15 @@ func (mr *MockCacheMockRecorder) GetCachedOrder(orderID interface{}) *gomock.Cal
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCachedOrder", reflect.TypeOf((*MockCache)(nil).GetCachedOrder), orderID)
 }
+
+// InvalidateOrder mocks base method.
+func (m *MockCache) InvalidateOrder(orderID string, logger logger.Logger) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "InvalidateOrder", orderID, logger)
+}
+
+// InvalidateOrder indicates an expected call of InvalidateOrder.
+func (mr *MockCacheMockRecorder) InvalidateOrder(orderID, logger interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InvalidateOrder", reflect.TypeOf((*MockCache)(nil).InvalidateOrder), orderID, logger)
+}
@@ -23,12 +23,13 @@ import (
 	"os"
 	"time"
 
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/pkg/fieldcrypt"
 	"github.com/joho/godotenv"
 	"github.com/spf13/viper"
 )
 
 // App holds all top-level configuration for the application,
-// including server, database, cache, consumer, logger, notifier, and worker settings.
+// including server, database, cache, consumer, logger, notifier, outbox, retention, and worker settings.
 type App struct {
 	Server          Server
 	Database        Database
@@ -36,6 +37,8 @@ type App struct {
 	Cache           Cache
 	Logger          Logger
 	Notifier        Notifier
+	Outbox          Outbox
+	Retention       Retention
 	Workers         int
 	RestartOnPanic  bool
 	RestartDelay    time.Duration
@@ -50,6 +53,7 @@ type Server struct {
 	WriteTimeout    time.Duration // maximum duration before timing out writes
 	MaxHeaderBytes  int           // maximum size of request headers
 	ShutdownTimeout time.Duration // graceful shutdown timeout
+	AdminToken      string        // bearer token for admin endpoints, which are disabled without it
 }
 
 // Database holds database connection parameters.
@@ -65,6 +69,22 @@ type Database struct {
 	MaxIdleConns    int
 	ConnMaxLifetime time.Duration
 	ConnMaxIdleTime time.Duration
+	QueryTimeout    time.Duration
+	Replicas        []string      // read replica hosts as host or host:port; the port defaults to Port
+	ReplicaMaxLag   time.Duration // replicas lagging further behind the primary are taken out of rotation
+	Shards          []Shard       // orders are spread over these databases by shard key; empty disables sharding
+
+	// Keyring encrypts the personal data of deliveries in Postgres; nil stores it as plaintext
+	Keyring *fieldcrypt.Keyring
+}
+
+// Shard is a database that owns the orders whose numeric shard key falls into [From, To].
+// DSN is a Postgres connection string; environment variables in it (e.g. ${DB_PASSWORD}) are expanded.
+type Shard struct {
+	Name string
+	From int
+	To   int
+	DSN  string
 }
 
 // Cache contains in-memory caching configuration.
@@ -109,13 +129,34 @@ func Load() (App, error) {
 		return App{}, fmt.Errorf("viper — %v", err)
 	}
 
+	database := dbConfig()
+	shards, err := shardsConfig()
+	if err != nil {
+		return App{}, fmt.Errorf("viper — failed to read database.shards: %v", err)
+	}
+	database.Shards = shards
+	keyring, err := encryptionConfig()
+	if err != nil {
+		return App{}, fmt.Errorf("failed to load database.encryption keys: %v", err)
+	}
+	database.Keyring = keyring
+	consumer := consConfig()
+	if consumer.Rules, err = rulesConfig(); err != nil {
+		return App{}, fmt.Errorf("viper — failed to read kafka.consumer.rules: %v", err)
+	}
+	if consumer.RetryTopics, err = retryTopicsConfig(); err != nil {
+		return App{}, fmt.Errorf("viper — failed to read kafka.consumer.retry_topics: %v", err)
+	}
+
 	return App{
 		Server:          srvConfig(),
-		Database:        dbConfig(),
+		Database:        database,
 		Cache:           cacheConfig(),
-		Consumer:        consConfig(),
+		Consumer:        consumer,
 		Logger:          loggerConfig(),
 		Notifier:        notifierConfig(),
+		Outbox:          outboxConfig(),
+		Retention:       retentionConfig(),
 		Workers:         viper.GetInt("app.workers.active_consumer_workers"),
 		RestartOnPanic:  viper.GetBool("app.workers.restart_on_panic"),
 		RestartDelay:    viper.GetDuration("app.workers.restart_delay"),
@@ -132,11 +173,16 @@ func srvConfig() Server {
 		WriteTimeout:    viper.GetDuration("server.write_timeout"),
 		MaxHeaderBytes:  viper.GetInt("server.max_header_bytes"),
 		ShutdownTimeout: viper.GetDuration("server.shutdown_timeout"),
+		AdminToken:      os.Getenv("ADMIN_TOKEN"),
 	}
 }
 
+// defaultQueryTimeout bounds database operations whose caller sets no deadline if database.query_timeout is left out.
+const defaultQueryTimeout = 5 * time.Second
+
 // dbConfig reads database-related configuration from viper and environment variables.
 func dbConfig() Database {
+	viper.SetDefault("database.query_timeout", defaultQueryTimeout)
 	return Database{
 		Driver:          viper.GetString("database.driver"),
 		Host:            viper.GetString("database.host"),
@@ -149,7 +195,22 @@ func dbConfig() Database {
 		MaxIdleConns:    viper.GetInt("database.max_idle_conns"),
 		ConnMaxLifetime: viper.GetDuration("database.conn_max_lifetime"),
 		ConnMaxIdleTime: viper.GetDuration("database.conn_max_idle_time"),
+		QueryTimeout:    viper.GetDuration("database.query_timeout"),
+		Replicas:        viper.GetStringSlice("database.replicas"),
+		ReplicaMaxLag:   viper.GetDuration("database.replica_max_lag"),
+	}
+}
+
+// shardsConfig reads the shard list from viper and expands environment variables in the DSNs.
+func shardsConfig() ([]Shard, error) {
+	var shards []Shard
+	if err := viper.UnmarshalKey("database.shards", &shards); err != nil {
+		return nil, err
+	}
+	for i := range shards {
+		shards[i].DSN = os.ExpandEnv(shards[i].DSN)
 	}
+	return shards, nil
 }
 
 // cacheConfig reads cache settings from viper.
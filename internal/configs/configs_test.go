@@ -2,9 +2,13 @@ package configs_test
 
 import (
 	"os"
+	"path/filepath"
+	"regexp"
 	"testing"
+	"time"
 
 	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/configs"
+	"github.com/spf13/viper"
 )
 
 func TestLoad_MissingEnvFile(t *testing.T) {
@@ -40,6 +44,36 @@ func TestLoad_ValidConfig(t *testing.T) {
 	}
 }
 
+func TestLoad_DefaultQueryTimeout(t *testing.T) {
+	config, err := os.ReadFile("config.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	env, err := os.ReadFile(".env")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	config = regexp.MustCompile(`(?m)^\s*query_timeout:.*$\n`).ReplaceAll(config, nil)
+	if err := os.WriteFile(filepath.Join(dir, "config.yaml"), config, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, ".env"), env, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(dir)
+	viper.Reset() // config paths of earlier loads are absolute and would be searched first
+	t.Cleanup(viper.Reset)
+
+	cfg, err := configs.Load()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if cfg.Database.QueryTimeout != 5*time.Second {
+		t.Fatalf("expected queries to time out after 5s when query_timeout is left out, got %v", cfg.Database.QueryTimeout)
+	}
+}
+
 func TestProdConfig(t *testing.T) {
 	cfg, err := configs.ProdConfig()
 	if err != nil {
@@ -13,23 +13,61 @@ import (
 type Consumer struct {
 	Brokers                  []string
 	Topic                    string
+	StatusTopic              string // topic with item status-change events, optional
 	ClientID                 string
 	GroupID                  string
 	AutoAck                  bool
 	SessionTimeoutMs         int
 	MaxPollIntervalMs        int
-	SaveOrderRetryDelay      time.Duration
+	SaveOrderRetryDelay      time.Duration // delay before the first retry of a transient failure, doubled on every retry
+	SaveOrderRetryMaxDelay   time.Duration // upper bound of the retry delay, 0 for none
 	SaveOrderRetryMax        int
 	CommitRetryDelay         time.Duration
 	CommitRetryMax           int
 	EventTypeErrorsMax       int
 	EventTypeErrorRetryDelay time.Duration
 	DbConnectionCheckDelay   time.Duration
+	BatchSize                int             // max number of orders saved together, 1 saves every order on its own
+	BatchWait                time.Duration   // max time to wait for a batch to fill up
+	Workers                  int             // number of workers the partitions are spread over, see app.workers.active_consumer_workers
+	QueueSize                int             // max number of messages waiting for every worker
+	Rules                    map[string]Rule // business rules orders are checked against, by name; rules left out keep their defaults
+	RetryTopics              []RetryTopic    // chain of retry topics failed messages pass through before the DLQ, in order; empty retries in place
 	DLQ                      Producer
 	Notifier                 Notifier
 	Kafka                    *Kafka // interchangeable
 }
 
+// Rule switches a business rule on or off and sets what happens to orders that break it.
+type Rule struct {
+	Enabled  *bool  // left out, the rule stays enabled
+	Severity string // "reject" or "flag"; empty keeps the default severity of the rule
+}
+
+// RetryTopic is a stage of the retry chain: messages forwarded to Topic are processed again Delay after they failed.
+type RetryTopic struct {
+	Topic string
+	Delay time.Duration
+}
+
+// retryTopicsConfig reads the retry chain from viper.
+func retryTopicsConfig() ([]RetryTopic, error) {
+	var topics []RetryTopic
+	if err := viper.UnmarshalKey("kafka.consumer.retry_topics", &topics); err != nil {
+		return nil, err
+	}
+	return topics, nil
+}
+
+// rulesConfig reads the business rule settings from viper.
+func rulesConfig() (map[string]Rule, error) {
+	var rules map[string]Rule
+	if err := viper.UnmarshalKey("kafka.consumer.rules", &rules); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
 // Kafka contains Kafka-specific configuration options.
 //
 // Allows adjusting commit behavior, offset reset policy, retries,
@@ -52,18 +90,24 @@ func consConfig() Consumer {
 	return Consumer{
 		Brokers:                  viper.GetStringSlice("kafka.consumer.brokers"),
 		Topic:                    viper.GetString("kafka.consumer.topic"),
+		StatusTopic:              viper.GetString("kafka.consumer.status_topic"),
 		ClientID:                 viper.GetString("kafka.consumer.client_id"),
 		GroupID:                  viper.GetString("kafka.consumer.group_id"),
 		AutoAck:                  viper.GetBool("kafka.consumer.auto_ack"),
 		SessionTimeoutMs:         viper.GetInt("kafka.consumer.session_timeout_ms"),
 		MaxPollIntervalMs:        viper.GetInt("kafka.consumer.max_poll_interval_ms"),
 		SaveOrderRetryDelay:      viper.GetDuration("kafka.consumer.save_order_retry_delay"),
+		SaveOrderRetryMaxDelay:   viper.GetDuration("kafka.consumer.save_order_retry_max_delay"),
 		SaveOrderRetryMax:        viper.GetInt("kafka.consumer.save_order_retry_max"),
 		CommitRetryDelay:         viper.GetDuration("kafka.consumer.commit_retry_delay"),
 		CommitRetryMax:           viper.GetInt("kafka.consumer.commit_retry_max"),
 		EventTypeErrorsMax:       viper.GetInt("kafka.consumer.event_type_errors_max"),
 		EventTypeErrorRetryDelay: viper.GetDuration("kafka.consumer.event_type_error_retry_delay"),
 		DbConnectionCheckDelay:   viper.GetDuration("kafka.consumer.db_connection_check_delay"),
+		BatchSize:                viper.GetInt("kafka.consumer.batch_size"),
+		BatchWait:                viper.GetDuration("kafka.consumer.batch_wait"),
+		Workers:                  viper.GetInt("app.workers.active_consumer_workers"),
+		QueueSize:                viper.GetInt("kafka.consumer.queue_size"),
 		DLQ:                      dlqConfig(),
 		Notifier:                 notifierConfig(),
 		Kafka:                    kafkaConfig(),
@@ -85,6 +129,7 @@ func dlqConfig() Producer {
 		Brokers:           viper.GetStringSlice("kafka.dlq.brokers"),
 		Topic:             viper.GetString("kafka.dlq.topic"),
 		ClientID:          viper.GetString("kafka.dlq.client_id"),
+		GroupID:           viper.GetString("kafka.dlq.group_id"),
 		FlushTimeOut:      viper.GetInt("kafka.dlq.flush_time_out_ms"),
 		RetryAttempts:     viper.GetInt("kafka.dlq.produce_retry_attempts"),
 		ProduceRetryDelay: viper.GetDuration("kafka.dlq.produce_retry_delay"),
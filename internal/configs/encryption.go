@@ -0,0 +1,82 @@
+package configs
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/pkg/fieldcrypt"
+	"github.com/spf13/viper"
+)
+
+/*
+encryptionConfig builds the keyring of the field-level encryption of personal data.
+Returns nil if database.encryption.active_key is not set, which disables encryption.
+
+Keys are base64-encoded 32-byte AES keys. They are read from the PII_KEYS environment variable
+as comma-separated id:key pairs, and from the files named <id>.key in database.encryption.keys_dir
+(e.g. mounted secrets). The blind index key is read from PII_INDEX_KEY or, if that is not set,
+from the file database.encryption.index_key_file.
+*/
+func encryptionConfig() (*fieldcrypt.Keyring, error) {
+	active := viper.GetString("database.encryption.active_key")
+	if active == "" {
+		return nil, nil
+	}
+
+	keys := make(map[string][]byte)
+	if env := os.Getenv("PII_KEYS"); env != "" {
+		for _, pair := range strings.Split(env, ",") {
+			id, encoded, ok := strings.Cut(strings.TrimSpace(pair), ":")
+			if !ok {
+				return nil, fmt.Errorf("PII_KEYS: expected id:key pairs, got %q", pair)
+			}
+			key, err := base64.StdEncoding.DecodeString(encoded)
+			if err != nil {
+				return nil, fmt.Errorf("PII_KEYS: key %q is not base64: %v", id, err)
+			}
+			keys[id] = key
+		}
+	}
+	if dir := viper.GetString("database.encryption.keys_dir"); dir != "" {
+		paths, err := filepath.Glob(filepath.Join(dir, "*.key"))
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range paths {
+			key, err := readKeyFile(path)
+			if err != nil {
+				return nil, err
+			}
+			keys[strings.TrimSuffix(filepath.Base(path), ".key")] = key
+		}
+	}
+
+	var indexKey []byte
+	var err error
+	if env := os.Getenv("PII_INDEX_KEY"); env != "" {
+		if indexKey, err = base64.StdEncoding.DecodeString(strings.TrimSpace(env)); err != nil {
+			return nil, fmt.Errorf("PII_INDEX_KEY is not base64: %v", err)
+		}
+	} else if path := viper.GetString("database.encryption.index_key_file"); path != "" {
+		if indexKey, err = readKeyFile(path); err != nil {
+			return nil, err
+		}
+	}
+	return fieldcrypt.New(keys, active, indexKey)
+}
+
+// readKeyFile reads a base64-encoded key from a file.
+func readKeyFile(path string) ([]byte, error) {
+	encoded, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(encoded)))
+	if err != nil {
+		return nil, fmt.Errorf("%s is not base64: %v", path, err)
+	}
+	return key, nil
+}
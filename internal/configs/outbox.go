@@ -0,0 +1,50 @@
+package configs
+
+import (
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// Outbox holds configuration for the outbox relay that publishes events
+// written to the transactional outbox, along with the producer it publishes through.
+type Outbox struct {
+	BatchSize      int           // maximum number of events claimed at once
+	PollInterval   time.Duration // pause between polls when there is nothing to publish
+	Lease          time.Duration // how long claimed events are hidden from other claims
+	RetryBaseDelay time.Duration // delay before the first retry of a failed event, doubled on every failure
+	RetryMaxDelay  time.Duration // upper bound of the retry delay
+	SentRetention  time.Duration // how long published events are kept before they are deleted, 0 keeps them forever
+	PurgeInterval  time.Duration // pause between deletions of published events
+	Producer       Producer
+}
+
+// outboxConfig reads outbox relay settings and its Kafka producer configuration from viper.
+func outboxConfig() Outbox {
+	return Outbox{
+		BatchSize:      viper.GetInt("app.outbox.batch_size"),
+		PollInterval:   viper.GetDuration("app.outbox.poll_interval"),
+		Lease:          viper.GetDuration("app.outbox.lease"),
+		RetryBaseDelay: viper.GetDuration("app.outbox.retry_base_delay"),
+		RetryMaxDelay:  viper.GetDuration("app.outbox.retry_max_delay"),
+		SentRetention:  viper.GetDuration("app.outbox.sent_retention"),
+		PurgeInterval:  viper.GetDuration("app.outbox.purge_interval"),
+		Producer: Producer{
+			Brokers:           viper.GetStringSlice("kafka.outbox.brokers"),
+			Topic:             viper.GetString("kafka.outbox.topic"),
+			ClientID:          viper.GetString("kafka.outbox.client_id"),
+			FlushTimeOut:      viper.GetInt("kafka.outbox.flush_time_out_ms"),
+			RetryAttempts:     viper.GetInt("kafka.outbox.produce_retry_attempts"),
+			ProduceRetryDelay: viper.GetDuration("kafka.outbox.produce_retry_delay"),
+			EventTimeout:      viper.GetDuration("kafka.outbox.event_timeout"),
+			Kafka: &KafkaProducer{
+				Acks:              viper.GetString("kafka.outbox.acks"),
+				EnableIdempotence: viper.GetBool("kafka.outbox.enable_idempotence"),
+				Retries:           viper.GetInt("kafka.outbox.retry_max"),
+				LingerMs:          viper.GetInt("kafka.outbox.linger_ms"),
+				BatchSize:         viper.GetInt("kafka.outbox.batch_size"),
+				CompressionType:   viper.GetString("kafka.outbox.compression_type"),
+			},
+		},
+	}
+}
@@ -14,6 +14,7 @@ type Producer struct {
 	Topic             string         // topic to produce messages to
 	ClientID          string         // producer client ID
 	MsgsToSend        int            // number of messages (orders) to send (order-producer-specific)
+	GroupID           string         // consumer group the dlq subcommand reads the topic with (DLQ-specific)
 	FlushTimeOut      int            // maximum time to wait for message flush
 	RetryAttempts     int            // number of application-level retry attempts
 	ProduceRetryDelay time.Duration  // delay between application-level retry attempts
@@ -36,8 +37,11 @@ type KafkaProducer struct {
 // Message represents a Kafka message payload.
 //
 // Contains topic, key/value data, headers, timestamps, metadata, DLQ flag, and originating worker ID.
+// Messages read from a broker also carry their partition and offset.
 type Message struct {
 	Topic     string
+	Partition int32
+	Offset    int64
 	Key       []byte
 	Value     []byte
 	Headers   map[string]string
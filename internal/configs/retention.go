@@ -0,0 +1,26 @@
+package configs
+
+import (
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// Retention holds configuration for the job that keeps the monthly partitions of the order tables
+// and moves orders past their retention age into archives.
+type Retention struct {
+	Interval        time.Duration // period between runs of the job
+	MaxAge          time.Duration // orders older than this are archived; zero keeps orders forever
+	ArchiveDir      string        // directory the compressed archives are written to
+	PartitionsAhead int           // number of monthly partitions created in advance, after the current one
+}
+
+// retentionConfig reads retention job settings from viper.
+func retentionConfig() Retention {
+	return Retention{
+		Interval:        viper.GetDuration("app.retention.interval"),
+		MaxAge:          viper.GetDuration("app.retention.max_age"),
+		ArchiveDir:      viper.GetString("app.retention.archive_dir"),
+		PartitionsAhead: viper.GetInt("app.retention.partitions_ahead"),
+	}
+}
@@ -0,0 +1,228 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/models"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository/errs"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// maxPatchBytes is the largest amendment request body accepted.
+const maxPatchBytes = 64 << 10
+
+// adminAuth guards admin endpoints with the bearer token from AdminToken (the ADMIN_TOKEN environment variable).
+//
+// Responds with:
+// - 401 Unauthorized if the Authorization header is missing or holds another token
+// - 403 Forbidden if no admin token is configured, which disables admin endpoints altogether
+func (h *Handler) adminAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if h.AdminToken == "" {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "admin endpoints are disabled"})
+			return
+		}
+		token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(h.AdminToken)) != 1 {
+			h.logger.Debug("handler — admin request rejected", "path", c.FullPath(), "layer", "handler")
+			c.Header("WWW-Authenticate", "Bearer")
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "admin token required"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// getOrderProvenance handles GET /api/v1/orders/:orderId/provenance. Admin only.
+//
+// Returns the Kafka message the order was received in: its topic, partition, offset, key,
+// timestamps and the raw payload.
+//
+// Responds with:
+// - 200 OK + provenance JSON
+// - 401 Unauthorized / 403 Forbidden, see adminAuth
+// - 404 Not Found if the order does not exist or was saved without an ingest record
+// - 503 Service Unavailable if the database is down
+// - 504 Gateway Timeout if the database didn't respond in time
+// - 500 Internal Server Error on unexpected failures
+//
+// @Summary Get the Kafka message an order was received in
+// @Description Returns the topic, partition, offset, key and timestamp of the message the order was received in, along with its raw payload.<br>Requires the admin token: <strong>Authorization: Bearer &lt;token&gt;</strong>
+// @Tags Admin
+// @Produce json
+// @Param orderId path string true "Order ID (UUID)"
+// @Success 200 {object} models.OrderProvenance "Order provenance"
+// @Failure 401 {object} ErrorResponse "Missing or invalid admin token"
+// @Failure 403 {object} ErrorResponse "Admin endpoints are disabled"
+// @Failure 404 {object} ErrorResponse "No ingest record for the order"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Failure 503 {object} ErrorResponse "Database unavailable"
+// @Failure 504 {object} ErrorResponse "Database timeout"
+// @Router /api/v1/orders/{orderId}/provenance [get]
+func (h *Handler) getOrderProvenance(c *gin.Context) {
+	orderID := c.Param("orderId")
+	provenance, err := h.service.GetOrderProvenance(c.Request.Context(), orderID)
+	if err != nil {
+		h.logger.Debug("handler — failed to get order provenance", "orderUID", orderID, "layer", "handler")
+		if errors.Is(err, errs.ErrNotFound) {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("%s — no ingest record for the order", orderID)})
+			return
+		}
+		abortWithError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, provenance)
+}
+
+// orderETag returns the entity tag of an order at the given version.
+func orderETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// parseETag returns the order version held by an entity tag made by orderETag.
+func parseETag(tag string) (int, bool) {
+	quoted, ok := strings.CutPrefix(tag, `"`)
+	if !ok {
+		return 0, false
+	}
+	quoted, ok = strings.CutSuffix(quoted, `"`)
+	if !ok {
+		return 0, false
+	}
+	version, err := strconv.Atoi(quoted)
+	return version, err == nil && version > 0
+}
+
+// amendOrder handles PATCH /api/v1/orders/:orderId. Admin only.
+//
+// Applies a JSON Merge Patch (RFC 7386) to the order. Only the delivery and the order fields that are not
+// referenced elsewhere can be changed. The If-Match header must hold the ETag the order was read with,
+// so a change made in the meantime is never overwritten.
+//
+// Responds with:
+// - 200 OK + amended order JSON, with its new version in the ETag header
+// - 400 Bad Request if the body can't be read
+// - 401 Unauthorized / 403 Forbidden, see adminAuth
+// - 404 Not Found if order does not exist
+// - 412 Precondition Failed if the order is no longer at the version in If-Match
+// - 413 Request Entity Too Large if the patch is larger than 64 KiB
+// - 415 Unsupported Media Type unless the patch is sent as application/merge-patch+json or application/json
+// - 422 Unprocessable Entity if the patch is not a JSON object, touches a field that can't be amended or makes the order invalid
+// - 428 Precondition Required if there is no If-Match header
+// - 503 Service Unavailable if the database is down
+// - 504 Gateway Timeout if the database didn't respond in time
+// - 500 Internal Server Error on unexpected failures
+//
+// @Summary Amend an order
+// @Description Applies a JSON Merge Patch to the order, e.g. <strong>{"delivery": {"phone": "+9720000001"}}</strong>.<br>Amendable fields: delivery, entry, locale, internal_signature, customer_id, delivery_service, sm_id, oof_shard.<br>Pass the <strong>ETag</strong> of the order as <strong>If-Match</strong>. Every amendment is recorded along with the order as it was before.<br>Requires the admin token: <strong>Authorization: Bearer &lt;token&gt;</strong>
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param orderId path string true "Order ID (UUID)"
+// @Param If-Match header string true "ETag of the order"
+// @Param patch body object true "JSON Merge Patch"
+// @Success 200 {object} models.Order "Amended order"
+// @Header 200 {string} ETag "New version of the order"
+// @Failure 400 {object} ErrorResponse "Unreadable body"
+// @Failure 401 {object} ErrorResponse "Missing or invalid admin token"
+// @Failure 403 {object} ErrorResponse "Admin endpoints are disabled"
+// @Failure 404 {object} ErrorResponse "Order not found"
+// @Failure 412 {object} ErrorResponse "Order was changed in the meantime"
+// @Failure 413 {object} ErrorResponse "Patch too large"
+// @Failure 415 {object} ErrorResponse "Unsupported content type"
+// @Failure 422 {object} ErrorResponse "Invalid patch"
+// @Failure 428 {object} ErrorResponse "Missing If-Match header"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Failure 503 {object} ErrorResponse "Database unavailable"
+// @Failure 504 {object} ErrorResponse "Database timeout"
+// @Router /api/v1/orders/{orderId} [patch]
+func (h *Handler) amendOrder(c *gin.Context) {
+	orderID := c.Param("orderId")
+	ifMatch := c.GetHeader("If-Match")
+	if ifMatch == "" {
+		c.AbortWithStatusJSON(http.StatusPreconditionRequired, gin.H{"error": "If-Match header with the ETag of the order is required"})
+		return
+	}
+	version, ok := parseETag(ifMatch)
+	if !ok {
+		c.AbortWithStatusJSON(http.StatusPreconditionFailed, gin.H{"error": "If-Match doesn't match the ETag of the order"})
+		return
+	}
+	if contentType := c.ContentType(); contentType != "application/merge-patch+json" && contentType != "application/json" {
+		c.AbortWithStatusJSON(http.StatusUnsupportedMediaType, gin.H{"error": "patch must be sent as application/merge-patch+json"})
+		return
+	}
+	patch, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxPatchBytes))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("patch must not exceed %d bytes", maxPatchBytes)})
+			return
+		}
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "failed to read the patch"})
+		return
+	}
+
+	order, err := h.service.AmendOrder(c.Request.Context(), orderID, version, patch, h.logger)
+	if err != nil {
+		h.logger.Debug("handler — failed to amend order", "orderUID", orderID, "error", err.Error(), "layer", "handler")
+		var invalid *service.InvalidPatchError
+		switch {
+		case errors.As(err, &invalid):
+			c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": invalid.Reason})
+		case errors.Is(err, errs.ErrNotFound):
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("%s — order not found", orderID)})
+		case errors.Is(err, errs.ErrConflict):
+			c.AbortWithStatusJSON(http.StatusPreconditionFailed, gin.H{"error": "order was changed in the meantime, fetch it again"})
+		default:
+			abortWithError(c, err)
+		}
+		return
+	}
+	h.logger.LogInfo("handler — order amended", "orderUID", orderID, "version", strconv.Itoa(order.Version), "layer", "handler")
+	c.Header("ETag", orderETag(order.Version))
+	c.JSON(http.StatusOK, order)
+}
+
+// erasePersonalData handles DELETE /api/v1/customers/:customerId/personal-data. Admin only.
+//
+// Replaces the personal data in every order of the customer with irreversible placeholders and returns
+// the compliance record of the erasure. The reference query parameter (e.g. a ticket number) is kept in the record.
+//
+// Responds with:
+// - 200 OK + erasure record JSON; a customer without orders is recorded with none
+// - 401 Unauthorized / 403 Forbidden, see adminAuth
+// - 503 Service Unavailable if the database is down
+// - 504 Gateway Timeout if the database didn't respond in time
+// - 500 Internal Server Error on unexpected failures
+//
+// @Summary Erase the personal data of a customer
+// @Description Replaces the delivery details (name, phone, address, email) in every order of the customer with placeholders, including the copies in ingested messages and amendments. Items and payment are kept for accounting.<br>The erasure is recorded along with the erased orders and the given reference.<br>Requires the admin token: <strong>Authorization: Bearer &lt;token&gt;</strong>
+// @Tags Admin
+// @Produce json
+// @Param customerId path string true "Customer ID"
+// @Param reference query string false "Reference of the request, e.g. a ticket number"
+// @Success 200 {object} models.PersonalDataErasure "Erasure record"
+// @Failure 401 {object} ErrorResponse "Missing or invalid admin token"
+// @Failure 403 {object} ErrorResponse "Admin endpoints are disabled"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Failure 503 {object} ErrorResponse "Database unavailable"
+// @Failure 504 {object} ErrorResponse "Database timeout"
+// @Router /api/v1/customers/{customerId}/personal-data [delete]
+func (h *Handler) erasePersonalData(c *gin.Context) {
+	customerID := c.Param("customerId")
+	erasure, err := h.service.ErasePersonalData(c.Request.Context(), customerID, models.ErasureSourceAPI, c.Query("reference"), h.logger)
+	if err != nil {
+		h.logger.LogError("handler — failed to erase personal data", err, "customerID", customerID, "layer", "handler")
+		abortWithError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, erasure)
+}
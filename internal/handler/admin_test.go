@@ -0,0 +1,165 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/models"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository/errs"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/service"
+	mock_service "github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/service/mocks"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupAdminRouter(t *testing.T, token string) (*mock_service.MockServiceProvider, *gin.Engine) {
+	h, mockService, _ := setupHandlerWithMock(t)
+	h.AdminToken = token
+	router := gin.New()
+	router.GET("/orders/:orderId/provenance", h.adminAuth(), h.getOrderProvenance)
+	router.PATCH("/orders/:orderId", h.adminAuth(), h.amendOrder)
+	router.DELETE("/customers/:customerId/personal-data", h.adminAuth(), h.erasePersonalData)
+	return mockService, router
+}
+
+func provenanceRequest(router *gin.Engine, orderID, authorization string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/orders/"+orderID+"/provenance", nil)
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func amendRequest(router *gin.Engine, orderID, ifMatch, patch string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPatch, "/orders/"+orderID, strings.NewReader(patch))
+	req.Header.Set("Authorization", "Bearer secret")
+	req.Header.Set("Content-Type", "application/merge-patch+json")
+	if ifMatch != "" {
+		req.Header.Set("If-Match", ifMatch)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestGetOrderProvenance_Success(t *testing.T) {
+	mockService, router := setupAdminRouter(t, "secret")
+
+	provenance := models.OrderProvenance{OrderUID: "aboba", IngestRecord: models.IngestRecord{
+		Topic: "orders", Partition: 2, Offset: 42, Key: "aboba", Payload: []byte(`{"order_uid":"aboba"}`)}}
+	mockService.EXPECT().GetOrderProvenance(gomock.Any(), "aboba").Return(provenance, nil)
+
+	w := provenanceRequest(router, "aboba", "Bearer secret")
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"offset":42`)
+	assert.Contains(t, w.Body.String(), `"payload":{"order_uid":"aboba"}`)
+}
+
+func TestGetOrderProvenance_NotFound(t *testing.T) {
+	mockService, router := setupAdminRouter(t, "secret")
+
+	mockService.EXPECT().GetOrderProvenance(gomock.Any(), "nope").
+		Return(models.OrderProvenance{}, fmt.Errorf("failed to get provenance of order nope: %w", errs.ErrNotFound))
+
+	w := provenanceRequest(router, "nope", "Bearer secret")
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestAdminAuth_WrongToken(t *testing.T) {
+	_, router := setupAdminRouter(t, "secret")
+
+	for _, authorization := range []string{"", "Bearer nope", "secret", "Basic secret"} {
+		w := provenanceRequest(router, "aboba", authorization)
+		assert.Equal(t, http.StatusUnauthorized, w.Code, "authorization %q", authorization)
+	}
+}
+
+func TestAdminAuth_Disabled(t *testing.T) {
+	_, router := setupAdminRouter(t, "")
+
+	w := provenanceRequest(router, "aboba", "Bearer ")
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestAmendOrder_Success(t *testing.T) {
+	mockService, router := setupAdminRouter(t, "secret")
+
+	patch := `{"delivery":{"phone":"+9720000001"}}`
+	mockService.EXPECT().AmendOrder(gomock.Any(), "aboba", 1, []byte(patch), gomock.Any()).
+		Return(&models.Order{OrderUID: "aboba", Version: 2}, nil)
+
+	w := amendRequest(router, "aboba", `"1"`, patch)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"2"`, w.Header().Get("ETag"))
+	assert.Contains(t, w.Body.String(), `"version":2`)
+}
+
+func TestAmendOrder_MissingIfMatch(t *testing.T) {
+	_, router := setupAdminRouter(t, "secret")
+
+	w := amendRequest(router, "aboba", "", `{"entry":"WBIL"}`)
+
+	assert.Equal(t, http.StatusPreconditionRequired, w.Code)
+}
+
+func TestAmendOrder_Stale(t *testing.T) {
+	mockService, router := setupAdminRouter(t, "secret")
+
+	mockService.EXPECT().AmendOrder(gomock.Any(), "aboba", 1, gomock.Any(), gomock.Any()).
+		Return(nil, fmt.Errorf("order aboba is at version 2: %w", errs.ErrConflict))
+
+	w := amendRequest(router, "aboba", `"1"`, `{"entry":"WBIL"}`)
+
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+}
+
+func TestAmendOrder_InvalidPatch(t *testing.T) {
+	mockService, router := setupAdminRouter(t, "secret")
+
+	mockService.EXPECT().AmendOrder(gomock.Any(), "aboba", 1, gomock.Any(), gomock.Any()).
+		Return(nil, &service.InvalidPatchError{Reason: "items can't be amended"})
+
+	w := amendRequest(router, "aboba", `"1"`, `{"items":[]}`)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Contains(t, w.Body.String(), "items can't be amended")
+}
+
+func TestErasePersonalData_Success(t *testing.T) {
+	mockService, router := setupAdminRouter(t, "secret")
+
+	mockService.EXPECT().ErasePersonalData(gomock.Any(), "test", models.ErasureSourceAPI, "TICKET-1", gomock.Any()).
+		Return(models.PersonalDataErasure{CustomerID: "test", OrderUIDs: []string{"uid1"}, Source: models.ErasureSourceAPI, Reference: "TICKET-1"}, nil)
+
+	req := httptest.NewRequest(http.MethodDelete, "/customers/test/personal-data?reference=TICKET-1", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"order_uids":["uid1"]`)
+}
+
+func TestErasePersonalData_Unavailable(t *testing.T) {
+	mockService, router := setupAdminRouter(t, "secret")
+
+	mockService.EXPECT().ErasePersonalData(gomock.Any(), "test", models.ErasureSourceAPI, "", gomock.Any()).
+		Return(models.PersonalDataErasure{}, errs.ErrUnavailable)
+
+	req := httptest.NewRequest(http.MethodDelete, "/customers/test/personal-data", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+}
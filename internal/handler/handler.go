@@ -4,11 +4,14 @@
 package handler
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
-	"strings"
+	"strconv"
+	"time"
 
-	_ "github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/models"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/models"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository/errs"
 	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/service"
 	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/pkg/logger"
 	"github.com/gin-gonic/gin"
@@ -23,6 +26,7 @@ type Handler struct {
 	service      service.ServiceProvider // service layer interface
 	logger       logger.Logger           // structured logger
 	TemplatePath string                  // path pattern to HTML templates
+	AdminToken   string                  // bearer token for admin endpoints; they are disabled if empty
 }
 
 // NewHandler constructs a new Handler with the given service and logger.
@@ -39,7 +43,8 @@ func NewHandler(service service.ServiceProvider, logger logger.Logger) *Handler
 // Includes:
 // - Swagger documentation at /swagger/*any
 // - Static files under /static
-// - API endpoints under /api/v1
+// - API endpoints under /api/v1 (single order, order listing, status history, tracking and customer lookups)
+// - Admin API endpoints under /api/v1, behind a bearer token (order provenance, amendments and erasure of personal data)
 // - HTML pages at root and /orders/:orderId
 func (h *Handler) InitRoutes() *gin.Engine {
 	router := gin.New()
@@ -51,7 +56,14 @@ func (h *Handler) InitRoutes() *gin.Engine {
 
 	api := router.Group("/api/v1")
 	{
+		api.GET("/orders", h.listOrders)
 		api.GET("/orders/:orderId", h.getOrder)
+		api.GET("/orders/:orderId/history", h.getOrderHistory)
+		api.GET("/tracking/:trackNumber", h.getOrderByTrackNumber)
+		api.GET("/customers/:customerId/orders", h.getCustomerOrders)
+		api.GET("/orders/:orderId/provenance", h.adminAuth(), h.getOrderProvenance)
+		api.PATCH("/orders/:orderId", h.adminAuth(), h.amendOrder)
+		api.DELETE("/customers/:customerId/personal-data", h.adminAuth(), h.erasePersonalData)
 	}
 
 	basePath := router.Group("/")
@@ -69,15 +81,33 @@ type ErrorResponse struct {
 	Error string `json:"error"`
 }
 
+// abortWithError responds with a status code that matches the storage error class.
+//
+// Details of the underlying error are never exposed to the client.
+func abortWithError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, errs.ErrUnavailable):
+		c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "database is temporarily unavailable, try again later"})
+	case errors.Is(err, errs.ErrTimeout):
+		c.AbortWithStatusJSON(http.StatusGatewayTimeout, gin.H{"error": "database took too long to respond, try again later"})
+	default:
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "something broke on our end, sorry :("})
+	}
+}
+
 // getOrder handles GET /api/v1/orders/:orderId.
 //
-// Returns order data in JSON with cache status indicated in the X-Cache header.
+// Returns order data in JSON with cache status indicated in the X-Cache header
+// and the version of the order in the ETag header, for amending it.
+// The request context is passed down, so a client disconnect aborts the database query.
 // - HIT: order retrieved from cache
 // - MISS: order retrieved from database
 //
 // Responds with:
 // - 200 OK + order JSON
 // - 404 Not Found if order does not exist
+// - 503 Service Unavailable if the order is not cached and the database is down
+// - 504 Gateway Timeout if the database didn't respond in time
 // - 500 Internal Server Error on unexpected failures
 //
 // @Summary Get order by UID with cache status indication
@@ -88,18 +118,21 @@ type ErrorResponse struct {
 // @Success 200 {object} models.Order "Order data"
 // @Failure 404 {object} ErrorResponse "Order not found"
 // @Failure 500 {object} ErrorResponse "Internal server error"
+// @Failure 503 {object} ErrorResponse "Database unavailable"
+// @Failure 504 {object} ErrorResponse "Database timeout"
 // @Header 200 {string} X-Cache "Cache status: HIT or MISS"
+// @Header 200 {string} ETag "Version of the order"
 // @Router /api/v1/orders/{orderId} [get]
 func (h *Handler) getOrder(c *gin.Context) {
 	orderID := c.Param("orderId")
-	order, fromCache, err := h.service.GetOrder(orderID, h.logger)
+	order, fromCache, err := h.service.GetOrder(c.Request.Context(), orderID, h.logger)
 	if err != nil {
 		h.logger.Debug("handler — failed to get order", "orderUID", orderID, "layer", "handler")
-		if strings.Contains(err.Error(), "sql: no rows in result set") {
+		if errors.Is(err, errs.ErrNotFound) {
 			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("%s — order not found", orderID)})
 			return
 		}
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "something broke on our end, sorry :("})
+		abortWithError(c, err)
 		return
 	}
 	if fromCache {
@@ -107,5 +140,256 @@ func (h *Handler) getOrder(c *gin.Context) {
 	} else {
 		c.Header("X-Cache", "MISS") // I guess they never miss, huh? 💀
 	}
+	if order.Version > 0 {
+		c.Header("ETag", orderETag(order.Version))
+	}
+	c.JSON(http.StatusOK, order)
+}
+
+const (
+	defaultPageSize = 20  // orders per page when the client doesn't specify a limit
+	maxPageSize     = 100 // upper bound for the limit query parameter
+)
+
+// listOrders handles GET /api/v1/orders.
+//
+// Returns a page of orders that match the filters, together with a cursor for the next page.
+// Orders are always read from the database, since the cache can't answer filtered queries.
+//
+// Responds with:
+// - 200 OK + page JSON (next_cursor is omitted on the last page)
+// - 400 Bad Request on malformed query parameters or cursor
+// - 503 Service Unavailable if the database is down
+// - 504 Gateway Timeout if the database didn't respond in time
+// - 500 Internal Server Error on unexpected failures
+//
+// @Summary List orders
+// @Description Returns orders page by page using cursor (keyset) pagination.<br>Pass <strong>next_cursor</strong> from the previous response as <strong>cursor</strong> to get the next page, keeping the same filters and sort.
+// @Tags Orders
+// @Produce json
+// @Param customer_id query string false "Filter by customer ID"
+// @Param delivery_service query string false "Filter by delivery service"
+// @Param locale query string false "Filter by locale"
+// @Param currency query string false "Filter by payment currency"
+// @Param item_status query int false "Only orders with at least one item in this status"
+// @Param phone query string false "Exact delivery phone"
+// @Param email query string false "Exact delivery email"
+// @Param date_from query string false "Created at or after this time (RFC 3339)"
+// @Param date_to query string false "Created before this time (RFC 3339)"
+// @Param sort query string false "Sort order" Enums(date_desc, date_asc, amount_desc, amount_asc) default(date_desc)
+// @Param limit query int false "Page size (1-100)" default(20)
+// @Param cursor query string false "Cursor from the previous page"
+// @Success 200 {object} models.OrderPage "Page of orders"
+// @Failure 400 {object} ErrorResponse "Invalid query parameters"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Failure 503 {object} ErrorResponse "Database unavailable"
+// @Failure 504 {object} ErrorResponse "Database timeout"
+// @Router /api/v1/orders [get]
+func (h *Handler) listOrders(c *gin.Context) {
+	query, err := parseOrderQuery(c)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	page, err := h.service.ListOrders(c.Request.Context(), query)
+	if err != nil {
+		h.logger.Debug("handler — failed to list orders", "error", err.Error(), "layer", "handler")
+		if errors.Is(err, errs.ErrInvalidArgument) {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "invalid cursor"})
+			return
+		}
+		abortWithError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, page)
+}
+
+// parseOrderQuery reads listing filters, sort order, limit and cursor from the query string.
+func parseOrderQuery(c *gin.Context) (models.OrderQuery, error) {
+	query, err := parsePageParams(c)
+	if err != nil {
+		return query, err
+	}
+	query.Filter = models.OrderFilter{
+		CustomerID:      c.Query("customer_id"),
+		DeliveryService: c.Query("delivery_service"),
+		Locale:          c.Query("locale"),
+		Currency:        c.Query("currency"),
+		Phone:           c.Query("phone"),
+		Email:           c.Query("email"),
+	}
+	if raw := c.Query("item_status"); raw != "" {
+		status, err := strconv.Atoi(raw)
+		if err != nil {
+			return query, fmt.Errorf("item_status must be a number")
+		}
+		query.Filter.ItemStatus = status
+	}
+	if query.Filter.CreatedFrom, err = parseTimeParam(c, "date_from"); err != nil {
+		return query, err
+	}
+	if query.Filter.CreatedTo, err = parseTimeParam(c, "date_to"); err != nil {
+		return query, err
+	}
+	return query, nil
+}
+
+// parsePageParams reads sort order, limit and cursor from the query string, applying defaults.
+func parsePageParams(c *gin.Context) (models.OrderQuery, error) {
+	query := models.OrderQuery{
+		Sort:   models.OrderSort(c.DefaultQuery("sort", string(models.SortDateDesc))),
+		Cursor: c.Query("cursor"),
+		Limit:  defaultPageSize,
+	}
+	if !query.Sort.Valid() {
+		return query, fmt.Errorf("unknown sort %q", query.Sort)
+	}
+	if raw := c.Query("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 || limit > maxPageSize {
+			return query, fmt.Errorf("limit must be a number between 1 and %d", maxPageSize)
+		}
+		query.Limit = limit
+	}
+	return query, nil
+}
+
+// parseTimeParam parses an optional RFC 3339 query parameter. A missing parameter yields the zero time.
+func parseTimeParam(c *gin.Context, name string) (time.Time, error) {
+	raw := c.Query(name)
+	if raw == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s must be an RFC 3339 timestamp", name)
+	}
+	return t, nil
+}
+
+// getOrderByTrackNumber handles GET /api/v1/tracking/:trackNumber.
+//
+// Returns the order with the given track number. The cache is keyed by order UID,
+// so the order is always read from the database.
+//
+// Responds with:
+// - 200 OK + order JSON
+// - 404 Not Found if there is no order with such track number
+// - 503 Service Unavailable if the database is down
+// - 504 Gateway Timeout if the database didn't respond in time
+// - 500 Internal Server Error on unexpected failures
+//
+// @Summary Get order by track number
+// @Description Returns order details in JSON format, looked up by its track number.
+// @Tags Orders
+// @Produce json
+// @Param trackNumber path string true "Track number"
+// @Success 200 {object} models.Order "Order data"
+// @Failure 404 {object} ErrorResponse "Order not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Failure 503 {object} ErrorResponse "Database unavailable"
+// @Failure 504 {object} ErrorResponse "Database timeout"
+// @Router /api/v1/tracking/{trackNumber} [get]
+func (h *Handler) getOrderByTrackNumber(c *gin.Context) {
+	trackNumber := c.Param("trackNumber")
+	order, err := h.service.GetOrderByTrackNumber(c.Request.Context(), trackNumber)
+	if err != nil {
+		h.logger.Debug("handler — failed to get order by track number", "trackNumber", trackNumber, "layer", "handler")
+		if errors.Is(err, errs.ErrNotFound) {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("%s — order not found", trackNumber)})
+			return
+		}
+		abortWithError(c, err)
+		return
+	}
 	c.JSON(http.StatusOK, order)
 }
+
+// getCustomerOrders handles GET /api/v1/customers/:customerId/orders.
+//
+// Returns stats over all orders of the customer (order count, total spend per currency,
+// last order date) along with a page of their orders. Pagination works like in listOrders.
+//
+// Responds with:
+// - 200 OK + stats and page JSON
+// - 400 Bad Request on malformed query parameters or cursor
+// - 404 Not Found if the customer has no orders
+// - 503 Service Unavailable if the database is down
+// - 504 Gateway Timeout if the database didn't respond in time
+// - 500 Internal Server Error on unexpected failures
+//
+// @Summary Get customer orders with stats
+// @Description Returns order count, total spend per currency and last order date of the customer, along with a page of their orders.<br>Pass <strong>next_cursor</strong> from the previous response as <strong>cursor</strong> to get the next page.
+// @Tags Customers
+// @Produce json
+// @Param customerId path string true "Customer ID"
+// @Param sort query string false "Sort order" Enums(date_desc, date_asc, amount_desc, amount_asc) default(date_desc)
+// @Param limit query int false "Page size (1-100)" default(20)
+// @Param cursor query string false "Cursor from the previous page"
+// @Success 200 {object} models.CustomerOrders "Customer stats and orders"
+// @Failure 400 {object} ErrorResponse "Invalid query parameters"
+// @Failure 404 {object} ErrorResponse "Customer has no orders"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Failure 503 {object} ErrorResponse "Database unavailable"
+// @Failure 504 {object} ErrorResponse "Database timeout"
+// @Router /api/v1/customers/{customerId}/orders [get]
+func (h *Handler) getCustomerOrders(c *gin.Context) {
+	customerID := c.Param("customerId")
+	query, err := parsePageParams(c)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	result, err := h.service.GetCustomerOrders(c.Request.Context(), customerID, query)
+	if err != nil {
+		h.logger.Debug("handler — failed to get customer orders", "customerID", customerID, "layer", "handler")
+		switch {
+		case errors.Is(err, errs.ErrNotFound):
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("%s — customer has no orders", customerID)})
+		case errors.Is(err, errs.ErrInvalidArgument):
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "invalid cursor"})
+		default:
+			abortWithError(c, err)
+		}
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// getOrderHistory handles GET /api/v1/orders/:orderId/history.
+//
+// Returns the status timeline of all items of the order, oldest change first.
+// An order whose items never changed status has an empty timeline.
+//
+// Responds with:
+// - 200 OK + history JSON
+// - 404 Not Found if order does not exist
+// - 503 Service Unavailable if the database is down
+// - 504 Gateway Timeout if the database didn't respond in time
+// - 500 Internal Server Error on unexpected failures
+//
+// @Summary Get item status history of an order
+// @Description Returns every recorded item status change of the order, oldest first.<br>Each event carries the status before and after the change, when it happened and when the service recorded it.
+// @Tags Orders
+// @Produce json
+// @Param orderId path string true "Order ID (UUID)"
+// @Success 200 {object} models.OrderHistory "Status timeline"
+// @Failure 404 {object} ErrorResponse "Order not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Failure 503 {object} ErrorResponse "Database unavailable"
+// @Failure 504 {object} ErrorResponse "Database timeout"
+// @Router /api/v1/orders/{orderId}/history [get]
+func (h *Handler) getOrderHistory(c *gin.Context) {
+	orderID := c.Param("orderId")
+	history, err := h.service.GetOrderHistory(c.Request.Context(), orderID)
+	if err != nil {
+		h.logger.Debug("handler — failed to get order history", "orderUID", orderID, "layer", "handler")
+		if errors.Is(err, errs.ErrNotFound) {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("%s — order not found", orderID)})
+			return
+		}
+		abortWithError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, history)
+}
@@ -2,12 +2,15 @@ package handler
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/configs"
 	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/models"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository/errs"
 	mock_service "github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/service/mocks"
 	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/pkg/logger"
 	mock_logger "github.com/Pur1st2EpicONE/WBTECH-sample-microservice/pkg/logger/mocks"
@@ -29,7 +32,7 @@ func TestInitRoutes_OrderRoute(t *testing.T) {
 	gin.SetMode(gin.ReleaseMode)
 	router := h.InitRoutes()
 	order := &models.Order{OrderUID: "orderAbobaId"}
-	mockService.EXPECT().GetOrder("orderAbobaId", gomock.Any()).Return(order, false, nil)
+	mockService.EXPECT().GetOrder(gomock.Any(), "orderAbobaId", gomock.Any()).Return(order, false, nil)
 
 	req := httptest.NewRequest(http.MethodGet, "/api/v1/orders/orderAbobaId", nil)
 	w := httptest.NewRecorder()
@@ -47,7 +50,11 @@ func setupHandlerWithMock(t *testing.T) (*Handler, *mock_service.MockServiceProv
 	gin.SetMode(gin.ReleaseMode)
 	h := NewHandler(mockService, mockLogger)
 	router := gin.New()
+	router.GET("/orders", h.listOrders)
 	router.GET("/orders/:orderId", h.getOrder)
+	router.GET("/orders/:orderId/history", h.getOrderHistory)
+	router.GET("/tracking/:trackNumber", h.getOrderByTrackNumber)
+	router.GET("/customers/:customerId/orders", h.getCustomerOrders)
 
 	return h, mockService, router
 }
@@ -56,7 +63,7 @@ func TestGetOrder_Miss(t *testing.T) {
 	_, mockService, router := setupHandlerWithMock(t)
 
 	order := &models.Order{OrderUID: "test_aboba"}
-	mockService.EXPECT().GetOrder("test_aboba", gomock.Any()).Return(order, false, nil)
+	mockService.EXPECT().GetOrder(gomock.Any(), "test_aboba", gomock.Any()).Return(order, false, nil)
 
 	req := httptest.NewRequest(http.MethodGet, "/orders/test_aboba", nil)
 	w := httptest.NewRecorder()
@@ -71,7 +78,7 @@ func TestGetOrder_Hit(t *testing.T) {
 	_, mockService, router := setupHandlerWithMock(t)
 
 	order := &models.Order{OrderUID: "squid_aboba456"}
-	mockService.EXPECT().GetOrder("squid_aboba456", gomock.Any()).Return(order, true, nil)
+	mockService.EXPECT().GetOrder(gomock.Any(), "squid_aboba456", gomock.Any()).Return(order, true, nil)
 
 	req := httptest.NewRequest(http.MethodGet, "/orders/squid_aboba456", nil)
 	w := httptest.NewRecorder()
@@ -85,7 +92,7 @@ func TestGetOrder_Hit(t *testing.T) {
 func TestGetOrder_Error(t *testing.T) {
 	_, mockService, router := setupHandlerWithMock(t)
 
-	mockService.EXPECT().GetOrder("game_of_abobas", gomock.Any()).Return(nil, false, errors.New("not found"))
+	mockService.EXPECT().GetOrder(gomock.Any(), "game_of_abobas", gomock.Any()).Return(nil, false, errors.New("not found"))
 
 	req := httptest.NewRequest(http.MethodGet, "/orders/game_of_abobas", nil)
 	w := httptest.NewRecorder()
@@ -104,7 +111,7 @@ func TestGetOrder_NotFound(t *testing.T) {
 	h.logger = mockLogger
 
 	orderID := "a1b2o3b4a5"
-	mockService.EXPECT().GetOrder(orderID, gomock.Any()).Return(nil, false, errors.New("sql: no rows in result set"))
+	mockService.EXPECT().GetOrder(gomock.Any(), orderID, gomock.Any()).Return(nil, false, fmt.Errorf("failed to get order: %w", errs.ErrNotFound))
 	mockLogger.EXPECT().Debug("handler — failed to get order", "orderUID", orderID, "layer", "handler")
 
 	req := httptest.NewRequest(http.MethodGet, "/orders/"+orderID, nil)
@@ -114,3 +121,226 @@ func TestGetOrder_NotFound(t *testing.T) {
 	assert.Equal(t, http.StatusNotFound, w.Code)
 	assert.Contains(t, w.Body.String(), "order not found")
 }
+
+func TestGetOrder_Unavailable(t *testing.T) {
+	_, mockService, router := setupHandlerWithMock(t)
+
+	mockService.EXPECT().GetOrder(gomock.Any(), "aboba_down", gomock.Any()).Return(nil, false, fmt.Errorf("%w: connection refused", errs.ErrUnavailable))
+
+	req := httptest.NewRequest(http.MethodGet, "/orders/aboba_down", nil)
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+}
+
+func TestGetOrder_Timeout(t *testing.T) {
+	_, mockService, router := setupHandlerWithMock(t)
+
+	mockService.EXPECT().GetOrder(gomock.Any(), "aboba_slow", gomock.Any()).Return(nil, false, fmt.Errorf("%w: context deadline exceeded", errs.ErrTimeout))
+
+	req := httptest.NewRequest(http.MethodGet, "/orders/aboba_slow", nil)
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusGatewayTimeout, w.Code)
+}
+
+func TestListOrders_Success(t *testing.T) {
+	_, mockService, router := setupHandlerWithMock(t)
+
+	from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	expected := models.OrderQuery{
+		Filter: models.OrderFilter{
+			CustomerID:  "test",
+			Currency:    "USD",
+			ItemStatus:  202,
+			CreatedFrom: from,
+		},
+		Sort:   models.SortAmountAsc,
+		Cursor: "abc",
+		Limit:  5,
+	}
+	page := models.OrderPage{Orders: []*models.Order{{OrderUID: "aboba"}}, NextCursor: "next"}
+	mockService.EXPECT().ListOrders(gomock.Any(), expected).Return(page, nil)
+
+	req := httptest.NewRequest(http.MethodGet,
+		"/orders?customer_id=test&currency=USD&item_status=202&date_from=2025-01-01T00:00:00Z&sort=amount_asc&cursor=abc&limit=5", nil)
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"next_cursor":"next"`)
+	assert.Contains(t, w.Body.String(), `"order_uid":"aboba"`)
+}
+
+func TestListOrders_Defaults(t *testing.T) {
+	_, mockService, router := setupHandlerWithMock(t)
+
+	expected := models.OrderQuery{Sort: models.SortDateDesc, Limit: defaultPageSize}
+	mockService.EXPECT().ListOrders(gomock.Any(), expected).Return(models.OrderPage{Orders: []*models.Order{}}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/orders", nil)
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"orders":[]}`, w.Body.String())
+}
+
+func TestListOrders_BadParams(t *testing.T) {
+	_, _, router := setupHandlerWithMock(t)
+
+	for _, query := range []string{
+		"limit=0",
+		"limit=101",
+		"limit=ten",
+		"sort=random",
+		"item_status=done",
+		"date_from=yesterday",
+		"date_to=2025-13-01",
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/orders?"+query, nil)
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+}
+
+func TestListOrders_InvalidCursor(t *testing.T) {
+	_, mockService, router := setupHandlerWithMock(t)
+
+	mockService.EXPECT().ListOrders(gomock.Any(), gomock.Any()).
+		Return(models.OrderPage{}, fmt.Errorf("failed to list orders: %w", errs.ErrInvalidArgument))
+
+	req := httptest.NewRequest(http.MethodGet, "/orders?cursor=garbage", nil)
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestListOrders_Unavailable(t *testing.T) {
+	_, mockService, router := setupHandlerWithMock(t)
+
+	mockService.EXPECT().ListOrders(gomock.Any(), gomock.Any()).
+		Return(models.OrderPage{}, fmt.Errorf("failed to list orders: %w", errs.ErrUnavailable))
+
+	req := httptest.NewRequest(http.MethodGet, "/orders", nil)
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+}
+
+func TestGetOrderByTrackNumber_Success(t *testing.T) {
+	_, mockService, router := setupHandlerWithMock(t)
+
+	mockService.EXPECT().GetOrderByTrackNumber(gomock.Any(), "WBILMTESTTRACK").Return(&models.Order{OrderUID: "aboba"}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/tracking/WBILMTESTTRACK", nil)
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"order_uid":"aboba"`)
+}
+
+func TestGetOrderByTrackNumber_NotFound(t *testing.T) {
+	_, mockService, router := setupHandlerWithMock(t)
+
+	mockService.EXPECT().GetOrderByTrackNumber(gomock.Any(), "nope").
+		Return(nil, fmt.Errorf("failed to get order by track number nope: %w", errs.ErrNotFound))
+
+	req := httptest.NewRequest(http.MethodGet, "/tracking/nope", nil)
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestGetCustomerOrders_Success(t *testing.T) {
+	_, mockService, router := setupHandlerWithMock(t)
+
+	expected := models.OrderQuery{Sort: models.SortAmountDesc, Limit: 2}
+	result := models.CustomerOrders{
+		CustomerID: "test",
+		Stats:      models.CustomerStats{OrderCount: 3, TotalSpend: map[string]float64{"USD": 42}},
+		OrderPage:  models.OrderPage{Orders: []*models.Order{{OrderUID: "aboba"}}, NextCursor: "next"},
+	}
+	mockService.EXPECT().GetCustomerOrders(gomock.Any(), "test", expected).Return(result, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/customers/test/orders?sort=amount_desc&limit=2", nil)
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"order_count":3`)
+	assert.Contains(t, w.Body.String(), `"total_spend":{"USD":42}`)
+	assert.Contains(t, w.Body.String(), `"next_cursor":"next"`)
+}
+
+func TestGetCustomerOrders_NotFound(t *testing.T) {
+	_, mockService, router := setupHandlerWithMock(t)
+
+	mockService.EXPECT().GetCustomerOrders(gomock.Any(), "ghost", gomock.Any()).
+		Return(models.CustomerOrders{}, fmt.Errorf("failed to get stats for customer ghost: %w", errs.ErrNotFound))
+
+	req := httptest.NewRequest(http.MethodGet, "/customers/ghost/orders", nil)
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestGetCustomerOrders_BadLimit(t *testing.T) {
+	_, _, router := setupHandlerWithMock(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/customers/test/orders?limit=-1", nil)
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestGetOrderHistory_Success(t *testing.T) {
+	_, mockService, router := setupHandlerWithMock(t)
+
+	history := models.OrderHistory{OrderUID: "aboba", Events: []models.StatusEvent{{ChrtID: 1, PreviousStatus: 202, Status: 300}}}
+	mockService.EXPECT().GetOrderHistory(gomock.Any(), "aboba").Return(history, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/orders/aboba/history", nil)
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"previous_status":202`)
+}
+
+func TestGetOrderHistory_NotFound(t *testing.T) {
+	_, mockService, router := setupHandlerWithMock(t)
+
+	mockService.EXPECT().GetOrderHistory(gomock.Any(), "nope").
+		Return(models.OrderHistory{}, fmt.Errorf("failed to get history of order nope: %w", errs.ErrNotFound))
+
+	req := httptest.NewRequest(http.MethodGet, "/orders/nope/history", nil)
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
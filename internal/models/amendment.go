@@ -0,0 +1,18 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// OrderAmendment is a recorded change of a stored order.
+type OrderAmendment struct {
+	Version   int             `json:"version"`                       // version of the order the amendment produced
+	Patch     json.RawMessage `json:"patch" swaggertype:"object"`    // JSON Merge Patch that was applied
+	Previous  json.RawMessage `json:"previous" swaggertype:"object"` // the order as it was before
+	AmendedAt time.Time       `json:"amended_at"`
+
+	// KeyID is the key the personal data of the deliveries in Patch and Previous is encrypted with, as archives hold it;
+	// empty for plaintext
+	KeyID string `json:"key_id,omitempty" swaggerignore:"true"`
+}
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// OrderSnapshot is an order along with everything stored about it. It is the unit
+// in which orders are moved between shards and written to archives.
+type OrderSnapshot struct {
+	Order      *Order           `json:"order"`
+	History    []StatusEvent    `json:"history"`
+	Checksum   string           `json:"checksum,omitempty"`   // checksum of the order as it was received, empty for orders saved before checksums
+	KeyID      string           `json:"key_id,omitempty"`     // key the name, phone, address and email of the delivery are encrypted with, empty for plaintext
+	Ingest     *IngestRecord    `json:"ingest,omitempty"`     // message the order was received in, if it was recorded
+	Amendments []OrderAmendment `json:"amendments,omitempty"` // changes made to the order after it was received, oldest first
+}
+
+// Partition is a set of partitions of the order tables, one per table, that share a name suffix.
+// A monthly partition holds the orders dated within [From, To); the default one holds the orders
+// of the months that have no partition of their own.
+type Partition struct {
+	Name    string // suffix of the table names, e.g. p202501 for orders_p202501, items_p202501 and so on
+	From    time.Time
+	To      time.Time
+	Default bool
+}
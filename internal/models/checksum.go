@@ -0,0 +1,34 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+)
+
+/*
+OrderChecksum returns a hex-encoded SHA-256 of the fields an order is received with, in a fixed order.
+It tells a redelivered order from a conflicting one with the same UID, so it doesn't depend on
+how Order is marshalled: fields that are not received with an order, such as Version, are left out.
+Listing another field here changes the checksum of every order, so stored orders are reported as conflicts.
+*/
+func OrderChecksum(order *Order) string {
+	fields := []any{
+		order.OrderUID, order.TrackNumber, order.Entry, order.Locale, order.InternalSignature,
+		order.CustomerID, order.DeliveryService, order.ShardKey, order.SmID,
+		order.DateCreated.UTC().Format(time.RFC3339Nano), order.OofShard,
+		order.Delivery.Name, order.Delivery.Phone, order.Delivery.Zip, order.Delivery.City,
+		order.Delivery.Address, order.Delivery.Region, order.Delivery.Email,
+		order.Payment.Transaction, order.Payment.RequestID, order.Payment.Currency, order.Payment.Provider,
+		order.Payment.Amount, order.Payment.PaymentDT, order.Payment.Bank, order.Payment.DeliveryCost,
+		order.Payment.GoodsTotal, order.Payment.CustomFee,
+	}
+	for _, item := range order.Items {
+		fields = append(fields, []any{item.ChrtID, item.TrackNumber, item.Price, item.Rid, item.Name,
+			item.Sale, item.Size, item.TotalPrice, item.NmID, item.Brand, item.Status})
+	}
+	canonical, _ := json.Marshal(fields) // strings and numbers only, can't fail
+	sum := sha256.Sum256(canonical)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,87 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Sources of an erasure request.
+const (
+	ErasureSourceAPI = "api"
+	ErasureSourceCLI = "cli"
+)
+
+// ErasedDelivery replaces the delivery of every order of a customer whose personal data was erased.
+// The placeholders carry nothing of the original values, so they can't be reversed,
+// and they pass the validation of a delivery, so erased orders can still be amended.
+var ErasedDelivery = Delivery{
+	Name:    "Erased",
+	Phone:   "+0000000000",
+	Zip:     "0000000",
+	City:    "Erased",
+	Address: "Erased",
+	Region:  "Erased",
+	Email:   "erased@erased.invalid",
+}
+
+// PersonalDataErasure is the compliance record of an erasure of the personal data of a customer.
+type PersonalDataErasure struct {
+	CustomerID string    `json:"customer_id"`
+	OrderUIDs  []string  `json:"order_uids"` // orders whose personal data was replaced, filled in by storage
+	Source     string    `json:"source"`     // ErasureSourceAPI or ErasureSourceCLI
+	Reference  string    `json:"reference"`  // reference of the request, e.g. a ticket number; may be empty
+	ErasedAt   time.Time `json:"erased_at"`
+}
+
+/*
+ErasePersonalData replaces the personal data in the snapshot of an order the way storage erases stored orders:
+the delivery of the order, and the delivery in the ingested payload and in the recorded amendments, where they have one.
+The version of the order is bumped. It is applied to orders that were not in the database when their customer
+was erased, such as archived orders being restored. An order whose delivery is erased already is left as it is.
+*/
+func (s *OrderSnapshot) ErasePersonalData() error {
+	if s.Order.Delivery == ErasedDelivery {
+		return nil
+	}
+	delivery, err := json.Marshal(ErasedDelivery)
+	if err != nil {
+		return fmt.Errorf("failed to marshal erased delivery: %w", err)
+	}
+	s.Order.Delivery = ErasedDelivery
+	s.KeyID = ""
+	s.Order.Version++
+	if s.Ingest != nil {
+		if s.Ingest.Payload, err = replaceDelivery(s.Ingest.Payload, delivery); err != nil {
+			return fmt.Errorf("failed to erase ingested payload: %w", err)
+		}
+		s.Ingest.KeyID = ""
+	}
+	for i := range s.Amendments {
+		amendment := &s.Amendments[i]
+		amendment.KeyID = ""
+		if amendment.Patch, err = replaceDelivery(amendment.Patch, delivery); err != nil {
+			return fmt.Errorf("failed to erase amendment %d: %w", amendment.Version, err)
+		}
+		if amendment.Previous, err = replaceDelivery(amendment.Previous, delivery); err != nil {
+			return fmt.Errorf("failed to erase amendment %d: %w", amendment.Version, err)
+		}
+	}
+	return nil
+}
+
+// replaceDelivery replaces the "delivery" member of a JSON object, if it has one.
+func replaceDelivery(document json.RawMessage, delivery json.RawMessage) (json.RawMessage, error) {
+	if len(document) == 0 {
+		return document, nil
+	}
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(document, &members); err != nil {
+		return nil, err
+	}
+	if _, ok := members["delivery"]; !ok {
+		return document, nil
+	}
+	members["delivery"] = delivery
+	return json.Marshal(members)
+}
@@ -0,0 +1,45 @@
+package models
+
+import "time"
+
+// EventOrderAccepted is the type of the event published once an order has been persisted.
+const EventOrderAccepted = "order.accepted"
+
+// OutboxEvent is an event stored in the transactional outbox, waiting to be published.
+// Key is the message key, so all events of the same order land in the same partition.
+type OutboxEvent struct {
+	ID        int64
+	Type      string
+	Key       string
+	Payload   []byte
+	Attempts  int // failed publish attempts so far
+	CreatedAt time.Time
+}
+
+// OrderAccepted is the payload of an "order.accepted" event.
+type OrderAccepted struct {
+	Event       string    `json:"event"`
+	OrderUID    string    `json:"order_uid"`
+	TrackNumber string    `json:"track_number"`
+	CustomerID  string    `json:"customer_id"`
+	Amount      float64   `json:"amount"`
+	Currency    string    `json:"currency"`
+	ItemCount   int       `json:"item_count"`
+	DateCreated time.Time `json:"date_created"`
+	AcceptedAt  time.Time `json:"accepted_at"` // when the order was persisted
+}
+
+// NewOrderAccepted builds the "order.accepted" payload for an order persisted at acceptedAt.
+func NewOrderAccepted(order *Order, acceptedAt time.Time) OrderAccepted {
+	return OrderAccepted{
+		Event:       EventOrderAccepted,
+		OrderUID:    order.OrderUID,
+		TrackNumber: order.TrackNumber,
+		CustomerID:  order.CustomerID,
+		Amount:      order.Payment.Amount,
+		Currency:    order.Payment.Currency,
+		ItemCount:   len(order.Items),
+		DateCreated: order.DateCreated,
+		AcceptedAt:  acceptedAt,
+	}
+}
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// IngestRecord is the Kafka message an order was received in: where it came from and its raw payload.
+// The payload is stored as JSONB, so it keeps the content of the message but not its exact formatting.
+type IngestRecord struct {
+	Topic      string          `json:"topic"`
+	Partition  int32           `json:"partition"`
+	Offset     int64           `json:"offset"`
+	Key        string          `json:"key"`
+	Timestamp  time.Time       `json:"timestamp"`   // timestamp of the message set by the producer or the broker
+	IngestedAt time.Time       `json:"ingested_at"` // when the service received the message
+	Payload    json.RawMessage `json:"payload" swaggertype:"object"`
+	Violations []RuleViolation `json:"violations,omitempty"` // business rules the order was flagged by when it was received
+
+	// KeyID is the key the personal data of the delivery in Payload is encrypted with, as archives hold it; empty for plaintext
+	KeyID string `json:"key_id,omitempty" swaggerignore:"true"`
+}
+
+// OrderProvenance is the ingest record of an order.
+type OrderProvenance struct {
+	OrderUID string `json:"order_uid"`
+	IngestRecord
+}
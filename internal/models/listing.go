@@ -0,0 +1,68 @@
+package models
+
+import "time"
+
+// OrderSort defines the order in which a listing is returned.
+type OrderSort string
+
+// Supported sort orders. Ties are always broken by insertion order, so pagination is stable.
+const (
+	SortDateDesc   OrderSort = "date_desc"   // newest first (default)
+	SortDateAsc    OrderSort = "date_asc"    // oldest first
+	SortAmountDesc OrderSort = "amount_desc" // most expensive first
+	SortAmountAsc  OrderSort = "amount_asc"  // cheapest first
+)
+
+// Valid reports whether s is one of the supported sort orders.
+func (s OrderSort) Valid() bool {
+	switch s {
+	case SortDateDesc, SortDateAsc, SortAmountDesc, SortAmountAsc:
+		return true
+	}
+	return false
+}
+
+// OrderFilter narrows down an order listing. Zero values mean "no filter".
+type OrderFilter struct {
+	CustomerID      string    // exact match on orders.customer_id
+	DeliveryService string    // exact match on orders.delivery_service
+	Locale          string    // exact match on orders.locale
+	Currency        string    // exact match on payment currency
+	ItemStatus      int       // order has at least one item with this status
+	Phone           string    // exact match on the delivery phone
+	Email           string    // exact match on the delivery email
+	CreatedFrom     time.Time // date_created >= CreatedFrom
+	CreatedTo       time.Time // date_created < CreatedTo
+}
+
+// OrderQuery describes a single page request of an order listing.
+//
+// Cursor is an opaque token taken from a previous OrderPage.NextCursor.
+// It is only valid together with the same Sort it was issued for.
+type OrderQuery struct {
+	Filter OrderFilter
+	Sort   OrderSort
+	Cursor string
+	Limit  int
+}
+
+// OrderPage is a single page of an order listing.
+// NextCursor is empty when there are no more orders.
+type OrderPage struct {
+	Orders     []*Order `json:"orders"`
+	NextCursor string   `json:"next_cursor,omitempty"`
+}
+
+// CustomerStats summarizes all orders of a single customer.
+type CustomerStats struct {
+	OrderCount    int                `json:"order_count"`
+	TotalSpend    map[string]float64 `json:"total_spend"` // sum of payment amounts keyed by currency
+	LastOrderDate time.Time          `json:"last_order_date"`
+}
+
+// CustomerOrders is a page of a customer's orders along with stats over all of their orders.
+type CustomerOrders struct {
+	CustomerID string        `json:"customer_id"`
+	Stats      CustomerStats `json:"stats"`
+	OrderPage
+}
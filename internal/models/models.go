@@ -23,6 +23,9 @@ type Order struct {
 	SmID              int       `json:"sm_id" validate:"required,gt=0"`
 	DateCreated       time.Time `json:"date_created" validate:"required"`
 	OofShard          string    `json:"oof_shard" validate:"required,numeric,min=1,max=10"`
+	Version           int       `json:"version,omitempty"` // assigned by storage and bumped by every amendment, ignored when an order is saved
+
+	Ingest *IngestRecord `json:"-"` // message the order was received in, stored along with it; nil if unknown
 }
 
 // Delivery holds the recipient and address information for an order.
@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// ReplicaStatus is the result of a health check of a read replica.
+type ReplicaStatus struct {
+	Name    string        // host:port of the replica
+	Healthy bool          // whether the replica serves reads
+	Lag     time.Duration // replication lag at the time of the check
+	Err     error         // why the replica is unreachable, if it is
+}
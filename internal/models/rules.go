@@ -0,0 +1,14 @@
+package models
+
+// Severities of business rules.
+const (
+	SeverityReject = "reject" // the order is not saved and goes to the DLQ
+	SeverityFlag   = "flag"   // the order is saved, the violation is recorded with it
+)
+
+// RuleViolation is a business rule an order breaks.
+type RuleViolation struct {
+	Rule     string `json:"rule"`
+	Severity string `json:"severity"` // SeverityReject or SeverityFlag
+	Message  string `json:"message"`
+}
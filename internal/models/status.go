@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// StatusUpdate is a status-change event for a single item of an order,
+// received from the status-update topic. The item is identified by order UID, chrt_id and rid.
+type StatusUpdate struct {
+	OrderUID  string    `json:"order_uid" validate:"required,alphanum,min=1,max=255"`
+	ChrtID    int       `json:"chrt_id" validate:"required,gt=0"`
+	Rid       string    `json:"rid" validate:"required,min=5,max=255"`
+	Status    int       `json:"status" validate:"required,oneof=100 200 202 300 400"`
+	ChangedAt time.Time `json:"changed_at" validate:"required"`
+}
+
+// StatusEvent is a single recorded status change of an item.
+// PreviousStatus is the item status right before the change was applied.
+type StatusEvent struct {
+	ChrtID         int       `json:"chrt_id"`
+	Rid            string    `json:"rid"`
+	PreviousStatus int       `json:"previous_status"`
+	Status         int       `json:"status"`
+	ChangedAt      time.Time `json:"changed_at"`
+	RecordedAt     time.Time `json:"recorded_at"`
+}
+
+// OrderHistory is the status timeline of all items of an order, oldest change first.
+type OrderHistory struct {
+	OrderUID string        `json:"order_uid"`
+	Events   []StatusEvent `json:"events"`
+}
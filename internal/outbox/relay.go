@@ -0,0 +1,157 @@
+/*
+Package outbox publishes events written to the transactional outbox.
+
+Storage writes an event in the same transaction as the change it describes (e.g. an "order.accepted"
+event together with the order), so there is no gap between persisting a change and announcing it.
+The Relay picks those events up and publishes them through a broker.Producer.
+
+Delivery is at-least-once: an event is marked as sent only after the producer has confirmed
+the delivery, so a crash in between results in the event being published again.
+Consumers should deduplicate by the event key (the order UID).
+*/
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/broker"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/configs"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/models"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/pkg/logger"
+)
+
+// Relay moves events from the outbox table to the message broker.
+type Relay struct {
+	storage        repository.Storage
+	producer       broker.Producer
+	logger         logger.Logger
+	topic          string
+	batchSize      int
+	pollInterval   time.Duration
+	lease          time.Duration
+	retryBaseDelay time.Duration
+	retryMaxDelay  time.Duration
+	sentRetention  time.Duration
+	purgeInterval  time.Duration
+	lastPurge      time.Time
+}
+
+// NewRelay creates a Relay that publishes outbox events of storage through producer to config.Producer.Topic.
+func NewRelay(config configs.Outbox, storage repository.Storage, producer broker.Producer, logger logger.Logger) *Relay {
+	return &Relay{
+		storage:        storage,
+		producer:       producer,
+		logger:         logger,
+		topic:          config.Producer.Topic,
+		batchSize:      config.BatchSize,
+		pollInterval:   config.PollInterval,
+		lease:          config.Lease,
+		retryBaseDelay: config.RetryBaseDelay,
+		retryMaxDelay:  config.RetryMaxDelay,
+		sentRetention:  config.SentRetention,
+		purgeInterval:  config.PurgeInterval,
+	}
+}
+
+/*
+Run publishes outbox events until ctx is cancelled.
+
+Each iteration claims a batch of due events and publishes them one by one.
+A published event is marked as sent; a failed one is rescheduled with exponential backoff.
+When there is nothing to publish, or the storage can't be reached, the relay waits for
+the poll interval before trying again. A full batch is followed by the next one right away.
+Once per purge interval, events published longer than the sent retention ago are deleted, see purgeSent.
+*/
+func (r *Relay) Run(ctx context.Context) {
+	r.logger.LogInfo("outbox — relay started", "topic", r.topic, "layer", "outbox")
+	for {
+		r.purgeSent(ctx, time.Now())
+		published, err := r.publishBatch(ctx)
+		if err != nil {
+			r.logger.LogError("outbox — failed to claim events", err, "layer", "outbox")
+		}
+		if err != nil || published < r.batchSize {
+			select {
+			case <-ctx.Done():
+				r.logger.LogInfo("outbox — relay stopped", "layer", "outbox")
+				return
+			case <-time.After(r.pollInterval):
+			}
+		} else if ctx.Err() != nil {
+			r.logger.LogInfo("outbox — relay stopped", "layer", "outbox")
+			return
+		}
+	}
+}
+
+// publishBatch claims a batch of due events and publishes them.
+// Returns the number of claimed events, or an error if they could not be claimed.
+func (r *Relay) publishBatch(ctx context.Context) (int, error) {
+	events, err := r.storage.ClaimOutbox(ctx, r.batchSize, r.lease)
+	if err != nil {
+		return 0, err
+	}
+	for _, event := range events {
+		if ctx.Err() != nil {
+			break // unpublished events become due again when their lease expires
+		}
+		r.publish(ctx, event)
+	}
+	return len(events), nil
+}
+
+// purgeSent deletes the events published before the sent retention, unless that has been done
+// less than the purge interval ago or no retention is configured. A failed purge is repeated on the next interval.
+func (r *Relay) purgeSent(ctx context.Context, now time.Time) {
+	if r.sentRetention <= 0 || now.Sub(r.lastPurge) < r.purgeInterval {
+		return
+	}
+	r.lastPurge = now
+	purged, err := r.storage.PurgeOutbox(ctx, now.Add(-r.sentRetention))
+	if err != nil {
+		r.logger.LogError("outbox — failed to delete published events", err, "layer", "outbox")
+	}
+	if purged > 0 {
+		r.logger.LogInfo(fmt.Sprintf("outbox — %d published events deleted", purged), "sentBefore", now.Add(-r.sentRetention).Format(time.RFC3339), "layer", "outbox")
+	}
+}
+
+// publish sends a single event and records the outcome in the outbox.
+// Failing to record a successful delivery only results in a duplicate after the lease expires.
+func (r *Relay) publish(ctx context.Context, event models.OutboxEvent) {
+	message := configs.Message{
+		Topic: r.topic,
+		Key:   []byte(event.Key),
+		Value: event.Payload,
+	}
+	if err := r.producer.Produce(message); err != nil {
+		retryAt := time.Now().Add(r.backoff(event.Attempts))
+		r.logger.LogError(fmt.Sprintf("outbox — failed to publish event %d, retrying at %s", event.ID, retryAt.Format(time.RFC3339)),
+			err, "eventType", event.Type, "key", event.Key, "attempts", event.Attempts+1, "layer", "outbox")
+		if err := r.storage.MarkOutboxFailed(ctx, event.ID, retryAt, err.Error()); err != nil {
+			r.logger.LogError(fmt.Sprintf("outbox — failed to reschedule event %d", event.ID), err, "layer", "outbox")
+		}
+		return
+	}
+	if err := r.storage.MarkOutboxSent(ctx, event.ID); err != nil {
+		r.logger.LogError(fmt.Sprintf("outbox — event %d is published but not marked as sent", event.ID), err, "layer", "outbox")
+		return
+	}
+	r.logger.Debug(fmt.Sprintf("outbox — event %d published", event.ID), "eventType", event.Type, "key", event.Key, "layer", "outbox")
+}
+
+// backoff returns the delay before the next attempt of an event that has failed attempts times before:
+// the base delay doubled on every previous failure, capped at the max delay.
+func (r *Relay) backoff(attempts int) time.Duration {
+	delay := r.retryBaseDelay
+	for range attempts {
+		if delay >= r.retryMaxDelay/2 {
+			return r.retryMaxDelay
+		}
+		delay *= 2
+	}
+	return min(delay, r.retryMaxDelay)
+}
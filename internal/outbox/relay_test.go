@@ -0,0 +1,134 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	mock_broker "github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/broker/mocks"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/configs"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/models"
+	mock_repository "github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository/mocks"
+	mock_logger "github.com/Pur1st2EpicONE/WBTECH-sample-microservice/pkg/logger/mocks"
+	"github.com/golang/mock/gomock"
+)
+
+func testConfig() configs.Outbox {
+	return configs.Outbox{
+		BatchSize:      2,
+		PollInterval:   10 * time.Millisecond,
+		Lease:          time.Minute,
+		RetryBaseDelay: time.Second,
+		RetryMaxDelay:  time.Minute,
+		Producer:       configs.Producer{Topic: "order-events"},
+	}
+}
+
+func newTestRelay(t *testing.T) (*Relay, *mock_repository.MockStorage, *mock_broker.MockProducer) {
+	controller := gomock.NewController(t)
+	storage := mock_repository.NewMockStorage(controller)
+	producer := mock_broker.NewMockProducer(controller)
+	logger := mock_logger.NewMockLogger(controller)
+	logger.EXPECT().LogInfo(gomock.Any(), gomock.Any()).AnyTimes()
+	logger.EXPECT().LogError(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+	logger.EXPECT().Debug(gomock.Any(), gomock.Any()).AnyTimes()
+	return NewRelay(testConfig(), storage, producer, logger), storage, producer
+}
+
+func TestRelay_PublishBatch(t *testing.T) {
+	relay, storage, producer := newTestRelay(t)
+	ctx := context.Background()
+	events := []models.OutboxEvent{
+		{ID: 1, Type: models.EventOrderAccepted, Key: "uid1", Payload: []byte(`{"order_uid":"uid1"}`)},
+		{ID: 2, Type: models.EventOrderAccepted, Key: "uid2", Payload: []byte(`{"order_uid":"uid2"}`), Attempts: 2},
+	}
+
+	storage.EXPECT().ClaimOutbox(ctx, 2, time.Minute).Return(events, nil)
+	gomock.InOrder(
+		producer.EXPECT().Produce(configs.Message{Topic: "order-events", Key: []byte("uid1"), Value: events[0].Payload}).Return(nil),
+		storage.EXPECT().MarkOutboxSent(ctx, int64(1)).Return(nil),
+		producer.EXPECT().Produce(configs.Message{Topic: "order-events", Key: []byte("uid2"), Value: events[1].Payload}).
+			Return(errors.New("broker down")),
+		storage.EXPECT().MarkOutboxFailed(ctx, int64(2), gomock.Any(), "broker down").
+			DoAndReturn(func(_ context.Context, _ int64, retryAt time.Time, _ string) error {
+				if delay := time.Until(retryAt); delay < 3*time.Second || delay > 4*time.Second {
+					t.Errorf("expected a retry in 4s after two failures, got %s", delay)
+				}
+				return nil
+			}),
+	)
+
+	published, err := relay.publishBatch(ctx)
+	if err != nil {
+		t.Fatalf("publishBatch failed: %v", err)
+	}
+	if published != 2 {
+		t.Fatalf("expected 2 claimed events, got %d", published)
+	}
+}
+
+func TestRelay_PublishBatch_ClaimError(t *testing.T) {
+	relay, storage, _ := newTestRelay(t)
+	storage.EXPECT().ClaimOutbox(gomock.Any(), 2, time.Minute).Return(nil, errors.New("db down"))
+
+	if _, err := relay.publishBatch(context.Background()); err == nil {
+		t.Fatal("expected claim error")
+	}
+}
+
+func TestRelay_Run_StopsOnCancel(t *testing.T) {
+	relay, storage, _ := newTestRelay(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	storage.EXPECT().ClaimOutbox(gomock.Any(), 2, time.Minute).Return(nil, nil).MinTimes(1)
+
+	done := make(chan struct{})
+	go func() {
+		relay.Run(ctx)
+		close(done)
+	}()
+	time.Sleep(30 * time.Millisecond)
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("relay did not stop after cancellation")
+	}
+}
+
+func TestRelay_PurgeSent(t *testing.T) {
+	relay, storage, _ := newTestRelay(t)
+	relay.sentRetention, relay.purgeInterval = 24*time.Hour, time.Hour
+	now := time.Date(2025, 1, 8, 10, 0, 0, 0, time.UTC)
+
+	gomock.InOrder(
+		storage.EXPECT().PurgeOutbox(gomock.Any(), now.Add(-24*time.Hour)).Return(int64(3), nil),
+		storage.EXPECT().PurgeOutbox(gomock.Any(), now.Add(time.Hour-24*time.Hour)).Return(int64(0), errors.New("db down")),
+	)
+	relay.purgeSent(context.Background(), now)
+	relay.purgeSent(context.Background(), now.Add(time.Minute)) // not due yet
+	relay.purgeSent(context.Background(), now.Add(time.Hour))
+
+	relay.sentRetention = 0
+	relay.purgeSent(context.Background(), now.Add(48*time.Hour)) // published events are kept forever
+}
+
+func TestRelay_Backoff(t *testing.T) {
+	relay := NewRelay(testConfig(), nil, nil, nil)
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{0, time.Second},
+		{1, 2 * time.Second},
+		{5, 32 * time.Second},
+		{6, time.Minute},
+		{100, time.Minute},
+	}
+	for _, tt := range tests {
+		if got := relay.backoff(tt.attempts); got != tt.want {
+			t.Errorf("backoff(%d) = %s, want %s", tt.attempts, got, tt.want)
+		}
+	}
+}
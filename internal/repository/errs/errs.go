@@ -0,0 +1,40 @@
+// Package errs defines the error classes shared by all storage implementations.
+//
+// Implementations (e.g. postgres) wrap driver errors into one of these sentinels,
+// so callers (service, handler, broker) can make decisions with errors.Is
+// instead of matching driver-specific error messages.
+//
+// It lives in its own package so that concrete implementations and their callers
+// can refer to the same sentinel errors without an import cycle through the repository package.
+package errs
+
+import "errors"
+
+var (
+	// ErrNotFound reports that the requested record does not exist.
+	ErrNotFound = errors.New("not found")
+
+	// ErrDuplicate reports that the very same record is already stored,
+	// e.g. when Kafka redelivers an order that has been saved before.
+	// Implementations return it only once they have checked the stored record is identical,
+	// never for a unique violation as such (see ErrConstraint).
+	ErrDuplicate = errors.New("already stored")
+
+	// ErrConflict reports that an order with the same UID or track number
+	// is already stored, but its content differs from the incoming one.
+	ErrConflict = errors.New("conflicts with a stored order")
+
+	// ErrConstraint reports that the data violates a schema constraint
+	// (unique, foreign key, NOT NULL, CHECK, value too long, etc.). Retrying won't help.
+	ErrConstraint = errors.New("constraint violation")
+
+	// ErrUnavailable reports that the storage can't be reached at the moment.
+	ErrUnavailable = errors.New("storage unavailable")
+
+	// ErrTimeout reports that the operation did not complete in time.
+	ErrTimeout = errors.New("storage timeout")
+
+	// ErrInvalidArgument reports that the request itself is malformed,
+	// e.g. a pagination cursor that was tampered with or issued for another sort order.
+	ErrInvalidArgument = errors.New("invalid argument")
+)
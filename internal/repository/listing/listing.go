@@ -0,0 +1,175 @@
+// Package listing builds keyset-paginated order listing queries shared by all SQL storage implementations.
+//
+// A listing is sorted by a key (date or amount) with the order ID as a tie-breaker.
+// The pagination cursor is an opaque token holding the key and ID of the last order of a page,
+// so the next page starts right after it no matter how many orders were saved in the meantime.
+package listing
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/models"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository/errs"
+)
+
+// sortSpec describes how a listing sort order maps to SQL.
+// idColumn breaks ties between equal keys and lives in the same table as keyColumn,
+// so the keyset condition can be served by a single index.
+type sortSpec struct {
+	keyColumn string
+	idColumn  string
+	byAmount  bool // the key is payments.amount rather than orders.date_created
+	desc      bool
+}
+
+var sortSpecs = map[models.OrderSort]sortSpec{
+	models.SortDateDesc:   {keyColumn: "orders.date_created", idColumn: "orders.id", desc: true},
+	models.SortDateAsc:    {keyColumn: "orders.date_created", idColumn: "orders.id", desc: false},
+	models.SortAmountDesc: {keyColumn: "payments.amount", idColumn: "payments.order_id", byAmount: true, desc: true},
+	models.SortAmountAsc:  {keyColumn: "payments.amount", idColumn: "payments.order_id", byAmount: true, desc: false},
+}
+
+// BlindIndex returns the blind index of a value of a delivery column ("phone" or "email")
+// for storage that encrypts them, see BuildQuery.
+type BlindIndex func(column, value string) string
+
+// listCursor is the decoded form of a pagination cursor.
+// It points at the last order of the previous page.
+type listCursor struct {
+	Sort models.OrderSort `json:"s"`
+	Key  string           `json:"k"` // sort key of the last order (RFC 3339 date or decimal amount)
+	ID   int64            `json:"i"` // database ID of the last order
+}
+
+// BuildQuery appends filters, the keyset condition, ORDER BY and LIMIT to selectClause
+// and returns the query along with its positional ($N) arguments.
+//
+// selectClause must select from orders joined with payments and deliveries and must not have a WHERE clause.
+// The query fetches Limit+1 rows, so the caller can tell whether another page exists.
+// An unknown sort order, a non-positive limit or a malformed cursor result in errs.ErrInvalidArgument.
+//
+// Storage that encrypts deliveries passes the BlindIndex of their phone and email: the filters on them then
+// match the <column>_index columns of encrypted rows, and the plaintext of rows without a key_id.
+// With a nil index the plaintext columns are matched.
+func BuildQuery(selectClause string, query models.OrderQuery, index BlindIndex) (string, []any, error) {
+	spec, ok := sortSpecs[query.Sort]
+	if !ok {
+		return "", nil, fmt.Errorf("%w: unknown sort order %q", errs.ErrInvalidArgument, query.Sort)
+	}
+	if query.Limit <= 0 {
+		return "", nil, fmt.Errorf("%w: limit must be positive, got %d", errs.ErrInvalidArgument, query.Limit)
+	}
+
+	var conditions []string
+	var args []any
+	add := func(condition string, arg any) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	filter := query.Filter
+	if filter.CustomerID != "" {
+		add("orders.customer_id = $%d", filter.CustomerID)
+	}
+	if filter.DeliveryService != "" {
+		add("orders.delivery_service = $%d", filter.DeliveryService)
+	}
+	if filter.Locale != "" {
+		add("orders.locale = $%d", filter.Locale)
+	}
+	if filter.Currency != "" {
+		add("payments.currency = $%d", filter.Currency)
+	}
+	if filter.ItemStatus != 0 {
+		add("EXISTS (SELECT 1 FROM items WHERE items.order_id = orders.id AND items.status = $%d)", filter.ItemStatus)
+	}
+	for _, exact := range []struct{ column, value string }{{"phone", filter.Phone}, {"email", filter.Email}} {
+		column, value := exact.column, exact.value
+		if value == "" {
+			continue
+		}
+		if index == nil {
+			add("deliveries."+column+" = $%d", value)
+			continue
+		}
+		args = append(args, index(column, value), value)
+		conditions = append(conditions, fmt.Sprintf("(deliveries.%s_index = $%d OR (deliveries.key_id IS NULL AND deliveries.%s = $%d))",
+			column, len(args)-1, column, len(args)))
+	}
+	if !filter.CreatedFrom.IsZero() {
+		add("orders.date_created >= $%d", filter.CreatedFrom.UTC())
+	}
+	if !filter.CreatedTo.IsZero() {
+		add("orders.date_created < $%d", filter.CreatedTo.UTC())
+	}
+
+	direction, comparison := "ASC", ">"
+	if spec.desc {
+		direction, comparison = "DESC", "<"
+	}
+
+	if query.Cursor != "" {
+		key, id, err := decodeCursor(query.Cursor, query.Sort)
+		if err != nil {
+			return "", nil, err
+		}
+		args = append(args, key, id)
+		conditions = append(conditions, fmt.Sprintf("(%s, %s) %s ($%d, $%d)",
+			spec.keyColumn, spec.idColumn, comparison, len(args)-1, len(args)))
+	}
+
+	var sb strings.Builder
+	sb.WriteString(selectClause)
+	if len(conditions) > 0 {
+		sb.WriteString("\n    WHERE ")
+		sb.WriteString(strings.Join(conditions, "\n    AND "))
+	}
+	fmt.Fprintf(&sb, "\n    ORDER BY %s %s, %s %s", spec.keyColumn, direction, spec.idColumn, direction)
+	fmt.Fprintf(&sb, "\n    LIMIT %d", query.Limit+1)
+	return sb.String(), args, nil
+}
+
+// EncodeCursor builds an opaque cursor that points right after the given order.
+func EncodeCursor(sort models.OrderSort, order *models.Order, orderId int64) string {
+	cursor := listCursor{Sort: sort, ID: orderId}
+	if sortSpecs[sort].byAmount {
+		cursor.Key = strconv.FormatFloat(order.Payment.Amount, 'f', -1, 64)
+	} else {
+		cursor.Key = order.DateCreated.UTC().Format(time.RFC3339Nano)
+	}
+	raw, _ := json.Marshal(cursor) // can't fail: plain strings and numbers
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// decodeCursor parses a cursor issued by EncodeCursor and returns the typed sort key and order ID.
+// The cursor must have been issued for the same sort order.
+func decodeCursor(encoded string, sort models.OrderSort) (any, int64, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%w: malformed cursor", errs.ErrInvalidArgument)
+	}
+	var cursor listCursor
+	if err := json.Unmarshal(raw, &cursor); err != nil {
+		return nil, 0, fmt.Errorf("%w: malformed cursor", errs.ErrInvalidArgument)
+	}
+	if cursor.Sort != sort {
+		return nil, 0, fmt.Errorf("%w: cursor was issued for sort %q", errs.ErrInvalidArgument, cursor.Sort)
+	}
+	if sortSpecs[sort].byAmount {
+		amount, err := strconv.ParseFloat(cursor.Key, 64)
+		if err != nil {
+			return nil, 0, fmt.Errorf("%w: malformed cursor", errs.ErrInvalidArgument)
+		}
+		return amount, cursor.ID, nil
+	}
+	date, err := time.Parse(time.RFC3339Nano, cursor.Key)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%w: malformed cursor", errs.ErrInvalidArgument)
+	}
+	return date, cursor.ID, nil
+}
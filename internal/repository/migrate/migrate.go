@@ -0,0 +1,286 @@
+/*
+Package migrate applies the embedded schema migrations (see the schema package) to a database.
+
+The current version is kept in a schema_migrations table with the same layout as the one used by
+the golang-migrate CLI, so databases migrated with the CLI before keep their version.
+Every migration runs in its own transaction together with the version update,
+so a failed migration leaves the schema at the previous version.
+*/
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	// ErrVersionMismatch reports that the database schema version differs from the latest embedded migration.
+	ErrVersionMismatch = errors.New("database schema version mismatch")
+
+	// ErrDirty reports that a migration applied by another tool failed halfway.
+	// The schema has to be repaired manually before it can be migrated again.
+	ErrDirty = errors.New("database schema is dirty")
+
+	// ErrUnknownVersion reports that the requested version has no migration.
+	ErrUnknownVersion = errors.New("unknown schema version")
+)
+
+// Migration is a single numbered schema change.
+type Migration struct {
+	Version uint
+	Name    string // title part of the file name, e.g. "init"
+	Up      string
+	Down    string
+}
+
+// Status describes the schema state of a database.
+type Status struct {
+	Current uint        // version applied to the database, 0 for an empty one
+	Latest  uint        // version of the newest embedded migration
+	Dirty   bool        // a migration failed halfway, see ErrDirty
+	Pending []Migration // migrations above Current, oldest first
+}
+
+// Migrator applies migrations to a single database.
+type Migrator struct {
+	db         *sqlx.DB
+	migrations []Migration // sorted by version
+}
+
+// fileName matches migration files, e.g. 000001_init.up.sql
+var fileName = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+// New reads the migrations from source and returns a Migrator for db.
+// Every version must have both an up and a down file.
+func New(db *sqlx.DB, source fs.FS) (*Migrator, error) {
+	migrations, err := load(source)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+// load parses all migration files in the root of source.
+func load(source fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(source, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %v", err)
+	}
+	byVersion := make(map[uint]*Migration)
+	for _, entry := range entries {
+		match := fileName.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+		version, err := strconv.ParseUint(match[1], 10, 32)
+		if err != nil || version == 0 {
+			return nil, fmt.Errorf("invalid migration version in %s", entry.Name())
+		}
+		content, err := fs.ReadFile(source, entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %v", entry.Name(), err)
+		}
+		migration, found := byVersion[uint(version)]
+		if !found {
+			migration = &Migration{Version: uint(version), Name: match[2]}
+			byVersion[uint(version)] = migration
+		}
+		if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has files with different names: %s and %s", version, migration.Name, match[2])
+		}
+		if match[3] == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s must have both up and down files", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Latest returns the version of the newest migration, i.e. the version the binary expects.
+func (m *Migrator) Latest() uint {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Status reports the current, latest and pending versions of the database.
+func (m *Migrator) Status(ctx context.Context) (Status, error) {
+	current, dirty, err := m.version(ctx)
+	if err != nil {
+		return Status{}, err
+	}
+	status := Status{Current: current, Latest: m.Latest(), Dirty: dirty}
+	for _, migration := range m.migrations {
+		if migration.Version > current {
+			status.Pending = append(status.Pending, migration)
+		}
+	}
+	return status, nil
+}
+
+// Check returns ErrVersionMismatch unless the database is exactly at the latest version,
+// and ErrDirty if the last migration failed halfway.
+func (m *Migrator) Check(ctx context.Context) error {
+	current, dirty, err := m.version(ctx)
+	if err != nil {
+		return err
+	}
+	if dirty {
+		return fmt.Errorf("%w at version %d", ErrDirty, current)
+	}
+	if current != m.Latest() {
+		return fmt.Errorf("%w: database is at version %d, binary expects %d", ErrVersionMismatch, current, m.Latest())
+	}
+	return nil
+}
+
+// Up applies all pending migrations.
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.To(ctx, m.Latest())
+}
+
+// Down reverts the most recently applied migration. It does nothing on an empty database.
+func (m *Migrator) Down(ctx context.Context) error {
+	current, _, err := m.version(ctx)
+	if err != nil {
+		return err
+	}
+	if current == 0 {
+		return nil
+	}
+	return m.To(ctx, m.previous(current))
+}
+
+/*
+To migrates the database up or down to the given version, one migration at a time.
+Version 0 reverts all migrations.
+
+Returns ErrUnknownVersion if there is no migration with such version,
+and ErrDirty if the schema was left dirty by another tool.
+*/
+func (m *Migrator) To(ctx context.Context, target uint) error {
+	if target != 0 && m.index(target) < 0 {
+		return fmt.Errorf("%w: %d", ErrUnknownVersion, target)
+	}
+	current, dirty, err := m.version(ctx)
+	if err != nil {
+		return err
+	}
+	if dirty {
+		return fmt.Errorf("%w at version %d", ErrDirty, current)
+	}
+	if current != 0 && m.index(current) < 0 {
+		return fmt.Errorf("%w: database is at version %d, which is newer than this binary", ErrUnknownVersion, current)
+	}
+
+	for current < target {
+		next := m.migrations[m.index(m.next(current))]
+		if err := m.apply(ctx, next.Up, next.Version); err != nil {
+			return fmt.Errorf("migration %d_%s up failed: %w", next.Version, next.Name, err)
+		}
+		current = next.Version
+	}
+	for current > target {
+		migration := m.migrations[m.index(current)]
+		previous := m.previous(current)
+		if err := m.apply(ctx, migration.Down, previous); err != nil {
+			return fmt.Errorf("migration %d_%s down failed: %w", migration.Version, migration.Name, err)
+		}
+		current = previous
+	}
+	return nil
+}
+
+// apply runs a migration and records the resulting version in a single transaction.
+func (m *Migrator) apply(ctx context.Context, script string, version uint) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM schema_migrations`); err != nil {
+		return err
+	}
+	if version > 0 {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO schema_migrations (version, dirty) VALUES ($1, FALSE)`, version); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// version returns the version recorded in the database, creating the version table if needed.
+func (m *Migrator) version(ctx context.Context) (uint, bool, error) {
+	if _, err := m.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+        version BIGINT NOT NULL PRIMARY KEY,
+        dirty BOOLEAN NOT NULL
+    )`); err != nil {
+		return 0, false, fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+	var version int64
+	var dirty bool
+	err := m.db.QueryRowContext(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&version, &dirty)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	return uint(version), dirty, nil
+}
+
+// index returns the position of the migration with the given version, or -1.
+func (m *Migrator) index(version uint) int {
+	for i, migration := range m.migrations {
+		if migration.Version == version {
+			return i
+		}
+	}
+	return -1
+}
+
+// next returns the version of the first migration above version.
+// Callers make sure there is one.
+func (m *Migrator) next(version uint) uint {
+	for _, migration := range m.migrations {
+		if migration.Version > version {
+			return migration.Version
+		}
+	}
+	return version
+}
+
+// previous returns the version of the migration right below version, or 0.
+func (m *Migrator) previous(version uint) uint {
+	var previous uint
+	for _, migration := range m.migrations {
+		if migration.Version >= version {
+			break
+		}
+		previous = migration.Version
+	}
+	return previous
+}
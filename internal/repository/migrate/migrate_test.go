@@ -0,0 +1,234 @@
+package migrate_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository/migrate"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/schema"
+	"github.com/jmoiron/sqlx"
+	_ "modernc.org/sqlite"
+)
+
+func testMigrations() fstest.MapFS {
+	return fstest.MapFS{
+		"000001_first.up.sql":    {Data: []byte("CREATE TABLE first (id INTEGER);")},
+		"000001_first.down.sql":  {Data: []byte("DROP TABLE first;")},
+		"000002_second.up.sql":   {Data: []byte("CREATE TABLE second (id INTEGER);")},
+		"000002_second.down.sql": {Data: []byte("DROP TABLE second;")},
+		"000005_third.up.sql":    {Data: []byte("CREATE TABLE third (id INTEGER);")},
+		"000005_third.down.sql":  {Data: []byte("DROP TABLE third;")},
+		"README.md":              {Data: []byte("not a migration")},
+	}
+}
+
+func openTestDB(t *testing.T) *sqlx.DB {
+	t.Helper()
+	db, err := sqlx.Open("sqlite", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to open db: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { _ = db.Close() })
+	return db
+}
+
+func newTestMigrator(t *testing.T, source fstest.MapFS) (*migrate.Migrator, *sqlx.DB) {
+	t.Helper()
+	db := openTestDB(t)
+	migrator, err := migrate.New(db, source)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	return migrator, db
+}
+
+func tableExists(t *testing.T, db *sqlx.DB, name string) bool {
+	t.Helper()
+	var count int
+	if err := db.Get(&count, `SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = $1`, name); err != nil {
+		t.Fatalf("failed to check table %s: %v", name, err)
+	}
+	return count == 1
+}
+
+func assertVersion(t *testing.T, migrator *migrate.Migrator, want uint) {
+	t.Helper()
+	status, err := migrator.Status(context.Background())
+	if err != nil {
+		t.Fatalf("Status failed: %v", err)
+	}
+	if status.Current != want {
+		t.Fatalf("expected version %d, got %d", want, status.Current)
+	}
+}
+
+func TestMigrator_UpDownTo(t *testing.T) {
+	migrator, db := newTestMigrator(t, testMigrations())
+	ctx := context.Background()
+
+	if migrator.Latest() != 5 {
+		t.Fatalf("expected latest version 5, got %d", migrator.Latest())
+	}
+
+	status, err := migrator.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status failed: %v", err)
+	}
+	if status.Current != 0 || len(status.Pending) != 3 {
+		t.Fatalf("unexpected status of an empty database: %+v", status)
+	}
+
+	if err := migrator.Up(ctx); err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+	assertVersion(t, migrator, 5)
+	if !tableExists(t, db, "third") {
+		t.Fatal("expected table third to exist")
+	}
+	if err := migrator.Up(ctx); err != nil {
+		t.Fatalf("second Up should be a no-op, got: %v", err)
+	}
+
+	if err := migrator.Down(ctx); err != nil {
+		t.Fatalf("Down failed: %v", err)
+	}
+	assertVersion(t, migrator, 2)
+	if tableExists(t, db, "third") {
+		t.Fatal("expected table third to be dropped")
+	}
+
+	if err := migrator.To(ctx, 0); err != nil {
+		t.Fatalf("To(0) failed: %v", err)
+	}
+	assertVersion(t, migrator, 0)
+	if tableExists(t, db, "first") {
+		t.Fatal("expected table first to be dropped")
+	}
+	if err := migrator.Down(ctx); err != nil {
+		t.Fatalf("Down on an empty database should be a no-op, got: %v", err)
+	}
+
+	if err := migrator.To(ctx, 2); err != nil {
+		t.Fatalf("To(2) failed: %v", err)
+	}
+	assertVersion(t, migrator, 2)
+}
+
+func TestMigrator_To_UnknownVersion(t *testing.T) {
+	migrator, _ := newTestMigrator(t, testMigrations())
+
+	if err := migrator.To(context.Background(), 3); !errors.Is(err, migrate.ErrUnknownVersion) {
+		t.Fatalf("expected ErrUnknownVersion, got %v", err)
+	}
+}
+
+func TestMigrator_FailedMigrationRollsBack(t *testing.T) {
+	source := testMigrations()
+	source["000002_second.up.sql"] = &fstest.MapFile{Data: []byte("CREATE TABLE second (id INTEGER); NOT SQL;")}
+	migrator, db := newTestMigrator(t, source)
+
+	if err := migrator.Up(context.Background()); err == nil {
+		t.Fatal("expected Up to fail")
+	}
+	assertVersion(t, migrator, 1)
+	if tableExists(t, db, "second") {
+		t.Fatal("expected the failed migration to be rolled back")
+	}
+}
+
+func TestMigrator_Check(t *testing.T) {
+	migrator, db := newTestMigrator(t, testMigrations())
+	ctx := context.Background()
+
+	if err := migrator.Check(ctx); !errors.Is(err, migrate.ErrVersionMismatch) {
+		t.Fatalf("expected ErrVersionMismatch on an empty database, got %v", err)
+	}
+	if err := migrator.Up(ctx); err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+	if err := migrator.Check(ctx); err != nil {
+		t.Fatalf("expected Check to pass, got %v", err)
+	}
+
+	if _, err := db.Exec(`UPDATE schema_migrations SET dirty = TRUE`); err != nil {
+		t.Fatalf("failed to mark schema dirty: %v", err)
+	}
+	if err := migrator.Check(ctx); !errors.Is(err, migrate.ErrDirty) {
+		t.Fatalf("expected ErrDirty, got %v", err)
+	}
+	if err := migrator.Down(ctx); !errors.Is(err, migrate.ErrDirty) {
+		t.Fatalf("expected Down to refuse a dirty schema, got %v", err)
+	}
+}
+
+func TestMigrator_DatabaseNewerThanBinary(t *testing.T) {
+	migrator, db := newTestMigrator(t, testMigrations())
+	ctx := context.Background()
+	if err := migrator.Up(ctx); err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+	if _, err := db.Exec(`UPDATE schema_migrations SET version = 9`); err != nil {
+		t.Fatalf("failed to bump version: %v", err)
+	}
+
+	if err := migrator.Check(ctx); !errors.Is(err, migrate.ErrVersionMismatch) {
+		t.Fatalf("expected ErrVersionMismatch, got %v", err)
+	}
+	if err := migrator.Up(ctx); !errors.Is(err, migrate.ErrUnknownVersion) {
+		t.Fatalf("expected Up to refuse an unknown version, got %v", err)
+	}
+}
+
+func TestNew_InvalidSource(t *testing.T) {
+	tests := []struct {
+		name   string
+		source fstest.MapFS
+	}{
+		{"missing down", fstest.MapFS{"000001_first.up.sql": {Data: []byte("SELECT 1;")}}},
+		{"mismatched names", fstest.MapFS{
+			"000001_first.up.sql":   {Data: []byte("SELECT 1;")},
+			"000001_other.down.sql": {Data: []byte("SELECT 1;")},
+		}},
+		{"zero version", fstest.MapFS{
+			"000000_zero.up.sql":   {Data: []byte("SELECT 1;")},
+			"000000_zero.down.sql": {Data: []byte("SELECT 1;")},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := migrate.New(nil, tt.source); err == nil {
+				t.Fatal("expected error")
+			}
+		})
+	}
+}
+
+func TestEmbeddedMigrations(t *testing.T) {
+	postgres, err := migrate.New(nil, schema.Postgres)
+	if err != nil {
+		t.Fatalf("failed to load Postgres migrations: %v", err)
+	}
+	sqlite, err := migrate.New(nil, schema.SQLite)
+	if err != nil {
+		t.Fatalf("failed to load SQLite migrations: %v", err)
+	}
+	if postgres.Latest() != sqlite.Latest() {
+		t.Fatalf("Postgres and SQLite migrations are out of sync: %d vs %d", postgres.Latest(), sqlite.Latest())
+	}
+
+	// the SQLite migrations must apply and revert cleanly
+	migrator, err := migrate.New(openTestDB(t), schema.SQLite)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if err := migrator.Up(context.Background()); err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+	if err := migrator.To(context.Background(), 0); err != nil {
+		t.Fatalf("To(0) failed: %v", err)
+	}
+}
@@ -5,7 +5,9 @@
 package mock_repository
 
 import (
+	context "context"
 	reflect "reflect"
+	time "time"
 
 	models "github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/models"
 	gomock "github.com/golang/mock/gomock"
@@ -34,6 +36,49 @@ func (m *MockStorage) EXPECT() *MockStorageMockRecorder {
 	return m.recorder
 }
 
+// AmendOrder mocks base method.
+func (m *MockStorage) AmendOrder(ctx context.Context, order *models.Order, amendment models.OrderAmendment) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AmendOrder", ctx, order, amendment)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AmendOrder indicates an expected call of AmendOrder.
+func (mr *MockStorageMockRecorder) AmendOrder(ctx, order, amendment interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AmendOrder", reflect.TypeOf((*MockStorage)(nil).AmendOrder), ctx, order, amendment)
+}
+
+// CheckReplicas mocks base method.
+func (m *MockStorage) CheckReplicas(ctx context.Context) []models.ReplicaStatus {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CheckReplicas", ctx)
+	ret0, _ := ret[0].([]models.ReplicaStatus)
+	return ret0
+}
+
+// CheckReplicas indicates an expected call of CheckReplicas.
+func (mr *MockStorageMockRecorder) CheckReplicas(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckReplicas", reflect.TypeOf((*MockStorage)(nil).CheckReplicas), ctx)
+}
+
+// ClaimOutbox mocks base method.
+func (m *MockStorage) ClaimOutbox(ctx context.Context, limit int, lease time.Duration) ([]models.OutboxEvent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClaimOutbox", ctx, limit, lease)
+	ret0, _ := ret[0].([]models.OutboxEvent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ClaimOutbox indicates an expected call of ClaimOutbox.
+func (mr *MockStorageMockRecorder) ClaimOutbox(ctx, limit, lease interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClaimOutbox", reflect.TypeOf((*MockStorage)(nil).ClaimOutbox), ctx, limit, lease)
+}
+
 // Close mocks base method.
 func (m *MockStorage) Close() {
 	m.ctrl.T.Helper()
@@ -46,25 +91,114 @@ func (mr *MockStorageMockRecorder) Close() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Close", reflect.TypeOf((*MockStorage)(nil).Close))
 }
 
+// ErasePersonalData mocks base method.
+func (m *MockStorage) ErasePersonalData(ctx context.Context, erasure *models.PersonalDataErasure) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ErasePersonalData", ctx, erasure)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ErasePersonalData indicates an expected call of ErasePersonalData.
+func (mr *MockStorageMockRecorder) ErasePersonalData(ctx, erasure interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ErasePersonalData", reflect.TypeOf((*MockStorage)(nil).ErasePersonalData), ctx, erasure)
+}
+
+// GetCustomerStats mocks base method.
+func (m *MockStorage) GetCustomerStats(ctx context.Context, customerID string) (models.CustomerStats, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCustomerStats", ctx, customerID)
+	ret0, _ := ret[0].(models.CustomerStats)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetCustomerStats indicates an expected call of GetCustomerStats.
+func (mr *MockStorageMockRecorder) GetCustomerStats(ctx, customerID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCustomerStats", reflect.TypeOf((*MockStorage)(nil).GetCustomerStats), ctx, customerID)
+}
+
 // GetOrder mocks base method.
-func (m *MockStorage) GetOrder(id string) (*models.Order, error) {
+func (m *MockStorage) GetOrder(ctx context.Context, id string) (*models.Order, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetOrder", id)
+	ret := m.ctrl.Call(m, "GetOrder", ctx, id)
 	ret0, _ := ret[0].(*models.Order)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetOrder indicates an expected call of GetOrder.
-func (mr *MockStorageMockRecorder) GetOrder(id interface{}) *gomock.Call {
+func (mr *MockStorageMockRecorder) GetOrder(ctx, id interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOrder", reflect.TypeOf((*MockStorage)(nil).GetOrder), id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOrder", reflect.TypeOf((*MockStorage)(nil).GetOrder), ctx, id)
+}
+
+// GetOrderByTrackNumber mocks base method.
+func (m *MockStorage) GetOrderByTrackNumber(ctx context.Context, trackNumber string) (*models.Order, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOrderByTrackNumber", ctx, trackNumber)
+	ret0, _ := ret[0].(*models.Order)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOrderByTrackNumber indicates an expected call of GetOrderByTrackNumber.
+func (mr *MockStorageMockRecorder) GetOrderByTrackNumber(ctx, trackNumber interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOrderByTrackNumber", reflect.TypeOf((*MockStorage)(nil).GetOrderByTrackNumber), ctx, trackNumber)
+}
+
+// GetOrderFromPrimary mocks base method.
+func (m *MockStorage) GetOrderFromPrimary(ctx context.Context, id string) (*models.Order, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOrderFromPrimary", ctx, id)
+	ret0, _ := ret[0].(*models.Order)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOrderFromPrimary indicates an expected call of GetOrderFromPrimary.
+func (mr *MockStorageMockRecorder) GetOrderFromPrimary(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOrderFromPrimary", reflect.TypeOf((*MockStorage)(nil).GetOrderFromPrimary), ctx, id)
+}
+
+// GetOrderHistory mocks base method.
+func (m *MockStorage) GetOrderHistory(ctx context.Context, orderUID string) (models.OrderHistory, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOrderHistory", ctx, orderUID)
+	ret0, _ := ret[0].(models.OrderHistory)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOrderHistory indicates an expected call of GetOrderHistory.
+func (mr *MockStorageMockRecorder) GetOrderHistory(ctx, orderUID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOrderHistory", reflect.TypeOf((*MockStorage)(nil).GetOrderHistory), ctx, orderUID)
+}
+
+// GetOrderProvenance mocks base method.
+func (m *MockStorage) GetOrderProvenance(ctx context.Context, orderUID string) (models.OrderProvenance, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOrderProvenance", ctx, orderUID)
+	ret0, _ := ret[0].(models.OrderProvenance)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOrderProvenance indicates an expected call of GetOrderProvenance.
+func (mr *MockStorageMockRecorder) GetOrderProvenance(ctx, orderUID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOrderProvenance", reflect.TypeOf((*MockStorage)(nil).GetOrderProvenance), ctx, orderUID)
 }
 
 // GetOrders mocks base method.
-func (m *MockStorage) GetOrders(amount ...int) ([]*models.Order, error) {
+func (m *MockStorage) GetOrders(ctx context.Context, amount ...int) ([]*models.Order, error) {
 	m.ctrl.T.Helper()
-	varargs := []interface{}{}
+	varargs := []interface{}{ctx}
 	for _, a := range amount {
 		varargs = append(varargs, a)
 	}
@@ -75,35 +209,190 @@ func (m *MockStorage) GetOrders(amount ...int) ([]*models.Order, error) {
 }
 
 // GetOrders indicates an expected call of GetOrders.
-func (mr *MockStorageMockRecorder) GetOrders(amount ...interface{}) *gomock.Call {
+func (mr *MockStorageMockRecorder) GetOrders(ctx interface{}, amount ...interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOrders", reflect.TypeOf((*MockStorage)(nil).GetOrders), amount...)
+	varargs := append([]interface{}{ctx}, amount...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOrders", reflect.TypeOf((*MockStorage)(nil).GetOrders), varargs...)
+}
+
+// ListOrders mocks base method.
+func (m *MockStorage) ListOrders(ctx context.Context, query models.OrderQuery) (models.OrderPage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListOrders", ctx, query)
+	ret0, _ := ret[0].(models.OrderPage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListOrders indicates an expected call of ListOrders.
+func (mr *MockStorageMockRecorder) ListOrders(ctx, query interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListOrders", reflect.TypeOf((*MockStorage)(nil).ListOrders), ctx, query)
+}
+
+// MarkOutboxFailed mocks base method.
+func (m *MockStorage) MarkOutboxFailed(ctx context.Context, id int64, retryAt time.Time, reason string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkOutboxFailed", ctx, id, retryAt, reason)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MarkOutboxFailed indicates an expected call of MarkOutboxFailed.
+func (mr *MockStorageMockRecorder) MarkOutboxFailed(ctx, id, retryAt, reason interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkOutboxFailed", reflect.TypeOf((*MockStorage)(nil).MarkOutboxFailed), ctx, id, retryAt, reason)
+}
+
+// MarkOutboxSent mocks base method.
+func (m *MockStorage) MarkOutboxSent(ctx context.Context, id int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkOutboxSent", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MarkOutboxSent indicates an expected call of MarkOutboxSent.
+func (mr *MockStorageMockRecorder) MarkOutboxSent(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkOutboxSent", reflect.TypeOf((*MockStorage)(nil).MarkOutboxSent), ctx, id)
 }
 
 // Ping mocks base method.
-func (m *MockStorage) Ping() error {
+func (m *MockStorage) Ping(ctx context.Context) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Ping")
+	ret := m.ctrl.Call(m, "Ping", ctx)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // Ping indicates an expected call of Ping.
-func (mr *MockStorageMockRecorder) Ping() *gomock.Call {
+func (mr *MockStorageMockRecorder) Ping(ctx interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Ping", reflect.TypeOf((*MockStorage)(nil).Ping))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Ping", reflect.TypeOf((*MockStorage)(nil).Ping), ctx)
+}
+
+// PurgeOutbox mocks base method.
+func (m *MockStorage) PurgeOutbox(ctx context.Context, sentBefore time.Time) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PurgeOutbox", ctx, sentBefore)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PurgeOutbox indicates an expected call of PurgeOutbox.
+func (mr *MockStorageMockRecorder) PurgeOutbox(ctx, sentBefore interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PurgeOutbox", reflect.TypeOf((*MockStorage)(nil).PurgeOutbox), ctx, sentBefore)
 }
 
 // SaveOrder mocks base method.
-func (m *MockStorage) SaveOrder(order *models.Order) error {
+func (m *MockStorage) SaveOrder(ctx context.Context, order *models.Order) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SaveOrder", order)
+	ret := m.ctrl.Call(m, "SaveOrder", ctx, order)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // SaveOrder indicates an expected call of SaveOrder.
-func (mr *MockStorageMockRecorder) SaveOrder(order interface{}) *gomock.Call {
+func (mr *MockStorageMockRecorder) SaveOrder(ctx, order interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveOrder", reflect.TypeOf((*MockStorage)(nil).SaveOrder), ctx, order)
+}
+
+// SaveOrders mocks base method.
+func (m *MockStorage) SaveOrders(ctx context.Context, orders []*models.Order) ([]error, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveOrders", ctx, orders)
+	ret0, _ := ret[0].([]error)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SaveOrders indicates an expected call of SaveOrders.
+func (mr *MockStorageMockRecorder) SaveOrders(ctx, orders interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveOrders", reflect.TypeOf((*MockStorage)(nil).SaveOrders), ctx, orders)
+}
+
+// UpdateItemStatus mocks base method.
+func (m *MockStorage) UpdateItemStatus(ctx context.Context, update models.StatusUpdate) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateItemStatus", ctx, update)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateItemStatus indicates an expected call of UpdateItemStatus.
+func (mr *MockStorageMockRecorder) UpdateItemStatus(ctx, update interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateItemStatus", reflect.TypeOf((*MockStorage)(nil).UpdateItemStatus), ctx, update)
+}
+
+// MockArchiver is a mock of Archiver interface.
+type MockArchiver struct {
+	ctrl     *gomock.Controller
+	recorder *MockArchiverMockRecorder
+}
+
+// MockArchiverMockRecorder is the mock recorder for MockArchiver.
+type MockArchiverMockRecorder struct {
+	mock *MockArchiver
+}
+
+// NewMockArchiver creates a new mock instance.
+func NewMockArchiver(ctrl *gomock.Controller) *MockArchiver {
+	mock := &MockArchiver{ctrl: ctrl}
+	mock.recorder = &MockArchiverMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockArchiver) EXPECT() *MockArchiverMockRecorder {
+	return m.recorder
+}
+
+// ArchivePartition mocks base method.
+func (m *MockArchiver) ArchivePartition(ctx context.Context, partition models.Partition, before time.Time, write func(models.OrderSnapshot) error, seal func() error) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ArchivePartition", ctx, partition, before, write, seal)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ArchivePartition indicates an expected call of ArchivePartition.
+func (mr *MockArchiverMockRecorder) ArchivePartition(ctx, partition, before, write, seal interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ArchivePartition", reflect.TypeOf((*MockArchiver)(nil).ArchivePartition), ctx, partition, before, write, seal)
+}
+
+// EnsurePartitions mocks base method.
+func (m *MockArchiver) EnsurePartitions(ctx context.Context, from, to time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnsurePartitions", ctx, from, to)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// EnsurePartitions indicates an expected call of EnsurePartitions.
+func (mr *MockArchiverMockRecorder) EnsurePartitions(ctx, from, to interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnsurePartitions", reflect.TypeOf((*MockArchiver)(nil).EnsurePartitions), ctx, from, to)
+}
+
+// OrderPartitions mocks base method.
+func (m *MockArchiver) OrderPartitions(ctx context.Context) ([]models.Partition, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "OrderPartitions", ctx)
+	ret0, _ := ret[0].([]models.Partition)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// OrderPartitions indicates an expected call of OrderPartitions.
+func (mr *MockArchiverMockRecorder) OrderPartitions(ctx interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveOrder", reflect.TypeOf((*MockStorage)(nil).SaveOrder), order)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OrderPartitions", reflect.TypeOf((*MockArchiver)(nil).OrderPartitions), ctx)
 }
@@ -0,0 +1,192 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/models"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository/errs"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/pkg/fieldcrypt"
+	"github.com/lib/pq"
+)
+
+// amendmentColumns are the columns of order_amendments written for every amendment.
+var amendmentColumns = []string{"order_uid", "version", "patch", "previous", "amended_at", "key_id"}
+
+// amendmentRow returns the values of amendmentColumns for an amendment of an order.
+// The personal data in the patch and the previous order is encrypted with keys if it is set, see sealDocument.
+func amendmentRow(keys *fieldcrypt.Keyring, orderUID string, amendment models.OrderAmendment) ([]any, error) {
+	patch, keyID, err := sealDocument(keys, amendment.Patch)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt amendment %d of order %s: %w", amendment.Version, orderUID, err)
+	}
+	previous, _, err := sealDocument(keys, amendment.Previous)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt amendment %d of order %s: %w", amendment.Version, orderUID, err)
+	}
+	return []any{orderUID, amendment.Version, string(patch), string(previous), amendment.AmendedAt.UTC(), keyID}, nil
+}
+
+// openAmendment decrypts in place the patch and the previous order of an amendment read with queryAmendments.
+func openAmendment(keys *fieldcrypt.Keyring, orderUID string, amendment *models.OrderAmendment) error {
+	keyID := sql.NullString{String: amendment.KeyID, Valid: amendment.KeyID != ""}
+	patch, err := openDocument(keys, amendment.Patch, keyID)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt amendment %d of order %s: %w", amendment.Version, orderUID, err)
+	}
+	previous, err := openDocument(keys, amendment.Previous, keyID)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt amendment %d of order %s: %w", amendment.Version, orderUID, err)
+	}
+	amendment.Patch, amendment.Previous, amendment.KeyID = patch, previous, ""
+	return nil
+}
+
+/*
+AmendOrder stores the changes made to an order along with the record of the amendment, as a single transaction.
+
+Only the fields that can be amended are written: the order columns other than its keys, shard key
+and date, and the delivery. Items, payment and the checksum of the order as it was received stay as they are.
+
+The stored order must still be at version amendment.Version-1. If it was amended in the meantime,
+errs.ErrConflict is returned and nothing is written. Returns errs.ErrNotFound if there is no such order.
+On success order.Version is set to amendment.Version.
+*/
+func (s *Storage) AmendOrder(ctx context.Context, order *models.Order, amendment models.OrderAmendment) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", mapError(ctx, err))
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var orderId int
+	err = tx.QueryRowContext(ctx, `UPDATE orders SET
+        entry = $3,
+        locale = $4,
+        internal_signature = $5,
+        customer_id = $6,
+        delivery_service = $7,
+        sm_id = $8,
+        oof_shard = $9,
+        version = version + 1
+    WHERE order_uid = $1 AND version = $2
+    RETURNING id`,
+		order.OrderUID, amendment.Version-1, order.Entry, order.Locale, order.InternalSignature,
+		order.CustomerID, order.DeliveryService, order.SmID, order.OofShard).Scan(&orderId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return checkOrderExists(ctx, tx, order.OrderUID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update order: %w", mapError(ctx, err))
+	}
+	if err := updateDelivery(ctx, tx, s.keys, &order.Delivery, orderId); err != nil {
+		return fmt.Errorf("failed to update delivery: %w", mapError(ctx, err))
+	}
+	row, err := amendmentRow(s.keys, order.OrderUID, amendment)
+	if err != nil {
+		return err
+	}
+	if err := insertRows(ctx, tx, "order_amendments", amendmentColumns, [][]any{row}); err != nil {
+		return fmt.Errorf("failed to record amendment: %w", mapError(ctx, err))
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("transaction commit failed: %w", mapError(ctx, err))
+	}
+	order.Version = amendment.Version
+	return nil
+}
+
+// checkOrderExists is called when the version-guarded update of an order matched no row.
+// It tells a missing order from one that was amended concurrently.
+func checkOrderExists(ctx context.Context, tx *sql.Tx, orderUID string) error {
+	var version int
+	err := tx.QueryRowContext(ctx, `SELECT version FROM orders WHERE order_uid = $1`, orderUID).Scan(&version)
+	if err != nil {
+		return fmt.Errorf("failed to get order %s: %w", orderUID, mapError(ctx, err))
+	}
+	return fmt.Errorf("order %s is at version %d already: %w", orderUID, version, errs.ErrConflict)
+}
+
+// updateDelivery overwrites the delivery details of the given order ID, encrypted with keys if it is set.
+func updateDelivery(ctx context.Context, tx *sql.Tx, keys *fieldcrypt.Keyring, delivery *models.Delivery, orderID int) error {
+	sealed, err := sealDelivery(keys, *delivery)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `UPDATE deliveries SET
+        name = $2,
+        phone = $3,
+        zip = $4,
+        city = $5,
+        address = $6,
+        region = $7,
+        email = $8,
+        key_id = $9,
+        phone_index = $10,
+        email_index = $11
+    WHERE order_id = $1`,
+		orderID, sealed.Name, sealed.Phone, sealed.Zip, sealed.City, sealed.Address, sealed.Region, sealed.Email,
+		sealed.KeyID, sealed.PhoneIndex, sealed.EmailIndex)
+	return err
+}
+
+// importAmendments restores the version and the amendments of an order moved from another shard or restored from an archive.
+// Amendments encrypted with another key, as archives hold them, are decrypted and encrypted again with the active key of keys.
+func importAmendments(ctx context.Context, tx *sql.Tx, keys *fieldcrypt.Keyring, orderId int, moved models.OrderSnapshot) error {
+	if moved.Order.Version > 1 {
+		if _, err := tx.ExecContext(ctx, `UPDATE orders SET version = $2 WHERE id = $1`, orderId, moved.Order.Version); err != nil {
+			return err
+		}
+	}
+	rows := make([][]any, len(moved.Amendments))
+	for i, amendment := range moved.Amendments {
+		if err := openAmendment(keys, moved.Order.OrderUID, &amendment); err != nil {
+			return err
+		}
+		row, err := amendmentRow(keys, moved.Order.OrderUID, amendment)
+		if err != nil {
+			return err
+		}
+		rows[i] = row
+	}
+	return insertRows(ctx, tx, "order_amendments", amendmentColumns, rows)
+}
+
+// queryAmendments returns the amendments of the given orders by order UID, oldest first.
+// Orders that were never amended are missing from the result. Patches and previous orders are left encrypted,
+// with the key ID in the amendment, see openAmendment.
+func queryAmendments(ctx context.Context, db querier, orderUIDs []string) (map[string][]models.OrderAmendment, error) {
+	rows, err := db.QueryContext(ctx, `SELECT
+        order_uid,
+        version,
+        patch,
+        previous,
+        amended_at,
+        key_id
+    FROM order_amendments
+    WHERE order_uid = ANY($1)
+    ORDER BY order_uid, version`, pq.Array(orderUIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	amendments := make(map[string][]models.OrderAmendment)
+	for rows.Next() {
+		var orderUID string
+		var patch, previous []byte
+		var keyID sql.NullString
+		var amendment models.OrderAmendment
+		if err := rows.Scan(&orderUID, &amendment.Version, &patch, &previous, &amendment.AmendedAt, &keyID); err != nil {
+			return nil, fmt.Errorf("failed to scan amendment: %w", err)
+		}
+		amendment.Patch, amendment.Previous, amendment.KeyID = patch, previous, keyID.String
+		amendments[orderUID] = append(amendments[orderUID], amendment)
+	}
+	return amendments, rows.Err()
+}
@@ -0,0 +1,86 @@
+package postgres_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/configs"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/models"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository/errs"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository/postgres"
+)
+
+var amendmentColumns = []string{"order_uid", "version", "patch", "previous", "amended_at", "key_id"}
+
+func testAmendment() (*models.Order, models.OrderAmendment) {
+	order := &models.Order{OrderUID: "uid1", Entry: "WBIL", Locale: "ru", CustomerID: "test", DeliveryService: "meest",
+		SmID: 99, OofShard: "1", Version: 2, Delivery: models.Delivery{Name: "Test Testov", City: "Moscow"}}
+	amendment := models.OrderAmendment{Version: 3, Patch: []byte(`{"locale":"ru"}`), Previous: []byte(`{"locale":"en"}`),
+		AmendedAt: time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)}
+	return order, amendment
+}
+
+func TestPostgresStorer_AmendOrder(t *testing.T) {
+	db, mock := newMockDB(t)
+	ps := postgres.NewStorage(db, configs.Database{}, nil)
+	order, amendment := testAmendment()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("UPDATE orders SET").WithArgs("uid1", 2, "WBIL", "ru", "", "test", "meest", 99, "1").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5))
+	mock.ExpectExec("UPDATE deliveries SET").WithArgs(5, "Test Testov", "", "", "Moscow", "", "", "", nil, nil, nil).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO order_amendments").
+		WithArgs("uid1", 3, `{"locale":"ru"}`, `{"locale":"en"}`, amendment.AmendedAt, nil).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	if err := ps.AmendOrder(context.Background(), order, amendment); err != nil {
+		t.Fatalf("AmendOrder failed: %v", err)
+	}
+	if order.Version != 3 {
+		t.Errorf("expected the order to be at version 3, got %d", order.Version)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestPostgresStorer_AmendOrder_StaleVersion(t *testing.T) {
+	db, mock := newMockDB(t)
+	ps := postgres.NewStorage(db, configs.Database{}, nil)
+	order, amendment := testAmendment()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("UPDATE orders SET").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery("SELECT version FROM orders").WithArgs("uid1").WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(4))
+	mock.ExpectRollback()
+
+	if err := ps.AmendOrder(context.Background(), order, amendment); !errors.Is(err, errs.ErrConflict) {
+		t.Fatalf("expected ErrConflict, got %v", err)
+	}
+	if order.Version != 2 {
+		t.Errorf("expected the version of a rejected amendment to stay, got %d", order.Version)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestPostgresStorer_AmendOrder_NotFound(t *testing.T) {
+	db, mock := newMockDB(t)
+	ps := postgres.NewStorage(db, configs.Database{}, nil)
+	order, amendment := testAmendment()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("UPDATE orders SET").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery("SELECT version FROM orders").WithArgs("uid1").WillReturnRows(sqlmock.NewRows([]string{"version"}))
+	mock.ExpectRollback()
+
+	if err := ps.AmendOrder(context.Background(), order, amendment); !errors.Is(err, errs.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,103 @@
+package postgres_test
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/configs"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository/errs"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository/postgres"
+	"github.com/jmoiron/sqlx"
+)
+
+func TestPostgresStorer_GetOrderByTrackNumber_Success(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open mock db: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	ps := postgres.NewStorage(sqlx.NewDb(db, "postgres"), configs.Database{}, nil)
+
+	mock.ExpectQuery(regexp.QuoteMeta("WHERE orders.track_number = $1")).WithArgs("WBILMTESTTRACK").
+		WillReturnRows(mockOrderRows(1))
+	mock.ExpectQuery("FROM items WHERE order_id = ANY").WillReturnRows(mockItemRows([]int{1}, 2))
+
+	order, err := ps.GetOrderByTrackNumber(context.Background(), "WBILMTESTTRACK")
+	if err != nil {
+		t.Fatalf("expected success, got error: %v", err)
+	}
+	if order.OrderUID != "uid1" || len(order.Items) != 2 {
+		t.Fatalf("unexpected order: %+v", order)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestPostgresStorer_GetOrderByTrackNumber_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open mock db: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	ps := postgres.NewStorage(sqlx.NewDb(db, "postgres"), configs.Database{}, nil)
+
+	mock.ExpectQuery("WHERE orders.track_number").WillReturnRows(mockOrderRows(0))
+
+	if _, err := ps.GetOrderByTrackNumber(context.Background(), "nope"); !errors.Is(err, errs.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestPostgresStorer_GetCustomerStats_Success(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open mock db: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	ps := postgres.NewStorage(sqlx.NewDb(db, "postgres"), configs.Database{}, nil)
+
+	older := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	newer := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(regexp.QuoteMeta("WHERE orders.customer_id = $1")).WithArgs("test").
+		WillReturnRows(sqlmock.NewRows([]string{"currency", "count", "sum", "max"}).
+			AddRow("USD", 3, 300.5, older).
+			AddRow("RUB", 2, 1000, newer))
+
+	stats, err := ps.GetCustomerStats(context.Background(), "test")
+	if err != nil {
+		t.Fatalf("expected success, got error: %v", err)
+	}
+	if stats.OrderCount != 5 {
+		t.Errorf("expected 5 orders, got %d", stats.OrderCount)
+	}
+	if stats.TotalSpend["USD"] != 300.5 || stats.TotalSpend["RUB"] != 1000 {
+		t.Errorf("unexpected total spend: %v", stats.TotalSpend)
+	}
+	if !stats.LastOrderDate.Equal(newer) {
+		t.Errorf("expected last order date %v, got %v", newer, stats.LastOrderDate)
+	}
+}
+
+func TestPostgresStorer_GetCustomerStats_NoOrders(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open mock db: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	ps := postgres.NewStorage(sqlx.NewDb(db, "postgres"), configs.Database{}, nil)
+
+	mock.ExpectQuery("WHERE orders.customer_id").WillReturnRows(sqlmock.NewRows([]string{"currency", "count", "sum", "max"}))
+
+	if _, err := ps.GetCustomerStats(context.Background(), "ghost"); !errors.Is(err, errs.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
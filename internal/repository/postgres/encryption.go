@@ -0,0 +1,160 @@
+package postgres
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/models"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository/listing"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/pkg/fieldcrypt"
+)
+
+// Names of the encrypted delivery fields, authenticated along with their values.
+const (
+	fieldName    = "delivery.name"
+	fieldPhone   = "delivery.phone"
+	fieldAddress = "delivery.address"
+	fieldEmail   = "delivery.email"
+)
+
+// sealedDelivery is a delivery as it is stored: its name, phone, address and email encrypted with KeyID,
+// or plaintext if KeyID is NULL. PhoneIndex and EmailIndex are the blind indexes of encrypted rows.
+type sealedDelivery struct {
+	models.Delivery
+	KeyID      sql.NullString
+	PhoneIndex sql.NullString
+	EmailIndex sql.NullString
+}
+
+// sealDelivery encrypts the personal data of a delivery with the active key of keys.
+// The delivery is stored as plaintext if keys is nil.
+func sealDelivery(keys *fieldcrypt.Keyring, delivery models.Delivery) (sealedDelivery, error) {
+	sealed := sealedDelivery{Delivery: delivery}
+	if keys == nil {
+		return sealed, nil
+	}
+	for _, field := range []struct {
+		name  string
+		value *string
+	}{
+		{fieldName, &sealed.Name},
+		{fieldPhone, &sealed.Phone},
+		{fieldAddress, &sealed.Address},
+		{fieldEmail, &sealed.Email},
+	} {
+		encrypted, err := keys.Encrypt(field.name, *field.value)
+		if err != nil {
+			return sealedDelivery{}, err
+		}
+		*field.value = encrypted
+	}
+	sealed.KeyID = sql.NullString{String: keys.ActiveKey(), Valid: true}
+	sealed.PhoneIndex = sql.NullString{String: keys.BlindIndex(fieldPhone, delivery.Phone), Valid: true}
+	sealed.EmailIndex = sql.NullString{String: keys.BlindIndex(fieldEmail, delivery.Email), Valid: true}
+	return sealed, nil
+}
+
+// openDelivery decrypts in place the personal data of a delivery read from a row encrypted with keyID.
+// Plaintext rows (NULL keyID) are left as they are.
+func openDelivery(keys *fieldcrypt.Keyring, delivery *models.Delivery, keyID sql.NullString) error {
+	if !keyID.Valid {
+		return nil
+	}
+	if keys == nil {
+		return fmt.Errorf("delivery is encrypted with key %q, but database.encryption is not configured: %w", keyID.String, fieldcrypt.ErrUnknownKey)
+	}
+	for _, field := range []struct {
+		name  string
+		value *string
+	}{
+		{fieldName, &delivery.Name},
+		{fieldPhone, &delivery.Phone},
+		{fieldAddress, &delivery.Address},
+		{fieldEmail, &delivery.Email},
+	} {
+		decrypted, err := keys.Decrypt(keyID.String, field.name, *field.value)
+		if err != nil {
+			return err
+		}
+		*field.value = decrypted
+	}
+	return nil
+}
+
+/*
+sealDocument encrypts the personal data in the "delivery" member of a JSON object, such as an ingested payload
+or an amendment, with the active key of keys: its name, phone, address and email, under the same field names
+as the columns of a sealed delivery. Returns the document and the key ID to store along with it.
+The document is stored as plaintext, with a NULL key ID, if keys is nil.
+*/
+func sealDocument(keys *fieldcrypt.Keyring, document []byte) ([]byte, sql.NullString, error) {
+	if keys == nil {
+		return document, sql.NullString{}, nil
+	}
+	sealed, err := mapDelivery(document, keys.Encrypt)
+	if err != nil {
+		return nil, sql.NullString{}, err
+	}
+	return sealed, sql.NullString{String: keys.ActiveKey(), Valid: true}, nil
+}
+
+// openDocument decrypts the delivery in a JSON object sealed with keyID, see sealDocument.
+// Plaintext documents (NULL keyID) are returned as they are.
+func openDocument(keys *fieldcrypt.Keyring, document []byte, keyID sql.NullString) ([]byte, error) {
+	if !keyID.Valid {
+		return document, nil
+	}
+	if keys == nil {
+		return nil, fmt.Errorf("document is encrypted with key %q, but database.encryption is not configured: %w", keyID.String, fieldcrypt.ErrUnknownKey)
+	}
+	return mapDelivery(document, func(field, value string) (string, error) {
+		return keys.Decrypt(keyID.String, field, value)
+	})
+}
+
+// mapDelivery replaces the name, phone, address and email in the "delivery" member of a JSON object
+// with what apply returns for them. Members that are missing or are not strings, e.g. in a patch that leaves them
+// as they are, stay as they are, and so do documents without a delivery.
+func mapDelivery(document []byte, apply func(field, value string) (string, error)) ([]byte, error) {
+	if len(document) == 0 {
+		return document, nil
+	}
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(document, &members); err != nil {
+		return nil, fmt.Errorf("malformed document: %w", err)
+	}
+	var delivery map[string]json.RawMessage
+	if err := json.Unmarshal(members["delivery"], &delivery); err != nil || delivery == nil {
+		return document, nil
+	}
+	for member, field := range map[string]string{"name": fieldName, "phone": fieldPhone, "address": fieldAddress, "email": fieldEmail} {
+		var value string
+		if err := json.Unmarshal(delivery[member], &value); err != nil {
+			continue
+		}
+		mapped, err := apply(field, value)
+		if err != nil {
+			return nil, err
+		}
+		if delivery[member], err = json.Marshal(mapped); err != nil {
+			return nil, err
+		}
+	}
+	var err error
+	if members["delivery"], err = json.Marshal(delivery); err != nil {
+		return nil, err
+	}
+	return json.Marshal(members)
+}
+
+// blindIndex returns the listing.BlindIndex of the storage keys, or nil if deliveries are not encrypted.
+func (s *Storage) blindIndex() listing.BlindIndex {
+	if s.keys == nil {
+		return nil
+	}
+	fields := map[string]string{"phone": fieldPhone, "email": fieldEmail}
+	return func(column, value string) string {
+		return s.keys.BlindIndex(fields[column], value)
+	}
+}
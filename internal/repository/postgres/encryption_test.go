@@ -0,0 +1,381 @@
+package postgres_test
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/configs"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/models"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository/postgres"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/pkg/fieldcrypt"
+	"github.com/jmoiron/sqlx"
+)
+
+// testKeyring holds the keys "k1" and "k2", with the given one active.
+func testKeyring(t *testing.T, active string) *fieldcrypt.Keyring {
+	t.Helper()
+	keys := map[string][]byte{"k1": bytes.Repeat([]byte{1}, fieldcrypt.KeySize), "k2": bytes.Repeat([]byte{2}, fieldcrypt.KeySize)}
+	keyring, err := fieldcrypt.New(keys, active, bytes.Repeat([]byte{9}, fieldcrypt.KeySize))
+	if err != nil {
+		t.Fatalf("failed to create keyring: %v", err)
+	}
+	return keyring
+}
+
+// captured is a sqlmock argument that matches anything and keeps the value it was matched against.
+type captured struct{ value driver.Value }
+
+func (c *captured) Match(value driver.Value) bool {
+	c.value = value
+	return true
+}
+
+func TestPostgresStorer_SaveOrder_EncryptsDelivery(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	keys := testKeyring(t, "k1")
+	s := postgres.NewStorage(sqlx.NewDb(db, "postgres"), configs.Database{Keyring: keys}, nil)
+	order := &models.Order{OrderUID: "uid1", Delivery: models.Delivery{Name: "Test Testov", Phone: "+9720000000", City: "Moscow", Email: "test@gmail.com"}}
+
+	var phone, keyID, phoneIndex captured
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO orders").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectExec("INSERT INTO deliveries").
+		WithArgs(1, sqlmock.AnyArg(), &phone, sqlmock.AnyArg(), "Moscow", sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(),
+			sqlmock.AnyArg(), &keyID, &phoneIndex, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO payments").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO outbox").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	if err := s.SaveOrder(context.Background(), order); err != nil {
+		t.Fatalf("expected success, got error: %v", err)
+	}
+	if phone.value == order.Delivery.Phone {
+		t.Fatal("expected the phone to be stored encrypted")
+	}
+	if decrypted, err := keys.Decrypt("k1", "delivery.phone", phone.value.(string)); err != nil || decrypted != order.Delivery.Phone {
+		t.Fatalf("expected the stored phone to decrypt to %q, got %q (%v)", order.Delivery.Phone, decrypted, err)
+	}
+	if keyID.value != "k1" {
+		t.Fatalf("expected key_id k1, got %v", keyID.value)
+	}
+	if phoneIndex.value != keys.BlindIndex("delivery.phone", order.Delivery.Phone) {
+		t.Fatalf("expected the blind index of the phone, got %v", phoneIndex.value)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestPostgresStorer_EncryptsIngestRecordsAndAmendments(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	keys := testKeyring(t, "k1")
+	s := postgres.NewStorage(sqlx.NewDb(db, "postgres"), configs.Database{Keyring: keys}, nil)
+	ingestedAt := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	plaintext := `{"delivery":{"city":"Moscow","phone":"+9720000000"},"order_uid":"uid1"}`
+	order := &models.Order{OrderUID: "uid1", Delivery: models.Delivery{Phone: "+9720000000", City: "Moscow"},
+		Ingest: &models.IngestRecord{Topic: "orders", Key: "uid1", IngestedAt: ingestedAt, Payload: []byte(plaintext)}}
+
+	var payload captured
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO orders").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectExec("INSERT INTO deliveries").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO payments").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO order_ingest_log").
+		WithArgs("uid1", "orders", int32(0), int64(0), "uid1", sqlmock.AnyArg(), ingestedAt, &payload, nil, "k1").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO outbox").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	if err := s.SaveOrder(context.Background(), order); err != nil {
+		t.Fatalf("expected success, got error: %v", err)
+	}
+	if bytes.Contains([]byte(payload.value.(string)), []byte("+9720000000")) || !bytes.Contains([]byte(payload.value.(string)), []byte("Moscow")) {
+		t.Fatalf("expected only the personal data of the payload to be stored encrypted, got %v", payload.value)
+	}
+
+	mock.ExpectQuery("FROM order_ingest_log").WillReturnRows(sqlmock.NewRows(ingestColumns).
+		AddRow("uid1", "orders", 0, 0, "uid1", nil, ingestedAt, []byte(payload.value.(string)), nil, "k1"))
+	provenance, err := s.GetOrderProvenance(context.Background(), "uid1")
+	if err != nil {
+		t.Fatalf("GetOrderProvenance failed: %v", err)
+	}
+	if string(provenance.Payload) != plaintext || provenance.KeyID != "" {
+		t.Fatalf("expected the payload to be decrypted, got %s", provenance.Payload)
+	}
+
+	var patch, previous captured
+	mock.ExpectBegin()
+	mock.ExpectQuery("UPDATE orders SET").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectExec("UPDATE deliveries SET").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO order_amendments").WithArgs("uid1", 2, &patch, &previous, ingestedAt, "k1").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	amendment := models.OrderAmendment{Version: 2, Patch: []byte(`{"delivery":{"phone":"+9720000001"}}`),
+		Previous: []byte(plaintext), AmendedAt: ingestedAt}
+	if err := s.AmendOrder(context.Background(), order, amendment); err != nil {
+		t.Fatalf("AmendOrder failed: %v", err)
+	}
+	for _, document := range []any{patch.value, previous.value} {
+		if bytes.Contains([]byte(document.(string)), []byte("+972000000")) {
+			t.Fatalf("expected the amendment to be stored encrypted, got %v", document)
+		}
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestPostgresStorer_GetOrders_DecryptsDeliveries(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open mock db: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	keys := testKeyring(t, "k2")
+	encrypt := func(field, value string) string {
+		encrypted, err := keys.Encrypt(field, value)
+		if err != nil {
+			t.Fatalf("failed to encrypt: %v", err)
+		}
+		return encrypted
+	}
+	now := time.Now()
+	rows := sqlmock.NewRows(mockOrderColumns).
+		AddRow(1, "uid1", "track", "entry", "en", "sig", "customer", "d_service", "shard", 1, now, "oof", 1,
+			encrypt("delivery.name", "Test Testov"), encrypt("delivery.phone", "+9720000000"), "zip", "city",
+			encrypt("delivery.address", "Ploshad Mira 15"), "region", encrypt("delivery.email", "test@gmail.com"), "k2",
+			"tx", "req", "USD", "prov", 100, now, "bank", 10, 90, 0).
+		AddRow(2, "uid2", "track", "entry", "en", "sig", "customer", "d_service", "shard", 1, now, "oof", 1,
+			"Plain Name", "+1", "zip", "city", "address", "region", "plain@gmail.com", nil,
+			"tx", "req", "USD", "prov", 100, now, "bank", 10, 90, 0)
+	mock.ExpectQuery("FROM orders").WillReturnRows(rows)
+	mock.ExpectQuery("FROM items WHERE order_id = ANY").WillReturnRows(mockItemRows(nil, 0))
+
+	s := postgres.NewStorage(sqlx.NewDb(db, "postgres"), configs.Database{Keyring: keys}, nil)
+	orders, err := s.GetOrders(context.Background())
+	if err != nil {
+		t.Fatalf("expected success, got error: %v", err)
+	}
+	want := models.Delivery{Name: "Test Testov", Phone: "+9720000000", Zip: "zip", City: "city", Address: "Ploshad Mira 15", Region: "region", Email: "test@gmail.com"}
+	if orders[0].Delivery != want {
+		t.Fatalf("expected decrypted delivery %+v, got %+v", want, orders[0].Delivery)
+	}
+	if orders[1].Delivery.Phone != "+1" {
+		t.Fatalf("expected plaintext delivery to be returned as is, got %+v", orders[1].Delivery)
+	}
+
+	mock.ExpectQuery("FROM orders").WillReturnRows(sqlmock.NewRows(mockOrderColumns).
+		AddRow(1, "uid1", "track", "entry", "en", "sig", "customer", "d_service", "shard", 1, now, "oof", 1,
+			"name", "phone", "zip", "city", "address", "region", "email", "k2",
+			"tx", "req", "USD", "prov", 100, now, "bank", 10, 90, 0))
+	plain := postgres.NewStorage(sqlx.NewDb(db, "postgres"), configs.Database{}, nil)
+	if _, err := plain.GetOrders(context.Background()); !errors.Is(err, fieldcrypt.ErrUnknownKey) {
+		t.Fatalf("expected ErrUnknownKey without keys, got %v", err)
+	}
+}
+
+func TestPostgresStorer_ListOrders_PhoneFilterUsesBlindIndex(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open mock db: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	keys := testKeyring(t, "k1")
+	s := postgres.NewStorage(sqlx.NewDb(db, "postgres"), configs.Database{Keyring: keys}, nil)
+
+	where := regexp.QuoteMeta(`WHERE (deliveries.phone_index = $1 OR (deliveries.key_id IS NULL AND deliveries.phone = $2))`)
+	mock.ExpectQuery(where).WithArgs(keys.BlindIndex("delivery.phone", "+9720000000"), "+9720000000").
+		WillReturnRows(sqlmock.NewRows(mockOrderColumns))
+
+	query := models.OrderQuery{Filter: models.OrderFilter{Phone: "+9720000000"}, Limit: 1}
+	if _, err := s.ListOrders(context.Background(), query); err != nil {
+		t.Fatalf("expected success, got error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestPostgresStorer_RotateKeys(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open mock db: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	old := testKeyring(t, "k1")
+	keys := testKeyring(t, "k2")
+	encrypt := func(field, value string) string {
+		encrypted, err := old.Encrypt(field, value)
+		if err != nil {
+			t.Fatalf("failed to encrypt: %v", err)
+		}
+		return encrypted
+	}
+	orderDate := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	columns := []string{"id", "order_date", "name", "phone", "address", "email", "key_id"}
+
+	var phone captured
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`WHERE id > $1 AND key_id IS DISTINCT FROM $3 ORDER BY id LIMIT $2 FOR UPDATE`)).
+		WithArgs(int64(0), 100, "k2").
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(7, orderDate, encrypt("delivery.name", "Test Testov"), encrypt("delivery.phone", "+9720000000"),
+				encrypt("delivery.address", "Ploshad Mira 15"), encrypt("delivery.email", "test@gmail.com"), "k1").
+			AddRow(9, orderDate, "Plain Name", "+1", "address", "plain@gmail.com", nil))
+	mock.ExpectExec("UPDATE deliveries SET").
+		WithArgs(7, orderDate, sqlmock.AnyArg(), &phone, sqlmock.AnyArg(), sqlmock.AnyArg(), "k2",
+			keys.BlindIndex("delivery.phone", "+9720000000"), keys.BlindIndex("delivery.email", "test@gmail.com")).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE deliveries SET").
+		WithArgs(9, orderDate, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), "k2",
+			keys.BlindIndex("delivery.phone", "+1"), keys.BlindIndex("delivery.email", "plain@gmail.com")).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectQuery("FROM deliveries").WithArgs(int64(9), 100, "k2").WillReturnRows(sqlmock.NewRows(columns))
+	mock.ExpectCommit()
+
+	// the ingested payloads and the amendments follow the deliveries
+	var payload captured
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT order_uid, key_id, payload FROM order_ingest_log WHERE true AND key_id IS DISTINCT FROM $2 ORDER BY order_uid LIMIT $1 FOR UPDATE`)).
+		WithArgs(100, "k2").
+		WillReturnRows(sqlmock.NewRows([]string{"order_uid", "key_id", "payload"}).
+			AddRow("uid1", "k1", []byte(`{"delivery":{"city":"Kiryat Mozkin","phone":"`+encrypt("delivery.phone", "+9720000000")+`"},"order_uid":"uid1"}`)))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE order_ingest_log SET payload = $2, key_id = $3 WHERE order_uid = $1`)).
+		WithArgs("uid1", &payload, "k2").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`WHERE true AND order_uid > $2 AND key_id IS DISTINCT FROM $3`)).WithArgs(100, "uid1", "k2").
+		WillReturnRows(sqlmock.NewRows([]string{"order_uid", "key_id", "payload"}))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, key_id, patch, previous FROM order_amendments`)).WithArgs(100, "k2").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "key_id", "patch", "previous"}))
+	mock.ExpectCommit()
+
+	s := postgres.NewStorage(sqlx.NewDb(db, "postgres"), configs.Database{Keyring: keys}, nil)
+	rewritten, err := s.RotateKeys(context.Background(), 100, postgres.RotateOutdated)
+	if err != nil {
+		t.Fatalf("expected success, got error: %v", err)
+	}
+	if rewritten != 3 {
+		t.Fatalf("expected 2 deliveries and an ingest record rewritten, got %d", rewritten)
+	}
+	if decrypted, err := keys.Decrypt("k2", "delivery.phone", phone.value.(string)); err != nil || decrypted != "+9720000000" {
+		t.Fatalf("expected the phone to be re-encrypted with k2, got %q (%v)", decrypted, err)
+	}
+	var document struct {
+		Delivery struct{ City, Phone string } `json:"delivery"`
+	}
+	if err := json.Unmarshal([]byte(payload.value.(string)), &document); err != nil || document.Delivery.City != "Kiryat Mozkin" {
+		t.Fatalf("expected the payload to keep its plaintext members, got %v (%v)", payload.value, err)
+	}
+	if decrypted, err := keys.Decrypt("k2", "delivery.phone", document.Delivery.Phone); err != nil || decrypted != "+9720000000" {
+		t.Fatalf("expected the phone in the payload to be re-encrypted with k2, got %q (%v)", decrypted, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestPostgresStorer_ArchivePartition_KeepsDeliveriesEncrypted(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open mock db: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	keys := testKeyring(t, "k1")
+	encrypt := func(field, value string) string {
+		encrypted, err := keys.Encrypt(field, value)
+		if err != nil {
+			t.Fatalf("failed to encrypt: %v", err)
+		}
+		return encrypted
+	}
+	phone := encrypt("delivery.phone", "+9720000000")
+	before := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	created := before.AddDate(0, -1, 0)
+	mock.ExpectBegin()
+	mock.ExpectExec("LOCK TABLE").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("orders.tableoid").WithArgs("orders_default", before, int64(0), 500).WillReturnRows(sqlmock.NewRows(mockOrderColumns).
+		AddRow(1, "uid1", "track", "entry", "en", "sig", "customer", "d_service", "shard", 1, created, "oof", 1,
+			encrypt("delivery.name", "Test Testov"), phone, "zip", "city",
+			encrypt("delivery.address", "Ploshad Mira 15"), "region", encrypt("delivery.email", "test@gmail.com"), "k1",
+			"tx", "req", "USD", "prov", 100, created, "bank", 10, 90, 0))
+	mock.ExpectQuery("FROM items WHERE order_id").WillReturnRows(mockItemRows(nil, 0))
+	mock.ExpectQuery("FROM order_ingest_log").WillReturnRows(sqlmock.NewRows(ingestColumns))
+	mock.ExpectQuery("FROM order_amendments").WillReturnRows(sqlmock.NewRows(amendmentColumns))
+	mock.ExpectQuery("SELECT id, checksum FROM orders WHERE id = ANY").WillReturnRows(sqlmock.NewRows([]string{"id", "checksum"}).AddRow(1, "sum"))
+	mock.ExpectQuery("FROM item_status_history").
+		WillReturnRows(sqlmock.NewRows([]string{"order_id", "chrt_id", "rid", "previous_status", "status", "changed_at", "recorded_at"}))
+	mock.ExpectExec("DELETE FROM orders_default").WithArgs(before).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	// the archive holds the delivery as it is stored, the keys are only needed to restore it
+	var written []models.OrderSnapshot
+	s := postgres.NewStorage(sqlx.NewDb(db, "postgres"), configs.Database{}, nil)
+	if _, err := s.ArchivePartition(context.Background(), models.Partition{Name: "default", Default: true}, before,
+		func(order models.OrderSnapshot) error { written = append(written, order); return nil },
+		func() error { return nil }); err != nil {
+		t.Fatalf("ArchivePartition failed: %v", err)
+	}
+	if len(written) != 1 || written[0].KeyID != "k1" || written[0].Order.Delivery.Phone != phone {
+		t.Fatalf("expected the delivery to be archived encrypted with k1, got %+v", written)
+	}
+
+	// restoring decrypts it and encrypts it again with the active key
+	rotated := testKeyring(t, "k2")
+	var restoredPhone, keyID captured
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO orders").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectExec("INSERT INTO deliveries").
+		WithArgs(1, sqlmock.AnyArg(), &restoredPhone, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(),
+			sqlmock.AnyArg(), &keyID, rotated.BlindIndex("delivery.phone", "+9720000000"), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO payments").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	s = postgres.NewStorage(sqlx.NewDb(db, "postgres"), configs.Database{Keyring: rotated}, nil)
+	if err := s.ImportOrder(context.Background(), written[0]); err != nil {
+		t.Fatalf("ImportOrder failed: %v", err)
+	}
+	if keyID.value != "k2" {
+		t.Fatalf("expected the restored delivery to be encrypted with k2, got %v", keyID.value)
+	}
+	if decrypted, err := rotated.Decrypt("k2", "delivery.phone", restoredPhone.value.(string)); err != nil || decrypted != "+9720000000" {
+		t.Fatalf("expected the restored phone to decrypt to the original, got %q (%v)", decrypted, err)
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO orders").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+	mock.ExpectRollback()
+	plain := postgres.NewStorage(sqlx.NewDb(db, "postgres"), configs.Database{}, nil)
+	if err := plain.ImportOrder(context.Background(), written[0]); !errors.Is(err, fieldcrypt.ErrUnknownKey) {
+		t.Fatalf("expected ErrUnknownKey without keys, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unfulfilled expectations: %v", err)
+	}
+}
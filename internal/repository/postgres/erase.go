@@ -0,0 +1,194 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/models"
+	"github.com/lib/pq"
+)
+
+// erasureColumns are the columns of personal_data_erasures written for every erasure.
+var erasureColumns = []string{"customer_id", "order_uids", "source", "reference", "erased_at"}
+
+/*
+ErasePersonalData replaces the personal data of a customer with the placeholders of models.ErasedDelivery
+and writes the compliance record of the erasure, as a single transaction. See EraseCustomerOrders
+for what is replaced. On success erasure.OrderUIDs holds the orders of the customer.
+
+A customer without orders is recorded as well, with no orders.
+*/
+func (s *Storage) ErasePersonalData(ctx context.Context, erasure *models.PersonalDataErasure) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", mapError(ctx, err))
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	orderUIDs, err := eraseCustomerOrders(ctx, tx, erasure.CustomerID)
+	if err != nil {
+		return err
+	}
+	record := *erasure
+	record.OrderUIDs = orderUIDs
+	if err := insertErasure(ctx, tx, record); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("transaction commit failed: %w", mapError(ctx, err))
+	}
+	erasure.OrderUIDs = orderUIDs
+	return nil
+}
+
+/*
+EraseCustomerOrders replaces the personal data in the orders of a customer and returns their UIDs, sorted.
+The sharded storage erases the orders of every shard with it and records the erasure once, with RecordErasure.
+
+The delivery of every order is replaced, and so is the delivery in the ingested payload of the order
+and in its recorded amendments. The placeholders are stored as plaintext, they hold nothing to protect.
+Items, payment and the rest of the order stay for accounting.
+The version of the erased orders is bumped, so amendments made to them before are rejected.
+*/
+func (s *Storage) EraseCustomerOrders(ctx context.Context, customerID string) ([]string, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", mapError(ctx, err))
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	orderUIDs, err := eraseCustomerOrders(ctx, tx, customerID)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("transaction commit failed: %w", mapError(ctx, err))
+	}
+	return orderUIDs, nil
+}
+
+// RecordErasure writes the compliance record of an erasure made with EraseCustomerOrders.
+func (s *Storage) RecordErasure(ctx context.Context, erasure models.PersonalDataErasure) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", mapError(ctx, err))
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := insertErasure(ctx, tx, erasure); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("transaction commit failed: %w", mapError(ctx, err))
+	}
+	return nil
+}
+
+/*
+CustomerErasedSince reports whether the personal data of a customer was erased at or after the given time.
+
+An erasure only reaches the orders stored when it is made. Restoring an archive and moving orders between shards
+use it to erase the orders dated before an erasure that were elsewhere at the time.
+*/
+func (s *Storage) CustomerErasedSince(ctx context.Context, customerID string, since time.Time) (bool, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	var erased bool
+	err := s.db.QueryRowContext(ctx, `SELECT EXISTS (
+        SELECT 1 FROM personal_data_erasures WHERE customer_id = $1 AND erased_at >= $2
+    )`, customerID, since.UTC()).Scan(&erased)
+	if err != nil {
+		return false, fmt.Errorf("failed to check erasures: %w", mapError(ctx, err))
+	}
+	return erased, nil
+}
+
+// eraseCustomerOrders replaces the personal data in the orders of a customer, see EraseCustomerOrders.
+func eraseCustomerOrders(ctx context.Context, tx *sql.Tx, customerID string) ([]string, error) {
+	rows, err := tx.QueryContext(ctx, `UPDATE orders SET version = version + 1 WHERE customer_id = $1 RETURNING id, order_uid`, customerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update orders: %w", mapError(ctx, err))
+	}
+	var orderIDs []int64
+	var orderUIDs []string
+	for rows.Next() {
+		var orderID int64
+		var orderUID string
+		if err := rows.Scan(&orderID, &orderUID); err != nil {
+			_ = rows.Close()
+			return nil, fmt.Errorf("failed to scan order: %w", err)
+		}
+		orderIDs = append(orderIDs, orderID)
+		orderUIDs = append(orderUIDs, orderUID)
+	}
+	_ = rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to update orders: %w", mapError(ctx, err))
+	}
+	if len(orderUIDs) == 0 {
+		return []string{}, nil
+	}
+	slices.Sort(orderUIDs)
+
+	erased := models.ErasedDelivery
+	if _, err := tx.ExecContext(ctx, `UPDATE deliveries SET
+        name = $2,
+        phone = $3,
+        zip = $4,
+        city = $5,
+        address = $6,
+        region = $7,
+        email = $8,
+        key_id = NULL,
+        phone_index = NULL,
+        email_index = NULL
+    WHERE order_id = ANY($1)`,
+		pq.Array(orderIDs), erased.Name, erased.Phone, erased.Zip, erased.City, erased.Address, erased.Region, erased.Email); err != nil {
+		return nil, fmt.Errorf("failed to erase deliveries: %w", mapError(ctx, err))
+	}
+	delivery, err := json.Marshal(erased)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal erased delivery: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE order_ingest_log SET
+        payload = jsonb_set(payload, '{delivery}', $2::jsonb, false),
+        key_id = NULL
+    WHERE order_uid = ANY($1)`, pq.Array(orderUIDs), string(delivery)); err != nil {
+		return nil, fmt.Errorf("failed to erase ingested payloads: %w", mapError(ctx, err))
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE order_amendments SET
+        patch = jsonb_set(patch, '{delivery}', $2::jsonb, false),
+        previous = jsonb_set(previous, '{delivery}', $2::jsonb, false),
+        key_id = NULL
+    WHERE order_uid = ANY($1)`, pq.Array(orderUIDs), string(delivery)); err != nil {
+		return nil, fmt.Errorf("failed to erase amendments: %w", mapError(ctx, err))
+	}
+	return orderUIDs, nil
+}
+
+// insertErasure writes the compliance record of an erasure.
+func insertErasure(ctx context.Context, tx *sql.Tx, erasure models.PersonalDataErasure) error {
+	if erasure.OrderUIDs == nil {
+		erasure.OrderUIDs = []string{}
+	}
+	orderUIDs, err := json.Marshal(erasure.OrderUIDs)
+	if err != nil {
+		return fmt.Errorf("failed to marshal erased orders: %w", err)
+	}
+	row := []any{erasure.CustomerID, string(orderUIDs), erasure.Source, erasure.Reference, erasure.ErasedAt.UTC()}
+	if err := insertRows(ctx, tx, "personal_data_erasures", erasureColumns, [][]any{row}); err != nil {
+		return fmt.Errorf("failed to record erasure: %w", mapError(ctx, err))
+	}
+	return nil
+}
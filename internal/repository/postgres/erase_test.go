@@ -0,0 +1,134 @@
+package postgres_test
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/configs"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/models"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository/postgres"
+)
+
+func TestPostgresStorer_ErasePersonalData(t *testing.T) {
+	db, mock := newMockDB(t)
+	ps := postgres.NewStorage(db, configs.Database{}, nil)
+	erasure := &models.PersonalDataErasure{CustomerID: "test", Source: models.ErasureSourceCLI, Reference: "TICKET-1",
+		ErasedAt: time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)}
+	erased := models.ErasedDelivery
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("UPDATE orders SET version = version \\+ 1 WHERE customer_id").WithArgs("test").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "order_uid"}).AddRow(7, "uid2").AddRow(5, "uid1"))
+	mock.ExpectExec("UPDATE deliveries SET").
+		WithArgs(sqlmock.AnyArg(), erased.Name, erased.Phone, erased.Zip, erased.City, erased.Address, erased.Region, erased.Email).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec("UPDATE order_ingest_log SET").WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec("UPDATE order_amendments SET").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO personal_data_erasures").
+		WithArgs("test", `["uid1","uid2"]`, models.ErasureSourceCLI, "TICKET-1", erasure.ErasedAt).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	if err := ps.ErasePersonalData(context.Background(), erasure); err != nil {
+		t.Fatalf("ErasePersonalData failed: %v", err)
+	}
+	if !slices.Equal(erasure.OrderUIDs, []string{"uid1", "uid2"}) {
+		t.Errorf("unexpected erased orders: %v", erasure.OrderUIDs)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestPostgresStorer_ErasePersonalData_NoOrders(t *testing.T) {
+	db, mock := newMockDB(t)
+	ps := postgres.NewStorage(db, configs.Database{}, nil)
+	erasure := &models.PersonalDataErasure{CustomerID: "nobody", Source: models.ErasureSourceAPI}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("UPDATE orders SET version").WithArgs("nobody").WillReturnRows(sqlmock.NewRows([]string{"id", "order_uid"}))
+	mock.ExpectExec("INSERT INTO personal_data_erasures").
+		WithArgs("nobody", `[]`, models.ErasureSourceAPI, "", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	if err := ps.ErasePersonalData(context.Background(), erasure); err != nil {
+		t.Fatalf("ErasePersonalData failed: %v", err)
+	}
+	if len(erasure.OrderUIDs) != 0 {
+		t.Errorf("expected no erased orders, got %v", erasure.OrderUIDs)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestPostgresStorer_CustomerErasedSince(t *testing.T) {
+	db, mock := newMockDB(t)
+	ps := postgres.NewStorage(db, configs.Database{}, nil)
+	placed := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery("SELECT 1 FROM personal_data_erasures WHERE customer_id").WithArgs("test", placed).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	erased, err := ps.CustomerErasedSince(context.Background(), "test", placed)
+	if err != nil {
+		t.Fatalf("CustomerErasedSince failed: %v", err)
+	}
+	if !erased {
+		t.Error("expected the customer to be reported as erased")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestPostgresStorer_ImportOrder_ErasedSnapshot(t *testing.T) {
+	db, mock := newMockDB(t)
+	ps := postgres.NewStorage(db, configs.Database{}, nil)
+	placed := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	snapshot := models.OrderSnapshot{
+		Order: &models.Order{OrderUID: "uid1", TrackNumber: "track", CustomerID: "test", DateCreated: placed, Version: 2,
+			Delivery: models.Delivery{Name: "Test Testov", Phone: "+9720000000", Email: "test@gmail.com"}},
+		Checksum: "sum",
+		Ingest: &models.IngestRecord{Topic: "orders", Key: "uid1", IngestedAt: placed,
+			Payload: []byte(`{"delivery":{"name":"Test Testov"},"order_uid":"uid1"}`)},
+		Amendments: []models.OrderAmendment{
+			{Version: 2, Patch: []byte(`{"locale":"ru"}`), Previous: []byte(`{"delivery":{"name":"Test Testov"},"locale":"en"}`), AmendedAt: placed},
+		},
+	}
+	if err := snapshot.ErasePersonalData(); err != nil {
+		t.Fatalf("ErasePersonalData failed: %v", err)
+	}
+	if err := snapshot.ErasePersonalData(); err != nil || snapshot.Order.Version != 3 {
+		t.Fatalf("expected an erased order to be left as it is, got version %d, error %v", snapshot.Order.Version, err)
+	}
+	erased := models.ErasedDelivery
+	delivery, _ := json.Marshal(erased)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO orders").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+	mock.ExpectExec("INSERT INTO deliveries").
+		WithArgs(3, erased.Name, erased.Phone, erased.Zip, erased.City, erased.Address, erased.Region, erased.Email,
+			placed, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO payments").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO order_ingest_log").
+		WithArgs("uid1", "orders", int32(0), int64(0), "uid1", sqlmock.AnyArg(), placed,
+			`{"delivery":`+string(delivery)+`,"order_uid":"uid1"}`, nil, nil).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("UPDATE orders SET version").WithArgs(3, 3).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO order_amendments").
+		WithArgs("uid1", 2, `{"locale":"ru"}`, `{"delivery":`+string(delivery)+`,"locale":"en"}`, placed, nil).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	if err := ps.ImportOrder(context.Background(), snapshot); err != nil {
+		t.Fatalf("ImportOrder failed: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
@@ -0,0 +1,77 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository/errs"
+	"github.com/lib/pq"
+)
+
+// mapError wraps a database error into one of the errs classes.
+//
+// The original error stays in the chain, so both errors.Is(err, errs.ErrX)
+// and errors.As(err, *pq.Error) keep working for callers.
+// If ctx has hit its deadline, the error is reported as a timeout, since the driver
+// only sees a cancelled query. Errors that don't belong to any known class are returned unchanged.
+func mapError(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+	class := errorClass(err)
+	if class == nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		class = errs.ErrTimeout
+	}
+	if class != nil {
+		return fmt.Errorf("%w: %w", class, err)
+	}
+	return err
+}
+
+// errorClass returns the errs sentinel that matches err, or nil if there is none.
+func errorClass(err error) error {
+	var pqErr *pq.Error
+	var netErr net.Error
+	switch {
+	case errors.Is(err, errs.ErrNotFound), errors.Is(err, errs.ErrDuplicate), errors.Is(err, errs.ErrConflict),
+		errors.Is(err, errs.ErrConstraint), errors.Is(err, errs.ErrUnavailable), errors.Is(err, errs.ErrTimeout),
+		errors.Is(err, errs.ErrInvalidArgument):
+		return nil // already classified
+	case errors.Is(err, sql.ErrNoRows):
+		return errs.ErrNotFound
+	case errors.Is(err, context.DeadlineExceeded):
+		return errs.ErrTimeout
+	case errors.Is(err, driver.ErrBadConn), errors.Is(err, sql.ErrConnDone):
+		return errs.ErrUnavailable
+	case errors.As(err, &pqErr):
+		return pqErrorClass(pqErr)
+	case errors.As(err, &netErr):
+		if netErr.Timeout() {
+			return errs.ErrTimeout
+		}
+		return errs.ErrUnavailable
+	}
+	return nil
+}
+
+// pqErrorClass maps a Postgres SQLSTATE code to an errs class.
+// A unique violation is a constraint violation like any other: storage tells a redelivered order
+// by its checksum (see checkStoredOrder), not by the constraint it hits.
+// See https://www.postgresql.org/docs/current/errcodes-appendix.html
+func pqErrorClass(pqErr *pq.Error) error {
+	switch pqErr.Code {
+	case "57014": // query_canceled (statement_timeout)
+		return errs.ErrTimeout
+	}
+	switch pqErr.Code.Class() {
+	case "22", "23": // data_exception, integrity_constraint_violation
+		return errs.ErrConstraint
+	case "08", "53", "57": // connection_exception, insufficient_resources, operator_intervention
+		return errs.ErrUnavailable
+	}
+	return nil
+}
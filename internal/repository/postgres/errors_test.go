@@ -0,0 +1,79 @@
+package postgres_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/configs"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository/errs"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository/postgres"
+	mock_logger "github.com/Pur1st2EpicONE/WBTECH-sample-microservice/pkg/logger/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+func TestPostgresStorer_ErrorClasses(t *testing.T) {
+	tests := []struct {
+		name     string
+		dbErr    error
+		expected error
+	}{
+		{"no rows", sql.ErrNoRows, errs.ErrNotFound},
+		{"unique violation", &pq.Error{Code: "23505"}, errs.ErrConstraint},
+		{"foreign key violation", &pq.Error{Code: "23503"}, errs.ErrConstraint},
+		{"value too long", &pq.Error{Code: "22001"}, errs.ErrConstraint},
+		{"connection failure", &pq.Error{Code: "08006"}, errs.ErrUnavailable},
+		{"admin shutdown", &pq.Error{Code: "57P01"}, errs.ErrUnavailable},
+		{"statement timeout", &pq.Error{Code: "57014"}, errs.ErrTimeout},
+		{"deadline exceeded", context.DeadlineExceeded, errs.ErrTimeout},
+		{"connection refused", &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}, errs.ErrUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("failed to open sqlmock: %v", err)
+			}
+			defer func() { _ = db.Close() }()
+
+			logger := mock_logger.NewMockLogger(gomock.NewController(t))
+			ps := postgres.NewStorage(sqlx.NewDb(db, "postgres"), configs.Database{}, logger)
+
+			mock.ExpectQuery("SELECT").WillReturnError(tt.dbErr)
+
+			_, err = ps.GetOrder(context.Background(), "aboba")
+			if !errors.Is(err, tt.expected) {
+				t.Fatalf("expected %v, got %v", tt.expected, err)
+			}
+			if !errors.Is(err, tt.dbErr) {
+				t.Fatalf("expected original error to stay in the chain, got %v", err)
+			}
+		})
+	}
+}
+
+func TestPostgresStorer_UnknownErrorUnchanged(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	logger := mock_logger.NewMockLogger(gomock.NewController(t))
+	ps := postgres.NewStorage(sqlx.NewDb(db, "postgres"), configs.Database{}, logger)
+
+	dbErr := fmt.Errorf("something odd")
+	mock.ExpectQuery("SELECT").WillReturnError(dbErr)
+
+	_, err = ps.GetOrder(context.Background(), "aboba")
+	if err != dbErr {
+		t.Fatalf("expected unclassified error to be returned as is, got %v", err)
+	}
+}
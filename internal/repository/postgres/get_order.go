@@ -2,29 +2,50 @@ package postgres
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"time"
 
 	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/models"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/pkg/fieldcrypt"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 )
 
 // GetOrder retrieves a single order by its UID, including delivery, payment, and item details.
-func (s *Storage) GetOrder(orderUID string) (*models.Order, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+// Returns errs.ErrNotFound if there is no order with such UID.
+func (s *Storage) GetOrder(ctx context.Context, orderUID string) (*models.Order, error) {
+	ctx, cancel := s.withTimeout(ctx)
 	defer cancel()
+	return read(ctx, s, func(db *sqlx.DB) (*models.Order, error) {
+		return s.getOrder(ctx, db, orderUID)
+	})
+}
+
+// GetOrderFromPrimary retrieves a single order like GetOrder, but never from a read replica,
+// so the order is at its latest version even if the replicas lag behind.
+func (s *Storage) GetOrderFromPrimary(ctx context.Context, orderUID string) (*models.Order, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	return s.getOrder(ctx, s.db, orderUID)
+}
+
+// getOrder queries a single order by its UID on db.
+func (s *Storage) getOrder(ctx context.Context, db *sqlx.DB, orderUID string) (*models.Order, error) {
 	var orderId int
 	order := new(models.Order)
-	if err := queryAllButItems(ctx, s, order, orderUID, &orderId); err != nil {
-		return nil, err
+	if err := queryAllButItems(ctx, db, s.keys, order, orderUID, &orderId); err != nil {
+		return nil, mapError(ctx, err)
 	}
-	if err := queryItems(ctx, s, &order.Items, orderId); err != nil {
-		return nil, err
+	if err := queryItems(ctx, db, &order.Items, orderId); err != nil {
+		return nil, mapError(ctx, err)
 	}
 	return order, nil
 }
 
 // queryAllButItems queries order, delivery, and payment information excluding items.
-func queryAllButItems(ctx context.Context, s *Storage, order *models.Order, orderUID string, orderId *int) error {
+// The delivery is decrypted with keys if it is stored encrypted.
+func queryAllButItems(ctx context.Context, db *sqlx.DB, keys *fieldcrypt.Keyring, order *models.Order, orderUID string, orderId *int) error {
 	query := `SELECT 
 
         orders.id, 
@@ -39,6 +60,7 @@ func queryAllButItems(ctx context.Context, s *Storage, order *models.Order, orde
         orders.sm_id,
         orders.date_created,
         orders.oof_shard,
+        orders.version,
 
         deliveries.name, 
         deliveries.phone, 
@@ -47,6 +69,7 @@ func queryAllButItems(ctx context.Context, s *Storage, order *models.Order, orde
         deliveries.address,
         deliveries.region,
         deliveries.email,
+        deliveries.key_id,
 
         payments.transaction, 
         payments.request_id, 
@@ -64,8 +87,9 @@ func queryAllButItems(ctx context.Context, s *Storage, order *models.Order, orde
         JOIN payments ON orders.id = payments.order_id
         WHERE orders.order_uid = $1`
 
-	row := s.db.QueryRowContext(ctx, query, orderUID)
+	row := db.QueryRowContext(ctx, query, orderUID)
 	var paymentTime time.Time
+	var keyID sql.NullString
 	if err := row.Scan(orderId,
 
 		&order.OrderUID,
@@ -79,6 +103,7 @@ func queryAllButItems(ctx context.Context, s *Storage, order *models.Order, orde
 		&order.SmID,
 		&order.DateCreated,
 		&order.OofShard,
+		&order.Version,
 
 		&order.Delivery.Name,
 		&order.Delivery.Phone,
@@ -87,6 +112,7 @@ func queryAllButItems(ctx context.Context, s *Storage, order *models.Order, orde
 		&order.Delivery.Address,
 		&order.Delivery.Region,
 		&order.Delivery.Email,
+		&keyID,
 
 		&order.Payment.Transaction,
 		&order.Payment.RequestID,
@@ -102,11 +128,11 @@ func queryAllButItems(ctx context.Context, s *Storage, order *models.Order, orde
 		return err
 	}
 	order.Payment.PaymentDT = paymentTime.Unix()
-	return nil
+	return openDelivery(keys, &order.Delivery, keyID)
 }
 
 // queryItems retrieves all item records associated with a given order ID.
-func queryItems(ctx context.Context, s *Storage, items *[]models.Item, orderId int) error {
+func queryItems(ctx context.Context, db *sqlx.DB, items *[]models.Item, orderId int) error {
 	query := `SELECT 
         chrt_id,
         track_number,
@@ -121,7 +147,7 @@ func queryItems(ctx context.Context, s *Storage, items *[]models.Item, orderId i
         status
         FROM items WHERE order_id = $1`
 
-	rows, err := s.db.QueryContext(ctx, query, orderId)
+	rows, err := db.QueryContext(ctx, query, orderId)
 	if err != nil {
 		return err
 	}
@@ -153,11 +179,34 @@ func queryItems(ctx context.Context, s *Storage, items *[]models.Item, orderId i
 
 // GetOrders retrieves multiple orders, optionally limited by a specified amount.
 // Each order includes delivery, payment, and item details.
-func (s *Storage) GetOrders(amount ...int) ([]*models.Order, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+//
+// Items for all orders are loaded with a single query, so the number of round-trips
+// stays constant regardless of how many orders are returned.
+func (s *Storage) GetOrders(ctx context.Context, amount ...int) ([]*models.Order, error) {
+	ctx, cancel := s.withTimeout(ctx)
 	defer cancel()
 
-	query := `SELECT 
+	query := ordersSelect
+
+	if len(amount) > 0 && amount[0] > 0 {
+		query += fmt.Sprintf("\nLIMIT %d", amount[0])
+	}
+
+	return read(ctx, s, func(db *sqlx.DB) ([]*models.Order, error) {
+		orders, orderIds, err := queryOrders(ctx, db, s.keys, query)
+		if err != nil {
+			return nil, mapError(ctx, err)
+		}
+		if err := queryItemsBulk(ctx, db, orders, orderIds); err != nil {
+			return nil, mapError(ctx, err)
+		}
+		return orders, nil
+	})
+}
+
+// ordersSelect selects order, delivery, and payment columns in the order expected by queryOrders.
+// Callers append their own WHERE, ORDER BY, and LIMIT clauses.
+const ordersSelect = `SELECT 
 
         orders.id,
         orders.order_uid, 
@@ -171,6 +220,7 @@ func (s *Storage) GetOrders(amount ...int) ([]*models.Order, error) {
         orders.sm_id,
         orders.date_created,
         orders.oof_shard,
+        orders.version,
 
         deliveries.name, 
         deliveries.phone, 
@@ -179,6 +229,7 @@ func (s *Storage) GetOrders(amount ...int) ([]*models.Order, error) {
         deliveries.address,
         deliveries.region,
         deliveries.email,
+        deliveries.key_id,
 
         payments.transaction, 
         payments.request_id, 
@@ -195,22 +246,42 @@ func (s *Storage) GetOrders(amount ...int) ([]*models.Order, error) {
     JOIN deliveries ON orders.id = deliveries.order_id
     JOIN payments ON orders.id = payments.order_id`
 
-	if len(amount) > 0 && amount[0] > 0 {
-		query += fmt.Sprintf("\nLIMIT %d", amount[0])
+// queryOrders runs a query built on top of ordersSelect and returns the scanned orders
+// along with their database IDs. Items are not loaded.
+//
+// All rows are read and closed before returning, so the connection is free for the follow-up items query.
+// Deliveries are decrypted with keys if they are stored encrypted.
+func queryOrders(ctx context.Context, db querier, keys *fieldcrypt.Keyring, query string, args ...any) ([]*models.Order, []int64, error) {
+	orders, orderIds, keyIDs, err := querySealedOrders(ctx, db, query, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	for i, order := range orders {
+		if err := openDelivery(keys, &order.Delivery, keyIDs[i]); err != nil {
+			return nil, nil, fmt.Errorf("order %s: %w", order.OrderUID, err)
+		}
 	}
+	return orders, orderIds, nil
+}
 
-	rows, err := s.db.QueryContext(ctx, query)
+// querySealedOrders is queryOrders that leaves deliveries as they are stored,
+// returning the IDs of the keys they are encrypted with (NULL for plaintext) along with them.
+func querySealedOrders(ctx context.Context, db querier, query string, args ...any) ([]*models.Order, []int64, []sql.NullString, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, err
+		return nil, nil, nil, err
 	}
 	defer func() { _ = rows.Close() }()
 
 	var orders []*models.Order
-	var orderId int
+	var orderIds []int64
+	var keyIDs []sql.NullString
 
 	for rows.Next() {
 		order := new(models.Order)
+		var orderId int64
 		var paymentTime time.Time
+		var keyID sql.NullString
 
 		err := rows.Scan(
 			&orderId,
@@ -225,6 +296,7 @@ func (s *Storage) GetOrders(amount ...int) ([]*models.Order, error) {
 			&order.SmID,
 			&order.DateCreated,
 			&order.OofShard,
+			&order.Version,
 
 			&order.Delivery.Name,
 			&order.Delivery.Phone,
@@ -233,6 +305,7 @@ func (s *Storage) GetOrders(amount ...int) ([]*models.Order, error) {
 			&order.Delivery.Address,
 			&order.Delivery.Region,
 			&order.Delivery.Email,
+			&keyID,
 
 			&order.Payment.Transaction,
 			&order.Payment.RequestID,
@@ -246,13 +319,73 @@ func (s *Storage) GetOrders(amount ...int) ([]*models.Order, error) {
 			&order.Payment.CustomFee,
 		)
 		if err != nil {
-			return nil, err
+			return nil, nil, nil, err
 		}
 		order.Payment.PaymentDT = paymentTime.Unix()
-		if err := queryItems(ctx, s, &order.Items, orderId); err != nil {
-			return nil, err
-		}
 		orders = append(orders, order)
+		orderIds = append(orderIds, orderId)
+		keyIDs = append(keyIDs, keyID)
+	}
+	return orders, orderIds, keyIDs, rows.Err()
+}
+
+// queryItemsBulk loads items for all given orders with a single query and attaches them to their orders.
+// orders and orderIds must be parallel slices, as returned by queryOrders.
+func queryItemsBulk(ctx context.Context, db querier, orders []*models.Order, orderIds []int64) error {
+	if len(orderIds) == 0 {
+		return nil
+	}
+	query := `SELECT 
+        order_id,
+        chrt_id,
+        track_number,
+        price,
+        rid,
+        name,
+        sale,
+        size,
+        total_price,
+        nm_id,
+        brand,
+        status
+        FROM items WHERE order_id = ANY($1)
+        ORDER BY order_id, id`
+
+	rows, err := db.QueryContext(ctx, query, pq.Array(orderIds))
+	if err != nil {
+		return err
+	}
+
+	defer func() { _ = rows.Close() }()
+
+	byId := make(map[int64]*models.Order, len(orders))
+	for i, order := range orders {
+		byId[orderIds[i]] = order
 	}
-	return orders, rows.Err()
+
+	for rows.Next() {
+		var orderId int64
+		var item models.Item
+		err := rows.Scan(
+			&orderId,
+			&item.ChrtID,
+			&item.TrackNumber,
+			&item.Price,
+			&item.Rid,
+			&item.Name,
+			&item.Sale,
+			&item.Size,
+			&item.TotalPrice,
+			&item.NmID,
+			&item.Brand,
+			&item.Status,
+		)
+		if err != nil {
+			return err
+		}
+		if order, found := byId[orderId]; found {
+			order.Items = append(order.Items, item)
+		}
+	}
+	return rows.Err()
 }
@@ -1,11 +1,13 @@
 package postgres_test
 
 import (
+	"context"
 	"fmt"
 	"testing"
 	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/configs"
 	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository/postgres"
 	mock_logger "github.com/Pur1st2EpicONE/WBTECH-sample-microservice/pkg/logger/mocks"
 	"github.com/golang/mock/gomock"
@@ -20,7 +22,7 @@ func TestPostgresStorer_GetOrder_Success(t *testing.T) {
 	defer func() { _ = db.Close() }()
 
 	logger := mock_logger.NewMockLogger(gomock.NewController(t))
-	ps := postgres.NewStorage(sqlx.NewDb(db, "postgres"), logger)
+	ps := postgres.NewStorage(sqlx.NewDb(db, "postgres"), configs.Database{}, logger)
 
 	orderUID := "test-uid"
 	orderID := 1
@@ -40,6 +42,7 @@ func TestPostgresStorer_GetOrder_Success(t *testing.T) {
         orders.sm_id,
         orders.date_created,
         orders.oof_shard,
+        orders.version,
 
         deliveries.name, 
         deliveries.phone, 
@@ -48,6 +51,7 @@ func TestPostgresStorer_GetOrder_Success(t *testing.T) {
         deliveries.address,
         deliveries.region,
         deliveries.email,
+        deliveries.key_id,
 
         payments.transaction, 
         payments.request_id, 
@@ -78,6 +82,7 @@ func TestPostgresStorer_GetOrder_Success(t *testing.T) {
 		"shardkey", "sm_id",
 		"date_created",
 		"oof_shard",
+		"version",
 		"name",
 		"phone",
 		"zip",
@@ -85,6 +90,7 @@ func TestPostgresStorer_GetOrder_Success(t *testing.T) {
 		"address",
 		"region",
 		"email",
+		"key_id",
 		"transaction",
 		"request_id",
 		"currency",
@@ -109,6 +115,7 @@ func TestPostgresStorer_GetOrder_Success(t *testing.T) {
 		1,
 		time.Now(),
 		"oof",
+		1,
 		"name",
 		"phone",
 		"zip",
@@ -116,6 +123,7 @@ func TestPostgresStorer_GetOrder_Success(t *testing.T) {
 		"address",
 		"region",
 		"email",
+		nil,
 		"tx",
 		"req",
 		"USD",
@@ -170,7 +178,7 @@ func TestPostgresStorer_GetOrder_Success(t *testing.T) {
 		1,
 	))
 
-	order, err := ps.GetOrder(orderUID)
+	order, err := ps.GetOrder(context.Background(), orderUID)
 	if err != nil {
 		t.Fatalf("expected success, got error: %v", err)
 	}
@@ -190,7 +198,7 @@ func TestPostgresStorer_QueryAllButItems_RowsScanError(t *testing.T) {
 	defer func() { _ = db.Close() }()
 
 	logger := mock_logger.NewMockLogger(gomock.NewController(t))
-	ps := postgres.NewStorage(sqlx.NewDb(db, "postgres"), logger)
+	ps := postgres.NewStorage(sqlx.NewDb(db, "postgres"), configs.Database{}, logger)
 
 	orderQuery := `SELECT
 
@@ -206,6 +214,7 @@ func TestPostgresStorer_QueryAllButItems_RowsScanError(t *testing.T) {
         orders.sm_id,
         orders.date_created,
         orders.oof_shard,
+        orders.version,
 
         deliveries.name,
         deliveries.phone,
@@ -214,6 +223,7 @@ func TestPostgresStorer_QueryAllButItems_RowsScanError(t *testing.T) {
         deliveries.address,
         deliveries.region,
         deliveries.email,
+        deliveries.key_id,
 
         payments.transaction,
         payments.request_id,
@@ -233,7 +243,7 @@ func TestPostgresStorer_QueryAllButItems_RowsScanError(t *testing.T) {
 
 	mock.ExpectQuery(orderQuery).WithArgs(sqlmock.AnyArg()).WillReturnError(fmt.Errorf("query failed"))
 
-	_, err = ps.GetOrder("some-uid")
+	_, err = ps.GetOrder(context.Background(), "some-uid")
 	if err == nil {
 		t.Fatalf("expected query error, got: %v", err)
 	}
@@ -247,7 +257,7 @@ func TestPostgresStorer_QueryItems_RowsScanError(t *testing.T) {
 	defer func() { _ = db.Close() }()
 
 	logger := mock_logger.NewMockLogger(gomock.NewController(t))
-	ps := postgres.NewStorage(sqlx.NewDb(db, "postgres"), logger)
+	ps := postgres.NewStorage(sqlx.NewDb(db, "postgres"), configs.Database{}, logger)
 
 	paymentTime := time.Now()
 
@@ -265,6 +275,7 @@ func TestPostgresStorer_QueryItems_RowsScanError(t *testing.T) {
         orders.sm_id,
         orders.date_created,
         orders.oof_shard,
+        orders.version,
 
         deliveries.name,
         deliveries.phone,
@@ -273,6 +284,7 @@ func TestPostgresStorer_QueryItems_RowsScanError(t *testing.T) {
         deliveries.address,
         deliveries.region,
         deliveries.email,
+        deliveries.key_id,
 
         payments.transaction,
         payments.request_id,
@@ -305,6 +317,7 @@ func TestPostgresStorer_QueryItems_RowsScanError(t *testing.T) {
 			"sm_id",
 			"date_created",
 			"oof_shard",
+			"version",
 			"name",
 			"phone",
 			"zip",
@@ -312,6 +325,7 @@ func TestPostgresStorer_QueryItems_RowsScanError(t *testing.T) {
 			"address",
 			"region",
 			"email",
+			"key_id",
 			"transaction",
 			"request_id",
 			"currency",
@@ -335,6 +349,7 @@ func TestPostgresStorer_QueryItems_RowsScanError(t *testing.T) {
 			1,
 			time.Now(),
 			"oof",
+			1,
 			"name",
 			"phone",
 			"zip",
@@ -342,6 +357,7 @@ func TestPostgresStorer_QueryItems_RowsScanError(t *testing.T) {
 			"address",
 			"region",
 			"email",
+			nil,
 			"tx",
 			"req",
 			"USD",
@@ -396,7 +412,7 @@ func TestPostgresStorer_QueryItems_RowsScanError(t *testing.T) {
 		1,
 	))
 
-	_, err = ps.GetOrder("uid1")
+	_, err = ps.GetOrder(context.Background(), "uid1")
 	if err == nil {
 		t.Fatalf("expected scan error, got nil")
 	}
@@ -410,7 +426,7 @@ func TestPostgresStorer_QueryItems_QueryContextError(t *testing.T) {
 	defer func() { _ = db.Close() }()
 
 	logger := mock_logger.NewMockLogger(gomock.NewController(t))
-	ps := postgres.NewStorage(sqlx.NewDb(db, "postgres"), logger)
+	ps := postgres.NewStorage(sqlx.NewDb(db, "postgres"), configs.Database{}, logger)
 
 	paymentTime := time.Now()
 
@@ -428,6 +444,7 @@ func TestPostgresStorer_QueryItems_QueryContextError(t *testing.T) {
         orders.sm_id,
         orders.date_created,
         orders.oof_shard,
+        orders.version,
 
         deliveries.name,
         deliveries.phone,
@@ -436,6 +453,7 @@ func TestPostgresStorer_QueryItems_QueryContextError(t *testing.T) {
         deliveries.address,
         deliveries.region,
         deliveries.email,
+        deliveries.key_id,
 
         payments.transaction,
         payments.request_id,
@@ -466,6 +484,7 @@ func TestPostgresStorer_QueryItems_QueryContextError(t *testing.T) {
 		"sm_id",
 		"date_created",
 		"oof_shard",
+		"version",
 		"name",
 		"phone",
 		"zip",
@@ -473,6 +492,7 @@ func TestPostgresStorer_QueryItems_QueryContextError(t *testing.T) {
 		"address",
 		"region",
 		"email",
+		"key_id",
 		"transaction",
 		"request_id",
 		"currency",
@@ -496,6 +516,7 @@ func TestPostgresStorer_QueryItems_QueryContextError(t *testing.T) {
 		1,
 		time.Now(),
 		"oof",
+		1,
 		"name",
 		"phone",
 		"zip",
@@ -503,6 +524,7 @@ func TestPostgresStorer_QueryItems_QueryContextError(t *testing.T) {
 		"address",
 		"region",
 		"email",
+		nil,
 		"tx",
 		"req",
 		"USD",
@@ -515,8 +537,8 @@ func TestPostgresStorer_QueryItems_QueryContextError(t *testing.T) {
 		0,
 	))
 
-	itemsQuery := `SELECT
-
+	itemsQuery := `SELECT 
+        order_id,
         chrt_id,
         track_number,
         price,
@@ -528,11 +550,12 @@ func TestPostgresStorer_QueryItems_QueryContextError(t *testing.T) {
         nm_id,
         brand,
         status
-        FROM items WHERE order_id = $1`
+        FROM items WHERE order_id = ANY($1)
+        ORDER BY order_id, id`
 
 	mock.ExpectQuery(itemsQuery).WithArgs(sqlmock.AnyArg()).WillReturnError(fmt.Errorf("items query failed"))
 
-	_, err = ps.GetOrders()
+	_, err = ps.GetOrders(context.Background())
 	if err == nil || err.Error() != "items query failed" {
 		t.Fatalf("expected items query error, got: %v", err)
 	}
@@ -546,7 +569,7 @@ func TestPostgresStorer_GetOrders_Success(t *testing.T) {
 	defer func() { _ = db.Close() }()
 
 	logger := mock_logger.NewMockLogger(gomock.NewController(t))
-	ps := postgres.NewStorage(sqlx.NewDb(db, "postgres"), logger)
+	ps := postgres.NewStorage(sqlx.NewDb(db, "postgres"), configs.Database{}, logger)
 
 	orderID := 1
 	paymentTime := time.Now()
@@ -565,6 +588,7 @@ func TestPostgresStorer_GetOrders_Success(t *testing.T) {
         orders.sm_id,
         orders.date_created,
         orders.oof_shard,
+        orders.version,
 
         deliveries.name, 
         deliveries.phone, 
@@ -573,6 +597,7 @@ func TestPostgresStorer_GetOrders_Success(t *testing.T) {
         deliveries.address,
         deliveries.region,
         deliveries.email,
+        deliveries.key_id,
 
         payments.transaction, 
         payments.request_id, 
@@ -603,6 +628,7 @@ func TestPostgresStorer_GetOrders_Success(t *testing.T) {
 		"shardkey", "sm_id",
 		"date_created",
 		"oof_shard",
+		"version",
 		"name",
 		"phone",
 		"zip",
@@ -610,6 +636,7 @@ func TestPostgresStorer_GetOrders_Success(t *testing.T) {
 		"address",
 		"region",
 		"email",
+		"key_id",
 		"transaction",
 		"request_id",
 		"currency",
@@ -633,6 +660,7 @@ func TestPostgresStorer_GetOrders_Success(t *testing.T) {
 		1,
 		time.Now(),
 		"oof",
+		1,
 		"name",
 		"phone",
 		"zip",
@@ -640,6 +668,7 @@ func TestPostgresStorer_GetOrders_Success(t *testing.T) {
 		"address",
 		"region",
 		"email",
+		nil,
 		"tx",
 		"req",
 		"USD",
@@ -653,7 +682,7 @@ func TestPostgresStorer_GetOrders_Success(t *testing.T) {
 	))
 
 	itemsQuery := `SELECT 
-
+        order_id,
         chrt_id,
         track_number,
         price,
@@ -665,10 +694,12 @@ func TestPostgresStorer_GetOrders_Success(t *testing.T) {
         nm_id,
         brand,
         status
-        FROM items WHERE order_id = $1`
+        FROM items WHERE order_id = ANY($1)
+        ORDER BY order_id, id`
 
-	mock.ExpectQuery(itemsQuery).WithArgs(orderID).WillReturnRows(sqlmock.NewRows([]string{
+	mock.ExpectQuery(itemsQuery).WithArgs(sqlmock.AnyArg()).WillReturnRows(sqlmock.NewRows([]string{
 
+		"order_id",
 		"chrt_id",
 		"track_number",
 		"price",
@@ -681,6 +712,7 @@ func TestPostgresStorer_GetOrders_Success(t *testing.T) {
 		"brand",
 		"status",
 	}).AddRow(
+		orderID,
 		1,
 		"track1",
 		100,
@@ -694,7 +726,7 @@ func TestPostgresStorer_GetOrders_Success(t *testing.T) {
 		1,
 	))
 
-	orders, err := ps.GetOrders(1)
+	orders, err := ps.GetOrders(context.Background(), 1)
 	if err != nil {
 		t.Fatalf("expected success, got error: %v", err)
 	}
@@ -714,7 +746,7 @@ func TestPostgresStorer_GetAllOrders_QueryContextError(t *testing.T) {
 	defer func() { _ = db.Close() }()
 
 	logger := mock_logger.NewMockLogger(gomock.NewController(t))
-	ps := postgres.NewStorage(sqlx.NewDb(db, "postgres"), logger)
+	ps := postgres.NewStorage(sqlx.NewDb(db, "postgres"), configs.Database{}, logger)
 
 	allQuery := `SELECT
 
@@ -730,6 +762,7 @@ func TestPostgresStorer_GetAllOrders_QueryContextError(t *testing.T) {
         orders.sm_id,
         orders.date_created,
         orders.oof_shard,
+        orders.version,
 
         deliveries.name,
         deliveries.phone,
@@ -738,6 +771,7 @@ func TestPostgresStorer_GetAllOrders_QueryContextError(t *testing.T) {
         deliveries.address,
         deliveries.region,
         deliveries.email,
+        deliveries.key_id,
 
         payments.transaction,
         payments.request_id,
@@ -756,7 +790,7 @@ func TestPostgresStorer_GetAllOrders_QueryContextError(t *testing.T) {
 
 	mock.ExpectQuery(allQuery).WillReturnError(fmt.Errorf("all orders query failed"))
 
-	_, err = ps.GetOrders()
+	_, err = ps.GetOrders(context.Background())
 	if err == nil || err.Error() != "all orders query failed" {
 		t.Fatalf("expected all orders query error, got: %v", err)
 	}
@@ -770,7 +804,7 @@ func TestPostgresStorer_GetAllOrders_RowsScanError(t *testing.T) {
 	defer func() { _ = db.Close() }()
 
 	logger := mock_logger.NewMockLogger(gomock.NewController(t))
-	ps := postgres.NewStorage(sqlx.NewDb(db, "postgres"), logger)
+	ps := postgres.NewStorage(sqlx.NewDb(db, "postgres"), configs.Database{}, logger)
 
 	allQuery := `SELECT
 
@@ -786,6 +820,7 @@ func TestPostgresStorer_GetAllOrders_RowsScanError(t *testing.T) {
         orders.sm_id,
         orders.date_created,
         orders.oof_shard,
+        orders.version,
 
         deliveries.name,
         deliveries.phone,
@@ -794,6 +829,7 @@ func TestPostgresStorer_GetAllOrders_RowsScanError(t *testing.T) {
         deliveries.address,
         deliveries.region,
         deliveries.email,
+        deliveries.key_id,
 
         payments.transaction,
         payments.request_id,
@@ -824,6 +860,7 @@ func TestPostgresStorer_GetAllOrders_RowsScanError(t *testing.T) {
 		"sm_id",
 		"date_created",
 		"oof_shard",
+		"version",
 		"name",
 		"phone",
 		"zip",
@@ -831,6 +868,7 @@ func TestPostgresStorer_GetAllOrders_RowsScanError(t *testing.T) {
 		"address",
 		"region",
 		"email",
+		"key_id",
 		"transaction",
 		"request_id",
 		"currency",
@@ -854,6 +892,7 @@ func TestPostgresStorer_GetAllOrders_RowsScanError(t *testing.T) {
 		1,
 		time.Now(),
 		"oof",
+		1,
 		"name",
 		"phone",
 		"zip",
@@ -861,6 +900,7 @@ func TestPostgresStorer_GetAllOrders_RowsScanError(t *testing.T) {
 		"address",
 		"region",
 		"email",
+		nil,
 		"tx",
 		"req",
 		"USD",
@@ -873,8 +913,174 @@ func TestPostgresStorer_GetAllOrders_RowsScanError(t *testing.T) {
 		0,
 	))
 
-	_, err = ps.GetOrders()
+	_, err = ps.GetOrders(context.Background())
 	if err == nil {
 		t.Fatalf("expected scan error, got nil")
 	}
 }
+
+// benchRoundTrip simulates network latency of a single query round-trip to the database.
+const benchRoundTrip = 100 * time.Microsecond
+
+var mockOrderColumns = []string{
+	"id", "order_uid", "track_number", "entry", "locale", "internal_signature", "customer_id",
+	"delivery_service", "shardkey", "sm_id", "date_created", "oof_shard", "version",
+	"name", "phone", "zip", "city", "address", "region", "email", "key_id",
+	"transaction", "request_id", "currency", "provider", "amount", "payment_dt",
+	"bank", "delivery_cost", "goods_total", "custom_fee",
+}
+
+var mockItemColumns = []string{
+	"order_id", "chrt_id", "track_number", "price", "rid", "name", "sale",
+	"size", "total_price", "nm_id", "brand", "status",
+}
+
+func mockOrderRows(n int) *sqlmock.Rows {
+	rows := sqlmock.NewRows(mockOrderColumns)
+	now := time.Now()
+	for i := 1; i <= n; i++ {
+		rows.AddRow(i, fmt.Sprintf("uid%d", i), "track", "entry", "en", "sig", "customer",
+			"d_service", "shard", 1, now, "oof", 1,
+			"name", "phone", "zip", "city", "address", "region", "email", nil,
+			"tx", "req", "USD", "prov", 100, now, "bank", 10, 90, 0)
+	}
+	return rows
+}
+
+func mockItemRows(orderIds []int, perOrder int) *sqlmock.Rows {
+	rows := sqlmock.NewRows(mockItemColumns)
+	for _, id := range orderIds {
+		for j := 0; j < perOrder; j++ {
+			rows.AddRow(id, j, "track", 100, "rid", "item", 0, "M", 100, j, "brand", 202)
+		}
+	}
+	return rows
+}
+
+// getOrdersPerOrderItems reproduces the previous access pattern of GetOrders:
+// one query for orders followed by a separate items query for every order row.
+func getOrdersPerOrderItems(ctx context.Context, db *sqlx.DB) error {
+	rows, err := db.QueryContext(ctx, "SELECT orders.id FROM orders")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = rows.Close() }()
+	dest := make([]any, len(mockOrderColumns))
+	for i := range dest {
+		dest[i] = new(any)
+	}
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return err
+		}
+		id := *dest[0].(*any)
+		items, err := db.QueryContext(ctx, "SELECT chrt_id FROM items WHERE order_id = $1", id)
+		if err != nil {
+			return err
+		}
+		for items.Next() {
+		}
+		_ = items.Close()
+	}
+	return rows.Err()
+}
+
+func BenchmarkGetOrders(b *testing.B) {
+	const itemsPerOrder = 3
+	for _, n := range []int{10, 100, 1000} {
+		ids := make([]int, n)
+		for i := range ids {
+			ids[i] = i + 1
+		}
+
+		b.Run(fmt.Sprintf("single_items_query/%d", n), func(b *testing.B) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				b.Fatalf("failed to open mock db: %v", err)
+			}
+			defer func() { _ = db.Close() }()
+			ps := postgres.NewStorage(sqlx.NewDb(db, "postgres"), configs.Database{}, nil)
+
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				mock.ExpectQuery("FROM orders").WillDelayFor(benchRoundTrip).WillReturnRows(mockOrderRows(n))
+				mock.ExpectQuery("FROM items").WillDelayFor(benchRoundTrip).WillReturnRows(mockItemRows(ids, itemsPerOrder))
+				b.StartTimer()
+				if _, err := ps.GetOrders(context.Background(), n); err != nil {
+					b.Fatalf("GetOrders failed: %v", err)
+				}
+			}
+		})
+
+		b.Run(fmt.Sprintf("per_order_items_query/%d", n), func(b *testing.B) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				b.Fatalf("failed to open mock db: %v", err)
+			}
+			defer func() { _ = db.Close() }()
+			sqlxDB := sqlx.NewDb(db, "postgres")
+
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				mock.ExpectQuery("FROM orders").WillDelayFor(benchRoundTrip).WillReturnRows(mockOrderRows(n))
+				for _, id := range ids {
+					mock.ExpectQuery("FROM items").WithArgs(id).WillDelayFor(benchRoundTrip).
+						WillReturnRows(mockItemRows([]int{id}, itemsPerOrder))
+				}
+				b.StartTimer()
+				if err := getOrdersPerOrderItems(context.Background(), sqlxDB); err != nil {
+					b.Fatalf("per-order baseline failed: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestPostgresStorer_GetOrders_ItemsDistributed(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open mock db: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	ps := postgres.NewStorage(sqlx.NewDb(db, "postgres"), configs.Database{}, nil)
+
+	mock.ExpectQuery("FROM orders").WillReturnRows(mockOrderRows(3))
+	mock.ExpectQuery("FROM items WHERE order_id = ANY").WillReturnRows(mockItemRows([]int{1, 3}, 2))
+
+	orders, err := ps.GetOrders(context.Background())
+	if err != nil {
+		t.Fatalf("expected success, got error: %v", err)
+	}
+	if len(orders) != 3 {
+		t.Fatalf("expected 3 orders, got %d", len(orders))
+	}
+	for i, want := range []int{2, 0, 2} {
+		if got := len(orders[i].Items); got != want {
+			t.Errorf("order %s: expected %d items, got %d", orders[i].OrderUID, want, got)
+		}
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestPostgresStorer_GetOrders_NoOrdersSkipsItems(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open mock db: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	ps := postgres.NewStorage(sqlx.NewDb(db, "postgres"), configs.Database{}, nil)
+
+	mock.ExpectQuery("FROM orders").WillReturnRows(mockOrderRows(0))
+
+	orders, err := ps.GetOrders(context.Background())
+	if err != nil || len(orders) != 0 {
+		t.Fatalf("expected no orders and no error, got %v, %v", orders, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unfulfilled expectations: %v", err)
+	}
+}
@@ -0,0 +1,140 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/models"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/pkg/fieldcrypt"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// ingestColumns are the columns of order_ingest_log written for every ingest record.
+var ingestColumns = []string{"order_uid", "topic", "kafka_partition", "kafka_offset", "message_key",
+	"message_timestamp", "ingested_at", "payload", "violations", "key_id"}
+
+// ingestRow returns the values of ingestColumns for the ingest record of an order.
+// Violations are stored as a JSON array, or NULL if there are none.
+// The personal data in the payload is encrypted with keys if it is set, see sealDocument.
+func ingestRow(keys *fieldcrypt.Keyring, orderUID string, record *models.IngestRecord) ([]any, error) {
+	timestamp := sql.NullTime{Time: record.Timestamp.UTC(), Valid: !record.Timestamp.IsZero()}
+	var violations sql.NullString
+	if len(record.Violations) > 0 {
+		encoded, _ := json.Marshal(record.Violations) // plain strings always encode
+		violations = sql.NullString{String: string(encoded), Valid: true}
+	}
+	payload, keyID, err := sealDocument(keys, record.Payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt payload of order %s: %w", orderUID, err)
+	}
+	return []any{orderUID, record.Topic, record.Partition, record.Offset, record.Key,
+		timestamp, record.IngestedAt.UTC(), string(payload), violations, keyID}, nil
+}
+
+// insertIngestRecords records the messages orders were received in, within the transaction of the orders.
+// Orders without an ingest record are skipped.
+func insertIngestRecords(ctx context.Context, tx *sql.Tx, keys *fieldcrypt.Keyring, orders []*models.Order) error {
+	var rows [][]any
+	for _, order := range orders {
+		if order.Ingest == nil {
+			continue
+		}
+		row, err := ingestRow(keys, order.OrderUID, order.Ingest)
+		if err != nil {
+			return err
+		}
+		rows = append(rows, row)
+	}
+	return insertRows(ctx, tx, "order_ingest_log", ingestColumns, rows)
+}
+
+// openIngestRecord decrypts in place the payload of an ingest record read with queryIngestRecords.
+func openIngestRecord(keys *fieldcrypt.Keyring, orderUID string, record *models.IngestRecord) error {
+	payload, err := openDocument(keys, record.Payload, sql.NullString{String: record.KeyID, Valid: record.KeyID != ""})
+	if err != nil {
+		return fmt.Errorf("failed to decrypt payload of order %s: %w", orderUID, err)
+	}
+	record.Payload, record.KeyID = payload, ""
+	return nil
+}
+
+// GetOrderProvenance returns the message an order was received in.
+// Returns errs.ErrNotFound if no message was recorded for the order,
+// e.g. because it was saved before ingest records were kept.
+func (s *Storage) GetOrderProvenance(ctx context.Context, orderUID string) (models.OrderProvenance, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	return read(ctx, s, func(db *sqlx.DB) (models.OrderProvenance, error) {
+		records, err := queryIngestRecords(ctx, db, []string{orderUID})
+		if err != nil {
+			return models.OrderProvenance{}, mapError(ctx, err)
+		}
+		record, ok := records[orderUID]
+		if !ok {
+			return models.OrderProvenance{}, mapError(ctx, sql.ErrNoRows)
+		}
+		if err := openIngestRecord(s.keys, orderUID, record); err != nil {
+			return models.OrderProvenance{}, err
+		}
+		return models.OrderProvenance{OrderUID: orderUID, IngestRecord: *record}, nil
+	})
+}
+
+// queryIngestRecords returns the ingest records of the given orders by order UID. Orders without one are missing from the result.
+// Payloads are left encrypted, with the key ID in the record, see openIngestRecord.
+func queryIngestRecords(ctx context.Context, db querier, orderUIDs []string) (map[string]*models.IngestRecord, error) {
+	rows, err := db.QueryContext(ctx, `SELECT
+        order_uid,
+        topic,
+        kafka_partition,
+        kafka_offset,
+        message_key,
+        message_timestamp,
+        ingested_at,
+        payload,
+        violations,
+        key_id
+    FROM order_ingest_log
+    WHERE order_uid = ANY($1)`, pq.Array(orderUIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	records := make(map[string]*models.IngestRecord, len(orderUIDs))
+	for rows.Next() {
+		var orderUID string
+		var timestamp sql.NullTime
+		var payload, violations []byte
+		var keyID sql.NullString
+		record := new(models.IngestRecord)
+		if err := rows.Scan(
+			&orderUID,
+			&record.Topic,
+			&record.Partition,
+			&record.Offset,
+			&record.Key,
+			&timestamp,
+			&record.IngestedAt,
+			&payload,
+			&violations,
+			&keyID,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan ingest record: %w", err)
+		}
+		if violations != nil {
+			if err := json.Unmarshal(violations, &record.Violations); err != nil {
+				return nil, fmt.Errorf("malformed violations of order %s: %w", orderUID, err)
+			}
+		}
+		record.Timestamp = timestamp.Time
+		record.Payload = payload
+		record.KeyID = keyID.String
+		records[orderUID] = record
+	}
+	return records, rows.Err()
+}
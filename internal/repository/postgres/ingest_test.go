@@ -0,0 +1,71 @@
+package postgres_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/configs"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/models"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository/errs"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository/postgres"
+)
+
+var ingestColumns = []string{"order_uid", "topic", "kafka_partition", "kafka_offset", "message_key",
+	"message_timestamp", "ingested_at", "payload", "violations", "key_id"}
+
+func TestPostgresStorer_SaveOrder_WritesIngestRecord(t *testing.T) {
+	db, mock := newMockDB(t)
+	ps := postgres.NewStorage(db, configs.Database{}, nil)
+	ingestedAt := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	order := &models.Order{OrderUID: "uid1", Ingest: &models.IngestRecord{
+		Topic: "orders", Partition: 2, Offset: 42, Key: "uid1", IngestedAt: ingestedAt, Payload: []byte(`{"order_uid":"uid1"}`),
+		Violations: []models.RuleViolation{{Rule: "payment_amount", Severity: models.SeverityFlag, Message: "off"}},
+	}}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO orders").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectExec("INSERT INTO deliveries").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO payments").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO order_ingest_log").
+		WithArgs("uid1", "orders", int32(2), int64(42), "uid1", sqlmock.AnyArg(), ingestedAt, `{"order_uid":"uid1"}`,
+			`[{"rule":"payment_amount","severity":"flag","message":"off"}]`, nil).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO outbox").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	if err := ps.SaveOrder(context.Background(), order); err != nil {
+		t.Fatalf("SaveOrder failed: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestPostgresStorer_GetOrderProvenance(t *testing.T) {
+	db, mock := newMockDB(t)
+	ps := postgres.NewStorage(db, configs.Database{}, nil)
+	ingestedAt := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery("FROM order_ingest_log").WillReturnRows(sqlmock.NewRows(ingestColumns).
+		AddRow("uid1", "orders", 2, 42, "uid1", nil, ingestedAt, []byte(`{"order_uid":"uid1"}`), []byte(`[{"rule":"payment_amount","severity":"flag","message":"off"}]`), nil))
+	provenance, err := ps.GetOrderProvenance(context.Background(), "uid1")
+	if err != nil {
+		t.Fatalf("GetOrderProvenance failed: %v", err)
+	}
+	if provenance.OrderUID != "uid1" || provenance.Partition != 2 || provenance.Offset != 42 ||
+		!provenance.Timestamp.IsZero() || string(provenance.Payload) != `{"order_uid":"uid1"}` ||
+		len(provenance.Violations) != 1 || provenance.Violations[0].Rule != "payment_amount" {
+		t.Fatalf("unexpected provenance: %+v", provenance)
+	}
+
+	mock.ExpectQuery("FROM order_ingest_log").WillReturnRows(sqlmock.NewRows(ingestColumns))
+	if _, err := ps.GetOrderProvenance(context.Background(), "uid2"); !errors.Is(err, errs.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
@@ -0,0 +1,52 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/models"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository/listing"
+	"github.com/jmoiron/sqlx"
+)
+
+/*
+ListOrders returns a single page of orders that match the query filter.
+
+Pagination is keyset-based (see the listing package), so a deep page costs as much as the first one,
+and orders saved in the meantime don't shift the pages a client is scrolling through.
+
+The phone and email filters match encrypted deliveries by their blind index.
+
+An empty sort defaults to models.SortDateDesc. A malformed cursor, an unknown sort order,
+or a non-positive limit result in errs.ErrInvalidArgument. Items for the whole page are
+loaded with one extra query.
+*/
+func (s *Storage) ListOrders(ctx context.Context, query models.OrderQuery) (models.OrderPage, error) {
+	if query.Sort == "" {
+		query.Sort = models.SortDateDesc
+	}
+	sqlQuery, args, err := listing.BuildQuery(ordersSelect, query, s.blindIndex())
+	if err != nil {
+		return models.OrderPage{}, err
+	}
+
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	return read(ctx, s, func(db *sqlx.DB) (models.OrderPage, error) {
+		orders, orderIds, err := queryOrders(ctx, db, s.keys, sqlQuery, args...)
+		if err != nil {
+			return models.OrderPage{}, mapError(ctx, err)
+		}
+
+		page := models.OrderPage{Orders: []*models.Order{}}
+		if len(orders) > query.Limit { // one extra row was requested to tell whether there is a next page
+			orders, orderIds = orders[:query.Limit], orderIds[:query.Limit]
+			page.NextCursor = listing.EncodeCursor(query.Sort, orders[len(orders)-1], orderIds[len(orderIds)-1])
+		}
+		if err := queryItemsBulk(ctx, db, orders, orderIds); err != nil {
+			return models.OrderPage{}, mapError(ctx, err)
+		}
+		page.Orders = append(page.Orders, orders...)
+		return page, nil
+	})
+}
@@ -0,0 +1,129 @@
+package postgres_test
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/configs"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/models"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository/errs"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository/postgres"
+	"github.com/jmoiron/sqlx"
+)
+
+func TestPostgresStorer_ListOrders_FiltersAndPagination(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open mock db: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	ps := postgres.NewStorage(sqlx.NewDb(db, "postgres"), configs.Database{}, nil)
+
+	from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	query := models.OrderQuery{
+		Filter: models.OrderFilter{CustomerID: "customer", Currency: "USD", ItemStatus: 202, CreatedFrom: from},
+		Limit:  2,
+	}
+
+	firstPage := regexp.QuoteMeta(`WHERE orders.customer_id = $1
+    AND payments.currency = $2
+    AND EXISTS (SELECT 1 FROM items WHERE items.order_id = orders.id AND items.status = $3)
+    AND orders.date_created >= $4
+    ORDER BY orders.date_created DESC, orders.id DESC
+    LIMIT 3`)
+	mock.ExpectQuery(firstPage).WithArgs("customer", "USD", 202, from).WillReturnRows(mockOrderRows(3))
+	mock.ExpectQuery("FROM items WHERE order_id = ANY").WillReturnRows(mockItemRows([]int{1, 2}, 1))
+
+	page, err := ps.ListOrders(context.Background(), query)
+	if err != nil {
+		t.Fatalf("expected success, got error: %v", err)
+	}
+	if len(page.Orders) != 2 || page.NextCursor == "" {
+		t.Fatalf("expected 2 orders and a next cursor, got %d orders, cursor %q", len(page.Orders), page.NextCursor)
+	}
+
+	query.Cursor = page.NextCursor
+	secondPage := regexp.QuoteMeta(`AND (orders.date_created, orders.id) < ($5, $6)
+    ORDER BY orders.date_created DESC, orders.id DESC
+    LIMIT 3`)
+	mock.ExpectQuery(secondPage).WithArgs("customer", "USD", 202, from, sqlmock.AnyArg(), 2).
+		WillReturnRows(mockOrderRows(0))
+
+	page, err = ps.ListOrders(context.Background(), query)
+	if err != nil {
+		t.Fatalf("expected success, got error: %v", err)
+	}
+	if len(page.Orders) != 0 || page.NextCursor != "" {
+		t.Fatalf("expected an empty last page, got %d orders, cursor %q", len(page.Orders), page.NextCursor)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestPostgresStorer_ListOrders_SortByAmount(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open mock db: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	ps := postgres.NewStorage(sqlx.NewDb(db, "postgres"), configs.Database{}, nil)
+
+	mock.ExpectQuery(regexp.QuoteMeta("ORDER BY payments.amount ASC, payments.order_id ASC\n    LIMIT 2")).
+		WillReturnRows(mockOrderRows(2))
+	mock.ExpectQuery("FROM items").WillReturnRows(mockItemRows([]int{1}, 1))
+
+	page, err := ps.ListOrders(context.Background(), models.OrderQuery{Sort: models.SortAmountAsc, Limit: 1})
+	if err != nil {
+		t.Fatalf("expected success, got error: %v", err)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta("WHERE (payments.amount, payments.order_id) > ($1, $2)")).
+		WithArgs(float64(100), 1).WillReturnRows(mockOrderRows(0))
+
+	_, err = ps.ListOrders(context.Background(), models.OrderQuery{Sort: models.SortAmountAsc, Limit: 1, Cursor: page.NextCursor})
+	if err != nil {
+		t.Fatalf("expected success, got error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestPostgresStorer_ListOrders_InvalidArgument(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open mock db: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	ps := postgres.NewStorage(sqlx.NewDb(db, "postgres"), configs.Database{}, nil)
+
+	// a valid cursor issued for another sort order
+	mock.ExpectQuery("FROM orders").WillReturnRows(mockOrderRows(2))
+	mock.ExpectQuery("FROM items").WillReturnRows(mockItemRows([]int{1}, 1))
+	page, err := ps.ListOrders(context.Background(), models.OrderQuery{Sort: models.SortDateAsc, Limit: 1})
+	if err != nil {
+		t.Fatalf("expected success, got error: %v", err)
+	}
+
+	for name, query := range map[string]models.OrderQuery{
+		"garbage cursor":     {Limit: 10, Cursor: "not a cursor"},
+		"foreign sort":       {Limit: 10, Cursor: page.NextCursor, Sort: models.SortAmountDesc},
+		"unknown sort":       {Limit: 10, Sort: "random"},
+		"non-positive limit": {Limit: 0},
+	} {
+		if _, err := ps.ListOrders(context.Background(), query); !errors.Is(err, errs.ErrInvalidArgument) {
+			t.Errorf("%s: expected ErrInvalidArgument, got %v", name, err)
+		}
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unfulfilled expectations: %v", err)
+	}
+}
@@ -0,0 +1,145 @@
+package postgres_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/configs"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/models"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository/errs"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository/postgres"
+	"github.com/jmoiron/sqlx"
+)
+
+func TestPostgresStorer_SaveOrder_OutboxErrorRollsBack(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	ps := postgres.NewStorage(sqlx.NewDb(db, "postgres"), configs.Database{}, nil)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO orders").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectExec("INSERT INTO deliveries").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO payments").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO items").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO outbox").WillReturnError(fmt.Errorf("outbox insert failed"))
+	mock.ExpectRollback()
+
+	order := &models.Order{OrderUID: "uid1", Items: []models.Item{{ChrtID: 1}}}
+	if err := ps.SaveOrder(context.Background(), order); err == nil {
+		t.Fatal("expected outbox insert error")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestPostgresStorer_ClaimOutbox(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	ps := postgres.NewStorage(sqlx.NewDb(db, "postgres"), configs.Database{}, nil)
+	createdAt := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery(regexp.QuoteMeta("FOR UPDATE SKIP LOCKED")).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), 10).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "event_type", "event_key", "payload", "attempts", "created_at"}).
+			AddRow(2, models.EventOrderAccepted, "uid2", []byte(`{"order_uid":"uid2"}`), 1, createdAt).
+			AddRow(1, models.EventOrderAccepted, "uid1", []byte(`{"order_uid":"uid1"}`), 0, createdAt))
+
+	events, err := ps.ClaimOutbox(context.Background(), 10, time.Minute)
+	if err != nil {
+		t.Fatalf("ClaimOutbox failed: %v", err)
+	}
+	if len(events) != 2 || events[0].ID != 1 || events[1].ID != 2 {
+		t.Fatalf("expected events ordered by id, got %+v", events)
+	}
+	if events[1].Key != "uid2" || events[1].Attempts != 1 || string(events[1].Payload) != `{"order_uid":"uid2"}` {
+		t.Fatalf("unexpected event: %+v", events[1])
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestPostgresStorer_MarkOutboxSent(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	ps := postgres.NewStorage(sqlx.NewDb(db, "postgres"), configs.Database{}, nil)
+
+	mock.ExpectExec("UPDATE outbox SET sent_at").WithArgs(int64(1), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE outbox SET sent_at").WithArgs(int64(2), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	if err := ps.MarkOutboxSent(context.Background(), 1); err != nil {
+		t.Fatalf("MarkOutboxSent failed: %v", err)
+	}
+	if err := ps.MarkOutboxSent(context.Background(), 2); !errors.Is(err, errs.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound for a missing event, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestPostgresStorer_MarkOutboxFailed(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	ps := postgres.NewStorage(sqlx.NewDb(db, "postgres"), configs.Database{}, nil)
+	retryAt := time.Date(2025, 6, 1, 12, 0, 30, 0, time.UTC)
+
+	mock.ExpectExec(regexp.QuoteMeta("SET attempts = attempts + 1")).WithArgs(int64(1), retryAt, "broker down").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	if err := ps.MarkOutboxFailed(context.Background(), 1, retryAt, "broker down"); err != nil {
+		t.Fatalf("MarkOutboxFailed failed: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestPostgresStorer_PurgeOutbox(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	ps := postgres.NewStorage(sqlx.NewDb(db, "postgres"), configs.Database{}, nil)
+	sentBefore := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	mock.ExpectExec("DELETE FROM outbox WHERE sent_at IS NOT NULL AND sent_at < \\$1").WithArgs(sentBefore).
+		WillReturnResult(sqlmock.NewResult(0, 5))
+
+	purged, err := ps.PurgeOutbox(context.Background(), sentBefore)
+	if err != nil {
+		t.Fatalf("PurgeOutbox failed: %v", err)
+	}
+	if purged != 5 {
+		t.Fatalf("expected 5 purged events, got %d", purged)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unfulfilled expectations: %v", err)
+	}
+}
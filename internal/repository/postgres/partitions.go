@@ -0,0 +1,269 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/models"
+	"github.com/lib/pq"
+)
+
+// archiveBatchSize is the number of orders read at a time while a partition is archived.
+const archiveBatchSize = 500
+
+// partitionedTables are the order tables partitioned by order date, referencing tables first.
+var partitionedTables = []string{"item_status_history", "items", "payments", "deliveries", "orders"}
+
+/*
+EnsurePartitions creates the monthly partitions of the order tables for every month from the month of from
+up to and including the month of to, unless they exist. Times are taken in UTC.
+
+A month whose orders are already in the default partition can't get a partition of its own;
+Postgres reports that as an error.
+*/
+func (s *Storage) EnsurePartitions(ctx context.Context, from, to time.Time) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	for month := monthOf(from); !month.After(monthOf(to)); month = month.AddDate(0, 1, 0) {
+		if _, err := s.db.ExecContext(ctx, `SELECT create_order_partitions($1)`, month); err != nil {
+			return fmt.Errorf("failed to create partitions for %s: %w", month.Format("2006-01"), mapError(ctx, err))
+		}
+	}
+	return nil
+}
+
+// monthOf returns the first instant of the month of t in UTC.
+func monthOf(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// OrderPartitions returns the partitions of the order tables, oldest month first and the default partition last.
+// Partitions that were not created by the service (see create_order_partitions) are not reported.
+func (s *Storage) OrderPartitions(ctx context.Context) ([]models.Partition, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	rows, err := s.db.QueryContext(ctx, `SELECT child.relname
+        FROM pg_inherits
+        JOIN pg_class child ON child.oid = pg_inherits.inhrelid
+        WHERE pg_inherits.inhparent = 'orders'::regclass
+        ORDER BY child.relname`)
+	if err != nil {
+		return nil, mapError(ctx, err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var partitions []models.Partition
+	var hasDefault bool
+	for rows.Next() {
+		var table string
+		if err := rows.Scan(&table); err != nil {
+			return nil, mapError(ctx, err)
+		}
+		name := strings.TrimPrefix(table, "orders_")
+		if name == "default" {
+			hasDefault = true
+			continue
+		}
+		month, err := time.Parse("p200601", name)
+		if err != nil {
+			continue
+		}
+		partitions = append(partitions, models.Partition{Name: name, From: month, To: month.AddDate(0, 1, 0)})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, mapError(ctx, err)
+	}
+	if hasDefault {
+		partitions = append(partitions, models.Partition{Name: "default", Default: true})
+	}
+	return partitions, nil
+}
+
+/*
+ArchivePartition hands every order of a partition dated before the given time over to write, and then removes them.
+
+A monthly partition is dropped as a whole, so before must not be earlier than its end.
+The default partition only loses the orders dated before the given time.
+
+The partition is locked against changes for the whole time, so no order can be added to it or changed
+after it was written: status updates of its orders wait and then find no order. Once every order is written,
+seal is called, and the orders are removed only if it succeeds; seal is where the archive is made durable.
+Dropping the partition briefly locks the order tables as a whole, right before the transaction commits.
+
+Archiving a month may take a while, so the default query timeout is not applied: ctx alone controls it.
+Returns the number of archived orders.
+*/
+func (s *Storage) ArchivePartition(ctx context.Context, partition models.Partition, before time.Time,
+	write func(order models.OrderSnapshot) error, seal func() error) (int, error) {
+	if !partition.Default && partition.To.After(before) {
+		return 0, fmt.Errorf("partition %s holds orders dated up to %s, which are not to be archived yet", partition.Name, partition.To.Format(time.DateOnly))
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to start transaction: %w", mapError(ctx, err))
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	tables := make([]string, len(partitionedTables))
+	for i, table := range partitionedTables {
+		tables[i] = pq.QuoteIdentifier(table + "_" + partition.Name)
+	}
+	if _, err := tx.ExecContext(ctx, "LOCK TABLE "+strings.Join(tables, ", ")+" IN SHARE MODE"); err != nil {
+		return 0, fmt.Errorf("failed to lock partition %s: %w", partition.Name, mapError(ctx, err))
+	}
+
+	var archived int
+	var afterID int64
+	for {
+		orders, err := querySnapshots(ctx, tx, ordersSelect+`
+    WHERE orders.tableoid = $1::regclass AND orders.date_created < $2 AND orders.id > $3
+    ORDER BY orders.id
+    LIMIT $4`, "orders_"+partition.Name, before, afterID, archiveBatchSize)
+		if err != nil {
+			return archived, fmt.Errorf("failed to read orders of partition %s: %w", partition.Name, mapError(ctx, err))
+		}
+		for _, order := range orders {
+			if err := write(order.OrderSnapshot); err != nil {
+				return archived, err
+			}
+			archived++
+			afterID = order.id
+		}
+		if len(orders) < archiveBatchSize {
+			break
+		}
+	}
+	if err := seal(); err != nil {
+		return archived, err
+	}
+
+	if partition.Default {
+		// cascades to the other tables, and the order keys are released by trigger
+		if _, err := tx.ExecContext(ctx, `DELETE FROM orders_default WHERE date_created < $1`, before); err != nil {
+			return archived, fmt.Errorf("failed to delete archived orders: %w", mapError(ctx, err))
+		}
+	} else {
+		if err := dropPartition(ctx, tx, partition); err != nil {
+			return archived, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return archived, fmt.Errorf("transaction commit failed: %w", mapError(ctx, err))
+	}
+	return archived, nil
+}
+
+// dropPartition releases the order keys of a monthly partition and drops it from every order table.
+// Partitions are detached before they are dropped, which is how Postgres lets go of a referenced partition.
+func dropPartition(ctx context.Context, tx *sql.Tx, partition models.Partition) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM order_keys WHERE order_date >= $1 AND order_date < $2`, partition.From, partition.To); err != nil {
+		return fmt.Errorf("failed to release order keys of partition %s: %w", partition.Name, mapError(ctx, err))
+	}
+	for _, table := range partitionedTables {
+		name := pq.QuoteIdentifier(table + "_" + partition.Name)
+		if _, err := tx.ExecContext(ctx, "ALTER TABLE "+table+" DETACH PARTITION "+name); err != nil {
+			return fmt.Errorf("failed to detach %s: %w", name, mapError(ctx, err))
+		}
+		if _, err := tx.ExecContext(ctx, "DROP TABLE "+name); err != nil {
+			return fmt.Errorf("failed to drop %s: %w", name, mapError(ctx, err))
+		}
+	}
+	return nil
+}
+
+// snapshot is an OrderSnapshot along with the database ID of the order.
+type snapshot struct {
+	models.OrderSnapshot
+	id int64
+}
+
+// querySnapshots runs a query built on top of ordersSelect and returns the orders with their items,
+// status history, checksums, ingest records and amendments. Deliveries are left encrypted, along with their key IDs.
+func querySnapshots(ctx context.Context, db querier, query string, args ...any) ([]snapshot, error) {
+	orders, orderIds, keyIDs, err := querySealedOrders(ctx, db, query, args...)
+	if err != nil || len(orders) == 0 {
+		return nil, err
+	}
+	if err := queryItemsBulk(ctx, db, orders, orderIds); err != nil {
+		return nil, err
+	}
+	orderUIDs := make([]string, len(orders))
+	for i, order := range orders {
+		orderUIDs[i] = order.OrderUID
+	}
+	ingest, err := queryIngestRecords(ctx, db, orderUIDs)
+	if err != nil {
+		return nil, err
+	}
+	amendments, err := queryAmendments(ctx, db, orderUIDs)
+	if err != nil {
+		return nil, err
+	}
+	snapshots := make([]snapshot, len(orders))
+	byId := make(map[int64]*snapshot, len(orders))
+	for i, order := range orders {
+		snapshots[i] = snapshot{OrderSnapshot: models.OrderSnapshot{
+			Order:      order,
+			KeyID:      keyIDs[i].String,
+			History:    []models.StatusEvent{},
+			Ingest:     ingest[order.OrderUID],
+			Amendments: amendments[order.OrderUID],
+		}, id: orderIds[i]}
+		byId[orderIds[i]] = &snapshots[i]
+	}
+
+	rows, err := db.QueryContext(ctx, `SELECT id, checksum FROM orders WHERE id = ANY($1)`, pq.Array(orderIds))
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+	for rows.Next() {
+		var orderId int64
+		var checksum sql.NullString
+		if err := rows.Scan(&orderId, &checksum); err != nil {
+			return nil, err
+		}
+		byId[orderId].Checksum = checksum.String
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	history, err := db.QueryContext(ctx, `SELECT
+        item_status_history.order_id,
+        items.chrt_id,
+        items.rid,
+        item_status_history.previous_status,
+        item_status_history.status,
+        item_status_history.changed_at,
+        item_status_history.recorded_at
+    FROM item_status_history
+    JOIN items ON items.id = item_status_history.item_id
+    WHERE item_status_history.order_id = ANY($1)
+    ORDER BY item_status_history.order_id, item_status_history.changed_at, item_status_history.id`, pq.Array(orderIds))
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = history.Close() }()
+	for history.Next() {
+		var orderId int64
+		var event models.StatusEvent
+		if err := history.Scan(
+			&orderId,
+			&event.ChrtID,
+			&event.Rid,
+			&event.PreviousStatus,
+			&event.Status,
+			&event.ChangedAt,
+			&event.RecordedAt,
+		); err != nil {
+			return nil, err
+		}
+		byId[orderId].History = append(byId[orderId].History, event)
+	}
+	return snapshots, history.Err()
+}
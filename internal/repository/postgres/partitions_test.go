@@ -0,0 +1,178 @@
+package postgres_test
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/configs"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/models"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository/postgres"
+)
+
+func TestPostgresStorer_EnsurePartitions(t *testing.T) {
+	db, mock := newMockDB(t)
+	ps := postgres.NewStorage(db, configs.Database{}, nil)
+
+	for _, month := range []time.Time{
+		time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC),
+	} {
+		mock.ExpectExec(regexp.QuoteMeta("SELECT create_order_partitions($1)")).WithArgs(month).WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+	err := ps.EnsurePartitions(context.Background(), time.Date(2024, 12, 31, 23, 0, 0, 0, time.UTC), time.Date(2025, 2, 3, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("EnsurePartitions failed: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestPostgresStorer_OrderPartitions(t *testing.T) {
+	db, mock := newMockDB(t)
+	ps := postgres.NewStorage(db, configs.Database{}, nil)
+
+	mock.ExpectQuery("FROM pg_inherits").WillReturnRows(sqlmock.NewRows([]string{"relname"}).
+		AddRow("orders_default").AddRow("orders_old").AddRow("orders_p202501").AddRow("orders_p202502"))
+
+	partitions, err := ps.OrderPartitions(context.Background())
+	if err != nil {
+		t.Fatalf("OrderPartitions failed: %v", err)
+	}
+	expected := []models.Partition{
+		{Name: "p202501", From: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), To: time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{Name: "p202502", From: time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC), To: time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{Name: "default", Default: true},
+	}
+	if len(partitions) != len(expected) {
+		t.Fatalf("expected %d partitions, got %+v", len(expected), partitions)
+	}
+	for i := range expected {
+		if partitions[i].Name != expected[i].Name || !partitions[i].From.Equal(expected[i].From) ||
+			!partitions[i].To.Equal(expected[i].To) || partitions[i].Default != expected[i].Default {
+			t.Errorf("partition %d: expected %+v, got %+v", i, expected[i], partitions[i])
+		}
+	}
+}
+
+var january = models.Partition{
+	Name: "p202501",
+	From: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+	To:   time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC),
+}
+
+func expectArchivedOrders(mock sqlmock.Sqlmock, changedAt time.Time) {
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`LOCK TABLE "item_status_history_p202501", "items_p202501", "payments_p202501", "deliveries_p202501", "orders_p202501" IN SHARE MODE`)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("orders.tableoid = \\$1::regclass").WithArgs("orders_p202501", sqlmock.AnyArg(), int64(0), 500).
+		WillReturnRows(mockOrderRows(2))
+	mock.ExpectQuery("FROM items WHERE order_id").WillReturnRows(mockItemRows([]int{1, 2}, 1))
+	mock.ExpectQuery("FROM order_ingest_log").WillReturnRows(sqlmock.NewRows(ingestColumns).
+		AddRow("uid1", "orders", 0, 7, "uid1", nil, changedAt, []byte(`{}`), nil, nil))
+	mock.ExpectQuery("FROM order_amendments").WillReturnRows(sqlmock.NewRows(amendmentColumns).
+		AddRow("uid2", 2, []byte(`{"locale":"ru"}`), []byte(`{}`), changedAt, nil))
+	mock.ExpectQuery("SELECT id, checksum FROM orders WHERE id = ANY").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "checksum"}).AddRow(1, "sum").AddRow(2, nil))
+	mock.ExpectQuery("FROM item_status_history").
+		WillReturnRows(sqlmock.NewRows([]string{"order_id", "chrt_id", "rid", "previous_status", "status", "changed_at", "recorded_at"}).
+			AddRow(2, 0, "rid", 0, 202, changedAt, changedAt))
+}
+
+func TestPostgresStorer_ArchivePartition(t *testing.T) {
+	db, mock := newMockDB(t)
+	ps := postgres.NewStorage(db, configs.Database{}, nil)
+	changedAt := time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	expectArchivedOrders(mock, changedAt)
+	mock.ExpectExec("DELETE FROM order_keys").WithArgs(january.From, january.To).WillReturnResult(sqlmock.NewResult(0, 2))
+	for _, table := range []string{"item_status_history", "items", "payments", "deliveries", "orders"} {
+		mock.ExpectExec(regexp.QuoteMeta("ALTER TABLE " + table + ` DETACH PARTITION "` + table + `_p202501"`)).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(regexp.QuoteMeta(`DROP TABLE "` + table + `_p202501"`)).WillReturnResult(sqlmock.NewResult(0, 0))
+	}
+	mock.ExpectCommit()
+
+	var written []models.OrderSnapshot
+	sealed := false
+	archived, err := ps.ArchivePartition(context.Background(), january, january.To,
+		func(order models.OrderSnapshot) error { written = append(written, order); return nil },
+		func() error { sealed = true; return nil })
+	if err != nil {
+		t.Fatalf("ArchivePartition failed: %v", err)
+	}
+	if archived != 2 || len(written) != 2 || !sealed {
+		t.Fatalf("expected 2 orders to be written and sealed, got %d written, sealed %v", len(written), sealed)
+	}
+	if written[0].Checksum != "sum" || len(written[0].History) != 0 || len(written[0].Order.Items) != 1 ||
+		written[0].Ingest == nil || written[0].Ingest.Offset != 7 {
+		t.Errorf("unexpected first order: %+v", written[0])
+	}
+	if written[1].Checksum != "" || len(written[1].History) != 1 || written[1].History[0].Status != 202 || written[1].Ingest != nil ||
+		len(written[1].Amendments) != 1 || written[0].Amendments != nil {
+		t.Errorf("unexpected second order: %+v", written[1])
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestPostgresStorer_ArchivePartition_SealFailureKeepsOrders(t *testing.T) {
+	db, mock := newMockDB(t)
+	ps := postgres.NewStorage(db, configs.Database{}, nil)
+
+	expectArchivedOrders(mock, time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC))
+	mock.ExpectRollback()
+
+	sealErr := errors.New("disk full")
+	_, err := ps.ArchivePartition(context.Background(), january, january.To,
+		func(models.OrderSnapshot) error { return nil },
+		func() error { return sealErr })
+	if !errors.Is(err, sealErr) {
+		t.Fatalf("expected the seal error, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestPostgresStorer_ArchivePartition_Default(t *testing.T) {
+	db, mock := newMockDB(t)
+	ps := postgres.NewStorage(db, configs.Database{}, nil)
+	before := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("LOCK TABLE").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("orders.tableoid").WithArgs("orders_default", before, int64(0), 500).WillReturnRows(sqlmock.NewRows(mockOrderColumns))
+	mock.ExpectExec("DELETE FROM orders_default WHERE date_created < \\$1").WithArgs(before).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	archived, err := ps.ArchivePartition(context.Background(), models.Partition{Name: "default", Default: true}, before,
+		func(models.OrderSnapshot) error { return nil },
+		func() error { return nil })
+	if err != nil || archived != 0 {
+		t.Fatalf("expected an empty default partition to be archived, got %d, %v", archived, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestPostgresStorer_ArchivePartition_NotOldEnough(t *testing.T) {
+	db, mock := newMockDB(t)
+	ps := postgres.NewStorage(db, configs.Database{}, nil)
+
+	_, err := ps.ArchivePartition(context.Background(), january, january.To.Add(-time.Hour),
+		func(models.OrderSnapshot) error { return nil },
+		func() error { return nil })
+	if err == nil {
+		t.Fatal("expected a partition with orders that are not to be archived yet to be refused")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
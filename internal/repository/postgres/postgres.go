@@ -1,9 +1,20 @@
 // Package postgres provides a PostgreSQL storage implementation for the Storage interface.
 // It wraps the database connection and logger, and offers methods for managing orders, deliveries,
 // payments, and items in a transactional and safe way. It also includes connection management utilities.
+//
+// Order reads can be served by read replicas, see CheckReplicas.
+//
+// Driver errors are mapped to the classes defined in the repository/errs package.
 package postgres
 
 import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/configs"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/pkg/fieldcrypt"
 	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/pkg/logger"
 	"github.com/jmoiron/sqlx"
 	_ "github.com/lib/pq"
@@ -11,22 +22,49 @@ import (
 
 // Storage wraps the database connection and logger for interacting with PostgreSQL
 type Storage struct {
-	db     *sqlx.DB
-	logger logger.Logger
+	db           *sqlx.DB
+	logger       logger.Logger
+	queryTimeout time.Duration       // applied to operations whose context has no deadline
+	replicas     *replicaSet         // read replicas of db, if any
+	keys         *fieldcrypt.Keyring // encrypts the personal data of deliveries; nil stores it as plaintext
+}
+
+// querier runs queries on a connection pool (*sqlx.DB) or within a transaction (*sql.Tx),
+// so that read helpers can serve both.
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
 }
 
-// NewStorage creates a new Storage instance with the provided database connection and logger
-func NewStorage(db *sqlx.DB, logger logger.Logger) *Storage {
-	return &Storage{db: db, logger: logger}
+// NewStorage creates a new Storage instance with the provided database connection, configuration and logger.
+// Order reads are spread over the given replicas once they have passed a health check.
+// The personal data of deliveries is encrypted with config.Keyring, if it is set.
+func NewStorage(db *sqlx.DB, config configs.Database, logger logger.Logger, replicas ...Replica) *Storage {
+	return &Storage{db: db, logger: logger, queryTimeout: config.QueryTimeout, replicas: newReplicaSet(replicas, config.ReplicaMaxLag),
+		keys: config.Keyring}
+}
+
+// withTimeout applies the default query timeout to ctx unless the caller has already set a deadline
+func (s *Storage) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok || s.queryTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, s.queryTimeout)
 }
 
 // Ping checks the database connection to ensure it is alive
-func (s *Storage) Ping() error {
-	return s.db.Ping()
+func (s *Storage) Ping(ctx context.Context) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	return mapError(ctx, s.db.PingContext(ctx))
 }
 
-// Close safely closes the database connection and logs the result
+// Close safely closes the database connection and the replica connections, and logs the result
 func (s *Storage) Close() {
+	for _, replica := range s.replicas.replicas {
+		if err := replica.DB.Close(); err != nil {
+			s.logger.LogError(fmt.Sprintf("postgres — failed to close replica %s properly", replica.Name), err, "layer", "repository.postgres")
+		}
+	}
 	if err := s.db.Close(); err != nil {
 		s.logger.LogError("postgres — failed to close properly", err, "layer", "repository.postgres")
 	} else {
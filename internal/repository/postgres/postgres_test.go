@@ -1,10 +1,14 @@
 package postgres_test
 
 import (
+	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/configs"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository/errs"
 	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository/postgres"
 	mock_logger "github.com/Pur1st2EpicONE/WBTECH-sample-microservice/pkg/logger/mocks"
 	"github.com/golang/mock/gomock"
@@ -20,16 +24,16 @@ func TestPostgresStorer_Ping(t *testing.T) {
 
 	xdb := sqlx.NewDb(db, "sqlmock")
 	logger := mock_logger.NewMockLogger(gomock.NewController(t))
-	storer := postgres.NewStorage(xdb, logger)
+	storer := postgres.NewStorage(xdb, configs.Database{}, logger)
 
 	mock.ExpectPing()
-	if err := storer.Ping(); err != nil {
+	if err := storer.Ping(context.Background()); err != nil {
 		t.Errorf("expected no error, got %v", err)
 	}
 
 	pingErr := errors.New("ping failed")
 	mock.ExpectPing().WillReturnError(pingErr)
-	if err := storer.Ping(); !errors.Is(err, pingErr) {
+	if err := storer.Ping(context.Background()); !errors.Is(err, pingErr) {
 		t.Errorf("expected pingErr, got %v", err)
 	}
 
@@ -52,7 +56,7 @@ func TestPostgresStorer_Close_Success(t *testing.T) {
 	mock.ExpectClose()
 
 	xdb := sqlx.NewDb(db, "sqlmock")
-	storer := postgres.NewStorage(xdb, mockLogger)
+	storer := postgres.NewStorage(xdb, configs.Database{}, mockLogger)
 
 	mockLogger.EXPECT().LogInfo("postgres — stopped", "layer", "repository.postgres").Times(1)
 
@@ -62,3 +66,46 @@ func TestPostgresStorer_Close_Success(t *testing.T) {
 		t.Errorf("unfulfilled db expectations: %v", err)
 	}
 }
+
+func TestPostgresStorer_DefaultQueryTimeout(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = db.Close() }()
+
+	logger := mock_logger.NewMockLogger(gomock.NewController(t))
+	storer := postgres.NewStorage(sqlx.NewDb(db, "postgres"), configs.Database{QueryTimeout: 50 * time.Millisecond}, logger)
+
+	mock.ExpectQuery("SELECT").WillDelayFor(time.Second).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	_, err = storer.GetOrder(context.Background(), "aboba")
+	if !errors.Is(err, errs.ErrTimeout) {
+		t.Fatalf("expected ErrTimeout, got %v", err)
+	}
+}
+
+func TestPostgresStorer_CallerCancellation(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = db.Close() }()
+
+	logger := mock_logger.NewMockLogger(gomock.NewController(t))
+	storer := postgres.NewStorage(sqlx.NewDb(db, "postgres"), configs.Database{QueryTimeout: time.Minute}, logger)
+
+	mock.ExpectQuery("SELECT").WillDelayFor(time.Second).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start := time.Now()
+	_, err = storer.GetOrder(ctx, "aboba")
+	if err == nil {
+		t.Fatal("expected cancellation error, got nil")
+	}
+	if time.Since(start) > 500*time.Millisecond {
+		t.Fatalf("query was not aborted by caller cancellation")
+	}
+}
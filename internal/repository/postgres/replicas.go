@@ -0,0 +1,140 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/models"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository/errs"
+	"github.com/jmoiron/sqlx"
+)
+
+// Replica is a read replica of the primary database.
+type Replica struct {
+	Name string // host:port of the replica, used in logs and health reports
+	DB   *sqlx.DB
+}
+
+// replica is a Replica along with its place in the read rotation.
+type replica struct {
+	Replica
+	healthy atomic.Bool
+}
+
+/*
+replicaSet spreads reads over healthy replicas round-robin.
+
+A replica joins the rotation after its first successful health check and leaves it
+as soon as a query finds it unreachable or a health check finds it lagging more than maxLag
+behind the primary. A replica that has left the rotation is brought back by the next passing check.
+*/
+type replicaSet struct {
+	replicas []*replica
+	next     atomic.Uint64
+	maxLag   time.Duration
+}
+
+// newReplicaSet returns a set of replicas that are all out of rotation until they are checked.
+func newReplicaSet(replicas []Replica, maxLag time.Duration) *replicaSet {
+	set := &replicaSet{maxLag: maxLag}
+	for _, r := range replicas {
+		set.replicas = append(set.replicas, &replica{Replica: r})
+	}
+	return set
+}
+
+// pick returns the next healthy replica, or nil if there is none.
+func (r *replicaSet) pick() *replica {
+	n := uint64(len(r.replicas))
+	if n == 0 {
+		return nil
+	}
+	start := r.next.Add(1) - 1
+	for i := range n {
+		if candidate := r.replicas[(start+i)%n]; candidate.healthy.Load() {
+			return candidate
+		}
+	}
+	return nil
+}
+
+/*
+read runs a read-only query on a healthy replica, or on the primary if there is none.
+
+The query is repeated on the primary if the replica turns out to be unreachable, which also takes
+the replica out of rotation, and if the replica has no such record: it may have been saved
+on the primary too recently to be replicated yet.
+*/
+func read[T any](ctx context.Context, s *Storage, query func(db *sqlx.DB) (T, error)) (T, error) {
+	replica := s.replicas.pick()
+	if replica == nil {
+		return query(s.db)
+	}
+	result, err := query(replica.DB)
+	if err == nil || ctx.Err() != nil {
+		return result, err
+	}
+	switch {
+	case errors.Is(err, errs.ErrUnavailable):
+		if replica.healthy.Swap(false) {
+			s.logger.LogError(fmt.Sprintf("postgres — replica %s is unreachable, taken out of rotation", replica.Name), err, "layer", "repository.postgres")
+		}
+	case !errors.Is(err, errs.ErrNotFound):
+		return result, err
+	}
+	return query(s.db)
+}
+
+// replicaLagQuery returns the replication lag in seconds. A replica that has replayed
+// everything it received is not lagging, even if the primary has been idle for a while.
+const replicaLagQuery = `SELECT COALESCE(CASE
+        WHEN NOT pg_is_in_recovery() OR pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+        ELSE EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp())
+    END, 0)::float8`
+
+/*
+CheckReplicas checks every read replica and updates the read rotation accordingly.
+
+A replica serves reads if it is reachable and its replication lag does not exceed
+the configured maximum (database.replica_max_lag; zero means no limit).
+Reads fall back to the primary while no replica is healthy. Returns nil if there are no replicas.
+*/
+func (s *Storage) CheckReplicas(ctx context.Context) []models.ReplicaStatus {
+	if len(s.replicas.replicas) == 0 {
+		return nil
+	}
+	statuses := make([]models.ReplicaStatus, len(s.replicas.replicas))
+	for i, replica := range s.replicas.replicas {
+		status := s.checkReplica(ctx, replica)
+		if replica.healthy.Swap(status.Healthy) != status.Healthy {
+			switch {
+			case status.Healthy:
+				s.logger.LogInfo(fmt.Sprintf("postgres — replica %s is back in rotation", replica.Name), "lag", status.Lag.String(), "layer", "repository.postgres")
+			case status.Err != nil:
+				s.logger.LogError(fmt.Sprintf("postgres — replica %s is unreachable, taken out of rotation", replica.Name), status.Err, "layer", "repository.postgres")
+			default:
+				s.logger.LogInfo(fmt.Sprintf("postgres — replica %s lags too far behind, taken out of rotation", replica.Name), "lag", status.Lag.String(), "layer", "repository.postgres")
+			}
+		}
+		statuses[i] = status
+	}
+	return statuses
+}
+
+// checkReplica measures the replication lag of a single replica.
+func (s *Storage) checkReplica(ctx context.Context, replica *replica) models.ReplicaStatus {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	status := models.ReplicaStatus{Name: replica.Name}
+	var lagSeconds float64
+	if err := replica.DB.QueryRowContext(ctx, replicaLagQuery).Scan(&lagSeconds); err != nil {
+		status.Err = mapError(ctx, err)
+		return status
+	}
+	status.Lag = time.Duration(lagSeconds * float64(time.Second))
+	status.Healthy = s.replicas.maxLag <= 0 || status.Lag <= s.replicas.maxLag
+	return status
+}
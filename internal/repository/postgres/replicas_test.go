@@ -0,0 +1,201 @@
+package postgres_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/configs"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository/errs"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository/postgres"
+	mock_logger "github.com/Pur1st2EpicONE/WBTECH-sample-microservice/pkg/logger/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/jmoiron/sqlx"
+)
+
+// newMockDB returns a sqlmock database wrapped for the postgres driver, closed at the end of the test.
+func newMockDB(t *testing.T) (*sqlx.DB, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open mock db: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	return sqlx.NewDb(db, "postgres"), mock
+}
+
+func lagRows(seconds float64) *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"lag"}).AddRow(seconds)
+}
+
+// quietLogger returns a logger that accepts the replica rotation logs.
+func quietLogger(t *testing.T) *mock_logger.MockLogger {
+	logger := mock_logger.NewMockLogger(gomock.NewController(t))
+	logger.EXPECT().LogInfo(gomock.Any(), gomock.Any()).AnyTimes()
+	return logger
+}
+
+func TestPostgresStorer_Replicas_RoundRobin(t *testing.T) {
+	primary, primaryMock := newMockDB(t)
+	first, firstMock := newMockDB(t)
+	second, secondMock := newMockDB(t)
+	ps := postgres.NewStorage(primary, configs.Database{ReplicaMaxLag: 10 * time.Second}, quietLogger(t),
+		postgres.Replica{Name: "replica-1:5432", DB: first}, postgres.Replica{Name: "replica-2:5432", DB: second})
+
+	firstMock.ExpectQuery("pg_last_xact_replay_timestamp").WillReturnRows(lagRows(0))
+	secondMock.ExpectQuery("pg_last_xact_replay_timestamp").WillReturnRows(lagRows(2))
+	statuses := ps.CheckReplicas(context.Background())
+	if len(statuses) != 2 || !statuses[0].Healthy || !statuses[1].Healthy || statuses[1].Lag != 2*time.Second {
+		t.Fatalf("expected both replicas healthy, got %+v", statuses)
+	}
+
+	firstMock.ExpectQuery("FROM orders").WillReturnRows(mockOrderRows(0))
+	secondMock.ExpectQuery("FROM orders").WillReturnRows(mockOrderRows(0))
+	firstMock.ExpectQuery("FROM orders").WillReturnRows(mockOrderRows(0))
+	for range 3 {
+		if _, err := ps.GetOrders(context.Background()); err != nil {
+			t.Fatalf("GetOrders failed: %v", err)
+		}
+	}
+
+	for _, mock := range []sqlmock.Sqlmock{primaryMock, firstMock, secondMock} {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unfulfilled expectations: %v", err)
+		}
+	}
+}
+
+func TestPostgresStorer_Replicas_UncheckedServeNothing(t *testing.T) {
+	primary, primaryMock := newMockDB(t)
+	replica, replicaMock := newMockDB(t)
+	ps := postgres.NewStorage(primary, configs.Database{}, nil, postgres.Replica{Name: "replica:5432", DB: replica})
+
+	primaryMock.ExpectQuery("FROM orders").WillReturnRows(mockOrderRows(0))
+	if _, err := ps.GetOrders(context.Background()); err != nil {
+		t.Fatalf("GetOrders failed: %v", err)
+	}
+
+	if err := primaryMock.ExpectationsWereMet(); err != nil {
+		t.Errorf("expected the primary to serve reads before replicas are checked: %v", err)
+	}
+	if err := replicaMock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unexpected replica queries: %v", err)
+	}
+}
+
+func TestPostgresStorer_Replicas_FallbackWhenUnreachable(t *testing.T) {
+	controller := gomock.NewController(t)
+	logger := mock_logger.NewMockLogger(controller)
+	primary, primaryMock := newMockDB(t)
+	replica, replicaMock := newMockDB(t)
+	ps := postgres.NewStorage(primary, configs.Database{}, logger, postgres.Replica{Name: "replica:5432", DB: replica})
+
+	replicaMock.ExpectQuery("pg_last_xact_replay_timestamp").WillReturnRows(lagRows(0))
+	logger.EXPECT().LogInfo(gomock.Any(), gomock.Any())
+	ps.CheckReplicas(context.Background())
+
+	refused := &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+	replicaMock.ExpectQuery("FROM orders").WillReturnError(refused)
+	logger.EXPECT().LogError("postgres — replica replica:5432 is unreachable, taken out of rotation", gomock.Any(), "layer", "repository.postgres")
+	primaryMock.ExpectQuery("FROM orders").WillReturnRows(mockOrderRows(0))
+	primaryMock.ExpectQuery("FROM orders").WillReturnRows(mockOrderRows(0))
+	for range 2 {
+		if _, err := ps.GetOrders(context.Background()); err != nil {
+			t.Fatalf("expected the primary to serve the read, got %v", err)
+		}
+	}
+
+	for _, mock := range []sqlmock.Sqlmock{primaryMock, replicaMock} {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unfulfilled expectations: %v", err)
+		}
+	}
+}
+
+func TestPostgresStorer_Replicas_FallbackWhenNotReplicatedYet(t *testing.T) {
+	primary, primaryMock := newMockDB(t)
+	replica, replicaMock := newMockDB(t)
+	ps := postgres.NewStorage(primary, configs.Database{}, quietLogger(t), postgres.Replica{Name: "replica:5432", DB: replica})
+
+	replicaMock.ExpectQuery("pg_last_xact_replay_timestamp").WillReturnRows(lagRows(0))
+	ps.CheckReplicas(context.Background())
+
+	replicaMock.ExpectQuery("WHERE orders.track_number").WillReturnRows(mockOrderRows(0))
+	primaryMock.ExpectQuery("WHERE orders.track_number").WillReturnRows(mockOrderRows(0))
+	if _, err := ps.GetOrderByTrackNumber(context.Background(), "WBILMTESTTRACK"); !errors.Is(err, errs.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound from the primary, got %v", err)
+	}
+
+	for _, mock := range []sqlmock.Sqlmock{primaryMock, replicaMock} {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unfulfilled expectations: %v", err)
+		}
+	}
+}
+
+func TestPostgresStorer_GetOrderFromPrimary(t *testing.T) {
+	primary, primaryMock := newMockDB(t)
+	replica, replicaMock := newMockDB(t)
+	ps := postgres.NewStorage(primary, configs.Database{}, quietLogger(t), postgres.Replica{Name: "replica:5432", DB: replica})
+
+	replicaMock.ExpectQuery("pg_last_xact_replay_timestamp").WillReturnRows(lagRows(0))
+	ps.CheckReplicas(context.Background())
+
+	primaryMock.ExpectQuery("FROM orders").WillReturnRows(mockOrderRows(0))
+	if _, err := ps.GetOrderFromPrimary(context.Background(), "b563feb7b2b84b6test"); !errors.Is(err, errs.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound from the primary, got %v", err)
+	}
+
+	for _, mock := range []sqlmock.Sqlmock{primaryMock, replicaMock} {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("expected only the primary to be queried: %v", err)
+		}
+	}
+}
+
+func TestPostgresStorer_CheckReplicas_Lag(t *testing.T) {
+	controller := gomock.NewController(t)
+	logger := mock_logger.NewMockLogger(controller)
+	primary, primaryMock := newMockDB(t)
+	replica, replicaMock := newMockDB(t)
+	ps := postgres.NewStorage(primary, configs.Database{ReplicaMaxLag: 10 * time.Second}, logger, postgres.Replica{Name: "replica:5432", DB: replica})
+
+	replicaMock.ExpectQuery("pg_last_xact_replay_timestamp").WillReturnRows(lagRows(0.5))
+	logger.EXPECT().LogInfo("postgres — replica replica:5432 is back in rotation", "lag", "500ms", "layer", "repository.postgres")
+	if statuses := ps.CheckReplicas(context.Background()); !statuses[0].Healthy {
+		t.Fatalf("expected the replica to be healthy, got %+v", statuses[0])
+	}
+
+	replicaMock.ExpectQuery("pg_last_xact_replay_timestamp").WillReturnRows(lagRows(30))
+	logger.EXPECT().LogInfo("postgres — replica replica:5432 lags too far behind, taken out of rotation", "lag", "30s", "layer", "repository.postgres")
+	statuses := ps.CheckReplicas(context.Background())
+	if statuses[0].Healthy || statuses[0].Lag != 30*time.Second || statuses[0].Err != nil {
+		t.Fatalf("expected the replica to lag 30s behind, got %+v", statuses[0])
+	}
+
+	primaryMock.ExpectQuery("FROM orders").WillReturnRows(mockOrderRows(0))
+	if _, err := ps.GetOrders(context.Background()); err != nil {
+		t.Fatalf("GetOrders failed: %v", err)
+	}
+
+	replicaMock.ExpectQuery("pg_last_xact_replay_timestamp").WillReturnError(errors.New("boom"))
+	if statuses := ps.CheckReplicas(context.Background()); statuses[0].Healthy || statuses[0].Err == nil {
+		t.Fatalf("expected the failed check to be reported, got %+v", statuses[0])
+	}
+
+	for _, mock := range []sqlmock.Sqlmock{primaryMock, replicaMock} {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unfulfilled expectations: %v", err)
+		}
+	}
+}
+
+func TestPostgresStorer_CheckReplicas_None(t *testing.T) {
+	primary, _ := newMockDB(t)
+	if statuses := postgres.NewStorage(primary, configs.Database{}, nil).CheckReplicas(context.Background()); statuses != nil {
+		t.Fatalf("expected no statuses without replicas, got %+v", statuses)
+	}
+}
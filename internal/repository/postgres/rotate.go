@@ -0,0 +1,261 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/pkg/fieldcrypt"
+)
+
+// RotationMode selects the rows RotateKeys rewrites.
+type RotationMode int
+
+const (
+	RotateOutdated RotationMode = iota // rows that are plaintext or encrypted with another than the active key
+	RotateAll                          // every row, e.g. after the blind index key has changed
+	RotateDecrypt                      // encrypted rows, which are turned back into plaintext
+)
+
+// documentTable is a table of JSON documents holding a delivery encrypted with the key_id of their row, see sealDocument.
+type documentTable struct {
+	name    string
+	key     string   // unique column the rows are paged by
+	columns []string // documents of a row
+}
+
+// sealedDocuments are the tables of documents RotateKeys rewrites after the deliveries.
+var sealedDocuments = []documentTable{
+	{name: "order_ingest_log", key: "order_uid", columns: []string{"payload"}},
+	{name: "order_amendments", key: "id", columns: []string{"patch", "previous"}},
+}
+
+/*
+RotateKeys re-encrypts the personal data of deliveries with the active key, and so the personal data
+in the ingested payloads and the amendments of orders, see sealDocument. It goes page by page
+(batchSize rows at a time, each page in a transaction of its own), so no long locks are held
+and the service can keep running meanwhile. Rows written by the service during the run
+are sealed with the active key already. Returns the number of rows rewritten.
+
+The old keys must stay in the keyring until the run has finished; rows encrypted with a key
+the keyring doesn't hold stop the run with fieldcrypt.ErrUnknownKey.
+An interrupted run leaves every row readable and can simply be started again.
+*/
+func (s *Storage) RotateKeys(ctx context.Context, batchSize int, mode RotationMode) (int, error) {
+	if s.keys == nil {
+		return 0, fmt.Errorf("database.encryption is not configured: %w", fieldcrypt.ErrUnknownKey)
+	}
+	var afterID int64
+	rewritten := 0
+	for {
+		n, lastID, err := s.rotateBatch(ctx, afterID, batchSize, mode)
+		if err != nil {
+			return rewritten, err
+		}
+		if n == 0 {
+			break
+		}
+		rewritten += n
+		afterID = lastID
+	}
+	for _, table := range sealedDocuments {
+		var after sql.NullString
+		for {
+			n, last, err := s.rotateDocumentBatch(ctx, table, after, batchSize, mode)
+			if err != nil {
+				return rewritten, err
+			}
+			if n == 0 {
+				break
+			}
+			rewritten += n
+			after = sql.NullString{String: last, Valid: true}
+		}
+	}
+	return rewritten, nil
+}
+
+// rotationFilter returns the condition that selects the rows mode rewrites, with its argument numbered next if it has one.
+func (s *Storage) rotationFilter(mode RotationMode, next int) (string, []any, error) {
+	switch mode {
+	case RotateOutdated:
+		return fmt.Sprintf(` AND key_id IS DISTINCT FROM $%d`, next), []any{s.keys.ActiveKey()}, nil
+	case RotateDecrypt:
+		return ` AND key_id IS NOT NULL`, nil, nil
+	case RotateAll:
+		return "", nil, nil
+	default:
+		return "", nil, errors.New("unknown rotation mode")
+	}
+}
+
+// rotateBatch rewrites the deliveries of a page that starts after afterID.
+// Returns the number of deliveries rewritten and the ID of the last one.
+func (s *Storage) rotateBatch(ctx context.Context, afterID int64, limit int, mode RotationMode) (int, int64, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to start transaction: %w", mapError(ctx, err))
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	filter, filterArgs, err := s.rotationFilter(mode, 3)
+	if err != nil {
+		return 0, 0, err
+	}
+	query := `SELECT id, order_date, name, phone, address, email, key_id FROM deliveries WHERE id > $1` + filter
+	rows, err := tx.QueryContext(ctx, query+` ORDER BY id LIMIT $2 FOR UPDATE`, append([]any{afterID, limit}, filterArgs...)...)
+	if err != nil {
+		return 0, 0, mapError(ctx, err)
+	}
+
+	type row struct {
+		id        int64
+		orderDate time.Time
+		sealed    sealedDelivery
+	}
+	var page []row
+	for rows.Next() {
+		var r row
+		if err := rows.Scan(&r.id, &r.orderDate, &r.sealed.Name, &r.sealed.Phone, &r.sealed.Address, &r.sealed.Email, &r.sealed.KeyID); err != nil {
+			_ = rows.Close()
+			return 0, 0, mapError(ctx, err)
+		}
+		page = append(page, r)
+	}
+	if err := rows.Close(); err != nil {
+		return 0, 0, mapError(ctx, err)
+	}
+	if err := rows.Err(); err != nil {
+		return 0, 0, mapError(ctx, err)
+	}
+
+	keys := s.keys
+	if mode == RotateDecrypt {
+		keys = nil
+	}
+	for _, r := range page {
+		delivery := r.sealed.Delivery
+		if err := openDelivery(s.keys, &delivery, r.sealed.KeyID); err != nil {
+			return 0, 0, fmt.Errorf("delivery %d: %w", r.id, err)
+		}
+		sealed, err := sealDelivery(keys, delivery)
+		if err != nil {
+			return 0, 0, fmt.Errorf("delivery %d: %w", r.id, err)
+		}
+		if _, err := tx.ExecContext(ctx, `UPDATE deliveries SET
+        name = $3,
+        phone = $4,
+        address = $5,
+        email = $6,
+        key_id = $7,
+        phone_index = $8,
+        email_index = $9
+    WHERE id = $1 AND order_date = $2`,
+			r.id, r.orderDate, sealed.Name, sealed.Phone, sealed.Address, sealed.Email, sealed.KeyID, sealed.PhoneIndex, sealed.EmailIndex); err != nil {
+			return 0, 0, mapError(ctx, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, 0, fmt.Errorf("transaction commit failed: %w", mapError(ctx, err))
+	}
+	if len(page) == 0 {
+		return 0, 0, nil
+	}
+	return len(page), page[len(page)-1].id, nil
+}
+
+// rotateDocumentBatch rewrites the documents of a page of table that starts after the row with the key after,
+// or with the first row if after is NULL. Returns the number of rows rewritten and the key of the last one.
+func (s *Storage) rotateDocumentBatch(ctx context.Context, table documentTable, after sql.NullString, limit int, mode RotationMode) (int, string, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to start transaction: %w", mapError(ctx, err))
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	query := fmt.Sprintf(`SELECT %s, key_id, %s FROM %s WHERE true`, table.key, strings.Join(table.columns, ", "), table.name)
+	args := []any{limit}
+	if after.Valid {
+		args = append(args, after.String)
+		query += fmt.Sprintf(` AND %s > $%d`, table.key, len(args))
+	}
+	filter, filterArgs, err := s.rotationFilter(mode, len(args)+1)
+	if err != nil {
+		return 0, "", err
+	}
+	query += filter + fmt.Sprintf(` ORDER BY %s LIMIT $1 FOR UPDATE`, table.key)
+	rows, err := tx.QueryContext(ctx, query, append(args, filterArgs...)...)
+	if err != nil {
+		return 0, "", mapError(ctx, err)
+	}
+
+	type row struct {
+		key       string
+		keyID     sql.NullString
+		documents [][]byte
+	}
+	var page []row
+	for rows.Next() {
+		r := row{documents: make([][]byte, len(table.columns))}
+		dest := []any{&r.key, &r.keyID}
+		for i := range r.documents {
+			dest = append(dest, &r.documents[i])
+		}
+		if err := rows.Scan(dest...); err != nil {
+			_ = rows.Close()
+			return 0, "", mapError(ctx, err)
+		}
+		page = append(page, r)
+	}
+	if err := rows.Close(); err != nil {
+		return 0, "", mapError(ctx, err)
+	}
+	if err := rows.Err(); err != nil {
+		return 0, "", mapError(ctx, err)
+	}
+
+	keys := s.keys
+	if mode == RotateDecrypt {
+		keys = nil
+	}
+	assignments := make([]string, len(table.columns))
+	for i, column := range table.columns {
+		assignments[i] = fmt.Sprintf("%s = $%d", column, i+2)
+	}
+	update := fmt.Sprintf(`UPDATE %s SET %s, key_id = $%d WHERE %s = $1`, table.name, strings.Join(assignments, ", "), len(table.columns)+2, table.key)
+	for _, r := range page {
+		args := []any{r.key}
+		var keyID sql.NullString
+		for _, document := range r.documents {
+			opened, err := openDocument(s.keys, document, r.keyID)
+			if err != nil {
+				return 0, "", fmt.Errorf("%s %s: %w", table.name, r.key, err)
+			}
+			sealed, sealedKeyID, err := sealDocument(keys, opened)
+			if err != nil {
+				return 0, "", fmt.Errorf("%s %s: %w", table.name, r.key, err)
+			}
+			args = append(args, string(sealed))
+			keyID = sealedKeyID
+		}
+		if _, err := tx.ExecContext(ctx, update, append(args, keyID)...); err != nil {
+			return 0, "", mapError(ctx, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, "", fmt.Errorf("transaction commit failed: %w", mapError(ctx, err))
+	}
+	if len(page) == 0 {
+		return 0, "", nil
+	}
+	return len(page), page[len(page)-1].key, nil
+}
@@ -3,46 +3,83 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/models"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository/errs"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/pkg/fieldcrypt"
 )
 
-// SaveOrder inserts a complete order with delivery, payment, and items into the database as a single transaction
-func (s *Storage) SaveOrder(order *models.Order) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+// SaveOrder inserts a complete order with delivery, payment, and items into the database as a single transaction.
+// The message the order was received in, if known, and an "order.accepted" event are written in the same transaction.
+//
+// Saving is idempotent: if an identical order is already stored, errs.ErrDuplicate is returned
+// and nothing is written. If an order with the same UID or track number is stored but its content
+// differs, errs.ErrConflict is returned instead.
+func (s *Storage) SaveOrder(ctx context.Context, order *models.Order) error {
+	ctx, cancel := s.withTimeout(ctx)
 	defer cancel()
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
-		return fmt.Errorf("failed to start transaction: %v", err)
+		return fmt.Errorf("failed to start transaction: %w", mapError(ctx, err))
 	}
 	defer func() { _ = tx.Rollback() }()
 
-	orderId, err := insertOrder(ctx, tx, order)
+	checksum := models.OrderChecksum(order)
+	orderId, err := insertOrder(ctx, tx, order, checksum)
+	if errors.Is(err, sql.ErrNoRows) {
+		return checkStoredOrder(ctx, tx, order.OrderUID, checksum)
+	}
 	if err != nil {
-		return fmt.Errorf("failed to insert order: %v", err)
+		return fmt.Errorf("failed to insert order: %w", mapError(ctx, err))
 	}
-	if err := insertDelivery(ctx, tx, &order.Delivery, orderId); err != nil {
-		return fmt.Errorf("failed to insert delivery: %v", err)
+	if err := insertDelivery(ctx, tx, s.keys, &order.Delivery, orderId, order.DateCreated); err != nil {
+		return fmt.Errorf("failed to insert delivery: %w", mapError(ctx, err))
 	}
-	if err := insertPayment(ctx, tx, &order.Payment, orderId); err != nil {
-		return fmt.Errorf("failed to insert payment: %v", err)
+	if err := insertPayment(ctx, tx, &order.Payment, orderId, order.DateCreated); err != nil {
+		return fmt.Errorf("failed to insert payment: %w", mapError(ctx, err))
 	}
 	for i := range order.Items {
-		if err := insertItem(ctx, tx, &order.Items[i], orderId); err != nil {
-			return fmt.Errorf("failed to insert item: %v", err)
+		if err := insertItem(ctx, tx, &order.Items[i], orderId, order.DateCreated); err != nil {
+			return fmt.Errorf("failed to insert item: %w", mapError(ctx, err))
 		}
 	}
+	if err := insertIngestRecords(ctx, tx, s.keys, []*models.Order{order}); err != nil {
+		return fmt.Errorf("failed to insert ingest record: %w", mapError(ctx, err))
+	}
+	if err := insertOutboxEvent(ctx, tx, order); err != nil {
+		return fmt.Errorf("failed to insert outbox event: %w", mapError(ctx, err))
+	}
 
 	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("transaction commit failed: %v", err)
+		return fmt.Errorf("transaction commit failed: %w", mapError(ctx, err))
 	}
 	return nil
 }
 
-// insertOrder inserts the main order record and returns the generated order ID
-func insertOrder(ctx context.Context, tx *sql.Tx, order *models.Order) (int, error) {
+// checkStoredOrder is called when the order insert hit a unique constraint.
+// It compares the stored checksum with the incoming one to tell a redelivery from a conflict.
+// Orders saved before checksums were introduced can't be verified and are reported as conflicts.
+func checkStoredOrder(ctx context.Context, tx *sql.Tx, orderUID string, checksum string) error {
+	var storedChecksum sql.NullString
+	err := tx.QueryRowContext(ctx, `SELECT checksum FROM orders WHERE order_uid = $1`, orderUID).Scan(&storedChecksum)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("track number of order %s belongs to another order: %w", orderUID, errs.ErrConflict)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get stored order checksum: %w", mapError(ctx, err))
+	}
+	if storedChecksum.Valid && storedChecksum.String == checksum {
+		return fmt.Errorf("order %s: %w", orderUID, errs.ErrDuplicate)
+	}
+	return fmt.Errorf("order %s: %w", orderUID, errs.ErrConflict)
+}
+
+// insertOrder inserts the main order record and returns the generated order ID.
+// If the order UID or track number is already taken, nothing is inserted and sql.ErrNoRows is returned.
+func insertOrder(ctx context.Context, tx *sql.Tx, order *models.Order, checksum string) (int, error) {
 	var id int
 	query := `
 	INSERT INTO orders (
@@ -56,7 +93,8 @@ func insertOrder(ctx context.Context, tx *sql.Tx, order *models.Order) (int, err
 		shardkey, 
 		sm_id, 
 		date_created, 
-		oof_shard
+		oof_shard,
+		checksum
 	) 
 	VALUES (
 		$1, 
@@ -69,8 +107,10 @@ func insertOrder(ctx context.Context, tx *sql.Tx, order *models.Order) (int, err
 		$8, 
 		$9, 
 		$10, 
-		$11
+		$11,
+		$12
 	) 
+	ON CONFLICT DO NOTHING
 	RETURNING id`
 
 	row := tx.QueryRowContext(
@@ -86,15 +126,20 @@ func insertOrder(ctx context.Context, tx *sql.Tx, order *models.Order) (int, err
 		order.ShardKey,
 		order.SmID,
 		order.DateCreated,
-		order.OofShard)
+		order.OofShard,
+		checksum)
 	if err := row.Scan(&id); err != nil {
-		return id, fmt.Errorf("row.Scan failed to get order id: %v", err)
+		return id, fmt.Errorf("row.Scan failed to get order id: %w", err)
 	}
 	return id, nil
 }
 
-// insertDelivery inserts delivery details associated with the given order ID
-func insertDelivery(ctx context.Context, tx *sql.Tx, delivery *models.Delivery, orderID int) error {
+// insertDelivery inserts delivery details associated with the given order ID and date, encrypted with keys if it is set
+func insertDelivery(ctx context.Context, tx *sql.Tx, keys *fieldcrypt.Keyring, delivery *models.Delivery, orderID int, orderDate time.Time) error {
+	sealed, err := sealDelivery(keys, *delivery)
+	if err != nil {
+		return err
+	}
 	query := `
 	INSERT INTO deliveries (
 		order_id,
@@ -104,7 +149,11 @@ func insertDelivery(ctx context.Context, tx *sql.Tx, delivery *models.Delivery,
 		city,
 		address,
 		region, 
-		email
+		email,
+		order_date,
+		key_id,
+		phone_index,
+		email_index
 	) 
 	VALUES (
 		$1, 
@@ -114,26 +163,34 @@ func insertDelivery(ctx context.Context, tx *sql.Tx, delivery *models.Delivery,
 		$5, 
 		$6, 
 		$7, 
-		$8
+		$8,
+		$9,
+		$10,
+		$11,
+		$12
 	)`
 
-	_, err := tx.ExecContext(
+	_, err = tx.ExecContext(
 		ctx,
 		query,
 		orderID,
-		delivery.Name,
-		delivery.Phone,
-		delivery.Zip,
-		delivery.City,
-		delivery.Address,
-		delivery.Region,
-		delivery.Email)
+		sealed.Name,
+		sealed.Phone,
+		sealed.Zip,
+		sealed.City,
+		sealed.Address,
+		sealed.Region,
+		sealed.Email,
+		orderDate,
+		sealed.KeyID,
+		sealed.PhoneIndex,
+		sealed.EmailIndex)
 
 	return err
 }
 
-// insertPayment inserts payment details associated with the given order ID
-func insertPayment(ctx context.Context, tx *sql.Tx, payment *models.Payment, orderID int) error {
+// insertPayment inserts payment details associated with the given order ID and date
+func insertPayment(ctx context.Context, tx *sql.Tx, payment *models.Payment, orderID int, orderDate time.Time) error {
 	paymentTime := time.Unix(payment.PaymentDT, 0)
 	query := `
 	INSERT INTO payments (
@@ -147,7 +204,8 @@ func insertPayment(ctx context.Context, tx *sql.Tx, payment *models.Payment, ord
 		bank,
 		delivery_cost,
 		goods_total,
-		custom_fee
+		custom_fee,
+		order_date
 	) 
 	VALUES (
 		$1, 
@@ -160,7 +218,8 @@ func insertPayment(ctx context.Context, tx *sql.Tx, payment *models.Payment, ord
 		$8,
 		$9,
 		$10,
-		$11 
+		$11,
+		$12
 	)`
 
 	_, err := tx.ExecContext(
@@ -176,13 +235,14 @@ func insertPayment(ctx context.Context, tx *sql.Tx, payment *models.Payment, ord
 		payment.Bank,
 		payment.DeliveryCost,
 		payment.GoodsTotal,
-		payment.CustomFee)
+		payment.CustomFee,
+		orderDate)
 
 	return err
 }
 
-// insertItem inserts a single item associated with the given order ID
-func insertItem(ctx context.Context, tx *sql.Tx, item *models.Item, orderID int) error {
+// insertItem inserts a single item associated with the given order ID and date
+func insertItem(ctx context.Context, tx *sql.Tx, item *models.Item, orderID int, orderDate time.Time) error {
 	query := `
 	INSERT INTO items (
 		order_id,
@@ -196,7 +256,8 @@ func insertItem(ctx context.Context, tx *sql.Tx, item *models.Item, orderID int)
     	total_price,
     	nm_id,
     	brand,
-    	status
+    	status,
+    	order_date
 	) 
 	VALUES (
 		$1, 
@@ -210,7 +271,8 @@ func insertItem(ctx context.Context, tx *sql.Tx, item *models.Item, orderID int)
 		$9,
 		$10,
 		$11,
-		$12
+		$12,
+		$13
 	)`
 
 	_, err := tx.ExecContext(
@@ -228,6 +290,7 @@ func insertItem(ctx context.Context, tx *sql.Tx, item *models.Item, orderID int)
 		item.NmID,
 		item.Brand,
 		item.Status,
+		orderDate,
 	)
 	return err
 }
@@ -1,11 +1,16 @@
 package postgres_test
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/configs"
 	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/models"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository/errs"
 	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository/postgres"
 	mock_logger "github.com/Pur1st2EpicONE/WBTECH-sample-microservice/pkg/logger/mocks"
 	"github.com/golang/mock/gomock"
@@ -20,7 +25,7 @@ func TestPostgresStorer_SaveOrder_Success(t *testing.T) {
 	defer func() { _ = db.Close() }()
 
 	logger := mock_logger.NewMockLogger(gomock.NewController(t))
-	s := postgres.NewStorage(sqlx.NewDb(db, "postgres"), logger)
+	s := postgres.NewStorage(sqlx.NewDb(db, "postgres"), configs.Database{}, logger)
 
 	order := &models.Order{
 		Items: []models.Item{
@@ -55,16 +60,19 @@ func TestPostgresStorer_SaveOrder_Success(t *testing.T) {
 			sqlmock.AnyArg(),
 			sqlmock.AnyArg(),
 			sqlmock.AnyArg(),
+			sqlmock.AnyArg(),
 		).
 		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
 
 	mock.ExpectExec("INSERT INTO deliveries").WillReturnResult(sqlmock.NewResult(1, 1))
 	mock.ExpectExec("INSERT INTO payments").WillReturnResult(sqlmock.NewResult(1, 1))
 	mock.ExpectExec("INSERT INTO items").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO outbox").WithArgs(models.EventOrderAccepted, order.OrderUID, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
 
 	mock.ExpectCommit()
 
-	err = s.SaveOrder(order)
+	err = s.SaveOrder(context.Background(), order)
 	if err != nil {
 		t.Fatalf("expected success, got error: %v", err)
 	}
@@ -81,11 +89,11 @@ func TestPostgresStorer_SaveOrder_BeginTxError(t *testing.T) {
 	defer func() { _ = db.Close() }()
 
 	logger := mock_logger.NewMockLogger(gomock.NewController(t))
-	s := postgres.NewStorage(sqlx.NewDb(db, "postgres"), logger)
+	s := postgres.NewStorage(sqlx.NewDb(db, "postgres"), configs.Database{}, logger)
 
 	mock.ExpectBegin().WillReturnError(fmt.Errorf("begin failed"))
 
-	err = s.SaveOrder(new(models.Order))
+	err = s.SaveOrder(context.Background(), new(models.Order))
 	if err == nil {
 		t.Fatalf("expected begin error, got %v", err)
 	}
@@ -102,7 +110,7 @@ func TestPostgresStorer_InsertOrder_Rollback(t *testing.T) {
 	defer func() { _ = db.Close() }()
 
 	logger := mock_logger.NewMockLogger(gomock.NewController(t))
-	s := postgres.NewStorage(sqlx.NewDb(db, "postgres"), logger)
+	s := postgres.NewStorage(sqlx.NewDb(db, "postgres"), configs.Database{}, logger)
 
 	mock.ExpectBegin()
 
@@ -119,12 +127,13 @@ func TestPostgresStorer_InsertOrder_Rollback(t *testing.T) {
 			sqlmock.AnyArg(),
 			sqlmock.AnyArg(),
 			sqlmock.AnyArg(),
+			sqlmock.AnyArg(),
 		).
 		WillReturnError(fmt.Errorf("failed to insert order"))
 
 	mock.ExpectRollback()
 
-	err = s.SaveOrder(new(models.Order))
+	err = s.SaveOrder(context.Background(), new(models.Order))
 	if err == nil {
 		t.Fatal("expected error, got nil")
 	}
@@ -141,7 +150,7 @@ func TestPostgresStorer_InsertDelivery_Rollback(t *testing.T) {
 	defer func() { _ = db.Close() }()
 
 	logger := mock_logger.NewMockLogger(gomock.NewController(t))
-	s := postgres.NewStorage(sqlx.NewDb(db, "postgres"), logger)
+	s := postgres.NewStorage(sqlx.NewDb(db, "postgres"), configs.Database{}, logger)
 
 	mock.ExpectBegin()
 
@@ -156,12 +165,14 @@ func TestPostgresStorer_InsertDelivery_Rollback(t *testing.T) {
 			sqlmock.AnyArg(),
 			sqlmock.AnyArg(),
 			sqlmock.AnyArg(),
+			sqlmock.AnyArg(),
+			nil, nil, nil,
 		).
 		WillReturnError(fmt.Errorf("failed to insert delivery"))
 
 	mock.ExpectRollback()
 
-	err = s.SaveOrder(new(models.Order))
+	err = s.SaveOrder(context.Background(), new(models.Order))
 	if err == nil {
 		t.Fatal("expected error, got nil")
 	}
@@ -178,7 +189,7 @@ func TestPostgresStorer_InsertPayment_Rollback(t *testing.T) {
 	defer func() { _ = db.Close() }()
 
 	logger := mock_logger.NewMockLogger(gomock.NewController(t))
-	s := postgres.NewStorage(sqlx.NewDb(db, "postgres"), logger)
+	s := postgres.NewStorage(sqlx.NewDb(db, "postgres"), configs.Database{}, logger)
 
 	mock.ExpectBegin()
 
@@ -196,11 +207,12 @@ func TestPostgresStorer_InsertPayment_Rollback(t *testing.T) {
 		sqlmock.AnyArg(),
 		sqlmock.AnyArg(),
 		sqlmock.AnyArg(),
+		sqlmock.AnyArg(),
 	).WillReturnError(fmt.Errorf("failed to insert payment"))
 
 	mock.ExpectRollback()
 
-	err = s.SaveOrder(new(models.Order))
+	err = s.SaveOrder(context.Background(), new(models.Order))
 	if err == nil {
 		t.Fatal("expected error, got nil")
 	}
@@ -217,7 +229,7 @@ func TestPostgresStorer_InsertItem_Rollback(t *testing.T) {
 	defer func() { _ = db.Close() }()
 
 	logger := mock_logger.NewMockLogger(gomock.NewController(t))
-	s := postgres.NewStorage(sqlx.NewDb(db, "postgres"), logger)
+	s := postgres.NewStorage(sqlx.NewDb(db, "postgres"), configs.Database{}, logger)
 
 	order := &models.Order{
 		Items: []models.Item{
@@ -256,12 +268,13 @@ func TestPostgresStorer_InsertItem_Rollback(t *testing.T) {
 			sqlmock.AnyArg(),
 			sqlmock.AnyArg(),
 			sqlmock.AnyArg(),
+			sqlmock.AnyArg(),
 		).
 		WillReturnError(fmt.Errorf("failed to insert item"))
 
 	mock.ExpectRollback()
 
-	err = s.SaveOrder(order)
+	err = s.SaveOrder(context.Background(), order)
 	if err == nil {
 		t.Fatal("expected error, got nil")
 	}
@@ -278,7 +291,7 @@ func TestPostgresStorer_SaveOrder_CommitError(t *testing.T) {
 	defer func() { _ = db.Close() }()
 
 	logger := mock_logger.NewMockLogger(gomock.NewController(t))
-	s := postgres.NewStorage(sqlx.NewDb(db, "postgres"), logger)
+	s := postgres.NewStorage(sqlx.NewDb(db, "postgres"), configs.Database{}, logger)
 
 	order := &models.Order{
 		Items: []models.Item{
@@ -304,10 +317,11 @@ func TestPostgresStorer_SaveOrder_CommitError(t *testing.T) {
 	mock.ExpectExec("INSERT INTO deliveries").WillReturnResult(sqlmock.NewResult(1, 1))
 	mock.ExpectExec("INSERT INTO payments").WillReturnResult(sqlmock.NewResult(1, 1))
 	mock.ExpectExec("INSERT INTO items").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO outbox").WillReturnResult(sqlmock.NewResult(1, 1))
 
 	mock.ExpectCommit().WillReturnError(fmt.Errorf("commit failed"))
 
-	err = s.SaveOrder(order)
+	err = s.SaveOrder(context.Background(), order)
 	if err == nil {
 		t.Fatalf("expected commit error, got %v", err)
 	}
@@ -315,3 +329,90 @@ func TestPostgresStorer_SaveOrder_CommitError(t *testing.T) {
 		t.Fatalf("unfulfilled expectations: %v", err)
 	}
 }
+
+func TestPostgresStorer_SaveOrder_Duplicate(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	logger := mock_logger.NewMockLogger(gomock.NewController(t))
+	s := postgres.NewStorage(sqlx.NewDb(db, "postgres"), configs.Database{}, logger)
+
+	order := &models.Order{OrderUID: "aboba", DateCreated: time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)}
+	sum := models.OrderChecksum(order)
+	order.Version = 3 // set by storage, not part of the order as it is received
+	order.DateCreated = order.DateCreated.In(time.FixedZone("MSK", 3*60*60))
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO orders").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery("SELECT checksum FROM orders").
+		WithArgs(order.OrderUID).
+		WillReturnRows(sqlmock.NewRows([]string{"checksum"}).AddRow(sum))
+	mock.ExpectRollback()
+
+	err = s.SaveOrder(context.Background(), order)
+	if !errors.Is(err, errs.ErrDuplicate) {
+		t.Fatalf("expected ErrDuplicate, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestPostgresStorer_SaveOrder_Conflict(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	logger := mock_logger.NewMockLogger(gomock.NewController(t))
+	s := postgres.NewStorage(sqlx.NewDb(db, "postgres"), configs.Database{}, logger)
+
+	order := &models.Order{OrderUID: "aboba"}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO orders").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery("SELECT checksum FROM orders").
+		WithArgs(order.OrderUID).
+		WillReturnRows(sqlmock.NewRows([]string{"checksum"}).AddRow("not-the-same-checksum"))
+	mock.ExpectRollback()
+
+	err = s.SaveOrder(context.Background(), order)
+	if !errors.Is(err, errs.ErrConflict) {
+		t.Fatalf("expected ErrConflict, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestPostgresStorer_SaveOrder_TrackNumberTaken(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	logger := mock_logger.NewMockLogger(gomock.NewController(t))
+	s := postgres.NewStorage(sqlx.NewDb(db, "postgres"), configs.Database{}, logger)
+
+	order := &models.Order{OrderUID: "aboba"}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO orders").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery("SELECT checksum FROM orders").
+		WithArgs(order.OrderUID).
+		WillReturnRows(sqlmock.NewRows([]string{"checksum"}))
+	mock.ExpectRollback()
+
+	err = s.SaveOrder(context.Background(), order)
+	if !errors.Is(err, errs.ErrConflict) {
+		t.Fatalf("expected ErrConflict, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unfulfilled expectations: %v", err)
+	}
+}
@@ -0,0 +1,266 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/models"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository/errs"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/pkg/fieldcrypt"
+)
+
+// maxBindParams is the number of bind parameters Postgres accepts in a single statement.
+const maxBindParams = 65535
+
+/*
+SaveOrders saves a batch of orders, committing once per batch instead of once per order.
+
+Rows of every table are written with multi-row INSERT statements, so a batch takes
+a handful of round trips regardless of its size. The returned slice holds the outcome of
+every order, in order: nil if it was saved, errs.ErrDuplicate or errs.ErrConflict just like SaveOrder,
+or errs.ErrConstraint if the order can never be saved.
+
+A failing statement aborts the whole transaction, so when an order violates a constraint
+the batch is split in halves and each half is saved on its own, until the bad orders are
+isolated and the rest are committed. The error is returned only if the batch could not be
+saved at all (e.g. the database is unavailable); halves committed before that are reported
+as duplicates when the batch is retried.
+*/
+func (s *Storage) SaveOrders(ctx context.Context, orders []*models.Order) ([]error, error) {
+	results := make([]error, len(orders))
+	if len(orders) == 0 {
+		return results, nil
+	}
+	if err := s.saveOrSplit(ctx, orders, results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// saveOrSplit saves orders as a single batch, or splits it in halves if an order of the batch makes it fail.
+// Outcomes are written to results, which is aligned with orders.
+func (s *Storage) saveOrSplit(ctx context.Context, orders []*models.Order, results []error) error {
+	batchResults, err := s.saveBatch(ctx, orders)
+	if err == nil {
+		copy(results, batchResults)
+		return nil
+	}
+	if ctx.Err() != nil || !errors.Is(err, errs.ErrConstraint) {
+		return err
+	}
+	if len(orders) == 1 {
+		results[0] = err
+		return nil
+	}
+	half := len(orders) / 2
+	if err := s.saveOrSplit(ctx, orders[:half], results[:half]); err != nil {
+		return err
+	}
+	return s.saveOrSplit(ctx, orders[half:], results[half:])
+}
+
+// saveBatch saves orders in a single transaction.
+// Orders that are already stored are skipped and reported in the results; any other failure aborts the batch.
+func (s *Storage) saveBatch(ctx context.Context, orders []*models.Order) ([]error, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", mapError(ctx, err))
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	checksums := make([]string, len(orders))
+	for i, order := range orders {
+		checksums[i] = models.OrderChecksum(order)
+	}
+	ids, err := insertOrders(ctx, tx, orders, checksums)
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert orders: %w", mapError(ctx, err))
+	}
+
+	results := make([]error, len(orders))
+	saved := make([]*models.Order, 0, len(orders))
+	savedIDs := make([]int, 0, len(orders))
+	for i, order := range orders {
+		id, ok := ids[order.OrderUID]
+		if !ok { // already stored, or repeated within the batch
+			results[i] = checkStoredOrder(ctx, tx, order.OrderUID, checksums[i])
+			if !errors.Is(results[i], errs.ErrDuplicate) && !errors.Is(results[i], errs.ErrConflict) {
+				return nil, results[i]
+			}
+			continue
+		}
+		delete(ids, order.OrderUID)
+		saved = append(saved, order)
+		savedIDs = append(savedIDs, id)
+	}
+
+	if err := insertDeliveries(ctx, tx, s.keys, saved, savedIDs); err != nil {
+		return nil, fmt.Errorf("failed to insert deliveries: %w", mapError(ctx, err))
+	}
+	if err := insertPayments(ctx, tx, saved, savedIDs); err != nil {
+		return nil, fmt.Errorf("failed to insert payments: %w", mapError(ctx, err))
+	}
+	if err := insertItems(ctx, tx, saved, savedIDs); err != nil {
+		return nil, fmt.Errorf("failed to insert items: %w", mapError(ctx, err))
+	}
+	if err := insertIngestRecords(ctx, tx, s.keys, saved); err != nil {
+		return nil, fmt.Errorf("failed to insert ingest records: %w", mapError(ctx, err))
+	}
+	if err := insertOutboxEvents(ctx, tx, saved); err != nil {
+		return nil, fmt.Errorf("failed to insert outbox events: %w", mapError(ctx, err))
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("transaction commit failed: %w", mapError(ctx, err))
+	}
+	return results, nil
+}
+
+// insertOrders inserts the main order records and returns the generated IDs by order UID.
+// Orders whose UID or track number is already taken are not inserted and are missing from the result.
+func insertOrders(ctx context.Context, tx *sql.Tx, orders []*models.Order, checksums []string) (map[string]int, error) {
+	columns := []string{"order_uid", "track_number", "entry", "locale", "internal_signature", "customer_id",
+		"delivery_service", "shardkey", "sm_id", "date_created", "oof_shard", "checksum"}
+	rows := make([][]any, len(orders))
+	for i, order := range orders {
+		rows[i] = []any{order.OrderUID, order.TrackNumber, order.Entry, order.Locale, order.InternalSignature,
+			order.CustomerID, order.DeliveryService, order.ShardKey, order.SmID, order.DateCreated, order.OofShard, checksums[i]}
+	}
+
+	ids := make(map[string]int, len(orders))
+	for _, chunk := range chunkRows(rows, len(columns)) {
+		query, args := multiRowInsert("orders", columns, chunk)
+		if err := func() error {
+			inserted, err := tx.QueryContext(ctx, query+" ON CONFLICT DO NOTHING RETURNING id, order_uid", args...)
+			if err != nil {
+				return err
+			}
+			defer func() { _ = inserted.Close() }()
+			for inserted.Next() {
+				var id int
+				var orderUID string
+				if err := inserted.Scan(&id, &orderUID); err != nil {
+					return err
+				}
+				ids[orderUID] = id
+			}
+			return inserted.Err()
+		}(); err != nil {
+			return nil, err
+		}
+	}
+	return ids, nil
+}
+
+// insertDeliveries inserts delivery details of orders, encrypted with keys if it is set. orderIDs holds the ID of every order.
+func insertDeliveries(ctx context.Context, tx *sql.Tx, keys *fieldcrypt.Keyring, orders []*models.Order, orderIDs []int) error {
+	columns := []string{"order_id", "name", "phone", "zip", "city", "address", "region", "email", "order_date",
+		"key_id", "phone_index", "email_index"}
+	rows := make([][]any, len(orders))
+	for i, order := range orders {
+		delivery, err := sealDelivery(keys, order.Delivery)
+		if err != nil {
+			return err
+		}
+		rows[i] = []any{orderIDs[i], delivery.Name, delivery.Phone, delivery.Zip, delivery.City,
+			delivery.Address, delivery.Region, delivery.Email, order.DateCreated, delivery.KeyID, delivery.PhoneIndex, delivery.EmailIndex}
+	}
+	return insertRows(ctx, tx, "deliveries", columns, rows)
+}
+
+// insertPayments inserts payment details of orders, orderIDs holds the ID of every order.
+func insertPayments(ctx context.Context, tx *sql.Tx, orders []*models.Order, orderIDs []int) error {
+	columns := []string{"order_id", "transaction", "request_id", "currency", "provider", "amount",
+		"payment_dt", "bank", "delivery_cost", "goods_total", "custom_fee", "order_date"}
+	rows := make([][]any, len(orders))
+	for i, order := range orders {
+		payment := &order.Payment
+		rows[i] = []any{orderIDs[i], payment.Transaction, payment.RequestID, payment.Currency, payment.Provider,
+			payment.Amount, time.Unix(payment.PaymentDT, 0), payment.Bank, payment.DeliveryCost, payment.GoodsTotal, payment.CustomFee, order.DateCreated}
+	}
+	return insertRows(ctx, tx, "payments", columns, rows)
+}
+
+// insertItems inserts the items of all orders, orderIDs holds the ID of every order.
+func insertItems(ctx context.Context, tx *sql.Tx, orders []*models.Order, orderIDs []int) error {
+	columns := []string{"order_id", "chrt_id", "track_number", "price", "rid", "name", "sale", "size",
+		"total_price", "nm_id", "brand", "status", "order_date"}
+	var rows [][]any
+	for i, order := range orders {
+		for _, item := range order.Items {
+			rows = append(rows, []any{orderIDs[i], item.ChrtID, item.TrackNumber, item.Price, item.Rid, item.Name,
+				item.Sale, item.Size, item.TotalPrice, item.NmID, item.Brand, item.Status, order.DateCreated})
+		}
+	}
+	return insertRows(ctx, tx, "items", columns, rows)
+}
+
+// insertOutboxEvents records an "order.accepted" event for every order, see insertOutboxEvent.
+func insertOutboxEvents(ctx context.Context, tx *sql.Tx, orders []*models.Order) error {
+	columns := []string{"event_type", "event_key", "payload", "created_at", "next_attempt_at"}
+	now := time.Now().UTC()
+	rows := make([][]any, len(orders))
+	for i, order := range orders {
+		payload, err := json.Marshal(models.NewOrderAccepted(order, now))
+		if err != nil {
+			return fmt.Errorf("failed to marshal outbox event: %v", err)
+		}
+		rows[i] = []any{models.EventOrderAccepted, order.OrderUID, string(payload), now, now}
+	}
+	return insertRows(ctx, tx, "outbox", columns, rows)
+}
+
+// insertRows inserts rows into table, using as few multi-row INSERT statements as the bind parameter limit allows.
+func insertRows(ctx context.Context, tx *sql.Tx, table string, columns []string, rows [][]any) error {
+	for _, chunk := range chunkRows(rows, len(columns)) {
+		query, args := multiRowInsert(table, columns, chunk)
+		if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// chunkRows splits rows of the given width into chunks that fit into a single statement.
+func chunkRows(rows [][]any, width int) [][][]any {
+	size := maxBindParams / width
+	var chunks [][][]any
+	for len(rows) > size {
+		chunks = append(chunks, rows[:size])
+		rows = rows[size:]
+	}
+	if len(rows) > 0 {
+		chunks = append(chunks, rows)
+	}
+	return chunks
+}
+
+// multiRowInsert builds an INSERT statement for rows and returns it along with its flattened arguments.
+func multiRowInsert(table string, columns []string, rows [][]any) (string, []any) {
+	var query strings.Builder
+	args := make([]any, 0, len(rows)*len(columns))
+	query.WriteString("INSERT INTO " + table + " (" + strings.Join(columns, ", ") + ") VALUES ")
+	for i, row := range rows {
+		if i > 0 {
+			query.WriteString(", ")
+		}
+		query.WriteByte('(')
+		for j, value := range row {
+			if j > 0 {
+				query.WriteString(", ")
+			}
+			args = append(args, value)
+			query.WriteString("$" + strconv.Itoa(len(args)))
+		}
+		query.WriteByte(')')
+	}
+	return query.String(), args
+}
@@ -0,0 +1,286 @@
+package postgres_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/configs"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/models"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository/errs"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository/postgres"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// batchOrders returns n orders with two items each, unique by their position.
+func batchOrders(n int) []*models.Order {
+	orders := make([]*models.Order, n)
+	for i := range orders {
+		uid := fmt.Sprintf("uid%d", i+1)
+		orders[i] = &models.Order{
+			OrderUID:    uid,
+			TrackNumber: "TRACK" + uid,
+			Payment:     models.Payment{Transaction: uid},
+			Items:       []models.Item{{ChrtID: 1, Rid: uid + "-1"}, {ChrtID: 2, Rid: uid + "-2"}},
+		}
+	}
+	return orders
+}
+
+// expectBatch sets up the statements of a batch transaction in which every order is inserted.
+// The transaction fails on the items insert if itemsErr is not nil.
+func expectBatch(mock sqlmock.Sqlmock, orders []*models.Order, firstID int, itemsErr error) {
+	inserted := sqlmock.NewRows([]string{"id", "order_uid"})
+	for i, order := range orders {
+		inserted.AddRow(firstID+i, order.OrderUID)
+	}
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO orders").WillReturnRows(inserted)
+	mock.ExpectExec("INSERT INTO deliveries").WillReturnResult(sqlmock.NewResult(0, int64(len(orders))))
+	mock.ExpectExec("INSERT INTO payments").WillReturnResult(sqlmock.NewResult(0, int64(len(orders))))
+	if itemsErr != nil {
+		mock.ExpectExec("INSERT INTO items").WillReturnError(itemsErr)
+		mock.ExpectRollback()
+		return
+	}
+	mock.ExpectExec("INSERT INTO items").WillReturnResult(sqlmock.NewResult(0, int64(2*len(orders))))
+	mock.ExpectExec("INSERT INTO outbox").WillReturnResult(sqlmock.NewResult(0, int64(len(orders))))
+	mock.ExpectCommit()
+}
+
+func TestPostgresStorer_SaveOrders_Success(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	ps := postgres.NewStorage(sqlx.NewDb(db, "postgres"), configs.Database{}, nil)
+	orders := batchOrders(2)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO orders \(.+\) VALUES \(\$1, .+, \$12\), \(\$13, .+, \$24\) ON CONFLICT DO NOTHING RETURNING id, order_uid`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "order_uid"}).AddRow(1, "uid1").AddRow(2, "uid2"))
+	mock.ExpectExec("INSERT INTO deliveries").WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec("INSERT INTO payments").WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(`INSERT INTO items \(.+\) VALUES (\(.+\), ){3}\(.+\)$`).
+		WithArgs(1, 1, "", 0.0, "uid1-1", "", 0, "", 0.0, 0, "", 0, orders[0].DateCreated,
+			1, 2, "", 0.0, "uid1-2", "", 0, "", 0.0, 0, "", 0, orders[0].DateCreated,
+			2, 1, "", 0.0, "uid2-1", "", 0, "", 0.0, 0, "", 0, orders[1].DateCreated,
+			2, 2, "", 0.0, "uid2-2", "", 0, "", 0.0, 0, "", 0, orders[1].DateCreated).
+		WillReturnResult(sqlmock.NewResult(0, 4))
+	mock.ExpectExec("INSERT INTO outbox").
+		WithArgs(models.EventOrderAccepted, "uid1", sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(),
+			models.EventOrderAccepted, "uid2", sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	results, err := ps.SaveOrders(context.Background(), orders)
+	if err != nil {
+		t.Fatalf("SaveOrders failed: %v", err)
+	}
+	for i, result := range results {
+		if result != nil {
+			t.Fatalf("expected order %d to be saved, got %v", i, result)
+		}
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestPostgresStorer_SaveOrders_SkipsStoredOrders(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	ps := postgres.NewStorage(sqlx.NewDb(db, "postgres"), configs.Database{}, nil)
+	orders := batchOrders(3)
+	sum := models.OrderChecksum(orders[0])
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO orders").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "order_uid"}).AddRow(3, "uid2"))
+	mock.ExpectQuery("SELECT checksum FROM orders").WithArgs("uid1").
+		WillReturnRows(sqlmock.NewRows([]string{"checksum"}).AddRow(sum))
+	mock.ExpectQuery("SELECT checksum FROM orders").WithArgs("uid3").
+		WillReturnRows(sqlmock.NewRows([]string{"checksum"}).AddRow("other"))
+	mock.ExpectExec("INSERT INTO deliveries").WithArgs(3, "", "", "", "", "", "", "", orders[1].DateCreated, nil, nil, nil).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO payments").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO items").WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec("INSERT INTO outbox").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	results, err := ps.SaveOrders(context.Background(), orders)
+	if err != nil {
+		t.Fatalf("SaveOrders failed: %v", err)
+	}
+	if !errors.Is(results[0], errs.ErrDuplicate) || results[1] != nil || !errors.Is(results[2], errs.ErrConflict) {
+		t.Fatalf("expected [duplicate, saved, conflict], got %v", results)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestPostgresStorer_SaveOrders_SplitsOutBadOrders(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	ps := postgres.NewStorage(sqlx.NewDb(db, "postgres"), configs.Database{}, nil)
+	orders := batchOrders(4)
+	tooLong := &pq.Error{Code: "22001"}
+
+	expectBatch(mock, orders, 1, tooLong)     // the whole batch fails
+	expectBatch(mock, orders[:2], 1, nil)     // the first half is fine
+	expectBatch(mock, orders[2:], 3, tooLong) // the second half fails again
+	expectBatch(mock, orders[2:3], 3, nil)
+	expectBatch(mock, orders[3:], 4, tooLong)
+
+	results, err := ps.SaveOrders(context.Background(), orders)
+	if err != nil {
+		t.Fatalf("SaveOrders failed: %v", err)
+	}
+	if results[0] != nil || results[1] != nil || results[2] != nil || !errors.Is(results[3], errs.ErrConstraint) {
+		t.Fatalf("expected only the last order to be rejected, got %v", results)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestPostgresStorer_SaveOrders_UniqueViolationIsNotADuplicate(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	ps := postgres.NewStorage(sqlx.NewDb(db, "postgres"), configs.Database{}, nil)
+	orders := batchOrders(2)
+	uniqueViolation := &pq.Error{Code: "23505", Constraint: "uq_ingest_log"}
+
+	expectBatch(mock, orders, 1, uniqueViolation)
+	expectBatch(mock, orders[:1], 1, nil)
+	expectBatch(mock, orders[1:], 2, uniqueViolation)
+
+	results, err := ps.SaveOrders(context.Background(), orders)
+	if err != nil {
+		t.Fatalf("SaveOrders failed: %v", err)
+	}
+	if results[0] != nil || errors.Is(results[1], errs.ErrDuplicate) || !errors.Is(results[1], errs.ErrConstraint) {
+		t.Fatalf("expected the order hitting the unique constraint to be rejected, not skipped as saved, got %v", results)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestPostgresStorer_SaveOrders_Unavailable(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	ps := postgres.NewStorage(sqlx.NewDb(db, "postgres"), configs.Database{}, nil)
+	orders := batchOrders(2)
+
+	expectBatch(mock, orders, 1, &pq.Error{Code: "08006"}) // connection failure is not split
+
+	if _, err := ps.SaveOrders(context.Background(), orders); !errors.Is(err, errs.ErrUnavailable) {
+		t.Fatalf("expected ErrUnavailable for the whole batch, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestPostgresStorer_SaveOrders_Empty(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open sqlmock: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	ps := postgres.NewStorage(sqlx.NewDb(db, "postgres"), configs.Database{}, nil)
+	if results, err := ps.SaveOrders(context.Background(), nil); err != nil || len(results) != 0 {
+		t.Fatalf("expected no results, got %v (%v)", results, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unfulfilled expectations: %v", err)
+	}
+}
+
+// BenchmarkSaveOrders compares saving orders one transaction each with saving them as a batch.
+// Every statement costs a simulated round trip, which is what batching saves on.
+func BenchmarkSaveOrders(b *testing.B) {
+	for _, n := range []int{10, 100} {
+		orders := batchOrders(n)
+
+		b.Run(fmt.Sprintf("per_order/%d", n), func(b *testing.B) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				b.Fatalf("failed to open mock db: %v", err)
+			}
+			defer func() { _ = db.Close() }()
+			ps := postgres.NewStorage(sqlx.NewDb(db, "postgres"), configs.Database{}, nil)
+
+			for i := 0; i < b.N; i++ {
+				for j, order := range orders {
+					b.StopTimer()
+					mock.ExpectBegin().WillDelayFor(benchRoundTrip)
+					mock.ExpectQuery("INSERT INTO orders").WillDelayFor(benchRoundTrip).
+						WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(j + 1))
+					mock.ExpectExec("INSERT INTO deliveries").WillDelayFor(benchRoundTrip).WillReturnResult(sqlmock.NewResult(0, 1))
+					mock.ExpectExec("INSERT INTO payments").WillDelayFor(benchRoundTrip).WillReturnResult(sqlmock.NewResult(0, 1))
+					mock.ExpectExec("INSERT INTO items").WillDelayFor(benchRoundTrip).WillReturnResult(sqlmock.NewResult(0, 1))
+					mock.ExpectExec("INSERT INTO items").WillDelayFor(benchRoundTrip).WillReturnResult(sqlmock.NewResult(0, 1))
+					mock.ExpectExec("INSERT INTO outbox").WillDelayFor(benchRoundTrip).WillReturnResult(sqlmock.NewResult(0, 1))
+					mock.ExpectCommit()
+					b.StartTimer()
+					if err := ps.SaveOrder(context.Background(), order); err != nil {
+						b.Fatalf("SaveOrder failed: %v", err)
+					}
+				}
+			}
+		})
+
+		b.Run(fmt.Sprintf("batch/%d", n), func(b *testing.B) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				b.Fatalf("failed to open mock db: %v", err)
+			}
+			defer func() { _ = db.Close() }()
+			ps := postgres.NewStorage(sqlx.NewDb(db, "postgres"), configs.Database{}, nil)
+
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				inserted := sqlmock.NewRows([]string{"id", "order_uid"})
+				for j, order := range orders {
+					inserted.AddRow(j+1, order.OrderUID)
+				}
+				mock.ExpectBegin().WillDelayFor(benchRoundTrip)
+				mock.ExpectQuery("INSERT INTO orders").WillDelayFor(benchRoundTrip).WillReturnRows(inserted)
+				mock.ExpectExec("INSERT INTO deliveries").WillDelayFor(benchRoundTrip).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("INSERT INTO payments").WillDelayFor(benchRoundTrip).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("INSERT INTO items").WillDelayFor(benchRoundTrip).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec("INSERT INTO outbox").WillDelayFor(benchRoundTrip).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+				b.StartTimer()
+				if _, err := ps.SaveOrders(context.Background(), orders); err != nil {
+					b.Fatalf("SaveOrders failed: %v", err)
+				}
+			}
+		})
+	}
+}
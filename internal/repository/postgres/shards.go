@@ -0,0 +1,256 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/models"
+)
+
+// This file holds the building blocks of a sharded deployment (see the repository/sharded package):
+// the order_shards lookup table kept in the main database, and moving orders between shards.
+
+// AssignShards records the shard of every order that has none yet in the order_shards lookup table.
+// owners maps order UIDs to the shard proposed to hold them. The returned map holds the shard
+// of every order, which differs from the proposed one for orders that are already recorded elsewhere.
+func (s *Storage) AssignShards(ctx context.Context, owners map[string]string) (map[string]string, error) {
+	assigned := make(map[string]string, len(owners))
+	if len(owners) == 0 {
+		return assigned, nil
+	}
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	rows := make([][]any, 0, len(owners))
+	for _, orderUID := range slices.Sorted(maps.Keys(owners)) {
+		rows = append(rows, []any{orderUID, owners[orderUID]})
+	}
+	for _, chunk := range chunkRows(rows, 2) {
+		query, args := multiRowInsert("order_shards", []string{"order_uid", "shard"}, chunk)
+		query += `
+        ON CONFLICT (order_uid) DO UPDATE SET order_uid = EXCLUDED.order_uid
+        RETURNING order_uid, shard` // a no-op update, so that already recorded orders are returned as well
+		if err := scanAssignedShards(ctx, s, query, args, assigned); err != nil {
+			return nil, mapError(ctx, err)
+		}
+	}
+	return assigned, nil
+}
+
+// scanAssignedShards runs an order_shards upsert and adds the returned order UIDs and shards to assigned.
+func scanAssignedShards(ctx context.Context, s *Storage, query string, args []any, assigned map[string]string) error {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = rows.Close() }()
+	for rows.Next() {
+		var orderUID, shard string
+		if err := rows.Scan(&orderUID, &shard); err != nil {
+			return err
+		}
+		assigned[orderUID] = shard
+	}
+	return rows.Err()
+}
+
+// LookupShard returns the shard that holds an order according to the order_shards lookup table.
+// Returns errs.ErrNotFound if the order is not recorded there.
+func (s *Storage) LookupShard(ctx context.Context, orderUID string) (string, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	var shard string
+	err := s.db.QueryRowContext(ctx, `SELECT shard FROM order_shards WHERE order_uid = $1`, orderUID).Scan(&shard)
+	if err != nil {
+		return "", mapError(ctx, err)
+	}
+	return shard, nil
+}
+
+// SetShard records that an order is now held by the given shard.
+func (s *Storage) SetShard(ctx context.Context, orderUID string, shard string) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	_, err := s.db.ExecContext(ctx, `INSERT INTO order_shards (order_uid, shard) VALUES ($1, $2)
+        ON CONFLICT (order_uid) DO UPDATE SET shard = EXCLUDED.shard, assigned_at = NOW()`, orderUID, shard)
+	return mapError(ctx, err)
+}
+
+// OrderKey identifies a stored order along with its shard key.
+type OrderKey struct {
+	ID       int64 // database ID, only meaningful within a single database
+	OrderUID string
+	ShardKey string
+}
+
+// OrderKeys returns up to limit stored orders with an ID greater than afterID, in ID order,
+// so all orders can be scanned page by page while orders are being added and removed.
+func (s *Storage) OrderKeys(ctx context.Context, afterID int64, limit int) ([]OrderKey, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	rows, err := s.db.QueryContext(ctx, `SELECT id, order_uid, shardkey FROM orders
+        WHERE id > $1
+        ORDER BY id
+        LIMIT $2`, afterID, limit)
+	if err != nil {
+		return nil, mapError(ctx, err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var keys []OrderKey
+	for rows.Next() {
+		var key OrderKey
+		if err := rows.Scan(&key.ID, &key.OrderUID, &key.ShardKey); err != nil {
+			return nil, mapError(ctx, err)
+		}
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, mapError(ctx, err)
+	}
+	return keys, nil
+}
+
+/*
+MoveOrder removes an order from the database after handing it over to transfer.
+
+The order and its items are locked for the whole move, so a concurrent status update waits for it
+to finish and then finds no order here. The order is deleted only if transfer succeeds, in the same
+transaction that holds the lock. If the deletion fails after transfer, the order exists in both places:
+moving it again is safe as long as transfer is idempotent.
+The delivery is handed over decrypted, the ingested payload and the amendments keep the key IDs they are encrypted with.
+
+Returns errs.ErrNotFound if there is no order with such UID.
+*/
+func (s *Storage) MoveOrder(ctx context.Context, orderUID string, transfer func(order models.OrderSnapshot) error) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", mapError(ctx, err))
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var orderId int
+	var checksum sql.NullString
+	err = tx.QueryRowContext(ctx, `SELECT id, checksum FROM orders WHERE order_uid = $1 FOR UPDATE`, orderUID).Scan(&orderId, &checksum)
+	if err != nil {
+		return fmt.Errorf("failed to lock order %s: %w", orderUID, mapError(ctx, err))
+	}
+	if _, err := tx.ExecContext(ctx, `SELECT id FROM items WHERE order_id = $1 FOR UPDATE`, orderId); err != nil {
+		return fmt.Errorf("failed to lock items: %w", mapError(ctx, err))
+	}
+
+	// the locks keep the order from changing, so it is read with plain queries of the primary
+	moved := models.OrderSnapshot{Order: new(models.Order), Checksum: checksum.String}
+	if err := queryAllButItems(ctx, s.db, s.keys, moved.Order, orderUID, &orderId); err != nil {
+		return fmt.Errorf("failed to read order: %w", mapError(ctx, err))
+	}
+	if err := queryItems(ctx, s.db, &moved.Order.Items, orderId); err != nil {
+		return fmt.Errorf("failed to read items: %w", mapError(ctx, err))
+	}
+	if moved.History, err = queryHistory(ctx, s.db, orderId); err != nil {
+		return fmt.Errorf("failed to read status history: %w", mapError(ctx, err))
+	}
+	ingest, err := queryIngestRecords(ctx, s.db, []string{orderUID})
+	if err != nil {
+		return fmt.Errorf("failed to read ingest record: %w", mapError(ctx, err))
+	}
+	moved.Ingest = ingest[orderUID]
+	amendments, err := queryAmendments(ctx, s.db, []string{orderUID})
+	if err != nil {
+		return fmt.Errorf("failed to read amendments: %w", mapError(ctx, err))
+	}
+	moved.Amendments = amendments[orderUID]
+
+	if err := transfer(moved); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM orders WHERE id = $1`, orderId); err != nil {
+		return fmt.Errorf("failed to delete order: %w", mapError(ctx, err))
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("transaction commit failed: %w", mapError(ctx, err))
+	}
+	return nil
+}
+
+/*
+ImportOrder stores an order moved from another shard or restored from an archive as a single transaction,
+keeping its checksum, item statuses, status history, ingest record, version and amendments.
+A delivery encrypted with moved.KeyID, as archives hold it, is decrypted and encrypted again with the active key,
+and so are the ingested payload and the amendments, encrypted with the key IDs they carry.
+
+Unlike SaveOrder, it writes no "order.accepted" event: the order was accepted once already.
+Returns errs.ErrDuplicate if the order has already been imported, and errs.ErrConflict
+if a different order with the same UID or track number is stored.
+*/
+func (s *Storage) ImportOrder(ctx context.Context, moved models.OrderSnapshot) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", mapError(ctx, err))
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	order := moved.Order
+	orderId, err := insertOrder(ctx, tx, order, moved.Checksum)
+	if errors.Is(err, sql.ErrNoRows) {
+		return checkStoredOrder(ctx, tx, order.OrderUID, moved.Checksum)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to insert order: %w", mapError(ctx, err))
+	}
+	delivery := order.Delivery
+	if err := openDelivery(s.keys, &delivery, sql.NullString{String: moved.KeyID, Valid: moved.KeyID != ""}); err != nil {
+		return fmt.Errorf("failed to decrypt delivery: %w", err)
+	}
+	if err := insertDelivery(ctx, tx, s.keys, &delivery, orderId, order.DateCreated); err != nil {
+		return fmt.Errorf("failed to insert delivery: %w", mapError(ctx, err))
+	}
+	if err := insertPayment(ctx, tx, &order.Payment, orderId, order.DateCreated); err != nil {
+		return fmt.Errorf("failed to insert payment: %w", mapError(ctx, err))
+	}
+	for i := range order.Items {
+		if err := insertItem(ctx, tx, &order.Items[i], orderId, order.DateCreated); err != nil {
+			return fmt.Errorf("failed to insert item: %w", mapError(ctx, err))
+		}
+	}
+	for _, event := range moved.History {
+		_, err := tx.ExecContext(ctx, `INSERT INTO item_status_history (item_id, order_id, previous_status, status, changed_at, recorded_at, order_date)
+        SELECT id, order_id, $3, $4, $5, $6, order_date FROM items
+        WHERE order_id = $1 AND chrt_id = $2 AND rid = $7
+        LIMIT 1`,
+			orderId, event.ChrtID, event.PreviousStatus, event.Status, event.ChangedAt.UTC(), event.RecordedAt.UTC(), event.Rid)
+		if err != nil {
+			return fmt.Errorf("failed to insert status history: %w", mapError(ctx, err))
+		}
+	}
+	if moved.Ingest != nil {
+		ingest := *moved.Ingest
+		if err := openIngestRecord(s.keys, order.OrderUID, &ingest); err != nil {
+			return err
+		}
+		row, err := ingestRow(s.keys, order.OrderUID, &ingest)
+		if err != nil {
+			return err
+		}
+		if err := insertRows(ctx, tx, "order_ingest_log", ingestColumns, [][]any{row}); err != nil {
+			return fmt.Errorf("failed to insert ingest record: %w", mapError(ctx, err))
+		}
+	}
+	if err := importAmendments(ctx, tx, s.keys, orderId, moved); err != nil {
+		return fmt.Errorf("failed to insert amendments: %w", mapError(ctx, err))
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("transaction commit failed: %w", mapError(ctx, err))
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package postgres_test
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/configs"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/models"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository/errs"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository/postgres"
+)
+
+func TestPostgresStorer_AssignShards(t *testing.T) {
+	db, mock := newMockDB(t)
+	ps := postgres.NewStorage(db, configs.Database{}, nil)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO order_shards (order_uid, shard) VALUES ($1, $2), ($3, $4)`)).
+		WithArgs("uid1", "shard-0", "uid2", "shard-1").
+		WillReturnRows(sqlmock.NewRows([]string{"order_uid", "shard"}).AddRow("uid1", "shard-0").AddRow("uid2", "shard-0"))
+
+	assigned, err := ps.AssignShards(context.Background(), map[string]string{"uid2": "shard-1", "uid1": "shard-0"})
+	if err != nil {
+		t.Fatalf("AssignShards failed: %v", err)
+	}
+	if assigned["uid1"] != "shard-0" || assigned["uid2"] != "shard-0" {
+		t.Fatalf("expected the recorded shard to win over the proposed one, got %v", assigned)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestPostgresStorer_LookupShard_NotRecorded(t *testing.T) {
+	db, mock := newMockDB(t)
+	ps := postgres.NewStorage(db, configs.Database{}, nil)
+
+	mock.ExpectQuery("SELECT shard FROM order_shards").WithArgs("uid1").WillReturnRows(sqlmock.NewRows([]string{"shard"}))
+	if _, err := ps.LookupShard(context.Background(), "uid1"); !errors.Is(err, errs.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestPostgresStorer_MoveOrder(t *testing.T) {
+	db, mock := newMockDB(t)
+	ps := postgres.NewStorage(db, configs.Database{}, nil)
+
+	changedAt := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	expectLockedOrder := func() {
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT id, checksum FROM orders WHERE order_uid = \\$1 FOR UPDATE").WithArgs("uid1").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "checksum"}).AddRow(1, "sum"))
+		mock.ExpectExec("SELECT id FROM items WHERE order_id = \\$1 FOR UPDATE").WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectQuery("FROM orders").WillReturnRows(mockOrderRows(1))
+		mock.ExpectQuery("FROM items WHERE order_id").WillReturnRows(sqlmock.NewRows(mockItemColumns[1:]).
+			AddRow(1, "track", 100, "rid", "item", 0, "M", 100, 1, "brand", 202))
+		mock.ExpectQuery("FROM item_status_history").WillReturnRows(sqlmock.NewRows([]string{"chrt_id", "rid", "previous_status", "status", "changed_at", "recorded_at"}).
+			AddRow(1, "rid", 0, 202, changedAt, changedAt))
+		mock.ExpectQuery("FROM order_ingest_log").WillReturnRows(sqlmock.NewRows(ingestColumns).
+			AddRow("uid1", "orders", 0, 42, "uid1", changedAt, changedAt, []byte(`{"order_uid":"uid1"}`), nil, nil))
+		mock.ExpectQuery("FROM order_amendments").WithArgs(`{"uid1"}`).WillReturnRows(sqlmock.NewRows(amendmentColumns).
+			AddRow("uid1", 2, []byte(`{"locale":"ru"}`), []byte(`{"order_uid":"uid1"}`), changedAt, nil))
+	}
+
+	expectLockedOrder()
+	mock.ExpectRollback()
+	transferErr := errors.New("target shard is down")
+	err := ps.MoveOrder(context.Background(), "uid1", func(models.OrderSnapshot) error { return transferErr })
+	if !errors.Is(err, transferErr) {
+		t.Fatalf("expected the transfer error, got %v", err)
+	}
+
+	expectLockedOrder()
+	mock.ExpectExec("DELETE FROM orders WHERE id = \\$1").WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	var moved models.OrderSnapshot
+	if err := ps.MoveOrder(context.Background(), "uid1", func(order models.OrderSnapshot) error { moved = order; return nil }); err != nil {
+		t.Fatalf("MoveOrder failed: %v", err)
+	}
+	if moved.Checksum != "sum" || moved.Order.OrderUID != "uid1" || len(moved.Order.Items) != 1 || len(moved.History) != 1 ||
+		moved.Ingest == nil || moved.Ingest.Offset != 42 || len(moved.Amendments) != 1 || moved.Amendments[0].Version != 2 {
+		t.Fatalf("expected the whole order to be handed over, got %+v", moved)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestPostgresStorer_ImportOrder(t *testing.T) {
+	db, mock := newMockDB(t)
+	ps := postgres.NewStorage(db, configs.Database{}, nil)
+
+	changedAt := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	moved := models.OrderSnapshot{
+		Order: &models.Order{OrderUID: "uid1", TrackNumber: "track", ShardKey: "7", Version: 2,
+			Items: []models.Item{{ChrtID: 1, TrackNumber: "track", Rid: "rid", Status: 202}}},
+		History:  []models.StatusEvent{{ChrtID: 1, Rid: "rid", PreviousStatus: 0, Status: 202, ChangedAt: changedAt, RecordedAt: changedAt}},
+		Checksum: "sum",
+		Ingest:   &models.IngestRecord{Topic: "orders", Offset: 42, Key: "uid1", IngestedAt: changedAt, Payload: []byte(`{"order_uid":"uid1"}`)},
+		Amendments: []models.OrderAmendment{
+			{Version: 2, Patch: []byte(`{"locale":"ru"}`), Previous: []byte(`{"order_uid":"uid1"}`), AmendedAt: changedAt},
+		},
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO orders").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+	mock.ExpectExec("INSERT INTO deliveries").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO payments").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO items").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO item_status_history").WithArgs(3, 1, 0, 202, changedAt, changedAt, "rid").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO order_ingest_log").
+		WithArgs("uid1", "orders", int32(0), int64(42), "uid1", sqlmock.AnyArg(), changedAt, `{"order_uid":"uid1"}`, nil, nil).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE orders SET version = $2 WHERE id = $1")).WithArgs(3, 2).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO order_amendments").
+		WithArgs("uid1", 2, `{"locale":"ru"}`, `{"order_uid":"uid1"}`, changedAt, nil).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	if err := ps.ImportOrder(context.Background(), moved); err != nil {
+		t.Fatalf("ImportOrder failed: %v", err)
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO orders").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery("SELECT checksum FROM orders").WithArgs("uid1").WillReturnRows(sqlmock.NewRows([]string{"checksum"}).AddRow("sum"))
+	mock.ExpectRollback()
+	if err := ps.ImportOrder(context.Background(), moved); !errors.Is(err, errs.ErrDuplicate) {
+		t.Fatalf("expected an imported order to be reported as duplicate, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %v", err)
+	}
+}
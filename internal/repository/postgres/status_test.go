@@ -0,0 +1,137 @@
+package postgres_test
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/configs"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/models"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository/errs"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository/postgres"
+	"github.com/jmoiron/sqlx"
+)
+
+func testStatusUpdate() models.StatusUpdate {
+	return models.StatusUpdate{
+		OrderUID:  "uid1",
+		ChrtID:    9934930,
+		Rid:       "ab4219087a764ae0btest",
+		Status:    300,
+		ChangedAt: time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestPostgresStorer_UpdateItemStatus_Success(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open mock db: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	ps := postgres.NewStorage(sqlx.NewDb(db, "postgres"), configs.Database{}, nil)
+	update := testStatusUpdate()
+	orderDate := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta("FOR UPDATE OF items")).WithArgs(update.OrderUID, update.ChrtID, update.Rid).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "order_id", "order_date", "status"}).AddRow(7, 1, orderDate, 202))
+	mock.ExpectQuery("INSERT INTO item_status_history").WithArgs(7, 1, 202, 300, update.ChangedAt, orderDate).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectExec("UPDATE items SET status").WithArgs(300, 7, update.ChangedAt).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	if err := ps.UpdateItemStatus(context.Background(), update); err != nil {
+		t.Fatalf("expected success, got error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestPostgresStorer_UpdateItemStatus_ItemNotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open mock db: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	ps := postgres.NewStorage(sqlx.NewDb(db, "postgres"), configs.Database{}, nil)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("FOR UPDATE OF items").WillReturnRows(sqlmock.NewRows([]string{"id", "order_id", "order_date", "status"}))
+	mock.ExpectRollback()
+
+	if err := ps.UpdateItemStatus(context.Background(), testStatusUpdate()); !errors.Is(err, errs.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestPostgresStorer_UpdateItemStatus_Duplicate(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open mock db: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	ps := postgres.NewStorage(sqlx.NewDb(db, "postgres"), configs.Database{}, nil)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("FOR UPDATE OF items").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "order_id", "order_date", "status"}).AddRow(7, 1, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), 300))
+	mock.ExpectQuery("INSERT INTO item_status_history").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectRollback()
+
+	if err := ps.UpdateItemStatus(context.Background(), testStatusUpdate()); !errors.Is(err, errs.ErrDuplicate) {
+		t.Fatalf("expected ErrDuplicate, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unfulfilled expectations: %v", err)
+	}
+}
+
+func TestPostgresStorer_GetOrderHistory_Success(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open mock db: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	ps := postgres.NewStorage(sqlx.NewDb(db, "postgres"), configs.Database{}, nil)
+	now := time.Now()
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id FROM orders WHERE order_uid = $1")).WithArgs("uid1").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectQuery("FROM item_status_history").WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"chrt_id", "rid", "previous_status", "status", "changed_at", "recorded_at"}).
+			AddRow(1, "rid01", 202, 300, now, now).
+			AddRow(1, "rid01", 300, 400, now, now))
+
+	history, err := ps.GetOrderHistory(context.Background(), "uid1")
+	if err != nil {
+		t.Fatalf("expected success, got error: %v", err)
+	}
+	if history.OrderUID != "uid1" || len(history.Events) != 2 || history.Events[1].Status != 400 {
+		t.Fatalf("unexpected history: %+v", history)
+	}
+}
+
+func TestPostgresStorer_GetOrderHistory_OrderNotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to open mock db: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	ps := postgres.NewStorage(sqlx.NewDb(db, "postgres"), configs.Database{}, nil)
+
+	mock.ExpectQuery("SELECT id FROM orders").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	if _, err := ps.GetOrderHistory(context.Background(), "nope"); !errors.Is(err, errs.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
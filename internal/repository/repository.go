@@ -3,46 +3,238 @@
 // It defines the Storage interface, which exposes methods for saving,
 // fetching, and listing orders, as well as managing the database connection.
 //
-// Currently, a Postgres implementation is provided under the internal/repository/postgres package.
+// Two implementations are provided: Postgres (internal/repository/postgres) and an embedded,
+// CGO-free SQLite (internal/repository/sqlite) for running without a database server.
+// The implementation is selected with the database.driver setting. Postgres orders can also be
+// spread over several databases by shard key (internal/repository/sharded, see database.shards).
 // The package also includes helper functions for connecting to the database
 // and configuring connection pool settings.
 package repository
 
 import (
+	"context"
 	"fmt"
+	"net"
+	"time"
 
 	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/configs"
 	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/models"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository/migrate"
 	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository/postgres"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository/sharded"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository/sqlite"
 	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/pkg/logger"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/schema"
 	"github.com/jmoiron/sqlx"
 )
 
 // Storage defines methods for interacting with order storage (DB).
+//
+// Implementations report failures using the error classes from the errs package
+// (not found, duplicate, conflict, constraint violation, unavailable, timeout).
+//
+// Every method except Close accepts a context, so callers control cancellation and deadlines.
+// If the context has no deadline, the configured default query timeout is applied.
+//
+// SaveOrders reports the outcome of every order of a batch separately;
+// its error means the batch as a whole could not be saved.
+//
+// The message an order was received in (models.Order.Ingest) is stored along with it, in the same transaction;
+// GetOrderProvenance returns it.
+//
+// AmendOrder stores the changes made to an order, if it is still at the version the changes were made to
+// (otherwise errs.ErrConflict), and records the amendment in the same transaction.
+//
+// ErasePersonalData replaces the personal data in every order of a customer with irreversible placeholders
+// and writes the compliance record of the erasure. It fills in the orders that were erased.
+//
+// Order reads may be served by read replicas; CheckReplicas checks them and updates
+// which of them serve reads. It returns nil if there are none. GetOrderFromPrimary never reads
+// from a replica, for callers that need the latest version of an order (e.g. to amend it).
 type Storage interface {
-	SaveOrder(order *models.Order) error
-	GetOrder(id string) (*models.Order, error)
-	GetOrders(amount ...int) ([]*models.Order, error)
-	Ping() error
+	SaveOrder(ctx context.Context, order *models.Order) error
+	SaveOrders(ctx context.Context, orders []*models.Order) ([]error, error)
+	GetOrder(ctx context.Context, id string) (*models.Order, error)
+	GetOrderFromPrimary(ctx context.Context, id string) (*models.Order, error)
+	GetOrderByTrackNumber(ctx context.Context, trackNumber string) (*models.Order, error)
+	GetOrders(ctx context.Context, amount ...int) ([]*models.Order, error)
+	ListOrders(ctx context.Context, query models.OrderQuery) (models.OrderPage, error)
+	GetCustomerStats(ctx context.Context, customerID string) (models.CustomerStats, error)
+	UpdateItemStatus(ctx context.Context, update models.StatusUpdate) error
+	GetOrderHistory(ctx context.Context, orderUID string) (models.OrderHistory, error)
+	GetOrderProvenance(ctx context.Context, orderUID string) (models.OrderProvenance, error)
+	AmendOrder(ctx context.Context, order *models.Order, amendment models.OrderAmendment) error
+	ErasePersonalData(ctx context.Context, erasure *models.PersonalDataErasure) error
+	ClaimOutbox(ctx context.Context, limit int, lease time.Duration) ([]models.OutboxEvent, error)
+	MarkOutboxSent(ctx context.Context, id int64) error
+	MarkOutboxFailed(ctx context.Context, id int64, retryAt time.Time, reason string) error
+	PurgeOutbox(ctx context.Context, sentBefore time.Time) (int64, error)
+	CheckReplicas(ctx context.Context) []models.ReplicaStatus
+	Ping(ctx context.Context) error
 	Close()
 }
 
-// NewStorage wraps a specific storage implementation (Postgres) into the Storage interface.
-func NewStorage(db *sqlx.DB, logger logger.Logger) Storage {
-	return postgres.NewStorage(db, logger)
+/*
+Archiver manages the date-based partitions of the order tables. It is implemented by the Postgres
+storage of a single database; SQLite and sharded storage don't partition orders.
+
+EnsurePartitions creates the monthly partitions covering the given period.
+OrderPartitions lists them, oldest first, followed by the default partition.
+ArchivePartition hands the orders of a partition dated before the given time to write,
+calls seal once they are all written and then removes them from the database.
+*/
+type Archiver interface {
+	EnsurePartitions(ctx context.Context, from, to time.Time) error
+	OrderPartitions(ctx context.Context) ([]models.Partition, error)
+	ArchivePartition(ctx context.Context, partition models.Partition, before time.Time,
+		write func(order models.OrderSnapshot) error, seal func() error) (int, error)
+}
+
+// NewStorage wraps the storage implementation selected by config.Driver into the Storage interface.
+// Any driver other than sqlite is served by the Postgres implementation, which spreads order reads over replicas.
+func NewStorage(db *sqlx.DB, config configs.Database, logger logger.Logger, replicas ...postgres.Replica) Storage {
+	if config.Driver == sqlite.DriverName {
+		return sqlite.NewStorage(db, config, logger)
+	}
+	return postgres.NewStorage(db, config, logger, replicas...)
+}
+
+// NewShardedStorage returns a Storage that spreads orders over the given shards,
+// keeping the order-to-shard lookup table in db. See the sharded package.
+func NewShardedStorage(db *sqlx.DB, shards []sharded.Shard, config configs.Database, logger logger.Logger) (Storage, error) {
+	storage, err := sharded.NewStorage(db, shards, config, logger)
+	if err != nil {
+		return nil, err
+	}
+	return storage, nil
+}
+
+// NewMigrator returns a Migrator with the embedded migrations for the database selected by config.Driver.
+func NewMigrator(db *sqlx.DB, config configs.Database) (*migrate.Migrator, error) {
+	if config.Driver == sqlite.DriverName {
+		return migrate.New(db, schema.SQLite)
+	}
+	return migrate.New(db, schema.Postgres)
+}
+
+/*
+CheckSchema makes sure the database schema is at the version the binary expects.
+
+An embedded SQLite database is owned by the service, so its pending migrations are applied first.
+A Postgres schema is only checked: it is migrated explicitly with "wb-service migrate".
+Returns migrate.ErrVersionMismatch or migrate.ErrDirty if the service must not start.
+*/
+func CheckSchema(ctx context.Context, db *sqlx.DB, config configs.Database) error {
+	migrator, err := NewMigrator(db, config)
+	if err != nil {
+		return err
+	}
+	if config.Driver == sqlite.DriverName {
+		if err := migrator.Up(ctx); err != nil {
+			return err
+		}
+	}
+	return migrator.Check(ctx)
 }
 
 // ConnectDB establishes a connection to the database using the given configuration.
 // Configures connection pool parameters and verifies connectivity with Ping.
+//
+// For the sqlite driver, config.DBName is the path to the database file, which is created if it doesn't exist yet.
 func ConnectDB(config configs.Database) (*sqlx.DB, error) {
-	db, err := sqlx.Open(config.Driver, fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
-		config.Host, config.Port, config.Username, config.Password, config.DBName, config.SSLMode))
+	if config.Driver == sqlite.DriverName {
+		return sqlite.Connect(config)
+	}
+	db, err := openPostgres(config, config.Host, config.Port)
 	if err != nil {
-		return nil, fmt.Errorf("database driver not found or DSN invalid: %v", err)
+		return nil, err
 	}
 	if err = db.Ping(); err != nil {
 		return nil, fmt.Errorf("database ping failed: %v", err)
 	}
+	return db, nil
+}
+
+/*
+ConnectReplicas opens connections to the read replicas listed in config.Replicas.
+
+Replicas are not pinged: one that is down must not keep the service from starting,
+it just stays out of the read rotation until a health check finds it reachable.
+An embedded sqlite database can't have replicas, so listing any is an error.
+*/
+func ConnectReplicas(config configs.Database) ([]postgres.Replica, error) {
+	if len(config.Replicas) == 0 {
+		return nil, nil
+	}
+	if config.Driver == sqlite.DriverName {
+		return nil, fmt.Errorf("read replicas are not supported by the %s driver", sqlite.DriverName)
+	}
+	replicas := make([]postgres.Replica, 0, len(config.Replicas))
+	for _, address := range config.Replicas {
+		host, port, err := net.SplitHostPort(address)
+		if err != nil {
+			host, port = address, config.Port
+		}
+		db, err := openPostgres(config, host, port)
+		if err != nil {
+			for _, replica := range replicas {
+				_ = replica.DB.Close()
+			}
+			return nil, fmt.Errorf("replica %s: %v", address, err)
+		}
+		replicas = append(replicas, postgres.Replica{Name: net.JoinHostPort(host, port), DB: db})
+	}
+	return replicas, nil
+}
+
+/*
+ConnectShards connects to every database listed in config.Shards and verifies connectivity with Ping.
+Returns no shards if sharding is not configured.
+
+Sharding is only supported by Postgres and can't be combined with read replicas.
+*/
+func ConnectShards(config configs.Database) ([]sharded.Shard, error) {
+	if len(config.Shards) == 0 {
+		return nil, nil
+	}
+	if config.Driver == sqlite.DriverName {
+		return nil, fmt.Errorf("sharding is not supported by the %s driver", sqlite.DriverName)
+	}
+	if len(config.Replicas) > 0 {
+		return nil, fmt.Errorf("sharding can't be combined with read replicas")
+	}
+	shards := make([]sharded.Shard, 0, len(config.Shards))
+	closeAll := func() {
+		for _, shard := range shards {
+			_ = shard.DB.Close()
+		}
+	}
+	for _, shard := range config.Shards {
+		db, err := sqlx.Open(config.Driver, shard.DSN)
+		if err != nil {
+			closeAll()
+			return nil, fmt.Errorf("shard %s: database driver not found or DSN invalid: %v", shard.Name, err)
+		}
+		db.SetMaxOpenConns(config.MaxOpenConns)
+		db.SetMaxIdleConns(config.MaxIdleConns)
+		db.SetConnMaxLifetime(config.ConnMaxLifetime)
+		db.SetConnMaxIdleTime(config.ConnMaxIdleTime)
+		shards = append(shards, sharded.Shard{Name: shard.Name, From: shard.From, To: shard.To, DB: db})
+		if err := db.Ping(); err != nil {
+			closeAll()
+			return nil, fmt.Errorf("shard %s: database ping failed: %v", shard.Name, err)
+		}
+	}
+	return shards, nil
+}
+
+// openPostgres opens a pool of connections to the Postgres server at host:port with the configured credentials and pool parameters.
+func openPostgres(config configs.Database, host, port string) (*sqlx.DB, error) {
+	db, err := sqlx.Open(config.Driver, fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
+		host, port, config.Username, config.Password, config.DBName, config.SSLMode))
+	if err != nil {
+		return nil, fmt.Errorf("database driver not found or DSN invalid: %v", err)
+	}
 	db.SetMaxOpenConns(config.MaxOpenConns)
 	db.SetMaxIdleConns(config.MaxIdleConns)
 	db.SetConnMaxLifetime(config.ConnMaxLifetime)
@@ -1,6 +1,7 @@
 package repository_test
 
 import (
+	"context"
 	"testing"
 
 	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/cmd/producer/order"
@@ -39,9 +40,9 @@ func TestConnectDB_Success_Integration(t *testing.T) {
 	}
 	defer func() { _ = db.Close() }()
 
-	ps := postgres.NewStorage(db, logger)
+	ps := postgres.NewStorage(db, configs.Database{}, logger)
 
-	if err := ps.Ping(); err != nil {
+	if err := ps.Ping(context.Background()); err != nil {
 		t.Fatalf("Ping failed: %v", err)
 	}
 
@@ -59,13 +60,13 @@ func TestPostgresStorer_SaveOrder_Integration(t *testing.T) {
 	defer func() { _ = db.Close() }()
 
 	logger := mock_logger.NewMockLogger(gomock.NewController(t))
-	ps := repository.NewStorage(db, logger)
+	ps := repository.NewStorage(db, configs.Database{}, logger)
 
 	order := order.CreateOrder(logger)
 	order.OrderUID = "1"
 	order.Payment.Transaction = order.OrderUID
 
-	if err := ps.SaveOrder(&order); err != nil {
+	if err := ps.SaveOrder(context.Background(), &order); err != nil {
 		t.Fatalf("SaveOrder failed: %v", err)
 	}
 }
@@ -81,9 +82,9 @@ func TestPostgresStorer_GetOrder_Integration(t *testing.T) {
 	defer func() { _ = db.Close() }()
 
 	logger := mock_logger.NewMockLogger(gomock.NewController(t))
-	ps := repository.NewStorage(db, logger)
+	ps := repository.NewStorage(db, configs.Database{}, logger)
 
-	order, err := ps.GetOrder("1")
+	order, err := ps.GetOrder(context.Background(), "1")
 	if err != nil {
 		t.Fatalf("GetOrder failed: %v", err)
 	}
@@ -103,15 +104,15 @@ func TestPostgresStorer_SaveAndGetOrder_Integration(t *testing.T) {
 	}
 	defer func() { _ = db.Close() }()
 	logger := mock_logger.NewMockLogger(gomock.NewController(t))
-	ps := repository.NewStorage(db, logger)
+	ps := repository.NewStorage(db, configs.Database{}, logger)
 
 	order := order.CreateOrder(logger)
 
-	if err := ps.SaveOrder(&order); err != nil {
+	if err := ps.SaveOrder(context.Background(), &order); err != nil {
 		t.Fatalf("SaveOrder failed: %v", err)
 	}
 
-	gotOrder, err := ps.GetOrder(order.OrderUID)
+	gotOrder, err := ps.GetOrder(context.Background(), order.OrderUID)
 	if err != nil {
 		t.Fatalf("GetOrder failed: %v", err)
 	}
@@ -1,6 +1,8 @@
 package repository_test
 
 import (
+	"context"
+	"path/filepath"
 	"testing"
 
 	"github.com/DATA-DOG/go-sqlmock"
@@ -8,6 +10,7 @@ import (
 	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/models"
 	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository"
 	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository/postgres"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository/sqlite"
 	mock_logger "github.com/Pur1st2EpicONE/WBTECH-sample-microservice/pkg/logger/mocks"
 	"github.com/golang/mock/gomock"
 	"github.com/jmoiron/sqlx"
@@ -20,7 +23,7 @@ func TestPostgresStorer_SaveOrder_Success(t *testing.T) {
 	}
 	defer func() { _ = db.Close() }()
 	logger := mock_logger.NewMockLogger(gomock.NewController(t))
-	ps := postgres.NewStorage(sqlx.NewDb(db, "postgres"), logger)
+	ps := postgres.NewStorage(sqlx.NewDb(db, "postgres"), configs.Database{}, logger)
 
 	order := &models.Order{
 		OrderUID: "123",
@@ -42,6 +45,7 @@ func TestPostgresStorer_SaveOrder_Success(t *testing.T) {
 			sqlmock.AnyArg(),
 			sqlmock.AnyArg(),
 			sqlmock.AnyArg(),
+			sqlmock.AnyArg(),
 			sqlmock.AnyArg()).
 		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
 
@@ -54,7 +58,9 @@ func TestPostgresStorer_SaveOrder_Success(t *testing.T) {
 			sqlmock.AnyArg(),
 			sqlmock.AnyArg(),
 			sqlmock.AnyArg(),
-			sqlmock.AnyArg()).
+			sqlmock.AnyArg(),
+			order.DateCreated,
+			nil, nil, nil).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
 	mock.ExpectExec("INSERT INTO payments").
@@ -69,7 +75,8 @@ func TestPostgresStorer_SaveOrder_Success(t *testing.T) {
 			sqlmock.AnyArg(),
 			sqlmock.AnyArg(),
 			sqlmock.AnyArg(),
-			sqlmock.AnyArg()).
+			sqlmock.AnyArg(),
+			order.DateCreated).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
 	mock.ExpectExec("INSERT INTO items").
@@ -85,12 +92,17 @@ func TestPostgresStorer_SaveOrder_Success(t *testing.T) {
 			sqlmock.AnyArg(),
 			sqlmock.AnyArg(),
 			sqlmock.AnyArg(),
-			sqlmock.AnyArg()).
+			sqlmock.AnyArg(),
+			order.DateCreated).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	mock.ExpectExec("INSERT INTO outbox").
+		WithArgs(models.EventOrderAccepted, order.OrderUID, sqlmock.AnyArg(), sqlmock.AnyArg()).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
 	mock.ExpectCommit()
 
-	if err := ps.SaveOrder(order); err != nil {
+	if err := ps.SaveOrder(context.Background(), order); err != nil {
 		t.Fatalf("SaveOrder failed: %v", err)
 	}
 
@@ -132,3 +144,74 @@ func TestConnectDB_PingFail(t *testing.T) {
 		t.Fatal("expected ping to fail, got nil")
 	}
 }
+
+func TestConnectDB_SQLite(t *testing.T) {
+	config := configs.Database{Driver: "sqlite", DBName: filepath.Join(t.TempDir(), "orders.db")}
+
+	db, err := repository.ConnectDB(config)
+	if err != nil {
+		t.Fatalf("ConnectDB failed: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+	if err := repository.CheckSchema(context.Background(), db, config); err != nil {
+		t.Fatalf("CheckSchema failed: %v", err)
+	}
+
+	storage := repository.NewStorage(db, config, mock_logger.NewMockLogger(gomock.NewController(t)))
+	if _, ok := storage.(*sqlite.Storage); !ok {
+		t.Fatalf("expected *sqlite.Storage, got %T", storage)
+	}
+
+	order := &models.Order{
+		OrderUID:    "123",
+		TrackNumber: "TRACK",
+		Items:       []models.Item{{ChrtID: 1, TrackNumber: "TRACK", Name: "Cool hat", Price: 100}},
+		Delivery:    models.Delivery{Name: "Aboba"},
+		Payment:     models.Payment{Transaction: "123", Amount: 100},
+	}
+	if err := storage.SaveOrder(context.Background(), order); err != nil {
+		t.Fatalf("SaveOrder failed: %v", err)
+	}
+	got, err := storage.GetOrder(context.Background(), order.OrderUID)
+	if err != nil {
+		t.Fatalf("GetOrder failed: %v", err)
+	}
+	if len(got.Items) != 1 || got.Payment.Amount != 100 {
+		t.Fatalf("unexpected order: %+v", got)
+	}
+}
+
+func TestNewStorage_DefaultsToPostgres(t *testing.T) {
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create mock db: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	storage := repository.NewStorage(sqlx.NewDb(db, "postgres"), configs.Database{}, nil)
+	if _, ok := storage.(*postgres.Storage); !ok {
+		t.Fatalf("expected *postgres.Storage, got %T", storage)
+	}
+}
+
+func TestConnectReplicas(t *testing.T) {
+	config := configs.Database{Driver: "postgres", Port: "5432", Replicas: []string{"replica-1", "replica-2:5433"}}
+
+	replicas, err := repository.ConnectReplicas(config)
+	if err != nil {
+		t.Fatalf("ConnectReplicas failed: %v", err)
+	}
+	defer func() {
+		for _, replica := range replicas {
+			_ = replica.DB.Close()
+		}
+	}()
+	if len(replicas) != 2 || replicas[0].Name != "replica-1:5432" || replicas[1].Name != "replica-2:5433" {
+		t.Fatalf("unexpected replicas: %+v", replicas)
+	}
+
+	config.Driver = sqlite.DriverName
+	if _, err := repository.ConnectReplicas(config); err == nil {
+		t.Fatal("expected replicas of an embedded database to be rejected")
+	}
+}
@@ -0,0 +1,346 @@
+package sharded
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/models"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository/errs"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository/postgres"
+)
+
+/*
+SaveOrder saves an order on the shard that owns its shard key.
+
+The shard is recorded in the lookup table first. An order that is already recorded is saved on its recorded shard,
+so a redelivery is recognized as a duplicate even if the ranges have changed since. Orders whose shard key
+is not a number or is not owned by any shard are rejected with errs.ErrConstraint.
+*/
+func (s *Storage) SaveOrder(ctx context.Context, order *models.Order) error {
+	owner, err := s.owner(order.ShardKey)
+	if err != nil {
+		return fmt.Errorf("order %s: %w", order.OrderUID, err)
+	}
+	assigned, err := s.directory.AssignShards(ctx, map[string]string{order.OrderUID: owner.name})
+	if err != nil {
+		return fmt.Errorf("failed to assign a shard to order %s: %w", order.OrderUID, err)
+	}
+	sh, err := s.named(assigned[order.OrderUID])
+	if err != nil {
+		return err
+	}
+	return sh.storage.SaveOrder(ctx, order)
+}
+
+/*
+SaveOrders saves a batch of orders, split into one batch per shard saved concurrently.
+
+The outcome of every order is reported just like by postgres.Storage.SaveOrders.
+The error means that at least one shard could not save its part of the batch;
+the other parts may have been saved, which is fine since saving the batch again is idempotent.
+*/
+func (s *Storage) SaveOrders(ctx context.Context, orders []*models.Order) ([]error, error) {
+	results := make([]error, len(orders))
+	owners := make(map[string]string, len(orders))
+	for i, order := range orders {
+		owner, err := s.owner(order.ShardKey)
+		if err != nil {
+			results[i] = fmt.Errorf("order %s: %w", order.OrderUID, err)
+			continue
+		}
+		owners[order.OrderUID] = owner.name
+	}
+	assigned, err := s.directory.AssignShards(ctx, owners)
+	if err != nil {
+		return nil, fmt.Errorf("failed to assign shards to orders: %w", err)
+	}
+
+	positions := make(map[*shard][]int) // positions in orders of the orders saved by each shard
+	for i, order := range orders {
+		if results[i] != nil {
+			continue
+		}
+		sh, err := s.named(assigned[order.OrderUID])
+		if err != nil {
+			return nil, err
+		}
+		positions[sh] = append(positions[sh], i)
+	}
+	shards := make([]*shard, 0, len(positions))
+	for _, sh := range s.shards {
+		if len(positions[sh]) > 0 {
+			shards = append(shards, sh)
+		}
+	}
+
+	shardResults, failures := scatter(shards, func(sh *shard) ([]error, error) {
+		batch := make([]*models.Order, len(positions[sh]))
+		for j, i := range positions[sh] {
+			batch[j] = orders[i]
+		}
+		return sh.storage.SaveOrders(ctx, batch)
+	})
+	if err := errors.Join(failures...); err != nil {
+		return nil, err
+	}
+	for k, sh := range shards {
+		for j, i := range positions[sh] {
+			results[i] = shardResults[k][j]
+		}
+	}
+	return results, nil
+}
+
+// GetOrder retrieves a single order by its UID from the shard that holds it.
+// Returns errs.ErrNotFound if there is no order with such UID.
+func (s *Storage) GetOrder(ctx context.Context, orderUID string) (*models.Order, error) {
+	return onOrderShard(ctx, s, orderUID, func(storage *postgres.Storage) (*models.Order, error) {
+		return storage.GetOrder(ctx, orderUID)
+	})
+}
+
+// GetOrderFromPrimary retrieves a single order like GetOrder. Shards have no read replicas.
+func (s *Storage) GetOrderFromPrimary(ctx context.Context, orderUID string) (*models.Order, error) {
+	return s.GetOrder(ctx, orderUID)
+}
+
+// GetOrderByTrackNumber looks for an order with the given track number on every shard.
+// Returns errs.ErrNotFound if there is no order with such track number.
+func (s *Storage) GetOrderByTrackNumber(ctx context.Context, trackNumber string) (*models.Order, error) {
+	return first(scatter(s.shards, func(sh *shard) (*models.Order, error) {
+		return sh.storage.GetOrderByTrackNumber(ctx, trackNumber)
+	}))
+}
+
+// GetOrders retrieves orders from every shard, up to amount orders in total if it is given.
+func (s *Storage) GetOrders(ctx context.Context, amount ...int) ([]*models.Order, error) {
+	shardOrders, failures := scatter(s.shards, func(sh *shard) ([]*models.Order, error) {
+		return sh.storage.GetOrders(ctx, amount...)
+	})
+	if err := errors.Join(failures...); err != nil {
+		return nil, err
+	}
+	orders := slices.Concat(shardOrders...)
+	if len(amount) > 0 && amount[0] > 0 && len(orders) > amount[0] {
+		orders = orders[:amount[0]]
+	}
+	return orders, nil
+}
+
+// GetCustomerStats aggregates the orders of a customer over every shard.
+// Returns errs.ErrNotFound if the customer has no orders.
+func (s *Storage) GetCustomerStats(ctx context.Context, customerID string) (models.CustomerStats, error) {
+	shardStats, failures := scatter(s.shards, func(sh *shard) (models.CustomerStats, error) {
+		return sh.storage.GetCustomerStats(ctx, customerID)
+	})
+	stats := models.CustomerStats{TotalSpend: make(map[string]float64)}
+	for i, err := range failures {
+		if errors.Is(err, errs.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return models.CustomerStats{}, err
+		}
+		stats.OrderCount += shardStats[i].OrderCount
+		for currency, total := range shardStats[i].TotalSpend {
+			stats.TotalSpend[currency] += total
+		}
+		if shardStats[i].LastOrderDate.After(stats.LastOrderDate) {
+			stats.LastOrderDate = shardStats[i].LastOrderDate
+		}
+	}
+	if stats.OrderCount == 0 {
+		return models.CustomerStats{}, errs.ErrNotFound
+	}
+	return stats, nil
+}
+
+// UpdateItemStatus applies a status change on the shard that holds the order.
+func (s *Storage) UpdateItemStatus(ctx context.Context, update models.StatusUpdate) error {
+	_, err := onOrderShard(ctx, s, update.OrderUID, func(storage *postgres.Storage) (struct{}, error) {
+		return struct{}{}, storage.UpdateItemStatus(ctx, update)
+	})
+	return err
+}
+
+// GetOrderHistory returns the status timeline of an order from the shard that holds it.
+func (s *Storage) GetOrderHistory(ctx context.Context, orderUID string) (models.OrderHistory, error) {
+	return onOrderShard(ctx, s, orderUID, func(storage *postgres.Storage) (models.OrderHistory, error) {
+		return storage.GetOrderHistory(ctx, orderUID)
+	})
+}
+
+// AmendOrder stores the changes made to an order on the shard that holds it.
+func (s *Storage) AmendOrder(ctx context.Context, order *models.Order, amendment models.OrderAmendment) error {
+	_, err := onOrderShard(ctx, s, order.OrderUID, func(storage *postgres.Storage) (struct{}, error) {
+		return struct{}{}, storage.AmendOrder(ctx, order, amendment)
+	})
+	return err
+}
+
+// GetOrderProvenance returns the message an order was received in from the shard that holds it.
+func (s *Storage) GetOrderProvenance(ctx context.Context, orderUID string) (models.OrderProvenance, error) {
+	return onOrderShard(ctx, s, orderUID, func(storage *postgres.Storage) (models.OrderProvenance, error) {
+		return storage.GetOrderProvenance(ctx, orderUID)
+	})
+}
+
+/*
+ErasePersonalData replaces the personal data of a customer on every shard and records the erasure in the main database.
+If any shard fails, the erasure is not recorded; it can simply be repeated, as erasing orders again changes nothing.
+*/
+func (s *Storage) ErasePersonalData(ctx context.Context, erasure *models.PersonalDataErasure) error {
+	shardOrders, failures := scatter(s.shards, func(sh *shard) ([]string, error) {
+		return sh.storage.EraseCustomerOrders(ctx, erasure.CustomerID)
+	})
+	if err := errors.Join(failures...); err != nil {
+		return err
+	}
+	record := *erasure
+	record.OrderUIDs = append([]string{}, slices.Concat(shardOrders...)...)
+	slices.Sort(record.OrderUIDs)
+	if err := s.directory.RecordErasure(ctx, record); err != nil {
+		return err
+	}
+	erasure.OrderUIDs = record.OrderUIDs
+	return nil
+}
+
+/*
+ListOrders returns a single page of orders that match the query filter, merged from every shard.
+
+Every shard is asked for a full page, and the pages are merged in the requested sort order.
+The cursor holds the position reached within every shard, so pagination stays keyset-based:
+a shard whose page was only partly used is asked once more for just that part, to get the cursor
+right after it. Orders with equal sort keys on different shards are returned in shard order.
+*/
+func (s *Storage) ListOrders(ctx context.Context, query models.OrderQuery) (models.OrderPage, error) {
+	if query.Sort == "" {
+		query.Sort = models.SortDateDesc
+	}
+	if query.Limit <= 0 {
+		return models.OrderPage{}, fmt.Errorf("%w: limit must be positive, got %d", errs.ErrInvalidArgument, query.Limit)
+	}
+	cursors, err := s.decodeCursor(query.Cursor)
+	if err != nil {
+		return models.OrderPage{}, err
+	}
+	shards := make([]*shard, 0, len(cursors))
+	for _, sh := range s.shards {
+		if _, ok := cursors[sh.name]; ok {
+			shards = append(shards, sh)
+		}
+	}
+
+	pages, failures := scatter(shards, func(sh *shard) (models.OrderPage, error) {
+		shardQuery := query
+		shardQuery.Cursor = cursors[sh.name]
+		return sh.storage.ListOrders(ctx, shardQuery)
+	})
+	if err := errors.Join(failures...); err != nil {
+		return models.OrderPage{}, err
+	}
+
+	type listed struct {
+		order *models.Order
+		shard int // position in shards
+	}
+	var merged []listed
+	for i, page := range pages {
+		for _, order := range page.Orders {
+			merged = append(merged, listed{order: order, shard: i})
+		}
+	}
+	slices.SortStableFunc(merged, func(a, b listed) int { return compareOrders(query.Sort, a.order, b.order) })
+	merged = merged[:min(len(merged), query.Limit)]
+
+	page := models.OrderPage{Orders: make([]*models.Order, len(merged))}
+	used := make([]int, len(shards))
+	for i, entry := range merged {
+		page.Orders[i] = entry.order
+		used[entry.shard]++
+	}
+
+	next := make(map[string]string)
+	for i, sh := range shards {
+		switch {
+		case used[i] == len(pages[i].Orders):
+			if pages[i].NextCursor != "" {
+				next[sh.name] = pages[i].NextCursor
+			}
+		case used[i] == 0:
+			next[sh.name] = cursors[sh.name]
+		default:
+			shardQuery := query
+			shardQuery.Cursor, shardQuery.Limit = cursors[sh.name], used[i]
+			partial, err := sh.storage.ListOrders(ctx, shardQuery)
+			if err != nil {
+				return models.OrderPage{}, err
+			}
+			next[sh.name] = partial.NextCursor
+		}
+	}
+	if len(next) > 0 {
+		page.NextCursor = encodeCursor(next)
+	}
+	return page, nil
+}
+
+// compareOrders compares two orders in the given sort order.
+func compareOrders(sort models.OrderSort, a, b *models.Order) int {
+	switch sort {
+	case models.SortDateAsc:
+		return a.DateCreated.Compare(b.DateCreated)
+	case models.SortAmountDesc:
+		return compareAmounts(b.Payment.Amount, a.Payment.Amount)
+	case models.SortAmountAsc:
+		return compareAmounts(a.Payment.Amount, b.Payment.Amount)
+	}
+	return b.DateCreated.Compare(a.DateCreated)
+}
+
+func compareAmounts(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// encodeCursor encodes the cursors within shards into an opaque listing cursor.
+// Shards missing from it have no more orders to list.
+func encodeCursor(cursors map[string]string) string {
+	raw, _ := json.Marshal(cursors) // can't fail: a map of strings
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// decodeCursor returns the cursor within every shard that still has orders to list.
+// An empty cursor starts every shard from the beginning.
+func (s *Storage) decodeCursor(encoded string) (map[string]string, error) {
+	cursors := make(map[string]string, len(s.shards))
+	if encoded == "" {
+		for _, sh := range s.shards {
+			cursors[sh.name] = ""
+		}
+		return cursors, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor", errs.ErrInvalidArgument)
+	}
+	if err := json.Unmarshal(raw, &cursors); err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor", errs.ErrInvalidArgument)
+	}
+	for name := range cursors {
+		if _, err := s.named(name); err != nil {
+			return nil, fmt.Errorf("%w: cursor refers to unknown shard %q", errs.ErrInvalidArgument, name)
+		}
+	}
+	return cursors, nil
+}
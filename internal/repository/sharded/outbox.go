@@ -0,0 +1,80 @@
+package sharded
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/models"
+)
+
+// Every shard has an outbox of its own. Event IDs of different shards overlap, so the IDs
+// handed out by ClaimOutbox carry the shard as well: id = shard-local id * number of shards + shard position.
+
+/*
+ClaimOutbox claims up to limit due events from the outboxes of the shards.
+
+Every claim starts with the next shard in turn, so a busy shard can't hold back the events of the others.
+A shard that fails to claim is skipped and logged; the error is returned only if no shard could be claimed from.
+*/
+func (s *Storage) ClaimOutbox(ctx context.Context, limit int, lease time.Duration) ([]models.OutboxEvent, error) {
+	n := len(s.shards)
+	start := int(s.nextClaim.Add(1) - 1)
+	var events []models.OutboxEvent
+	var failures []error
+	for k := range n {
+		if len(events) >= limit {
+			break
+		}
+		i := (start + k) % n
+		claimed, err := s.shards[i].storage.ClaimOutbox(ctx, limit-len(events), lease)
+		if err != nil {
+			s.logger.LogError(fmt.Sprintf("sharded — failed to claim outbox events of shard %s", s.shards[i].name), err, "layer", "repository.sharded")
+			failures = append(failures, err)
+			continue
+		}
+		for _, event := range claimed {
+			event.ID = event.ID*int64(n) + int64(i)
+			events = append(events, event)
+		}
+	}
+	if len(failures) == n {
+		return nil, errors.Join(failures...)
+	}
+	return events, nil
+}
+
+// MarkOutboxSent marks an event claimed by ClaimOutbox as published.
+func (s *Storage) MarkOutboxSent(ctx context.Context, id int64) error {
+	sh, localID := s.eventShard(id)
+	return sh.storage.MarkOutboxSent(ctx, localID)
+}
+
+// MarkOutboxFailed schedules an event claimed by ClaimOutbox for another attempt at retryAt.
+func (s *Storage) MarkOutboxFailed(ctx context.Context, id int64, retryAt time.Time, reason string) error {
+	sh, localID := s.eventShard(id)
+	return sh.storage.MarkOutboxFailed(ctx, localID, retryAt, reason)
+}
+
+// PurgeOutbox deletes the events published before sentBefore from the outboxes of every shard.
+// Shards that fail are skipped; their errors are returned along with the number of events deleted from the others.
+func (s *Storage) PurgeOutbox(ctx context.Context, sentBefore time.Time) (int64, error) {
+	var purged int64
+	var failures []error
+	for _, sh := range s.shards {
+		n, err := sh.storage.PurgeOutbox(ctx, sentBefore)
+		if err != nil {
+			failures = append(failures, fmt.Errorf("shard %s: %w", sh.name, err))
+			continue
+		}
+		purged += n
+	}
+	return purged, errors.Join(failures...)
+}
+
+// eventShard returns the shard of an event ID handed out by ClaimOutbox along with its shard-local ID.
+func (s *Storage) eventShard(id int64) (*shard, int64) {
+	n := int64(len(s.shards))
+	return s.shards[id%n], id / n
+}
@@ -0,0 +1,113 @@
+package sharded
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/models"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository/errs"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository/postgres"
+)
+
+// RebalanceStats summarizes a rebalance run.
+type RebalanceStats struct {
+	Scanned   int // orders looked at
+	Misplaced int // orders held by a shard that doesn't own their shard key
+	Moved     int // misplaced orders moved to their owner
+	Unowned   int // orders whose shard key no shard owns; they are left where they are
+}
+
+/*
+Rebalance moves every order to the shard that owns its shard key under the current ranges.
+
+It is meant to be run after the ranges have changed, while the service keeps running with the new ranges:
+
+  - The shards are scanned page by page (batchSize orders at a time), so no long locks are held.
+  - Every misplaced order is locked on its old shard, copied to its owner along with its status history,
+    switched over in the lookup table and only then deleted from the old shard. Reads find the order
+    in one place or the other throughout, and status updates arriving meanwhile wait for the move
+    and then follow the lookup table to the new shard.
+  - Orders that are in the right place but missing from the lookup table are recorded there.
+  - An interrupted run leaves nothing inconsistent and can simply be started again.
+
+With dryRun, misplaced orders are only counted.
+*/
+func (s *Storage) Rebalance(ctx context.Context, batchSize int, dryRun bool) (RebalanceStats, error) {
+	var stats RebalanceStats
+	for _, sh := range s.shards {
+		before := stats
+		var afterID int64
+		for {
+			keys, err := sh.storage.OrderKeys(ctx, afterID, batchSize)
+			if err != nil {
+				return stats, fmt.Errorf("failed to scan shard %s: %w", sh.name, err)
+			}
+			if len(keys) == 0 {
+				break
+			}
+			afterID = keys[len(keys)-1].ID
+			if err := s.rebalanceBatch(ctx, sh, keys, dryRun, &stats); err != nil {
+				return stats, err
+			}
+		}
+		s.logger.LogInfo(fmt.Sprintf("sharded — shard %s rebalanced", sh.name), "scanned", fmt.Sprintf("%d", stats.Scanned-before.Scanned),
+			"misplaced", fmt.Sprintf("%d", stats.Misplaced-before.Misplaced), "moved", fmt.Sprintf("%d", stats.Moved-before.Moved), "layer", "repository.sharded")
+	}
+	return stats, nil
+}
+
+// rebalanceBatch moves the misplaced orders of a page of shard sh and records the others in the lookup table.
+func (s *Storage) rebalanceBatch(ctx context.Context, sh *shard, keys []postgres.OrderKey, dryRun bool, stats *RebalanceStats) error {
+	placed := make(map[string]string)
+	for _, key := range keys {
+		stats.Scanned++
+		owner, err := s.owner(key.ShardKey)
+		if err != nil {
+			s.logger.LogError(fmt.Sprintf("sharded — order on shard %s can't be rebalanced", sh.name), err, "orderUID", key.OrderUID, "layer", "repository.sharded")
+			stats.Unowned++
+			continue
+		}
+		if owner == sh {
+			placed[key.OrderUID] = sh.name
+			continue
+		}
+		stats.Misplaced++
+		if dryRun {
+			continue
+		}
+		err = sh.storage.MoveOrder(ctx, key.OrderUID, func(moved models.OrderSnapshot) error {
+			if err := s.applyErasures(ctx, &moved); err != nil {
+				return err
+			}
+			if err := owner.storage.ImportOrder(ctx, moved); err != nil && !errors.Is(err, errs.ErrDuplicate) {
+				return fmt.Errorf("failed to copy the order to shard %s: %w", owner.name, err)
+			}
+			return s.directory.SetShard(ctx, key.OrderUID, owner.name)
+		})
+		if errors.Is(err, errs.ErrNotFound) {
+			continue // deleted or moved meanwhile
+		}
+		if err != nil {
+			return fmt.Errorf("failed to move order %s from shard %s to shard %s: %w", key.OrderUID, sh.name, owner.name, err)
+		}
+		stats.Moved++
+	}
+	if dryRun {
+		return nil
+	}
+	if _, err := s.directory.AssignShards(ctx, placed); err != nil {
+		return fmt.Errorf("failed to record the orders of shard %s: %w", sh.name, err)
+	}
+	return nil
+}
+
+// applyErasures erases the personal data of a moved order if its customer was erased after it was placed:
+// an erasure that ran while the order was on its way may have missed it on both shards.
+func (s *Storage) applyErasures(ctx context.Context, moved *models.OrderSnapshot) error {
+	erased, err := s.directory.CustomerErasedSince(ctx, moved.Order.CustomerID, moved.Order.DateCreated)
+	if err != nil || !erased {
+		return err
+	}
+	return moved.ErasePersonalData()
+}
@@ -0,0 +1,212 @@
+/*
+Package sharded provides a Storage implementation that spreads orders over several Postgres databases.
+
+Every shard owns a range of numeric shard keys (models.Order.ShardKey), and an order is written
+to the shard that owns its key. The shard of every order is recorded in the order_shards lookup table
+of the main database, which is all the main database holds. Reads by order UID go straight to the recorded
+shard; orders missing from the lookup table (e.g. saved before sharding was enabled) are looked for on
+every shard at once. Reads that are not bound to a single order scatter over all shards and merge the results.
+
+When the shard ranges change, Rebalance moves the orders to their new owners while the service keeps running.
+*/
+package sharded
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/configs"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/models"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository/errs"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository/postgres"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/pkg/logger"
+	"github.com/jmoiron/sqlx"
+)
+
+// Shard is a database that owns the orders whose shard key falls into [From, To].
+type Shard struct {
+	Name string
+	From int
+	To   int
+	DB   *sqlx.DB
+}
+
+// shard is a Shard wrapped into the Postgres storage.
+type shard struct {
+	name     string
+	from, to int
+	storage  *postgres.Storage
+}
+
+// Storage routes every operation to the shards that hold the orders it deals with.
+type Storage struct {
+	directory *postgres.Storage // main database with the order_shards lookup table
+	shards    []*shard          // ordered by range
+	logger    logger.Logger
+	nextClaim atomic.Uint64 // shard the next outbox claim starts with
+}
+
+/*
+NewStorage creates a sharded Storage on top of the main database and the given shards.
+
+Returns an error if there are no shards, if two of them share a name, or if their ranges are empty or overlap.
+Shard keys outside of every range can't be saved.
+*/
+func NewStorage(directory *sqlx.DB, shards []Shard, config configs.Database, logger logger.Logger) (*Storage, error) {
+	if len(shards) == 0 {
+		return nil, fmt.Errorf("no shards configured")
+	}
+	s := &Storage{directory: postgres.NewStorage(directory, config, logger), logger: logger}
+	names := make(map[string]bool, len(shards))
+	for _, sh := range shards {
+		if names[sh.Name] {
+			return nil, fmt.Errorf("shard name %q is used twice", sh.Name)
+		}
+		if sh.From > sh.To {
+			return nil, fmt.Errorf("shard %s has an empty range [%d, %d]", sh.Name, sh.From, sh.To)
+		}
+		names[sh.Name] = true
+		s.shards = append(s.shards, &shard{name: sh.Name, from: sh.From, to: sh.To, storage: postgres.NewStorage(sh.DB, config, logger)})
+	}
+	slices.SortFunc(s.shards, func(a, b *shard) int { return a.from - b.from })
+	for i := 1; i < len(s.shards); i++ {
+		if prev, next := s.shards[i-1], s.shards[i]; next.from <= prev.to {
+			return nil, fmt.Errorf("ranges of shards %s and %s overlap", prev.name, next.name)
+		}
+	}
+	return s, nil
+}
+
+// owner returns the shard that owns a shard key.
+// Returns errs.ErrConstraint if the key is not a number or no shard owns it, since such an order can never be saved.
+func (s *Storage) owner(shardKey string) (*shard, error) {
+	key, err := strconv.Atoi(strings.TrimSpace(shardKey))
+	if err != nil {
+		return nil, fmt.Errorf("shard key %q is not a number: %w", shardKey, errs.ErrConstraint)
+	}
+	for _, sh := range s.shards {
+		if key >= sh.from && key <= sh.to {
+			return sh, nil
+		}
+	}
+	return nil, fmt.Errorf("no shard owns shard key %d: %w", key, errs.ErrConstraint)
+}
+
+// named returns the shard with the given name, as recorded in the lookup table.
+func (s *Storage) named(name string) (*shard, error) {
+	for _, sh := range s.shards {
+		if sh.name == name {
+			return sh, nil
+		}
+	}
+	return nil, fmt.Errorf("order is recorded on shard %q, which is not configured", name)
+}
+
+// lookup returns the shard recorded for an order in the lookup table, or nil if the order is not recorded.
+func (s *Storage) lookup(ctx context.Context, orderUID string) (*shard, error) {
+	name, err := s.directory.LookupShard(ctx, orderUID)
+	if errors.Is(err, errs.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up the shard of order %s: %w", orderUID, err)
+	}
+	return s.named(name)
+}
+
+// scatter runs fn on every shard concurrently and returns the results and errors in shard order.
+func scatter[T any](shards []*shard, fn func(sh *shard) (T, error)) ([]T, []error) {
+	results := make([]T, len(shards))
+	failures := make([]error, len(shards))
+	var wg sync.WaitGroup
+	for i, sh := range shards {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], failures[i] = fn(sh)
+		}()
+	}
+	wg.Wait()
+	return results, failures
+}
+
+// first returns the result of the shard that found what was looked for.
+// Any error other than errs.ErrNotFound takes precedence; errs.ErrNotFound is returned if no shard found anything.
+func first[T any](results []T, failures []error) (T, error) {
+	var zero T
+	found := -1
+	for i, err := range failures {
+		switch {
+		case err == nil:
+			if found < 0 {
+				found = i
+			}
+		case !errors.Is(err, errs.ErrNotFound):
+			return zero, err
+		}
+	}
+	if found < 0 {
+		return zero, errs.ErrNotFound
+	}
+	return results[found], nil
+}
+
+/*
+onOrderShard runs fn on the shard that holds an order.
+
+That is the shard recorded in the lookup table or, for orders missing from it, every shard at once.
+If the recorded shard doesn't have the order, it may have just been moved by a rebalance,
+so the lookup table is checked once more and fn is run on the new shard.
+*/
+func onOrderShard[T any](ctx context.Context, s *Storage, orderUID string, fn func(storage *postgres.Storage) (T, error)) (T, error) {
+	var zero T
+	sh, err := s.lookup(ctx, orderUID)
+	if err != nil {
+		return zero, err
+	}
+	if sh == nil {
+		return first(scatter(s.shards, func(sh *shard) (T, error) { return fn(sh.storage) }))
+	}
+	result, err := fn(sh.storage)
+	if !errors.Is(err, errs.ErrNotFound) {
+		return result, err
+	}
+	moved, lookupErr := s.lookup(ctx, orderUID)
+	if lookupErr != nil || moved == nil || moved == sh {
+		return result, err
+	}
+	return fn(moved.storage)
+}
+
+// Ping checks the connections to the main database and every shard
+func (s *Storage) Ping(ctx context.Context) error {
+	if err := s.directory.Ping(ctx); err != nil {
+		return err
+	}
+	_, pingErrs := scatter(s.shards, func(sh *shard) (struct{}, error) {
+		if err := sh.storage.Ping(ctx); err != nil {
+			return struct{}{}, fmt.Errorf("shard %s: %w", sh.name, err)
+		}
+		return struct{}{}, nil
+	})
+	return errors.Join(pingErrs...)
+}
+
+// CheckReplicas returns nil: shards are not served by read replicas.
+func (s *Storage) CheckReplicas(ctx context.Context) []models.ReplicaStatus {
+	return nil
+}
+
+// Close closes the connections to every shard and to the main database
+func (s *Storage) Close() {
+	for _, sh := range s.shards {
+		sh.storage.Close()
+	}
+	s.directory.Close()
+}
@@ -0,0 +1,340 @@
+package sharded_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/configs"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/models"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository/errs"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository/sharded"
+	mock_logger "github.com/Pur1st2EpicONE/WBTECH-sample-microservice/pkg/logger/mocks"
+	"github.com/golang/mock/gomock"
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	orderColumns = []string{
+		"id", "order_uid", "track_number", "entry", "locale", "internal_signature", "customer_id",
+		"delivery_service", "shardkey", "sm_id", "date_created", "oof_shard", "version",
+		"name", "phone", "zip", "city", "address", "region", "email", "key_id",
+		"transaction", "request_id", "currency", "provider", "amount", "payment_dt",
+		"bank", "delivery_cost", "goods_total", "custom_fee",
+	}
+	itemColumns = []string{"order_id", "chrt_id", "track_number", "price", "rid", "name", "sale", "size", "total_price", "nm_id", "brand", "status"}
+)
+
+// testOrder is an order row returned by a mocked shard.
+type testOrder struct {
+	id       int
+	uid      string
+	shardKey string
+	created  time.Time
+}
+
+func orderRows(orders ...testOrder) *sqlmock.Rows {
+	rows := sqlmock.NewRows(orderColumns)
+	for _, o := range orders {
+		rows.AddRow(o.id, o.uid, "track-"+o.uid, "entry", "en", "sig", "customer",
+			"d_service", o.shardKey, 1, o.created, "oof", 1,
+			"name", "phone", "zip", "city", "address", "region", "email", nil,
+			o.uid, "req", "USD", "prov", 100, o.created, "bank", 10, 90, 0)
+	}
+	return rows
+}
+
+// testShards returns a sharded storage over two mocked shards owning keys 0-4 and 5-9,
+// along with the mocks of the main database and the shards.
+func testShards(t *testing.T, logger *mock_logger.MockLogger) (*sharded.Storage, sqlmock.Sqlmock, []sqlmock.Sqlmock) {
+	t.Helper()
+	open := func() (*sqlx.DB, sqlmock.Sqlmock) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("failed to open mock db: %v", err)
+		}
+		t.Cleanup(func() { _ = db.Close() })
+		return sqlx.NewDb(db, "postgres"), mock
+	}
+	directory, directoryMock := open()
+	first, firstMock := open()
+	second, secondMock := open()
+	storage, err := sharded.NewStorage(directory, []sharded.Shard{
+		{Name: "shard-1", From: 5, To: 9, DB: second},
+		{Name: "shard-0", From: 0, To: 4, DB: first},
+	}, configs.Database{}, logger)
+	if err != nil {
+		t.Fatalf("NewStorage failed: %v", err)
+	}
+	return storage, directoryMock, []sqlmock.Sqlmock{firstMock, secondMock}
+}
+
+func expectationsMet(t *testing.T, mocks ...sqlmock.Sqlmock) {
+	t.Helper()
+	for _, mock := range mocks {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unfulfilled expectations: %v", err)
+		}
+	}
+}
+
+func TestNewStorage_InvalidShards(t *testing.T) {
+	tests := map[string][]sharded.Shard{
+		"no shards":   nil,
+		"empty range": {{Name: "a", From: 5, To: 4}},
+		"same name":   {{Name: "a", From: 0, To: 4}, {Name: "a", From: 5, To: 9}},
+		"overlap":     {{Name: "a", From: 0, To: 5}, {Name: "b", From: 5, To: 9}},
+	}
+	for name, shards := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := sharded.NewStorage(nil, shards, configs.Database{}, nil); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
+
+func TestStorage_SaveOrder_RoutesByShardKey(t *testing.T) {
+	storage, directory, shards := testShards(t, nil)
+
+	if err := storage.SaveOrder(context.Background(), &models.Order{OrderUID: "uid1", ShardKey: "12"}); !errors.Is(err, errs.ErrConstraint) {
+		t.Fatalf("expected ErrConstraint for an unowned shard key, got %v", err)
+	}
+	if err := storage.SaveOrder(context.Background(), &models.Order{OrderUID: "uid1", ShardKey: "x"}); !errors.Is(err, errs.ErrConstraint) {
+		t.Fatalf("expected ErrConstraint for a malformed shard key, got %v", err)
+	}
+
+	saveErr := errors.New("shard-1 reached")
+	directory.ExpectQuery("INSERT INTO order_shards").WithArgs("uid1", "shard-1").
+		WillReturnRows(sqlmock.NewRows([]string{"order_uid", "shard"}).AddRow("uid1", "shard-1"))
+	shards[1].ExpectBegin().WillReturnError(saveErr)
+	if err := storage.SaveOrder(context.Background(), &models.Order{OrderUID: "uid1", ShardKey: "7"}); !errors.Is(err, saveErr) {
+		t.Fatalf("expected the order to be saved on shard-1, got %v", err)
+	}
+
+	saveErr = errors.New("shard-0 reached")
+	directory.ExpectQuery("INSERT INTO order_shards").WithArgs("uid2", "shard-1").
+		WillReturnRows(sqlmock.NewRows([]string{"order_uid", "shard"}).AddRow("uid2", "shard-0"))
+	shards[0].ExpectBegin().WillReturnError(saveErr)
+	if err := storage.SaveOrder(context.Background(), &models.Order{OrderUID: "uid2", ShardKey: "7"}); !errors.Is(err, saveErr) {
+		t.Fatalf("expected an already recorded order to be saved on its recorded shard, got %v", err)
+	}
+
+	expectationsMet(t, directory, shards[0], shards[1])
+}
+
+func TestStorage_GetOrderHistory_Lookup(t *testing.T) {
+	storage, directory, shards := testShards(t, nil)
+	historyRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"chrt_id", "rid", "previous_status", "status", "changed_at", "recorded_at"})
+	}
+
+	// recorded on shard-1
+	directory.ExpectQuery("SELECT shard FROM order_shards").WithArgs("uid1").WillReturnRows(sqlmock.NewRows([]string{"shard"}).AddRow("shard-1"))
+	shards[1].ExpectQuery("SELECT id FROM orders").WithArgs("uid1").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+	shards[1].ExpectQuery("FROM item_status_history").WillReturnRows(historyRows())
+	if history, err := storage.GetOrderHistory(context.Background(), "uid1"); err != nil || history.OrderUID != "uid1" {
+		t.Fatalf("expected the history from shard-1, got %+v (%v)", history, err)
+	}
+
+	// not recorded: every shard is asked
+	directory.ExpectQuery("SELECT shard FROM order_shards").WithArgs("uid2").WillReturnRows(sqlmock.NewRows([]string{"shard"}))
+	shards[0].ExpectQuery("SELECT id FROM orders").WithArgs("uid2").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5))
+	shards[0].ExpectQuery("FROM item_status_history").WillReturnRows(historyRows())
+	shards[1].ExpectQuery("SELECT id FROM orders").WithArgs("uid2").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	if history, err := storage.GetOrderHistory(context.Background(), "uid2"); err != nil || history.OrderUID != "uid2" {
+		t.Fatalf("expected the history from shard-0, got %+v (%v)", history, err)
+	}
+
+	// moved by a rebalance after the lookup
+	directory.ExpectQuery("SELECT shard FROM order_shards").WithArgs("uid3").WillReturnRows(sqlmock.NewRows([]string{"shard"}).AddRow("shard-0"))
+	shards[0].ExpectQuery("SELECT id FROM orders").WithArgs("uid3").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	directory.ExpectQuery("SELECT shard FROM order_shards").WithArgs("uid3").WillReturnRows(sqlmock.NewRows([]string{"shard"}).AddRow("shard-1"))
+	shards[1].ExpectQuery("SELECT id FROM orders").WithArgs("uid3").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(8))
+	shards[1].ExpectQuery("FROM item_status_history").WillReturnRows(historyRows())
+	if _, err := storage.GetOrderHistory(context.Background(), "uid3"); err != nil {
+		t.Fatalf("expected the moved order to be followed, got %v", err)
+	}
+
+	// nowhere
+	directory.ExpectQuery("SELECT shard FROM order_shards").WithArgs("uid4").WillReturnRows(sqlmock.NewRows([]string{"shard"}))
+	shards[0].ExpectQuery("SELECT id FROM orders").WithArgs("uid4").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	shards[1].ExpectQuery("SELECT id FROM orders").WithArgs("uid4").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	if _, err := storage.GetOrderHistory(context.Background(), "uid4"); !errors.Is(err, errs.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	expectationsMet(t, directory, shards[0], shards[1])
+}
+
+func TestStorage_ListOrders_MergesShards(t *testing.T) {
+	storage, directory, shards := testShards(t, nil)
+	day := func(d int) time.Time { return time.Date(2025, 1, d, 0, 0, 0, 0, time.UTC) }
+	a5, a3 := testOrder{1, "a5", "1", day(5)}, testOrder{2, "a3", "2", day(3)}
+	b4, b1 := testOrder{1, "b4", "6", day(4)}, testOrder{2, "b1", "7", day(1)}
+
+	// both shards are asked for a full page, one row more to tell whether there is a next page
+	shards[0].ExpectQuery("LIMIT 3").WillReturnRows(orderRows(a5, a3))
+	shards[0].ExpectQuery("FROM items").WillReturnRows(sqlmock.NewRows(itemColumns))
+	shards[1].ExpectQuery("LIMIT 3").WillReturnRows(orderRows(b4, b1))
+	shards[1].ExpectQuery("FROM items").WillReturnRows(sqlmock.NewRows(itemColumns))
+	// half of both pages is used, so both are asked for the cursor right after the used half
+	shards[0].ExpectQuery("LIMIT 2").WillReturnRows(orderRows(a5, a3))
+	shards[0].ExpectQuery("FROM items").WillReturnRows(sqlmock.NewRows(itemColumns))
+	shards[1].ExpectQuery("LIMIT 2").WillReturnRows(orderRows(b4, b1))
+	shards[1].ExpectQuery("FROM items").WillReturnRows(sqlmock.NewRows(itemColumns))
+
+	page, err := storage.ListOrders(context.Background(), models.OrderQuery{Limit: 2})
+	if err != nil {
+		t.Fatalf("ListOrders failed: %v", err)
+	}
+	if len(page.Orders) != 2 || page.Orders[0].OrderUID != "a5" || page.Orders[1].OrderUID != "b4" || page.NextCursor == "" {
+		t.Fatalf("expected [a5 b4] and a next cursor, got %d orders, cursor %q", len(page.Orders), page.NextCursor)
+	}
+
+	// the second page continues every shard after its own last listed order
+	shards[0].ExpectQuery(`\(orders.date_created, orders.id\) < \(\$1, \$2\)`).WithArgs(day(5), 1).WillReturnRows(orderRows(a3))
+	shards[0].ExpectQuery("FROM items").WillReturnRows(sqlmock.NewRows(itemColumns))
+	shards[1].ExpectQuery(`\(orders.date_created, orders.id\) < \(\$1, \$2\)`).WithArgs(day(4), 1).WillReturnRows(orderRows(b1))
+	shards[1].ExpectQuery("FROM items").WillReturnRows(sqlmock.NewRows(itemColumns))
+
+	page, err = storage.ListOrders(context.Background(), models.OrderQuery{Limit: 2, Cursor: page.NextCursor})
+	if err != nil {
+		t.Fatalf("ListOrders failed: %v", err)
+	}
+	if len(page.Orders) != 2 || page.Orders[0].OrderUID != "a3" || page.Orders[1].OrderUID != "b1" || page.NextCursor != "" {
+		t.Fatalf("expected the last page [a3 b1], got %d orders, cursor %q", len(page.Orders), page.NextCursor)
+	}
+
+	if _, err := storage.ListOrders(context.Background(), models.OrderQuery{Limit: 2, Cursor: "garbage!"}); !errors.Is(err, errs.ErrInvalidArgument) {
+		t.Fatalf("expected ErrInvalidArgument for a malformed cursor, got %v", err)
+	}
+
+	expectationsMet(t, directory, shards[0], shards[1])
+}
+
+func TestStorage_Outbox_EventIDsCarryShard(t *testing.T) {
+	storage, _, shards := testShards(t, nil)
+	eventRows := func(ids ...int64) *sqlmock.Rows {
+		rows := sqlmock.NewRows([]string{"id", "event_type", "event_key", "payload", "attempts", "created_at"})
+		for _, id := range ids {
+			rows.AddRow(id, models.EventOrderAccepted, "uid", []byte("{}"), 0, time.Now())
+		}
+		return rows
+	}
+
+	shards[0].ExpectQuery("UPDATE outbox").WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), 3).WillReturnRows(eventRows(1, 2))
+	shards[1].ExpectQuery("UPDATE outbox").WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), 1).WillReturnRows(eventRows(1))
+	events, err := storage.ClaimOutbox(context.Background(), 3, time.Minute)
+	if err != nil {
+		t.Fatalf("ClaimOutbox failed: %v", err)
+	}
+	if len(events) != 3 || events[0].ID != 2 || events[1].ID != 4 || events[2].ID != 3 {
+		t.Fatalf("expected event IDs 2, 4 and 3, got %+v", events)
+	}
+
+	shards[1].ExpectExec("UPDATE outbox SET sent_at").WithArgs(1, sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+	if err := storage.MarkOutboxSent(context.Background(), events[2].ID); err != nil {
+		t.Fatalf("MarkOutboxSent failed: %v", err)
+	}
+
+	// the next claim starts with the other shard
+	shards[1].ExpectQuery("UPDATE outbox").WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), 1).WillReturnRows(eventRows(2))
+	if events, err := storage.ClaimOutbox(context.Background(), 1, time.Minute); err != nil || len(events) != 1 || events[0].ID != 5 {
+		t.Fatalf("expected event 5 of shard-1, got %+v (%v)", events, err)
+	}
+
+	expectationsMet(t, shards[0], shards[1])
+}
+
+func TestStorage_ErasePersonalData_RecordsOnce(t *testing.T) {
+	storage, directory, shards := testShards(t, nil)
+	orderRows := func(uids ...string) *sqlmock.Rows {
+		rows := sqlmock.NewRows([]string{"id", "order_uid"})
+		for i, uid := range uids {
+			rows.AddRow(i+1, uid)
+		}
+		return rows
+	}
+
+	shards[0].ExpectBegin()
+	shards[0].ExpectQuery("UPDATE orders SET version").WithArgs("test").WillReturnRows(orderRows("uid3"))
+	shards[0].ExpectExec("UPDATE deliveries SET").WillReturnResult(sqlmock.NewResult(0, 1))
+	shards[0].ExpectExec("UPDATE order_ingest_log SET").WillReturnResult(sqlmock.NewResult(0, 1))
+	shards[0].ExpectExec("UPDATE order_amendments SET").WillReturnResult(sqlmock.NewResult(0, 0))
+	shards[0].ExpectCommit()
+	shards[1].ExpectBegin()
+	shards[1].ExpectQuery("UPDATE orders SET version").WithArgs("test").WillReturnRows(orderRows())
+	shards[1].ExpectCommit()
+	directory.ExpectBegin()
+	directory.ExpectExec("INSERT INTO personal_data_erasures").
+		WithArgs("test", `["uid3"]`, models.ErasureSourceAPI, "", sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+	directory.ExpectCommit()
+
+	erasure := &models.PersonalDataErasure{CustomerID: "test", Source: models.ErasureSourceAPI}
+	if err := storage.ErasePersonalData(context.Background(), erasure); err != nil {
+		t.Fatalf("ErasePersonalData failed: %v", err)
+	}
+	if len(erasure.OrderUIDs) != 1 || erasure.OrderUIDs[0] != "uid3" {
+		t.Errorf("unexpected erased orders: %v", erasure.OrderUIDs)
+	}
+	expectationsMet(t, directory, shards[0], shards[1])
+}
+
+func TestStorage_Rebalance(t *testing.T) {
+	logger := mock_logger.NewMockLogger(gomock.NewController(t))
+	logger.EXPECT().LogInfo(gomock.Any(), gomock.Any()).AnyTimes()
+	storage, directory, shards := testShards(t, logger)
+	keyColumns := []string{"id", "order_uid", "shardkey"}
+	created := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// shard-0 holds uid1 which it owns, and uid2 whose key 7 belongs to shard-1 now
+	shards[0].ExpectQuery("SELECT id, order_uid, shardkey FROM orders").WithArgs(int64(0), 10).
+		WillReturnRows(sqlmock.NewRows(keyColumns).AddRow(1, "uid1", "3").AddRow(2, "uid2", "7"))
+	shards[0].ExpectBegin()
+	shards[0].ExpectQuery("FOR UPDATE").WithArgs("uid2").WillReturnRows(sqlmock.NewRows([]string{"id", "checksum"}).AddRow(2, "sum"))
+	shards[0].ExpectExec("FOR UPDATE").WithArgs(2).WillReturnResult(sqlmock.NewResult(0, 0))
+	shards[0].ExpectQuery("FROM orders").WillReturnRows(orderRows(testOrder{2, "uid2", "7", created}))
+	shards[0].ExpectQuery("FROM items").WillReturnRows(sqlmock.NewRows(itemColumns[1:]))
+	shards[0].ExpectQuery("FROM item_status_history").WillReturnRows(sqlmock.NewRows([]string{"chrt_id", "rid", "previous_status", "status", "changed_at", "recorded_at"}))
+	shards[0].ExpectQuery("FROM order_ingest_log").WithArgs(`{"uid2"}`).WillReturnRows(sqlmock.NewRows([]string{"order_uid"}))
+	shards[0].ExpectQuery("FROM order_amendments").WithArgs(`{"uid2"}`).WillReturnRows(sqlmock.NewRows([]string{"order_uid"}))
+	// the customer was erased while the order was on shard-0, so it is erased on its way
+	directory.ExpectQuery("FROM personal_data_erasures").WithArgs("customer", created).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	erased := models.ErasedDelivery
+	shards[1].ExpectBegin()
+	shards[1].ExpectQuery("INSERT INTO orders").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	shards[1].ExpectExec("INSERT INTO deliveries").
+		WithArgs(1, erased.Name, erased.Phone, erased.Zip, erased.City, erased.Address, erased.Region, erased.Email,
+			sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	shards[1].ExpectExec("INSERT INTO payments").WillReturnResult(sqlmock.NewResult(1, 1))
+	shards[1].ExpectExec("UPDATE orders SET version").WithArgs(1, 2).WillReturnResult(sqlmock.NewResult(0, 1))
+	shards[1].ExpectCommit()
+	directory.ExpectExec("INSERT INTO order_shards").WithArgs("uid2", "shard-1").WillReturnResult(sqlmock.NewResult(0, 1))
+	shards[0].ExpectExec("DELETE FROM orders").WithArgs(2).WillReturnResult(sqlmock.NewResult(0, 1))
+	shards[0].ExpectCommit()
+	directory.ExpectQuery("INSERT INTO order_shards").WithArgs("uid1", "shard-0").
+		WillReturnRows(sqlmock.NewRows([]string{"order_uid", "shard"}).AddRow("uid1", "shard-0"))
+	shards[0].ExpectQuery("SELECT id, order_uid, shardkey FROM orders").WithArgs(int64(2), 10).WillReturnRows(sqlmock.NewRows(keyColumns))
+
+	// shard-1 now holds the moved order
+	shards[1].ExpectQuery("SELECT id, order_uid, shardkey FROM orders").WithArgs(int64(0), 10).
+		WillReturnRows(sqlmock.NewRows(keyColumns).AddRow(1, "uid2", "7"))
+	directory.ExpectQuery("INSERT INTO order_shards").WithArgs("uid2", "shard-1").
+		WillReturnRows(sqlmock.NewRows([]string{"order_uid", "shard"}).AddRow("uid2", "shard-1"))
+	shards[1].ExpectQuery("SELECT id, order_uid, shardkey FROM orders").WithArgs(int64(1), 10).WillReturnRows(sqlmock.NewRows(keyColumns))
+
+	stats, err := storage.Rebalance(context.Background(), 10, false)
+	if err != nil {
+		t.Fatalf("Rebalance failed: %v", err)
+	}
+	if stats.Misplaced != 1 || stats.Moved != 1 {
+		t.Fatalf("expected one order to be moved, got %+v", stats)
+	}
+
+	expectationsMet(t, directory, shards[0], shards[1])
+}
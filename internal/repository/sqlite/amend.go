@@ -0,0 +1,82 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/models"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository/errs"
+)
+
+/*
+AmendOrder stores the changes made to an order along with the record of the amendment, as a single transaction.
+
+Only the fields that can be amended are written: the order columns other than its keys, shard key
+and date, and the delivery. Items, payment and the checksum of the order as it was received stay as they are.
+
+The stored order must still be at version amendment.Version-1. If it was amended in the meantime,
+errs.ErrConflict is returned and nothing is written. Returns errs.ErrNotFound if there is no such order.
+On success order.Version is set to amendment.Version.
+*/
+func (s *Storage) AmendOrder(ctx context.Context, order *models.Order, amendment models.OrderAmendment) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", mapError(ctx, err))
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var orderId int
+	err = tx.QueryRowContext(ctx, `UPDATE orders SET
+        entry = $3,
+        locale = $4,
+        internal_signature = $5,
+        customer_id = $6,
+        delivery_service = $7,
+        sm_id = $8,
+        oof_shard = $9,
+        version = version + 1
+    WHERE order_uid = $1 AND version = $2
+    RETURNING id`,
+		order.OrderUID, amendment.Version-1, order.Entry, order.Locale, order.InternalSignature,
+		order.CustomerID, order.DeliveryService, order.SmID, order.OofShard).Scan(&orderId)
+	if errors.Is(err, sql.ErrNoRows) {
+		var version int
+		if err := tx.QueryRowContext(ctx, `SELECT version FROM orders WHERE order_uid = $1`, order.OrderUID).Scan(&version); err != nil {
+			return fmt.Errorf("failed to get order %s: %w", order.OrderUID, mapError(ctx, err))
+		}
+		return fmt.Errorf("order %s is at version %d already: %w", order.OrderUID, version, errs.ErrConflict)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update order: %w", mapError(ctx, err))
+	}
+	delivery := order.Delivery
+	_, err = tx.ExecContext(ctx, `UPDATE deliveries SET
+        name = $2,
+        phone = $3,
+        zip = $4,
+        city = $5,
+        address = $6,
+        region = $7,
+        email = $8
+    WHERE order_id = $1`,
+		orderId, delivery.Name, delivery.Phone, delivery.Zip, delivery.City, delivery.Address, delivery.Region, delivery.Email)
+	if err != nil {
+		return fmt.Errorf("failed to update delivery: %w", mapError(ctx, err))
+	}
+	_, err = tx.ExecContext(ctx, `INSERT INTO order_amendments (order_uid, version, patch, previous, amended_at)
+        VALUES ($1, $2, $3, $4, $5)`,
+		order.OrderUID, amendment.Version, string(amendment.Patch), string(amendment.Previous), amendment.AmendedAt.UTC())
+	if err != nil {
+		return fmt.Errorf("failed to record amendment: %w", mapError(ctx, err))
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("transaction commit failed: %w", mapError(ctx, err))
+	}
+	order.Version = amendment.Version
+	return nil
+}
@@ -0,0 +1,51 @@
+package sqlite_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/models"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository/errs"
+)
+
+func TestSQLiteStorer_AmendOrder(t *testing.T) {
+	storage, db := newTestStorage(t)
+	order := testOrder(1)
+	if err := storage.SaveOrder(context.Background(), order); err != nil {
+		t.Fatalf("SaveOrder failed: %v", err)
+	}
+
+	amended := testOrder(1)
+	amended.Delivery.City = "Moscow"
+	amended.Locale = "ru"
+	amendment := models.OrderAmendment{Version: 2, Patch: []byte(`{"locale":"ru"}`), Previous: []byte(`{}`),
+		AmendedAt: time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)}
+	if err := storage.AmendOrder(context.Background(), amended, amendment); err != nil {
+		t.Fatalf("AmendOrder failed: %v", err)
+	}
+	if amended.Version != 2 {
+		t.Errorf("expected the order to be at version 2, got %d", amended.Version)
+	}
+
+	got, err := storage.GetOrder(context.Background(), order.OrderUID)
+	if err != nil {
+		t.Fatalf("GetOrder failed: %v", err)
+	}
+	if got.Version != 2 || got.Delivery.City != "Moscow" || got.Locale != "ru" || len(got.Items) != 2 {
+		t.Fatalf("expected the amended order, got %+v", got)
+	}
+	var recorded int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM order_amendments WHERE order_uid = $1 AND version = 2`, order.OrderUID).Scan(&recorded); err != nil || recorded != 1 {
+		t.Fatalf("expected the amendment to be recorded, got %d (%v)", recorded, err)
+	}
+
+	// another amendment made to version 1 is stale now
+	if err := storage.AmendOrder(context.Background(), testOrder(1), amendment); !errors.Is(err, errs.ErrConflict) {
+		t.Fatalf("expected ErrConflict, got %v", err)
+	}
+	if err := storage.AmendOrder(context.Background(), testOrder(2), amendment); !errors.Is(err, errs.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
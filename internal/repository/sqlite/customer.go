@@ -0,0 +1,81 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/models"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository/errs"
+)
+
+// GetOrderByTrackNumber retrieves a single order by its track number, including delivery, payment, and item details.
+// Returns errs.ErrNotFound if there is no order with such track number.
+func (s *Storage) GetOrderByTrackNumber(ctx context.Context, trackNumber string) (*models.Order, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	orders, orderIds, err := queryOrders(ctx, s, ordersSelect+"\n    WHERE orders.track_number = $1", trackNumber)
+	if err != nil {
+		return nil, mapError(ctx, err)
+	}
+	if len(orders) == 0 {
+		return nil, errs.ErrNotFound
+	}
+	if err := queryItemsBulk(ctx, s, orders, orderIds); err != nil {
+		return nil, mapError(ctx, err)
+	}
+	return orders[0], nil
+}
+
+// GetCustomerStats aggregates all orders of a customer: order count, total spend per currency and the last order date.
+// Returns errs.ErrNotFound if the customer has no orders.
+//
+// SQLite returns aggregates without a declared type, so the last order date comes back as text and is parsed here.
+func (s *Storage) GetCustomerStats(ctx context.Context, customerID string) (models.CustomerStats, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	query := `SELECT 
+        payments.currency,
+        COUNT(*),
+        SUM(payments.amount),
+        MAX(orders.date_created)
+    FROM orders 
+    JOIN payments ON orders.id = payments.order_id
+    WHERE orders.customer_id = $1
+    GROUP BY payments.currency`
+
+	rows, err := s.db.QueryContext(ctx, query, customerID)
+	if err != nil {
+		return models.CustomerStats{}, mapError(ctx, err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	stats := models.CustomerStats{TotalSpend: make(map[string]float64)}
+	for rows.Next() {
+		var currency string
+		var count int
+		var total float64
+		var lastOrderText string
+		if err := rows.Scan(&currency, &count, &total, &lastOrderText); err != nil {
+			return models.CustomerStats{}, mapError(ctx, err)
+		}
+		lastOrder, err := time.Parse(timeFormat, lastOrderText)
+		if err != nil {
+			return models.CustomerStats{}, fmt.Errorf("failed to parse last order date %q: %v", lastOrderText, err)
+		}
+		stats.OrderCount += count
+		stats.TotalSpend[currency] = total
+		if lastOrder.After(stats.LastOrderDate) {
+			stats.LastOrderDate = lastOrder
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return models.CustomerStats{}, mapError(ctx, err)
+	}
+	if stats.OrderCount == 0 {
+		return models.CustomerStats{}, errs.ErrNotFound
+	}
+	return stats, nil
+}
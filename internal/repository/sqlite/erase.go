@@ -0,0 +1,99 @@
+package sqlite
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/models"
+)
+
+/*
+ErasePersonalData replaces the personal data of a customer with the placeholders of models.ErasedDelivery
+and writes the compliance record of the erasure, as a single transaction.
+
+The delivery of every order is replaced, and so is the delivery in the ingested payload of the order
+and in its recorded amendments. Items, payment and the rest of the order stay for accounting.
+The version of the erased orders is bumped, so amendments made to them before are rejected.
+On success erasure.OrderUIDs holds the orders of the customer, sorted. A customer without orders is recorded as well.
+*/
+func (s *Storage) ErasePersonalData(ctx context.Context, erasure *models.PersonalDataErasure) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", mapError(ctx, err))
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	rows, err := tx.QueryContext(ctx, `UPDATE orders SET version = version + 1 WHERE customer_id = $1 RETURNING id, order_uid`, erasure.CustomerID)
+	if err != nil {
+		return fmt.Errorf("failed to update orders: %w", mapError(ctx, err))
+	}
+	orderIDs := make(map[string]int)
+	orderUIDs := []string{}
+	for rows.Next() {
+		var orderID int
+		var orderUID string
+		if err := rows.Scan(&orderID, &orderUID); err != nil {
+			_ = rows.Close()
+			return fmt.Errorf("failed to scan order: %w", err)
+		}
+		orderIDs[orderUID] = orderID
+		orderUIDs = append(orderUIDs, orderUID)
+	}
+	_ = rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to update orders: %w", mapError(ctx, err))
+	}
+	slices.Sort(orderUIDs)
+
+	erased := models.ErasedDelivery
+	delivery, err := json.Marshal(erased)
+	if err != nil {
+		return fmt.Errorf("failed to marshal erased delivery: %w", err)
+	}
+	for _, orderUID := range orderUIDs {
+		if _, err := tx.ExecContext(ctx, `UPDATE deliveries SET
+        name = $2,
+        phone = $3,
+        zip = $4,
+        city = $5,
+        address = $6,
+        region = $7,
+        email = $8
+    WHERE order_id = $1`,
+			orderIDs[orderUID], erased.Name, erased.Phone, erased.Zip, erased.City, erased.Address, erased.Region, erased.Email); err != nil {
+			return fmt.Errorf("failed to erase delivery: %w", mapError(ctx, err))
+		}
+		if _, err := tx.ExecContext(ctx, `UPDATE order_ingest_log SET
+        payload = json_replace(payload, '$.delivery', json($2))
+    WHERE order_uid = $1`, orderUID, string(delivery)); err != nil {
+			return fmt.Errorf("failed to erase ingested payload: %w", mapError(ctx, err))
+		}
+		if _, err := tx.ExecContext(ctx, `UPDATE order_amendments SET
+        patch = json_replace(patch, '$.delivery', json($2)),
+        previous = json_replace(previous, '$.delivery', json($2))
+    WHERE order_uid = $1`, orderUID, string(delivery)); err != nil {
+			return fmt.Errorf("failed to erase amendments: %w", mapError(ctx, err))
+		}
+	}
+
+	recorded, err := json.Marshal(orderUIDs)
+	if err != nil {
+		return fmt.Errorf("failed to marshal erased orders: %w", err)
+	}
+	_, err = tx.ExecContext(ctx, `INSERT INTO personal_data_erasures (customer_id, order_uids, source, reference, erased_at)
+        VALUES ($1, $2, $3, $4, $5)`,
+		erasure.CustomerID, string(recorded), erasure.Source, erasure.Reference, erasure.ErasedAt.UTC())
+	if err != nil {
+		return fmt.Errorf("failed to record erasure: %w", mapError(ctx, err))
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("transaction commit failed: %w", mapError(ctx, err))
+	}
+	erasure.OrderUIDs = orderUIDs
+	return nil
+}
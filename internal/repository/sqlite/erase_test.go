@@ -0,0 +1,69 @@
+package sqlite_test
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/models"
+)
+
+func TestSQLiteStorer_ErasePersonalData(t *testing.T) {
+	storage, db := newTestStorage(t)
+	first, second, other := testOrder(1), testOrder(2), testOrder(3)
+	first.Ingest = &models.IngestRecord{Topic: "orders", Key: first.OrderUID,
+		Payload: []byte(`{"order_uid":"uid1","delivery":{"name":"Test Testov","phone":"+9720000000"}}`)}
+	other.CustomerID = "other"
+	for _, order := range []*models.Order{first, second, other} {
+		if err := storage.SaveOrder(context.Background(), order); err != nil {
+			t.Fatalf("SaveOrder failed: %v", err)
+		}
+	}
+	amended := testOrder(1)
+	amendment := models.OrderAmendment{Version: 2, Patch: []byte(`{"locale":"ru"}`),
+		Previous: []byte(`{"locale":"en","delivery":{"name":"Test Testov"}}`), AmendedAt: time.Now()}
+	if err := storage.AmendOrder(context.Background(), amended, amendment); err != nil {
+		t.Fatalf("AmendOrder failed: %v", err)
+	}
+
+	erasure := &models.PersonalDataErasure{CustomerID: "customer", Source: models.ErasureSourceCLI, Reference: "TICKET-1", ErasedAt: time.Now()}
+	if err := storage.ErasePersonalData(context.Background(), erasure); err != nil {
+		t.Fatalf("ErasePersonalData failed: %v", err)
+	}
+	if !slices.Equal(erasure.OrderUIDs, []string{"uid1", "uid2"}) {
+		t.Errorf("unexpected erased orders: %v", erasure.OrderUIDs)
+	}
+
+	got, err := storage.GetOrder(context.Background(), "uid1")
+	if err != nil {
+		t.Fatalf("GetOrder failed: %v", err)
+	}
+	if got.Delivery != models.ErasedDelivery || got.Version != 3 || len(got.Items) != 2 || got.Payment != first.Payment {
+		t.Errorf("expected only the delivery to be erased, got %+v", got)
+	}
+	if got, err := storage.GetOrder(context.Background(), "uid3"); err != nil || got.Delivery != other.Delivery {
+		t.Errorf("expected the order of another customer to stay, got %+v (%v)", got, err)
+	}
+	provenance, err := storage.GetOrderProvenance(context.Background(), "uid1")
+	if err != nil {
+		t.Fatalf("GetOrderProvenance failed: %v", err)
+	}
+	if strings.Contains(string(provenance.Payload), "Test Testov") || !strings.Contains(string(provenance.Payload), `"order_uid":"uid1"`) {
+		t.Errorf("expected the delivery to be erased from the payload, got %s", provenance.Payload)
+	}
+	var previous, orderUIDs, source string
+	if err := db.QueryRow(`SELECT previous FROM order_amendments WHERE order_uid = 'uid1'`).Scan(&previous); err != nil {
+		t.Fatalf("failed to read amendment: %v", err)
+	}
+	if strings.Contains(previous, "Test Testov") {
+		t.Errorf("expected the delivery to be erased from the amendment, got %s", previous)
+	}
+	if err := db.QueryRow(`SELECT order_uids, source FROM personal_data_erasures WHERE customer_id = 'customer'`).Scan(&orderUIDs, &source); err != nil {
+		t.Fatalf("failed to read erasure record: %v", err)
+	}
+	if orderUIDs != `["uid1","uid2"]` || source != models.ErasureSourceCLI {
+		t.Errorf("unexpected erasure record: %s %s", orderUIDs, source)
+	}
+}
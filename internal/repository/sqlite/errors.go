@@ -0,0 +1,68 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository/errs"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// mapError wraps a database error into one of the errs classes.
+//
+// The original error stays in the chain, so both errors.Is(err, errs.ErrX)
+// and errors.As(err, **sqlite.Error) keep working for callers.
+// If ctx has hit its deadline, the error is reported as a timeout, since the driver
+// only sees an interrupted query. Errors that don't belong to any known class are returned unchanged.
+func mapError(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+	class := errorClass(err)
+	if class == nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		class = errs.ErrTimeout
+	}
+	if class != nil {
+		return fmt.Errorf("%w: %w", class, err)
+	}
+	return err
+}
+
+// errorClass returns the errs sentinel that matches err, or nil if there is none.
+func errorClass(err error) error {
+	var sqliteErr *sqlite.Error
+	switch {
+	case errors.Is(err, errs.ErrNotFound), errors.Is(err, errs.ErrDuplicate), errors.Is(err, errs.ErrConflict),
+		errors.Is(err, errs.ErrConstraint), errors.Is(err, errs.ErrUnavailable), errors.Is(err, errs.ErrTimeout),
+		errors.Is(err, errs.ErrInvalidArgument):
+		return nil // already classified
+	case errors.Is(err, sql.ErrNoRows):
+		return errs.ErrNotFound
+	case errors.Is(err, context.DeadlineExceeded):
+		return errs.ErrTimeout
+	case errors.Is(err, driver.ErrBadConn), errors.Is(err, sql.ErrConnDone):
+		return errs.ErrUnavailable
+	case errors.As(err, &sqliteErr):
+		return sqliteErrorClass(sqliteErr)
+	}
+	return nil
+}
+
+// sqliteErrorClass maps an SQLite result code to an errs class.
+// Extended codes carry the primary code in their lowest byte.
+// Unique violations are constraint violations, redelivered orders are told by their checksum (see checkStoredOrder).
+// See https://www.sqlite.org/rescode.html
+func sqliteErrorClass(sqliteErr *sqlite.Error) error {
+	switch sqliteErr.Code() & 0xff {
+	case sqlite3.SQLITE_CONSTRAINT, sqlite3.SQLITE_TOOBIG, sqlite3.SQLITE_MISMATCH:
+		return errs.ErrConstraint
+	case sqlite3.SQLITE_BUSY, sqlite3.SQLITE_LOCKED, sqlite3.SQLITE_IOERR, sqlite3.SQLITE_FULL,
+		sqlite3.SQLITE_CANTOPEN, sqlite3.SQLITE_READONLY, sqlite3.SQLITE_NOMEM:
+		return errs.ErrUnavailable
+	}
+	return nil
+}
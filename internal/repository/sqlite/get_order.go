@@ -0,0 +1,356 @@
+package sqlite
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/models"
+)
+
+// GetOrderFromPrimary retrieves a single order like GetOrder. An embedded database has no replicas to lag behind.
+func (s *Storage) GetOrderFromPrimary(ctx context.Context, orderUID string) (*models.Order, error) {
+	return s.GetOrder(ctx, orderUID)
+}
+
+// GetOrder retrieves a single order by its UID, including delivery, payment, and item details.
+// Returns errs.ErrNotFound if there is no order with such UID.
+func (s *Storage) GetOrder(ctx context.Context, orderUID string) (*models.Order, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	var orderId int
+	order := new(models.Order)
+	if err := queryAllButItems(ctx, s, order, orderUID, &orderId); err != nil {
+		return nil, mapError(ctx, err)
+	}
+	if err := queryItems(ctx, s, &order.Items, orderId); err != nil {
+		return nil, mapError(ctx, err)
+	}
+	return order, nil
+}
+
+// queryAllButItems queries order, delivery, and payment information excluding items.
+func queryAllButItems(ctx context.Context, s *Storage, order *models.Order, orderUID string, orderId *int) error {
+	query := `SELECT 
+
+        orders.id, 
+        orders.order_uid, 
+        orders.track_number, 
+        orders.entry, 
+        orders.locale, 
+        orders.internal_signature,
+        orders.customer_id,
+        orders.delivery_service,
+        orders.shardkey,
+        orders.sm_id,
+        orders.date_created,
+        orders.oof_shard,
+        orders.version,
+
+        deliveries.name, 
+        deliveries.phone, 
+        deliveries.zip, 
+        deliveries.city, 
+        deliveries.address,
+        deliveries.region,
+        deliveries.email,
+
+        payments."transaction", 
+        payments.request_id, 
+        payments.currency, 
+        payments.provider, 
+        payments.amount,
+        payments.payment_dt,
+        payments.bank,
+        payments.delivery_cost,
+        payments.goods_total,
+        payments.custom_fee
+
+        FROM orders 
+        JOIN deliveries ON orders.id = deliveries.order_id
+        JOIN payments ON orders.id = payments.order_id
+        WHERE orders.order_uid = $1`
+
+	row := s.db.QueryRowContext(ctx, query, orderUID)
+	var paymentTime time.Time
+	if err := row.Scan(orderId,
+
+		&order.OrderUID,
+		&order.TrackNumber,
+		&order.Entry,
+		&order.Locale,
+		&order.InternalSignature,
+		&order.CustomerID,
+		&order.DeliveryService,
+		&order.ShardKey,
+		&order.SmID,
+		&order.DateCreated,
+		&order.OofShard,
+		&order.Version,
+
+		&order.Delivery.Name,
+		&order.Delivery.Phone,
+		&order.Delivery.Zip,
+		&order.Delivery.City,
+		&order.Delivery.Address,
+		&order.Delivery.Region,
+		&order.Delivery.Email,
+
+		&order.Payment.Transaction,
+		&order.Payment.RequestID,
+		&order.Payment.Currency,
+		&order.Payment.Provider,
+		&order.Payment.Amount,
+		&paymentTime,
+		&order.Payment.Bank,
+		&order.Payment.DeliveryCost,
+		&order.Payment.GoodsTotal,
+		&order.Payment.CustomFee,
+	); err != nil {
+		return err
+	}
+	order.Payment.PaymentDT = paymentTime.Unix()
+	return nil
+}
+
+// queryItems retrieves all item records associated with a given order ID.
+func queryItems(ctx context.Context, s *Storage, items *[]models.Item, orderId int) error {
+	query := `SELECT 
+        chrt_id,
+        track_number,
+        price,
+        rid,
+        name,
+        sale,
+        size,
+        total_price,
+        nm_id,
+        brand,
+        status
+        FROM items WHERE order_id = $1`
+
+	rows, err := s.db.QueryContext(ctx, query, orderId)
+	if err != nil {
+		return err
+	}
+
+	defer func() { _ = rows.Close() }()
+
+	for rows.Next() {
+		var item models.Item
+		err := rows.Scan(
+			&item.ChrtID,
+			&item.TrackNumber,
+			&item.Price,
+			&item.Rid,
+			&item.Name,
+			&item.Sale,
+			&item.Size,
+			&item.TotalPrice,
+			&item.NmID,
+			&item.Brand,
+			&item.Status,
+		)
+		if err != nil {
+			return err
+		}
+		*items = append(*items, item)
+	}
+	return rows.Err()
+}
+
+// GetOrders retrieves multiple orders, optionally limited by a specified amount.
+// Each order includes delivery, payment, and item details.
+//
+// Items for all orders are loaded with a single query, so the number of round-trips
+// stays constant regardless of how many orders are returned.
+func (s *Storage) GetOrders(ctx context.Context, amount ...int) ([]*models.Order, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	query := ordersSelect
+
+	if len(amount) > 0 && amount[0] > 0 {
+		query += fmt.Sprintf("\nLIMIT %d", amount[0])
+	}
+
+	orders, orderIds, err := queryOrders(ctx, s, query)
+	if err != nil {
+		return nil, mapError(ctx, err)
+	}
+	if err := queryItemsBulk(ctx, s, orders, orderIds); err != nil {
+		return nil, mapError(ctx, err)
+	}
+	return orders, nil
+}
+
+// ordersSelect selects order, delivery, and payment columns in the order expected by queryOrders.
+// Callers append their own WHERE, ORDER BY, and LIMIT clauses.
+const ordersSelect = `SELECT 
+
+        orders.id,
+        orders.order_uid, 
+        orders.track_number, 
+        orders.entry, 
+        orders.locale, 
+        orders.internal_signature,
+        orders.customer_id,
+        orders.delivery_service,
+        orders.shardkey,
+        orders.sm_id,
+        orders.date_created,
+        orders.oof_shard,
+        orders.version,
+
+        deliveries.name, 
+        deliveries.phone, 
+        deliveries.zip, 
+        deliveries.city, 
+        deliveries.address,
+        deliveries.region,
+        deliveries.email,
+
+        payments."transaction", 
+        payments.request_id, 
+        payments.currency, 
+        payments.provider, 
+        payments.amount,
+        payments.payment_dt,
+        payments.bank,
+        payments.delivery_cost,
+        payments.goods_total,
+        payments.custom_fee
+
+    FROM orders 
+    JOIN deliveries ON orders.id = deliveries.order_id
+    JOIN payments ON orders.id = payments.order_id`
+
+// queryOrders runs a query built on top of ordersSelect and returns the scanned orders
+// along with their database IDs. Items are not loaded.
+//
+// All rows are read and closed before returning, so the connection is free for the follow-up items query.
+func queryOrders(ctx context.Context, s *Storage, query string, args ...any) ([]*models.Order, []int64, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var orders []*models.Order
+	var orderIds []int64
+
+	for rows.Next() {
+		order := new(models.Order)
+		var orderId int64
+		var paymentTime time.Time
+
+		err := rows.Scan(
+			&orderId,
+			&order.OrderUID,
+			&order.TrackNumber,
+			&order.Entry,
+			&order.Locale,
+			&order.InternalSignature,
+			&order.CustomerID,
+			&order.DeliveryService,
+			&order.ShardKey,
+			&order.SmID,
+			&order.DateCreated,
+			&order.OofShard,
+			&order.Version,
+
+			&order.Delivery.Name,
+			&order.Delivery.Phone,
+			&order.Delivery.Zip,
+			&order.Delivery.City,
+			&order.Delivery.Address,
+			&order.Delivery.Region,
+			&order.Delivery.Email,
+
+			&order.Payment.Transaction,
+			&order.Payment.RequestID,
+			&order.Payment.Currency,
+			&order.Payment.Provider,
+			&order.Payment.Amount,
+			&paymentTime,
+			&order.Payment.Bank,
+			&order.Payment.DeliveryCost,
+			&order.Payment.GoodsTotal,
+			&order.Payment.CustomFee,
+		)
+		if err != nil {
+			return nil, nil, err
+		}
+		order.Payment.PaymentDT = paymentTime.Unix()
+		orders = append(orders, order)
+		orderIds = append(orderIds, orderId)
+	}
+	return orders, orderIds, rows.Err()
+}
+
+// queryItemsBulk loads items for all given orders with a single query and attaches them to their orders.
+// orders and orderIds must be parallel slices, as returned by queryOrders.
+// SQLite has no array parameters, so the IDs are passed as a JSON array and expanded with json_each.
+func queryItemsBulk(ctx context.Context, s *Storage, orders []*models.Order, orderIds []int64) error {
+	if len(orderIds) == 0 {
+		return nil
+	}
+	query := `SELECT 
+        order_id,
+        chrt_id,
+        track_number,
+        price,
+        rid,
+        name,
+        sale,
+        size,
+        total_price,
+        nm_id,
+        brand,
+        status
+        FROM items WHERE order_id IN (SELECT value FROM json_each($1))
+        ORDER BY order_id, id`
+
+	ids, err := json.Marshal(orderIds)
+	if err != nil {
+		return fmt.Errorf("failed to marshal order ids: %v", err)
+	}
+	rows, err := s.db.QueryContext(ctx, query, string(ids))
+	if err != nil {
+		return err
+	}
+
+	defer func() { _ = rows.Close() }()
+
+	byId := make(map[int64]*models.Order, len(orders))
+	for i, order := range orders {
+		byId[orderIds[i]] = order
+	}
+
+	for rows.Next() {
+		var orderId int64
+		var item models.Item
+		err := rows.Scan(
+			&orderId,
+			&item.ChrtID,
+			&item.TrackNumber,
+			&item.Price,
+			&item.Rid,
+			&item.Name,
+			&item.Sale,
+			&item.Size,
+			&item.TotalPrice,
+			&item.NmID,
+			&item.Brand,
+			&item.Status,
+		)
+		if err != nil {
+			return err
+		}
+		if order, found := byId[orderId]; found {
+			order.Items = append(order.Items, item)
+		}
+	}
+	return rows.Err()
+}
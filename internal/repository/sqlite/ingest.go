@@ -0,0 +1,61 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/models"
+)
+
+// insertIngestRecord records the message an order was received in within the order's transaction.
+// Timestamps are stored in UTC, a missing message timestamp as NULL, and so are missing violations.
+func insertIngestRecord(ctx context.Context, tx *sql.Tx, orderUID string, record *models.IngestRecord) error {
+	timestamp := sql.NullTime{Time: record.Timestamp.UTC(), Valid: !record.Timestamp.IsZero()}
+	var violations sql.NullString
+	if len(record.Violations) > 0 {
+		encoded, _ := json.Marshal(record.Violations) // plain strings always encode
+		violations = sql.NullString{String: string(encoded), Valid: true}
+	}
+	_, err := tx.ExecContext(ctx, `INSERT INTO order_ingest_log (order_uid, topic, kafka_partition, kafka_offset,
+        message_key, message_timestamp, ingested_at, payload, violations)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+		orderUID, record.Topic, record.Partition, record.Offset, record.Key, timestamp, record.IngestedAt.UTC(), string(record.Payload), violations)
+	return err
+}
+
+// GetOrderProvenance returns the message an order was received in.
+// Returns errs.ErrNotFound if no message was recorded for the order.
+func (s *Storage) GetOrderProvenance(ctx context.Context, orderUID string) (models.OrderProvenance, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	provenance := models.OrderProvenance{OrderUID: orderUID}
+	var timestamp sql.NullTime
+	var payload string
+	var violations sql.NullString
+	err := s.db.QueryRowContext(ctx, `SELECT topic, kafka_partition, kafka_offset, message_key, message_timestamp, ingested_at, payload, violations
+        FROM order_ingest_log
+        WHERE order_uid = $1`, orderUID).Scan(
+		&provenance.Topic,
+		&provenance.Partition,
+		&provenance.Offset,
+		&provenance.Key,
+		&timestamp,
+		&provenance.IngestedAt,
+		&payload,
+		&violations,
+	)
+	if err != nil {
+		return models.OrderProvenance{}, mapError(ctx, err)
+	}
+	if violations.Valid {
+		if err := json.Unmarshal([]byte(violations.String), &provenance.Violations); err != nil {
+			return models.OrderProvenance{}, fmt.Errorf("malformed violations of order %s: %w", orderUID, err)
+		}
+	}
+	provenance.Timestamp = timestamp.Time
+	provenance.Payload = []byte(payload)
+	return provenance, nil
+}
@@ -0,0 +1,40 @@
+package sqlite_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/models"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository/errs"
+)
+
+func TestSQLiteStorer_GetOrderProvenance(t *testing.T) {
+	storage, _ := newTestStorage(t)
+	ingestedAt := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	order := testOrder(1)
+	order.Ingest = &models.IngestRecord{Topic: "orders", Partition: 2, Offset: 42, Key: order.OrderUID,
+		IngestedAt: ingestedAt, Payload: []byte(`{"order_uid":"` + order.OrderUID + `"}`),
+		Violations: []models.RuleViolation{{Rule: "payment_amount", Severity: models.SeverityFlag, Message: "off"}}}
+
+	if err := storage.SaveOrder(context.Background(), order); err != nil {
+		t.Fatalf("SaveOrder failed: %v", err)
+	}
+	if err := storage.SaveOrder(context.Background(), testOrder(2)); err != nil {
+		t.Fatalf("SaveOrder failed: %v", err)
+	}
+
+	provenance, err := storage.GetOrderProvenance(context.Background(), order.OrderUID)
+	if err != nil {
+		t.Fatalf("GetOrderProvenance failed: %v", err)
+	}
+	if provenance.Topic != "orders" || provenance.Partition != 2 || provenance.Offset != 42 || provenance.Key != order.OrderUID ||
+		!provenance.IngestedAt.Equal(ingestedAt) || !provenance.Timestamp.IsZero() || string(provenance.Payload) != string(order.Ingest.Payload) ||
+		len(provenance.Violations) != 1 || provenance.Violations[0] != order.Ingest.Violations[0] {
+		t.Fatalf("unexpected provenance: %+v", provenance)
+	}
+	if _, err := storage.GetOrderProvenance(context.Background(), testOrder(2).OrderUID); !errors.Is(err, errs.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound for an order saved without ingest record, got %v", err)
+	}
+}
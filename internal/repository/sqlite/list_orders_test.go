@@ -0,0 +1,142 @@
+package sqlite_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/models"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository/errs"
+)
+
+func TestSQLiteStorer_ListOrders_Pagination(t *testing.T) {
+	storage, _ := newTestStorage(t)
+	for n := 1; n <= 5; n++ {
+		if err := storage.SaveOrder(context.Background(), testOrder(n)); err != nil {
+			t.Fatalf("SaveOrder failed: %v", err)
+		}
+	}
+
+	tests := []struct {
+		sort models.OrderSort
+		want []string
+	}{
+		{models.SortDateDesc, []string{"uid5", "uid4", "uid3", "uid2", "uid1"}},
+		{models.SortDateAsc, []string{"uid1", "uid2", "uid3", "uid4", "uid5"}},
+		{models.SortAmountDesc, []string{"uid5", "uid4", "uid3", "uid2", "uid1"}},
+		{models.SortAmountAsc, []string{"uid1", "uid2", "uid3", "uid4", "uid5"}},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.sort), func(t *testing.T) {
+			query := models.OrderQuery{Sort: tt.sort, Limit: 2}
+			var got []string
+			for pages := 0; ; pages++ {
+				if pages > 3 {
+					t.Fatal("pagination did not terminate")
+				}
+				page, err := storage.ListOrders(context.Background(), query)
+				if err != nil {
+					t.Fatalf("ListOrders failed: %v", err)
+				}
+				for _, order := range page.Orders {
+					got = append(got, order.OrderUID)
+					if len(order.Items) != 2 {
+						t.Errorf("order %s: expected 2 items, got %d", order.OrderUID, len(order.Items))
+					}
+				}
+				if page.NextCursor == "" {
+					break
+				}
+				query.Cursor = page.NextCursor
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("expected %v, got %v", tt.want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestSQLiteStorer_ListOrders_Filters(t *testing.T) {
+	storage, _ := newTestStorage(t)
+	for n := 1; n <= 4; n++ {
+		order := testOrder(n)
+		if n%2 == 0 {
+			order.Payment.Currency = "RUB"
+			order.Items[0].Status = 300
+		}
+		if err := storage.SaveOrder(context.Background(), order); err != nil {
+			t.Fatalf("SaveOrder failed: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		filter models.OrderFilter
+		want   int
+	}{
+		{"currency", models.OrderFilter{Currency: "RUB"}, 2},
+		{"item status", models.OrderFilter{ItemStatus: 300}, 2},
+		{"created range", models.OrderFilter{
+			CreatedFrom: time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC),
+			CreatedTo:   time.Date(2025, 1, 4, 0, 0, 0, 0, time.UTC),
+		}, 2},
+		{"no match", models.OrderFilter{CustomerID: "nobody"}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := storage.ListOrders(context.Background(), models.OrderQuery{Filter: tt.filter, Limit: 10})
+			if err != nil {
+				t.Fatalf("ListOrders failed: %v", err)
+			}
+			if len(page.Orders) != tt.want {
+				t.Fatalf("expected %d orders, got %d", tt.want, len(page.Orders))
+			}
+		})
+	}
+}
+
+func TestSQLiteStorer_ListOrders_InvalidCursor(t *testing.T) {
+	storage, _ := newTestStorage(t)
+
+	_, err := storage.ListOrders(context.Background(), models.OrderQuery{Cursor: "garbage", Limit: 10})
+	if !errors.Is(err, errs.ErrInvalidArgument) {
+		t.Fatalf("expected ErrInvalidArgument, got %v", err)
+	}
+}
+
+func TestSQLiteStorer_GetCustomerStats(t *testing.T) {
+	storage, _ := newTestStorage(t)
+	for n := 1; n <= 3; n++ {
+		order := testOrder(n)
+		if n == 3 {
+			order.Payment.Currency = "RUB"
+		}
+		if err := storage.SaveOrder(context.Background(), order); err != nil {
+			t.Fatalf("SaveOrder failed: %v", err)
+		}
+	}
+
+	stats, err := storage.GetCustomerStats(context.Background(), "customer")
+	if err != nil {
+		t.Fatalf("GetCustomerStats failed: %v", err)
+	}
+	if stats.OrderCount != 3 {
+		t.Errorf("expected 3 orders, got %d", stats.OrderCount)
+	}
+	if stats.TotalSpend["USD"] != 300 || stats.TotalSpend["RUB"] != 300 {
+		t.Errorf("unexpected total spend: %v", stats.TotalSpend)
+	}
+	if want := time.Date(2025, 1, 3, 10, 0, 0, 0, time.UTC); !stats.LastOrderDate.Equal(want) {
+		t.Errorf("expected last order date %v, got %v", want, stats.LastOrderDate)
+	}
+
+	if _, err := storage.GetCustomerStats(context.Background(), "nobody"); !errors.Is(err, errs.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
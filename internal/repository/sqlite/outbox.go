@@ -0,0 +1,115 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/models"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository/errs"
+)
+
+// insertOutboxEvent records an "order.accepted" event for the order within the order's transaction,
+// so the event is stored if and only if the order is committed.
+func insertOutboxEvent(ctx context.Context, tx *sql.Tx, order *models.Order) error {
+	now := time.Now().UTC()
+	payload, err := json.Marshal(models.NewOrderAccepted(order, now))
+	if err != nil {
+		return fmt.Errorf("failed to marshal outbox event: %v", err)
+	}
+	_, err = tx.ExecContext(ctx, `INSERT INTO outbox (event_type, event_key, payload, created_at, next_attempt_at)
+        VALUES ($1, $2, $3, $4, $4)`,
+		models.EventOrderAccepted, order.OrderUID, string(payload), now)
+	return err
+}
+
+/*
+ClaimOutbox returns up to limit unsent events that are due for publishing, oldest first.
+
+Claimed events are leased: their next attempt is moved lease into the future, so the relay
+doesn't pick them up again while they are being published. If the relay dies before marking them,
+the events become due again once the lease expires. SQLite serializes writers, so claims never overlap.
+*/
+func (s *Storage) ClaimOutbox(ctx context.Context, limit int, lease time.Duration) ([]models.OutboxEvent, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	now := time.Now().UTC()
+	rows, err := s.db.QueryContext(ctx, `UPDATE outbox SET next_attempt_at = $2
+    WHERE id IN (
+        SELECT id FROM outbox
+        WHERE sent_at IS NULL AND next_attempt_at <= $1
+        ORDER BY next_attempt_at, id
+        LIMIT $3
+    )
+    RETURNING id, event_type, event_key, payload, attempts, created_at`,
+		now, now.Add(lease), limit)
+	if err != nil {
+		return nil, mapError(ctx, err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var events []models.OutboxEvent
+	for rows.Next() {
+		var event models.OutboxEvent
+		if err := rows.Scan(&event.ID, &event.Type, &event.Key, &event.Payload, &event.Attempts, &event.CreatedAt); err != nil {
+			return nil, mapError(ctx, err)
+		}
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, mapError(ctx, err)
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].ID < events[j].ID }) // RETURNING has no defined order
+	return events, nil
+}
+
+// MarkOutboxSent marks an event as published, so it is never claimed again.
+// Returns errs.ErrNotFound if there is no such event.
+func (s *Storage) MarkOutboxSent(ctx context.Context, id int64) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	result, err := s.db.ExecContext(ctx, `UPDATE outbox SET sent_at = $2, last_error = NULL WHERE id = $1`, id, time.Now().UTC())
+	return outboxUpdateResult(ctx, result, err, id)
+}
+
+// MarkOutboxFailed records a failed publish attempt and schedules the next one at retryAt.
+// Returns errs.ErrNotFound if there is no such event.
+func (s *Storage) MarkOutboxFailed(ctx context.Context, id int64, retryAt time.Time, reason string) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	result, err := s.db.ExecContext(ctx, `UPDATE outbox
+        SET attempts = attempts + 1, next_attempt_at = $2, last_error = $3
+        WHERE id = $1`, id, retryAt.UTC(), reason)
+	return outboxUpdateResult(ctx, result, err, id)
+}
+
+// PurgeOutbox deletes the events published before sentBefore and returns how many were deleted.
+// Unsent events are never deleted, however old they are.
+func (s *Storage) PurgeOutbox(ctx context.Context, sentBefore time.Time) (int64, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	result, err := s.db.ExecContext(ctx, `DELETE FROM outbox WHERE sent_at IS NOT NULL AND sent_at < $1`, sentBefore.UTC())
+	if err != nil {
+		return 0, mapError(ctx, err)
+	}
+	purged, err := result.RowsAffected()
+	if err != nil {
+		return 0, mapError(ctx, err)
+	}
+	return purged, nil
+}
+
+// outboxUpdateResult maps the result of an update of a single outbox event.
+func outboxUpdateResult(ctx context.Context, result sql.Result, err error, id int64) error {
+	if err != nil {
+		return mapError(ctx, err)
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return fmt.Errorf("outbox event %d: %w", id, errs.ErrNotFound)
+	}
+	return nil
+}
@@ -0,0 +1,168 @@
+package sqlite_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/models"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository/errs"
+)
+
+func TestSQLiteStorer_SaveOrder_WritesOutboxEvent(t *testing.T) {
+	storage, _ := newTestStorage(t)
+	ctx := context.Background()
+	if err := storage.SaveOrder(ctx, testOrder(1)); err != nil {
+		t.Fatalf("SaveOrder failed: %v", err)
+	}
+
+	events, err := storage.ClaimOutbox(ctx, 10, time.Minute)
+	if err != nil {
+		t.Fatalf("ClaimOutbox failed: %v", err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("expected 1 event, got %d", len(events))
+	}
+	if events[0].Type != models.EventOrderAccepted || events[0].Key != "uid1" || events[0].Attempts != 0 {
+		t.Fatalf("unexpected event: %+v", events[0])
+	}
+	var payload models.OrderAccepted
+	if err := json.Unmarshal(events[0].Payload, &payload); err != nil {
+		t.Fatalf("failed to decode payload: %v", err)
+	}
+	if payload.OrderUID != "uid1" || payload.ItemCount != 2 || payload.Amount != 100 {
+		t.Fatalf("unexpected payload: %+v", payload)
+	}
+}
+
+func TestSQLiteStorer_SaveOrder_DuplicateWritesNoEvent(t *testing.T) {
+	storage, db := newTestStorage(t)
+	ctx := context.Background()
+	if err := storage.SaveOrder(ctx, testOrder(1)); err != nil {
+		t.Fatalf("SaveOrder failed: %v", err)
+	}
+	if err := storage.SaveOrder(ctx, testOrder(1)); !errors.Is(err, errs.ErrDuplicate) {
+		t.Fatalf("expected ErrDuplicate, got %v", err)
+	}
+
+	var count int
+	if err := db.Get(&count, `SELECT COUNT(*) FROM outbox`); err != nil {
+		t.Fatalf("failed to count events: %v", err)
+	}
+	if count != 1 {
+		t.Fatalf("expected 1 event, got %d", count)
+	}
+}
+
+func TestSQLiteStorer_ClaimOutbox_LeaseHidesEvents(t *testing.T) {
+	storage, _ := newTestStorage(t)
+	ctx := context.Background()
+	for n := 1; n <= 3; n++ {
+		if err := storage.SaveOrder(ctx, testOrder(n)); err != nil {
+			t.Fatalf("SaveOrder failed: %v", err)
+		}
+	}
+
+	first, err := storage.ClaimOutbox(ctx, 2, time.Minute)
+	if err != nil {
+		t.Fatalf("ClaimOutbox failed: %v", err)
+	}
+	if len(first) != 2 || first[0].Key != "uid1" || first[1].Key != "uid2" {
+		t.Fatalf("expected the two oldest events, got %+v", first)
+	}
+	second, err := storage.ClaimOutbox(ctx, 2, time.Minute)
+	if err != nil {
+		t.Fatalf("ClaimOutbox failed: %v", err)
+	}
+	if len(second) != 1 || second[0].Key != "uid3" {
+		t.Fatalf("expected only the unclaimed event, got %+v", second)
+	}
+}
+
+func TestSQLiteStorer_ClaimOutbox_ExpiredLeaseIsReclaimed(t *testing.T) {
+	storage, _ := newTestStorage(t)
+	ctx := context.Background()
+	if err := storage.SaveOrder(ctx, testOrder(1)); err != nil {
+		t.Fatalf("SaveOrder failed: %v", err)
+	}
+
+	if events, err := storage.ClaimOutbox(ctx, 10, -time.Second); err != nil || len(events) != 1 {
+		t.Fatalf("expected 1 claimed event, got %d (%v)", len(events), err)
+	}
+	if events, err := storage.ClaimOutbox(ctx, 10, time.Minute); err != nil || len(events) != 1 {
+		t.Fatalf("expected the event to be claimed again after its lease expired, got %d (%v)", len(events), err)
+	}
+}
+
+func TestSQLiteStorer_MarkOutbox(t *testing.T) {
+	storage, db := newTestStorage(t)
+	ctx := context.Background()
+	for n := 1; n <= 2; n++ {
+		if err := storage.SaveOrder(ctx, testOrder(n)); err != nil {
+			t.Fatalf("SaveOrder failed: %v", err)
+		}
+	}
+	events, err := storage.ClaimOutbox(ctx, 10, time.Minute)
+	if err != nil || len(events) != 2 {
+		t.Fatalf("expected 2 claimed events, got %d (%v)", len(events), err)
+	}
+
+	if err := storage.MarkOutboxSent(ctx, events[0].ID); err != nil {
+		t.Fatalf("MarkOutboxSent failed: %v", err)
+	}
+	if err := storage.MarkOutboxFailed(ctx, events[1].ID, time.Now().Add(-time.Second), "broker down"); err != nil {
+		t.Fatalf("MarkOutboxFailed failed: %v", err)
+	}
+
+	retried, err := storage.ClaimOutbox(ctx, 10, time.Minute)
+	if err != nil {
+		t.Fatalf("ClaimOutbox failed: %v", err)
+	}
+	if len(retried) != 1 || retried[0].ID != events[1].ID || retried[0].Attempts != 1 {
+		t.Fatalf("expected only the failed event with 1 attempt, got %+v", retried)
+	}
+	var lastError string
+	if err := db.Get(&lastError, `SELECT last_error FROM outbox WHERE id = $1`, events[1].ID); err != nil {
+		t.Fatalf("failed to read last error: %v", err)
+	}
+	if lastError != "broker down" {
+		t.Fatalf("expected last error to be recorded, got %q", lastError)
+	}
+
+	if err := storage.MarkOutboxSent(ctx, 999); !errors.Is(err, errs.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound for a missing event, got %v", err)
+	}
+}
+
+func TestSQLiteStorer_PurgeOutbox(t *testing.T) {
+	storage, db := newTestStorage(t)
+	ctx := context.Background()
+	for n := 1; n <= 2; n++ {
+		if err := storage.SaveOrder(ctx, testOrder(n)); err != nil {
+			t.Fatalf("SaveOrder failed: %v", err)
+		}
+	}
+	events, err := storage.ClaimOutbox(ctx, 10, time.Minute)
+	if err != nil || len(events) != 2 {
+		t.Fatalf("expected 2 claimed events, got %d (%v)", len(events), err)
+	}
+	if err := storage.MarkOutboxSent(ctx, events[0].ID); err != nil {
+		t.Fatalf("MarkOutboxSent failed: %v", err)
+	}
+
+	if purged, err := storage.PurgeOutbox(ctx, time.Now().Add(-time.Hour)); err != nil || purged != 0 {
+		t.Fatalf("expected nothing published an hour ago to be purged, got %d (%v)", purged, err)
+	}
+	if purged, err := storage.PurgeOutbox(ctx, time.Now().Add(time.Second)); err != nil || purged != 1 {
+		t.Fatalf("expected only the published event to be purged, got %d (%v)", purged, err)
+	}
+	var left []int64
+	if err := db.Select(&left, `SELECT id FROM outbox`); err != nil {
+		t.Fatalf("failed to read the outbox: %v", err)
+	}
+	if len(left) != 1 || left[0] != events[1].ID {
+		t.Fatalf("expected the unsent event to be kept, got %v", left)
+	}
+}
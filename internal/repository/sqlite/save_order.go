@@ -0,0 +1,285 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/models"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository/errs"
+)
+
+// SaveOrder inserts a complete order with delivery, payment, and items into the database as a single transaction.
+// The message the order was received in, if known, and an "order.accepted" event are written in the same transaction.
+// Timestamps are stored in UTC.
+//
+// Saving is idempotent: if an identical order is already stored, errs.ErrDuplicate is returned
+// and nothing is written. If an order with the same UID or track number is stored but its content
+// differs, errs.ErrConflict is returned instead.
+func (s *Storage) SaveOrder(ctx context.Context, order *models.Order) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", mapError(ctx, err))
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := saveOrder(ctx, tx, order); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("transaction commit failed: %w", mapError(ctx, err))
+	}
+	return nil
+}
+
+// saveOrder writes the order along with its ingest record and outbox event within tx.
+// If the order is already stored, nothing is written and errs.ErrDuplicate or errs.ErrConflict is returned.
+func saveOrder(ctx context.Context, tx *sql.Tx, order *models.Order) error {
+	checksum := models.OrderChecksum(order)
+	orderId, err := insertOrder(ctx, tx, order, checksum)
+	if errors.Is(err, sql.ErrNoRows) {
+		return checkStoredOrder(ctx, tx, order.OrderUID, checksum)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to insert order: %w", mapError(ctx, err))
+	}
+	if err := insertDelivery(ctx, tx, &order.Delivery, orderId); err != nil {
+		return fmt.Errorf("failed to insert delivery: %w", mapError(ctx, err))
+	}
+	if err := insertPayment(ctx, tx, &order.Payment, orderId); err != nil {
+		return fmt.Errorf("failed to insert payment: %w", mapError(ctx, err))
+	}
+	for i := range order.Items {
+		if err := insertItem(ctx, tx, &order.Items[i], orderId); err != nil {
+			return fmt.Errorf("failed to insert item: %w", mapError(ctx, err))
+		}
+	}
+	if order.Ingest != nil {
+		if err := insertIngestRecord(ctx, tx, order.OrderUID, order.Ingest); err != nil {
+			return fmt.Errorf("failed to insert ingest record: %w", mapError(ctx, err))
+		}
+	}
+	if err := insertOutboxEvent(ctx, tx, order); err != nil {
+		return fmt.Errorf("failed to insert outbox event: %w", mapError(ctx, err))
+	}
+	return nil
+}
+
+// checkStoredOrder is called when the order insert hit a unique constraint.
+// It compares the stored checksum with the incoming one to tell a redelivery from a conflict.
+// Orders saved before checksums were introduced can't be verified and are reported as conflicts.
+func checkStoredOrder(ctx context.Context, tx *sql.Tx, orderUID string, checksum string) error {
+	var storedChecksum sql.NullString
+	err := tx.QueryRowContext(ctx, `SELECT checksum FROM orders WHERE order_uid = $1`, orderUID).Scan(&storedChecksum)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("track number of order %s belongs to another order: %w", orderUID, errs.ErrConflict)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get stored order checksum: %w", mapError(ctx, err))
+	}
+	if storedChecksum.Valid && storedChecksum.String == checksum {
+		return fmt.Errorf("order %s: %w", orderUID, errs.ErrDuplicate)
+	}
+	return fmt.Errorf("order %s: %w", orderUID, errs.ErrConflict)
+}
+
+// insertOrder inserts the main order record and returns the generated order ID.
+// If the order UID or track number is already taken, nothing is inserted and sql.ErrNoRows is returned.
+func insertOrder(ctx context.Context, tx *sql.Tx, order *models.Order, checksum string) (int, error) {
+	var id int
+	query := `
+	INSERT INTO orders (
+		order_uid, 
+		track_number, 
+		entry, 
+		locale, 
+		internal_signature, 
+		customer_id, 
+		delivery_service, 
+		shardkey, 
+		sm_id, 
+		date_created, 
+		oof_shard,
+		checksum
+	) 
+	VALUES (
+		$1, 
+		$2, 
+		$3, 
+		$4, 
+		$5, 
+		$6, 
+		$7, 
+		$8, 
+		$9, 
+		$10, 
+		$11,
+		$12
+	) 
+	ON CONFLICT DO NOTHING
+	RETURNING id`
+
+	row := tx.QueryRowContext(
+		ctx,
+		query,
+		order.OrderUID,
+		order.TrackNumber,
+		order.Entry,
+		order.Locale,
+		order.InternalSignature,
+		order.CustomerID,
+		order.DeliveryService,
+		order.ShardKey,
+		order.SmID,
+		order.DateCreated.UTC(),
+		order.OofShard,
+		checksum)
+	if err := row.Scan(&id); err != nil {
+		return id, fmt.Errorf("row.Scan failed to get order id: %w", err)
+	}
+	return id, nil
+}
+
+// insertDelivery inserts delivery details associated with the given order ID
+func insertDelivery(ctx context.Context, tx *sql.Tx, delivery *models.Delivery, orderID int) error {
+	query := `
+	INSERT INTO deliveries (
+		order_id,
+		name,
+		phone, 
+		zip,
+		city,
+		address,
+		region, 
+		email
+	) 
+	VALUES (
+		$1, 
+		$2, 
+		$3, 
+		$4, 
+		$5, 
+		$6, 
+		$7, 
+		$8
+	)`
+
+	_, err := tx.ExecContext(
+		ctx,
+		query,
+		orderID,
+		delivery.Name,
+		delivery.Phone,
+		delivery.Zip,
+		delivery.City,
+		delivery.Address,
+		delivery.Region,
+		delivery.Email)
+
+	return err
+}
+
+// insertPayment inserts payment details associated with the given order ID
+func insertPayment(ctx context.Context, tx *sql.Tx, payment *models.Payment, orderID int) error {
+	paymentTime := time.Unix(payment.PaymentDT, 0).UTC()
+	query := `
+	INSERT INTO payments (
+		order_id,
+		"transaction",
+		request_id, 
+		currency, 
+		provider, 
+		amount, 
+		payment_dt, 
+		bank,
+		delivery_cost,
+		goods_total,
+		custom_fee
+	) 
+	VALUES (
+		$1, 
+		$2, 
+		$3, 
+		$4, 
+		$5, 
+		$6, 
+		$7, 
+		$8,
+		$9,
+		$10,
+		$11 
+	)`
+
+	_, err := tx.ExecContext(
+		ctx,
+		query,
+		orderID,
+		payment.Transaction,
+		payment.RequestID,
+		payment.Currency,
+		payment.Provider,
+		payment.Amount,
+		paymentTime,
+		payment.Bank,
+		payment.DeliveryCost,
+		payment.GoodsTotal,
+		payment.CustomFee)
+
+	return err
+}
+
+// insertItem inserts a single item associated with the given order ID
+func insertItem(ctx context.Context, tx *sql.Tx, item *models.Item, orderID int) error {
+	query := `
+	INSERT INTO items (
+		order_id,
+		chrt_id,
+    	track_number,
+    	price,
+    	rid,
+    	name,
+    	sale,
+    	size,
+    	total_price,
+    	nm_id,
+    	brand,
+    	status
+	) 
+	VALUES (
+		$1, 
+		$2, 
+		$3, 
+		$4, 
+		$5, 
+		$6, 
+		$7, 
+		$8,
+		$9,
+		$10,
+		$11,
+		$12
+	)`
+
+	_, err := tx.ExecContext(
+		ctx,
+		query,
+		orderID,
+		item.ChrtID,
+		item.TrackNumber,
+		item.Price,
+		item.Rid,
+		item.Name,
+		item.Sale,
+		item.Size,
+		item.TotalPrice,
+		item.NmID,
+		item.Brand,
+		item.Status,
+	)
+	return err
+}
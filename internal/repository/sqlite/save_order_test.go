@@ -0,0 +1,147 @@
+package sqlite_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository/errs"
+)
+
+func TestSQLiteStorer_SaveAndGetOrder(t *testing.T) {
+	storage, _ := newTestStorage(t)
+	order := testOrder(1)
+
+	if err := storage.SaveOrder(context.Background(), order); err != nil {
+		t.Fatalf("SaveOrder failed: %v", err)
+	}
+	got, err := storage.GetOrder(context.Background(), order.OrderUID)
+	if err != nil {
+		t.Fatalf("GetOrder failed: %v", err)
+	}
+	got.DateCreated = got.DateCreated.UTC() // the driver returns a fixed +00:00 zone
+	order.Version = 1                       // every stored order starts at version 1
+	if !reflect.DeepEqual(got, order) {
+		t.Fatalf("stored order differs:\nwant %+v\ngot  %+v", order, got)
+	}
+
+	byTrack, err := storage.GetOrderByTrackNumber(context.Background(), order.TrackNumber)
+	if err != nil {
+		t.Fatalf("GetOrderByTrackNumber failed: %v", err)
+	}
+	byTrack.DateCreated = byTrack.DateCreated.UTC()
+	if !reflect.DeepEqual(byTrack, order) {
+		t.Fatalf("order by track number differs:\nwant %+v\ngot  %+v", order, byTrack)
+	}
+}
+
+func TestSQLiteStorer_SaveOrder_Duplicate(t *testing.T) {
+	storage, _ := newTestStorage(t)
+
+	if err := storage.SaveOrder(context.Background(), testOrder(1)); err != nil {
+		t.Fatalf("SaveOrder failed: %v", err)
+	}
+	err := storage.SaveOrder(context.Background(), testOrder(1))
+	if !errors.Is(err, errs.ErrDuplicate) {
+		t.Fatalf("expected ErrDuplicate, got %v", err)
+	}
+}
+
+func TestSQLiteStorer_SaveOrder_Conflict(t *testing.T) {
+	storage, _ := newTestStorage(t)
+
+	if err := storage.SaveOrder(context.Background(), testOrder(1)); err != nil {
+		t.Fatalf("SaveOrder failed: %v", err)
+	}
+
+	changed := testOrder(1)
+	changed.Payment.Amount = 1
+	if err := storage.SaveOrder(context.Background(), changed); !errors.Is(err, errs.ErrConflict) {
+		t.Fatalf("expected ErrConflict for changed content, got %v", err)
+	}
+
+	sameTrack := testOrder(2)
+	sameTrack.TrackNumber = "TRACK1"
+	for i := range sameTrack.Items {
+		sameTrack.Items[i].TrackNumber = "TRACK1"
+	}
+	if err := storage.SaveOrder(context.Background(), sameTrack); !errors.Is(err, errs.ErrConflict) {
+		t.Fatalf("expected ErrConflict for taken track number, got %v", err)
+	}
+}
+
+func TestSQLiteStorer_SaveOrder_ConstraintRollsBack(t *testing.T) {
+	storage, _ := newTestStorage(t)
+	order := testOrder(1)
+	order.Items[1].TrackNumber = "UNKNOWN" // violates fk_item_track_number
+
+	err := storage.SaveOrder(context.Background(), order)
+	if !errors.Is(err, errs.ErrConstraint) {
+		t.Fatalf("expected ErrConstraint, got %v", err)
+	}
+	if _, err := storage.GetOrder(context.Background(), order.OrderUID); !errors.Is(err, errs.ErrNotFound) {
+		t.Fatalf("expected the order to be rolled back, got %v", err)
+	}
+}
+
+func TestSQLiteStorer_DeleteOrderCascades(t *testing.T) {
+	storage, db := newTestStorage(t)
+	if err := storage.SaveOrder(context.Background(), testOrder(1)); err != nil {
+		t.Fatalf("SaveOrder failed: %v", err)
+	}
+
+	if _, err := db.Exec(`DELETE FROM orders WHERE order_uid = 'uid1'`); err != nil {
+		t.Fatalf("delete failed: %v", err)
+	}
+	for _, table := range []string{"deliveries", "payments", "items"} {
+		var count int
+		if err := db.Get(&count, "SELECT COUNT(*) FROM "+table); err != nil {
+			t.Fatalf("failed to count %s: %v", table, err)
+		}
+		if count != 0 {
+			t.Errorf("expected %s to be cleaned up, got %d rows", table, count)
+		}
+	}
+}
+
+func TestSQLiteStorer_GetOrder_NotFound(t *testing.T) {
+	storage, _ := newTestStorage(t)
+
+	if _, err := storage.GetOrder(context.Background(), "missing"); !errors.Is(err, errs.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	if _, err := storage.GetOrderByTrackNumber(context.Background(), "missing"); !errors.Is(err, errs.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestSQLiteStorer_GetOrders(t *testing.T) {
+	storage, _ := newTestStorage(t)
+	for n := 1; n <= 3; n++ {
+		if err := storage.SaveOrder(context.Background(), testOrder(n)); err != nil {
+			t.Fatalf("SaveOrder failed: %v", err)
+		}
+	}
+
+	orders, err := storage.GetOrders(context.Background())
+	if err != nil {
+		t.Fatalf("GetOrders failed: %v", err)
+	}
+	if len(orders) != 3 {
+		t.Fatalf("expected 3 orders, got %d", len(orders))
+	}
+	for _, order := range orders {
+		if len(order.Items) != 2 {
+			t.Errorf("order %s: expected 2 items, got %d", order.OrderUID, len(order.Items))
+		}
+	}
+
+	limited, err := storage.GetOrders(context.Background(), 2)
+	if err != nil {
+		t.Fatalf("GetOrders failed: %v", err)
+	}
+	if len(limited) != 2 {
+		t.Fatalf("expected 2 orders, got %d", len(limited))
+	}
+}
@@ -0,0 +1,60 @@
+package sqlite
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/models"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository/errs"
+)
+
+/*
+SaveOrders saves a batch of orders, committing once per batch instead of once per order.
+
+Statements of an embedded database are cheap, it is the commit that costs a disk sync,
+so orders are written one by one within a single transaction. Every order is wrapped
+in a savepoint: an order that violates a constraint is rolled back on its own while
+the rest of the batch is kept.
+
+The returned slice holds the outcome of every order, in order: nil if it was saved,
+errs.ErrDuplicate or errs.ErrConflict just like SaveOrder, or errs.ErrConstraint if the order
+can never be saved. The error is returned only if the batch could not be saved at all
+(e.g. the database is unavailable); nothing is saved then.
+*/
+func (s *Storage) SaveOrders(ctx context.Context, orders []*models.Order) ([]error, error) {
+	results := make([]error, len(orders))
+	if len(orders) == 0 {
+		return results, nil
+	}
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", mapError(ctx, err))
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	for i, order := range orders {
+		if _, err := tx.ExecContext(ctx, `SAVEPOINT batch_order`); err != nil {
+			return nil, fmt.Errorf("failed to create savepoint: %w", mapError(ctx, err))
+		}
+		err := saveOrder(ctx, tx, order)
+		if err != nil && ctx.Err() == nil && errors.Is(err, errs.ErrConstraint) {
+			if _, err := tx.ExecContext(ctx, `ROLLBACK TO batch_order`); err != nil {
+				return nil, fmt.Errorf("failed to roll back to savepoint: %w", mapError(ctx, err))
+			}
+		} else if err != nil && !errors.Is(err, errs.ErrDuplicate) && !errors.Is(err, errs.ErrConflict) {
+			return nil, err
+		}
+		if _, err := tx.ExecContext(ctx, `RELEASE batch_order`); err != nil {
+			return nil, fmt.Errorf("failed to release savepoint: %w", mapError(ctx, err))
+		}
+		results[i] = err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("transaction commit failed: %w", mapError(ctx, err))
+	}
+	return results, nil
+}
@@ -0,0 +1,108 @@
+package sqlite_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/models"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository/errs"
+)
+
+func TestSQLiteStorer_SaveOrders(t *testing.T) {
+	storage, db := newTestStorage(t)
+	ctx := context.Background()
+	if err := storage.SaveOrder(ctx, testOrder(1)); err != nil {
+		t.Fatalf("SaveOrder failed: %v", err)
+	}
+
+	conflicting := testOrder(2)
+	conflicting.OrderUID = "uid1"
+	conflicting.Payment.Transaction = "other"
+	unknownTrack := testOrder(4)
+	unknownTrack.Items[1].TrackNumber = "UNKNOWNTRACK" // violates the items foreign key
+	batch := []*models.Order{testOrder(1), testOrder(3), conflicting, unknownTrack, testOrder(5), testOrder(3)}
+
+	results, err := storage.SaveOrders(ctx, batch)
+	if err != nil {
+		t.Fatalf("SaveOrders failed: %v", err)
+	}
+	expected := []error{errs.ErrDuplicate, nil, errs.ErrConflict, errs.ErrConstraint, nil, errs.ErrDuplicate}
+	for i, want := range expected {
+		if (want == nil && results[i] != nil) || !errors.Is(results[i], want) {
+			t.Fatalf("order %d: expected %v, got %v", i, want, results[i])
+		}
+	}
+
+	for _, uid := range []string{"uid3", "uid5"} {
+		if _, err := storage.GetOrder(ctx, uid); err != nil {
+			t.Fatalf("expected %s to be saved, got %v", uid, err)
+		}
+	}
+	if _, err := storage.GetOrder(ctx, "uid4"); !errors.Is(err, errs.ErrNotFound) {
+		t.Fatalf("expected the rejected order to be rolled back, got %v", err)
+	}
+	var items, events int
+	if err := db.Get(&items, `SELECT COUNT(*) FROM items`); err != nil {
+		t.Fatalf("failed to count items: %v", err)
+	}
+	if err := db.Get(&events, `SELECT COUNT(*) FROM outbox`); err != nil {
+		t.Fatalf("failed to count events: %v", err)
+	}
+	if items != 6 || events != 3 {
+		t.Fatalf("expected 6 items and 3 events of 3 saved orders, got %d and %d", items, events)
+	}
+}
+
+func TestSQLiteStorer_SaveOrders_Empty(t *testing.T) {
+	storage, _ := newTestStorage(t)
+	if results, err := storage.SaveOrders(context.Background(), nil); err != nil || len(results) != 0 {
+		t.Fatalf("expected no results, got %v (%v)", results, err)
+	}
+}
+
+// BenchmarkSaveOrders compares saving orders one transaction each with saving them as a batch.
+func BenchmarkSaveOrders(b *testing.B) {
+	for _, n := range []int{10, 100} {
+		b.Run(fmt.Sprintf("per_order/%d", n), func(b *testing.B) {
+			storage, _ := newTestStorage(b)
+			for i := 0; i < b.N; i++ {
+				for _, order := range benchOrders(i, n) {
+					if err := storage.SaveOrder(context.Background(), order); err != nil {
+						b.Fatalf("SaveOrder failed: %v", err)
+					}
+				}
+			}
+		})
+
+		b.Run(fmt.Sprintf("batch/%d", n), func(b *testing.B) {
+			storage, _ := newTestStorage(b)
+			for i := 0; i < b.N; i++ {
+				results, err := storage.SaveOrders(context.Background(), benchOrders(i, n))
+				if err != nil {
+					b.Fatalf("SaveOrders failed: %v", err)
+				}
+				if err := errors.Join(results...); err != nil {
+					b.Fatalf("SaveOrders rejected orders: %v", err)
+				}
+			}
+		})
+	}
+}
+
+// benchOrders returns n orders of the round-th benchmark iteration, unique across rounds.
+func benchOrders(round, n int) []*models.Order {
+	orders := make([]*models.Order, n)
+	for i := range orders {
+		order := testOrder(1)
+		order.OrderUID = fmt.Sprintf("uid%d-%d", round, i)
+		order.TrackNumber = fmt.Sprintf("TRACK%d-%d", round, i)
+		order.Payment.Transaction = order.OrderUID
+		for j := range order.Items {
+			order.Items[j].TrackNumber = order.TrackNumber
+		}
+		orders[i] = order
+	}
+	return orders
+}
@@ -0,0 +1,101 @@
+// Package sqlite provides an embedded SQLite storage implementation for the Storage interface.
+// It is built on a pure-Go driver (modernc.org/sqlite), so the service can run without CGO
+// and without a Postgres server, e.g. on a developer laptop or in tests.
+//
+// The schema mirrors the Postgres one and is managed by the same migrations (see schema/sqlite).
+// Driver errors are mapped to the classes defined in the repository/errs package.
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/configs"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/models"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/pkg/logger"
+	"github.com/jmoiron/sqlx"
+	_ "modernc.org/sqlite"
+)
+
+// DriverName is the value of database.driver that selects this implementation.
+const DriverName = "sqlite"
+
+// timeFormat is the layout timestamps are stored with. All timestamps are converted to UTC
+// before they are written, so string comparison in SQL matches chronological order.
+const timeFormat = "2006-01-02 15:04:05.999999999-07:00"
+
+// Storage wraps the database connection and logger for interacting with SQLite
+type Storage struct {
+	db           *sqlx.DB
+	logger       logger.Logger
+	queryTimeout time.Duration // applied to operations whose context has no deadline
+}
+
+// NewStorage creates a new Storage instance with the provided database connection, configuration and logger
+func NewStorage(db *sqlx.DB, config configs.Database, logger logger.Logger) *Storage {
+	return &Storage{db: db, logger: logger, queryTimeout: config.QueryTimeout}
+}
+
+/*
+Connect opens the SQLite database file at config.DBName. The file and its parent directory are created if needed.
+The schema is not applied here, see repository.CheckSchema.
+
+Foreign keys are enabled on every connection,
+and the pool is limited to a single connection: SQLite allows only one writer at a time,
+so serializing access in the pool is cheaper than retrying on "database is locked".
+Host, port, credentials and the rest of the pool settings are ignored.
+*/
+func Connect(config configs.Database) (*sqlx.DB, error) {
+	if config.DBName == "" {
+		return nil, fmt.Errorf("database file path (dbname) is empty")
+	}
+	if err := os.MkdirAll(filepath.Dir(config.DBName), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create database directory: %v", err)
+	}
+	dsn := fmt.Sprintf("file:%s?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_time_format=sqlite",
+		config.DBName)
+	db, err := sqlx.Open(DriverName, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("database driver not found or DSN invalid: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+	db.SetConnMaxIdleTime(0)
+	db.SetConnMaxLifetime(0)
+	if err = db.Ping(); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("database ping failed: %v", err)
+	}
+	return db, nil
+}
+
+// withTimeout applies the default query timeout to ctx unless the caller has already set a deadline
+func (s *Storage) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok || s.queryTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, s.queryTimeout)
+}
+
+// Ping checks the database connection to ensure it is alive
+func (s *Storage) Ping(ctx context.Context) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	return mapError(ctx, s.db.PingContext(ctx))
+}
+
+// CheckReplicas returns nil: an embedded database has no read replicas.
+func (s *Storage) CheckReplicas(ctx context.Context) []models.ReplicaStatus {
+	return nil
+}
+
+// Close safely closes the database connection and logs the result
+func (s *Storage) Close() {
+	if err := s.db.Close(); err != nil {
+		s.logger.LogError("sqlite — failed to close properly", err, "layer", "repository.sqlite")
+	} else {
+		s.logger.LogInfo("sqlite — stopped", "layer", "repository.sqlite")
+	}
+}
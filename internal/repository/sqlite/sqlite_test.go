@@ -0,0 +1,131 @@
+package sqlite_test
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/configs"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/models"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository/migrate"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository/sqlite"
+	mock_logger "github.com/Pur1st2EpicONE/WBTECH-sample-microservice/pkg/logger/mocks"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/schema"
+	"github.com/golang/mock/gomock"
+	"github.com/jmoiron/sqlx"
+)
+
+// newTestStorage opens a fresh, fully migrated database file in a temporary directory.
+func newTestStorage(t testing.TB) (*sqlite.Storage, *sqlx.DB) {
+	t.Helper()
+	config := configs.Database{Driver: sqlite.DriverName, DBName: filepath.Join(t.TempDir(), "orders.db")}
+	db := connectAndMigrate(t, config)
+	t.Cleanup(func() { _ = db.Close() })
+	return sqlite.NewStorage(db, config, nil), db
+}
+
+// connectAndMigrate opens the database and applies the embedded SQLite migrations.
+func connectAndMigrate(t testing.TB, config configs.Database) *sqlx.DB {
+	t.Helper()
+	db, err := sqlite.Connect(config)
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	migrator, err := migrate.New(db, schema.SQLite)
+	if err != nil {
+		t.Fatalf("failed to load migrations: %v", err)
+	}
+	if err := migrator.Up(context.Background()); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	return db
+}
+
+// testOrder returns a valid order with two items, unique by n.
+func testOrder(n int) *models.Order {
+	uid := fmt.Sprintf("uid%d", n)
+	track := fmt.Sprintf("TRACK%d", n)
+	return &models.Order{
+		OrderUID:        uid,
+		TrackNumber:     track,
+		Entry:           "WBIL",
+		Locale:          "en",
+		CustomerID:      "customer",
+		DeliveryService: "meest",
+		ShardKey:        "9",
+		SmID:            99,
+		DateCreated:     time.Date(2025, 1, n, 10, 0, 0, 0, time.UTC),
+		OofShard:        "1",
+		Delivery: models.Delivery{
+			Name: "Test Testov", Phone: "+9720000000", Zip: "2639809", City: "Kiryat Mozkin",
+			Address: "Ploshad Mira 15", Region: "Kraiot", Email: "test@gmail.com",
+		},
+		Payment: models.Payment{
+			Transaction: uid, Currency: "USD", Provider: "wbpay", Amount: float64(100 * n),
+			PaymentDT: 1637907727, Bank: "alpha", DeliveryCost: 1500, GoodsTotal: 317,
+		},
+		Items: []models.Item{
+			{ChrtID: 9934930, TrackNumber: track, Price: 453, Rid: fmt.Sprintf("rid%d-1", n), Name: "Mascaras",
+				Sale: 30, Size: "0", TotalPrice: 317, NmID: 2389212, Brand: "Vivienne Sabo", Status: 202},
+			{ChrtID: 9934931, TrackNumber: track, Price: 100, Rid: fmt.Sprintf("rid%d-2", n), Name: "Brush",
+				Size: "0", TotalPrice: 100, NmID: 2389213, Brand: "Vivienne Sabo", Status: 202},
+		},
+	}
+}
+
+func TestMigrations_CreateSchema(t *testing.T) {
+	_, db := newTestStorage(t)
+
+	var tables int
+	if err := db.Get(&tables, `SELECT COUNT(*) FROM sqlite_master WHERE type = 'table'
+        AND name IN ('orders', 'deliveries', 'payments', 'items', 'item_status_history')`); err != nil {
+		t.Fatalf("failed to count tables: %v", err)
+	}
+	if tables != 5 {
+		t.Fatalf("expected 5 tables, got %d", tables)
+	}
+}
+
+func TestConnect_ReopenKeepsData(t *testing.T) {
+	config := configs.Database{Driver: sqlite.DriverName, DBName: filepath.Join(t.TempDir(), "nested", "orders.db")}
+	db := connectAndMigrate(t, config)
+	if err := sqlite.NewStorage(db, config, nil).SaveOrder(context.Background(), testOrder(1)); err != nil {
+		t.Fatalf("SaveOrder failed: %v", err)
+	}
+	_ = db.Close()
+
+	db, err := sqlite.Connect(config)
+	if err != nil {
+		t.Fatalf("failed to reconnect: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+	if _, err := sqlite.NewStorage(db, config, nil).GetOrder(context.Background(), "uid1"); err != nil {
+		t.Fatalf("expected order to survive reconnect, got: %v", err)
+	}
+}
+
+func TestConnect_EmptyPath(t *testing.T) {
+	if _, err := sqlite.Connect(configs.Database{Driver: sqlite.DriverName}); err == nil {
+		t.Fatal("expected error for empty database path")
+	}
+}
+
+func TestSQLiteStorer_PingAndClose(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+	logger := mock_logger.NewMockLogger(controller)
+	logger.EXPECT().LogInfo("sqlite — stopped", "layer", "repository.sqlite")
+
+	config := configs.Database{Driver: sqlite.DriverName, DBName: filepath.Join(t.TempDir(), "orders.db")}
+	db, err := sqlite.Connect(config)
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	storage := sqlite.NewStorage(db, config, logger)
+	if err := storage.Ping(context.Background()); err != nil {
+		t.Fatalf("Ping failed: %v", err)
+	}
+	storage.Close()
+}
@@ -0,0 +1,124 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/models"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository/errs"
+)
+
+/*
+UpdateItemStatus records a status change of a single item and applies it as a single transaction.
+
+The change is always appended to item_status_history, but the current status of the item
+is only updated if the change is not older than the latest recorded one, so events that
+arrive out of order don't roll the item back.
+
+SQLite has no row locks: the transaction is serialized by the single-connection pool instead.
+
+Returns errs.ErrNotFound if the order has no such item, and errs.ErrDuplicate
+if the very same change has already been recorded (e.g. a Kafka redelivery).
+*/
+func (s *Storage) UpdateItemStatus(ctx context.Context, update models.StatusUpdate) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", mapError(ctx, err))
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var itemId, orderId, currentStatus int
+	err = tx.QueryRowContext(ctx, `SELECT items.id, items.order_id, items.status
+        FROM items
+        JOIN orders ON orders.id = items.order_id
+        WHERE orders.order_uid = $1 AND items.chrt_id = $2 AND items.rid = $3`,
+		update.OrderUID, update.ChrtID, update.Rid).Scan(&itemId, &orderId, &currentStatus)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("order %s has no item with chrt_id %d and rid %s: %w", update.OrderUID, update.ChrtID, update.Rid, errs.ErrNotFound)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to find item: %w", mapError(ctx, err))
+	}
+
+	var historyId int64
+	err = tx.QueryRowContext(ctx, `INSERT INTO item_status_history (item_id, order_id, previous_status, status, changed_at, recorded_at)
+        VALUES ($1, $2, $3, $4, $5, $6)
+        ON CONFLICT DO NOTHING
+        RETURNING id`,
+		itemId, orderId, currentStatus, update.Status, update.ChangedAt.UTC(), time.Now().UTC()).Scan(&historyId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("status change of order %s is already recorded: %w", update.OrderUID, errs.ErrDuplicate)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to insert status history: %w", mapError(ctx, err))
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE items SET status = $1
+        WHERE id = $2 AND NOT EXISTS (
+            SELECT 1 FROM item_status_history
+            WHERE item_id = $2 AND changed_at > $3
+        )`,
+		update.Status, itemId, update.ChangedAt.UTC())
+	if err != nil {
+		return fmt.Errorf("failed to update item status: %w", mapError(ctx, err))
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("transaction commit failed: %w", mapError(ctx, err))
+	}
+	return nil
+}
+
+// GetOrderHistory returns the status timeline of all items of an order, oldest change first.
+// Returns errs.ErrNotFound if there is no order with such UID. An order without recorded changes has no events.
+func (s *Storage) GetOrderHistory(ctx context.Context, orderUID string) (models.OrderHistory, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var orderId int
+	err := s.db.QueryRowContext(ctx, `SELECT id FROM orders WHERE order_uid = $1`, orderUID).Scan(&orderId)
+	if err != nil {
+		return models.OrderHistory{}, mapError(ctx, err)
+	}
+
+	rows, err := s.db.QueryContext(ctx, `SELECT
+        items.chrt_id,
+        items.rid,
+        item_status_history.previous_status,
+        item_status_history.status,
+        item_status_history.changed_at,
+        item_status_history.recorded_at
+    FROM item_status_history
+    JOIN items ON items.id = item_status_history.item_id
+    WHERE item_status_history.order_id = $1
+    ORDER BY item_status_history.changed_at, item_status_history.id`, orderId)
+	if err != nil {
+		return models.OrderHistory{}, mapError(ctx, err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	history := models.OrderHistory{OrderUID: orderUID, Events: []models.StatusEvent{}}
+	for rows.Next() {
+		var event models.StatusEvent
+		if err := rows.Scan(
+			&event.ChrtID,
+			&event.Rid,
+			&event.PreviousStatus,
+			&event.Status,
+			&event.ChangedAt,
+			&event.RecordedAt,
+		); err != nil {
+			return models.OrderHistory{}, mapError(ctx, err)
+		}
+		history.Events = append(history.Events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return models.OrderHistory{}, mapError(ctx, err)
+	}
+	return history, nil
+}
@@ -0,0 +1,68 @@
+package sqlite_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/models"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository/errs"
+)
+
+func TestSQLiteStorer_UpdateItemStatus(t *testing.T) {
+	storage, _ := newTestStorage(t)
+	if err := storage.SaveOrder(context.Background(), testOrder(1)); err != nil {
+		t.Fatalf("SaveOrder failed: %v", err)
+	}
+
+	newer := models.StatusUpdate{OrderUID: "uid1", ChrtID: 9934930, Rid: "rid1-1", Status: 300,
+		ChangedAt: time.Date(2025, 6, 2, 12, 0, 0, 0, time.UTC)}
+	older := newer
+	older.Status = 200
+	older.ChangedAt = newer.ChangedAt.Add(-time.Hour)
+
+	if err := storage.UpdateItemStatus(context.Background(), newer); err != nil {
+		t.Fatalf("UpdateItemStatus failed: %v", err)
+	}
+	if err := storage.UpdateItemStatus(context.Background(), older); err != nil {
+		t.Fatalf("UpdateItemStatus failed for an out-of-order event: %v", err)
+	}
+	if err := storage.UpdateItemStatus(context.Background(), newer); !errors.Is(err, errs.ErrDuplicate) {
+		t.Fatalf("expected ErrDuplicate on redelivery, got %v", err)
+	}
+
+	order, err := storage.GetOrder(context.Background(), "uid1")
+	if err != nil {
+		t.Fatalf("GetOrder failed: %v", err)
+	}
+	if order.Items[0].Status != 300 {
+		t.Errorf("expected the older event not to roll the status back, got %d", order.Items[0].Status)
+	}
+
+	history, err := storage.GetOrderHistory(context.Background(), "uid1")
+	if err != nil {
+		t.Fatalf("GetOrderHistory failed: %v", err)
+	}
+	if len(history.Events) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(history.Events))
+	}
+	if history.Events[0].Status != 200 || history.Events[1].Status != 300 {
+		t.Errorf("expected events in chronological order, got %+v", history.Events)
+	}
+	if !history.Events[1].ChangedAt.Equal(newer.ChangedAt) || history.Events[1].RecordedAt.IsZero() {
+		t.Errorf("unexpected event timestamps: %+v", history.Events[1])
+	}
+}
+
+func TestSQLiteStorer_UpdateItemStatus_NotFound(t *testing.T) {
+	storage, _ := newTestStorage(t)
+	update := models.StatusUpdate{OrderUID: "missing", ChrtID: 1, Rid: "rid", Status: 300, ChangedAt: time.Now()}
+
+	if err := storage.UpdateItemStatus(context.Background(), update); !errors.Is(err, errs.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	if _, err := storage.GetOrderHistory(context.Background(), "missing"); !errors.Is(err, errs.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
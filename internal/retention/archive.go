@@ -0,0 +1,129 @@
+package retention
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/models"
+)
+
+/*
+Writer writes orders to an archive: a gzip-compressed file with one JSON-encoded
+models.OrderSnapshot per line (NDJSON).
+
+Orders are written to a temporary file next to the archive, which is created with the first order.
+Seal makes the archive durable and moves it into place, so an archive either holds every order
+handed to it or does not exist. A Writer that was handed no orders creates no file.
+*/
+type Writer struct {
+	path    string
+	file    *os.File
+	gz      *gzip.Writer
+	encoder *json.Encoder
+	count   int
+}
+
+// NewWriter returns a Writer for the archive at path. The directory must exist.
+func NewWriter(path string) *Writer {
+	return &Writer{path: path}
+}
+
+// Write appends an order to the archive.
+func (w *Writer) Write(order models.OrderSnapshot) error {
+	if w.file == nil {
+		file, err := os.CreateTemp(filepath.Dir(w.path), filepath.Base(w.path)+".*.tmp")
+		if err != nil {
+			return fmt.Errorf("failed to create archive: %w", err)
+		}
+		w.file = file
+		w.gz = gzip.NewWriter(file)
+		w.encoder = json.NewEncoder(w.gz)
+	}
+	if err := w.encoder.Encode(order); err != nil {
+		return fmt.Errorf("failed to write order %s to archive: %w", order.Order.OrderUID, err)
+	}
+	w.count++
+	return nil
+}
+
+// Count returns the number of orders written so far.
+func (w *Writer) Count() int {
+	return w.count
+}
+
+// Seal flushes the archive to disk and moves it to its path, replacing a file that is already there.
+func (w *Writer) Seal() error {
+	if w.file == nil {
+		return nil
+	}
+	if err := w.gz.Close(); err != nil {
+		return fmt.Errorf("failed to compress archive: %w", err)
+	}
+	if err := w.file.Sync(); err != nil {
+		return fmt.Errorf("failed to flush archive: %w", err)
+	}
+	if err := w.file.Close(); err != nil {
+		return fmt.Errorf("failed to close archive: %w", err)
+	}
+	if err := os.Rename(w.file.Name(), w.path); err != nil {
+		return fmt.Errorf("failed to move archive into place: %w", err)
+	}
+	w.file = nil
+	return nil
+}
+
+// Discard removes the temporary file of an archive that was not sealed. It does nothing after Seal.
+func (w *Writer) Discard() {
+	if w.file == nil {
+		return
+	}
+	_ = w.file.Close()
+	_ = os.Remove(w.file.Name())
+	w.file = nil
+}
+
+// Reader reads the orders of an archive written by Writer.
+type Reader struct {
+	file    *os.File
+	gz      *gzip.Reader
+	decoder *json.Decoder
+}
+
+// OpenArchive opens the archive at path for reading.
+func OpenArchive(path string) (*Reader, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		_ = file.Close()
+		return nil, fmt.Errorf("%s is not a gzip archive: %w", path, err)
+	}
+	return &Reader{file: file, gz: gz, decoder: json.NewDecoder(gz)}, nil
+}
+
+// Next returns the next order of the archive, or io.EOF once all of them were read.
+func (r *Reader) Next() (models.OrderSnapshot, error) {
+	var order models.OrderSnapshot
+	if err := r.decoder.Decode(&order); err != nil {
+		if err == io.EOF {
+			return order, io.EOF
+		}
+		return order, fmt.Errorf("corrupted archive: %w", err)
+	}
+	if order.Order == nil {
+		return order, fmt.Errorf("corrupted archive: line without an order")
+	}
+	return order, nil
+}
+
+// Close closes the archive.
+func (r *Reader) Close() error {
+	_ = r.gz.Close()
+	return r.file.Close()
+}
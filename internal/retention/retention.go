@@ -0,0 +1,157 @@
+/*
+Package retention keeps the order tables partitioned by month and moves old orders into archives.
+
+The order tables are partitioned by order date, one partition per month, with a default partition
+for orders of months that have no partition of their own. On every run the Job creates the partitions
+of the current month and the configured number of months ahead, so that new orders never end up in
+the default partition. If a maximum age is configured, it then archives every monthly partition that
+lies entirely before the cutoff, and the orders in the default partition that are older than it.
+
+An archive is a gzip-compressed NDJSON file of models.OrderSnapshot, named after the partition,
+e.g. orders_p202501.ndjson.gz. A partition is dropped only after its archive is safely on disk;
+archives can be loaded back with "wb-service restore".
+*/
+package retention
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/configs"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/models"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/pkg/logger"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/pkg/notifier"
+)
+
+// Job maintains the order partitions and archives the ones past the retention age.
+type Job struct {
+	archiver        repository.Archiver
+	notifier        notifier.Notifier
+	logger          logger.Logger
+	interval        time.Duration
+	maxAge          time.Duration
+	dir             string
+	partitionsAhead int
+}
+
+// Archived describes an archive written by a run of the Job.
+type Archived struct {
+	Partition string // partition the orders were taken from
+	Path      string // archive the orders were written to
+	Orders    int
+}
+
+// NewJob creates a Job that manages the partitions of archiver according to config.
+func NewJob(config configs.Retention, archiver repository.Archiver, notifier notifier.Notifier, logger logger.Logger) *Job {
+	return &Job{
+		archiver:        archiver,
+		notifier:        notifier,
+		logger:          logger,
+		interval:        config.Interval,
+		maxAge:          config.MaxAge,
+		dir:             config.ArchiveDir,
+		partitionsAhead: config.PartitionsAhead,
+	}
+}
+
+/*
+Run runs the job right away and then once per interval until ctx is cancelled.
+
+Archived partitions are reported through the notifier, and so are failed runs;
+a failed run is simply repeated on the next tick.
+*/
+func (j *Job) Run(ctx context.Context) {
+	j.logger.LogInfo("retention — job started", "interval", j.interval, "max age", j.maxAge, "layer", "retention")
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+	for {
+		archived, err := j.runOnce(ctx, time.Now())
+		if len(archived) > 0 {
+			_ = j.notifier.Notify(archivedMessage(archived))
+		}
+		if err != nil && ctx.Err() == nil {
+			j.logger.LogError("retention — run failed", err, "layer", "retention")
+			_ = j.notifier.Notify(fmt.Sprintf("WARNING — retention job failed\n%v\nit will be retried in %s", err, j.interval))
+		}
+		select {
+		case <-ctx.Done():
+			j.logger.LogInfo("retention — job stopped", "layer", "retention")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// archivedMessage builds the notification about archived partitions.
+func archivedMessage(archived []Archived) string {
+	var message strings.Builder
+	message.WriteString("INFO — old orders archived")
+	for _, a := range archived {
+		fmt.Fprintf(&message, "\n%s: %d orders → %s", a.Partition, a.Orders, a.Path)
+	}
+	return message.String()
+}
+
+/*
+runOnce creates the upcoming partitions and archives the partitions past the retention age as of now.
+
+Partitions are archived oldest first and the run stops at the first failure, so the archived orders
+are always the oldest ones. Failing to create partitions does not stop archiving: both are reported.
+*/
+func (j *Job) runOnce(ctx context.Context, now time.Time) ([]Archived, error) {
+	now = now.UTC()
+	upkeepErr := j.archiver.EnsurePartitions(ctx, now, now.AddDate(0, j.partitionsAhead, 0))
+	if upkeepErr != nil {
+		upkeepErr = fmt.Errorf("failed to create partitions: %w", upkeepErr)
+	}
+	if j.maxAge <= 0 {
+		return nil, upkeepErr
+	}
+
+	if err := os.MkdirAll(j.dir, 0o755); err != nil {
+		return nil, errors.Join(upkeepErr, fmt.Errorf("failed to create archive directory: %w", err))
+	}
+	cutoff := now.Add(-j.maxAge)
+	partitions, err := j.archiver.OrderPartitions(ctx)
+	if err != nil {
+		return nil, errors.Join(upkeepErr, fmt.Errorf("failed to list partitions: %w", err))
+	}
+	var archived []Archived
+	for _, partition := range partitions {
+		if !partition.Default && partition.To.After(cutoff) {
+			continue
+		}
+		result, err := j.archive(ctx, partition, cutoff)
+		if err != nil {
+			return archived, errors.Join(upkeepErr, fmt.Errorf("failed to archive partition %s: %w", partition.Name, err))
+		}
+		if result.Orders > 0 {
+			j.logger.LogInfo("retention — partition archived", "partition", result.Partition, "orders", result.Orders, "path", result.Path, "layer", "retention")
+			archived = append(archived, result)
+		}
+	}
+	return archived, upkeepErr
+}
+
+// archive writes the orders of a partition dated before cutoff to an archive and removes them from the database.
+// The default partition gets a new archive every time, named after the cutoff.
+func (j *Job) archive(ctx context.Context, partition models.Partition, cutoff time.Time) (Archived, error) {
+	name := "orders_" + partition.Name
+	if partition.Default {
+		name += "_before_" + cutoff.Format("20060102T150405Z")
+	}
+	path := filepath.Join(j.dir, name+".ndjson.gz")
+	writer := NewWriter(path)
+	defer writer.Discard()
+	count, err := j.archiver.ArchivePartition(ctx, partition, cutoff, writer.Write, writer.Seal)
+	if err != nil {
+		return Archived{}, err
+	}
+	return Archived{Partition: "orders_" + partition.Name, Path: path, Orders: count}, nil
+}
@@ -0,0 +1,174 @@
+package retention
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/configs"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/models"
+	mock_repository "github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository/mocks"
+	mock_logger "github.com/Pur1st2EpicONE/WBTECH-sample-microservice/pkg/logger/mocks"
+	mock_notifier "github.com/Pur1st2EpicONE/WBTECH-sample-microservice/pkg/notifier/mocks"
+	"github.com/golang/mock/gomock"
+)
+
+var now = time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)
+
+func month(year int, m time.Month) models.Partition {
+	from := time.Date(year, m, 1, 0, 0, 0, 0, time.UTC)
+	return models.Partition{Name: from.Format("p200601"), From: from, To: from.AddDate(0, 1, 0)}
+}
+
+func newTestJob(t *testing.T, maxAge time.Duration) (*Job, *mock_repository.MockArchiver, *mock_notifier.MockNotifier) {
+	controller := gomock.NewController(t)
+	archiver := mock_repository.NewMockArchiver(controller)
+	notifier := mock_notifier.NewMockNotifier(controller)
+	logger := mock_logger.NewMockLogger(controller)
+	logger.EXPECT().LogInfo(gomock.Any(), gomock.Any()).AnyTimes()
+	logger.EXPECT().LogError(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+	config := configs.Retention{Interval: time.Hour, MaxAge: maxAge, ArchiveDir: t.TempDir(), PartitionsAhead: 2}
+	return NewJob(config, archiver, notifier, logger), archiver, notifier
+}
+
+// archiveOrders returns an ArchivePartition implementation that hands the given orders over.
+func archiveOrders(orders ...string) func(context.Context, models.Partition, time.Time, func(models.OrderSnapshot) error, func() error) (int, error) {
+	return func(_ context.Context, _ models.Partition, _ time.Time, write func(models.OrderSnapshot) error, seal func() error) (int, error) {
+		for _, uid := range orders {
+			if err := write(models.OrderSnapshot{Order: &models.Order{OrderUID: uid}, History: []models.StatusEvent{}}); err != nil {
+				return 0, err
+			}
+		}
+		return len(orders), seal()
+	}
+}
+
+func readArchive(t *testing.T, path string) []string {
+	t.Helper()
+	reader, err := OpenArchive(path)
+	if err != nil {
+		t.Fatalf("failed to open archive: %v", err)
+	}
+	defer func() { _ = reader.Close() }()
+	var uids []string
+	for {
+		order, err := reader.Next()
+		if err == io.EOF {
+			return uids
+		}
+		if err != nil {
+			t.Fatalf("failed to read archive: %v", err)
+		}
+		uids = append(uids, order.Order.OrderUID)
+	}
+}
+
+func TestJob_RunOnce_KeepsOrdersWithoutMaxAge(t *testing.T) {
+	job, archiver, _ := newTestJob(t, 0)
+	archiver.EXPECT().EnsurePartitions(gomock.Any(), now, now.AddDate(0, 2, 0)).Return(nil)
+
+	archived, err := job.runOnce(context.Background(), now)
+	if err != nil || len(archived) != 0 {
+		t.Fatalf("expected only partitions to be created, got %+v, %v", archived, err)
+	}
+}
+
+func TestJob_RunOnce_ArchivesOldPartitions(t *testing.T) {
+	job, archiver, _ := newTestJob(t, 90*24*time.Hour)
+	cutoff := now.Add(-90 * 24 * time.Hour) // 2025-03-17
+
+	archiver.EXPECT().EnsurePartitions(gomock.Any(), now, now.AddDate(0, 2, 0)).Return(nil)
+	archiver.EXPECT().OrderPartitions(gomock.Any()).
+		Return([]models.Partition{month(2025, 1), month(2025, 2), month(2025, 3), {Name: "default", Default: true}}, nil)
+	gomock.InOrder(
+		archiver.EXPECT().ArchivePartition(gomock.Any(), month(2025, 1), cutoff, gomock.Any(), gomock.Any()).DoAndReturn(archiveOrders("uid1", "uid2")),
+		archiver.EXPECT().ArchivePartition(gomock.Any(), month(2025, 2), cutoff, gomock.Any(), gomock.Any()).DoAndReturn(archiveOrders()),
+		archiver.EXPECT().ArchivePartition(gomock.Any(), models.Partition{Name: "default", Default: true}, cutoff, gomock.Any(), gomock.Any()).
+			DoAndReturn(archiveOrders("uid0")),
+	)
+
+	archived, err := job.runOnce(context.Background(), now)
+	if err != nil {
+		t.Fatalf("runOnce failed: %v", err)
+	}
+	if len(archived) != 2 || archived[0].Partition != "orders_p202501" || archived[0].Orders != 2 ||
+		archived[1].Partition != "orders_default" || archived[1].Orders != 1 {
+		t.Fatalf("expected January and the old orders of the default partition to be archived, got %+v", archived)
+	}
+	if uids := readArchive(t, filepath.Join(job.dir, "orders_p202501.ndjson.gz")); strings.Join(uids, ",") != "uid1,uid2" {
+		t.Errorf("unexpected orders in the January archive: %v", uids)
+	}
+	if uids := readArchive(t, filepath.Join(job.dir, "orders_default_before_20250317T120000Z.ndjson.gz")); strings.Join(uids, ",") != "uid0" {
+		t.Errorf("unexpected orders in the default partition archive: %v", uids)
+	}
+	if _, err := os.Stat(filepath.Join(job.dir, "orders_p202502.ndjson.gz")); !os.IsNotExist(err) {
+		t.Errorf("expected no archive for an empty partition, got %v", err)
+	}
+}
+
+func TestJob_RunOnce_StopsAtFailedPartition(t *testing.T) {
+	job, archiver, _ := newTestJob(t, 24*time.Hour)
+	dbErr := errors.New("lock timeout")
+
+	archiver.EXPECT().EnsurePartitions(gomock.Any(), gomock.Any(), gomock.Any()).Return(errors.New("month is in the default partition"))
+	archiver.EXPECT().OrderPartitions(gomock.Any()).Return([]models.Partition{month(2025, 1), month(2025, 2)}, nil)
+	archiver.EXPECT().ArchivePartition(gomock.Any(), month(2025, 1), gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ models.Partition, _ time.Time, write func(models.OrderSnapshot) error, _ func() error) (int, error) {
+			_ = write(models.OrderSnapshot{Order: &models.Order{OrderUID: "uid1"}})
+			return 1, dbErr
+		})
+
+	archived, err := job.runOnce(context.Background(), now)
+	if !errors.Is(err, dbErr) || !strings.Contains(err.Error(), "failed to create partitions") {
+		t.Fatalf("expected both failures to be reported, got %v", err)
+	}
+	if len(archived) != 0 {
+		t.Fatalf("expected nothing to be archived, got %+v", archived)
+	}
+	entries, _ := os.ReadDir(job.dir)
+	if len(entries) != 0 {
+		t.Errorf("expected the unsealed archive to be removed, found %d files", len(entries))
+	}
+}
+
+func TestJob_Run_Notifies(t *testing.T) {
+	job, archiver, notifier := newTestJob(t, 24*time.Hour)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	archiver.EXPECT().EnsurePartitions(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+	archiver.EXPECT().OrderPartitions(gomock.Any()).Return([]models.Partition{month(2025, 1)}, nil)
+	archiver.EXPECT().ArchivePartition(gomock.Any(), month(2025, 1), gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(archiveOrders("uid1"))
+	notifier.EXPECT().Notify(gomock.Any()).DoAndReturn(func(message string) error {
+		if !strings.Contains(message, "orders_p202501: 1 orders") {
+			t.Errorf("unexpected notification: %q", message)
+		}
+		cancel()
+		return nil
+	})
+
+	done := make(chan struct{})
+	go func() {
+		job.Run(ctx)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("job did not stop after cancellation")
+	}
+}
+
+func TestReader_CorruptedArchive(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "orders.ndjson.gz")
+	if err := os.WriteFile(path, []byte("not gzip"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenArchive(path); err == nil {
+		t.Fatal("expected a file that is not gzip to be rejected")
+	}
+}
@@ -0,0 +1,71 @@
+package rules
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/models"
+)
+
+// centsTolerance absorbs floating point noise when amounts are compared to the cent.
+const centsTolerance = 0.005
+
+/*
+Builtin returns the rules every order is checked against by default:
+
+  - payment_amount (flag): the payment amount is the goods total plus the delivery cost and the custom fee.
+  - item_total_price (flag): the total price of every item is its price less the sale,
+    rounded to a whole currency unit at most.
+  - item_track_number (reject): every item has the track number of the order.
+  - payment_transaction (reject): the payment transaction is the order UID.
+
+The last two are foreign keys of the original schema, which SQLite still enforces: an order breaking them
+would fail to save there rather than be rejected up front.
+*/
+func Builtin() []Rule {
+	return []Rule{
+		{Name: "payment_amount", Severity: models.SeverityFlag, Check: checkPaymentAmount},
+		{Name: "item_total_price", Severity: models.SeverityFlag, Check: checkItemTotalPrice},
+		{Name: "item_track_number", Severity: models.SeverityReject, Check: checkItemTrackNumber},
+		{Name: "payment_transaction", Severity: models.SeverityReject, Check: checkPaymentTransaction},
+	}
+}
+
+func checkPaymentAmount(order *models.Order) []string {
+	payment := order.Payment
+	expected := payment.GoodsTotal + payment.DeliveryCost + payment.CustomFee
+	if math.Abs(payment.Amount-expected) > centsTolerance {
+		return []string{fmt.Sprintf("amount %.2f is not goods_total + delivery_cost + custom_fee (%.2f)", payment.Amount, expected)}
+	}
+	return nil
+}
+
+func checkItemTotalPrice(order *models.Order) []string {
+	var messages []string
+	for _, item := range order.Items {
+		expected := item.Price * float64(100-item.Sale) / 100
+		if math.Abs(item.TotalPrice-expected) >= 1 {
+			messages = append(messages, fmt.Sprintf("item %d: total_price %.2f is not price %.2f less %d%% (%.2f)",
+				item.ChrtID, item.TotalPrice, item.Price, item.Sale, expected))
+		}
+	}
+	return messages
+}
+
+func checkItemTrackNumber(order *models.Order) []string {
+	var messages []string
+	for _, item := range order.Items {
+		if item.TrackNumber != order.TrackNumber {
+			messages = append(messages, fmt.Sprintf("item %d: track_number %s is not the track number of the order (%s)",
+				item.ChrtID, item.TrackNumber, order.TrackNumber))
+		}
+	}
+	return messages
+}
+
+func checkPaymentTransaction(order *models.Order) []string {
+	if order.Payment.Transaction != order.OrderUID {
+		return []string{fmt.Sprintf("transaction %s is not the order UID", order.Payment.Transaction)}
+	}
+	return nil
+}
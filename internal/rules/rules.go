@@ -0,0 +1,100 @@
+/*
+Package rules checks orders against business rules that struct validation can't express,
+such as totals that must add up.
+
+Every rule has a default severity: orders breaking a rejecting rule are not saved, orders breaking
+a flagging rule are saved with the violation recorded. Rules can be switched off and their severity
+changed in config (kafka.consumer.rules). New rules are plugged in by passing them to New.
+*/
+package rules
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/configs"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/models"
+)
+
+// Rule is a business rule orders are checked against.
+type Rule struct {
+	Name     string
+	Severity string // default severity, models.SeverityReject or models.SeverityFlag
+
+	// Check returns a description of every way the order breaks the rule, nothing if it keeps it.
+	Check func(order *models.Order) []string
+}
+
+// RejectedError is returned for an order that breaks a rejecting rule.
+// It holds every violation of the order, flagged ones included.
+type RejectedError struct {
+	Violations []models.RuleViolation
+}
+
+func (e *RejectedError) Error() string {
+	var broken []string
+	for _, violation := range e.Violations {
+		if violation.Severity == models.SeverityReject {
+			broken = append(broken, fmt.Sprintf("%s: %s", violation.Rule, violation.Message))
+		}
+	}
+	return "order breaks business rules: " + strings.Join(broken, "; ")
+}
+
+// Engine checks orders against the enabled rules. It is safe for concurrent use.
+type Engine struct {
+	rules []Rule
+}
+
+/*
+New creates an Engine with the given rules, applying config to them by rule name.
+
+A rule missing from config is enabled with its default severity, and so is a rule whose config
+leaves out enabled or severity. Config that names an unknown rule
+or an unknown severity is an error, so a typo doesn't silently leave a rule at its defaults.
+*/
+func New(config map[string]configs.Rule, rules ...Rule) (*Engine, error) {
+	engine := new(Engine)
+	known := make(map[string]bool, len(rules))
+	for _, rule := range rules {
+		known[rule.Name] = true
+		settings, ok := config[rule.Name]
+		if !ok {
+			engine.rules = append(engine.rules, rule)
+			continue
+		}
+		if settings.Enabled != nil && !*settings.Enabled {
+			continue
+		}
+		switch settings.Severity {
+		case "":
+		case models.SeverityReject, models.SeverityFlag:
+			rule.Severity = settings.Severity
+		default:
+			return nil, fmt.Errorf("rule %s: unknown severity %q", rule.Name, settings.Severity)
+		}
+		engine.rules = append(engine.rules, rule)
+	}
+	for name := range config {
+		if !known[name] {
+			return nil, fmt.Errorf("unknown rule %q", name)
+		}
+	}
+	return engine, nil
+}
+
+// Check returns the violations of the order. If any of them rejects the order,
+// a *RejectedError is returned as well.
+func (e *Engine) Check(order *models.Order) ([]models.RuleViolation, error) {
+	var violations []models.RuleViolation
+	for _, rule := range e.rules {
+		for _, message := range rule.Check(order) {
+			violations = append(violations, models.RuleViolation{Rule: rule.Name, Severity: rule.Severity, Message: message})
+		}
+	}
+	if slices.ContainsFunc(violations, func(v models.RuleViolation) bool { return v.Severity == models.SeverityReject }) {
+		return violations, &RejectedError{Violations: violations}
+	}
+	return violations, nil
+}
@@ -0,0 +1,87 @@
+package rules_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/configs"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/models"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/rules"
+)
+
+// testOrder returns an order that keeps every built-in rule.
+func testOrder() *models.Order {
+	return &models.Order{
+		OrderUID:    "b563feb7b2b84b6test",
+		TrackNumber: "WBILMTESTTRACK",
+		Payment:     models.Payment{Transaction: "b563feb7b2b84b6test", Amount: 1817, DeliveryCost: 1500, GoodsTotal: 317},
+		Items:       []models.Item{{ChrtID: 9934930, TrackNumber: "WBILMTESTTRACK", Price: 453, Sale: 30, TotalPrice: 317}},
+	}
+}
+
+func TestEngine_Builtin(t *testing.T) {
+	engine, err := rules.New(nil, rules.Builtin()...)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if violations, err := engine.Check(testOrder()); err != nil || len(violations) != 0 {
+		t.Fatalf("expected a valid order to keep every rule, got %v (%v)", violations, err)
+	}
+
+	order := testOrder()
+	order.Payment.Amount = 1800
+	order.Items[0].TotalPrice = 453
+	violations, err := engine.Check(order)
+	if err != nil {
+		t.Fatalf("expected flagging rules not to reject the order, got %v", err)
+	}
+	if len(violations) != 2 || violations[0].Rule != "payment_amount" || violations[1].Rule != "item_total_price" {
+		t.Fatalf("expected payment_amount and item_total_price to be flagged, got %+v", violations)
+	}
+
+	order = testOrder()
+	order.Items[0].TrackNumber = "WBILMOTHERTRACK"
+	order.Payment.Transaction = "other"
+	violations, err = engine.Check(order)
+	var rejection *rules.RejectedError
+	if !errors.As(err, &rejection) || len(rejection.Violations) != 2 || len(violations) != 2 {
+		t.Fatalf("expected both schema rules to reject the order, got %+v (%v)", violations, err)
+	}
+}
+
+func TestEngine_Config(t *testing.T) {
+	enabled, disabled := true, false
+	engine, err := rules.New(map[string]configs.Rule{
+		"payment_amount":    {Enabled: &enabled, Severity: models.SeverityReject},
+		"item_track_number": {Enabled: &disabled},
+	}, rules.Builtin()...)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	order := testOrder()
+	order.Payment.Amount = 1800
+	order.Items[0].TrackNumber = "WBILMOTHERTRACK"
+	violations, err := engine.Check(order)
+	if err == nil || len(violations) != 1 || violations[0].Rule != "payment_amount" || violations[0].Severity != models.SeverityReject {
+		t.Fatalf("expected only payment_amount to reject the order, got %+v (%v)", violations, err)
+	}
+
+	if _, err := rules.New(map[string]configs.Rule{"payment_amout": {Enabled: &enabled}}, rules.Builtin()...); err == nil {
+		t.Fatal("expected an unknown rule to be an error")
+	}
+	if _, err := rules.New(map[string]configs.Rule{"payment_amount": {Enabled: &enabled, Severity: "warn"}}, rules.Builtin()...); err == nil {
+		t.Fatal("expected an unknown severity to be an error")
+	}
+
+	// a rule configured without enabled, e.g. only to change its severity, stays enabled
+	engine, err = rules.New(map[string]configs.Rule{"item_total_price": {Severity: models.SeverityReject}}, rules.Builtin()...)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	order = testOrder()
+	order.Items[0].TotalPrice = 453
+	violations, err = engine.Check(order)
+	if err == nil || len(violations) != 1 || violations[0].Rule != "item_total_price" {
+		t.Fatalf("expected item_total_price to reject the order, got %+v (%v)", violations, err)
+	}
+}
@@ -4,6 +4,7 @@ package server
 
 import (
 	"context"
+	"net"
 	"net/http"
 	"time"
 
@@ -32,7 +33,11 @@ func NewServer(config configs.Server, handler http.Handler) *Server {
 }
 
 // Run starts the HTTP server and logs that it is receiving requests.
+//
+// ctx becomes the base context of every request, so its cancellation
+// (e.g. on shutdown) aborts database queries that are still running.
 func (s *Server) Run(ctx context.Context, logger logger.Logger) error {
+	s.HttpServer.BaseContext = func(net.Listener) context.Context { return ctx }
 	logger.LogInfo("server — receiving requests", "layer", "server")
 	return s.HttpServer.ListenAndServe()
 }
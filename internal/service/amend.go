@@ -0,0 +1,102 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/models"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository/errs"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/pkg/logger"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/pkg/mergepatch"
+	"github.com/go-playground/validator/v10"
+)
+
+// amendableFields are the members of an order a patch may change. Items change status through status-change events,
+// while the keys, shard key, date and payment of an order are referenced elsewhere (order keys, shards, partitions, sent events).
+var amendableFields = []string{"delivery", "entry", "locale", "internal_signature", "customer_id", "delivery_service", "sm_id", "oof_shard"}
+
+// InvalidPatchError reports a patch that can't be applied to an order. Its message is meant for the client.
+type InvalidPatchError struct {
+	Reason string
+}
+
+func (e *InvalidPatchError) Error() string {
+	return e.Reason
+}
+
+// Unwrap classifies the error as errs.ErrInvalidArgument.
+func (e *InvalidPatchError) Unwrap() error {
+	return errs.ErrInvalidArgument
+}
+
+/*
+AmendOrder applies a JSON Merge Patch (RFC 7386) to an order that must be at the given version,
+and returns the amended order.
+
+  - The order is read from the primary: a lagging replica would report an old version as a conflict.
+  - Only the members listed in amendableFields may be patched.
+  - The amended order must pass the same validation as an order received from Kafka.
+  - The stored order and the audit record of the amendment are written together, and only if nobody
+    amended the order in the meantime; otherwise the error is classified as errs.ErrConflict.
+  - The amended order takes the place of the cached copy. The cache keeps the latest version of an order,
+    so a reader that fetched the old one concurrently can't overwrite it.
+
+A malformed or forbidden patch and an invalid result are reported as *InvalidPatchError.
+*/
+func (s Service) AmendOrder(ctx context.Context, orderID string, version int, patch []byte, logger logger.Logger) (*models.Order, error) {
+	if err := checkPatch(patch); err != nil {
+		return nil, err
+	}
+	current, err := s.Storage.GetOrderFromPrimary(ctx, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get order %s: %w", orderID, err)
+	}
+	if current.Version != version {
+		return nil, fmt.Errorf("order %s is at version %d: %w", orderID, current.Version, errs.ErrConflict)
+	}
+
+	previous, err := json.Marshal(current)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal order %s: %w", orderID, err)
+	}
+	merged, err := mergepatch.Apply(previous, patch)
+	if err != nil {
+		return nil, &InvalidPatchError{Reason: err.Error()}
+	}
+	amended := new(models.Order)
+	if err := json.Unmarshal(merged, amended); err != nil {
+		return nil, &InvalidPatchError{Reason: fmt.Sprintf("patch doesn't fit the order: %v", err)}
+	}
+	if err := validator.New().Struct(amended); err != nil {
+		return nil, &InvalidPatchError{Reason: fmt.Sprintf("amended order is invalid: %v", err)}
+	}
+
+	amendment := models.OrderAmendment{Version: version + 1, Patch: patch, Previous: previous, AmendedAt: time.Now().UTC()}
+	if err := s.Storage.AmendOrder(ctx, amended, amendment); err != nil {
+		return nil, fmt.Errorf("failed to amend order %s: %w", orderID, err)
+	}
+	s.Cache.CacheOrder(amended, logger)
+	return amended, nil
+}
+
+// checkPatch makes sure a patch is a JSON object that only touches amendable fields.
+func checkPatch(patch []byte) error {
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(patch, &members); err != nil || members == nil {
+		return &InvalidPatchError{Reason: "patch must be a JSON object"}
+	}
+	names := make([]string, 0, len(members))
+	for name := range members {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		if !slices.Contains(amendableFields, name) {
+			return &InvalidPatchError{Reason: fmt.Sprintf("%s can't be amended, only %v can", name, amendableFields)}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	mock_cache "github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/cache/mocks"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/models"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository/errs"
+	mock_repo "github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository/mocks"
+	mock_logger "github.com/Pur1st2EpicONE/WBTECH-sample-microservice/pkg/logger/mocks"
+	"github.com/golang/mock/gomock"
+)
+
+// storedOrder returns a valid order as read from storage.
+func storedOrder(version int) *models.Order {
+	return &models.Order{
+		OrderUID: "b563feb7b2b84b6test", TrackNumber: "WBILMTESTTRACK", Entry: "WBIL", Locale: "en", CustomerID: "test",
+		DeliveryService: "meest", ShardKey: "9", SmID: 99, DateCreated: time.Date(2021, 11, 26, 6, 22, 19, 0, time.UTC), OofShard: "1",
+		Version: version,
+		Delivery: models.Delivery{Name: "Test Testov", Phone: "+9720000000", Zip: "2639809", City: "Kiryat Mozkin",
+			Address: "Ploshad Mira 15", Region: "Kraiot", Email: "test@gmail.com"},
+		Payment: models.Payment{Transaction: "b563feb7b2b84b6test", Currency: "USD", Provider: "wbpay", Amount: 1817,
+			PaymentDT: 1637907727, Bank: "alpha", DeliveryCost: 1500, GoodsTotal: 317},
+		Items: []models.Item{{ChrtID: 9934930, TrackNumber: "WBILMTESTTRACK", Price: 453, Rid: "ab4219087a764ae0btest",
+			Name: "Mascaras", Sale: 30, Size: "0", TotalPrice: 317, NmID: 2389212, Brand: "Vivienne Sabo", Status: 202}},
+	}
+}
+
+func newAmendService(t *testing.T) (*Service, *mock_repo.MockStorage, *mock_cache.MockCache) {
+	controller := gomock.NewController(t)
+	mockStorage := mock_repo.NewMockStorage(controller)
+	mockCacher := mock_cache.NewMockCache(controller)
+	return &Service{Storage: mockStorage, Cache: mockCacher}, mockStorage, mockCacher
+}
+
+func TestService_AmendOrder(t *testing.T) {
+	service, mockStorage, mockCacher := newAmendService(t)
+	logger := mock_logger.NewMockLogger(gomock.NewController(t))
+	patch := []byte(`{"delivery":{"phone":"+9720000001","city":"Haifa"}}`)
+
+	mockStorage.EXPECT().GetOrderFromPrimary(gomock.Any(), "b563feb7b2b84b6test").Return(storedOrder(2), nil)
+	mockStorage.EXPECT().AmendOrder(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, order *models.Order, amendment models.OrderAmendment) error {
+			if order.Delivery.Phone != "+9720000001" || order.Delivery.City != "Haifa" || order.Delivery.Name != "Test Testov" ||
+				len(order.Items) != 1 || order.Version != 2 {
+				t.Errorf("unexpected amended order: %+v", order)
+			}
+			if amendment.Version != 3 || string(amendment.Patch) != string(patch) || len(amendment.Previous) == 0 {
+				t.Errorf("unexpected amendment: %+v", amendment)
+			}
+			order.Version = amendment.Version
+			return nil
+		})
+	mockCacher.EXPECT().CacheOrder(gomock.Any(), logger).Do(func(order *models.Order, _ any) {
+		if order.Version != 3 {
+			t.Errorf("expected the amended version to be cached, got %d", order.Version)
+		}
+	})
+
+	amended, err := service.AmendOrder(context.Background(), "b563feb7b2b84b6test", 2, patch, logger)
+	if err != nil {
+		t.Fatalf("AmendOrder failed: %v", err)
+	}
+	if amended.Version != 3 || amended.Delivery.City != "Haifa" {
+		t.Fatalf("unexpected amended order: %+v", amended)
+	}
+}
+
+func TestService_AmendOrder_StaleVersion(t *testing.T) {
+	service, mockStorage, _ := newAmendService(t)
+
+	mockStorage.EXPECT().GetOrderFromPrimary(gomock.Any(), "b563feb7b2b84b6test").Return(storedOrder(3), nil)
+
+	_, err := service.AmendOrder(context.Background(), "b563feb7b2b84b6test", 2, []byte(`{"locale":"ru"}`), nil)
+	if !errors.Is(err, errs.ErrConflict) {
+		t.Fatalf("expected ErrConflict, got %v", err)
+	}
+}
+
+func TestService_AmendOrder_InvalidPatch(t *testing.T) {
+	cases := map[string]string{
+		"not an object":  `["locale"]`,
+		"immutable":      `{"items":[]}`,
+		"version":        `{"version":5}`,
+		"wrong type":     `{"sm_id":"ninety-nine"}`,
+		"fails validate": `{"delivery":{"phone":"not a phone"}}`,
+		"removes field":  `{"delivery":null}`,
+	}
+	for name, patch := range cases {
+		t.Run(name, func(t *testing.T) {
+			service, mockStorage, _ := newAmendService(t)
+			mockStorage.EXPECT().GetOrderFromPrimary(gomock.Any(), gomock.Any()).Return(storedOrder(1), nil).MaxTimes(1)
+
+			_, err := service.AmendOrder(context.Background(), "b563feb7b2b84b6test", 1, []byte(patch), nil)
+			var invalid *InvalidPatchError
+			if !errors.As(err, &invalid) || !errors.Is(err, errs.ErrInvalidArgument) {
+				t.Fatalf("expected InvalidPatchError, got %v", err)
+			}
+		})
+	}
+}
+
+func TestService_AmendOrder_StorageError(t *testing.T) {
+	service, mockStorage, _ := newAmendService(t)
+
+	mockStorage.EXPECT().GetOrderFromPrimary(gomock.Any(), "b563feb7b2b84b6test").Return(storedOrder(1), nil)
+	mockStorage.EXPECT().AmendOrder(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(fmt.Errorf("order is at version 2 already: %w", errs.ErrConflict))
+
+	_, err := service.AmendOrder(context.Background(), "b563feb7b2b84b6test", 1, []byte(`{"locale":"ru"}`), nil)
+	if !errors.Is(err, errs.ErrConflict) {
+		t.Fatalf("expected ErrConflict, got %v", err)
+	}
+}
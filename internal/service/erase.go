@@ -0,0 +1,54 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/models"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/pkg/logger"
+)
+
+/*
+ErasePersonalData erases the personal data of a customer on request (GDPR "right to erasure").
+
+The delivery details of every order of the customer, including the copies kept in the ingested
+messages and the amendment audit trail, are replaced with the placeholders of models.ErasedDelivery.
+Items and payment are kept for accounting. The erased orders take the place of their cached copies,
+see cacheErased, and the compliance record of the erasure is returned. source tells where the request came from
+(models.ErasureSourceAPI or models.ErasureSourceCLI), reference may hold e.g. a ticket number.
+
+Orders archived by the retention job, or on their way between shards, are erased when they are restored
+or stored on their new shard. Messages still in Kafka are out of reach: the orders topic, the retry topics
+and the DLQ keep their copies until they expire, and an order stored from them after the erasure keeps its personal data.
+
+Erasing the data of a customer again is harmless, and is recorded again.
+*/
+func (s Service) ErasePersonalData(ctx context.Context, customerID, source, reference string, logger logger.Logger) (models.PersonalDataErasure, error) {
+	erasure := models.PersonalDataErasure{CustomerID: customerID, Source: source, Reference: reference, ErasedAt: time.Now().UTC()}
+	if err := s.Storage.ErasePersonalData(ctx, &erasure); err != nil {
+		return models.PersonalDataErasure{}, fmt.Errorf("failed to erase personal data of customer %s: %w", customerID, err)
+	}
+	for _, orderUID := range erasure.OrderUIDs {
+		s.cacheErased(ctx, orderUID, logger)
+	}
+	logger.LogInfo("service — personal data erased", "customerID", customerID, "orders", len(erasure.OrderUIDs), "source", source, "layer", "service")
+	return erasure, nil
+}
+
+/*
+cacheErased reads an erased order from the primary and caches it, like AmendOrder does with an amended one.
+
+The erasure bumps the version of the order, and the cache keeps the latest version of an order.
+So a reader that fetched the order before the erasure, or from a lagging replica, can't bring its personal data
+back into the cache. If the erased order can't be read, its cached copy is only dropped.
+*/
+func (s Service) cacheErased(ctx context.Context, orderUID string, logger logger.Logger) {
+	order, err := s.Storage.GetOrderFromPrimary(ctx, orderUID)
+	if err != nil {
+		logger.LogError("service — failed to read erased order, cached copy dropped", err, "orderUID", orderUID, "layer", "service")
+		s.Cache.InvalidateOrder(orderUID, logger)
+		return
+	}
+	s.Cache.CacheOrder(order, logger)
+}
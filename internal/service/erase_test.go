@@ -0,0 +1,113 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/cache/memory"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/configs"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/models"
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository/errs"
+	mock_repo "github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository/mocks"
+	mock_logger "github.com/Pur1st2EpicONE/WBTECH-sample-microservice/pkg/logger/mocks"
+	"github.com/go-playground/validator/v10"
+	"github.com/golang/mock/gomock"
+)
+
+func TestService_ErasePersonalData(t *testing.T) {
+	service, mockStorage, mockCacher := newAmendService(t)
+	logger := mock_logger.NewMockLogger(gomock.NewController(t))
+
+	mockStorage.EXPECT().ErasePersonalData(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, erasure *models.PersonalDataErasure) error {
+			if erasure.CustomerID != "test" || erasure.Source != models.ErasureSourceAPI || erasure.Reference != "TICKET-1" || erasure.ErasedAt.IsZero() {
+				t.Errorf("unexpected erasure: %+v", erasure)
+			}
+			erasure.OrderUIDs = []string{"uid1", "uid2"}
+			return nil
+		})
+	erased := storedOrder(2)
+	erased.Delivery = models.ErasedDelivery
+	mockStorage.EXPECT().GetOrderFromPrimary(gomock.Any(), "uid1").Return(erased, nil)
+	mockStorage.EXPECT().GetOrderFromPrimary(gomock.Any(), "uid2").Return(nil, errs.ErrUnavailable)
+	mockCacher.EXPECT().CacheOrder(erased, logger)
+	mockCacher.EXPECT().InvalidateOrder("uid2", logger)
+	logger.EXPECT().LogInfo(gomock.Any(), gomock.Any()).AnyTimes()
+	logger.EXPECT().LogError(gomock.Any(), gomock.Any(), gomock.Any()).Times(1)
+
+	erasure, err := service.ErasePersonalData(context.Background(), "test", models.ErasureSourceAPI, "TICKET-1", logger)
+	if err != nil {
+		t.Fatalf("ErasePersonalData failed: %v", err)
+	}
+	if !slices.Equal(erasure.OrderUIDs, []string{"uid1", "uid2"}) {
+		t.Errorf("unexpected erased orders: %v", erasure.OrderUIDs)
+	}
+}
+
+func TestService_ErasePersonalData_StaleRead(t *testing.T) {
+	controller := gomock.NewController(t)
+	mockStorage := mock_repo.NewMockStorage(controller)
+	logger := mock_logger.NewMockLogger(controller)
+	logger.EXPECT().LogInfo(gomock.Any(), gomock.Any()).AnyTimes()
+	logger.EXPECT().Debug(gomock.Any(), gomock.Any()).AnyTimes()
+	mockStorage.EXPECT().GetOrders(gomock.Any(), gomock.Any()).Return(nil, nil)
+	service := NewService(mockStorage, memory.NewCache(context.Background(), mockStorage, configs.Cache{SaveInCache: true, CacheSize: 10}, logger))
+
+	// a reader fetches the order from a lagging replica before the erasure and caches it after
+	readStarted, erasureDone := make(chan struct{}), make(chan struct{})
+	mockStorage.EXPECT().GetOrder(gomock.Any(), "b563feb7b2b84b6test").DoAndReturn(func(context.Context, string) (*models.Order, error) {
+		close(readStarted)
+		<-erasureDone
+		return storedOrder(1), nil
+	})
+	erased := storedOrder(2)
+	erased.Delivery = models.ErasedDelivery
+	mockStorage.EXPECT().ErasePersonalData(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, erasure *models.PersonalDataErasure) error {
+			erasure.OrderUIDs = []string{"b563feb7b2b84b6test"}
+			return nil
+		})
+	mockStorage.EXPECT().GetOrderFromPrimary(gomock.Any(), "b563feb7b2b84b6test").Return(erased, nil)
+
+	read := make(chan struct{})
+	go func() {
+		defer close(read)
+		_, _, _ = service.GetOrder(context.Background(), "b563feb7b2b84b6test", logger)
+	}()
+	<-readStarted
+	if _, err := service.ErasePersonalData(context.Background(), "test", models.ErasureSourceAPI, "", logger); err != nil {
+		t.Fatalf("ErasePersonalData failed: %v", err)
+	}
+	close(erasureDone)
+	<-read
+
+	order, _, err := service.GetOrder(context.Background(), "b563feb7b2b84b6test", logger)
+	if err != nil {
+		t.Fatalf("GetOrder failed: %v", err)
+	}
+	if order.Delivery != models.ErasedDelivery {
+		t.Fatalf("expected the erased order to stay cached, got delivery %+v", order.Delivery)
+	}
+}
+
+func TestService_ErasePersonalData_StorageError(t *testing.T) {
+	service, mockStorage, _ := newAmendService(t)
+	logger := mock_logger.NewMockLogger(gomock.NewController(t))
+
+	mockStorage.EXPECT().ErasePersonalData(gomock.Any(), gomock.Any()).Return(errs.ErrUnavailable)
+
+	if _, err := service.ErasePersonalData(context.Background(), "test", models.ErasureSourceCLI, "", logger); !errors.Is(err, errs.ErrUnavailable) {
+		t.Fatalf("expected ErrUnavailable, got %v", err)
+	}
+}
+
+// Erased orders must stay valid, so they can still be amended.
+func TestErasedDelivery_Valid(t *testing.T) {
+	order := storedOrder(1)
+	order.Delivery = models.ErasedDelivery
+	if err := validator.New().Struct(order); err != nil {
+		t.Fatalf("erased order is invalid: %v", err)
+	}
+}
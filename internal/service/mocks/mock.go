@@ -5,6 +5,7 @@
 package mock_service
 
 import (
+	context "context"
 	reflect "reflect"
 
 	models "github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/models"
@@ -35,10 +36,55 @@ func (m *MockServiceProvider) EXPECT() *MockServiceProviderMockRecorder {
 	return m.recorder
 }
 
+// AmendOrder mocks base method.
+func (m *MockServiceProvider) AmendOrder(ctx context.Context, orderID string, version int, patch []byte, logger logger.Logger) (*models.Order, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AmendOrder", ctx, orderID, version, patch, logger)
+	ret0, _ := ret[0].(*models.Order)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AmendOrder indicates an expected call of AmendOrder.
+func (mr *MockServiceProviderMockRecorder) AmendOrder(ctx, orderID, version, patch, logger interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AmendOrder", reflect.TypeOf((*MockServiceProvider)(nil).AmendOrder), ctx, orderID, version, patch, logger)
+}
+
+// ErasePersonalData mocks base method.
+func (m *MockServiceProvider) ErasePersonalData(ctx context.Context, customerID, source, reference string, logger logger.Logger) (models.PersonalDataErasure, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ErasePersonalData", ctx, customerID, source, reference, logger)
+	ret0, _ := ret[0].(models.PersonalDataErasure)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ErasePersonalData indicates an expected call of ErasePersonalData.
+func (mr *MockServiceProviderMockRecorder) ErasePersonalData(ctx, customerID, source, reference, logger interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ErasePersonalData", reflect.TypeOf((*MockServiceProvider)(nil).ErasePersonalData), ctx, customerID, source, reference, logger)
+}
+
+// GetCustomerOrders mocks base method.
+func (m *MockServiceProvider) GetCustomerOrders(ctx context.Context, customerID string, query models.OrderQuery) (models.CustomerOrders, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCustomerOrders", ctx, customerID, query)
+	ret0, _ := ret[0].(models.CustomerOrders)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetCustomerOrders indicates an expected call of GetCustomerOrders.
+func (mr *MockServiceProviderMockRecorder) GetCustomerOrders(ctx, customerID, query interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCustomerOrders", reflect.TypeOf((*MockServiceProvider)(nil).GetCustomerOrders), ctx, customerID, query)
+}
+
 // GetOrder mocks base method.
-func (m *MockServiceProvider) GetOrder(orderID string, logger logger.Logger) (*models.Order, bool, error) {
+func (m *MockServiceProvider) GetOrder(ctx context.Context, orderID string, logger logger.Logger) (*models.Order, bool, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetOrder", orderID, logger)
+	ret := m.ctrl.Call(m, "GetOrder", ctx, orderID, logger)
 	ret0, _ := ret[0].(*models.Order)
 	ret1, _ := ret[1].(bool)
 	ret2, _ := ret[2].(error)
@@ -46,7 +92,67 @@ func (m *MockServiceProvider) GetOrder(orderID string, logger logger.Logger) (*m
 }
 
 // GetOrder indicates an expected call of GetOrder.
-func (mr *MockServiceProviderMockRecorder) GetOrder(orderID, logger interface{}) *gomock.Call {
+func (mr *MockServiceProviderMockRecorder) GetOrder(ctx, orderID, logger interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOrder", reflect.TypeOf((*MockServiceProvider)(nil).GetOrder), ctx, orderID, logger)
+}
+
+// GetOrderByTrackNumber mocks base method.
+func (m *MockServiceProvider) GetOrderByTrackNumber(ctx context.Context, trackNumber string) (*models.Order, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOrderByTrackNumber", ctx, trackNumber)
+	ret0, _ := ret[0].(*models.Order)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOrderByTrackNumber indicates an expected call of GetOrderByTrackNumber.
+func (mr *MockServiceProviderMockRecorder) GetOrderByTrackNumber(ctx, trackNumber interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOrderByTrackNumber", reflect.TypeOf((*MockServiceProvider)(nil).GetOrderByTrackNumber), ctx, trackNumber)
+}
+
+// GetOrderHistory mocks base method.
+func (m *MockServiceProvider) GetOrderHistory(ctx context.Context, orderID string) (models.OrderHistory, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOrderHistory", ctx, orderID)
+	ret0, _ := ret[0].(models.OrderHistory)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOrderHistory indicates an expected call of GetOrderHistory.
+func (mr *MockServiceProviderMockRecorder) GetOrderHistory(ctx, orderID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOrderHistory", reflect.TypeOf((*MockServiceProvider)(nil).GetOrderHistory), ctx, orderID)
+}
+
+// GetOrderProvenance mocks base method.
+func (m *MockServiceProvider) GetOrderProvenance(ctx context.Context, orderID string) (models.OrderProvenance, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOrderProvenance", ctx, orderID)
+	ret0, _ := ret[0].(models.OrderProvenance)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOrderProvenance indicates an expected call of GetOrderProvenance.
+func (mr *MockServiceProviderMockRecorder) GetOrderProvenance(ctx, orderID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOrderProvenance", reflect.TypeOf((*MockServiceProvider)(nil).GetOrderProvenance), ctx, orderID)
+}
+
+// ListOrders mocks base method.
+func (m *MockServiceProvider) ListOrders(ctx context.Context, query models.OrderQuery) (models.OrderPage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListOrders", ctx, query)
+	ret0, _ := ret[0].(models.OrderPage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListOrders indicates an expected call of ListOrders.
+func (mr *MockServiceProviderMockRecorder) ListOrders(ctx, query interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOrder", reflect.TypeOf((*MockServiceProvider)(nil).GetOrder), orderID, logger)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListOrders", reflect.TypeOf((*MockServiceProvider)(nil).ListOrders), ctx, query)
 }
@@ -1,20 +1,77 @@
 package service
 
 import (
+	"context"
+	"fmt"
+
 	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/models"
 	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/pkg/logger"
 )
 
 // GetOrder retrieves an order by ID.
 // If the order exists in cache, it returns it from cache; otherwise, it fetches from storage and caches it.
-func (s Service) GetOrder(orderID string, logger logger.Logger) (*models.Order, bool, error) {
+// Storage errors are wrapped, so their class (see repository/errs) can be checked with errors.Is.
+func (s Service) GetOrder(ctx context.Context, orderID string, logger logger.Logger) (*models.Order, bool, error) {
 	if order, found := s.Cache.GetCachedOrder(orderID); found {
 		return order, true, nil
 	}
-	order, err := s.Storage.GetOrder(orderID)
+	order, err := s.Storage.GetOrder(ctx, orderID)
 	if err != nil {
-		return nil, false, err
+		return nil, false, fmt.Errorf("failed to get order %s: %w", orderID, err)
 	}
 	s.Cache.CacheOrder(order, logger)
 	return order, false, nil
 }
+
+// ListOrders returns a page of orders straight from storage.
+// The cache holds individual orders only, so it can't answer filtered queries.
+func (s Service) ListOrders(ctx context.Context, query models.OrderQuery) (models.OrderPage, error) {
+	page, err := s.Storage.ListOrders(ctx, query)
+	if err != nil {
+		return models.OrderPage{}, fmt.Errorf("failed to list orders: %w", err)
+	}
+	return page, nil
+}
+
+// GetOrderByTrackNumber retrieves an order by its track number.
+// The cache is keyed by order UID, so the lookup always goes to storage.
+func (s Service) GetOrderByTrackNumber(ctx context.Context, trackNumber string) (*models.Order, error) {
+	order, err := s.Storage.GetOrderByTrackNumber(ctx, trackNumber)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get order by track number %s: %w", trackNumber, err)
+	}
+	return order, nil
+}
+
+// GetCustomerOrders collects stats over all orders of a customer and a single page of those orders.
+// If the customer has no orders, the stats query reports errs.ErrNotFound and no page is fetched.
+func (s Service) GetCustomerOrders(ctx context.Context, customerID string, query models.OrderQuery) (models.CustomerOrders, error) {
+	stats, err := s.Storage.GetCustomerStats(ctx, customerID)
+	if err != nil {
+		return models.CustomerOrders{}, fmt.Errorf("failed to get stats for customer %s: %w", customerID, err)
+	}
+	query.Filter = models.OrderFilter{CustomerID: customerID}
+	page, err := s.Storage.ListOrders(ctx, query)
+	if err != nil {
+		return models.CustomerOrders{}, fmt.Errorf("failed to list orders of customer %s: %w", customerID, err)
+	}
+	return models.CustomerOrders{CustomerID: customerID, Stats: stats, OrderPage: page}, nil
+}
+
+// GetOrderHistory returns the status timeline of an order straight from storage.
+func (s Service) GetOrderHistory(ctx context.Context, orderID string) (models.OrderHistory, error) {
+	history, err := s.Storage.GetOrderHistory(ctx, orderID)
+	if err != nil {
+		return models.OrderHistory{}, fmt.Errorf("failed to get history of order %s: %w", orderID, err)
+	}
+	return history, nil
+}
+
+// GetOrderProvenance returns the Kafka message an order was received in straight from storage.
+func (s Service) GetOrderProvenance(ctx context.Context, orderID string) (models.OrderProvenance, error) {
+	provenance, err := s.Storage.GetOrderProvenance(ctx, orderID)
+	if err != nil {
+		return models.OrderProvenance{}, fmt.Errorf("failed to get provenance of order %s: %w", orderID, err)
+	}
+	return provenance, nil
+}
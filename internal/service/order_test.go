@@ -1,6 +1,7 @@
 package service
 
 import (
+	"context"
 	"fmt"
 	"testing"
 
@@ -46,7 +47,7 @@ func TestService_GetOrder_CacheHit(t *testing.T) {
 	mockCacher.EXPECT().GetCachedOrder(orderID).Return(cachedOrder, true)
 	logger := mock_logger.NewMockLogger(gomock.NewController(t))
 
-	order, found, err := service.GetOrder(orderID, logger)
+	order, found, err := service.GetOrder(context.Background(), orderID, logger)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -75,10 +76,10 @@ func TestService_GetOrder_CacheMiss(t *testing.T) {
 	expectedOrder := &models.Order{OrderUID: orderID}
 
 	mockCacher.EXPECT().GetCachedOrder(orderID).Return(nil, false)
-	mockStorage.EXPECT().GetOrder(orderID).Return(expectedOrder, nil)
+	mockStorage.EXPECT().GetOrder(gomock.Any(), orderID).Return(expectedOrder, nil)
 	mockCacher.EXPECT().CacheOrder(expectedOrder, logger)
 
-	order, found, err := service.GetOrder(orderID, logger)
+	order, found, err := service.GetOrder(context.Background(), orderID, logger)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -105,10 +106,10 @@ func TestService_GetOrder_FromDB_NotFound(t *testing.T) {
 	orderID := "0"
 
 	mockCacher.EXPECT().GetCachedOrder(orderID).Return(nil, false)
-	mockStorage.EXPECT().GetOrder(orderID).Return(nil, fmt.Errorf("order not found in storage"))
+	mockStorage.EXPECT().GetOrder(gomock.Any(), orderID).Return(nil, fmt.Errorf("order not found in storage"))
 	logger := mock_logger.NewMockLogger(gomock.NewController(t))
 
-	order, fromCache, err := service.GetOrder(orderID, logger)
+	order, fromCache, err := service.GetOrder(context.Background(), orderID, logger)
 	if err == nil {
 		t.Fatalf("expected error, got nil")
 	}
@@ -119,3 +120,121 @@ func TestService_GetOrder_FromDB_NotFound(t *testing.T) {
 		t.Fatalf("expected fromCache value to be false, got true")
 	}
 }
+
+func TestService_ListOrders(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	mockStorage := mock_repo.NewMockStorage(controller)
+	service := &Service{Storage: mockStorage}
+
+	query := models.OrderQuery{Sort: models.SortDateDesc, Limit: 10}
+	page := models.OrderPage{Orders: []*models.Order{{OrderUID: "1703"}}, NextCursor: "next"}
+	mockStorage.EXPECT().ListOrders(gomock.Any(), query).Return(page, nil)
+
+	got, err := service.ListOrders(context.Background(), query)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got.Orders) != 1 || got.NextCursor != "next" {
+		t.Fatalf("unexpected page: %+v", got)
+	}
+}
+
+func TestService_ListOrders_StorageError(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	mockStorage := mock_repo.NewMockStorage(controller)
+	service := &Service{Storage: mockStorage}
+
+	mockStorage.EXPECT().ListOrders(gomock.Any(), gomock.Any()).Return(models.OrderPage{}, fmt.Errorf("db error"))
+
+	if _, err := service.ListOrders(context.Background(), models.OrderQuery{}); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
+func TestService_GetOrderByTrackNumber(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	mockStorage := mock_repo.NewMockStorage(controller)
+	service := &Service{Storage: mockStorage}
+
+	mockStorage.EXPECT().GetOrderByTrackNumber(gomock.Any(), "TRACK").Return(&models.Order{OrderUID: "1703"}, nil)
+
+	order, err := service.GetOrderByTrackNumber(context.Background(), "TRACK")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if order.OrderUID != "1703" {
+		t.Fatalf("expected order 1703, got %s", order.OrderUID)
+	}
+
+	mockStorage.EXPECT().GetOrderByTrackNumber(gomock.Any(), "TRACK").Return(nil, fmt.Errorf("db error"))
+	if _, err := service.GetOrderByTrackNumber(context.Background(), "TRACK"); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
+func TestService_GetCustomerOrders(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	mockStorage := mock_repo.NewMockStorage(controller)
+	service := &Service{Storage: mockStorage}
+
+	stats := models.CustomerStats{OrderCount: 1}
+	query := models.OrderQuery{Sort: models.SortDateDesc, Limit: 10, Filter: models.OrderFilter{Locale: "en"}}
+	expected := models.OrderQuery{Sort: models.SortDateDesc, Limit: 10, Filter: models.OrderFilter{CustomerID: "test"}}
+
+	mockStorage.EXPECT().GetCustomerStats(gomock.Any(), "test").Return(stats, nil)
+	mockStorage.EXPECT().ListOrders(gomock.Any(), expected).Return(models.OrderPage{Orders: []*models.Order{{}}}, nil)
+
+	result, err := service.GetCustomerOrders(context.Background(), "test", query)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.CustomerID != "test" || result.Stats.OrderCount != 1 || len(result.Orders) != 1 {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+}
+
+func TestService_GetCustomerOrders_StatsError(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	mockStorage := mock_repo.NewMockStorage(controller)
+	service := &Service{Storage: mockStorage}
+
+	mockStorage.EXPECT().GetCustomerStats(gomock.Any(), "test").Return(models.CustomerStats{}, fmt.Errorf("db error"))
+
+	if _, err := service.GetCustomerOrders(context.Background(), "test", models.OrderQuery{}); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
+func TestService_GetOrderHistory(t *testing.T) {
+	controller := gomock.NewController(t)
+	defer controller.Finish()
+
+	mockStorage := mock_repo.NewMockStorage(controller)
+	service := &Service{Storage: mockStorage}
+
+	history := models.OrderHistory{OrderUID: "1703", Events: []models.StatusEvent{{Status: 300}}}
+	mockStorage.EXPECT().GetOrderHistory(gomock.Any(), "1703").Return(history, nil)
+
+	got, err := service.GetOrderHistory(context.Background(), "1703")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got.Events) != 1 {
+		t.Fatalf("expected 1 event, got %d", len(got.Events))
+	}
+
+	mockStorage.EXPECT().GetOrderHistory(gomock.Any(), "1703").Return(models.OrderHistory{}, fmt.Errorf("db error"))
+	if _, err := service.GetOrderHistory(context.Background(), "1703"); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
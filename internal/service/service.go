@@ -3,6 +3,8 @@
 package service
 
 import (
+	"context"
+
 	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/cache"
 	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/models"
 	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/internal/repository"
@@ -13,7 +15,32 @@ import (
 type ServiceProvider interface {
 	// GetOrder retrieves an order by its ID.
 	// Returns the order, a boolean indicating if it was retrieved from cache, and an error if any.
-	GetOrder(orderID string, logger logger.Logger) (*models.Order, bool, error)
+	// Cancelling ctx aborts the database query if the order is not cached.
+	GetOrder(ctx context.Context, orderID string, logger logger.Logger) (*models.Order, bool, error)
+
+	// ListOrders returns a single page of orders that match the query, bypassing the cache.
+	ListOrders(ctx context.Context, query models.OrderQuery) (models.OrderPage, error)
+
+	// GetOrderByTrackNumber retrieves an order by its track number from storage.
+	GetOrderByTrackNumber(ctx context.Context, trackNumber string) (*models.Order, error)
+
+	// GetCustomerOrders returns stats over all orders of a customer along with a page of their orders.
+	// The customer filter always comes from customerID, overriding the one in query.
+	GetCustomerOrders(ctx context.Context, customerID string, query models.OrderQuery) (models.CustomerOrders, error)
+
+	// GetOrderHistory returns the status timeline of all items of an order.
+	GetOrderHistory(ctx context.Context, orderID string) (models.OrderHistory, error)
+
+	// GetOrderProvenance returns the Kafka message an order was received in.
+	GetOrderProvenance(ctx context.Context, orderID string) (models.OrderProvenance, error)
+
+	// AmendOrder applies a JSON Merge Patch to an order that must be at the given version
+	// and returns the amended order. The cached copy is replaced with it.
+	AmendOrder(ctx context.Context, orderID string, version int, patch []byte, logger logger.Logger) (*models.Order, error)
+
+	// ErasePersonalData replaces the personal data in every order of a customer with irreversible placeholders,
+	// purges the orders from the cache and returns the compliance record of the erasure.
+	ErasePersonalData(ctx context.Context, customerID, source, reference string, logger logger.Logger) (models.PersonalDataErasure, error)
 }
 
 // Service implements ServiceProvider using a storage backend and cache.
@@ -0,0 +1,117 @@
+// Package fieldcrypt encrypts individual database fields with AES-256-GCM and computes blind indexes of them.
+//
+// A Keyring holds every key that may have encrypted a stored value, by key ID, and the active key
+// new values are encrypted with. The ID of the key is stored next to the value, so keys can be rotated
+// by re-encrypting values with the active one while the old keys still decrypt the rest.
+//
+// Encrypted values are not searchable. A blind index, an HMAC-SHA256 of the plaintext under a separate key,
+// allows exact-match lookups without revealing the value.
+package fieldcrypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+// KeySize is the size of encryption and index keys in bytes.
+const KeySize = 32
+
+// ErrUnknownKey is returned when a value was encrypted with a key the keyring doesn't hold.
+var ErrUnknownKey = errors.New("unknown encryption key")
+
+// Keyring encrypts and decrypts fields. It is safe for concurrent use.
+type Keyring struct {
+	ciphers  map[string]cipher.AEAD
+	active   string
+	indexKey []byte
+}
+
+/*
+New creates a Keyring from AES-256 keys by key ID.
+
+active is the ID of the key new values are encrypted with, it must be one of keys.
+indexKey is the HMAC key of blind indexes. Every key must be KeySize bytes long.
+*/
+func New(keys map[string][]byte, active string, indexKey []byte) (*Keyring, error) {
+	if _, ok := keys[active]; !ok {
+		return nil, fmt.Errorf("active key %q is not among the keys", active)
+	}
+	if len(indexKey) != KeySize {
+		return nil, fmt.Errorf("index key must be %d bytes, got %d", KeySize, len(indexKey))
+	}
+	k := &Keyring{ciphers: make(map[string]cipher.AEAD, len(keys)), active: active, indexKey: indexKey}
+	for id, key := range keys {
+		if id == "" {
+			return nil, errors.New("key ID must not be empty")
+		}
+		if len(key) != KeySize {
+			return nil, fmt.Errorf("key %q must be %d bytes, got %d", id, KeySize, len(key))
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", id, err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", id, err)
+		}
+		k.ciphers[id] = aead
+	}
+	return k, nil
+}
+
+// ActiveKey returns the ID of the key new values are encrypted with.
+func (k *Keyring) ActiveKey() string {
+	return k.active
+}
+
+// Encrypt encrypts a value of the named field with the active key and returns it base64-encoded.
+// The field name is authenticated along with the value, so a value can't be moved to another field.
+func (k *Keyring) Encrypt(field, plaintext string) (string, error) {
+	aead := k.ciphers[k.active]
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), []byte(field))
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt decrypts a value of the named field that was encrypted with the given key.
+// Returns ErrUnknownKey if the keyring doesn't hold the key.
+func (k *Keyring) Decrypt(keyID, field, ciphertext string) (string, error) {
+	aead, ok := k.ciphers[keyID]
+	if !ok {
+		return "", fmt.Errorf("%w %q", ErrUnknownKey, keyID)
+	}
+	sealed, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil {
+		return "", fmt.Errorf("malformed %s: %w", field, err)
+	}
+	if len(sealed) < aead.NonceSize() {
+		return "", fmt.Errorf("malformed %s: too short", field)
+	}
+	nonce, sealed := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, sealed, []byte(field))
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt %s with key %q: %w", field, keyID, err)
+	}
+	return string(plaintext), nil
+}
+
+// BlindIndex returns the blind index of a value of the named field, hex-encoded.
+// Equal values of the same field have equal indexes; values of different fields never do.
+func (k *Keyring) BlindIndex(field, value string) string {
+	mac := hmac.New(sha256.New, k.indexKey)
+	mac.Write([]byte(field))
+	mac.Write([]byte{0})
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
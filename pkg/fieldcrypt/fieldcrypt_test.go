@@ -0,0 +1,80 @@
+package fieldcrypt_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/pkg/fieldcrypt"
+)
+
+func testKeyring(t *testing.T, active string) *fieldcrypt.Keyring {
+	t.Helper()
+	keys := map[string][]byte{"k1": bytes.Repeat([]byte{1}, 32), "k2": bytes.Repeat([]byte{2}, 32)}
+	keyring, err := fieldcrypt.New(keys, active, bytes.Repeat([]byte{3}, 32))
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	return keyring
+}
+
+func TestKeyring_RoundTrip(t *testing.T) {
+	old, current := testKeyring(t, "k1"), testKeyring(t, "k2")
+
+	sealed, err := old.Encrypt("phone", "+9720000000")
+	if err != nil {
+		t.Fatalf("Encrypt failed: %v", err)
+	}
+	again, _ := old.Encrypt("phone", "+9720000000")
+	if sealed == again {
+		t.Error("expected every encryption to use a fresh nonce")
+	}
+	// a keyring with another active key still decrypts values of its other keys
+	if got, err := current.Decrypt("k1", "phone", sealed); err != nil || got != "+9720000000" {
+		t.Fatalf("expected the phone back, got %q (%v)", got, err)
+	}
+	if _, err := current.Decrypt("k2", "phone", sealed); err == nil {
+		t.Error("expected decrypting with another key to fail")
+	}
+	if _, err := current.Decrypt("k1", "email", sealed); err == nil {
+		t.Error("expected decrypting as another field to fail")
+	}
+	if _, err := current.Decrypt("k9", "phone", sealed); !errors.Is(err, fieldcrypt.ErrUnknownKey) {
+		t.Errorf("expected ErrUnknownKey, got %v", err)
+	}
+}
+
+func TestKeyring_BlindIndex(t *testing.T) {
+	keyring := testKeyring(t, "k1")
+
+	if keyring.BlindIndex("phone", "+972") != testKeyring(t, "k2").BlindIndex("phone", "+972") {
+		t.Error("expected the index not to depend on the active key")
+	}
+	if keyring.BlindIndex("phone", "+972") == keyring.BlindIndex("email", "+972") {
+		t.Error("expected indexes of different fields to differ")
+	}
+	if keyring.BlindIndex("phone", "+972") == keyring.BlindIndex("phone", "+973") {
+		t.Error("expected indexes of different values to differ")
+	}
+}
+
+func TestNew_InvalidKeys(t *testing.T) {
+	key, short := bytes.Repeat([]byte{1}, 32), []byte("short")
+	tests := map[string]struct {
+		keys     map[string][]byte
+		active   string
+		indexKey []byte
+	}{
+		"unknown active key": {map[string][]byte{"k1": key}, "k2", key},
+		"short key":          {map[string][]byte{"k1": short}, "k1", key},
+		"short index key":    {map[string][]byte{"k1": key}, "k1", short},
+		"empty key ID":       {map[string][]byte{"k1": key, "": key}, "k1", key},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := fieldcrypt.New(tt.keys, tt.active, tt.indexKey); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+// Package mergepatch applies JSON Merge Patch documents (RFC 7386).
+//
+// A merge patch looks like the document it changes: members it holds replace those of the document,
+// objects are merged recursively and null removes a member. Arrays are replaced as a whole.
+package mergepatch
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// Apply returns doc with patch merged into it. Both must be valid JSON.
+// Numbers are kept as they were written, so large integers don't lose precision.
+func Apply(doc, patch []byte) ([]byte, error) {
+	target, err := decode(doc)
+	if err != nil {
+		return nil, fmt.Errorf("invalid document: %w", err)
+	}
+	changes, err := decode(patch)
+	if err != nil {
+		return nil, fmt.Errorf("invalid patch: %w", err)
+	}
+	return json.Marshal(merge(target, changes))
+}
+
+// merge implements the MergePatch function of RFC 7386, section 2.
+func merge(target, patch any) any {
+	changes, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+	merged, ok := target.(map[string]any)
+	if !ok {
+		merged = make(map[string]any, len(changes))
+	}
+	for name, value := range changes {
+		if value == nil {
+			delete(merged, name)
+			continue
+		}
+		merged[name] = merge(merged[name], value)
+	}
+	return merged
+}
+
+// decode unmarshals a single JSON value, keeping numbers as json.Number.
+func decode(data []byte) (any, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	if decoder.More() {
+		return nil, fmt.Errorf("unexpected data after the JSON value")
+	}
+	return value, nil
+}
@@ -0,0 +1,47 @@
+package mergepatch_test
+
+import (
+	"testing"
+
+	"github.com/Pur1st2EpicONE/WBTECH-sample-microservice/pkg/mergepatch"
+)
+
+// Examples from RFC 7386, appendix A.
+func TestApply(t *testing.T) {
+	cases := []struct{ doc, patch, want string }{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"a":"foo"}`, `null`, `null`},
+		{`{"a":"foo"}`, `"bar"`, `"bar"`},
+		{`{"e":null}`, `{"a":1}`, `{"a":1,"e":null}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+		{`{"id":9007199254740993}`, `{}`, `{"id":9007199254740993}`},
+	}
+	for _, c := range cases {
+		got, err := mergepatch.Apply([]byte(c.doc), []byte(c.patch))
+		if err != nil {
+			t.Fatalf("Apply(%s, %s) failed: %v", c.doc, c.patch, err)
+		}
+		if string(got) != c.want {
+			t.Errorf("Apply(%s, %s) = %s, want %s", c.doc, c.patch, got, c.want)
+		}
+	}
+}
+
+func TestApply_InvalidJSON(t *testing.T) {
+	if _, err := mergepatch.Apply([]byte(`{}`), []byte(`{"a":`)); err == nil {
+		t.Error("expected an invalid patch to be rejected")
+	}
+	if _, err := mergepatch.Apply([]byte(`{} {}`), []byte(`{}`)); err == nil {
+		t.Error("expected trailing data to be rejected")
+	}
+}
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_items_status_order_id;
+DROP INDEX IF EXISTS idx_payments_currency;
+DROP INDEX IF EXISTS idx_orders_locale;
+DROP INDEX IF EXISTS idx_orders_delivery_service_date_created_id;
+DROP INDEX IF EXISTS idx_orders_customer_id_date_created_id;
+DROP INDEX IF EXISTS idx_payments_amount_order_id;
+DROP INDEX IF EXISTS idx_orders_date_created_id;
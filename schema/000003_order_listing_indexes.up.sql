@@ -0,0 +1,16 @@
+-- Indexes backing GET /api/v1/orders. Every listing is ordered by a sort key plus the order ID
+-- as a tie-breaker, so each index ends with the ID to serve both the ORDER BY and the keyset condition.
+CREATE INDEX IF NOT EXISTS idx_orders_date_created_id ON orders(date_created, id);
+CREATE INDEX IF NOT EXISTS idx_payments_amount_order_id ON payments(amount, order_id);
+
+-- The most selective filters get their own composite index, so filtered pages can still
+-- be read in sort order without scanning the whole table.
+CREATE INDEX IF NOT EXISTS idx_orders_customer_id_date_created_id ON orders(customer_id, date_created, id);
+CREATE INDEX IF NOT EXISTS idx_orders_delivery_service_date_created_id ON orders(delivery_service, date_created, id);
+
+-- Low-cardinality filters, mostly combined with the ones above.
+CREATE INDEX IF NOT EXISTS idx_orders_locale ON orders(locale);
+CREATE INDEX IF NOT EXISTS idx_payments_currency ON payments(currency);
+
+-- Item status filter is an EXISTS subquery correlated by order_id.
+CREATE INDEX IF NOT EXISTS idx_items_status_order_id ON items(status, order_id);
@@ -0,0 +1,7 @@
+-- Lookup table of a sharded deployment: which shard holds an order.
+-- It is only filled in the main database; the tables of the shards themselves stay empty.
+CREATE TABLE IF NOT EXISTS order_shards (
+    order_uid VARCHAR(255) PRIMARY KEY,
+    shard VARCHAR(100) NOT NULL,
+    assigned_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
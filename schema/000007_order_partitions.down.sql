@@ -0,0 +1,133 @@
+-- Recreates the unpartitioned tables of migration 000006 and copies the rows back.
+
+CREATE TEMP TABLE orders_copy ON COMMIT DROP AS SELECT * FROM orders;
+CREATE TEMP TABLE deliveries_copy ON COMMIT DROP AS SELECT * FROM deliveries;
+CREATE TEMP TABLE payments_copy ON COMMIT DROP AS SELECT * FROM payments;
+CREATE TEMP TABLE items_copy ON COMMIT DROP AS SELECT * FROM items;
+CREATE TEMP TABLE item_status_history_copy ON COMMIT DROP AS SELECT * FROM item_status_history;
+
+DROP TABLE item_status_history, items, payments, deliveries, orders;
+DROP TABLE IF EXISTS order_keys;
+DROP FUNCTION IF EXISTS create_order_partitions(DATE);
+DROP FUNCTION IF EXISTS register_order_key();
+DROP FUNCTION IF EXISTS release_order_key();
+
+CREATE TABLE orders (
+    id INTEGER GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    order_uid VARCHAR(255) UNIQUE NOT NULL,
+    track_number VARCHAR(255) UNIQUE NOT NULL,
+    entry VARCHAR(255) NOT NULL,
+    locale VARCHAR(10) NOT NULL,
+    internal_signature VARCHAR(255) NULL,
+    customer_id VARCHAR(255) NOT NULL,
+    delivery_service VARCHAR(255) NOT NULL,
+    shardkey VARCHAR(10) NOT NULL,
+    sm_id INTEGER NOT NULL,
+    date_created TIMESTAMP NOT NULL,
+    oof_shard VARCHAR(10) NOT NULL,
+    checksum CHAR(64) NULL
+);
+
+CREATE TABLE deliveries (
+    id INTEGER GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    phone VARCHAR(50) NOT NULL,
+    zip VARCHAR(50) NOT NULL,
+    city VARCHAR(100) NOT NULL,
+    address VARCHAR(255) NOT NULL,
+    region VARCHAR(255) NOT NULL,
+    email VARCHAR(100) NOT NULL,
+    order_id INTEGER UNIQUE NOT NULL,
+    CONSTRAINT fk_delivery_order_id FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE CASCADE
+);
+
+CREATE TABLE payments (
+    id INTEGER GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    transaction VARCHAR(255) NOT NULL,
+    request_id VARCHAR(255) DEFAULT NULL,
+    currency VARCHAR(10) NOT NULL,
+    provider VARCHAR(50) NOT NULL,
+    amount NUMERIC(10,2) NOT NULL,
+    payment_dt TIMESTAMP NOT NULL,
+    bank VARCHAR(50) NULL,
+    delivery_cost NUMERIC(10,2) NOT NULL,
+    goods_total NUMERIC(10,2) NOT NULL,
+    custom_fee NUMERIC(10,2) NOT NULL DEFAULT 0,
+    order_id INTEGER UNIQUE NOT NULL,
+    CONSTRAINT fk_payment_order_id FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE CASCADE,
+    CONSTRAINT fk_payment_transaction FOREIGN KEY (transaction) REFERENCES orders(order_uid) ON DELETE CASCADE
+);
+
+CREATE TABLE items (
+    id INTEGER GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    chrt_id INTEGER NOT NULL,
+    track_number VARCHAR(255) NOT NULL,
+    price NUMERIC(10,2) NOT NULL,
+    rid VARCHAR(255) NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    sale INTEGER NOT NULL DEFAULT 0,
+    size VARCHAR(10) DEFAULT NULL,
+    total_price NUMERIC(10,2) NOT NULL,
+    nm_id INTEGER NOT NULL,
+    brand VARCHAR(100) NOT NULL,
+    status INTEGER NOT NULL,
+    order_id INTEGER NOT NULL,
+    CONSTRAINT fk_item_order_id FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE CASCADE,
+    CONSTRAINT fk_item_track_number FOREIGN KEY (track_number) REFERENCES orders(track_number) ON DELETE CASCADE
+);
+
+CREATE TABLE item_status_history (
+    id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    item_id INTEGER NOT NULL,
+    order_id INTEGER NOT NULL,
+    previous_status INTEGER NOT NULL,
+    status INTEGER NOT NULL,
+    changed_at TIMESTAMP NOT NULL,
+    recorded_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CONSTRAINT fk_status_history_item_id FOREIGN KEY (item_id) REFERENCES items(id) ON DELETE CASCADE,
+    CONSTRAINT fk_status_history_order_id FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE CASCADE,
+    CONSTRAINT uq_status_history_event UNIQUE (item_id, status, changed_at)
+);
+
+CREATE INDEX idx_items_order_id ON items(order_id);
+CREATE INDEX IF NOT EXISTS idx_orders_date_created_id ON orders(date_created, id);
+CREATE INDEX IF NOT EXISTS idx_payments_amount_order_id ON payments(amount, order_id);
+CREATE INDEX IF NOT EXISTS idx_orders_customer_id_date_created_id ON orders(customer_id, date_created, id);
+CREATE INDEX IF NOT EXISTS idx_orders_delivery_service_date_created_id ON orders(delivery_service, date_created, id);
+CREATE INDEX IF NOT EXISTS idx_orders_locale ON orders(locale);
+CREATE INDEX IF NOT EXISTS idx_payments_currency ON payments(currency);
+CREATE INDEX IF NOT EXISTS idx_items_status_order_id ON items(status, order_id);
+CREATE INDEX IF NOT EXISTS idx_status_history_order_id_changed_at ON item_status_history(order_id, changed_at);
+CREATE INDEX IF NOT EXISTS idx_items_order_id_chrt_id ON items(order_id, chrt_id);
+
+INSERT INTO orders (id, order_uid, track_number, entry, locale, internal_signature, customer_id,
+    delivery_service, shardkey, sm_id, date_created, oof_shard, checksum)
+OVERRIDING SYSTEM VALUE
+SELECT id, order_uid, track_number, entry, locale, internal_signature, customer_id,
+    delivery_service, shardkey, sm_id, date_created, oof_shard, checksum
+FROM orders_copy;
+
+INSERT INTO deliveries (id, name, phone, zip, city, address, region, email, order_id)
+OVERRIDING SYSTEM VALUE
+SELECT id, name, phone, zip, city, address, region, email, order_id FROM deliveries_copy;
+
+INSERT INTO payments (id, transaction, request_id, currency, provider, amount, payment_dt, bank,
+    delivery_cost, goods_total, custom_fee, order_id)
+OVERRIDING SYSTEM VALUE
+SELECT id, transaction, request_id, currency, provider, amount, payment_dt, bank,
+    delivery_cost, goods_total, custom_fee, order_id
+FROM payments_copy;
+
+INSERT INTO items (id, chrt_id, track_number, price, rid, name, sale, size, total_price, nm_id, brand, status, order_id)
+OVERRIDING SYSTEM VALUE
+SELECT id, chrt_id, track_number, price, rid, name, sale, size, total_price, nm_id, brand, status, order_id FROM items_copy;
+
+INSERT INTO item_status_history (id, item_id, order_id, previous_status, status, changed_at, recorded_at)
+OVERRIDING SYSTEM VALUE
+SELECT id, item_id, order_id, previous_status, status, changed_at, recorded_at FROM item_status_history_copy;
+
+SELECT setval(pg_get_serial_sequence('orders', 'id'), COALESCE(MAX(id), 0) + 1, FALSE) FROM orders;
+SELECT setval(pg_get_serial_sequence('deliveries', 'id'), COALESCE(MAX(id), 0) + 1, FALSE) FROM deliveries;
+SELECT setval(pg_get_serial_sequence('payments', 'id'), COALESCE(MAX(id), 0) + 1, FALSE) FROM payments;
+SELECT setval(pg_get_serial_sequence('items', 'id'), COALESCE(MAX(id), 0) + 1, FALSE) FROM items;
+SELECT setval(pg_get_serial_sequence('item_status_history', 'id'), COALESCE(MAX(id), 0) + 1, FALSE) FROM item_status_history;
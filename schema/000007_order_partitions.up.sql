@@ -0,0 +1,222 @@
+-- Orders and everything stored with them are partitioned by month of the order date (orders.date_created),
+-- so that old orders can be archived and dropped a month at a time. Postgres can't partition a table in place,
+-- so the tables are recreated and their rows copied over.
+--
+-- Every table gets the order date as order_date: it is the partition key, and it is part of every primary key,
+-- unique constraint and foreign key, since Postgres requires that of partitioned tables. The partitions of one month
+-- share a suffix, e.g. orders_p202501 and items_p202501, see create_order_partitions. Orders of a month that has
+-- no partitions go to the *_default partitions.
+
+CREATE TEMP TABLE orders_copy ON COMMIT DROP AS SELECT * FROM orders;
+CREATE TEMP TABLE deliveries_copy ON COMMIT DROP AS
+    SELECT deliveries.*, orders.date_created AS order_date FROM deliveries JOIN orders ON orders.id = deliveries.order_id;
+CREATE TEMP TABLE payments_copy ON COMMIT DROP AS
+    SELECT payments.*, orders.date_created AS order_date FROM payments JOIN orders ON orders.id = payments.order_id;
+CREATE TEMP TABLE items_copy ON COMMIT DROP AS
+    SELECT items.*, orders.date_created AS order_date FROM items JOIN orders ON orders.id = items.order_id;
+CREATE TEMP TABLE item_status_history_copy ON COMMIT DROP AS
+    SELECT item_status_history.*, orders.date_created AS order_date FROM item_status_history JOIN orders ON orders.id = item_status_history.order_id;
+
+DROP TABLE item_status_history, items, payments, deliveries, orders;
+
+-- Order UIDs and track numbers must stay unique across partitions, which no constraint of a partitioned table
+-- can enforce. order_keys does it instead: every order registers its keys there before it is inserted.
+CREATE TABLE IF NOT EXISTS order_keys (
+    order_uid VARCHAR(255) PRIMARY KEY,
+    track_number VARCHAR(255) UNIQUE NOT NULL,
+    order_date TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_keys_order_date ON order_keys(order_date);
+
+CREATE SEQUENCE IF NOT EXISTS orders_id_seq AS INTEGER;
+CREATE SEQUENCE IF NOT EXISTS deliveries_id_seq AS INTEGER;
+CREATE SEQUENCE IF NOT EXISTS payments_id_seq AS INTEGER;
+CREATE SEQUENCE IF NOT EXISTS items_id_seq AS INTEGER;
+CREATE SEQUENCE IF NOT EXISTS item_status_history_id_seq AS BIGINT;
+
+CREATE TABLE orders (
+    id INTEGER NOT NULL DEFAULT nextval('orders_id_seq'),
+    order_uid VARCHAR(255) NOT NULL,
+    track_number VARCHAR(255) NOT NULL,
+    entry VARCHAR(255) NOT NULL,
+    locale VARCHAR(10) NOT NULL,
+    internal_signature VARCHAR(255) NULL,
+    customer_id VARCHAR(255) NOT NULL,
+    delivery_service VARCHAR(255) NOT NULL,
+    shardkey VARCHAR(10) NOT NULL,
+    sm_id INTEGER NOT NULL,
+    date_created TIMESTAMP NOT NULL,
+    oof_shard VARCHAR(10) NOT NULL,
+    checksum CHAR(64) NULL,
+    PRIMARY KEY (id, date_created)
+) PARTITION BY RANGE (date_created);
+
+CREATE TABLE deliveries (
+    id INTEGER NOT NULL DEFAULT nextval('deliveries_id_seq'),
+    name VARCHAR(255) NOT NULL,
+    phone VARCHAR(50) NOT NULL,
+    zip VARCHAR(50) NOT NULL,
+    city VARCHAR(100) NOT NULL,
+    address VARCHAR(255) NOT NULL,
+    region VARCHAR(255) NOT NULL,
+    email VARCHAR(100) NOT NULL,
+    order_id INTEGER NOT NULL,
+    order_date TIMESTAMP NOT NULL,
+    PRIMARY KEY (id, order_date),
+    CONSTRAINT uq_delivery_order_id UNIQUE (order_id, order_date),
+    CONSTRAINT fk_delivery_order_id FOREIGN KEY (order_id, order_date) REFERENCES orders(id, date_created) ON DELETE CASCADE
+) PARTITION BY RANGE (order_date);
+
+CREATE TABLE payments (
+    id INTEGER NOT NULL DEFAULT nextval('payments_id_seq'),
+    transaction VARCHAR(255) NOT NULL,
+    request_id VARCHAR(255) DEFAULT NULL,
+    currency VARCHAR(10) NOT NULL,
+    provider VARCHAR(50) NOT NULL,
+    amount NUMERIC(10,2) NOT NULL,
+    payment_dt TIMESTAMP NOT NULL,
+    bank VARCHAR(50) NULL,
+    delivery_cost NUMERIC(10,2) NOT NULL,
+    goods_total NUMERIC(10,2) NOT NULL,
+    custom_fee NUMERIC(10,2) NOT NULL DEFAULT 0,
+    order_id INTEGER NOT NULL,
+    order_date TIMESTAMP NOT NULL,
+    PRIMARY KEY (id, order_date),
+    CONSTRAINT uq_payment_order_id UNIQUE (order_id, order_date),
+    CONSTRAINT fk_payment_order_id FOREIGN KEY (order_id, order_date) REFERENCES orders(id, date_created) ON DELETE CASCADE
+) PARTITION BY RANGE (order_date);
+
+CREATE TABLE items (
+    id INTEGER NOT NULL DEFAULT nextval('items_id_seq'),
+    chrt_id INTEGER NOT NULL,
+    track_number VARCHAR(255) NOT NULL,
+    price NUMERIC(10,2) NOT NULL,
+    rid VARCHAR(255) NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    sale INTEGER NOT NULL DEFAULT 0,
+    size VARCHAR(10) DEFAULT NULL,
+    total_price NUMERIC(10,2) NOT NULL,
+    nm_id INTEGER NOT NULL,
+    brand VARCHAR(100) NOT NULL,
+    status INTEGER NOT NULL,
+    order_id INTEGER NOT NULL,
+    order_date TIMESTAMP NOT NULL,
+    PRIMARY KEY (id, order_date),
+    CONSTRAINT fk_item_order_id FOREIGN KEY (order_id, order_date) REFERENCES orders(id, date_created) ON DELETE CASCADE
+) PARTITION BY RANGE (order_date);
+
+CREATE TABLE item_status_history (
+    id BIGINT NOT NULL DEFAULT nextval('item_status_history_id_seq'),
+    item_id INTEGER NOT NULL,
+    order_id INTEGER NOT NULL,
+    previous_status INTEGER NOT NULL,
+    status INTEGER NOT NULL,
+    changed_at TIMESTAMP NOT NULL,
+    recorded_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    order_date TIMESTAMP NOT NULL,
+    PRIMARY KEY (id, order_date),
+    CONSTRAINT fk_status_history_item_id FOREIGN KEY (item_id, order_date) REFERENCES items(id, order_date) ON DELETE CASCADE,
+    CONSTRAINT fk_status_history_order_id FOREIGN KEY (order_id, order_date) REFERENCES orders(id, date_created) ON DELETE CASCADE,
+    CONSTRAINT uq_status_history_event UNIQUE (item_id, status, changed_at, order_date)
+) PARTITION BY RANGE (order_date);
+
+ALTER SEQUENCE orders_id_seq OWNED BY orders.id;
+ALTER SEQUENCE deliveries_id_seq OWNED BY deliveries.id;
+ALTER SEQUENCE payments_id_seq OWNED BY payments.id;
+ALTER SEQUENCE items_id_seq OWNED BY items.id;
+ALTER SEQUENCE item_status_history_id_seq OWNED BY item_status_history.id;
+
+-- The indexes of migrations 000001, 000003 and 000004, created on every partition.
+CREATE INDEX IF NOT EXISTS idx_orders_order_uid ON orders(order_uid);
+CREATE INDEX IF NOT EXISTS idx_orders_track_number ON orders(track_number);
+CREATE INDEX IF NOT EXISTS idx_items_order_id ON items(order_id);
+CREATE INDEX IF NOT EXISTS idx_orders_date_created_id ON orders(date_created, id);
+CREATE INDEX IF NOT EXISTS idx_payments_amount_order_id ON payments(amount, order_id);
+CREATE INDEX IF NOT EXISTS idx_orders_customer_id_date_created_id ON orders(customer_id, date_created, id);
+CREATE INDEX IF NOT EXISTS idx_orders_delivery_service_date_created_id ON orders(delivery_service, date_created, id);
+CREATE INDEX IF NOT EXISTS idx_orders_locale ON orders(locale);
+CREATE INDEX IF NOT EXISTS idx_payments_currency ON payments(currency);
+CREATE INDEX IF NOT EXISTS idx_items_status_order_id ON items(status, order_id);
+CREATE INDEX IF NOT EXISTS idx_status_history_order_id_changed_at ON item_status_history(order_id, changed_at);
+CREATE INDEX IF NOT EXISTS idx_items_order_id_chrt_id ON items(order_id, chrt_id);
+
+-- register_order_key skips an order whose UID or track number is already taken,
+-- just like ON CONFLICT DO NOTHING would on an unpartitioned table.
+CREATE OR REPLACE FUNCTION register_order_key() RETURNS TRIGGER AS $$
+BEGIN
+    INSERT INTO order_keys (order_uid, track_number, order_date)
+    VALUES (NEW.order_uid, NEW.track_number, NEW.date_created)
+    ON CONFLICT DO NOTHING;
+    IF NOT FOUND THEN
+        RETURN NULL;
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+-- release_order_key frees the keys of a deleted order. Dropped partitions release theirs explicitly.
+CREATE OR REPLACE FUNCTION release_order_key() RETURNS TRIGGER AS $$
+BEGIN
+    DELETE FROM order_keys WHERE order_uid = OLD.order_uid;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trg_orders_register_key BEFORE INSERT ON orders FOR EACH ROW EXECUTE FUNCTION register_order_key();
+CREATE TRIGGER trg_orders_release_key AFTER DELETE ON orders FOR EACH ROW EXECUTE FUNCTION release_order_key();
+
+-- create_order_partitions creates the partitions of every order table for the month of in_month, unless they exist.
+-- The service calls it ahead of time for the coming months.
+CREATE OR REPLACE FUNCTION create_order_partitions(in_month DATE) RETURNS VOID AS $$
+DECLARE
+    first_day DATE := date_trunc('month', in_month)::DATE;
+    next_month DATE := (date_trunc('month', in_month) + INTERVAL '1 month')::DATE;
+    suffix TEXT := to_char(in_month, '"p"YYYYMM');
+    parent TEXT;
+BEGIN
+    FOREACH parent IN ARRAY ARRAY['orders', 'deliveries', 'payments', 'items', 'item_status_history'] LOOP
+        EXECUTE format('CREATE TABLE IF NOT EXISTS %I PARTITION OF %I FOR VALUES FROM (%L) TO (%L)',
+            parent || '_' || suffix, parent, first_day, next_month);
+    END LOOP;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TABLE orders_default PARTITION OF orders DEFAULT;
+CREATE TABLE deliveries_default PARTITION OF deliveries DEFAULT;
+CREATE TABLE payments_default PARTITION OF payments DEFAULT;
+CREATE TABLE items_default PARTITION OF items DEFAULT;
+CREATE TABLE item_status_history_default PARTITION OF item_status_history DEFAULT;
+
+SELECT create_order_partitions(month) FROM (
+    SELECT DISTINCT date_trunc('month', date_created)::DATE AS month FROM orders_copy
+    UNION SELECT date_trunc('month', NOW())::DATE
+    UNION SELECT (date_trunc('month', NOW()) + INTERVAL '1 month')::DATE
+) AS months;
+
+INSERT INTO orders (id, order_uid, track_number, entry, locale, internal_signature, customer_id,
+    delivery_service, shardkey, sm_id, date_created, oof_shard, checksum)
+SELECT id, order_uid, track_number, entry, locale, internal_signature, customer_id,
+    delivery_service, shardkey, sm_id, date_created, oof_shard, checksum
+FROM orders_copy;
+
+INSERT INTO deliveries (id, name, phone, zip, city, address, region, email, order_id, order_date)
+SELECT id, name, phone, zip, city, address, region, email, order_id, order_date FROM deliveries_copy;
+
+INSERT INTO payments (id, transaction, request_id, currency, provider, amount, payment_dt, bank,
+    delivery_cost, goods_total, custom_fee, order_id, order_date)
+SELECT id, transaction, request_id, currency, provider, amount, payment_dt, bank,
+    delivery_cost, goods_total, custom_fee, order_id, order_date
+FROM payments_copy;
+
+INSERT INTO items (id, chrt_id, track_number, price, rid, name, sale, size, total_price, nm_id, brand, status, order_id, order_date)
+SELECT id, chrt_id, track_number, price, rid, name, sale, size, total_price, nm_id, brand, status, order_id, order_date FROM items_copy;
+
+INSERT INTO item_status_history (id, item_id, order_id, previous_status, status, changed_at, recorded_at, order_date)
+SELECT id, item_id, order_id, previous_status, status, changed_at, recorded_at, order_date FROM item_status_history_copy;
+
+SELECT setval('orders_id_seq', COALESCE(MAX(id), 0) + 1, FALSE) FROM orders;
+SELECT setval('deliveries_id_seq', COALESCE(MAX(id), 0) + 1, FALSE) FROM deliveries;
+SELECT setval('payments_id_seq', COALESCE(MAX(id), 0) + 1, FALSE) FROM payments;
+SELECT setval('items_id_seq', COALESCE(MAX(id), 0) + 1, FALSE) FROM items;
+SELECT setval('item_status_history_id_seq', COALESCE(MAX(id), 0) + 1, FALSE) FROM item_status_history;
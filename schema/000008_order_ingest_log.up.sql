@@ -0,0 +1,17 @@
+-- Kafka message every order was received in, written in the same transaction as the order.
+-- Rows go away with the order: the key of an order is released when it is deleted, moved to another shard or archived.
+-- "offset" is a reserved word, hence the kafka_ prefixes.
+CREATE TABLE IF NOT EXISTS order_ingest_log (
+    order_uid VARCHAR(255) PRIMARY KEY,
+    topic VARCHAR(255) NOT NULL,
+    kafka_partition INTEGER NOT NULL,
+    kafka_offset BIGINT NOT NULL,
+    message_key TEXT NOT NULL,
+    message_timestamp TIMESTAMP NULL,
+    ingested_at TIMESTAMP NOT NULL,
+    payload JSONB NOT NULL,
+    CONSTRAINT fk_ingest_log_order_uid FOREIGN KEY (order_uid) REFERENCES order_keys(order_uid) ON DELETE CASCADE
+);
+
+-- Finds the order a given message produced.
+CREATE INDEX IF NOT EXISTS idx_ingest_log_message ON order_ingest_log(topic, kafka_partition, kafka_offset);
@@ -0,0 +1,16 @@
+-- Orders can be amended after they are stored (see PATCH /api/v1/orders/:orderId).
+-- version starts at 1 and grows by one with every amendment; it is compared on update for optimistic locking.
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
+
+-- Audit trail of amendments: the merge patch that was applied and the order as it was before.
+-- Rows go away with the order, like the ingest log.
+CREATE TABLE IF NOT EXISTS order_amendments (
+    id BIGSERIAL PRIMARY KEY,
+    order_uid VARCHAR(255) NOT NULL,
+    version INTEGER NOT NULL,
+    patch JSONB NOT NULL,
+    previous JSONB NOT NULL,
+    amended_at TIMESTAMP NOT NULL,
+    CONSTRAINT fk_amendments_order_uid FOREIGN KEY (order_uid) REFERENCES order_keys(order_uid) ON DELETE CASCADE,
+    CONSTRAINT uq_amendments_order_version UNIQUE (order_uid, version)
+);
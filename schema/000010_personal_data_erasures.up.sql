@@ -0,0 +1,12 @@
+-- Compliance record of every erasure of the personal data of a customer (see DELETE /api/v1/customers/:customerId/personal-data).
+-- The record itself holds no personal data: just the customer ID, the orders that were anonymised and who asked for it.
+CREATE TABLE IF NOT EXISTS personal_data_erasures (
+    id BIGSERIAL PRIMARY KEY,
+    customer_id VARCHAR(255) NOT NULL,
+    order_uids JSONB NOT NULL,
+    source VARCHAR(32) NOT NULL,
+    reference TEXT NOT NULL,
+    erased_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_erasures_customer_id ON personal_data_erasures(customer_id);
@@ -0,0 +1,19 @@
+-- Encrypted rows can't be turned back into plaintext here: run "wb-service rotate-keys -decrypt" first.
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM deliveries WHERE key_id IS NOT NULL) THEN
+        RAISE EXCEPTION 'deliveries hold encrypted rows, run "wb-service rotate-keys -decrypt" first';
+    END IF;
+END $$;
+
+DROP INDEX IF EXISTS idx_deliveries_key_id;
+DROP INDEX IF EXISTS idx_deliveries_email_index;
+DROP INDEX IF EXISTS idx_deliveries_phone_index;
+ALTER TABLE deliveries
+    DROP COLUMN IF EXISTS email_index,
+    DROP COLUMN IF EXISTS phone_index,
+    DROP COLUMN IF EXISTS key_id,
+    ALTER COLUMN name TYPE VARCHAR(255),
+    ALTER COLUMN phone TYPE VARCHAR(50),
+    ALTER COLUMN address TYPE VARCHAR(255),
+    ALTER COLUMN email TYPE VARCHAR(100);
@@ -0,0 +1,17 @@
+-- The name, phone, address and email of deliveries can be encrypted by the service (see database.encryption).
+-- Encrypted values are longer than the plaintext, so the columns become TEXT.
+-- key_id is the ID of the key a row is encrypted with, NULL for plaintext rows.
+-- phone_index and email_index are blind indexes (HMAC) of the plaintext for exact-match lookups; NULL for plaintext rows.
+ALTER TABLE deliveries
+    ALTER COLUMN name TYPE TEXT,
+    ALTER COLUMN phone TYPE TEXT,
+    ALTER COLUMN address TYPE TEXT,
+    ALTER COLUMN email TYPE TEXT,
+    ADD COLUMN IF NOT EXISTS key_id VARCHAR(64) NULL,
+    ADD COLUMN IF NOT EXISTS phone_index VARCHAR(64) NULL,
+    ADD COLUMN IF NOT EXISTS email_index VARCHAR(64) NULL;
+
+CREATE INDEX IF NOT EXISTS idx_deliveries_phone_index ON deliveries(phone_index);
+CREATE INDEX IF NOT EXISTS idx_deliveries_email_index ON deliveries(email_index);
+-- Finds the rows the key rotation has yet to re-encrypt.
+CREATE INDEX IF NOT EXISTS idx_deliveries_key_id ON deliveries(key_id);
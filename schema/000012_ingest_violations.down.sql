@@ -0,0 +1 @@
+ALTER TABLE order_ingest_log DROP COLUMN IF EXISTS violations;
@@ -0,0 +1,3 @@
+-- Business rules an order was flagged by when it was received (see kafka.consumer.rules), as a JSON array.
+-- NULL if it broke none, and for orders received before the rules were checked.
+ALTER TABLE order_ingest_log ADD COLUMN IF NOT EXISTS violations JSONB NULL;
@@ -0,0 +1,13 @@
+-- Encrypted rows can't be turned back into plaintext here: run "wb-service rotate-keys -decrypt" first.
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM order_ingest_log WHERE key_id IS NOT NULL)
+        OR EXISTS (SELECT 1 FROM order_amendments WHERE key_id IS NOT NULL) THEN
+        RAISE EXCEPTION 'order_ingest_log or order_amendments hold encrypted rows, run "wb-service rotate-keys -decrypt" first';
+    END IF;
+END $$;
+
+DROP INDEX IF EXISTS idx_order_amendments_key_id;
+DROP INDEX IF EXISTS idx_order_ingest_log_key_id;
+ALTER TABLE order_amendments DROP COLUMN IF EXISTS key_id;
+ALTER TABLE order_ingest_log DROP COLUMN IF EXISTS key_id;
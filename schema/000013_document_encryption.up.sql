@@ -0,0 +1,9 @@
+-- The delivery within the ingested payloads and the amendments of orders is encrypted like the deliveries
+-- (see 000011_delivery_encryption.up.sql): the name, phone, address and email of its JSON become ciphertext.
+-- key_id is the ID of the key a row is encrypted with, NULL for plaintext rows.
+ALTER TABLE order_ingest_log ADD COLUMN IF NOT EXISTS key_id VARCHAR(64) NULL;
+ALTER TABLE order_amendments ADD COLUMN IF NOT EXISTS key_id VARCHAR(64) NULL;
+
+-- Finds the rows the key rotation has yet to re-encrypt.
+CREATE INDEX IF NOT EXISTS idx_order_ingest_log_key_id ON order_ingest_log(key_id);
+CREATE INDEX IF NOT EXISTS idx_order_amendments_key_id ON order_amendments(key_id);
@@ -0,0 +1,33 @@
+// Package schema embeds the database migrations into the binary.
+//
+// Migrations are numbered files named <version>_<title>.up.sql and <version>_<title>.down.sql.
+// The files in this directory are the Postgres migrations. The sqlite subdirectory holds the same
+// migrations for the embedded SQLite storage, with the same version numbers.
+// Every schema change must be added to both.
+package schema
+
+import (
+	"embed"
+	"io/fs"
+)
+
+//go:embed *.sql
+var postgresFiles embed.FS
+
+//go:embed sqlite/*.sql
+var sqliteFiles embed.FS
+
+// Postgres holds the Postgres migrations.
+var Postgres fs.FS = postgresFiles
+
+// SQLite holds the SQLite migrations.
+var SQLite fs.FS = mustSub(sqliteFiles, "sqlite")
+
+// mustSub returns the subtree of an embedded FS. It can only fail on an invalid path, which is a programming error.
+func mustSub(fsys fs.FS, dir string) fs.FS {
+	sub, err := fs.Sub(fsys, dir)
+	if err != nil {
+		panic(err)
+	}
+	return sub
+}
@@ -0,0 +1,3 @@
+-- SHA-256 of the order payload, used to tell an identical redelivery apart from
+-- a conflicting order with the same UID. Orders saved before this migration keep NULL.
+ALTER TABLE orders ADD COLUMN checksum CHAR(64) NULL;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_items_order_id_chrt_id;
+DROP TABLE IF EXISTS item_status_history;
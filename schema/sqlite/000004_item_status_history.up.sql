@@ -0,0 +1,22 @@
+-- Every status change of an item received from the status-update topic.
+-- changed_at is when the change happened at the source, recorded_at is when the service stored it.
+-- recorded_at has no default: the storage sets it, so it has the same text format as changed_at.
+-- The unique constraint makes redelivered events a no-op.
+CREATE TABLE IF NOT EXISTS item_status_history (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    item_id INTEGER NOT NULL,
+    order_id INTEGER NOT NULL,
+    previous_status INTEGER NOT NULL,
+    status INTEGER NOT NULL,
+    changed_at TIMESTAMP NOT NULL,
+    recorded_at TIMESTAMP NOT NULL,
+    CONSTRAINT fk_status_history_item_id FOREIGN KEY (item_id) REFERENCES items(id) ON DELETE CASCADE,
+    CONSTRAINT fk_status_history_order_id FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE CASCADE,
+    CONSTRAINT uq_status_history_event UNIQUE (item_id, status, changed_at)
+);
+
+-- The timeline is always read for a whole order in chronological order.
+CREATE INDEX IF NOT EXISTS idx_status_history_order_id_changed_at ON item_status_history(order_id, changed_at);
+
+-- Status updates look items up by order, chrt_id and rid.
+CREATE INDEX IF NOT EXISTS idx_items_order_id_chrt_id ON items(order_id, chrt_id);
@@ -0,0 +1,17 @@
+-- Transactional outbox: events are written in the same transaction as the order they describe
+-- and published to Kafka by the outbox relay afterwards, so an event exists if and only if the order was committed.
+-- next_attempt_at is both the retry schedule of failed events and the lease of events claimed by a relay.
+CREATE TABLE IF NOT EXISTS outbox (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    event_type VARCHAR(100) NOT NULL,
+    event_key VARCHAR(255) NOT NULL,
+    payload TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP NOT NULL,
+    last_error TEXT NULL,
+    sent_at TIMESTAMP NULL
+);
+
+-- The relay only ever looks for unsent events that are due.
+CREATE INDEX IF NOT EXISTS idx_outbox_pending ON outbox(next_attempt_at, id) WHERE sent_at IS NULL;
@@ -0,0 +1,7 @@
+-- Lookup table of a sharded deployment: which shard holds an order.
+-- Sharding is Postgres-only, the table just keeps the schema versions of both databases in line.
+CREATE TABLE IF NOT EXISTS order_shards (
+    order_uid VARCHAR(255) PRIMARY KEY,
+    shard VARCHAR(100) NOT NULL,
+    assigned_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,2 @@
+-- Nothing to revert, see 000007_order_partitions.up.sql.
+SELECT 1;
@@ -0,0 +1,4 @@
+-- SQLite counterpart of schema/000007_order_partitions.up.sql.
+-- SQLite has no table partitioning, and old orders of the embedded database are not archived,
+-- so the tables stay as they are; the migration just keeps the schema versions of both databases in line.
+SELECT 1;
@@ -0,0 +1,15 @@
+-- SQLite counterpart of schema/000008_order_ingest_log.up.sql.
+-- The payload is stored as text.
+CREATE TABLE IF NOT EXISTS order_ingest_log (
+    order_uid VARCHAR(255) PRIMARY KEY,
+    topic VARCHAR(255) NOT NULL,
+    kafka_partition INTEGER NOT NULL,
+    kafka_offset BIGINT NOT NULL,
+    message_key TEXT NOT NULL,
+    message_timestamp TIMESTAMP NULL,
+    ingested_at TIMESTAMP NOT NULL,
+    payload TEXT NOT NULL,
+    CONSTRAINT fk_ingest_log_order_uid FOREIGN KEY (order_uid) REFERENCES orders(order_uid) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_ingest_log_message ON order_ingest_log(topic, kafka_partition, kafka_offset);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS order_amendments;
+ALTER TABLE orders DROP COLUMN version;
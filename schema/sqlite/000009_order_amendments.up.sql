@@ -0,0 +1,14 @@
+-- SQLite counterpart of schema/000009_order_amendments.up.sql.
+-- The patch and the previous order are stored as text.
+ALTER TABLE orders ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+
+CREATE TABLE IF NOT EXISTS order_amendments (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    order_uid VARCHAR(255) NOT NULL,
+    version INTEGER NOT NULL,
+    patch TEXT NOT NULL,
+    previous TEXT NOT NULL,
+    amended_at TIMESTAMP NOT NULL,
+    CONSTRAINT fk_amendments_order_uid FOREIGN KEY (order_uid) REFERENCES orders(order_uid) ON DELETE CASCADE,
+    CONSTRAINT uq_amendments_order_version UNIQUE (order_uid, version)
+);
@@ -0,0 +1,12 @@
+-- SQLite counterpart of schema/000010_personal_data_erasures.up.sql.
+-- The order UIDs are stored as a JSON array in text.
+CREATE TABLE IF NOT EXISTS personal_data_erasures (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    customer_id VARCHAR(255) NOT NULL,
+    order_uids TEXT NOT NULL,
+    source VARCHAR(32) NOT NULL,
+    reference TEXT NOT NULL,
+    erased_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_erasures_customer_id ON personal_data_erasures(customer_id);
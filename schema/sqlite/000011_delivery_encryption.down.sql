@@ -0,0 +1,2 @@
+-- Nothing to revert, see 000011_delivery_encryption.up.sql.
+SELECT 1;
@@ -0,0 +1,4 @@
+-- SQLite counterpart of schema/000011_delivery_encryption.up.sql.
+-- The embedded database stores deliveries as plaintext, as it is a local file of the service,
+-- so the tables stay as they are; the migration just keeps the schema versions of both databases in line.
+SELECT 1;
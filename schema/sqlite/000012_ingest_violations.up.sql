@@ -0,0 +1,3 @@
+-- SQLite counterpart of schema/000012_ingest_violations.up.sql.
+-- The violations are stored as a JSON array in text.
+ALTER TABLE order_ingest_log ADD COLUMN violations TEXT NULL;
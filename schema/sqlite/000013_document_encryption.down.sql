@@ -0,0 +1,2 @@
+-- Nothing to revert, see 000013_document_encryption.up.sql.
+SELECT 1;
@@ -0,0 +1,4 @@
+-- SQLite counterpart of schema/000013_document_encryption.up.sql.
+-- The embedded database stores ingested payloads and amendments as plaintext, like its deliveries,
+-- so the tables stay as they are; the migration just keeps the schema versions of both databases in line.
+SELECT 1;